@@ -1,9 +1,9 @@
 resource "grantory_request" "database" {
   host_id = grantory_host.app.host_id
 
-  payload = jsonencode({
+  payload = {
     db = "things"
-  })
+  }
 
   labels = {
     team = "operations"
@@ -0,0 +1,7 @@
+resource "grantory_webhook" "db_requests" {
+  url            = "https://hooks.example.com/grantory"
+  resource_types = ["requests", "grants"]
+  label_selector = {
+    type = "db_user"
+  }
+}
@@ -42,6 +42,10 @@ var (
 	terraformGrantTemplateSource string
 	terraformGrantTemplate       = template.Must(template.New("grantPipeline").Parse(terraformGrantTemplateSource))
 
+	//go:embed testdata/integration/import.hcl
+	terraformImportTemplateSource string
+	terraformImportTemplate       = template.Must(template.New("import").Parse(terraformImportTemplateSource))
+
 	//go:embed testdata/integration/cli-config.hcl
 	cliConfigTemplateSource string
 	cliConfigTemplate       = template.Must(template.New("cliConfig").Funcs(template.FuncMap{
@@ -190,6 +194,22 @@ func TestIntegrationTerraformApplyUpdatesServer(t *testing.T) {
 	freshRequest, err := store.GetRequest(context.Background(), requestID)
 	require.NoError(t, err, "retrieve request after grant creation")
 	require.True(t, freshRequest.HasGrant, "request should be marked as granted")
+
+	// A configuration without state adopts the records created above, by ID
+	// and by labels. The imported state must match the configuration.
+	importDir := filepath.Join(workspace, "import")
+	require.NoError(t, os.MkdirAll(importDir, 0o755))
+	writeImportConfig(t, importDir, serverURL, updatedLabels)
+	runTofuCommand(t, tofuPath, importDir, cliConfigPath, "import", "-input=false", "grantory_host.imported", integrationHost.ID)
+	runTofuCommand(t, tofuPath, importDir, cliConfigPath, "import", "-input=false", "grantory_request.imported", "labels:pipeline=integration")
+	runTofuCommand(t, tofuPath, importDir, cliConfigPath, "import", "-input=false", "grantory_register.imported", foundRegister.ID)
+	runTofuCommand(t, tofuPath, importDir, cliConfigPath, "import", "-input=false", "grantory_grant.imported", foundGrant.ID)
+	runTofuCommand(t, tofuPath, importDir, cliConfigPath, "plan", "-input=false", "-detailed-exitcode")
+
+	imported := showTofuState(t, tofuPath, importDir, cliConfigPath)
+	require.Equal(t, requestID, imported["grantory_request.imported"]["id"], "request imported by labels")
+	require.Equal(t, integrationHost.ID, imported["grantory_request.imported"]["host_id"])
+	require.Equal(t, requestID, imported["grantory_grant.imported"]["request_id"])
 }
 
 func buildProviderBinary(t *testing.T, dst string) {
@@ -246,6 +266,24 @@ func writeGrantPipelineConfig(t *testing.T, dir, serverURL string, labels map[st
 	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
 }
 
+func writeImportConfig(t *testing.T, dir, serverURL string, labels map[string]string) {
+	t.Helper()
+	labelEntries := labelEntriesFromMap(labels)
+
+	var buf bytes.Buffer
+	data := terraformTemplateData{
+		ProviderVersion:    integrationProviderVersion,
+		ServerURL:          serverURL,
+		RegisterDataSource: integrationRegisterDataSource,
+		RequestLabels:      labelEntries,
+		RegisterLabels:     labelEntries,
+	}
+	require.NoError(t, terraformImportTemplate.Execute(&buf, data), "render import template")
+
+	path := filepath.Join(dir, "main.tf")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+}
+
 func labelEntriesFromMap(labels map[string]string) []labelEntry {
 	if len(labels) == 0 {
 		return nil
@@ -290,6 +328,37 @@ func runTofuCommand(t *testing.T, tofuPath, tfDir, cliConfig string, args ...str
 	}
 }
 
+// showTofuState returns the attribute values of every resource in the state
+// of tfDir, keyed by resource address.
+func showTofuState(t *testing.T, tofuPath, tfDir, cliConfig string) map[string]map[string]any {
+	t.Helper()
+	cmd := exec.Command(tofuPath, "show", "-json")
+	cmd.Dir = tfDir
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("TF_CLI_CONFIG_FILE=%s", cliConfig),
+		fmt.Sprintf("TOFU_CLI_CONFIG_FILE=%s", cliConfig),
+	)
+	output, err := cmd.Output()
+	require.NoError(t, err, "tofu show")
+
+	var state struct {
+		Values struct {
+			RootModule struct {
+				Resources []struct {
+					Address string         `json:"address"`
+					Values  map[string]any `json:"values"`
+				} `json:"resources"`
+			} `json:"root_module"`
+		} `json:"values"`
+	}
+	require.NoError(t, json.Unmarshal(output, &state), "parse tofu show output")
+	resources := make(map[string]map[string]any)
+	for _, resource := range state.Values.RootModule.Resources {
+		resources[resource.Address] = resource.Values
+	}
+	return resources
+}
+
 func listHostsViaCLI(t *testing.T, serverURL string) []storage.Host {
 	t.Helper()
 	cmd := cli.NewRootCommand()
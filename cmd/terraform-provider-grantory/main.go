@@ -1,13 +1,20 @@
 package main
 
 import (
-	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
+	"context"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 
 	"github.com/tasansga/terraform-provider-grantory/internal/provider"
 )
 
 func main() {
-	plugin.Serve(&plugin.ServeOpts{
-		ProviderFunc: provider.New,
+	err := providerserver.Serve(context.Background(), provider.New, providerserver.ServeOpts{
+		Address:         "registry.terraform.io/tasansga/grantory",
+		ProtocolVersion: 5,
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
 }
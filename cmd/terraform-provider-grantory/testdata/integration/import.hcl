@@ -0,0 +1,54 @@
+terraform {
+  required_providers {
+    grantory = {
+      source  = "tasansga/grantory"
+      version = "{{ .ProviderVersion }}"
+    }
+  }
+}
+
+provider "grantory" {
+  server = "{{ .ServerURL }}"
+}
+
+resource "grantory_host" "imported" {
+  labels = {
+    env = "integration"
+  }
+}
+
+resource "grantory_request" "imported" {
+  host_id = grantory_host.imported.host_id
+  payload = jsonencode({
+    request = "integration"
+  })
+{{- if .RequestLabels }}
+  labels = {
+{{- range .RequestLabels }}
+    {{ .Key }} = "{{ .Value }}"
+{{- end }}
+  }
+{{- end }}
+}
+
+resource "grantory_register" "imported" {
+  host_id = grantory_host.imported.host_id
+  payload = jsonencode({
+    source = "{{ .RegisterDataSource }}"
+  })
+{{- if .RegisterLabels }}
+  labels = {
+{{- range .RegisterLabels }}
+    {{ .Key }} = "{{ .Value }}"
+{{- end }}
+  }
+{{- end }}
+}
+
+resource "grantory_grant" "imported" {
+  request_id = grantory_request.imported.id
+
+  payload = jsonencode({
+    granted = true
+  })
+}
@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+const (
+	auditOutputJSON  = "json"
+	auditOutputTable = "table"
+)
+
+func newAuditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Show the audit log of the selected namespace",
+		Long:  "Show who changed which resource, newest first. Payloads are shown as SHA-256 hashes.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			filters, err := auditFiltersFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				return err
+			}
+			output = strings.ToLower(strings.TrimSpace(output))
+			if output != auditOutputJSON && output != auditOutputTable {
+				return fmt.Errorf("invalid --output %q: must be %s or %s", output, auditOutputJSON, auditOutputTable)
+			}
+
+			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
+				events, err := backend.ListAuditEvents(ctx, filters)
+				if err != nil {
+					return err
+				}
+				if output == auditOutputTable {
+					return writeAuditTable(os.Stdout, events)
+				}
+				return outputJSON(events)
+			})
+		},
+	}
+	cmd.Flags().String("since", "", "only show events at or after this RFC 3339 timestamp")
+	cmd.Flags().String("until", "", "only show events before this RFC 3339 timestamp")
+	cmd.Flags().String("resource-type", "", "only show events for this resource type ("+strings.Join(storage.AuditResourceTypes(), ", ")+")")
+	cmd.Flags().String("resource-id", "", "only show events for this resource id")
+	cmd.Flags().Int("limit", 0, "maximum number of events to show")
+	cmd.Flags().StringP("output", "o", auditOutputJSON, "output format, json or table")
+	return cmd
+}
+
+func auditFiltersFromFlags(cmd *cobra.Command) (storage.AuditListFilters, error) {
+	var filters storage.AuditListFilters
+	for _, field := range []struct {
+		flag   string
+		target **time.Time
+	}{
+		{"since", &filters.Since},
+		{"until", &filters.Until},
+	} {
+		raw, err := cmd.Flags().GetString(field.flag)
+		if err != nil {
+			return storage.AuditListFilters{}, err
+		}
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		value, err := time.Parse(time.RFC3339, strings.TrimSpace(raw))
+		if err != nil {
+			return storage.AuditListFilters{}, fmt.Errorf("invalid --%s %q: must be an RFC 3339 timestamp", field.flag, raw)
+		}
+		*field.target = &value
+	}
+
+	resourceType, err := cmd.Flags().GetString("resource-type")
+	if err != nil {
+		return storage.AuditListFilters{}, err
+	}
+	filters.ResourceType = strings.ToLower(strings.TrimSpace(resourceType))
+	if filters.ResourceType != "" && !slices.Contains(storage.AuditResourceTypes(), filters.ResourceType) {
+		return storage.AuditListFilters{}, fmt.Errorf("invalid --resource-type %q: must be one of %s", resourceType, strings.Join(storage.AuditResourceTypes(), ", "))
+	}
+
+	if filters.ResourceID, err = cmd.Flags().GetString("resource-id"); err != nil {
+		return storage.AuditListFilters{}, err
+	}
+	if filters.Limit, err = cmd.Flags().GetInt("limit"); err != nil {
+		return storage.AuditListFilters{}, err
+	}
+	if filters.Limit < 0 {
+		return storage.AuditListFilters{}, fmt.Errorf("invalid --limit %d: must not be negative", filters.Limit)
+	}
+	return filters, nil
+}
+
+func writeAuditTable(w io.Writer, events []storage.AuditEvent) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(table, "TIME\tACTOR\tACTION\tRESOURCE\tID\tCHANGES"); err != nil {
+		return err
+	}
+	for _, event := range events {
+		if _, err := fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			event.CreatedAt.UTC().Format(time.RFC3339),
+			event.Actor,
+			event.Action,
+			event.ResourceType,
+			event.ResourceID,
+			auditChanges(event),
+		); err != nil {
+			return err
+		}
+	}
+	return table.Flush()
+}
+
+// auditChanges summarizes the difference between the before and after state.
+func auditChanges(event storage.AuditEvent) string {
+	before, after := event.Before, event.After
+	if before == nil {
+		before = &storage.AuditState{}
+	}
+	if after == nil {
+		after = &storage.AuditState{}
+	}
+
+	var changes []string
+	if before.Status != after.Status {
+		changes = append(changes, fmt.Sprintf("status %s→%s", auditValue(string(before.Status)), auditValue(string(after.Status))))
+	}
+	if before.PayloadHash != after.PayloadHash {
+		changes = append(changes, fmt.Sprintf("payload %s→%s", shortHash(before.PayloadHash), shortHash(after.PayloadHash)))
+	}
+	keys := make(map[string]struct{})
+	for key := range before.Labels {
+		keys[key] = struct{}{}
+	}
+	for key := range after.Labels {
+		keys[key] = struct{}{}
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+	for _, key := range sortedKeys {
+		oldValue, hadOld := before.Labels[key]
+		newValue, hasNew := after.Labels[key]
+		if hadOld == hasNew && oldValue == newValue {
+			continue
+		}
+		if !hadOld {
+			oldValue = "-"
+		}
+		if !hasNew {
+			newValue = "-"
+		}
+		changes = append(changes, fmt.Sprintf("%s=%s→%s", key, oldValue, newValue))
+	}
+	if len(changes) == 0 {
+		return "-"
+	}
+	return strings.Join(changes, ", ")
+}
+
+func auditValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return auditValue(hash)
+}
@@ -11,17 +11,20 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+	"github.com/tasansga/terraform-provider-grantory/internal/telemetry"
 )
 
 type cliBackend interface {
 	ListHosts(context.Context) ([]storage.Host, error)
+	ListStaleHosts(context.Context, time.Duration) ([]storage.Host, error)
 	ListRequests(context.Context, *storage.RequestListFilters) ([]storage.Request, error)
 	ListRegisters(context.Context, *storage.RegisterListFilters) ([]storage.Register, error)
-	ListGrants(context.Context) ([]storage.Grant, error)
+	ListGrants(context.Context, *storage.GrantListFilters) ([]storage.Grant, error)
 	GetHost(context.Context, string) (storage.Host, error)
 	GetRequest(context.Context, string) (storage.Request, error)
 	GetRegister(context.Context, string) (storage.Register, error)
@@ -31,8 +34,23 @@ type cliBackend interface {
 	DeleteRegister(context.Context, string) error
 	DeleteGrant(context.Context, string) error
 	UpdateHostLabels(context.Context, string, map[string]string) error
+	HeartbeatHost(context.Context, string) (storage.Host, error)
 	UpdateRequestLabels(context.Context, string, map[string]string) error
 	UpdateRegisterLabels(context.Context, string, map[string]string) error
+	UpdateGrantLabels(context.Context, string, map[string]string) error
+	ListWebhooks(context.Context) ([]storage.Webhook, error)
+	GetWebhook(context.Context, string) (storage.Webhook, error)
+	CreateWebhook(context.Context, storage.Webhook) (storage.Webhook, error)
+	UpdateWebhook(context.Context, storage.Webhook) (storage.Webhook, error)
+	DeleteWebhook(context.Context, string) error
+	ListWebhookDeliveries(context.Context, storage.WebhookDeliveryListFilters) ([]storage.WebhookDelivery, error)
+	ListAuditEvents(context.Context, storage.AuditListFilters) ([]storage.AuditEvent, error)
+	ListRequestTypes(context.Context) ([]storage.RequestType, error)
+	GetRequestType(context.Context, string) (storage.RequestType, error)
+	PutRequestType(context.Context, storage.RequestType) (storage.RequestType, error)
+	DeleteRequestType(context.Context, string) error
+	ExportNamespace(context.Context) (storage.Export, error)
+	ImportNamespace(context.Context, storage.Export, storage.ConflictPolicy) (storage.ImportReport, error)
 }
 
 type backendConfig struct {
@@ -121,18 +139,26 @@ func resolveBackendConfig(cmd *cobra.Command) (backendConfig, error) {
 	}, nil
 }
 
-func newDirectBackend(store *storage.Store) cliBackend {
+func newDirectBackend(store storage.Backend) cliBackend {
 	return &directBackend{store: store}
 }
 
 type directBackend struct {
-	store *storage.Store
+	store storage.Backend
 }
 
 func (d *directBackend) ListHosts(ctx context.Context) ([]storage.Host, error) {
 	return d.store.ListHosts(ctx)
 }
 
+func (d *directBackend) ListStaleHosts(ctx context.Context, staleAfter time.Duration) ([]storage.Host, error) {
+	return d.store.ListStaleHosts(ctx, time.Now().Add(-staleAfter))
+}
+
+func (d *directBackend) HeartbeatHost(ctx context.Context, id string) (storage.Host, error) {
+	return d.store.RecordHostHeartbeat(ctx, id, time.Now())
+}
+
 //go:noinline
 func (d *directBackend) ListRequests(ctx context.Context, filters *storage.RequestListFilters) ([]storage.Request, error) {
 	return d.store.ListRequests(ctx, filters)
@@ -144,8 +170,8 @@ func (d *directBackend) ListRegisters(ctx context.Context, filters *storage.Regi
 }
 
 //go:noinline
-func (d *directBackend) ListGrants(ctx context.Context) ([]storage.Grant, error) {
-	return d.store.ListGrants(ctx)
+func (d *directBackend) ListGrants(ctx context.Context, filters *storage.GrantListFilters) ([]storage.Grant, error) {
+	return d.store.ListGrants(ctx, filters)
 }
 
 func (d *directBackend) GetHost(ctx context.Context, id string) (storage.Host, error) {
@@ -198,6 +224,65 @@ func (d *directBackend) UpdateRegisterLabels(ctx context.Context, id string, lab
 	return d.store.UpdateRegisterLabels(ctx, id, labels)
 }
 
+func (d *directBackend) UpdateGrantLabels(ctx context.Context, id string, labels map[string]string) error {
+	return d.store.UpdateGrantLabels(ctx, id, labels)
+}
+
+func (d *directBackend) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	return d.store.ListWebhooks(ctx)
+}
+
+func (d *directBackend) GetWebhook(ctx context.Context, id string) (storage.Webhook, error) {
+	return d.store.GetWebhook(ctx, id)
+}
+
+func (d *directBackend) CreateWebhook(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error) {
+	return d.store.CreateWebhook(ctx, webhook)
+}
+
+func (d *directBackend) UpdateWebhook(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error) {
+	return d.store.UpdateWebhook(ctx, webhook)
+}
+
+func (d *directBackend) DeleteWebhook(ctx context.Context, id string) error {
+	return d.store.DeleteWebhook(ctx, id)
+}
+
+func (d *directBackend) ListWebhookDeliveries(ctx context.Context, filters storage.WebhookDeliveryListFilters) ([]storage.WebhookDelivery, error) {
+	if _, err := d.store.GetWebhook(ctx, filters.WebhookID); err != nil {
+		return nil, err
+	}
+	return d.store.ListWebhookDeliveries(ctx, filters)
+}
+
+func (d *directBackend) ListAuditEvents(ctx context.Context, filters storage.AuditListFilters) ([]storage.AuditEvent, error) {
+	return d.store.ListAuditEvents(ctx, filters)
+}
+
+func (d *directBackend) ListRequestTypes(ctx context.Context) ([]storage.RequestType, error) {
+	return d.store.ListRequestTypes(ctx)
+}
+
+func (d *directBackend) GetRequestType(ctx context.Context, name string) (storage.RequestType, error) {
+	return d.store.GetRequestType(ctx, name)
+}
+
+func (d *directBackend) PutRequestType(ctx context.Context, requestType storage.RequestType) (storage.RequestType, error) {
+	return d.store.PutRequestType(ctx, requestType)
+}
+
+func (d *directBackend) DeleteRequestType(ctx context.Context, name string) error {
+	return d.store.DeleteRequestType(ctx, name)
+}
+
+func (d *directBackend) ExportNamespace(ctx context.Context) (storage.Export, error) {
+	return d.store.ExportNamespace(ctx)
+}
+
+func (d *directBackend) ImportNamespace(ctx context.Context, doc storage.Export, policy storage.ConflictPolicy) (storage.ImportReport, error) {
+	return d.store.ImportNamespace(ctx, doc, policy)
+}
+
 func newAPIBackend(namespace, rawURL, token, user, password string) (cliBackend, error) {
 	if strings.TrimSpace(rawURL) == "" {
 		return nil, fmt.Errorf("server URL is required for API backend")
@@ -238,46 +323,47 @@ type labelsPayload struct {
 }
 
 func (a *apiBackend) ListHosts(ctx context.Context) ([]storage.Host, error) {
-	var hosts []storage.Host
-	if err := a.doJSON(ctx, http.MethodGet, "/hosts", nil, &hosts); err != nil {
-		return nil, err
+	return listAllPages[storage.Host](ctx, a, "/hosts")
+}
+
+func (a *apiBackend) ListStaleHosts(ctx context.Context, staleAfter time.Duration) ([]storage.Host, error) {
+	endpoint := "/hosts?" + url.Values{"stale_after": {staleAfter.String()}}.Encode()
+	return listAllPages[storage.Host](ctx, a, endpoint)
+}
+
+func (a *apiBackend) HeartbeatHost(ctx context.Context, id string) (storage.Host, error) {
+	var host storage.Host
+	if err := a.doJSON(ctx, http.MethodPost, fmt.Sprintf("/hosts/%s/heartbeat", id), nil, &host); err != nil {
+		return storage.Host{}, err
 	}
-	return hosts, nil
+	return host, nil
 }
 
 //go:noinline
 func (a *apiBackend) ListRequests(ctx context.Context, filters *storage.RequestListFilters) ([]storage.Request, error) {
-	var requests []storage.Request
 	endpoint, err := appendRequestListFilters("/requests", filters)
 	if err != nil {
 		return nil, err
 	}
-	if err := a.doJSON(ctx, http.MethodGet, endpoint, nil, &requests); err != nil {
-		return nil, err
-	}
-	return requests, nil
+	return listAllPages[storage.Request](ctx, a, endpoint)
 }
 
 //go:noinline
 func (a *apiBackend) ListRegisters(ctx context.Context, filters *storage.RegisterListFilters) ([]storage.Register, error) {
-	var registers []storage.Register
 	endpoint, err := appendRegisterListFilters("/registers", filters)
 	if err != nil {
 		return nil, err
 	}
-	if err := a.doJSON(ctx, http.MethodGet, endpoint, nil, &registers); err != nil {
-		return nil, err
-	}
-	return registers, nil
+	return listAllPages[storage.Register](ctx, a, endpoint)
 }
 
 //go:noinline
-func (a *apiBackend) ListGrants(ctx context.Context) ([]storage.Grant, error) {
-	var grants []storage.Grant
-	if err := a.doJSON(ctx, http.MethodGet, "/grants", nil, &grants); err != nil {
+func (a *apiBackend) ListGrants(ctx context.Context, filters *storage.GrantListFilters) ([]storage.Grant, error) {
+	endpoint, err := appendGrantListFilters("/grants", filters)
+	if err != nil {
 		return nil, err
 	}
-	return grants, nil
+	return listAllPages[storage.Grant](ctx, a, endpoint)
 }
 
 //go:noinline
@@ -349,6 +435,172 @@ func (a *apiBackend) UpdateRegisterLabels(ctx context.Context, id string, labels
 	return a.doJSON(ctx, http.MethodPatch, fmt.Sprintf("/registers/%s", id), labelsPayload{Labels: labels}, nil)
 }
 
+func (a *apiBackend) UpdateGrantLabels(ctx context.Context, id string, labels map[string]string) error {
+	return a.doJSON(ctx, http.MethodPatch, fmt.Sprintf("/grants/%s", id), labelsPayload{Labels: labels}, nil)
+}
+
+type webhookPayload struct {
+	URL            string            `json:"url"`
+	Secret         string            `json:"secret,omitempty"`
+	ResourceTypes  []string          `json:"resource_types"`
+	LabelSelector  map[string]string `json:"label_selector"`
+	IncludePayload bool              `json:"include_payload"`
+}
+
+func newWebhookPayload(webhook storage.Webhook) webhookPayload {
+	payload := webhookPayload{
+		URL:            webhook.URL,
+		Secret:         webhook.Secret,
+		ResourceTypes:  webhook.ResourceTypes,
+		LabelSelector:  webhook.LabelSelector,
+		IncludePayload: webhook.IncludePayload,
+	}
+	// Send empty values rather than null so updates clear the filters.
+	if payload.ResourceTypes == nil {
+		payload.ResourceTypes = []string{}
+	}
+	if payload.LabelSelector == nil {
+		payload.LabelSelector = map[string]string{}
+	}
+	return payload
+}
+
+func (a *apiBackend) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	var webhooks []storage.Webhook
+	if err := a.doJSON(ctx, http.MethodGet, "/webhooks", nil, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (a *apiBackend) GetWebhook(ctx context.Context, id string) (storage.Webhook, error) {
+	var webhook storage.Webhook
+	if err := a.doJSON(ctx, http.MethodGet, fmt.Sprintf("/webhooks/%s", id), nil, &webhook); err != nil {
+		return storage.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func (a *apiBackend) CreateWebhook(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error) {
+	var created storage.Webhook
+	if err := a.doJSON(ctx, http.MethodPost, "/webhooks", newWebhookPayload(webhook), &created); err != nil {
+		return storage.Webhook{}, err
+	}
+	return created, nil
+}
+
+func (a *apiBackend) UpdateWebhook(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error) {
+	var updated storage.Webhook
+	if err := a.doJSON(ctx, http.MethodPatch, fmt.Sprintf("/webhooks/%s", webhook.ID), newWebhookPayload(webhook), &updated); err != nil {
+		return storage.Webhook{}, err
+	}
+	return updated, nil
+}
+
+func (a *apiBackend) DeleteWebhook(ctx context.Context, id string) error {
+	return a.doJSON(ctx, http.MethodDelete, fmt.Sprintf("/webhooks/%s", id), nil, nil)
+}
+
+func (a *apiBackend) ListAuditEvents(ctx context.Context, filters storage.AuditListFilters) ([]storage.AuditEvent, error) {
+	params := url.Values{}
+	if filters.Since != nil {
+		params.Set("since", filters.Since.Format(time.RFC3339))
+	}
+	if filters.Until != nil {
+		params.Set("until", filters.Until.Format(time.RFC3339))
+	}
+	if filters.ResourceType != "" {
+		params.Set("resource_type", filters.ResourceType)
+	}
+	if filters.ResourceID != "" {
+		params.Set("resource_id", filters.ResourceID)
+	}
+	if filters.Limit > 0 {
+		params.Set("limit", strconv.Itoa(filters.Limit))
+	}
+	endpoint := "/audit"
+	if encoded := params.Encode(); encoded != "" {
+		endpoint = endpoint + "?" + encoded
+	}
+
+	var events []storage.AuditEvent
+	if err := a.doJSON(ctx, http.MethodGet, endpoint, nil, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (a *apiBackend) ListWebhookDeliveries(ctx context.Context, filters storage.WebhookDeliveryListFilters) ([]storage.WebhookDelivery, error) {
+	params := url.Values{}
+	for _, status := range filters.Statuses {
+		params.Add("status", string(status))
+	}
+	if filters.Limit > 0 {
+		params.Set("limit", strconv.Itoa(filters.Limit))
+	}
+	endpoint := fmt.Sprintf("/webhooks/%s/deliveries", filters.WebhookID)
+	if encoded := params.Encode(); encoded != "" {
+		endpoint = endpoint + "?" + encoded
+	}
+
+	var deliveries []storage.WebhookDelivery
+	if err := a.doJSON(ctx, http.MethodGet, endpoint, nil, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+type requestTypePayload struct {
+	RequestSchema json.RawMessage `json:"request_schema,omitempty"`
+	GrantSchema   json.RawMessage `json:"grant_schema,omitempty"`
+}
+
+func (a *apiBackend) ListRequestTypes(ctx context.Context) ([]storage.RequestType, error) {
+	var requestTypes []storage.RequestType
+	if err := a.doJSON(ctx, http.MethodGet, "/request-types", nil, &requestTypes); err != nil {
+		return nil, err
+	}
+	return requestTypes, nil
+}
+
+func (a *apiBackend) GetRequestType(ctx context.Context, name string) (storage.RequestType, error) {
+	var requestType storage.RequestType
+	if err := a.doJSON(ctx, http.MethodGet, "/request-types/"+url.PathEscape(name), nil, &requestType); err != nil {
+		return storage.RequestType{}, err
+	}
+	return requestType, nil
+}
+
+func (a *apiBackend) PutRequestType(ctx context.Context, requestType storage.RequestType) (storage.RequestType, error) {
+	payload := requestTypePayload{RequestSchema: requestType.RequestSchema, GrantSchema: requestType.GrantSchema}
+	var stored storage.RequestType
+	if err := a.doJSON(ctx, http.MethodPut, "/request-types/"+url.PathEscape(requestType.Name), payload, &stored); err != nil {
+		return storage.RequestType{}, err
+	}
+	return stored, nil
+}
+
+func (a *apiBackend) DeleteRequestType(ctx context.Context, name string) error {
+	return a.doJSON(ctx, http.MethodDelete, "/request-types/"+url.PathEscape(name), nil, nil)
+}
+
+func (a *apiBackend) ExportNamespace(ctx context.Context) (storage.Export, error) {
+	var doc storage.Export
+	if err := a.doJSON(ctx, http.MethodGet, "/export", nil, &doc); err != nil {
+		return storage.Export{}, err
+	}
+	return doc, nil
+}
+
+func (a *apiBackend) ImportNamespace(ctx context.Context, doc storage.Export, policy storage.ConflictPolicy) (storage.ImportReport, error) {
+	var report storage.ImportReport
+	endpoint := "/import?" + url.Values{"conflict": {string(policy)}}.Encode()
+	if err := a.doJSON(ctx, http.MethodPost, endpoint, doc, &report); err != nil {
+		return storage.ImportReport{}, err
+	}
+	return report, nil
+}
+
 func appendRequestListFilters(endpoint string, filters *storage.RequestListFilters) (string, error) {
 	if filters == nil {
 		return endpoint, nil
@@ -357,12 +609,24 @@ func appendRequestListFilters(endpoint string, filters *storage.RequestListFilte
 	if filters.HasGrant != nil {
 		params.Set("has_grant", strconv.FormatBool(*filters.HasGrant))
 	}
+	if filters.StaleGrant != nil {
+		params.Set("stale_grant", strconv.FormatBool(*filters.StaleGrant))
+	}
+	for _, status := range filters.Statuses {
+		params.Add("status", string(status))
+	}
 	for key, value := range filters.Labels {
 		params.Add("label", fmt.Sprintf("%s=%s", key, value))
 	}
 	for key, value := range filters.HostLabels {
 		params.Add("host_label", fmt.Sprintf("%s=%s", key, value))
 	}
+	if len(filters.Selector) > 0 {
+		params.Set("selector", filters.Selector.String())
+	}
+	for _, filter := range filters.Payload {
+		params.Add("payload", filter.String())
+	}
 	if encoded := params.Encode(); encoded != "" {
 		endpoint = endpoint + "?" + encoded
 	}
@@ -380,35 +644,141 @@ func appendRegisterListFilters(endpoint string, filters *storage.RegisterListFil
 	for key, value := range filters.HostLabels {
 		params.Add("host_label", fmt.Sprintf("%s=%s", key, value))
 	}
+	if len(filters.Selector) > 0 {
+		params.Set("selector", filters.Selector.String())
+	}
+	for _, filter := range filters.Payload {
+		params.Add("payload", filter.String())
+	}
+	if encoded := params.Encode(); encoded != "" {
+		endpoint = endpoint + "?" + encoded
+	}
+	return endpoint, nil
+}
+
+func appendGrantListFilters(endpoint string, filters *storage.GrantListFilters) (string, error) {
+	if filters == nil {
+		return endpoint, nil
+	}
+	params := url.Values{}
+	for key, value := range filters.Labels {
+		params.Add("label", fmt.Sprintf("%s=%s", key, value))
+	}
 	if encoded := params.Encode(); encoded != "" {
 		endpoint = endpoint + "?" + encoded
 	}
 	return endpoint, nil
 }
 
+// apiListPageSize is the number of rows requested per page when listing
+// through the API.
+const apiListPageSize = 500
+
+// listAllPages fetches a list endpoint page by page, following the cursor of
+// the next link until the last page. Servers without pagination answer the
+// first request with every row and no link.
+func listAllPages[T any](ctx context.Context, a *apiBackend, endpoint string) ([]T, error) {
+	separator := "?"
+	if strings.Contains(endpoint, "?") {
+		separator = "&"
+	}
+	items := make([]T, 0)
+	cursor := ""
+	for {
+		params := url.Values{"limit": {strconv.Itoa(apiListPageSize)}}
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+		var page []T
+		header, err := a.doJSONWithHeader(ctx, http.MethodGet, endpoint+separator+params.Encode(), nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		if cursor = nextPageCursor(header.Get("Link")); cursor == "" {
+			return items, nil
+		}
+	}
+}
+
+// nextPageCursor extracts the cursor from the rel="next" entry of a Link header.
+func nextPageCursor(link string) string {
+	for _, entry := range strings.Split(link, ",") {
+		target, params, found := strings.Cut(strings.TrimSpace(entry), ";")
+		if !found || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		next, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return ""
+		}
+		return next.Query().Get("cursor")
+	}
+	return ""
+}
+
 func (a *apiBackend) doJSON(ctx context.Context, method, endpoint string, body any, resp any) error {
+	_, err := a.doJSONWithHeader(ctx, method, endpoint, body, resp)
+	return err
+}
+
+func (a *apiBackend) doJSONWithHeader(ctx context.Context, method, endpoint string, body any, resp any) (http.Header, error) {
+	res, err := a.send(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		if cerr := res.Body.Close(); cerr != nil {
+			return nil, fmt.Errorf("read response: %w (close error: %v)", err, cerr)
+		}
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if err := res.Body.Close(); err != nil {
+		return nil, fmt.Errorf("close response body: %w", err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		return nil, fmt.Errorf("unexpected status %d: %s", res.StatusCode, msg)
+	}
+
+	if resp == nil || len(data) == 0 {
+		return res.Header, nil
+	}
+
+	if err := json.Unmarshal(data, resp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return res.Header, nil
+}
+
+// send performs an authenticated request against the API and returns the raw
+// response. The caller must close the response body.
+func (a *apiBackend) send(ctx context.Context, method, endpoint string, body any) (*http.Response, error) {
 	if a == nil {
-		return fmt.Errorf("api backend not configured")
+		return nil, fmt.Errorf("api backend not configured")
 	}
 
 	var payload io.Reader
 	if body != nil {
 		data, err := json.Marshal(body)
 		if err != nil {
-			return fmt.Errorf("marshal %s request: %w", endpoint, err)
+			return nil, fmt.Errorf("marshal %s request: %w", endpoint, err)
 		}
 		payload = bytes.NewReader(data)
 	}
 
 	rel, err := url.Parse(endpoint)
 	if err != nil {
-		return fmt.Errorf("parse endpoint %q: %w", endpoint, err)
+		return nil, fmt.Errorf("parse endpoint %q: %w", endpoint, err)
 	}
 	target := a.baseURL.ResolveReference(rel)
 
 	req, err := http.NewRequestWithContext(ctx, method, target.String(), payload)
 	if err != nil {
-		return fmt.Errorf("build request: %w", err)
+		return nil, fmt.Errorf("build request: %w", err)
 	}
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
@@ -416,6 +786,7 @@ func (a *apiBackend) doJSON(ctx context.Context, method, endpoint string, body a
 	if a.namespace != "" {
 		req.Header.Set("REMOTE_USER", a.namespace)
 	}
+	telemetry.InjectHeaders(ctx, req.Header)
 	if a.token != "" {
 		req.Header.Set("Authorization", "Bearer "+a.token)
 	} else if a.user != "" && a.password != "" {
@@ -424,31 +795,7 @@ func (a *apiBackend) doJSON(ctx context.Context, method, endpoint string, body a
 
 	res, err := a.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("perform request: %w", err)
-	}
-
-	data, err := io.ReadAll(res.Body)
-	if err != nil {
-		if cerr := res.Body.Close(); cerr != nil {
-			return fmt.Errorf("read response: %w (close error: %v)", err, cerr)
-		}
-		return fmt.Errorf("read response: %w", err)
-	}
-	if err := res.Body.Close(); err != nil {
-		return fmt.Errorf("close response body: %w", err)
-	}
-
-	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		msg := strings.TrimSpace(string(data))
-		return fmt.Errorf("unexpected status %d: %s", res.StatusCode, msg)
-	}
-
-	if resp == nil || len(data) == 0 {
-		return nil
-	}
-
-	if err := json.Unmarshal(data, resp); err != nil {
-		return fmt.Errorf("decode response: %w", err)
+		return nil, fmt.Errorf("perform request: %w", err)
 	}
-	return nil
+	return res, nil
 }
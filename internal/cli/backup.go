@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/server"
+)
+
+func newBackupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Write a consistent snapshot of a namespace database",
+		Long:  "Write a consistent snapshot of the selected namespace database to a file. The snapshot is taken with VACUUM INTO, so it is safe while the server is running. With --backend api the snapshot is downloaded from the server. Only SQLite storage can be backed up.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			out, err := cmd.Flags().GetString("out")
+			if err != nil {
+				return err
+			}
+			if out == "" {
+				return errors.New("--out is required")
+			}
+			if _, err := os.Stat(out); err == nil {
+				return fmt.Errorf("output file %s already exists", out)
+			}
+
+			namespace, err := resolveNamespace(cmd)
+			if err != nil {
+				return err
+			}
+
+			return runWithNamespaceAdmin(cmd, func(ctx context.Context, admin namespaceAdmin) error {
+				if err := admin.BackupNamespace(ctx, namespace, out); err != nil {
+					return err
+				}
+				size, err := server.NamespaceSize(out)
+				if err != nil {
+					return err
+				}
+				return outputJSON(map[string]any{
+					"namespace":  namespace,
+					"path":       out,
+					"size_bytes": size,
+				})
+			})
+		},
+	}
+	cmd.Flags().String("out", "", "path of the backup file to write; it must not exist")
+	return cmd
+}
+
+func newRestoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Replace a namespace database with a backup",
+		Long:  "Replace the selected namespace database with a backup written by 'grantory backup'. The backup is verified before the namespace is touched. Restore works on the data directory directly; stop the server first.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			in, err := cmd.Flags().GetString("in")
+			if err != nil {
+				return err
+			}
+			if in == "" {
+				return errors.New("--in is required")
+			}
+			force, err := cmd.Flags().GetBool("force")
+			if err != nil {
+				return err
+			}
+
+			namespace, err := resolveNamespace(cmd)
+			if err != nil {
+				return err
+			}
+			backendCfg, err := resolveBackendConfig(cmd)
+			if err != nil {
+				return err
+			}
+			if backendCfg.mode != backendModeDirect {
+				return errors.New("restore is only supported with the direct backend")
+			}
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			namespaces, err := server.OpenNamespaceStore(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := namespaces.Close(); err != nil {
+					if _, ferr := fmt.Fprintf(cmd.ErrOrStderr(), "close stores: %v\n", err); ferr != nil {
+						_ = ferr
+					}
+				}
+			}()
+
+			if err := namespaces.RestoreNamespace(cmd.Context(), namespace, in, force); err != nil {
+				if errors.Is(err, server.ErrNamespaceExists) {
+					return fmt.Errorf("%w: use --force to replace it", err)
+				}
+				return err
+			}
+			return outputJSON(map[string]any{
+				"namespace": namespace,
+				"source":    in,
+				"status":    "restored",
+			})
+		},
+	}
+	cmd.Flags().String("in", "", "path of the backup file to restore")
+	cmd.Flags().Bool("force", false, "replace the namespace if it already exists")
+	return cmd
+}
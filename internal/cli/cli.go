@@ -7,11 +7,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/user"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/tasansga/terraform-provider-grantory/internal/config"
+	"github.com/tasansga/terraform-provider-grantory/internal/selector"
 	"github.com/tasansga/terraform-provider-grantory/internal/server"
 	"github.com/tasansga/terraform-provider-grantory/internal/storage"
 )
@@ -45,31 +47,32 @@ func runWithBackend(cmd *cobra.Command, action func(context.Context, cliBackend)
 		return err
 	}
 
-	ctx := cmd.Context()
+	ctx, done, err := traceCommand(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	switch backendCfg.mode {
 	case backendModeDirect:
-		if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
-			return fmt.Errorf("create data directory: %w", err)
-		}
-		path := server.NamespaceDBPath(cfg.DataDir, namespace)
-		store, err := storage.New(ctx, path)
+		namespaces, err := server.OpenNamespaceStore(ctx, cfg)
 		if err != nil {
 			return err
 		}
-		store.SetNamespace(namespace)
 		defer func() {
-			if err := store.Close(); err != nil {
+			if err := namespaces.Close(); err != nil {
 				if _, ferr := fmt.Fprintf(cmd.ErrOrStderr(), "close store: %v\n", err); ferr != nil {
 					_ = ferr
 				}
 			}
 		}()
 
-		if err := store.Migrate(ctx); err != nil {
+		store, err := namespaces.StoreFor(ctx, namespace)
+		if err != nil {
 			return err
 		}
 
-		return action(ctx, newDirectBackend(store))
+		return action(storage.WithAuditActor(ctx, localAuditActor()), newDirectBackend(store))
 	case backendModeAPI:
 		backend, err := newAPIBackend(namespace, backendCfg.serverURL, backendCfg.token, backendCfg.user, backendCfg.password)
 		if err != nil {
@@ -81,8 +84,17 @@ func runWithBackend(cmd *cobra.Command, action func(context.Context, cliBackend)
 	}
 }
 
+// localAuditActor identifies the operating system user for audit events
+// recorded by commands that open the namespace database directly.
+func localAuditActor() string {
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return "cli:" + current.Username
+	}
+	return "cli"
+}
+
 func newListCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "list <resource_type>",
 		Short: "List hosts, requests, registers, or grants",
 		Args:  cobra.ExactArgs(1),
@@ -92,6 +104,34 @@ func newListCmd() *cobra.Command {
 				return err
 			}
 
+			rawSelector, err := cmd.Flags().GetString("selector")
+			if err != nil {
+				return err
+			}
+			sel, err := selector.Parse(rawSelector)
+			if err != nil {
+				return fmt.Errorf("invalid --selector: %w", err)
+			}
+			if len(sel) > 0 && resType != resourceTypeRequests && resType != resourceTypeRegisters {
+				return fmt.Errorf("--selector is only supported for requests and registers")
+			}
+
+			rawPayload, err := cmd.Flags().GetStringArray("payload")
+			if err != nil {
+				return err
+			}
+			payload := make([]storage.PayloadFilter, 0, len(rawPayload))
+			for _, raw := range rawPayload {
+				filter, err := storage.ParsePayloadFilter(raw)
+				if err != nil {
+					return fmt.Errorf("invalid --payload: %w", err)
+				}
+				payload = append(payload, filter)
+			}
+			if len(payload) > 0 && resType != resourceTypeRequests && resType != resourceTypeRegisters {
+				return fmt.Errorf("--payload is only supported for requests and registers")
+			}
+
 			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
 				switch resType {
 				case resourceTypeHosts:
@@ -101,19 +141,19 @@ func newListCmd() *cobra.Command {
 					}
 					return outputJSON(hosts)
 				case resourceTypeRequests:
-					requests, err := backend.ListRequests(ctx, nil)
+					requests, err := backend.ListRequests(ctx, &storage.RequestListFilters{Selector: sel, Payload: payload})
 					if err != nil {
 						return err
 					}
 					return outputJSON(requests)
 				case resourceTypeRegisters:
-					registers, err := backend.ListRegisters(ctx, nil)
+					registers, err := backend.ListRegisters(ctx, &storage.RegisterListFilters{Selector: sel, Payload: payload})
 					if err != nil {
 						return err
 					}
 					return outputJSON(registers)
 				case resourceTypeGrants:
-					grants, err := backend.ListGrants(ctx)
+					grants, err := backend.ListGrants(ctx, nil)
 					if err != nil {
 						return err
 					}
@@ -124,6 +164,9 @@ func newListCmd() *cobra.Command {
 			})
 		},
 	}
+	cmd.Flags().String("selector", "", "label selector for requests and registers, e.g. 'env in (prod,staging),!deprecated'")
+	cmd.Flags().StringArray("payload", nil, "payload filter <path>=<value> for requests and registers, e.g. spec.size=large (repeatable)")
+	return cmd
 }
 
 func newInspectCmd() *cobra.Command {
@@ -191,7 +234,7 @@ func newDeleteCmd() *cobra.Command {
 func newMutateCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "mutate <resource_type> <id>",
-		Short: "Mutate host, request, or register labels",
+		Short: "Mutate host, request, register, or grant labels",
 		Args:  cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			resType, err := parseResourceType(args[0])
@@ -234,6 +277,10 @@ func newMutateCmd() *cobra.Command {
 					if err := backend.UpdateRegisterLabels(ctx, id, labels); err != nil {
 						return err
 					}
+				case resourceTypeGrants:
+					if err := backend.UpdateGrantLabels(ctx, id, labels); err != nil {
+						return err
+					}
 				default:
 					return fmt.Errorf("mutate does not support resource type: %s", resType)
 				}
@@ -341,45 +388,6 @@ func outputJSON(value any) error {
 	return encoder.Encode(value)
 }
 
-func newNamespaceCmd() *cobra.Command {
-	cmd := &cobra.Command{
-		Use:   "namespace",
-		Short: "Manage namespace databases",
-	}
-	cmd.AddCommand(newNamespaceDeleteCmd())
-	return cmd
-}
-
-func newNamespaceDeleteCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "delete <namespace>",
-		Short: "Remove a namespace database",
-		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			namespace := args[0]
-			if err := server.ValidateNamespaceName(namespace); err != nil {
-				return err
-			}
-
-			cfg, err := loadConfig(cmd)
-			if err != nil {
-				return err
-			}
-
-			path := server.NamespaceDBPath(cfg.DataDir, namespace)
-			if err := removeNamespaceFiles(path); err != nil {
-				return err
-			}
-
-			return outputJSON(map[string]any{
-				"namespace": namespace,
-				"path":      path,
-				"status":    "deleted",
-			})
-		},
-	}
-}
-
 func resolveNamespace(cmd *cobra.Command) (string, error) {
 	flagSet := cmd.Root().PersistentFlags()
 	namespace, err := flagSet.GetString(FlagNamespace)
@@ -397,20 +405,3 @@ func resolveNamespace(cmd *cobra.Command) (string, error) {
 	}
 	return namespace, nil
 }
-
-func removeNamespaceFiles(path string) error {
-	suffixes := []string{"", "-wal", "-shm"}
-	for _, suffix := range suffixes {
-		name := path
-		if suffix != "" {
-			name = name + suffix
-		}
-		if err := os.Remove(name); err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				continue
-			}
-			return fmt.Errorf("remove %s: %w", name, err)
-		}
-	}
-	return nil
-}
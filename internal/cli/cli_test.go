@@ -1,18 +1,25 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/config"
 	"github.com/tasansga/terraform-provider-grantory/internal/server"
 	"github.com/tasansga/terraform-provider-grantory/internal/storage"
 )
@@ -71,6 +78,37 @@ func TestMutateHostLabelsCommand(t *testing.T) {
 	assert.Equal(t, "prod", host.Labels["env"], "host env labels after mutate via CLI")
 }
 
+func TestMutateGrantLabelsCommand(t *testing.T) {
+	t.Parallel()
+
+	var grantID string
+	dataDir := prepareTestDataDir(t, func(ctx context.Context, store *storage.Store) {
+		host, err := store.CreateHost(ctx, storage.Host{})
+		assert.NoError(t, err, "failed to create host for grant labels CLI test")
+		req, err := store.CreateRequest(ctx, storage.Request{HostID: host.ID})
+		assert.NoError(t, err, "failed to create request for grant labels CLI test")
+		grant, err := store.CreateGrant(ctx, storage.Grant{RequestID: req.ID, Labels: map[string]string{"pipeline": "manual"}})
+		assert.NoError(t, err, "failed to create grant for grant labels CLI test")
+		grantID = grant.ID
+	})
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{
+		"--data-dir", dataDir,
+		"mutate", "grants", grantID,
+		"--labels", `{"pipeline":"ci"}`,
+	})
+	err := cmd.Execute()
+	assert.NoError(t, err, "mutate grant labels command failed")
+
+	store := openStoreForTesting(t, dataDir)
+	defer closeStore(t, store)
+
+	grant, err := store.GetGrant(context.Background(), grantID)
+	assert.NoError(t, err, "GetGrant() error")
+	assert.Equal(t, map[string]string{"pipeline": "ci"}, grant.Labels, "grant labels after mutate via CLI")
+}
+
 func TestMutateHostLabelsFromFile(t *testing.T) {
 	t.Parallel()
 
@@ -452,22 +490,131 @@ func TestResolveLabelsHelpers(t *testing.T) {
 	assert.ErrorContains(t, err, "open labels file")
 }
 
-func TestRemoveNamespaceFiles(t *testing.T) {
+func TestNamespaceCommands(t *testing.T) {
 	t.Parallel()
 
-	dir := t.TempDir()
-	base := filepath.Join(dir, "demo.db")
-	for _, suffix := range []string{"", "-wal", "-shm"} {
-		assert.NoError(t, os.WriteFile(base+suffix, []byte("x"), 0o600))
+	dataDir := prepareTestDataDir(t, nil)
+
+	cmd := NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "namespace", "create", "team-a"})
+	assert.NoError(t, cmd.Execute(), "namespace create should succeed")
+	_, err := os.Stat(server.NamespaceDBPath(dataDir, "team-a"))
+	assert.NoError(t, err, "namespace database should exist")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "namespace", "create", "team-a"})
+	assert.ErrorIs(t, cmd.Execute(), server.ErrNamespaceExists, "duplicate namespaces are rejected")
+
+	for _, args := range [][]string{{"namespace", "list"}, {"namespace", "stats", "team-a"}} {
+		cmd = NewRootCommand()
+		cmd.SetOut(io.Discard)
+		cmd.SetArgs(append([]string{"--data-dir", dataDir}, args...))
+		assert.NoError(t, cmd.Execute(), "%v should succeed", args)
 	}
 
-	assert.NoError(t, removeNamespaceFiles(base))
-	for _, suffix := range []string{"", "-wal", "-shm"} {
-		_, err := os.Stat(base + suffix)
-		assert.ErrorIs(t, err, os.ErrNotExist)
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "namespace", "delete", "team-a"})
+	assert.NoError(t, cmd.Execute(), "namespace delete should succeed")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "namespace", "stats", "team-a"})
+	assert.ErrorIs(t, cmd.Execute(), server.ErrNamespaceNotFound, "deleted namespace should not be found")
+}
+
+func TestBackupRestoreCommands(t *testing.T) {
+	t.Parallel()
+
+	var hostID string
+	dataDir := prepareTestDataDir(t, func(ctx context.Context, store *storage.Store) {
+		host, err := store.CreateHost(ctx, storage.Host{})
+		assert.NoError(t, err, "CreateHost() error")
+		hostID = host.ID
+	})
+	out := filepath.Join(t.TempDir(), "backup.db")
+
+	cmd := NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "backup", "--out", out})
+	assert.NoError(t, cmd.Execute(), "backup should succeed")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "backup", "--out", out})
+	assert.Error(t, cmd.Execute(), "backup must not overwrite files")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "restore", "--in", out})
+	assert.ErrorIs(t, cmd.Execute(), server.ErrNamespaceExists, "restore needs --force for existing namespaces")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "--namespace", "restored", "restore", "--in", out})
+	assert.NoError(t, cmd.Execute(), "restore into a new namespace should succeed")
+
+	store, err := storage.New(context.Background(), server.NamespaceDBPath(dataDir, "restored"))
+	assert.NoError(t, err, "New() error")
+	_, err = store.GetHost(context.Background(), hostID)
+	closeStore(t, store)
+	assert.NoError(t, err, "restored namespace contains the host")
+}
+
+func TestExportImportCommands(t *testing.T) {
+	t.Parallel()
+
+	var hostID string
+	dataDir := prepareTestDataDir(t, func(ctx context.Context, store *storage.Store) {
+		host, err := store.CreateHost(ctx, storage.Host{Labels: map[string]string{"env": "prod"}})
+		assert.NoError(t, err, "CreateHost() error")
+		hostID = host.ID
+		_, err = store.CreateRequest(ctx, storage.Request{HostID: host.ID, Payload: map[string]any{"port": 5432}})
+		assert.NoError(t, err, "CreateRequest() error")
+	})
+
+	for _, name := range []string{"export.json", "export.yaml"} {
+		out := filepath.Join(t.TempDir(), name)
+		cmd := NewRootCommand()
+		cmd.SetOut(io.Discard)
+		cmd.SetArgs([]string{"--data-dir", dataDir, "export", "--out", out})
+		assert.NoError(t, cmd.Execute(), "export to %s should succeed", name)
+
+		namespace := "import-" + strings.TrimPrefix(filepath.Ext(name), ".")
+		cmd = NewRootCommand()
+		cmd.SetOut(io.Discard)
+		cmd.SetArgs([]string{"--data-dir", dataDir, "--namespace", namespace, "import", "--in", out})
+		assert.NoError(t, cmd.Execute(), "import of %s should succeed", name)
+
+		cmd = NewRootCommand()
+		cmd.SetOut(io.Discard)
+		cmd.SetArgs([]string{"--data-dir", dataDir, "--namespace", namespace, "import", "--in", out})
+		assert.ErrorIs(t, cmd.Execute(), storage.ErrImportConflict, "second import of %s should conflict", name)
+
+		cmd = NewRootCommand()
+		cmd.SetOut(io.Discard)
+		cmd.SetArgs([]string{"--data-dir", dataDir, "--namespace", namespace, "import", "--in", out, "--conflict", "skip"})
+		assert.NoError(t, cmd.Execute(), "import of %s with skip should succeed", name)
+
+		store, err := storage.New(context.Background(), server.NamespaceDBPath(dataDir, namespace))
+		assert.NoError(t, err, "New() error")
+		host, err := store.GetHost(context.Background(), hostID)
+		assert.NoError(t, err, "imported host should exist")
+		assert.Equal(t, "prod", host.Labels["env"])
+		requests, err := store.ListRequests(context.Background(), nil)
+		assert.NoError(t, err)
+		if assert.Len(t, requests, 1) {
+			assert.Equal(t, map[string]any{"port": float64(5432)}, requests[0].Payload)
+		}
+		closeStore(t, store)
 	}
 
-	assert.NoError(t, removeNamespaceFiles(filepath.Join(t.TempDir(), "missing.db")))
+	cmd := NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "export", "--format", "xml"})
+	assert.Error(t, cmd.Execute(), "unknown formats are rejected")
 }
 
 func TestResolveNamespaceInvalid(t *testing.T) {
@@ -588,7 +735,7 @@ func TestDirectBackendMethods(t *testing.T) {
 	if _, err := backend.ListRegisters(ctx, nil); err != nil {
 		t.Fatalf("list registers: %v", err)
 	}
-	if _, err := backend.ListGrants(ctx); err != nil {
+	if _, err := backend.ListGrants(ctx, nil); err != nil {
 		t.Fatalf("list grants: %v", err)
 	}
 	if _, err := backend.GetHost(ctx, host.ID); err != nil {
@@ -705,7 +852,7 @@ func TestAPIBackendMethods(t *testing.T) {
 	if _, err := backend.ListRegisters(ctx, nil); err != nil {
 		t.Fatalf("api list registers: %v", err)
 	}
-	if _, err := backend.ListGrants(ctx); err != nil {
+	if _, err := backend.ListGrants(ctx, nil); err != nil {
 		t.Fatalf("api list grants: %v", err)
 	}
 	if _, err := backend.GetHost(ctx, host.ID); err != nil {
@@ -737,6 +884,69 @@ func TestAPIBackendMethods(t *testing.T) {
 	}
 }
 
+func TestAPIBackendFollowsPages(t *testing.T) {
+	t.Parallel()
+
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		query := r.URL.Query()
+		page := []storage.Request{{ID: "req-1"}, {ID: "req-2"}}
+		if query.Get("cursor") == "" {
+			next := url.Values{"cursor": {"page-2"}, "has_grant": {"true"}, "limit": {query.Get("limit")}}
+			w.Header().Set("Link", fmt.Sprintf(`</requests?%s>; rel="next"`, next.Encode()))
+		} else {
+			page = []storage.Request{{ID: "req-3"}}
+		}
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			t.Errorf("encode requests: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	backend, err := newAPIBackend("", server.URL, "", "", "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	hasGrant := true
+	requests, err := backend.ListRequests(context.Background(), &storage.RequestListFilters{HasGrant: &hasGrant})
+	if !assert.NoError(t, err) {
+		return
+	}
+	ids := make([]string, 0, len(requests))
+	for _, req := range requests {
+		ids = append(ids, req.ID)
+	}
+	assert.Equal(t, []string{"req-1", "req-2", "req-3"}, ids)
+	assert.Equal(t, []string{"has_grant=true&limit=500", "has_grant=true&cursor=page-2&limit=500"}, queries, "every page keeps the filters")
+
+	assert.Empty(t, nextPageCursor(""))
+	assert.Equal(t, "abc", nextPageCursor(`</hosts?cursor=abc>; rel="next", </hosts>; rel="first"`))
+	assert.Empty(t, nextPageCursor(`</hosts?cursor=abc>; rel="prev"`))
+}
+
+func TestAPIBackendPropagatesTraceContext(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", r.Header.Get("traceparent"))
+		assert.NoError(t, json.NewEncoder(w).Encode([]storage.Host{}), "encode hosts")
+	}))
+	defer server.Close()
+
+	backend, err := newAPIBackend("", server.URL, "", "", "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	_, err = backend.ListHosts(ctx)
+	assert.NoError(t, err)
+}
+
 func TestListCommandsForAllResources(t *testing.T) {
 	t.Parallel()
 
@@ -772,6 +982,51 @@ func TestListCommandsForAllResources(t *testing.T) {
 	}
 }
 
+func TestListCommandFilters(t *testing.T) {
+	t.Parallel()
+
+	var selectors []string
+	var payloads [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		selectors = append(selectors, r.URL.Query().Get("selector"))
+		payloads = append(payloads, r.URL.Query()["payload"])
+		if _, err := w.Write([]byte("[]")); err != nil {
+			t.Errorf("write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	for _, resource := range []string{"requests", "registers"} {
+		cmd := NewRootCommand()
+		cmd.SetOut(io.Discard)
+		cmd.SetArgs([]string{"--backend", "api", "--server-url", server.URL, "list", resource, "--selector", "env in (prod, staging), !deprecated", "--payload", "spec.size=large", "--payload", "$.name=db"})
+		assert.NoError(t, cmd.Execute(), "listing %s with a selector should succeed", resource)
+	}
+	assert.Equal(t, []string{"env in (prod,staging),!deprecated", "env in (prod,staging),!deprecated"}, selectors, "the selector is sent in canonical form")
+	assert.Equal(t, [][]string{{"$.spec.size=large", "$.name=db"}, {"$.spec.size=large", "$.name=db"}}, payloads, "payload filters are sent as JSONPath")
+
+	cmd := NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--backend", "api", "--server-url", server.URL, "list", "hosts", "--selector", "env=prod"})
+	assert.ErrorContains(t, cmd.Execute(), "only supported for requests and registers")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--backend", "api", "--server-url", server.URL, "list", "requests", "--selector", "env in (prod"})
+	assert.ErrorContains(t, cmd.Execute(), "invalid --selector")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--backend", "api", "--server-url", server.URL, "list", "requests", "--payload", "spec.size"})
+	assert.ErrorContains(t, cmd.Execute(), "invalid --payload")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--backend", "api", "--server-url", server.URL, "list", "grants", "--payload", "a=b"})
+	assert.ErrorContains(t, cmd.Execute(), "--payload is only supported")
+	assert.Len(t, selectors, 2, "invalid selectors are rejected before any request")
+}
+
 func TestDeleteCommandsForAllResources(t *testing.T) {
 	t.Parallel()
 
@@ -803,6 +1058,304 @@ func TestDeleteCommandsForAllResources(t *testing.T) {
 	}
 }
 
+func TestTokenCommands(t *testing.T) {
+	t.Parallel()
+
+	dataDir := filepath.Join(t.TempDir(), "data")
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"--data-dir", dataDir, "token", "create", "--name", "ci", "--scope", "team-a=write", "--scope", "*=read"})
+	assert.NoError(t, cmd.Execute(), "token create command failed")
+
+	cmd = NewRootCommand()
+	cmd.SetArgs([]string{"--data-dir", dataDir, "token", "create", "--name", "broken"})
+	assert.Error(t, cmd.Execute(), "token create without scopes should fail")
+
+	ctx := context.Background()
+	tokens, err := server.OpenTokenStore(ctx, config.Config{DataDir: dataDir})
+	if err != nil {
+		assert.NoError(t, err, "OpenTokenStore() error")
+		t.FailNow()
+	}
+	list, err := tokens.ListTokens(ctx)
+	assert.NoError(t, tokens.Close(), "close token store")
+	if !assert.NoError(t, err, "ListTokens() error") || !assert.Len(t, list, 1, "one token should exist") {
+		return
+	}
+	assert.Equal(t, "ci", list[0].Name)
+	assert.Equal(t, map[string]storage.TokenScope{"team-a": storage.TokenScopeWrite, "*": storage.TokenScopeRead}, list[0].Scopes)
+
+	cmd = NewRootCommand()
+	cmd.SetArgs([]string{"--data-dir", dataDir, "token", "list"})
+	assert.NoError(t, cmd.Execute(), "token list command failed")
+
+	cmd = NewRootCommand()
+	cmd.SetArgs([]string{"--data-dir", dataDir, "token", "revoke", list[0].ID})
+	assert.NoError(t, cmd.Execute(), "token revoke command failed")
+
+	cmd = NewRootCommand()
+	cmd.SetArgs([]string{"--data-dir", dataDir, "token", "revoke", "missing"})
+	assert.ErrorIs(t, cmd.Execute(), storage.ErrTokenNotFound, "revoking unknown token should fail")
+
+	tokens, err = server.OpenTokenStore(ctx, config.Config{DataDir: dataDir})
+	if err != nil {
+		assert.NoError(t, err, "OpenTokenStore() error")
+		t.FailNow()
+	}
+	defer func() {
+		if err := tokens.Close(); err != nil {
+			t.Errorf("close token store: %v", err)
+		}
+	}()
+	list, err = tokens.ListTokens(ctx)
+	assert.NoError(t, err, "ListTokens() error")
+	assert.True(t, list[0].Revoked(), "token should be revoked")
+}
+
+func TestParseTokenScopesErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, value := range []string{"team-a", "=read", "team-a=admin", "bad ns=read"} {
+		_, err := parseTokenScopes([]string{value})
+		assert.Error(t, err, "scope %q should be rejected", value)
+	}
+}
+
+func TestMigrateCommands(t *testing.T) {
+	t.Parallel()
+
+	dataDir := prepareTestDataDir(t, nil)
+
+	ctx := context.Background()
+	legacy, err := storage.New(ctx, server.NamespaceDBPath(dataDir, "legacy-ns"))
+	if err != nil {
+		assert.NoError(t, err, "New() error")
+		t.FailNow()
+	}
+	closeStore(t, legacy)
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"--data-dir", dataDir, "migrate", "status"})
+	assert.NoError(t, cmd.Execute(), "migrate status command failed")
+
+	cmd = NewRootCommand()
+	cmd.SetArgs([]string{"--data-dir", dataDir, "--namespace", "missing-ns", "migrate", "status"})
+	assert.Error(t, cmd.Execute(), "status for a missing namespace should fail")
+
+	cmd = NewRootCommand()
+	cmd.SetArgs([]string{"--data-dir", dataDir, "migrate", "up"})
+	assert.NoError(t, cmd.Execute(), "migrate up command failed")
+
+	store, err := storage.New(ctx, server.NamespaceDBPath(dataDir, "legacy-ns"))
+	if err != nil {
+		assert.NoError(t, err, "New() error")
+		t.FailNow()
+	}
+	defer closeStore(t, store)
+	status, err := store.MigrationStatus(ctx)
+	assert.NoError(t, err, "MigrationStatus() error")
+	assert.Equal(t, storage.SchemaVersion(), status.CurrentVersion, "migrate up should apply every migration")
+	assert.Zero(t, status.Pending)
+}
+
+func TestWebhookCommands(t *testing.T) {
+	t.Parallel()
+
+	dataDir := prepareTestDataDir(t, nil)
+
+	cmd := NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{
+		"--data-dir", dataDir, "webhook", "create",
+		"--url", "https://hooks.example.com",
+		"--resource-type", "Requests",
+		"--label-selector", `{"type":"db_user"}`,
+	})
+	assert.NoError(t, cmd.Execute(), "webhook create should succeed")
+
+	store := openStoreForTesting(t, dataDir)
+	webhooks, err := store.ListWebhooks(context.Background())
+	closeStore(t, store)
+	if !assert.NoError(t, err, "ListWebhooks() error") || !assert.Len(t, webhooks, 1, "one webhook should exist") {
+		return
+	}
+	webhookID := webhooks[0].ID
+	assert.Equal(t, []string{storage.EventResourceRequests}, webhooks[0].ResourceTypes)
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "webhook", "update", webhookID, "--label-selector", "{}", "--include-payload"})
+	assert.NoError(t, cmd.Execute(), "webhook update should succeed")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "webhook", "deliveries", webhookID, "--status", "failed"})
+	assert.NoError(t, cmd.Execute(), "webhook deliveries should succeed")
+
+	store = openStoreForTesting(t, dataDir)
+	webhook, err := store.GetWebhook(context.Background(), webhookID)
+	closeStore(t, store)
+	assert.NoError(t, err, "GetWebhook() error")
+	assert.Equal(t, "https://hooks.example.com", webhook.URL, "update keeps unchanged fields")
+	assert.Equal(t, []string{storage.EventResourceRequests}, webhook.ResourceTypes, "update keeps unchanged fields")
+	assert.Empty(t, webhook.LabelSelector, "update clears the label selector")
+	assert.True(t, webhook.IncludePayload, "update opts in to payloads")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "webhook", "delete", webhookID})
+	assert.NoError(t, cmd.Execute(), "webhook delete should succeed")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "webhook", "inspect", webhookID})
+	assert.ErrorIs(t, cmd.Execute(), storage.ErrWebhookNotFound, "deleted webhook should not be found")
+}
+
+func TestRequestTypeCommands(t *testing.T) {
+	t.Parallel()
+
+	dataDir := prepareTestDataDir(t, nil)
+	grantSchemaFile := filepath.Join(t.TempDir(), "grant.json")
+	assert.NoError(t, os.WriteFile(grantSchemaFile, []byte(`{"type":"object","required":["password"]}`), 0o600))
+
+	cmd := NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetIn(strings.NewReader(`{"type":"object","required":["name"]}`))
+	cmd.SetArgs([]string{
+		"--data-dir", dataDir, "request-type", "put", "database",
+		"--request-schema-file", "-",
+		"--grant-schema-file", grantSchemaFile,
+	})
+	assert.NoError(t, cmd.Execute(), "request-type put should succeed")
+
+	store := openStoreForTesting(t, dataDir)
+	requestType, err := store.GetRequestType(context.Background(), "database")
+	closeStore(t, store)
+	assert.NoError(t, err, "GetRequestType() error")
+	assert.JSONEq(t, `{"type":"object","required":["name"]}`, string(requestType.RequestSchema))
+	assert.JSONEq(t, `{"type":"object","required":["password"]}`, string(requestType.GrantSchema))
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "request-type", "put", "database"})
+	assert.Error(t, cmd.Execute(), "put without schemas should fail")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetIn(strings.NewReader(`{"type":1}`))
+	cmd.SetArgs([]string{"--data-dir", dataDir, "request-type", "put", "database", "--request-schema-file", "-"})
+	assert.ErrorIs(t, cmd.Execute(), storage.ErrInvalidRequestType, "invalid schemas are rejected")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "request-type", "list"})
+	assert.NoError(t, cmd.Execute(), "request-type list should succeed")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "request-type", "delete", "database"})
+	assert.NoError(t, cmd.Execute(), "request-type delete should succeed")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "request-type", "inspect", "database"})
+	assert.ErrorIs(t, cmd.Execute(), storage.ErrRequestTypeNotFound, "deleted request type should not be found")
+}
+
+func TestHostsPruneCommand(t *testing.T) {
+	t.Parallel()
+
+	var staleID, freshID, silentID, requestID string
+	dataDir := prepareTestDataDir(t, func(ctx context.Context, store *storage.Store) {
+		stale, err := store.CreateHost(ctx, storage.Host{})
+		assert.NoError(t, err, "prepare hosts for prune test")
+		_, err = store.RecordHostHeartbeat(ctx, stale.ID, time.Now().Add(-8*24*time.Hour))
+		assert.NoError(t, err, "prepare hosts for prune test")
+		req, err := store.CreateRequest(ctx, storage.Request{HostID: stale.ID})
+		assert.NoError(t, err, "prepare hosts for prune test")
+		fresh, err := store.CreateHost(ctx, storage.Host{})
+		assert.NoError(t, err, "prepare hosts for prune test")
+		_, err = store.RecordHostHeartbeat(ctx, fresh.ID, time.Now())
+		assert.NoError(t, err, "prepare hosts for prune test")
+		silent, err := store.CreateHost(ctx, storage.Host{})
+		assert.NoError(t, err, "prepare hosts for prune test")
+		staleID, freshID, silentID, requestID = stale.ID, fresh.ID, silent.ID, req.ID
+	})
+
+	cmd := NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "hosts", "prune", "--stale-after", "7d", "--dry-run"})
+	assert.NoError(t, cmd.Execute(), "hosts prune --dry-run should succeed")
+
+	store := openStoreForTesting(t, dataDir)
+	_, err := store.GetHost(context.Background(), staleID)
+	closeStore(t, store)
+	assert.NoError(t, err, "a dry run keeps stale hosts")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "hosts", "prune", "--stale-after", "7d"})
+	assert.NoError(t, cmd.Execute(), "hosts prune should succeed")
+
+	store = openStoreForTesting(t, dataDir)
+	defer closeStore(t, store)
+	ctx := context.Background()
+	_, err = store.GetHost(ctx, staleID)
+	assert.ErrorIs(t, err, storage.ErrHostNotFound, "stale hosts are pruned")
+	_, err = store.GetRequest(ctx, requestID)
+	assert.ErrorIs(t, err, storage.ErrRequestNotFound, "requests of pruned hosts are removed")
+	_, err = store.GetHost(ctx, freshID)
+	assert.NoError(t, err, "fresh hosts are kept")
+	_, err = store.GetHost(ctx, silentID)
+	assert.NoError(t, err, "hosts without heartbeats are kept")
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "hosts", "prune"})
+	assert.Error(t, cmd.Execute(), "hosts prune requires --stale-after")
+}
+
+func TestAuditCommand(t *testing.T) {
+	t.Parallel()
+
+	var hostID string
+	dataDir := prepareTestDataDir(t, func(ctx context.Context, store *storage.Store) {
+		host, err := store.CreateHost(ctx, storage.Host{Labels: map[string]string{"env": "prod"}})
+		assert.NoError(t, err, "prepare host for audit test")
+		hostID = host.ID
+	})
+
+	cmd := NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "mutate", "hosts", hostID, "--labels", `{"env":"dev"}`})
+	assert.NoError(t, cmd.Execute(), "mutate host should succeed")
+
+	for _, output := range []string{"json", "table"} {
+		cmd = NewRootCommand()
+		cmd.SetOut(io.Discard)
+		cmd.SetArgs([]string{"--data-dir", dataDir, "audit", "--resource-type", "hosts", "--output", output})
+		assert.NoError(t, cmd.Execute(), "audit --output %s should succeed", output)
+	}
+
+	cmd = NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--data-dir", dataDir, "audit", "--output", "yaml"})
+	assert.Error(t, cmd.Execute(), "unknown output formats should fail")
+
+	store := openStoreForTesting(t, dataDir)
+	events, err := store.ListAuditEvents(context.Background(), storage.AuditListFilters{ResourceID: hostID})
+	closeStore(t, store)
+	if !assert.NoError(t, err, "ListAuditEvents() error") || !assert.Len(t, events, 2, "create and mutate are audited") {
+		return
+	}
+	assert.True(t, strings.HasPrefix(events[0].Actor, "cli"), "direct mode records the local user, got %q", events[0].Actor)
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeAuditTable(&buf, events), "writeAuditTable() error")
+	assert.Contains(t, buf.String(), "env=prod→dev", "the table summarizes label changes")
+}
+
 func prepareTestDataDir(t *testing.T, setup func(context.Context, *storage.Store)) string {
 	t.Helper()
 
@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+const (
+	exportFormatJSON = "json"
+	exportFormatYAML = "yaml"
+)
+
+func newExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the hosts, requests, registers and grants of a namespace",
+		Long:  "Export the hosts, requests, registers and grants of the selected namespace, with labels and timestamps, as a versioned JSON or YAML document. The format follows the --out file extension unless --format is given.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			out, err := cmd.Flags().GetString("out")
+			if err != nil {
+				return err
+			}
+			format, err := exportFormat(cmd, out)
+			if err != nil {
+				return err
+			}
+			namespace, err := resolveNamespace(cmd)
+			if err != nil {
+				return err
+			}
+
+			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
+				doc, err := backend.ExportNamespace(ctx)
+				if err != nil {
+					return err
+				}
+				doc.Namespace = namespace
+				data, err := encodeExport(doc, format)
+				if err != nil {
+					return err
+				}
+				if out == "" || out == "-" {
+					_, err = cmd.OutOrStdout().Write(data)
+					return err
+				}
+				if err := os.WriteFile(out, data, 0o600); err != nil {
+					return fmt.Errorf("write export: %w", err)
+				}
+				return nil
+			})
+		},
+	}
+	cmd.Flags().String("out", "", "file to write the export to (default stdout)")
+	cmd.Flags().String("format", "", "document format, json or yaml")
+	return cmd
+}
+
+func newImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import an exported namespace document",
+		Long:  "Import a document written by 'grantory export' into the selected namespace. Record IDs and timestamps are preserved. --conflict decides what happens to records that already exist: skip keeps them, overwrite replaces them and fail (the default) aborts the import without changes.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			in, err := cmd.Flags().GetString("in")
+			if err != nil {
+				return err
+			}
+			if in == "" {
+				return errors.New("--in is required")
+			}
+			format, err := exportFormat(cmd, in)
+			if err != nil {
+				return err
+			}
+			rawPolicy, err := cmd.Flags().GetString("conflict")
+			if err != nil {
+				return err
+			}
+			policy, err := storage.ParseConflictPolicy(rawPolicy)
+			if err != nil {
+				return err
+			}
+
+			var data []byte
+			if in == "-" {
+				data, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				data, err = os.ReadFile(in)
+			}
+			if err != nil {
+				return fmt.Errorf("read import: %w", err)
+			}
+			doc, err := decodeExport(data, format)
+			if err != nil {
+				return err
+			}
+
+			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
+				report, err := backend.ImportNamespace(ctx, doc, policy)
+				if err != nil {
+					return err
+				}
+				return outputJSON(report)
+			})
+		},
+	}
+	cmd.Flags().String("in", "", "file to import, or - for stdin")
+	cmd.Flags().String("format", "", "document format, json or yaml")
+	cmd.Flags().String("conflict", string(storage.ConflictFail), "how to handle existing records: skip, overwrite or fail")
+	return cmd
+}
+
+// exportFormat returns the --format flag or, without it, the format implied
+// by the file extension. JSON is the default.
+func exportFormat(cmd *cobra.Command, path string) (string, error) {
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return "", err
+	}
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			return exportFormatYAML, nil
+		}
+		return exportFormatJSON, nil
+	}
+	switch format = strings.ToLower(format); format {
+	case exportFormatJSON, exportFormatYAML:
+		return format, nil
+	}
+	return "", fmt.Errorf("unsupported format %q: must be %s or %s", format, exportFormatJSON, exportFormatYAML)
+}
+
+// encodeExport renders the document. YAML output is derived from the JSON
+// encoding so both formats use the same field names.
+func encodeExport(doc storage.Export, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode export: %w", err)
+	}
+	if format == exportFormatJSON {
+		return append(data, '\n'), nil
+	}
+
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("encode export: %w", err)
+	}
+	data, err = yaml.Marshal(generic)
+	if err != nil {
+		return nil, fmt.Errorf("encode export as yaml: %w", err)
+	}
+	return data, nil
+}
+
+func decodeExport(data []byte, format string) (storage.Export, error) {
+	if format == exportFormatYAML {
+		var generic any
+		if err := yaml.Unmarshal(data, &generic); err != nil {
+			return storage.Export{}, fmt.Errorf("decode yaml import: %w", err)
+		}
+		converted, err := json.Marshal(generic)
+		if err != nil {
+			return storage.Export{}, fmt.Errorf("decode yaml import: %w", err)
+		}
+		data = converted
+	}
+
+	var doc storage.Export
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return storage.Export{}, fmt.Errorf("decode import: %w", err)
+	}
+	return doc, nil
+}
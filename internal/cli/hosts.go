@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/config"
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func newHostsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hosts",
+		Short: "Manage host heartbeats and stale hosts",
+	}
+	cmd.AddCommand(
+		newHostsHeartbeatCmd(),
+		newHostsPruneCmd(),
+	)
+	return cmd
+}
+
+func newHostsHeartbeatCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "heartbeat <id>",
+		Short: "Record a heartbeat for a host",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
+				host, err := backend.HeartbeatHost(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				return outputJSON(host)
+			})
+		},
+	}
+}
+
+func newHostsPruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete hosts without a recent heartbeat",
+		Long:  "Delete hosts whose last heartbeat is older than --stale-after, together with their requests, grants and registers. Hosts that never sent a heartbeat are kept.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			rawStaleAfter, err := cmd.Flags().GetString("stale-after")
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(rawStaleAfter) == "" {
+				return errors.New("--stale-after is required")
+			}
+			staleAfter, err := config.ParseDuration(rawStaleAfter)
+			if err != nil || staleAfter <= 0 {
+				return fmt.Errorf("invalid --stale-after %q: must be a positive duration such as 12h or 7d", rawStaleAfter)
+			}
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			if err != nil {
+				return err
+			}
+
+			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
+				hosts, err := backend.ListStaleHosts(ctx, staleAfter)
+				if err != nil {
+					return err
+				}
+				pruned := make([]storage.Host, 0, len(hosts))
+				for _, host := range hosts {
+					if !dryRun {
+						if err := backend.DeleteHost(ctx, host.ID); err != nil {
+							return fmt.Errorf("delete host %s: %w", host.ID, err)
+						}
+					}
+					pruned = append(pruned, host)
+				}
+				return outputJSON(map[string]any{"dry_run": dryRun, "hosts": pruned})
+			})
+		},
+	}
+	cmd.Flags().String("stale-after", "", "delete hosts whose last heartbeat is older than this duration, such as 12h or 7d")
+	cmd.Flags().Bool("dry-run", false, "list the stale hosts without deleting them")
+	return cmd
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/server"
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+type namespaceMigrationStatus struct {
+	Namespace string `json:"namespace"`
+	Path      string `json:"path"`
+	storage.MigrationStatus
+}
+
+func newMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Inspect or apply namespace database schema migrations",
+		Long:  "Inspect or apply schema migrations for namespace databases in the data directory. Without --namespace, every existing namespace database is processed.",
+	}
+	cmd.AddCommand(newMigrateStatusCmd(), newMigrateUpCmd())
+	return cmd
+}
+
+func newMigrateStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show applied and pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runMigrations(cmd, false)
+		},
+	}
+}
+
+func newMigrateUpCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "up",
+		Short: "Apply all pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runMigrations(cmd, true)
+		},
+	}
+}
+
+func runMigrations(cmd *cobra.Command, apply bool) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	ctx := cmd.Context()
+	databases, err := server.OpenNamespaceStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := databases.Close(); err != nil {
+			if _, ferr := fmt.Fprintf(cmd.ErrOrStderr(), "close stores: %v\n", err); ferr != nil {
+				_ = ferr
+			}
+		}
+	}()
+
+	namespaces, err := migrationNamespaces(cmd, databases, apply)
+	if err != nil {
+		return err
+	}
+
+	results := make([]namespaceMigrationStatus, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		status, err := namespaceMigration(ctx, databases, namespace, apply)
+		if err != nil {
+			return fmt.Errorf("namespace %s: %w", namespace, err)
+		}
+		results = append(results, namespaceMigrationStatus{
+			Namespace:       namespace,
+			Path:            databases.NamespaceLocation(namespace),
+			MigrationStatus: status,
+		})
+	}
+	return outputJSON(results)
+}
+
+// migrationNamespaces returns the explicitly selected namespace, or every
+// namespace that has a database.
+func migrationNamespaces(cmd *cobra.Command, databases *server.NamespaceStore, apply bool) ([]string, error) {
+	if cmd.Root().PersistentFlags().Changed(FlagNamespace) || os.Getenv(EnvNamespace) != "" {
+		namespace, err := resolveNamespace(cmd)
+		if err != nil {
+			return nil, err
+		}
+		if !apply {
+			exists, err := databases.NamespaceExists(cmd.Context(), namespace)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				return nil, fmt.Errorf("namespace %s has no database at %s", namespace, databases.NamespaceLocation(namespace))
+			}
+		}
+		return []string{namespace}, nil
+	}
+	return databases.NamespaceNames(cmd.Context())
+}
+
+func namespaceMigration(ctx context.Context, databases *server.NamespaceStore, namespace string, apply bool) (status storage.MigrationStatus, err error) {
+	store, err := databases.OpenDatabase(ctx, namespace)
+	if err != nil {
+		return storage.MigrationStatus{}, err
+	}
+	defer func() {
+		if cerr := store.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("close store: %w", cerr)
+		}
+	}()
+
+	if apply {
+		if err := store.Migrate(ctx); err != nil {
+			return storage.MigrationStatus{}, err
+		}
+	}
+	return store.MigrationStatus(ctx)
+}
@@ -0,0 +1,259 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/server"
+)
+
+// namespaceAdmin manages namespace databases, either in the local data
+// directory or through the /namespaces API of a server.
+type namespaceAdmin interface {
+	ListNamespaces(context.Context) ([]server.NamespaceInfo, error)
+	CreateNamespace(context.Context, string) (server.NamespaceInfo, error)
+	NamespaceStats(context.Context, string) (server.NamespaceStats, error)
+	DeleteNamespace(context.Context, string) error
+	BackupNamespace(context.Context, string, string) error
+}
+
+func runWithNamespaceAdmin(cmd *cobra.Command, action func(context.Context, namespaceAdmin) error) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	backendCfg, err := resolveBackendConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	ctx, done, err := traceCommand(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	switch backendCfg.mode {
+	case backendModeDirect:
+		namespaces, err := server.OpenNamespaceStore(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := namespaces.Close(); err != nil {
+				if _, ferr := fmt.Fprintf(cmd.ErrOrStderr(), "close stores: %v\n", err); ferr != nil {
+					_ = ferr
+				}
+			}
+		}()
+		return action(ctx, directNamespaceAdmin{namespaces: namespaces})
+	case backendModeAPI:
+		backend, err := newAPIBackend("", backendCfg.serverURL, backendCfg.token, backendCfg.user, backendCfg.password)
+		if err != nil {
+			return err
+		}
+		admin, ok := backend.(namespaceAdmin)
+		if !ok {
+			return fmt.Errorf("backend %q cannot manage namespaces", backendCfg.mode)
+		}
+		return action(ctx, admin)
+	default:
+		return fmt.Errorf("unsupported backend %q", backendCfg.mode)
+	}
+}
+
+type directNamespaceAdmin struct {
+	namespaces *server.NamespaceStore
+}
+
+func (d directNamespaceAdmin) ListNamespaces(ctx context.Context) ([]server.NamespaceInfo, error) {
+	return d.namespaces.ListNamespaces(ctx)
+}
+
+func (d directNamespaceAdmin) CreateNamespace(ctx context.Context, name string) (server.NamespaceInfo, error) {
+	return d.namespaces.CreateNamespace(ctx, name)
+}
+
+func (d directNamespaceAdmin) NamespaceStats(ctx context.Context, name string) (server.NamespaceStats, error) {
+	return d.namespaces.NamespaceStats(ctx, name)
+}
+
+func (d directNamespaceAdmin) DeleteNamespace(ctx context.Context, name string) error {
+	return d.namespaces.DeleteNamespace(ctx, name)
+}
+
+func (d directNamespaceAdmin) BackupNamespace(ctx context.Context, name, out string) error {
+	return d.namespaces.BackupNamespace(ctx, name, out)
+}
+
+type namespaceCreatePayload struct {
+	Name string `json:"name"`
+}
+
+func (a *apiBackend) ListNamespaces(ctx context.Context) ([]server.NamespaceInfo, error) {
+	var namespaces []server.NamespaceInfo
+	if err := a.doJSON(ctx, http.MethodGet, "/namespaces", nil, &namespaces); err != nil {
+		return nil, err
+	}
+	return namespaces, nil
+}
+
+func (a *apiBackend) CreateNamespace(ctx context.Context, name string) (server.NamespaceInfo, error) {
+	var created server.NamespaceInfo
+	if err := a.doJSON(ctx, http.MethodPost, "/namespaces", namespaceCreatePayload{Name: name}, &created); err != nil {
+		return server.NamespaceInfo{}, err
+	}
+	return created, nil
+}
+
+func (a *apiBackend) NamespaceStats(ctx context.Context, name string) (server.NamespaceStats, error) {
+	var stats server.NamespaceStats
+	if err := a.doJSON(ctx, http.MethodGet, "/namespaces/"+url.PathEscape(name), nil, &stats); err != nil {
+		return server.NamespaceStats{}, err
+	}
+	return stats, nil
+}
+
+func (a *apiBackend) DeleteNamespace(ctx context.Context, name string) error {
+	return a.doJSON(ctx, http.MethodDelete, "/namespaces/"+url.PathEscape(name), nil, nil)
+}
+
+// BackupNamespace downloads a snapshot of the namespace into out, which must
+// not exist yet.
+func (a *apiBackend) BackupNamespace(ctx context.Context, name, out string) error {
+	res, err := a.send(ctx, http.MethodGet, "/namespaces/"+url.PathEscape(name)+"/backup", nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		data, err := io.ReadAll(res.Body)
+		if err != nil {
+			return fmt.Errorf("read response: %w", err)
+		}
+		return fmt.Errorf("unexpected status %d: %s", res.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	file, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("create backup file: %w", err)
+	}
+	if _, err := io.Copy(file, res.Body); err != nil {
+		if cerr := file.Close(); cerr != nil {
+			err = fmt.Errorf("%w (close error: %v)", err, cerr)
+		}
+		if rerr := os.Remove(out); rerr != nil {
+			err = fmt.Errorf("%w (remove error: %v)", err, rerr)
+		}
+		return fmt.Errorf("download backup: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("close backup file: %w", err)
+	}
+	return nil
+}
+
+func newNamespaceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "namespace",
+		Short: "Manage namespace databases",
+		Long:  "Manage namespace databases. With the direct backend the data directory is used; with the API backend the server's /namespaces endpoints are called, which require a token with access to every namespace (*).",
+	}
+	cmd.AddCommand(
+		newNamespaceListCmd(),
+		newNamespaceCreateCmd(),
+		newNamespaceStatsCmd(),
+		newNamespaceDeleteCmd(),
+	)
+	return cmd
+}
+
+func newNamespaceListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List namespaces and their database sizes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runWithNamespaceAdmin(cmd, func(ctx context.Context, admin namespaceAdmin) error {
+				namespaces, err := admin.ListNamespaces(ctx)
+				if err != nil {
+					return err
+				}
+				return outputJSON(namespaces)
+			})
+		},
+	}
+}
+
+func newNamespaceCreateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "create <namespace>",
+		Short: "Create an empty namespace database",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := server.ValidateNamespaceName(args[0]); err != nil {
+				return err
+			}
+			return runWithNamespaceAdmin(cmd, func(ctx context.Context, admin namespaceAdmin) error {
+				created, err := admin.CreateNamespace(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				return outputJSON(created)
+			})
+		},
+	}
+}
+
+func newNamespaceStatsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats <namespace>",
+		Short: "Show the size and record counts of a namespace",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := server.ValidateNamespaceName(args[0]); err != nil {
+				return err
+			}
+			return runWithNamespaceAdmin(cmd, func(ctx context.Context, admin namespaceAdmin) error {
+				stats, err := admin.NamespaceStats(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				return outputJSON(stats)
+			})
+		},
+	}
+}
+
+func newNamespaceDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <namespace>",
+		Short: "Remove a namespace database",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace := args[0]
+			if err := server.ValidateNamespaceName(namespace); err != nil {
+				return err
+			}
+			return runWithNamespaceAdmin(cmd, func(ctx context.Context, admin namespaceAdmin) error {
+				if err := admin.DeleteNamespace(ctx, namespace); err != nil {
+					return err
+				}
+				return outputJSON(map[string]any{
+					"namespace": namespace,
+					"status":    "deleted",
+				})
+			})
+		},
+	}
+}
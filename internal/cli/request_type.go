@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func newRequestTypeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "request-type",
+		Short: "Manage request types",
+		Long:  "Manage request types of the selected namespace. A request type holds JSON Schemas that the payloads of requests labelled type=<name>, and of their grants, must satisfy.",
+	}
+	cmd.AddCommand(
+		newRequestTypeListCmd(),
+		newRequestTypeInspectCmd(),
+		newRequestTypePutCmd(),
+		newRequestTypeDeleteCmd(),
+	)
+	return cmd
+}
+
+func newRequestTypeListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List request types",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
+				requestTypes, err := backend.ListRequestTypes(ctx)
+				if err != nil {
+					return err
+				}
+				return outputJSON(requestTypes)
+			})
+		},
+	}
+}
+
+func newRequestTypeInspectCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "inspect <name>",
+		Short: "Show a single request type",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
+				requestType, err := backend.GetRequestType(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				return outputJSON(requestType)
+			})
+		},
+	}
+}
+
+func newRequestTypePutCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "put <name>",
+		Short: "Create or replace a request type",
+		Long:  "Create or replace a request type. Both schemas are replaced; a schema that is not given is removed. Existing requests and grants are not revalidated.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			requestSchemaFile, err := cmd.Flags().GetString("request-schema-file")
+			if err != nil {
+				return err
+			}
+			grantSchemaFile, err := cmd.Flags().GetString("grant-schema-file")
+			if err != nil {
+				return err
+			}
+			if requestSchemaFile == "" && grantSchemaFile == "" {
+				return errors.New("--request-schema-file or --grant-schema-file is required")
+			}
+			if requestSchemaFile == "-" && grantSchemaFile == "-" {
+				return errors.New("only one schema can be read from stdin")
+			}
+
+			requestType := storage.RequestType{Name: args[0]}
+			if requestType.RequestSchema, err = loadSchemaFromSource(cmd, requestSchemaFile); err != nil {
+				return err
+			}
+			if requestType.GrantSchema, err = loadSchemaFromSource(cmd, grantSchemaFile); err != nil {
+				return err
+			}
+
+			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
+				stored, err := backend.PutRequestType(ctx, requestType)
+				if err != nil {
+					return err
+				}
+				return outputJSON(stored)
+			})
+		},
+	}
+	cmd.Flags().String("request-schema-file", "", "path to the JSON Schema for request payloads, or - for stdin")
+	cmd.Flags().String("grant-schema-file", "", "path to the JSON Schema for grant payloads, or - for stdin")
+	return cmd
+}
+
+func newRequestTypeDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a request type",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
+				if err := backend.DeleteRequestType(ctx, args[0]); err != nil {
+					return err
+				}
+				return outputJSON(map[string]string{"name": args[0], "resource": "request_types", "status": "deleted"})
+			})
+		},
+	}
+}
+
+// loadSchemaFromSource reads a JSON document from a file or, for "-", from
+// stdin. An empty source yields no schema.
+func loadSchemaFromSource(cmd *cobra.Command, source string) (json.RawMessage, error) {
+	if source == "" {
+		return nil, nil
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if source == "-" {
+		data, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read schema %s: %w", source, err)
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("schema %s is not valid JSON", source)
+	}
+	return json.RawMessage(data), nil
+}
@@ -45,6 +45,16 @@ func NewRootCommand() *cobra.Command {
 		newInspectCmd(),
 		newDeleteCmd(),
 		newMutateCmd(),
+		newTokenCmd(),
+		newMigrateCmd(),
+		newBackupCmd(),
+		newRestoreCmd(),
+		newExportCmd(),
+		newImportCmd(),
+		newWebhookCmd(),
+		newRequestTypeCmd(),
+		newHostsCmd(),
+		newAuditCmd(),
 	)
 
 	return root
@@ -65,17 +75,34 @@ func runServer(cmd *cobra.Command, _ []string) error {
 	}
 
 	configureLogging(cfg)
+	flushTraces, err := setupTracing(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	defer flushTraces()
+
 	tlsStatus := "disabled"
 	if server.IsTLSEnabled(cfg) {
 		tlsStatus = "enabled"
 	}
+	// The storage URL may carry credentials, so only the backend is logged.
+	storageBackend := "sqlite"
+	if cfg.StorageURL != "" {
+		storageBackend = "postgresql"
+	}
 	logrus.WithFields(logrus.Fields{
 		"data_dir": absDataDir,
+		"storage":  storageBackend,
 		"http_bind":  cfg.BindAddr,
 		"https_bind": cfg.TLSBind,
 		"tls_cert": cfg.TLSCert,
 		"tls_key":  cfg.TLSKey,
 		"tls":      tlsStatus,
+		"auth_mode": cfg.AuthMode,
+		"reap_interval": cfg.ReapInterval.String(),
+		"host_stale_after": cfg.HostStaleAfter.String(),
+		"backup_dir": cfg.BackupDir,
+		"otlp_endpoint": cfg.OTLPEndpoint,
 		"version":  versionString(),
 	}).Info("starting Grantory server")
 
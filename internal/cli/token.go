@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/server"
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+type createdToken struct {
+	storage.Token
+	Secret string `json:"token"`
+}
+
+func newTokenCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage API tokens",
+		Long:  "Manage the API tokens accepted by the server in token auth mode. Tokens are stored in the data directory, so these commands always operate on local files.",
+	}
+	cmd.AddCommand(newTokenCreateCmd(), newTokenListCmd(), newTokenRevokeCmd())
+	return cmd
+}
+
+func newTokenCreateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an API token bound to namespaces",
+		Long:  "Create an API token. The secret is printed once and only its hash is stored.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			name, err := cmd.Flags().GetString("name")
+			if err != nil {
+				return err
+			}
+			rawScopes, err := cmd.Flags().GetStringArray("scope")
+			if err != nil {
+				return err
+			}
+			scopes, err := parseTokenScopes(rawScopes)
+			if err != nil {
+				return err
+			}
+
+			return runWithTokenStore(cmd, func(ctx context.Context, tokens *storage.TokenStore) error {
+				secret, err := server.GenerateToken()
+				if err != nil {
+					return err
+				}
+				token, err := tokens.CreateToken(ctx, storage.Token{Name: name, Scopes: scopes}, server.HashToken(secret))
+				if err != nil {
+					return err
+				}
+				return outputJSON(createdToken{Token: token, Secret: secret})
+			})
+		},
+	}
+
+	cmd.Flags().String("name", "", "human-readable token name")
+	cmd.Flags().StringArray("scope", nil, "namespace scope as <namespace>=<read|write>; repeatable, use * for all namespaces")
+
+	return cmd
+}
+
+func newTokenListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List API tokens",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runWithTokenStore(cmd, func(ctx context.Context, tokens *storage.TokenStore) error {
+				list, err := tokens.ListTokens(ctx)
+				if err != nil {
+					return err
+				}
+				return outputJSON(list)
+			})
+		},
+	}
+}
+
+func newTokenRevokeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke <id>",
+		Short: "Revoke an API token",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithTokenStore(cmd, func(ctx context.Context, tokens *storage.TokenStore) error {
+				token, err := tokens.RevokeToken(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				return outputJSON(token)
+			})
+		},
+	}
+}
+
+func runWithTokenStore(cmd *cobra.Command, action func(context.Context, *storage.TokenStore) error) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	tokens, err := server.OpenTokenStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tokens.Close(); err != nil {
+			if _, ferr := fmt.Fprintf(cmd.ErrOrStderr(), "close token store: %v\n", err); ferr != nil {
+				_ = ferr
+			}
+		}
+	}()
+
+	return action(ctx, tokens)
+}
+
+func parseTokenScopes(values []string) (map[string]storage.TokenScope, error) {
+	if len(values) == 0 {
+		return nil, errors.New("at least one --scope is required")
+	}
+
+	scopes := make(map[string]storage.TokenScope, len(values))
+	for _, value := range values {
+		namespace, rawScope, ok := strings.Cut(value, "=")
+		namespace = strings.TrimSpace(namespace)
+		if !ok || namespace == "" {
+			return nil, fmt.Errorf("invalid scope %q: expected <namespace>=<read|write>", value)
+		}
+		if namespace != storage.TokenNamespaceWildcard {
+			if err := server.ValidateNamespaceName(namespace); err != nil {
+				return nil, err
+			}
+		}
+		scope, err := storage.ParseTokenScope(rawScope)
+		if err != nil {
+			return nil, err
+		}
+		scopes[namespace] = scope
+	}
+	return scopes, nil
+}
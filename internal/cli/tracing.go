@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/config"
+	"github.com/tasansga/terraform-provider-grantory/internal/telemetry"
+)
+
+const (
+	tracingServiceName     = "grantory"
+	tracingShutdownTimeout = 5 * time.Second
+)
+
+// setupTracing configures trace export from cfg. The returned func flushes
+// pending spans and is meant to be deferred.
+func setupTracing(cmd *cobra.Command, cfg config.Config) (func(), error) {
+	shutdown, err := telemetry.Setup(cmd.Context(), telemetry.Options{
+		Endpoint:    cfg.OTLPEndpoint,
+		SampleRatio: cfg.TraceSampleRatio,
+		ServiceName: tracingServiceName,
+		Version:     versionString(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			if _, ferr := fmt.Fprintf(cmd.ErrOrStderr(), "flush traces: %v\n", err); ferr != nil {
+				_ = ferr
+			}
+		}
+	}, nil
+}
+
+// traceCommand sets up tracing and opens a span for the command, so that the
+// API requests it sends share one trace. The returned func ends the span and
+// flushes it.
+func traceCommand(cmd *cobra.Command, cfg config.Config) (context.Context, func(), error) {
+	flush, err := setupTracing(cmd, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, span := telemetry.Tracer().Start(cmd.Context(), cmd.CommandPath())
+	return ctx, func() {
+		span.End()
+		flush()
+	}, nil
+}
@@ -0,0 +1,226 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func newWebhookCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "Manage webhook subscriptions",
+		Long:  "Manage webhooks that receive signed change feed events of the selected namespace. Deliveries are sent by a running server.",
+	}
+	cmd.AddCommand(
+		newWebhookListCmd(),
+		newWebhookInspectCmd(),
+		newWebhookCreateCmd(),
+		newWebhookUpdateCmd(),
+		newWebhookDeleteCmd(),
+		newWebhookDeliveriesCmd(),
+	)
+	return cmd
+}
+
+func newWebhookListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List webhooks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
+				webhooks, err := backend.ListWebhooks(ctx)
+				if err != nil {
+					return err
+				}
+				return outputJSON(webhooks)
+			})
+		},
+	}
+}
+
+func newWebhookInspectCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "inspect <id>",
+		Short: "Show a single webhook",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
+				webhook, err := backend.GetWebhook(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				return outputJSON(webhook)
+			})
+		},
+	}
+}
+
+func newWebhookCreateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a webhook",
+		Long:  "Create a webhook. When --secret is omitted a secret is generated; it is printed once and cannot be read back.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			webhook := storage.Webhook{}
+			if err := applyWebhookFlags(cmd, &webhook); err != nil {
+				return err
+			}
+			if strings.TrimSpace(webhook.URL) == "" {
+				return errors.New("--url is required")
+			}
+
+			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
+				created, err := backend.CreateWebhook(ctx, webhook)
+				if err != nil {
+					return err
+				}
+				return outputJSON(created)
+			})
+		},
+	}
+	addWebhookFlags(cmd)
+	return cmd
+}
+
+func newWebhookUpdateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update <id>",
+		Short: "Update a webhook",
+		Long:  "Update a webhook. Only the given flags are changed; pass an empty value to clear resource types or the label selector.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
+				webhook, err := backend.GetWebhook(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				if err := applyWebhookFlags(cmd, &webhook); err != nil {
+					return err
+				}
+				updated, err := backend.UpdateWebhook(ctx, webhook)
+				if err != nil {
+					return err
+				}
+				return outputJSON(updated)
+			})
+		},
+	}
+	addWebhookFlags(cmd)
+	return cmd
+}
+
+func newWebhookDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <id>",
+		Short: "Delete a webhook and its delivery log",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
+				if err := backend.DeleteWebhook(ctx, args[0]); err != nil {
+					return err
+				}
+				return outputJSON(map[string]string{"id": args[0], "resource": "webhooks", "status": "deleted"})
+			})
+		},
+	}
+}
+
+func newWebhookDeliveriesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deliveries <id>",
+		Short: "Show the delivery log of a webhook, newest first",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rawStatuses, err := cmd.Flags().GetStringSlice("status")
+			if err != nil {
+				return err
+			}
+			limit, err := cmd.Flags().GetInt("limit")
+			if err != nil {
+				return err
+			}
+			filters := storage.WebhookDeliveryListFilters{WebhookID: args[0], Limit: limit}
+			for _, raw := range rawStatuses {
+				status, err := storage.ParseWebhookDeliveryStatus(raw)
+				if err != nil {
+					return err
+				}
+				filters.Statuses = append(filters.Statuses, status)
+			}
+
+			return runWithBackend(cmd, func(ctx context.Context, backend cliBackend) error {
+				deliveries, err := backend.ListWebhookDeliveries(ctx, filters)
+				if err != nil {
+					return err
+				}
+				return outputJSON(deliveries)
+			})
+		},
+	}
+	cmd.Flags().StringSlice("status", nil, "only show deliveries with these statuses (pending|succeeded|failed)")
+	cmd.Flags().Int("limit", storage.DefaultWebhookDeliveryListLimit, "maximum number of deliveries to show")
+	return cmd
+}
+
+func addWebhookFlags(cmd *cobra.Command) {
+	cmd.Flags().String("url", "", "http(s) endpoint that receives deliveries")
+	cmd.Flags().String("secret", "", "HMAC secret used to sign deliveries")
+	cmd.Flags().StringSlice("resource-type", nil, "only deliver events for these resource types (hosts|requests|registers|grants)")
+	cmd.Flags().String("label-selector", "", "JSON object of labels an event must carry to be delivered")
+	cmd.Flags().Bool("include-payload", false, "include request, register and grant payloads in deliveries")
+}
+
+// applyWebhookFlags copies the flags that were set on the command into webhook.
+func applyWebhookFlags(cmd *cobra.Command, webhook *storage.Webhook) error {
+	flags := cmd.Flags()
+	if flags.Changed("url") {
+		value, err := flags.GetString("url")
+		if err != nil {
+			return err
+		}
+		webhook.URL = value
+	}
+	if flags.Changed("secret") {
+		value, err := flags.GetString("secret")
+		if err != nil {
+			return err
+		}
+		webhook.Secret = value
+	}
+	if flags.Changed("resource-type") {
+		values, err := flags.GetStringSlice("resource-type")
+		if err != nil {
+			return err
+		}
+		webhook.ResourceTypes = nil
+		for _, value := range values {
+			if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+				webhook.ResourceTypes = append(webhook.ResourceTypes, value)
+			}
+		}
+	}
+	if flags.Changed("label-selector") {
+		value, err := flags.GetString("label-selector")
+		if err != nil {
+			return err
+		}
+		if webhook.LabelSelector, err = parseLabels(value); err != nil {
+			return err
+		}
+	}
+	if flags.Changed("include-payload") {
+		value, err := flags.GetBool("include-payload")
+		if err != nil {
+			return err
+		}
+		webhook.IncludePayload = value
+	}
+	return nil
+}
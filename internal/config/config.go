@@ -3,51 +3,114 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 )
 
 const (
-	EnvDataDir  = "DATA_DIR"
-	EnvBindAddr = "HTTP_BIND"
-	EnvTLSBind  = "HTTPS_BIND"
-	EnvTLSCert  = "TLS_CERT"
-	EnvTLSKey   = "TLS_KEY"
-	EnvLogLevel = "LOG_LEVEL"
+	EnvDataDir          = "DATA_DIR"
+	EnvStorageURL       = "STORAGE_URL"
+	EnvBindAddr         = "HTTP_BIND"
+	EnvTLSBind          = "HTTPS_BIND"
+	EnvTLSCert          = "TLS_CERT"
+	EnvTLSKey           = "TLS_KEY"
+	EnvLogLevel         = "LOG_LEVEL"
+	EnvAuthMode         = "AUTH_MODE"
+	EnvReapInterval     = "REAP_INTERVAL"
+	EnvHostStaleAfter   = "HOST_STALE_AFTER"
+	EnvStrictNamespaces = "STRICT_NAMESPACES"
+	EnvBackupDir        = "BACKUP_DIR"
+	EnvBackupInterval   = "BACKUP_INTERVAL"
+	EnvBackupKeep       = "BACKUP_KEEP"
+	EnvOTLPEndpoint     = "OTLP_ENDPOINT"
+	EnvTraceSampleRatio = "TRACE_SAMPLE_RATIO"
 )
 
 const (
-	DefaultDataDir  = "data"
-	DefaultBindAddr = "0.0.0.0:8080"
-	DefaultTLSBind  = "0.0.0.0:8443"
+	DefaultDataDir          = "data"
+	DefaultBindAddr         = "0.0.0.0:8080"
+	DefaultTLSBind          = "0.0.0.0:8443"
+	DefaultAuthMode         = AuthModeToken
+	DefaultReapInterval     = time.Minute
+	DefaultHostStaleAfter   = 24 * time.Hour
+	DefaultBackupInterval   = 24 * time.Hour
+	DefaultBackupKeep       = 7
+	DefaultTraceSampleRatio = 1.0
+)
+
+const (
+	// AuthModeToken authenticates API calls with tokens issued by `grantory token create`.
+	AuthModeToken = "token"
+	// AuthModeProxy trusts the REMOTE_USER header set by an authenticating reverse proxy.
+	AuthModeProxy = "proxy"
 )
 
 const DefaultLogLevel = logrus.InfoLevel
 
 // Config holds the runtime configuration for the Grantory server.
 type Config struct {
-	DataDir  string
-	BindAddr string
-	TLSBind  string
-	TLSCert  string
-	TLSKey   string
-	LogLevel logrus.Level
+	DataDir string
+	// StorageURL selects a PostgreSQL database (postgres://...) that holds all
+	// namespaces and tokens; empty keeps them in SQLite files in DataDir.
+	StorageURL string
+	BindAddr   string
+	TLSBind    string
+	TLSCert    string
+	TLSKey     string
+	LogLevel   logrus.Level
+	AuthMode   string
+	// ReapInterval is how often expired records are removed; zero disables the reaper.
+	ReapInterval time.Duration
+	// HostStaleAfter is how long a host may go without a heartbeat before the
+	// index page marks it stale; zero disables the marker.
+	HostStaleAfter time.Duration
+	// StrictNamespaces rejects requests for namespaces without a database
+	// instead of creating one; namespaces are then created explicitly.
+	StrictNamespaces bool
+	// BackupDir receives scheduled namespace snapshots; empty disables them.
+	BackupDir string
+	// BackupInterval is how often scheduled snapshots are taken.
+	BackupInterval time.Duration
+	// BackupKeep is how many snapshots are kept per namespace; zero keeps all.
+	BackupKeep int
+	// OTLPEndpoint is the OTLP/HTTP collector URL traces are exported to;
+	// empty disables tracing.
+	OTLPEndpoint string
+	// TraceSampleRatio is the fraction of new traces that are recorded.
+	TraceSampleRatio float64
 }
 
 // RegisterFlags adds command-line flags to the provided FlagSet.
 func RegisterFlags(fs *pflag.FlagSet) {
 	fs.String("data-dir", "", "path to the directory that contains namespace databases (env: "+EnvDataDir+")")
+	fs.String("storage-url", "", "PostgreSQL URL (postgres://...) for storage shared by several servers (env: "+EnvStorageURL+"); namespaces are SQLite files in the data dir when empty")
 	fs.String("http-bind", "", "interface:port for the HTTP listener (env: "+EnvBindAddr+"); set to 'off' to disable")
 	fs.String("https-bind", "", "interface:port for the HTTPS listener when TLS is enabled (env: "+EnvTLSBind+"); set to 'off' to disable")
 	fs.String("tls-cert", "", "path to the TLS certificate file (env: "+EnvTLSCert+")")
 	fs.String("tls-key", "", "path to the TLS private key file (env: "+EnvTLSKey+")")
 	fs.String("log-level", "", "log level for the server (env: "+EnvLogLevel+")")
+	fs.String("auth-mode", "", "API authentication mode, token or proxy (env: "+EnvAuthMode+")")
+	fs.String("reap-interval", "", "how often expired requests, registers and grants are removed (env: "+EnvReapInterval+"); set to 'off' to disable")
+	fs.String("host-stale-after", "", "how long a host may go without a heartbeat before it is shown as stale (env: "+EnvHostStaleAfter+"); set to 'off' to disable")
+	fs.String("strict-namespaces", "", "reject requests for namespaces that were not created explicitly, true or false (env: "+EnvStrictNamespaces+")")
+	fs.String("backup-dir", "", "directory for scheduled namespace snapshots (env: "+EnvBackupDir+"); scheduled backups are disabled when empty")
+	fs.String("backup-interval", "", "how often scheduled snapshots are taken (env: "+EnvBackupInterval+")")
+	fs.String("backup-keep", "", "how many snapshots to keep per namespace, 0 keeps all (env: "+EnvBackupKeep+")")
+	fs.String("otlp-endpoint", "", "OTLP/HTTP collector URL for traces, e.g. http://localhost:4318 (env: "+EnvOTLPEndpoint+"); tracing is disabled when empty")
+	fs.String("trace-sample-ratio", "", "fraction of new traces to record, between 0 and 1 (env: "+EnvTraceSampleRatio+")")
 }
 
 // FromFlagSet builds a Config from the flag set and environment variables.
 func FromFlagSet(fs *pflag.FlagSet) (Config, error) {
 	dataDir := stringValue(fs, "data-dir", EnvDataDir, DefaultDataDir)
+	storageURL := stringValue(fs, "storage-url", EnvStorageURL, "")
+	if storageURL != "" && !strings.HasPrefix(storageURL, "postgres://") && !strings.HasPrefix(storageURL, "postgresql://") {
+		return Config{}, fmt.Errorf("invalid storage url: must start with postgres:// or postgresql://")
+	}
 	bind := stringValue(fs, "http-bind", EnvBindAddr, DefaultBindAddr)
 	tlsBind := stringValue(fs, "https-bind", EnvTLSBind, DefaultTLSBind)
 	tlsCert := stringValue(fs, "tls-cert", EnvTLSCert, "")
@@ -59,16 +122,98 @@ func FromFlagSet(fs *pflag.FlagSet) (Config, error) {
 		return Config{}, fmt.Errorf("invalid log level %q: %w", levelStr, err)
 	}
 
+	authMode := strings.ToLower(stringValue(fs, "auth-mode", EnvAuthMode, DefaultAuthMode))
+	if authMode != AuthModeToken && authMode != AuthModeProxy {
+		return Config{}, fmt.Errorf("invalid auth mode %q: must be %s or %s", authMode, AuthModeToken, AuthModeProxy)
+	}
+
+	reapInterval := DefaultReapInterval
+	if raw := stringValue(fs, "reap-interval", EnvReapInterval, ""); raw != "" {
+		if strings.EqualFold(raw, "off") {
+			reapInterval = 0
+		} else if reapInterval, err = ParseDuration(raw); err != nil || reapInterval <= 0 {
+			return Config{}, fmt.Errorf("invalid reap interval %q: must be a positive duration or off", raw)
+		}
+	}
+
+	staleAfter := DefaultHostStaleAfter
+	if raw := stringValue(fs, "host-stale-after", EnvHostStaleAfter, ""); raw != "" {
+		if strings.EqualFold(raw, "off") {
+			staleAfter = 0
+		} else if staleAfter, err = ParseDuration(raw); err != nil || staleAfter <= 0 {
+			return Config{}, fmt.Errorf("invalid host stale after %q: must be a positive duration or off", raw)
+		}
+	}
+
+	strictNamespaces := false
+	if raw := stringValue(fs, "strict-namespaces", EnvStrictNamespaces, ""); raw != "" {
+		if strictNamespaces, err = strconv.ParseBool(raw); err != nil {
+			return Config{}, fmt.Errorf("invalid strict namespaces %q: must be true or false", raw)
+		}
+	}
+
+	backupDir := stringValue(fs, "backup-dir", EnvBackupDir, "")
+	if backupDir != "" && storageURL != "" {
+		return Config{}, fmt.Errorf("invalid backup dir %q: scheduled backups require SQLite storage", backupDir)
+	}
+
+	backupInterval := DefaultBackupInterval
+	if raw := stringValue(fs, "backup-interval", EnvBackupInterval, ""); raw != "" {
+		if backupInterval, err = ParseDuration(raw); err != nil || backupInterval <= 0 {
+			return Config{}, fmt.Errorf("invalid backup interval %q: must be a positive duration", raw)
+		}
+	}
+
+	backupKeep := DefaultBackupKeep
+	if raw := stringValue(fs, "backup-keep", EnvBackupKeep, ""); raw != "" {
+		if backupKeep, err = strconv.Atoi(raw); err != nil || backupKeep < 0 {
+			return Config{}, fmt.Errorf("invalid backup keep %q: must be a non-negative number", raw)
+		}
+	}
+
+	otlpEndpoint := stringValue(fs, "otlp-endpoint", EnvOTLPEndpoint, "")
+
+	sampleRatio := DefaultTraceSampleRatio
+	if raw := stringValue(fs, "trace-sample-ratio", EnvTraceSampleRatio, ""); raw != "" {
+		if sampleRatio, err = strconv.ParseFloat(raw, 64); err != nil || sampleRatio < 0 || sampleRatio > 1 {
+			return Config{}, fmt.Errorf("invalid trace sample ratio %q: must be a number between 0 and 1", raw)
+		}
+	}
+
 	return Config{
-		DataDir:  dataDir,
-		BindAddr: bind,
-		TLSBind:  tlsBind,
-		TLSCert:  tlsCert,
-		TLSKey:   tlsKey,
-		LogLevel: level,
+		DataDir:          dataDir,
+		StorageURL:       storageURL,
+		BindAddr:         bind,
+		TLSBind:          tlsBind,
+		TLSCert:          tlsCert,
+		TLSKey:           tlsKey,
+		LogLevel:         level,
+		AuthMode:         authMode,
+		ReapInterval:     reapInterval,
+		HostStaleAfter:   staleAfter,
+		StrictNamespaces: strictNamespaces,
+		BackupDir:        backupDir,
+		BackupInterval:   backupInterval,
+		BackupKeep:       backupKeep,
+		OTLPEndpoint:     otlpEndpoint,
+		TraceSampleRatio: sampleRatio,
 	}, nil
 }
 
+// ParseDuration parses a Go duration string and additionally accepts a whole
+// number of days such as "7d".
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
 func stringValue(fs *pflag.FlagSet, name, envKey, defaultValue string) string {
 	if fs != nil {
 		if fs.Changed(name) {
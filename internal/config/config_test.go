@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
@@ -21,6 +22,9 @@ func TestFromFlagSetDefaults(t *testing.T) {
 	assert.Equal(t, "", cfg.TLSCert, "default tls cert")
 	assert.Equal(t, "", cfg.TLSKey, "default tls key")
 	assert.Equal(t, DefaultLogLevel, cfg.LogLevel, "default log level")
+	assert.Equal(t, AuthModeToken, cfg.AuthMode, "default auth mode")
+	assert.Equal(t, DefaultReapInterval, cfg.ReapInterval, "default reap interval")
+	assert.Equal(t, DefaultHostStaleAfter, cfg.HostStaleAfter, "default host stale after")
 }
 
 func TestFromFlagSetEnvOverrides(t *testing.T) {
@@ -30,6 +34,7 @@ func TestFromFlagSetEnvOverrides(t *testing.T) {
 	t.Setenv(EnvTLSCert, "/tmp/cert.pem")
 	t.Setenv(EnvTLSKey, "/tmp/key.pem")
 	t.Setenv(EnvLogLevel, "debug")
+	t.Setenv(EnvAuthMode, "proxy")
 
 	fs := newTestFlagSet(t)
 	assert.NoError(t, fs.Parse([]string{}), "unable to parse empty args")
@@ -43,6 +48,7 @@ func TestFromFlagSetEnvOverrides(t *testing.T) {
 	assert.Equal(t, "/tmp/cert.pem", cfg.TLSCert, "tls cert from env")
 	assert.Equal(t, "/tmp/key.pem", cfg.TLSKey, "tls key from env")
 	assert.Equal(t, logLevelOrDefault("debug"), cfg.LogLevel, "log level from env")
+	assert.Equal(t, AuthModeProxy, cfg.AuthMode, "auth mode from env")
 }
 
 func TestFromFlagSetFlagOverridesEnv(t *testing.T) {
@@ -80,6 +86,136 @@ func TestFromFlagSetInvalidLogLevel(t *testing.T) {
 	assert.Error(t, err, "expected an error for invalid log level")
 }
 
+func TestFromFlagSetInvalidAuthMode(t *testing.T) {
+	fs := newTestFlagSet(t)
+	assert.NoError(t, fs.Parse([]string{"--auth-mode=none"}), "unable to parse args")
+
+	_, err := FromFlagSet(fs)
+	assert.Error(t, err, "expected an error for invalid auth mode")
+}
+
+func TestFromFlagSetReapInterval(t *testing.T) {
+	fs := newTestFlagSet(t)
+	assert.NoError(t, fs.Parse([]string{"--reap-interval=off"}), "unable to parse args")
+	cfg, err := FromFlagSet(fs)
+	assert.NoError(t, err, "unexpected error from FromFlagSet")
+	assert.Zero(t, cfg.ReapInterval, "off disables the reaper")
+
+	t.Setenv(EnvReapInterval, "1d")
+	cfg, err = FromFlagSet(newTestFlagSet(t))
+	assert.NoError(t, err, "unexpected error from FromFlagSet")
+	assert.Equal(t, 24*time.Hour, cfg.ReapInterval, "reap interval from env")
+
+	fs = newTestFlagSet(t)
+	assert.NoError(t, fs.Parse([]string{"--reap-interval=0s"}), "unable to parse args")
+	_, err = FromFlagSet(fs)
+	assert.Error(t, err, "expected an error for a zero reap interval")
+}
+
+func TestFromFlagSetHostStaleAfter(t *testing.T) {
+	fs := newTestFlagSet(t)
+	assert.NoError(t, fs.Parse([]string{"--host-stale-after=7d"}), "unable to parse args")
+	cfg, err := FromFlagSet(fs)
+	assert.NoError(t, err, "unexpected error from FromFlagSet")
+	assert.Equal(t, 7*24*time.Hour, cfg.HostStaleAfter, "host stale after from flag")
+
+	t.Setenv(EnvHostStaleAfter, "off")
+	cfg, err = FromFlagSet(newTestFlagSet(t))
+	assert.NoError(t, err, "unexpected error from FromFlagSet")
+	assert.Zero(t, cfg.HostStaleAfter, "off disables the stale marker")
+
+	t.Setenv(EnvHostStaleAfter, "soon")
+	_, err = FromFlagSet(newTestFlagSet(t))
+	assert.Error(t, err, "expected an error for an invalid host stale after")
+}
+
+func TestFromFlagSetStrictNamespaces(t *testing.T) {
+	cfg, err := FromFlagSet(newTestFlagSet(t))
+	assert.NoError(t, err, "unexpected error from FromFlagSet")
+	assert.False(t, cfg.StrictNamespaces, "namespaces are created implicitly by default")
+
+	fs := newTestFlagSet(t)
+	assert.NoError(t, fs.Parse([]string{"--strict-namespaces=true"}), "unable to parse args")
+	cfg, err = FromFlagSet(fs)
+	assert.NoError(t, err, "unexpected error from FromFlagSet")
+	assert.True(t, cfg.StrictNamespaces, "strict namespaces from flag")
+
+	t.Setenv(EnvStrictNamespaces, "maybe")
+	_, err = FromFlagSet(newTestFlagSet(t))
+	assert.Error(t, err, "expected an error for an invalid boolean")
+}
+
+func TestFromFlagSetBackup(t *testing.T) {
+	cfg, err := FromFlagSet(newTestFlagSet(t))
+	assert.NoError(t, err, "unexpected error from FromFlagSet")
+	assert.Empty(t, cfg.BackupDir, "scheduled backups are disabled by default")
+	assert.Equal(t, DefaultBackupInterval, cfg.BackupInterval)
+	assert.Equal(t, DefaultBackupKeep, cfg.BackupKeep)
+
+	fs := newTestFlagSet(t)
+	assert.NoError(t, fs.Parse([]string{"--backup-dir=/backups", "--backup-interval=6h", "--backup-keep=0"}), "unable to parse args")
+	cfg, err = FromFlagSet(fs)
+	assert.NoError(t, err, "unexpected error from FromFlagSet")
+	assert.Equal(t, "/backups", cfg.BackupDir)
+	assert.Equal(t, 6*time.Hour, cfg.BackupInterval)
+	assert.Zero(t, cfg.BackupKeep)
+
+	t.Setenv(EnvBackupKeep, "-1")
+	_, err = FromFlagSet(newTestFlagSet(t))
+	assert.Error(t, err, "expected an error for a negative backup keep")
+}
+
+func TestFromFlagSetStorageURL(t *testing.T) {
+	cfg, err := FromFlagSet(newTestFlagSet(t))
+	assert.NoError(t, err, "unexpected error from FromFlagSet")
+	assert.Empty(t, cfg.StorageURL, "namespaces are SQLite files by default")
+
+	t.Setenv(EnvStorageURL, "postgres://grantory@db/grantory")
+	cfg, err = FromFlagSet(newTestFlagSet(t))
+	assert.NoError(t, err, "unexpected error from FromFlagSet")
+	assert.Equal(t, "postgres://grantory@db/grantory", cfg.StorageURL, "storage url from env")
+
+	fs := newTestFlagSet(t)
+	assert.NoError(t, fs.Parse([]string{"--backup-dir=/backups"}), "unable to parse args")
+	_, err = FromFlagSet(fs)
+	assert.Error(t, err, "expected an error for scheduled backups on postgres")
+
+	t.Setenv(EnvStorageURL, "mysql://grantory@db/grantory")
+	_, err = FromFlagSet(newTestFlagSet(t))
+	assert.Error(t, err, "expected an error for an unsupported storage url")
+}
+
+func TestFromFlagSetTracing(t *testing.T) {
+	cfg, err := FromFlagSet(newTestFlagSet(t))
+	assert.NoError(t, err, "unexpected error from FromFlagSet")
+	assert.Empty(t, cfg.OTLPEndpoint, "tracing is disabled by default")
+	assert.Equal(t, DefaultTraceSampleRatio, cfg.TraceSampleRatio)
+
+	fs := newTestFlagSet(t)
+	assert.NoError(t, fs.Parse([]string{"--otlp-endpoint=http://collector:4318", "--trace-sample-ratio=0.25"}), "unable to parse args")
+	cfg, err = FromFlagSet(fs)
+	assert.NoError(t, err, "unexpected error from FromFlagSet")
+	assert.Equal(t, "http://collector:4318", cfg.OTLPEndpoint)
+	assert.Equal(t, 0.25, cfg.TraceSampleRatio)
+
+	t.Setenv(EnvTraceSampleRatio, "1.5")
+	_, err = FromFlagSet(newTestFlagSet(t))
+	assert.Error(t, err, "expected an error for a sample ratio above 1")
+}
+
+func TestParseDuration(t *testing.T) {
+	value, err := ParseDuration("7d")
+	assert.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour, value)
+
+	value, err = ParseDuration("90m")
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, value)
+
+	_, err = ParseDuration("xd")
+	assert.Error(t, err)
+}
+
 func newTestFlagSet(t *testing.T) *pflag.FlagSet {
 	t.Helper()
 	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
@@ -11,10 +11,17 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/telemetry"
 )
 
 var errResourceNotFound = errors.New("grantory: resource not found")
 
+// namespaceHeader selects the namespace of an API call, as an authenticating
+// proxy would.
+const namespaceHeader = "REMOTE_USER"
+
 type apiHost struct {
 	ID        string            `json:"id"`
 	Labels    map[string]string `json:"labels,omitempty"`
@@ -26,15 +33,21 @@ type hostLabelsPayload struct {
 }
 
 type apiRequest struct {
-	ID        string            `json:"id"`
-	HostID    string            `json:"host_id"`
-	Payload   map[string]any    `json:"payload,omitempty"`
-	Labels    map[string]string `json:"labels,omitempty"`
-	HasGrant  bool              `json:"has_grant"`
-	Grant     *apiRequestGrant  `json:"grant"`
-	GrantID   string            `json:"grant_id,omitempty"`
-	CreatedAt string            `json:"created_at"`
-	UpdatedAt string            `json:"updated_at"`
+	ID           string            `json:"id"`
+	HostID       string            `json:"host_id"`
+	Payload      map[string]any    `json:"payload,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	HasGrant     bool              `json:"has_grant"`
+	Status       string            `json:"status,omitempty"`
+	StatusReason string            `json:"status_reason,omitempty"`
+	Revision     int               `json:"revision,omitempty"`
+	StaleGrant   bool              `json:"stale_grant"`
+	Grant        *apiRequestGrant  `json:"grant"`
+	GrantID      string            `json:"grant_id,omitempty"`
+	TTL          string            `json:"ttl,omitempty"`
+	ExpiresAt    string            `json:"expires_at,omitempty"`
+	CreatedAt    string            `json:"created_at"`
+	UpdatedAt    string            `json:"updated_at"`
 }
 
 type apiRequestGrant struct {
@@ -47,41 +60,65 @@ type apiRegister struct {
 	HostID    string            `json:"host_id"`
 	Payload   map[string]any    `json:"payload,omitempty"`
 	Labels    map[string]string `json:"labels,omitempty"`
+	TTL       string            `json:"ttl,omitempty"`
+	ExpiresAt string            `json:"expires_at,omitempty"`
 	CreatedAt string            `json:"created_at"`
 	UpdatedAt string            `json:"updated_at"`
 }
 
 type apiGrant struct {
-	ID        string          `json:"id"`
-	RequestID string          `json:"request_id"`
-	Payload   json.RawMessage `json:"payload"`
-	CreatedAt string          `json:"created_at"`
-	UpdatedAt string          `json:"updated_at"`
+	ID        string            `json:"id"`
+	RequestID string            `json:"request_id"`
+	Payload   json.RawMessage   `json:"payload"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	ExpiresAt string            `json:"expires_at,omitempty"`
+	CreatedAt string            `json:"created_at"`
+	UpdatedAt string            `json:"updated_at"`
 }
 
 type apiGrantCreatePayload struct {
-	RequestID string         `json:"request_id"`
-	Payload   map[string]any `json:"payload,omitempty"`
+	RequestID string            `json:"request_id"`
+	Payload   map[string]any    `json:"payload,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	TTL       string            `json:"ttl,omitempty"`
+}
+
+type apiGrantUpdatePayload struct {
+	Labels map[string]string `json:"labels"`
 }
 
 type requestListOptions struct {
 	Labels     map[string]string
 	HostLabels map[string]string
 	HasGrant   *bool
+	StaleGrant *bool
+	Status     string
+	Selector   string
+	Payload    map[string]string
+}
+
+type grantListOptions struct {
+	Labels map[string]string
 }
 
 type registerListOptions struct {
 	Labels     map[string]string
 	HostLabels map[string]string
+	Selector   string
+	Payload    map[string]string
 }
 
 type apiRequestUpdatePayload struct {
-	Payload map[string]any    `json:"payload,omitempty"`
-	Labels  map[string]string `json:"labels,omitempty"`
+	Payload   *map[string]any   `json:"payload,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	TTL       *string           `json:"ttl,omitempty"`
+	ExpiresAt *string           `json:"expires_at,omitempty"`
 }
 
 type apiRegisterUpdatePayload struct {
-	Labels map[string]string `json:"labels,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	TTL       *string           `json:"ttl,omitempty"`
+	ExpiresAt *string           `json:"expires_at,omitempty"`
 }
 
 func (c *grantoryClient) createHost(ctx context.Context, host apiHost) (apiHost, error) {
@@ -105,11 +142,7 @@ func (c *grantoryClient) deleteHost(ctx context.Context, id string) error {
 }
 
 func (c *grantoryClient) listHosts(ctx context.Context) ([]apiHost, error) {
-	var hosts []apiHost
-	if err := c.doJSON(ctx, http.MethodGet, "/hosts", nil, &hosts); err != nil {
-		return nil, err
-	}
-	return hosts, nil
+	return listAllPages[apiHost](ctx, c, "/hosts", url.Values{})
 }
 
 func (c *grantoryClient) updateHostLabels(ctx context.Context, id string, labels map[string]string) (apiHost, error) {
@@ -158,17 +191,20 @@ func (c *grantoryClient) listRequests(ctx context.Context, opts requestListOptio
 	if opts.HasGrant != nil {
 		params.Set("has_grant", strconv.FormatBool(*opts.HasGrant))
 	}
-
-	endpoint := "/requests"
-	if encoded := params.Encode(); encoded != "" {
-		endpoint = endpoint + "?" + encoded
+	if opts.StaleGrant != nil {
+		params.Set("stale_grant", strconv.FormatBool(*opts.StaleGrant))
 	}
-
-	var resp []apiRequest
-	if err := c.doJSON(ctx, http.MethodGet, endpoint, nil, &resp); err != nil {
-		return nil, err
+	if opts.Status != "" {
+		params.Set("status", opts.Status)
+	}
+	if opts.Selector != "" {
+		params.Set("selector", opts.Selector)
+	}
+	for path, value := range opts.Payload {
+		params.Add("payload", fmt.Sprintf("%s=%s", path, value))
 	}
-	return resp, nil
+
+	return listAllPages[apiRequest](ctx, c, "/requests", params)
 }
 
 func (c *grantoryClient) createRegister(ctx context.Context, reg apiRegister) (apiRegister, error) {
@@ -195,17 +231,14 @@ func (c *grantoryClient) listRegisters(ctx context.Context, opts registerListOpt
 	for key, value := range opts.HostLabels {
 		params.Add("host_label", fmt.Sprintf("%s=%s", key, value))
 	}
-
-	endpoint := "/registers"
-	if encoded := params.Encode(); encoded != "" {
-		endpoint = endpoint + "?" + encoded
+	if opts.Selector != "" {
+		params.Set("selector", opts.Selector)
 	}
-
-	var resp []apiRegister
-	if err := c.doJSON(ctx, http.MethodGet, endpoint, nil, &resp); err != nil {
-		return nil, err
+	for path, value := range opts.Payload {
+		params.Add("payload", fmt.Sprintf("%s=%s", path, value))
 	}
-	return resp, nil
+
+	return listAllPages[apiRegister](ctx, c, "/registers", params)
 }
 
 func (c *grantoryClient) updateRegister(ctx context.Context, id string, payload apiRegisterUpdatePayload) (apiRegister, error) {
@@ -216,49 +249,98 @@ func (c *grantoryClient) updateRegister(ctx context.Context, id string, payload
 	return updated, nil
 }
 
+// listPageSize is the number of rows requested per page from list endpoints.
+const listPageSize = 500
+
+// listAllPages fetches a list endpoint page by page, following the cursor of
+// the next link until the last page. Servers without pagination answer the
+// first request with every row and no link.
+func listAllPages[T any](ctx context.Context, c *grantoryClient, path string, params url.Values) ([]T, error) {
+	items := make([]T, 0)
+	params.Set("limit", strconv.Itoa(listPageSize))
+	for {
+		var page []T
+		header, err := c.doJSONWithHeader(ctx, http.MethodGet, path+"?"+params.Encode(), nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		cursor := nextPageCursor(header.Get("Link"))
+		if cursor == "" {
+			return items, nil
+		}
+		params.Set("cursor", cursor)
+	}
+}
+
+// nextPageCursor extracts the cursor from the rel="next" entry of a Link header.
+func nextPageCursor(link string) string {
+	for _, entry := range strings.Split(link, ",") {
+		target, params, found := strings.Cut(strings.TrimSpace(entry), ";")
+		if !found || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		next, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return ""
+		}
+		return next.Query().Get("cursor")
+	}
+	return ""
+}
+
 func (c *grantoryClient) doJSON(ctx context.Context, method, endpoint string, reqBody any, respBody any) error {
+	_, err := c.doJSONWithHeader(ctx, method, endpoint, reqBody, respBody)
+	return err
+}
+
+func (c *grantoryClient) doJSONWithHeader(ctx context.Context, method, endpoint string, reqBody any, respBody any) (http.Header, error) {
 	req, err := c.newRequest(ctx, method, endpoint, reqBody)
 	if err != nil {
-		return fmt.Errorf("build request: %w", err)
+		return nil, fmt.Errorf("build request: %w", err)
 	}
 	if c.token != "" {
 		req.Header.Set("Authorization", "Bearer "+c.token)
 	} else if c.user != "" && c.password != "" {
 		req.SetBasicAuth(c.user, c.password)
 	}
+	if c.namespace != "" {
+		req.Header.Set(namespaceHeader, c.namespace)
+	}
+	telemetry.InjectHeaders(ctx, req.Header)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("perform request: %w", err)
+		return nil, fmt.Errorf("perform request: %w", err)
 	}
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		if cerr := resp.Body.Close(); cerr != nil {
-			return fmt.Errorf("read response: %w (close error: %v)", err, cerr)
+			return nil, fmt.Errorf("read response: %w (close error: %v)", err, cerr)
 		}
-		return fmt.Errorf("read response: %w", err)
+		return nil, fmt.Errorf("read response: %w", err)
 	}
 	if err := resp.Body.Close(); err != nil {
-		return fmt.Errorf("close response body: %w", err)
+		return nil, fmt.Errorf("close response body: %w", err)
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		msg := strings.TrimSpace(string(bodyBytes))
 		if resp.StatusCode == http.StatusNotFound {
-			return fmt.Errorf("%w: %s", errResourceNotFound, msg)
+			return nil, fmt.Errorf("%w: %s", errResourceNotFound, msg)
 		}
-		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, msg)
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, msg)
 	}
 
 	if respBody == nil || len(bodyBytes) == 0 {
-		return nil
+		return resp.Header, nil
 	}
 
 	if err := json.Unmarshal(bodyBytes, respBody); err != nil {
-		return fmt.Errorf("decode response: %w", err)
+		return nil, fmt.Errorf("decode response: %w", err)
 	}
-	return nil
+	return resp.Header, nil
 }
 
 func (c *grantoryClient) newRequest(ctx context.Context, method, endpoint string, body any) (*http.Request, error) {
@@ -307,12 +389,21 @@ func (c *grantoryClient) getGrant(ctx context.Context, id string) (apiGrant, err
 	return grant, nil
 }
 
-func (c *grantoryClient) listGrants(ctx context.Context) ([]apiGrant, error) {
-	var grants []apiGrant
-	if err := c.doJSON(ctx, http.MethodGet, "/grants", nil, &grants); err != nil {
-		return nil, err
+func (c *grantoryClient) listGrants(ctx context.Context, opts grantListOptions) ([]apiGrant, error) {
+	params := url.Values{}
+	for key, value := range opts.Labels {
+		params.Add("label", fmt.Sprintf("%s=%s", key, value))
+	}
+
+	return listAllPages[apiGrant](ctx, c, "/grants", params)
+}
+
+func (c *grantoryClient) updateGrant(ctx context.Context, id string, payload apiGrantUpdatePayload) (apiGrant, error) {
+	var updated apiGrant
+	if err := c.doJSON(ctx, http.MethodPatch, fmt.Sprintf("/grants/%s", id), payload, &updated); err != nil {
+		return apiGrant{}, err
 	}
-	return grants, nil
+	return updated, nil
 }
 
 func (c *grantoryClient) deleteGrant(ctx context.Context, id string) error {
@@ -326,3 +417,106 @@ func (c *grantoryClient) updateRequest(ctx context.Context, id string, payload a
 	}
 	return updated, nil
 }
+
+type apiEvent struct {
+	Seq          int64  `json:"seq"`
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+	Action       string `json:"action"`
+}
+
+type apiEventList struct {
+	Events     []apiEvent `json:"events"`
+	NextCursor int64      `json:"next_cursor"`
+}
+
+// listEvents reads the change feed after since, which is a sequence number
+// or "latest". The server holds the call open for up to wait when there are
+// no events yet.
+func (c *grantoryClient) listEvents(ctx context.Context, since string, resourceType string, wait time.Duration) (apiEventList, error) {
+	params := url.Values{}
+	params.Set("since", since)
+	params.Set("resource_type", resourceType)
+	params.Set("wait", wait.String())
+
+	var page apiEventList
+	if err := c.doJSON(ctx, http.MethodGet, "/events?"+params.Encode(), nil, &page); err != nil {
+		return apiEventList{}, err
+	}
+	return page, nil
+}
+
+type apiWebhook struct {
+	ID             string            `json:"id"`
+	URL            string            `json:"url"`
+	Secret         string            `json:"secret,omitempty"`
+	ResourceTypes  []string          `json:"resource_types"`
+	LabelSelector  map[string]string `json:"label_selector"`
+	IncludePayload bool              `json:"include_payload"`
+	CreatedAt      string            `json:"created_at,omitempty"`
+	UpdatedAt      string            `json:"updated_at,omitempty"`
+}
+
+type apiWebhookUpdatePayload struct {
+	URL            string            `json:"url"`
+	Secret         *string           `json:"secret,omitempty"`
+	ResourceTypes  []string          `json:"resource_types"`
+	LabelSelector  map[string]string `json:"label_selector"`
+	IncludePayload bool              `json:"include_payload"`
+}
+
+func (c *grantoryClient) createWebhook(ctx context.Context, webhook apiWebhook) (apiWebhook, error) {
+	var created apiWebhook
+	if err := c.doJSON(ctx, http.MethodPost, "/webhooks", webhook, &created); err != nil {
+		return apiWebhook{}, err
+	}
+	return created, nil
+}
+
+func (c *grantoryClient) getWebhook(ctx context.Context, id string) (apiWebhook, error) {
+	var webhook apiWebhook
+	if err := c.doJSON(ctx, http.MethodGet, fmt.Sprintf("/webhooks/%s", id), nil, &webhook); err != nil {
+		return apiWebhook{}, err
+	}
+	return webhook, nil
+}
+
+func (c *grantoryClient) updateWebhook(ctx context.Context, id string, payload apiWebhookUpdatePayload) (apiWebhook, error) {
+	var updated apiWebhook
+	if err := c.doJSON(ctx, http.MethodPatch, fmt.Sprintf("/webhooks/%s", id), payload, &updated); err != nil {
+		return apiWebhook{}, err
+	}
+	return updated, nil
+}
+
+func (c *grantoryClient) deleteWebhook(ctx context.Context, id string) error {
+	return c.doJSON(ctx, http.MethodDelete, fmt.Sprintf("/webhooks/%s", id), nil, nil)
+}
+
+type apiRequestType struct {
+	Name          string          `json:"name"`
+	RequestSchema json.RawMessage `json:"request_schema,omitempty"`
+	GrantSchema   json.RawMessage `json:"grant_schema,omitempty"`
+	CreatedAt     string          `json:"created_at,omitempty"`
+	UpdatedAt     string          `json:"updated_at,omitempty"`
+}
+
+func (c *grantoryClient) putRequestType(ctx context.Context, requestType apiRequestType) (apiRequestType, error) {
+	var stored apiRequestType
+	if err := c.doJSON(ctx, http.MethodPut, "/request-types/"+url.PathEscape(requestType.Name), requestType, &stored); err != nil {
+		return apiRequestType{}, err
+	}
+	return stored, nil
+}
+
+func (c *grantoryClient) getRequestType(ctx context.Context, name string) (apiRequestType, error) {
+	var requestType apiRequestType
+	if err := c.doJSON(ctx, http.MethodGet, "/request-types/"+url.PathEscape(name), nil, &requestType); err != nil {
+		return apiRequestType{}, err
+	}
+	return requestType, nil
+}
+
+func (c *grantoryClient) deleteRequestType(ctx context.Context, name string) error {
+	return c.doJSON(ctx, http.MethodDelete, "/request-types/"+url.PathEscape(name), nil, nil)
+}
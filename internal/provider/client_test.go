@@ -3,11 +3,13 @@ package provider
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestGrantoryClientSetsAuthorizationHeader(t *testing.T) {
@@ -51,3 +53,85 @@ func TestGrantoryClientSetsBasicAuth(t *testing.T) {
 
 	assert.NoError(t, client.doJSON(context.Background(), http.MethodGet, "/auth", nil, nil))
 }
+
+func TestGrantoryClientPropagatesTraceContext(t *testing.T) {
+	t.Parallel()
+
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", r.Header.Get("traceparent"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := &grantoryClient{
+		baseURL:    mustParseURL(t, server.URL),
+		httpClient: server.Client(),
+	}
+
+	ctx := trace.ContextWithSpanContext(context.Background(), spanCtx)
+	assert.NoError(t, client.doJSON(ctx, http.MethodGet, "/trace", nil, nil))
+}
+
+func TestGrantoryClientFollowsPages(t *testing.T) {
+	t.Parallel()
+
+	var cursors []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		assert.Equal(t, "env=prod", query.Get("label"), "filters are sent with every page")
+		assert.Equal(t, "500", query.Get("limit"))
+		cursors = append(cursors, query.Get("cursor"))
+
+		page := []apiRegister{{ID: "reg-3"}}
+		if query.Get("cursor") == "" {
+			page = []apiRegister{{ID: "reg-1"}, {ID: "reg-2"}}
+			w.Header().Set("Link", `</registers?cursor=next-page&label=env%3Dprod&limit=500>; rel="next"`)
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(page))
+	}))
+	defer server.Close()
+
+	client := &grantoryClient{
+		baseURL:    mustParseURL(t, server.URL),
+		httpClient: server.Client(),
+	}
+
+	registers, err := client.listRegisters(context.Background(), registerListOptions{Labels: map[string]string{"env": "prod"}})
+	assert.NoError(t, err)
+	ids := make([]string, 0, len(registers))
+	for _, reg := range registers {
+		ids = append(ids, reg.ID)
+	}
+	assert.Equal(t, []string{"reg-1", "reg-2", "reg-3"}, ids)
+	assert.Equal(t, []string{"", "next-page"}, cursors)
+}
+
+func TestGrantoryClientSendsNamespace(t *testing.T) {
+	t.Parallel()
+
+	var namespaces []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespaces = append(namespaces, r.Header.Get(namespaceHeader))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := &grantoryClient{
+		baseURL:    mustParseURL(t, server.URL),
+		httpClient: server.Client(),
+		namespace:  "team-a",
+	}
+
+	ctx := context.Background()
+	assert.NoError(t, client.doJSON(ctx, http.MethodGet, "/hosts", nil, nil))
+	assert.NoError(t, client.withNamespace("team-b").doJSON(ctx, http.MethodGet, "/hosts", nil, nil))
+	assert.NoError(t, client.withNamespace("").doJSON(ctx, http.MethodGet, "/hosts", nil, nil))
+	assert.NoError(t, (&grantoryClient{baseURL: client.baseURL, httpClient: client.httpClient}).doJSON(ctx, http.MethodGet, "/hosts", nil, nil))
+	assert.Equal(t, []string{"team-a", "team-b", "team-a", ""}, namespaces, "an empty override keeps the provider namespace")
+	assert.Equal(t, "team-a", client.namespace, "overrides do not change the provider client")
+}
@@ -4,62 +4,89 @@ import (
 	"context"
 	"errors"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func dataGrant() *schema.Resource {
-	return &schema.Resource{
-		Schema: map[string]*schema.Schema{
-			"grant_id": {
-				Type:        schema.TypeString,
+var _ datasource.DataSourceWithConfigure = (*grantDataSource)(nil)
+
+func newGrantDataSource() datasource.DataSource {
+	return &grantDataSource{}
+}
+
+type grantDataSource struct {
+	client *grantoryClient
+}
+
+type grantDataSourceModel struct {
+	ID        types.String `tfsdk:"id"`
+	Namespace types.String `tfsdk:"namespace"`
+	GrantID   types.String `tfsdk:"grant_id"`
+	RequestID types.String `tfsdk:"request_id"`
+	Payload   types.String `tfsdk:"payload"`
+	Labels    types.Map    `tfsdk:"labels"`
+}
+
+func (d *grantDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_grant"
+}
+
+func (d *grantDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Identifier of the grant, the same as grant_id.",
+			},
+			namespaceAttr: dataNamespaceAttribute("grant"),
+			"grant_id": schema.StringAttribute{
 				Required:    true,
 				Description: "Identifier of the grant to fetch.",
 			},
-			"request_id": {
-				Type:        schema.TypeString,
+			"request_id": schema.StringAttribute{
 				Computed:    true,
 				Description: "Identifier of the request that owns the grant.",
 			},
-			"payload": {
-				Type:        schema.TypeString,
+			"payload": schema.StringAttribute{
 				Computed:    true,
 				Description: "JSON-encoded payload delivered by the grant, if any.",
 			},
+			"labels": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "Labels attached to the grant.",
+			},
 		},
-		ReadContext: dataGrantRead,
 	}
 }
 
-func dataGrantRead(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-	grantID := d.Get("grant_id").(string)
+func (d *grantDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.client = providerClient(req.ProviderData, &resp.Diagnostics)
+}
 
-	var diags diag.Diagnostics
+func (d *grantDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var config grantDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	grantID := config.GrantID.ValueString()
 
-	grant, err := client.getGrant(ctx, grantID)
+	grant, err := clientFor(d.client, config.Namespace).getGrant(ctx, grantID)
 	if err != nil {
 		if errors.Is(err, errResourceNotFound) {
-			d.SetId("")
-			return nil
-		}
-		return diag.FromErr(err)
-	}
-
-	if err := d.Set("grant_id", grant.ID); err != nil {
-		diags = append(diags, diag.FromErr(err)...)
-	}
-	if err := d.Set("request_id", grant.RequestID); err != nil {
-		diags = append(diags, diag.FromErr(err)...)
-	}
-
-	payloadBytes := sanitizeGrantPayload(grant.Payload)
-	if len(payloadBytes) > 0 {
-		if err := d.Set("payload", string(payloadBytes)); err != nil {
-			diags = append(diags, diag.FromErr(err)...)
+			resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+			return
 		}
+		resp.Diagnostics.AddError("failed to read grant", err.Error())
+		return
 	}
 
-	d.SetId(grant.ID)
-	return diags
+	config.ID = types.StringValue(grant.ID)
+	config.GrantID = types.StringValue(grant.ID)
+	config.RequestID = types.StringValue(grant.RequestID)
+	config.Labels = flattenLabels(grant.Labels)
+	config.Payload = types.StringValue(string(sanitizeGrantPayload(grant.Payload)))
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
 }
@@ -3,69 +3,123 @@ package provider
 import (
 	"context"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func dataGrants() *schema.Resource {
-	return &schema.Resource{
-		Schema: map[string]*schema.Schema{
-			"grants": {
-				Type:        schema.TypeList,
+var _ datasource.DataSourceWithConfigure = (*grantsDataSource)(nil)
+
+func newGrantsDataSource() datasource.DataSource {
+	return &grantsDataSource{}
+}
+
+type grantsDataSource struct {
+	client *grantoryClient
+}
+
+type grantsDataSourceModel struct {
+	ID        types.String     `tfsdk:"id"`
+	Namespace types.String     `tfsdk:"namespace"`
+	Labels    types.Map        `tfsdk:"labels"`
+	Include   types.Set        `tfsdk:"include"`
+	Grants    []grantListEntry `tfsdk:"grants"`
+}
+
+type grantListEntry struct {
+	GrantID   string            `json:"grant_id" tfsdk:"grant_id"`
+	RequestID string            `json:"request_id" tfsdk:"request_id"`
+	Payload   *string           `json:"payload,omitempty" tfsdk:"payload"`
+	Labels    map[string]string `json:"labels,omitempty" tfsdk:"labels"`
+	CreatedAt *string           `json:"created_at,omitempty" tfsdk:"created_at"`
+	UpdatedAt *string           `json:"updated_at,omitempty" tfsdk:"updated_at"`
+	ExpiresAt *string           `json:"expires_at,omitempty" tfsdk:"expires_at"`
+}
+
+// grantListEntryType is the element type of the grants attribute.
+var grantListEntryType = types.ObjectType{
+	AttrTypes: map[string]attr.Type{
+		"grant_id":   types.StringType,
+		"request_id": types.StringType,
+		"payload":    types.StringType,
+		"labels":     types.MapType{ElemType: types.StringType},
+		"created_at": types.StringType,
+		"updated_at": types.StringType,
+		"expires_at": types.StringType,
+	},
+}
+
+// grantListFields are the grant entry fields selected by include.
+var grantListFields = []string{"payload", "labels", "created_at", "updated_at", "expires_at"}
+
+func (d *grantsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_grants"
+}
+
+func (d *grantsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id":          dataIDAttribute(),
+			namespaceAttr: dataNamespaceAttribute("grants"),
+			"labels": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Labels that each returned grant must include.",
+			},
+			"include": includeAttribute(grantListFields),
+			"grants": schema.ListAttribute{
+				ElementType: grantListEntryType,
 				Computed:    true,
-				Description: "Grants stored in Grantory, one entry per ID.",
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"grant_id": {
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"request_id": {
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-					},
-				},
+				Description: "Grants stored in Grantory, one entry per ID. Payloads are JSON-encoded.",
 			},
 		},
-		ReadContext: dataGrantsRead,
 	}
 }
 
-func dataGrantsRead(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
+func (d *grantsDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.client = providerClient(req.ProviderData, &resp.Diagnostics)
+}
+
+func (d *grantsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var config grantsDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	labels, diags := expandLabels(ctx, config.Labels)
+	resp.Diagnostics.Append(diags...)
+	include, diags := expandIncludedFields(ctx, config.Include, grantListFields)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	grants, err := client.listGrants(ctx)
+	grants, err := clientFor(d.client, config.Namespace).listGrants(ctx, grantListOptions{Labels: labels})
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to list grants", err.Error())
+		return
 	}
 
-	values := make([]map[string]any, 0, len(grants))
-	hashEntries := make([]grantListEntry, 0, len(grants))
+	entries := make([]grantListEntry, 0, len(grants))
 	for _, grant := range grants {
-		entry := map[string]any{
-			"grant_id":   grant.ID,
-			"request_id": grant.RequestID,
-		}
-		values = append(values, entry)
-		hashEntries = append(hashEntries, grantListEntry{
+		entries = append(entries, grantListEntry{
 			GrantID:   grant.ID,
 			RequestID: grant.RequestID,
+			Payload:   include.string("payload", string(sanitizeGrantPayload(grant.Payload))),
+			Labels:    include.labels(grant.Labels),
+			CreatedAt: include.string("created_at", grant.CreatedAt),
+			UpdatedAt: include.string("updated_at", grant.UpdatedAt),
+			ExpiresAt: include.string("expires_at", grant.ExpiresAt),
 		})
 	}
 
-	if err := d.Set("grants", values); err != nil {
-		return diag.FromErr(err)
-	}
-	id, err := hashAsJSON(hashEntries)
+	id, err := hashAsJSON(entries)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to hash grants", err.Error())
+		return
 	}
-	d.SetId(id)
-	return nil
-}
-
-type grantListEntry struct {
-	GrantID   string `json:"grant_id"`
-	RequestID string `json:"request_id"`
+	config.ID = types.StringValue(id)
+	config.Grants = entries
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
 }
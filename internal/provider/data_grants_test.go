@@ -1,13 +1,12 @@
 package provider
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -18,17 +17,12 @@ func TestDataGrantsSource(t *testing.T) {
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
-
-	resource := dataGrants()
-	data := schema.TestResourceDataRaw(t, resource.Schema, nil)
+	p := newTestProvider(t, server.URL, nil)
 
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "unexpected diagnostics from grants data source")
+	data, diags := p.readDataSource("grantory_grants", map[string]any{})
+	assert.False(t, hasError(diags), "unexpected diagnostics from grants data source")
 
-	grants, ok := data.Get("grants").([]any)
+	grants, ok := data.get("grants").([]any)
 	assert.True(t, ok, "grants should be a list")
 	assert.Len(t, grants, 2, "expected two grant entries")
 
@@ -37,16 +31,72 @@ func TestDataGrantsSource(t *testing.T) {
 	assert.Equal(t, "grant-pending", first["grant_id"])
 	assert.Equal(t, "grant-pending", first["request_id"])
 
+	assert.Equal(t, "", first["payload"], "grants without a payload have an empty one")
+	assert.Equal(t, "2024-02-02T00:00:00Z", first["created_at"], "timestamps should be included by default")
+
+	second := grants[1].(map[string]any)
+	assert.Equal(t, `{"token":"abc"}`, second["payload"], "grant payload should be exposed")
+
+	timestamp, noPayload, payload := "2024-02-02T00:00:00Z", "", `{"token":"abc"}`
 	expectedEntries := []grantListEntry{
-		{GrantID: "grant-pending", RequestID: "grant-pending"},
-		{GrantID: "grant-delivered", RequestID: "grant-delivered"},
+		{GrantID: "grant-pending", RequestID: "grant-pending", Payload: &noPayload, CreatedAt: &timestamp, UpdatedAt: &timestamp, ExpiresAt: &noPayload},
+		{GrantID: "grant-delivered", RequestID: "grant-delivered", Payload: &payload, Labels: map[string]string{"pipeline": "ci"}, CreatedAt: &timestamp, UpdatedAt: &timestamp, ExpiresAt: &noPayload},
 	}
 	expectedID, err := hashAsJSON(expectedEntries)
 	assert.NoError(t, err, "hash grant list")
-	assert.Equal(t, expectedID, data.Id(), "id should be hash of grants list")
+	assert.Equal(t, expectedID, data.get("id"), "id should be hash of grants list")
 }
 
-type grantsDataSourceTestHandler struct{}
+func TestDataGrantsSourceLabelFilter(t *testing.T) {
+	t.Parallel()
+
+	handler := newGrantsDataSourceTestHandler()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	p := newTestProvider(t, server.URL, nil)
+
+	data, diags := p.readDataSource("grantory_grants", map[string]any{
+		"labels": map[string]any{"pipeline": "ci"},
+	})
+	assert.False(t, hasError(diags), "unexpected diagnostics from grants data source")
+	assert.Equal(t, []string{"pipeline=ci"}, handler.lastQuery["label"], "label filter should be sent to the server")
+
+	grants := data.get("grants").([]any)
+	assert.Len(t, grants, 1, "expected the labeled grant only")
+	entry := grants[0].(map[string]any)
+	assert.Equal(t, "grant-delivered", entry["grant_id"])
+	assert.Equal(t, map[string]any{"pipeline": "ci"}, entry["labels"], "grant labels should be exposed")
+}
+
+func TestDataGrantsSourceInclude(t *testing.T) {
+	t.Parallel()
+
+	handler := newGrantsDataSourceTestHandler()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	p := newTestProvider(t, server.URL, nil)
+
+	data, diags := p.readDataSource("grantory_grants", map[string]any{
+		"include": []any{"labels"},
+	})
+	assert.False(t, hasError(diags), "unexpected diagnostics from grants data source")
+
+	entry := data.get("grants").([]any)[1].(map[string]any)
+	assert.Equal(t, map[string]any{"pipeline": "ci"}, entry["labels"], "included labels should be exposed")
+	assert.Nil(t, entry["payload"], "payload should be null when left out")
+	assert.Nil(t, entry["created_at"], "created_at should be null when left out")
+
+	_, diags = p.readDataSource("grantory_grants", map[string]any{
+		"include": []any{"grant_payload"},
+	})
+	assert.True(t, hasError(diags), "unknown include fields should be rejected")
+}
+
+type grantsDataSourceTestHandler struct {
+	lastQuery url.Values
+}
 
 func newGrantsDataSourceTestHandler() *grantsDataSourceTestHandler {
 	return &grantsDataSourceTestHandler{}
@@ -54,9 +104,13 @@ func newGrantsDataSourceTestHandler() *grantsDataSourceTestHandler {
 
 func (h *grantsDataSourceTestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodGet && r.URL.Path == "/grants" {
+		h.lastQuery = r.URL.Query()
 		response := []apiGrant{
 			{ID: "grant-pending", RequestID: "grant-pending", CreatedAt: "2024-02-02T00:00:00Z", UpdatedAt: "2024-02-02T00:00:00Z"},
-			{ID: "grant-delivered", RequestID: "grant-delivered", CreatedAt: "2024-02-02T00:00:00Z", UpdatedAt: "2024-02-02T00:00:00Z"},
+			{ID: "grant-delivered", RequestID: "grant-delivered", Payload: json.RawMessage(`{"token":"abc"}`), Labels: map[string]string{"pipeline": "ci"}, CreatedAt: "2024-02-02T00:00:00Z", UpdatedAt: "2024-02-02T00:00:00Z"},
+		}
+		if h.lastQuery.Get("label") == "pipeline=ci" {
+			response = response[1:]
 		}
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(response)
@@ -4,40 +4,100 @@ import (
 	"context"
 	"sort"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func dataHosts() *schema.Resource {
-	return &schema.Resource{
-		Schema: map[string]*schema.Schema{
-			"labels": {
-				Type:        schema.TypeMap,
+var _ datasource.DataSourceWithConfigure = (*hostsDataSource)(nil)
+
+func newHostsDataSource() datasource.DataSource {
+	return &hostsDataSource{}
+}
+
+type hostsDataSource struct {
+	client *grantoryClient
+}
+
+type hostsDataSourceModel struct {
+	ID        types.String    `tfsdk:"id"`
+	Namespace types.String    `tfsdk:"namespace"`
+	Labels    types.Map       `tfsdk:"labels"`
+	Include   types.Set       `tfsdk:"include"`
+	Hosts     []string        `tfsdk:"hosts"`
+	Entries   []hostListEntry `tfsdk:"entries"`
+}
+
+type hostListEntry struct {
+	HostID    string            `json:"host_id" tfsdk:"host_id"`
+	Labels    map[string]string `json:"labels,omitempty" tfsdk:"labels"`
+	CreatedAt *string           `json:"created_at,omitempty" tfsdk:"created_at"`
+}
+
+// hostListEntryType is the element type of the entries attribute.
+var hostListEntryType = types.ObjectType{
+	AttrTypes: map[string]attr.Type{
+		"host_id":    types.StringType,
+		"labels":     types.MapType{ElemType: types.StringType},
+		"created_at": types.StringType,
+	},
+}
+
+// hostListFields are the host entry fields selected by include.
+var hostListFields = []string{"labels", "created_at"}
+
+func (d *hostsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_hosts"
+}
+
+func (d *hostsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id":          dataIDAttribute(),
+			namespaceAttr: dataNamespaceAttribute("hosts"),
+			"labels": schema.MapAttribute{
+				ElementType: types.StringType,
 				Optional:    true,
 				Description: "Labels that each returned host must include.",
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
-				},
 			},
-			"hosts": {
-				Type:        schema.TypeList,
+			"hosts": schema.ListAttribute{
+				ElementType: types.StringType,
 				Computed:    true,
 				Description: "List of registered host IDs.",
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
-				},
+			},
+			"include": includeAttribute(hostListFields),
+			"entries": schema.ListAttribute{
+				ElementType: hostListEntryType,
+				Computed:    true,
+				Description: "Registered hosts in the order of hosts, with their labels and creation time.",
 			},
 		},
-		ReadContext: dataHostsRead,
 	}
 }
 
-func dataHostsRead(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-	labels := expandStringMap(extractMap(d.Get("labels")))
-	hosts, err := client.listHosts(ctx)
+func (d *hostsDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.client = providerClient(req.ProviderData, &resp.Diagnostics)
+}
+
+func (d *hostsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var config hostsDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	labels, diags := expandLabels(ctx, config.Labels)
+	resp.Diagnostics.Append(diags...)
+	include, diags := expandIncludedFields(ctx, config.Include, hostListFields)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	hosts, err := clientFor(d.client, config.Namespace).listHosts(ctx)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to list hosts", err.Error())
+		return
 	}
 
 	filtered := hosts
@@ -51,24 +111,31 @@ func dataHostsRead(ctx context.Context, d *schema.ResourceData, meta any) diag.D
 	}
 
 	values := make([]string, 0, len(filtered))
+	entries := make([]hostListEntry, 0, len(filtered))
 
 	sort.SliceStable(filtered, func(i, j int) bool {
 		return filtered[i].ID < filtered[j].ID
 	})
 	for _, host := range filtered {
 		values = append(values, host.ID)
+		entries = append(entries, hostListEntry{
+			HostID:    host.ID,
+			Labels:    include.labels(host.Labels),
+			CreatedAt: include.string("created_at", host.CreatedAt),
+		})
 	}
 
-	if err := d.Set("hosts", values); err != nil {
-		return diag.FromErr(err)
-	}
 	id, err := hashAsJSON(map[string]any{
-		"labels": labels,
-		"hosts":  values,
+		"labels":  labels,
+		"hosts":   values,
+		"entries": entries,
 	})
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to hash hosts", err.Error())
+		return
 	}
-	d.SetId(id)
-	return nil
+	config.ID = types.StringValue(id)
+	config.Hosts = values
+	config.Entries = entries
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
 }
@@ -1,7 +1,6 @@
 package provider
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,7 +8,6 @@ import (
 	"sync"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,17 +29,12 @@ func TestDataHostsSource(t *testing.T) {
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
-
-	resource := dataHosts()
-	data := schema.TestResourceDataRaw(t, resource.Schema, nil)
+	p := newTestProvider(t, server.URL, nil)
 
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "unexpected diagnostics from hosts data read")
+	data, diags := p.readDataSource("grantory_hosts", map[string]any{})
+	assert.False(t, hasError(diags), "unexpected diagnostics from hosts data read")
 
-	hostIDs, ok := data.Get("hosts").([]any)
+	hostIDs, ok := data.get("hosts").([]any)
 	assert.True(t, ok, "hosts should be a list")
 	assert.Len(t, hostIDs, 2, "expected two host entries")
 
@@ -50,12 +43,24 @@ func TestDataHostsSource(t *testing.T) {
 		assert.Equal(t, id, hostIDs[i], "host IDs should stay sorted")
 	}
 
+	entries := data.get("entries").([]any)
+	assert.Equal(t, map[string]any{
+		"host_id":    "host-1",
+		"labels":     map[string]any{"env": "prod"},
+		"created_at": "2024-02-02T00:00:00Z",
+	}, entries[0], "entries should carry labels and creation time")
+
+	createdAt := "2024-02-02T00:00:00Z"
 	expectedID, err := hashAsJSON(map[string]any{
 		"labels": map[string]string(nil),
 		"hosts":  expectedIDs,
+		"entries": []hostListEntry{
+			{HostID: "host-1", Labels: map[string]string{"env": "prod"}, CreatedAt: &createdAt},
+			{HostID: "host-2", Labels: map[string]string{"env": "dev"}, CreatedAt: &createdAt},
+		},
 	})
 	assert.NoError(t, err, "hash hosts")
-	assert.Equal(t, expectedID, data.Id(), "id should reflect host list hash")
+	assert.Equal(t, expectedID, data.get("id"), "id should reflect host list hash")
 }
 
 func TestDataHostsSourceLabels(t *testing.T) {
@@ -76,30 +81,34 @@ func TestDataHostsSourceLabels(t *testing.T) {
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
+	p := newTestProvider(t, server.URL, nil)
 
-	resource := dataHosts()
-	data := schema.TestResourceDataRaw(t, resource.Schema, map[string]any{
+	data, diags := p.readDataSource("grantory_hosts", map[string]any{
 		"labels": map[string]any{
 			"env": "prod",
 		},
+		"include": []any{},
 	})
+	assert.False(t, hasError(diags), "unexpected diagnostics from hosts data read")
 
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "unexpected diagnostics from hosts data read")
-
-	hostIDs, ok := data.Get("hosts").([]any)
+	hostIDs, ok := data.get("hosts").([]any)
 	assert.True(t, ok, "hosts should be a list")
 	assert.Equal(t, []any{"host-1"}, hostIDs, "hosts should match labels filter")
 
+	entries := data.get("entries").([]any)
+	assert.Equal(t, []any{map[string]any{
+		"host_id":    "host-1",
+		"labels":     nil,
+		"created_at": nil,
+	}}, entries, "an empty include should leave only host IDs")
+
 	expectedID, err := hashAsJSON(map[string]any{
-		"labels": map[string]string{"env": "prod"},
-		"hosts":  []string{"host-1"},
+		"labels":  map[string]string{"env": "prod"},
+		"hosts":   []string{"host-1"},
+		"entries": []hostListEntry{{HostID: "host-1"}},
 	})
 	assert.NoError(t, err, "hash hosts")
-	assert.Equal(t, expectedID, data.Id(), "id should reflect host list hash")
+	assert.Equal(t, expectedID, data.get("id"), "id should reflect host list hash")
 
 	query := handler.lastQuery()
 	_, hasLabel := query["label"]
@@ -4,62 +4,98 @@ import (
 	"context"
 	"errors"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func dataRegister() *schema.Resource {
-	return &schema.Resource{
-		Schema: map[string]*schema.Schema{
-			"register_id": {
-				Type:        schema.TypeString,
+var _ datasource.DataSourceWithConfigure = (*registerDataSource)(nil)
+
+func newRegisterDataSource() datasource.DataSource {
+	return &registerDataSource{}
+}
+
+type registerDataSource struct {
+	client *grantoryClient
+}
+
+type registerDataSourceModel struct {
+	ID         types.String `tfsdk:"id"`
+	Namespace  types.String `tfsdk:"namespace"`
+	RegisterID types.String `tfsdk:"register_id"`
+	HostID     types.String `tfsdk:"host_id"`
+	Payload    types.String `tfsdk:"payload"`
+	Labels     types.Map    `tfsdk:"labels"`
+	ExpiresAt  types.String `tfsdk:"expires_at"`
+}
+
+func (d *registerDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_register"
+}
+
+func (d *registerDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Identifier of the register entry, the same as register_id.",
+			},
+			namespaceAttr: dataNamespaceAttribute("register"),
+			"register_id": schema.StringAttribute{
 				Required:    true,
 				Description: "Identifier of the register entry to fetch.",
 			},
-			"host_id": {
-				Type:        schema.TypeString,
+			"host_id": schema.StringAttribute{
 				Computed:    true,
 				Description: "Host identifier that owns the register entry.",
 			},
-			"payload": {
-				Type:        schema.TypeString,
+			"payload": schema.StringAttribute{
 				Computed:    true,
-				Optional:    true,
 				Description: "JSON-encoded payload that describes the registered item.",
 			},
-			"labels": {
-				Type:        schema.TypeMap,
+			"labels": schema.MapAttribute{
+				ElementType: types.StringType,
 				Computed:    true,
-				Optional:    true,
 				Description: "Labels associated with the register entry.",
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
-				},
 			},
+			"expires_at": dataExpiresAtAttribute("register entry"),
 		},
-		ReadContext: dataRegisterRead,
 	}
 }
 
-func dataRegisterRead(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-	registerID := d.Get("register_id").(string)
+func (d *registerDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.client = providerClient(req.ProviderData, &resp.Diagnostics)
+}
+
+func (d *registerDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var config registerDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	registerID := config.RegisterID.ValueString()
 	if registerID == "" {
-		return diag.Diagnostics{{
-			Severity: diag.Error,
-			Summary:  "register_id is required",
-		}}
+		resp.Diagnostics.AddError("register_id is required", "")
+		return
 	}
 
-	reg, err := client.getRegister(ctx, registerID)
+	reg, err := clientFor(d.client, config.Namespace).getRegister(ctx, registerID)
 	if err != nil {
 		if errors.Is(err, errResourceNotFound) {
-			d.SetId("")
-			return nil
+			resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+			return
 		}
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to read register", err.Error())
+		return
 	}
 
-	d.SetId(reg.ID)
-	return setRegisterAttributes(d, reg)
+	config.ID = types.StringValue(reg.ID)
+	config.HostID = types.StringValue(reg.HostID)
+	config.Labels = flattenLabels(reg.Labels)
+	config.ExpiresAt = types.StringValue(reg.ExpiresAt)
+	if config.Payload, err = encodeJSONAttribute(reg.Payload); err != nil {
+		resp.Diagnostics.AddError("failed to encode payload", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
 }
@@ -1,7 +1,6 @@
 package provider
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -10,7 +9,6 @@ import (
 	"sync"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,24 +19,19 @@ func TestDataRegisterSource(t *testing.T) {
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
+	p := newTestProvider(t, server.URL, nil)
 
-	resource := dataRegister()
-	data := schema.TestResourceDataRaw(t, resource.Schema, map[string]any{
+	data, diags := p.readDataSource("grantory_register", map[string]any{
 		"register_id": "reg-123",
 	})
+	assert.False(t, hasError(diags), "unexpected diagnostics from register data read")
 
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "unexpected diagnostics from register data read")
-
-	assert.Equal(t, "host-123", data.Get("host_id"), "host_id should match payload")
-	assert.Equal(t, "reg-123", data.Get("register_id"), "register_id should be preserved")
-	payloadValue, ok := data.Get("payload").(string)
+	assert.Equal(t, "host-123", data.get("host_id"), "host_id should match payload")
+	assert.Equal(t, "reg-123", data.get("register_id"), "register_id should be preserved")
+	payloadValue, ok := data.get("payload").(string)
 	assert.True(t, ok, "payload should be a string")
 	assert.JSONEq(t, `{"ip":"10.1.1.1"}`, payloadValue, "payload should match stored data")
-	labelsValue, ok := data.Get("labels").(map[string]any)
+	labelsValue, ok := data.get("labels").(map[string]any)
 	assert.True(t, ok, "labels should be a map")
 	assert.Equal(t, map[string]any{"env": "prod"}, labelsValue, "labels should match stored data")
 
@@ -3,83 +3,161 @@ package provider
 import (
 	"context"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func dataRegisters() *schema.Resource {
-	return &schema.Resource{
-		Schema: map[string]*schema.Schema{
-			"labels": {
-				Type:        schema.TypeMap,
+var _ datasource.DataSourceWithConfigure = (*registersDataSource)(nil)
+
+func newRegistersDataSource() datasource.DataSource {
+	return &registersDataSource{}
+}
+
+type registersDataSource struct {
+	client *grantoryClient
+}
+
+type registersDataSourceModel struct {
+	ID             types.String        `tfsdk:"id"`
+	Namespace      types.String        `tfsdk:"namespace"`
+	Labels         types.Map           `tfsdk:"labels"`
+	HostLabels     types.Map           `tfsdk:"host_labels"`
+	LabelSelector  types.String        `tfsdk:"label_selector"`
+	PayloadFilters types.Map           `tfsdk:"payload_filters"`
+	Include        types.Set           `tfsdk:"include"`
+	Registers      []registerListEntry `tfsdk:"registers"`
+}
+
+type registerListEntry struct {
+	RegisterID string            `json:"register_id" tfsdk:"register_id"`
+	HostID     string            `json:"host_id" tfsdk:"host_id"`
+	Payload    *string           `json:"payload,omitempty" tfsdk:"payload"`
+	Labels     map[string]string `json:"labels,omitempty" tfsdk:"labels"`
+	CreatedAt  *string           `json:"created_at,omitempty" tfsdk:"created_at"`
+	UpdatedAt  *string           `json:"updated_at,omitempty" tfsdk:"updated_at"`
+	ExpiresAt  *string           `json:"expires_at,omitempty" tfsdk:"expires_at"`
+}
+
+// registerListEntryType is the element type of the registers attribute.
+var registerListEntryType = types.ObjectType{
+	AttrTypes: map[string]attr.Type{
+		"register_id": types.StringType,
+		"host_id":     types.StringType,
+		"payload":     types.StringType,
+		"labels":      types.MapType{ElemType: types.StringType},
+		"created_at":  types.StringType,
+		"updated_at":  types.StringType,
+		"expires_at":  types.StringType,
+	},
+}
+
+// registerListFields are the register entry fields selected by include.
+var registerListFields = []string{"payload", "labels", "created_at", "updated_at", "expires_at"}
+
+func (d *registersDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_registers"
+}
+
+func (d *registersDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id":          dataIDAttribute(),
+			namespaceAttr: dataNamespaceAttribute("registers"),
+			"labels": schema.MapAttribute{
+				ElementType: types.StringType,
 				Optional:    true,
 				Description: "Labels that each returned register entry must include.",
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
-				},
 			},
-			"host_labels": {
-				Type:        schema.TypeMap,
+			"host_labels": schema.MapAttribute{
+				ElementType: types.StringType,
 				Optional:    true,
 				Description: "Labels that each returned register's host must include.",
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
-				},
 			},
-			"registers": {
-				Type:        schema.TypeList,
+			"label_selector": schema.StringAttribute{
+				Optional:    true,
+				Description: "Label selector that each returned register entry must match, e.g. `env in (prod,staging),!deprecated`.",
+			},
+			"payload_filters": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Payload values that each returned register entry must hold, keyed by JSON path (e.g. `spec.size` or `$.items[0].id`). Numbers and booleans compare by their JSON form.",
+			},
+			"include": includeAttribute(registerListFields),
+			"registers": schema.ListAttribute{
+				ElementType: registerListEntryType,
 				Computed:    true,
-				Description: "Register entries that matched the supplied filters.",
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"register_id": {
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"host_id": {
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-					},
-				},
+				Description: "Register entries that matched the supplied filters. Payloads are JSON-encoded.",
 			},
 		},
-		ReadContext: dataRegistersRead,
 	}
 }
 
-func dataRegistersRead(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
+func (d *registersDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.client = providerClient(req.ProviderData, &resp.Diagnostics)
+}
+
+func (d *registersDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var config registersDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	labels, diags := expandLabels(ctx, config.Labels)
+	resp.Diagnostics.Append(diags...)
+	hostLabels, diags := expandLabels(ctx, config.HostLabels)
+	resp.Diagnostics.Append(diags...)
+	payloadFilters, diags := expandLabels(ctx, config.PayloadFilters)
+	resp.Diagnostics.Append(diags...)
+	include, diags := expandIncludedFields(ctx, config.Include, registerListFields)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 	opts := registerListOptions{
-		Labels:     expandStringMap(extractMap(d.Get("labels"))),
-		HostLabels: expandStringMap(extractMap(d.Get("host_labels"))),
+		Labels:     labels,
+		HostLabels: hostLabels,
+		Selector:   config.LabelSelector.ValueString(),
+		Payload:    payloadFilters,
 	}
 
-	registers, err := client.listRegisters(ctx, opts)
+	registers, err := clientFor(d.client, config.Namespace).listRegisters(ctx, opts)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to list registers", err.Error())
+		return
 	}
 
-	values := make([]map[string]any, 0, len(registers))
+	entries := make([]registerListEntry, 0, len(registers))
 	for _, reg := range registers {
-		entry := map[string]any{
-			"register_id": reg.ID,
-			"host_id":     reg.HostID,
+		entry := registerListEntry{
+			RegisterID: reg.ID,
+			HostID:     reg.HostID,
+			Labels:     include.labels(reg.Labels),
+			CreatedAt:  include.string("created_at", reg.CreatedAt),
+			UpdatedAt:  include.string("updated_at", reg.UpdatedAt),
+			ExpiresAt:  include.string("expires_at", reg.ExpiresAt),
+		}
+		if entry.Payload, err = include.json("payload", reg.Payload); err != nil {
+			resp.Diagnostics.AddError("failed to encode payload", err.Error())
+			return
 		}
-		values = append(values, entry)
+		entries = append(entries, entry)
 	}
 
-	if err := d.Set("registers", values); err != nil {
-		return diag.FromErr(err)
-	}
 	id, err := hashAsJSON(map[string]any{
-		"labels":      opts.Labels,
-		"host_labels": opts.HostLabels,
-		"registers":   values,
+		"labels":         opts.Labels,
+		"host_labels":    opts.HostLabels,
+		"label_selector": opts.Selector,
+		"payload":        opts.Payload,
+		"registers":      entries,
 	})
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to hash registers", err.Error())
+		return
 	}
-	d.SetId(id)
-	return nil
+	config.ID = types.StringValue(id)
+	config.Registers = entries
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
 }
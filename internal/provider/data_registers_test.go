@@ -1,7 +1,6 @@
 package provider
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,7 +8,6 @@ import (
 	"sync"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -20,24 +18,23 @@ func TestDataRegistersSource(t *testing.T) {
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
+	p := newTestProvider(t, server.URL, nil)
 
-	resource := dataRegisters()
-	data := schema.TestResourceDataRaw(t, resource.Schema, map[string]any{
+	data, diags := p.readDataSource("grantory_registers", map[string]any{
 		"labels": map[string]any{
 			"env": "prod",
 		},
 		"host_labels": map[string]any{
 			"role": "db",
 		},
+		"label_selector": "tier notin (cache),!deprecated",
+		"payload_filters": map[string]any{
+			"endpoint.port": "443",
+		},
 	})
+	assert.False(t, hasError(diags), "unexpected diagnostics from registers data read")
 
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "unexpected diagnostics from registers data read")
-
-	registers, ok := data.Get("registers").([]any)
+	registers, ok := data.get("registers").([]any)
 	assert.True(t, ok, "registers should be a list")
 	assert.Len(t, registers, 1, "expected single register entry")
 
@@ -45,12 +42,37 @@ func TestDataRegistersSource(t *testing.T) {
 	assert.True(t, ok, "register entry should be structured")
 	assert.Equal(t, "host-123", entry["host_id"], "host_id should match filter")
 	assert.Equal(t, "reg-123", entry["register_id"], "register id should be exposed")
+	assert.Equal(t, `{"ip":"10.1.1.1"}`, entry["payload"], "payload should be included by default")
+	assert.Equal(t, map[string]any{"env": "prod"}, entry["labels"], "labels should be included by default")
+	assert.Equal(t, "2024-02-02T00:00:00Z", entry["updated_at"], "timestamps should be included by default")
 
 	query := handler.lastQuery()
 	labelValues := query["label"]
 	assert.Equal(t, []string{"env=prod"}, labelValues, "expected label query")
 	hostLabelValues := query["host_label"]
 	assert.Equal(t, []string{"role=db"}, hostLabelValues, "expected host_label query")
+	assert.Equal(t, "tier notin (cache),!deprecated", query.Get("selector"), "expected selector query")
+	assert.Equal(t, []string{"endpoint.port=443"}, query["payload"], "expected payload query")
+}
+
+func TestDataRegistersSourceInclude(t *testing.T) {
+	t.Parallel()
+
+	handler := newRegistersDataSourceTestHandler()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	p := newTestProvider(t, server.URL, nil)
+
+	data, diags := p.readDataSource("grantory_registers", map[string]any{
+		"include": []any{"payload"},
+	})
+	assert.False(t, hasError(diags), "unexpected diagnostics from registers data read")
+
+	entry := data.get("registers").([]any)[0].(map[string]any)
+	assert.Equal(t, `{"ip":"10.1.1.1"}`, entry["payload"], "included payload should be exposed")
+	assert.Nil(t, entry["labels"], "labels should be null when left out")
+	assert.Nil(t, entry["created_at"], "created_at should be null when left out")
 }
 
 func newRegistersDataSourceTestHandler() *registersDataSourceTestHandler {
@@ -4,79 +4,145 @@ import (
 	"context"
 	"errors"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func dataRequest() *schema.Resource {
-	return &schema.Resource{
-		Schema: map[string]*schema.Schema{
-			"request_id": {
-				Type:        schema.TypeString,
+var _ datasource.DataSourceWithConfigure = (*requestDataSource)(nil)
+
+func newRequestDataSource() datasource.DataSource {
+	return &requestDataSource{}
+}
+
+type requestDataSource struct {
+	client *grantoryClient
+}
+
+type requestDataSourceModel struct {
+	ID           types.String `tfsdk:"id"`
+	Namespace    types.String `tfsdk:"namespace"`
+	RequestID    types.String `tfsdk:"request_id"`
+	HostID       types.String `tfsdk:"host_id"`
+	Payload      types.String `tfsdk:"payload"`
+	Labels       types.Map    `tfsdk:"labels"`
+	HasGrant     types.Bool   `tfsdk:"has_grant"`
+	Status       types.String `tfsdk:"status"`
+	StatusReason types.String `tfsdk:"status_reason"`
+	Revision     types.Int64  `tfsdk:"revision"`
+	StaleGrant   types.Bool   `tfsdk:"stale_grant"`
+	GrantID      types.String `tfsdk:"grant_id"`
+	GrantPayload types.String `tfsdk:"grant_payload"`
+	ExpiresAt    types.String `tfsdk:"expires_at"`
+}
+
+func (d *requestDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_request"
+}
+
+func (d *requestDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Identifier of the request, the same as request_id.",
+			},
+			namespaceAttr: dataNamespaceAttribute("request"),
+			"request_id": schema.StringAttribute{
 				Required:    true,
 				Description: "Identifier of the request to fetch.",
 			},
-			"host_id": {
-				Type:        schema.TypeString,
+			"host_id": schema.StringAttribute{
 				Computed:    true,
 				Description: "Host identifier that owns the returned request.",
 			},
-			"payload": {
-				Type:        schema.TypeString,
+			"payload": schema.StringAttribute{
 				Computed:    true,
-				Optional:    true,
 				Description: "JSON-encoded payload that describes the requested resource.",
 			},
-			"labels": {
-				Type:        schema.TypeMap,
+			"labels": schema.MapAttribute{
+				ElementType: types.StringType,
 				Computed:    true,
-				Optional:    true,
 				Description: "Labels attached to the request.",
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
-				},
 			},
-			"has_grant": {
-				Type:        schema.TypeBool,
+			"has_grant": schema.BoolAttribute{
 				Computed:    true,
 				Description: "Indicates whether the server has created a matching grant.",
 			},
-			"grant_id": {
-				Type:        schema.TypeString,
+			"status": schema.StringAttribute{
+				Computed:    true,
+				Description: "Lifecycle status of the request: pending, approved, denied, revoked, or expired.",
+			},
+			"status_reason": schema.StringAttribute{
+				Computed:    true,
+				Description: "Reason recorded by the grantor for the current status, if any.",
+			},
+			"revision": schema.Int64Attribute{
+				Computed:    true,
+				Description: "Revision of the request payload, starting at 1 and increased by every payload change.",
+			},
+			"stale_grant": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Indicates whether the grant was issued for an earlier revision of the payload.",
+			},
+			"grant_id": schema.StringAttribute{
 				Computed:    true,
-				Optional:    true,
 				Description: "Identifier reported by the Grantory server for the applied grant.",
 			},
-			"grant_payload": {
-				Type:        schema.TypeString,
+			"grant_payload": schema.StringAttribute{
 				Computed:    true,
-				Optional:    true,
 				Description: "JSON-encoded payload delivered by the grant, if any.",
 			},
+			"expires_at": dataExpiresAtAttribute("request"),
 		},
-		ReadContext: dataRequestRead,
 	}
 }
 
-func dataRequestRead(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-	reqID := d.Get("request_id").(string)
-	if reqID == "" {
-		return diag.Diagnostics{{
-			Severity: diag.Error,
-			Summary:  "request_id is required",
-		}}
+func (d *requestDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.client = providerClient(req.ProviderData, &resp.Diagnostics)
+}
+
+func (d *requestDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var config requestDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	requestID := config.RequestID.ValueString()
+	if requestID == "" {
+		resp.Diagnostics.AddError("request_id is required", "")
+		return
 	}
 
-	req, err := client.getRequest(ctx, reqID)
+	found, err := clientFor(d.client, config.Namespace).getRequest(ctx, requestID)
 	if err != nil {
 		if errors.Is(err, errResourceNotFound) {
-			d.SetId("")
-			return nil
+			// A missing entry yields an empty result, leaving every computed
+			// attribute null.
+			resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+			return
 		}
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to read request", err.Error())
+		return
 	}
 
-	d.SetId(req.ID)
-	return setRequestAttributes(d, req)
+	config.ID = types.StringValue(found.ID)
+	config.HostID = types.StringValue(found.HostID)
+	config.Labels = flattenLabels(found.Labels)
+	config.HasGrant = types.BoolValue(found.HasGrant)
+	config.Status = types.StringValue(found.Status)
+	config.StatusReason = types.StringValue(found.StatusReason)
+	config.Revision = types.Int64Value(int64(found.Revision))
+	config.StaleGrant = types.BoolValue(found.StaleGrant)
+	config.GrantID = types.StringValue(found.GrantID)
+	config.ExpiresAt = types.StringValue(found.ExpiresAt)
+	if config.Payload, err = encodeJSONAttribute(found.Payload); err != nil {
+		resp.Diagnostics.AddError("failed to encode payload", err.Error())
+		return
+	}
+	if config.GrantPayload, err = encodeJSONAttribute(extractGrantPayload(found)); err != nil {
+		resp.Diagnostics.AddError("failed to encode grant_payload", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
 }
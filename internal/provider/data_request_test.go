@@ -1,7 +1,6 @@
 package provider
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -10,7 +9,6 @@ import (
 	"sync"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,28 +19,24 @@ func TestDataRequestSource(t *testing.T) {
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
+	p := newTestProvider(t, server.URL, nil)
 
-	resource := dataRequest()
-	data := schema.TestResourceDataRaw(t, resource.Schema, map[string]any{
+	data, diags := p.readDataSource("grantory_request", map[string]any{
 		"request_id": "req-123",
 	})
+	assert.False(t, hasError(diags), "unexpected diagnostics from request data read")
 
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "unexpected diagnostics from request data read")
-
-	assert.Equal(t, "host-123", data.Get("host_id"), "host_id should match payload")
-	assert.True(t, data.Get("has_grant").(bool), "has_grant should be populated")
-	payloadValue, ok := data.Get("payload").(string)
+	assert.Equal(t, "host-123", data.get("host_id"), "host_id should match payload")
+	assert.True(t, data.get("has_grant").(bool), "has_grant should be populated")
+	assert.Equal(t, "approved", data.get("status"), "status should be populated")
+	payloadValue, ok := data.get("payload").(string)
 	assert.True(t, ok, "payload should be a string")
 	assert.JSONEq(t, `{"name":"db"}`, payloadValue, "payload should match stored data")
-	labelsValue, ok := data.Get("labels").(map[string]any)
+	labelsValue, ok := data.get("labels").(map[string]any)
 	assert.True(t, ok, "labels should be a map")
 	assert.Equal(t, map[string]any{"env": "prod"}, labelsValue, "labels should match stored data")
-	assert.Equal(t, "grant-456", data.Get("grant_id"), "grant ID should be available")
-	grantPayload, ok := data.Get("grant_payload").(string)
+	assert.Equal(t, "grant-456", data.get("grant_id"), "grant ID should be available")
+	grantPayload, ok := data.get("grant_payload").(string)
 	assert.True(t, ok, "grant payload should be a string")
 	assert.JSONEq(t, `{"user":"alice"}`, grantPayload, "grant payload should match stored grant")
 
@@ -80,6 +74,7 @@ func (h *requestDataSourceTestHandler) ServeHTTP(w http.ResponseWriter, r *http.
 		Labels:   map[string]string{"env": "prod"},
 		HasGrant: true,
 		GrantID:  "grant-456",
+		Status:   "approved",
 		Grant: &apiRequestGrant{
 			GrantID: "grant-456",
 			Payload: map[string]any{"user": "alice"},
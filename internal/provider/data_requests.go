@@ -3,109 +3,215 @@ package provider
 import (
 	"context"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func dataRequests() *schema.Resource {
-	return &schema.Resource{
-		Schema: map[string]*schema.Schema{
-			"has_grant": {
-				Type:        schema.TypeBool,
+var _ datasource.DataSourceWithConfigure = (*requestsDataSource)(nil)
+
+func newRequestsDataSource() datasource.DataSource {
+	return &requestsDataSource{}
+}
+
+type requestsDataSource struct {
+	client *grantoryClient
+}
+
+type requestsDataSourceModel struct {
+	ID             types.String       `tfsdk:"id"`
+	Namespace      types.String       `tfsdk:"namespace"`
+	HasGrant       types.Bool         `tfsdk:"has_grant"`
+	StaleGrant     types.Bool         `tfsdk:"stale_grant"`
+	Status         types.String       `tfsdk:"status"`
+	Labels         types.Map          `tfsdk:"labels"`
+	HostLabels     types.Map          `tfsdk:"host_labels"`
+	LabelSelector  types.String       `tfsdk:"label_selector"`
+	PayloadFilters types.Map          `tfsdk:"payload_filters"`
+	Include        types.Set          `tfsdk:"include"`
+	Requests       []requestListEntry `tfsdk:"requests"`
+}
+
+type requestListEntry struct {
+	RequestID    string            `json:"request_id" tfsdk:"request_id"`
+	HostID       string            `json:"host_id" tfsdk:"host_id"`
+	HasGrant     bool              `json:"has_grant" tfsdk:"has_grant"`
+	Status       string            `json:"status" tfsdk:"status"`
+	StatusReason string            `json:"status_reason,omitempty" tfsdk:"status_reason"`
+	Revision     int               `json:"revision" tfsdk:"revision"`
+	StaleGrant   bool              `json:"stale_grant" tfsdk:"stale_grant"`
+	GrantID      string            `json:"grant_id,omitempty" tfsdk:"grant_id"`
+	Payload      *string           `json:"payload,omitempty" tfsdk:"payload"`
+	Labels       map[string]string `json:"labels,omitempty" tfsdk:"labels"`
+	GrantPayload *string           `json:"grant_payload,omitempty" tfsdk:"grant_payload"`
+	CreatedAt    *string           `json:"created_at,omitempty" tfsdk:"created_at"`
+	UpdatedAt    *string           `json:"updated_at,omitempty" tfsdk:"updated_at"`
+	ExpiresAt    *string           `json:"expires_at,omitempty" tfsdk:"expires_at"`
+}
+
+// requestListEntryType is the element type of the requests attribute.
+var requestListEntryType = types.ObjectType{
+	AttrTypes: map[string]attr.Type{
+		"request_id":    types.StringType,
+		"host_id":       types.StringType,
+		"has_grant":     types.BoolType,
+		"status":        types.StringType,
+		"status_reason": types.StringType,
+		"revision":      types.Int64Type,
+		"stale_grant":   types.BoolType,
+		"grant_id":      types.StringType,
+		"payload":       types.StringType,
+		"labels":        types.MapType{ElemType: types.StringType},
+		"grant_payload": types.StringType,
+		"created_at":    types.StringType,
+		"updated_at":    types.StringType,
+		"expires_at":    types.StringType,
+	},
+}
+
+// requestListFields are the request entry fields selected by include.
+var requestListFields = []string{"payload", "labels", "grant_payload", "created_at", "updated_at", "expires_at"}
+
+func (d *requestsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_requests"
+}
+
+func (d *requestsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id":          dataIDAttribute(),
+			namespaceAttr: dataNamespaceAttribute("requests"),
+			"has_grant": schema.BoolAttribute{
 				Optional:    true,
 				Description: "Whether returned requests must already have a grant.",
 			},
-			"labels": {
-				Type:        schema.TypeMap,
+			"stale_grant": schema.BoolAttribute{
 				Optional:    true,
-				Description: "Labels that each returned request must include.",
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
+				Description: "Whether returned requests must have a grant issued for an earlier payload revision.",
+			},
+			"status": schema.StringAttribute{
+				Optional:    true,
+				Description: "Lifecycle status that each returned request must have (pending, approved, denied, revoked, or expired).",
+				Validators: []validator.String{
+					stringvalidator.OneOf("pending", "approved", "denied", "revoked", "expired"),
 				},
 			},
-			"host_labels": {
-				Type:        schema.TypeMap,
+			"labels": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Labels that each returned request must include.",
+			},
+			"host_labels": schema.MapAttribute{
+				ElementType: types.StringType,
 				Optional:    true,
 				Description: "Labels that each returned request's host must include.",
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
-				},
 			},
-			"requests": {
-				Type:        schema.TypeList,
+			"label_selector": schema.StringAttribute{
+				Optional:    true,
+				Description: "Label selector that each returned request must match, e.g. `env in (prod,staging),!deprecated`.",
+			},
+			"payload_filters": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Payload values that each returned request must hold, keyed by JSON path (e.g. `spec.size` or `$.items[0].id`). Numbers and booleans compare by their JSON form.",
+			},
+			"include": includeAttribute(requestListFields),
+			"requests": schema.ListAttribute{
+				ElementType: requestListEntryType,
 				Computed:    true,
-				Description: "Requests returned by Grantory.",
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"request_id": {
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"host_id": {
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"has_grant": {
-							Type:     schema.TypeBool,
-							Computed: true,
-						},
-					},
-				},
+				Description: "Requests returned by Grantory. Payloads are JSON-encoded.",
 			},
 		},
-		ReadContext: dataRequestsRead,
 	}
 }
 
-func dataRequestsRead(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
+func (d *requestsDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.client = providerClient(req.ProviderData, &resp.Diagnostics)
+}
+
+func (d *requestsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var config requestsDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	labels, diags := expandLabels(ctx, config.Labels)
+	resp.Diagnostics.Append(diags...)
+	hostLabels, diags := expandLabels(ctx, config.HostLabels)
+	resp.Diagnostics.Append(diags...)
+	payloadFilters, diags := expandLabels(ctx, config.PayloadFilters)
+	resp.Diagnostics.Append(diags...)
+	include, diags := expandIncludedFields(ctx, config.Include, requestListFields)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 	opts := requestListOptions{
-		Labels:     expandStringMap(extractMap(d.Get("labels"))),
-		HostLabels: expandStringMap(extractMap(d.Get("host_labels"))),
+		Labels:     labels,
+		HostLabels: hostLabels,
+		Status:     config.Status.ValueString(),
+		Selector:   config.LabelSelector.ValueString(),
+		Payload:    payloadFilters,
 	}
-	if raw, ok := d.GetOkExists("has_grant"); ok {
-		value := raw.(bool)
+	if !config.HasGrant.IsNull() {
+		value := config.HasGrant.ValueBool()
 		opts.HasGrant = &value
 	}
+	if !config.StaleGrant.IsNull() {
+		value := config.StaleGrant.ValueBool()
+		opts.StaleGrant = &value
+	}
 
-	requests, err := client.listRequests(ctx, opts)
+	requests, err := clientFor(d.client, config.Namespace).listRequests(ctx, opts)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to list requests", err.Error())
+		return
 	}
 
-	values := make([]map[string]any, 0, len(requests))
-	hashEntries := make([]requestListEntry, 0, len(requests))
-	for _, req := range requests {
-		entry := map[string]any{
-			"request_id": req.ID,
-			"host_id":    req.HostID,
-			"has_grant":  req.HasGrant,
+	entries := make([]requestListEntry, 0, len(requests))
+	for _, request := range requests {
+		entry := requestListEntry{
+			RequestID:    request.ID,
+			HostID:       request.HostID,
+			HasGrant:     request.HasGrant,
+			Status:       request.Status,
+			StatusReason: request.StatusReason,
+			Revision:     request.Revision,
+			StaleGrant:   request.StaleGrant,
+			GrantID:      request.GrantID,
+			Labels:       include.labels(request.Labels),
+			CreatedAt:    include.string("created_at", request.CreatedAt),
+			UpdatedAt:    include.string("updated_at", request.UpdatedAt),
+			ExpiresAt:    include.string("expires_at", request.ExpiresAt),
+		}
+		if entry.Payload, err = include.json("payload", request.Payload); err != nil {
+			resp.Diagnostics.AddError("failed to encode payload", err.Error())
+			return
+		}
+		if entry.GrantPayload, err = include.json("grant_payload", extractGrantPayload(request)); err != nil {
+			resp.Diagnostics.AddError("failed to encode grant_payload", err.Error())
+			return
 		}
-		values = append(values, entry)
-		hashEntries = append(hashEntries, requestListEntry{
-			RequestID: req.ID,
-			HostID:    req.HostID,
-			HasGrant:  req.HasGrant,
-		})
+		entries = append(entries, entry)
 	}
 
-	if err := d.Set("requests", values); err != nil {
-		return diag.FromErr(err)
-	}
 	id, err := hashAsJSON(map[string]any{
-		"labels":      opts.Labels,
-		"host_labels": opts.HostLabels,
-		"requests":    hashEntries,
+		"labels":         opts.Labels,
+		"host_labels":    opts.HostLabels,
+		"label_selector": opts.Selector,
+		"payload":        opts.Payload,
+		"status":         opts.Status,
+		"requests":       entries,
 	})
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to hash requests", err.Error())
+		return
 	}
-	d.SetId(id)
-	return nil
-}
-
-type requestListEntry struct {
-	RequestID string `json:"request_id"`
-	HostID    string `json:"host_id"`
-	HasGrant  bool   `json:"has_grant"`
+	config.ID = types.StringValue(id)
+	config.Requests = entries
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
 }
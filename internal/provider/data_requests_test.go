@@ -1,7 +1,6 @@
 package provider
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,7 +8,6 @@ import (
 	"sync"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -20,13 +18,9 @@ func TestDataRequestsSource(t *testing.T) {
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
+	p := newTestProvider(t, server.URL, nil)
 
-	resource := dataRequests()
-	data := schema.TestResourceDataRaw(t, resource.Schema, map[string]any{
+	data, diags := p.readDataSource("grantory_requests", map[string]any{
 		"has_grant": true,
 		"labels": map[string]any{
 			"env": "prod",
@@ -34,11 +28,14 @@ func TestDataRequestsSource(t *testing.T) {
 		"host_labels": map[string]any{
 			"role": "db",
 		},
+		"label_selector": "env in (prod,staging),team",
+		"payload_filters": map[string]any{
+			"$.spec.size": "large",
+		},
 	})
+	assert.False(t, hasError(diags), "unexpected diagnostics from requests data read")
 
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "unexpected diagnostics from requests data read")
-
-	requests, ok := data.Get("requests").([]any)
+	requests, ok := data.get("requests").([]any)
 	assert.True(t, ok, "requests should be a list")
 	assert.Len(t, requests, 1, "expected single request entry")
 
@@ -46,6 +43,12 @@ func TestDataRequestsSource(t *testing.T) {
 	assert.True(t, ok, "request entry should be structured")
 	assert.Equal(t, "host-123", entry["host_id"], "host_id should match filter")
 	assert.True(t, entry["has_grant"].(bool), "has_grant should remain present")
+	assert.Equal(t, "grant-456", entry["grant_id"], "grant_id should be exposed")
+	assert.Equal(t, `{"name":"db"}`, entry["payload"], "payload should be included by default")
+	assert.Equal(t, `{"user":"alice"}`, entry["grant_payload"], "grant payload should be included by default")
+	assert.Equal(t, map[string]any{"env": "prod"}, entry["labels"], "labels should be included by default")
+	assert.Equal(t, "2024-02-02T00:00:00Z", entry["created_at"], "timestamps should be included by default")
+	assert.Equal(t, "", entry["expires_at"], "requests without a ttl have an empty expires_at")
 
 	query := handler.lastQuery()
 	labelValues := query["label"]
@@ -53,6 +56,8 @@ func TestDataRequestsSource(t *testing.T) {
 	hostLabelValues := query["host_label"]
 	assert.Equal(t, []string{"role=db"}, hostLabelValues, "expected host_label query")
 	assert.Equal(t, "true", query.Get("has_grant"), "expected has_grant query")
+	assert.Equal(t, "env in (prod,staging),team", query.Get("selector"), "expected selector query")
+	assert.Equal(t, []string{"$.spec.size=large"}, query["payload"], "expected payload query")
 }
 
 func TestDataRequestsSourceHasGrantFalse(t *testing.T) {
@@ -62,17 +67,12 @@ func TestDataRequestsSourceHasGrantFalse(t *testing.T) {
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
+	p := newTestProvider(t, server.URL, nil)
 
-	resource := dataRequests()
-	data := schema.TestResourceDataRaw(t, resource.Schema, map[string]any{
+	_, diags := p.readDataSource("grantory_requests", map[string]any{
 		"has_grant": false,
 	})
-
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "unexpected diagnostics from requests data read")
+	assert.False(t, hasError(diags), "unexpected diagnostics from requests data read")
 
 	query := handler.lastQuery()
 	assert.Equal(t, "false", query.Get("has_grant"), "expected has_grant=false query")
@@ -85,17 +85,12 @@ func TestDataRequestsSourceNoFilters(t *testing.T) {
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
-
-	resource := dataRequests()
-	data := schema.TestResourceDataRaw(t, resource.Schema, nil)
+	p := newTestProvider(t, server.URL, nil)
 
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "unexpected diagnostics from requests data read")
+	data, diags := p.readDataSource("grantory_requests", map[string]any{})
+	assert.False(t, hasError(diags), "unexpected diagnostics from requests data read")
 
-	requests, ok := data.Get("requests").([]any)
+	requests, ok := data.get("requests").([]any)
 	assert.True(t, ok, "requests should be a list")
 	assert.Len(t, requests, 1, "expected single request entry")
 
@@ -108,6 +103,32 @@ func TestDataRequestsSourceNoFilters(t *testing.T) {
 	assert.False(t, hasHost, "should not submit has_grant when absent")
 }
 
+func TestDataRequestsSourceInclude(t *testing.T) {
+	t.Parallel()
+
+	handler := newRequestsDataSourceTestHandler()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	p := newTestProvider(t, server.URL, nil)
+
+	data, diags := p.readDataSource("grantory_requests", map[string]any{
+		"include": []any{"payload", "grant_payload"},
+	})
+	assert.False(t, hasError(diags), "unexpected diagnostics from requests data read")
+
+	entry := data.get("requests").([]any)[0].(map[string]any)
+	assert.Equal(t, `{"name":"db"}`, entry["payload"], "included payload should be exposed")
+	assert.Equal(t, `{"user":"alice"}`, entry["grant_payload"], "included grant payload should be exposed")
+	assert.Nil(t, entry["labels"], "labels should be null when left out")
+	assert.Nil(t, entry["created_at"], "created_at should be null when left out")
+
+	_, diags = p.readDataSource("grantory_requests", map[string]any{
+		"include": []any{"token"},
+	})
+	assert.True(t, hasError(diags), "unknown include fields should be rejected")
+}
+
 func newRequestsDataSourceTestHandler() *requestsDataSourceTestHandler {
 	return &requestsDataSourceTestHandler{}
 }
@@ -1,133 +1,314 @@
 package provider
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	dschema "github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func expandStringMap(input map[string]any) map[string]string {
-	if len(input) == 0 {
-		return nil
+// expandLabels converts a labels attribute to the map sent to the API. Null
+// and empty maps are nil.
+func expandLabels(ctx context.Context, value types.Map) (map[string]string, diag.Diagnostics) {
+	if value.IsNull() || value.IsUnknown() || len(value.Elements()) == 0 {
+		return nil, nil
 	}
+	labels := make(map[string]string, len(value.Elements()))
+	diags := value.ElementsAs(ctx, &labels, false)
+	return labels, diags
+}
 
-	result := make(map[string]string, len(input))
-	for key, value := range input {
-		if value == nil {
-			continue
-		}
+// flattenLabels converts labels returned by the API to an attribute value.
+// Resources without labels have null labels, matching an omitted attribute.
+func flattenLabels(labels map[string]string) types.Map {
+	if len(labels) == 0 {
+		return types.MapNull(types.StringType)
+	}
+	values, _ := types.MapValueFrom(context.Background(), types.StringType, labels)
+	return values
+}
 
-		str, ok := value.(string)
-		if !ok {
-			continue
-		}
+// flattenLabelsLike is flattenLabels, but keeps an empty map when the prior
+// value was one, so that labels = {} does not show a perpetual diff.
+func flattenLabelsLike(labels map[string]string, prior types.Map) types.Map {
+	if len(labels) == 0 && !prior.IsNull() && !prior.IsUnknown() && len(prior.Elements()) == 0 {
+		return prior
+	}
+	return flattenLabels(labels)
+}
 
-		result[key] = str
+// encodeJSONAttribute renders a JSON document for a computed string
+// attribute; documents that are absent become an empty string.
+func encodeJSONAttribute(value map[string]any) (types.String, error) {
+	if value == nil {
+		return types.StringValue(""), nil
+	}
+	encoded, err := canonicalJSON(value)
+	if err != nil {
+		return types.String{}, err
 	}
+	return types.StringValue(encoded), nil
+}
 
-	if len(result) == 0 {
-		return nil
+func hashAsJSON(value any) (string, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func matchesLabelFilters(labels map[string]string, filters map[string]string) bool {
+	if len(filters) == 0 {
+		return true
 	}
-	return result
+	if len(labels) == 0 {
+		return false
+	}
+	for key, expected := range filters {
+		if labels[key] != expected {
+			return false
+		}
+	}
+	return true
 }
 
-func flattenStringMap(input map[string]string) map[string]any {
-	if len(input) == 0 {
+// providerClient returns the client passed on by the provider, or reports
+// unexpected provider data. It returns nil before the provider is configured.
+func providerClient(data any, diags *diag.Diagnostics) *grantoryClient {
+	if data == nil {
+		return nil
+	}
+	client, ok := data.(*grantoryClient)
+	if !ok {
+		diags.AddError("unexpected provider data", fmt.Sprintf("expected *grantoryClient, got %T", data))
 		return nil
 	}
+	return client
+}
+
+// clientFor returns the provider client, switched to namespace when the
+// resource or data source overrides it.
+func clientFor(client *grantoryClient, namespace types.String) *grantoryClient {
+	return client.withNamespace(strings.TrimSpace(namespace.ValueString()))
+}
 
-	result := make(map[string]any, len(input))
-	for key, value := range input {
-		result[key] = value
+// resourceNamespaceAttribute describes the namespace override of a resource.
+// Moving a resource to another namespace replaces it.
+func resourceNamespaceAttribute(resource string) schema.StringAttribute {
+	return schema.StringAttribute{
+		Optional:      true,
+		Description:   fmt.Sprintf("Namespace of the %s. Defaults to the provider namespace.", resource),
+		PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
 	}
-	return result
 }
 
-func expandAnyMap(input map[string]any) map[string]any {
-	if len(input) == 0 {
-		return nil
+// dataNamespaceAttribute describes the namespace override of a data source.
+func dataNamespaceAttribute(resource string) dschema.StringAttribute {
+	return dschema.StringAttribute{
+		Optional:    true,
+		Description: fmt.Sprintf("Namespace to read the %s from. Defaults to the provider namespace.", resource),
+	}
+}
+
+// dataIDAttribute describes the identifier of a data source result, a hash of
+// the filters and the entries they matched.
+func dataIDAttribute() dschema.StringAttribute {
+	return dschema.StringAttribute{
+		Computed:    true,
+		Description: "Identifier of the result, which changes whenever the returned entries change.",
+	}
+}
+
+// includeAttribute describes the include attribute of a list data source,
+// which selects the optional fields filled in for each returned entry.
+func includeAttribute(fields []string) dschema.SetAttribute {
+	return dschema.SetAttribute{
+		ElementType: types.StringType,
+		Optional:    true,
+		Description: fmt.Sprintf("Optional fields to fill in for each entry: %s. Defaults to all of them; fields left out are null.", strings.Join(fields, ", ")),
+		Validators: []validator.Set{
+			setvalidator.ValueStringsAre(stringvalidator.OneOf(fields...)),
+		},
 	}
+}
+
+// includedFields holds the optional entry fields selected by an include
+// attribute.
+type includedFields map[string]bool
 
-	result := make(map[string]any, len(input))
-	for key, value := range input {
-		if value == nil {
-			continue
+// expandIncludedFields converts an include attribute to the selected fields.
+// A null include selects every field.
+func expandIncludedFields(ctx context.Context, value types.Set, fields []string) (includedFields, diag.Diagnostics) {
+	included := make(includedFields, len(fields))
+	if value.IsNull() || value.IsUnknown() {
+		for _, field := range fields {
+			included[field] = true
 		}
-		result[key] = value
+		return included, nil
 	}
+	var selected []string
+	diags := value.ElementsAs(ctx, &selected, false)
+	for _, field := range selected {
+		included[field] = true
+	}
+	return included, diags
+}
 
-	if len(result) == 0 {
+// string returns value when field is included, and nil otherwise.
+func (f includedFields) string(field, value string) *string {
+	if !f[field] {
 		return nil
 	}
-	return result
+	return &value
 }
 
-func parseJSONString(value string) (map[string]any, error) {
-	trimmed := strings.TrimSpace(value)
-	if trimmed == "" {
+// json returns the canonical JSON form of document when field is included,
+// and nil otherwise. Absent documents are an empty string, as for
+// encodeJSONAttribute.
+func (f includedFields) json(field string, document map[string]any) (*string, error) {
+	if !f[field] {
 		return nil, nil
 	}
-	var result map[string]any
-	if err := json.Unmarshal([]byte(trimmed), &result); err != nil {
-		return nil, err
+	encoded, err := encodeJSONAttribute(document)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s: %w", field, err)
 	}
-	return result, nil
+	return f.string(field, encoded.ValueString()), nil
 }
 
-func encodeMapToJSONString(value map[string]any) (string, error) {
-	if value == nil {
-		return "", nil
+// labels returns labels when the labels field is included, and nil
+// otherwise.
+func (f includedFields) labels(labels map[string]string) map[string]string {
+	if !f["labels"] || len(labels) == 0 {
+		return nil
 	}
-	b, err := json.Marshal(value)
-	if err != nil {
-		return "", err
+	return labels
+}
+
+// idAttribute describes the identifier every resource carries.
+func idAttribute(description string) schema.StringAttribute {
+	return schema.StringAttribute{
+		Computed:      true,
+		Description:   description,
+		PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
 	}
-	return string(b), nil
 }
 
-func setJSONStringAttribute(d *schema.ResourceData, key string, value map[string]any) diag.Diagnostics {
-	payload, err := encodeMapToJSONString(value)
-	if err != nil {
-		return diag.Diagnostics{{
-			Severity: diag.Error,
-			Summary:  fmt.Sprintf("encode %s", key),
-			Detail:   err.Error(),
-		}}
+// ttlAttribute describes the optional lifetime attribute shared by resources
+// the server can expire.
+func ttlAttribute(resource string, forceNew bool) schema.StringAttribute {
+	description := fmt.Sprintf("Lifetime of the %s, such as 12h or 7d. The server removes the %s once it expires.", resource, resource)
+	attribute := schema.StringAttribute{
+		Optional:   true,
+		Validators: []validator.String{durationValidator{}},
 	}
-	if payload == "" {
-		return nil
+	if forceNew {
+		attribute.PlanModifiers = []planmodifier.String{stringplanmodifier.RequiresReplace()}
+	} else {
+		description += " Changing the value restarts the lifetime from the time of the change."
 	}
-	if err := d.Set(key, payload); err != nil {
-		return diag.FromErr(err)
+	attribute.Description = description
+	return attribute
+}
+
+func expiresAtAttribute(resource string) schema.StringAttribute {
+	return schema.StringAttribute{
+		Computed:    true,
+		Description: fmt.Sprintf("RFC 3339 timestamp after which the server removes the %s, if it expires.", resource),
 	}
-	return nil
 }
 
-func hashAsJSON(value any) (string, error) {
-	b, err := json.Marshal(value)
-	if err != nil {
-		return "", err
+func dataExpiresAtAttribute(resource string) dschema.StringAttribute {
+	return dschema.StringAttribute{
+		Computed:    true,
+		Description: fmt.Sprintf("RFC 3339 timestamp after which the server removes the %s, if it expires.", resource),
 	}
-	sum := sha256.Sum256(b)
-	return hex.EncodeToString(sum[:]), nil
 }
 
-func matchesLabelFilters(labels map[string]string, filters map[string]string) bool {
-	if len(filters) == 0 {
-		return true
+// ttlUpdate returns the update fields for a changed ttl: a new lifetime, or
+// an empty expires_at that clears the expiry when the ttl was removed.
+func ttlUpdate(ttl types.String) (*string, *string) {
+	value := ttl.ValueString()
+	if value == "" {
+		return nil, &value
 	}
-	if len(labels) == 0 {
-		return false
+	return &value, nil
+}
+
+// durationValidator accepts positive durations as understood by
+// parseDuration.
+type durationValidator struct{}
+
+func (durationValidator) Description(context.Context) string {
+	return "value must be a positive duration such as 30m, 12h or 7d"
+}
+
+func (v durationValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v durationValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
 	}
-	for key, expected := range filters {
-		if labels[key] != expected {
-			return false
-		}
+	raw := req.ConfigValue.ValueString()
+	if _, err := parseDuration(raw); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid duration", fmt.Sprintf("%s must be a positive duration such as 30m, 12h or 7d, got %q", req.Path, raw))
 	}
-	return true
+}
+
+// parseDuration parses a positive Go duration, or a number of days such as 7d.
+func parseDuration(raw string) (time.Duration, error) {
+	var (
+		duration time.Duration
+		err      error
+	)
+	if days, found := strings.CutSuffix(raw, "d"); found {
+		var n int
+		n, err = strconv.Atoi(days)
+		duration = time.Duration(n) * 24 * time.Hour
+	} else {
+		duration, err = time.ParseDuration(raw)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("duration %q is not positive", raw)
+	}
+	return duration, nil
+}
+
+// upgradeOptionalString maps the empty string the SDKv2 provider stored for
+// unset optional attributes to null, the value of an omitted attribute.
+func upgradeOptionalString(value types.String) types.String {
+	if value.ValueString() == "" {
+		return types.StringNull()
+	}
+	return value
+}
+
+// upgradeMap maps the empty maps the SDKv2 provider stored for unset optional
+// attributes to null.
+func upgradeMap(value types.Map) types.Map {
+	if len(value.Elements()) == 0 {
+		return types.MapNull(value.ElementType(context.Background()))
+	}
+	return value
 }
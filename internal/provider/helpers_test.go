@@ -1,94 +1,214 @@
 package provider
 
 import (
+	"context"
+	"encoding/json"
+	"math/big"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestExpandStringMapSkipsInvalidValues(t *testing.T) {
+func TestExpandLabels(t *testing.T) {
 	t.Parallel()
 
-	result := expandStringMap(map[string]any{
-		"valid":   "value",
-		"nil":     nil,
-		"numeric": 1,
-	})
-	assert.Equal(t, map[string]string{"valid": "value"}, result)
-	assert.Nil(t, expandStringMap(map[string]any{}))
+	ctx := context.Background()
+	labels, diags := expandLabels(ctx, types.MapValueMust(types.StringType, map[string]attr.Value{
+		"env": types.StringValue("prod"),
+	}))
+	assert.False(t, diags.HasError())
+	assert.Equal(t, map[string]string{"env": "prod"}, labels)
+
+	labels, _ = expandLabels(ctx, types.MapNull(types.StringType))
+	assert.Nil(t, labels)
+	labels, _ = expandLabels(ctx, types.MapValueMust(types.StringType, map[string]attr.Value{}))
+	assert.Nil(t, labels)
 }
 
-func TestFlattenStringMapReturnsValue(t *testing.T) {
+func TestFlattenLabels(t *testing.T) {
 	t.Parallel()
 
-	input := map[string]string{"env": "prod"}
-	flattened := flattenStringMap(input)
-	assert.Equal(t, map[string]any{"env": "prod"}, flattened)
-	assert.Nil(t, flattenStringMap(nil))
+	flattened := flattenLabels(map[string]string{"env": "prod"})
+	assert.Equal(t, map[string]attr.Value{"env": types.StringValue("prod")}, flattened.Elements())
+	assert.True(t, flattenLabels(nil).IsNull())
+
+	empty := types.MapValueMust(types.StringType, map[string]attr.Value{})
+	assert.True(t, flattenLabelsLike(nil, empty).Equal(empty), "configured empty labels are kept")
+	assert.True(t, flattenLabelsLike(nil, types.MapNull(types.StringType)).IsNull())
 }
 
-func TestExpandAnyMapFiltersNilValues(t *testing.T) {
+func TestEncodeJSONAttribute(t *testing.T) {
 	t.Parallel()
 
-	entries := map[string]any{"keep": "value", "skip": nil}
-	result := expandAnyMap(entries)
-	assert.Equal(t, map[string]any{"keep": "value"}, result)
-	assert.Nil(t, expandAnyMap(map[string]any{}))
+	encoded, err := encodeJSONAttribute(map[string]any{"key": "value", "n": 1.5})
+	require.NoError(t, err)
+	assert.Equal(t, `{"key":"value","n":1.5}`, encoded.ValueString())
+
+	empty, err := encodeJSONAttribute(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", empty.ValueString())
 }
 
-func TestParseAndEncodeJSONString(t *testing.T) {
+func TestHashAsJSONIsDeterministic(t *testing.T) {
 	t.Parallel()
 
-	empty, err := parseJSONString("  ")
+	first, err := hashAsJSON(map[string]any{"k": "v"})
 	assert.NoError(t, err)
-	assert.Nil(t, empty)
-
-	decoded, err := parseJSONString("{\"key\": \"value\"}")
+	second, err := hashAsJSON(map[string]any{"k": "v"})
 	assert.NoError(t, err)
-	assert.Equal(t, map[string]any{"key": "value"}, decoded)
+	assert.Equal(t, first, second)
+}
 
-	encoded, err := encodeMapToJSONString(decoded)
-	assert.NoError(t, err)
-	assert.JSONEq(t, "{\"key\":\"value\"}", encoded)
-	emptyPayload, err := encodeMapToJSONString(nil)
-	assert.NoError(t, err)
-	assert.Empty(t, emptyPayload)
+func TestNormalizeJSON(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		`{ "b": [1, 2.50], "a": "x" }`: `{"a":"x","b":[1,2.5]}`,
+		`{"big": 1e21, "small": 1E-7}`: `{"big":1000000000000000000000,"small":0.0000001}`,
+		`{"html": "<a&b>"}`:            `{"html":"\u003ca\u0026b\u003e"}`,
+	}
+	for raw, expected := range tests {
+		normalized, err := normalizeJSON(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, expected, normalized, raw)
+	}
+
+	_, err := normalizeJSON(`{"a":1} {"b":2}`)
+	assert.Error(t, err, "trailing documents are rejected")
 }
 
-func TestSetJSONStringAttributeClearsValue(t *testing.T) {
+func TestPayloadSemanticEquality(t *testing.T) {
 	t.Parallel()
 
-	resource := &schema.Resource{Schema: map[string]*schema.Schema{
-		"payload": {Type: schema.TypeString},
-	}}
-	data := schema.TestResourceDataRaw(t, resource.Schema, nil)
+	ctx := context.Background()
+	object := types.ObjectValueMust(
+		map[string]attr.Type{"size": types.StringType, "count": types.NumberType},
+		map[string]attr.Value{"size": types.StringValue("large"), "count": types.NumberValue(mustParseNumber(t, "0.1"))},
+	)
+	tests := []struct {
+		name  string
+		a, b  attr.Value
+		equal bool
+	}{
+		{"formatting", types.StringValue(`{"size":"large","count":0.1}`), types.StringValue("{\n  \"count\": 0.10,\n  \"size\": \"large\"\n}"), true},
+		{"object and string", object, types.StringValue(`{"count":0.1,"size":"large"}`), true},
+		{"different documents", object, types.StringValue(`{"count":0.2,"size":"large"}`), false},
+		{"null and empty object", types.DynamicNull(), types.StringValue(`{}`), true},
+		{"null and empty string", types.DynamicNull(), types.StringValue(""), true},
+		{"null and document", types.DynamicNull(), object, false},
+	}
+	for _, tc := range tests {
+		equal, diags := dynamicPayload(tc.a).DynamicSemanticEquals(ctx, dynamicPayload(tc.b))
+		assert.False(t, diags.HasError(), tc.name)
+		assert.Equal(t, tc.equal, equal, tc.name)
+	}
+}
 
-	diags := setJSONStringAttribute(data, "payload", nil)
-	assert.Empty(t, diags)
-	assert.Equal(t, "", data.Get("payload"))
+func TestNewPayloadKeepsForm(t *testing.T) {
+	t.Parallel()
 
-	payload := map[string]any{"name": "test"}
-	diags = setJSONStringAttribute(data, "payload", payload)
-	assert.Empty(t, diags)
-	assert.Equal(t, "{\"name\":\"test\"}", data.Get("payload"))
+	ctx := context.Background()
+	document := map[string]any{"size": "large", "count": float64(3)}
+
+	str, err := newPayload(document, dynamicPayload(types.StringValue("{}")))
+	require.NoError(t, err)
+	assert.True(t, str.isString())
+	assert.Equal(t, types.StringValue(`{"count":3,"size":"large"}`), str.UnderlyingValue())
+
+	imported, err := newPayload(document, newPayloadNull())
+	require.NoError(t, err)
+	assert.True(t, imported.isString(), "payloads without a prior form take the jsonencode form")
+
+	like := dynamicPayload(types.ObjectValueMust(map[string]attr.Type{}, map[string]attr.Value{}))
+	obj, err := newPayload(document, like)
+	require.NoError(t, err)
+	assert.False(t, obj.isString())
+	decoded, err := obj.decode(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `{"count":3,"size":"large"}`, mustCanonicalJSON(t, decoded))
+
+	null, err := newPayload(nil, obj)
+	require.NoError(t, err)
+	assert.True(t, null.IsNull())
 }
 
-func TestHashAsJSONIsDeterministic(t *testing.T) {
+func TestUpgradePayload(t *testing.T) {
 	t.Parallel()
 
-	first, err := hashAsJSON(map[string]any{"k": "v"})
-	assert.NoError(t, err)
-	second, err := hashAsJSON(map[string]any{"k": "v"})
-	assert.NoError(t, err)
-	assert.Equal(t, first, second)
+	upgraded, err := upgradePayload(types.StringValue("{\n  \"b\": 1,\n  \"a\": [true, null]\n}"))
+	require.NoError(t, err)
+	assert.Equal(t, types.StringValue(`{"a":[true,null],"b":1}`), upgraded.UnderlyingValue())
+
+	empty, err := upgradePayload(types.StringValue(""))
+	require.NoError(t, err)
+	assert.True(t, empty.IsNull())
+
+	_, err = upgradePayload(types.StringValue("not json"))
+	assert.Error(t, err)
+}
+
+func TestPayloadValidator(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	for value, valid := range map[string]bool{
+		`{"a":1}`:  true,
+		`[1,2]`:    false,
+		`"string"`: false,
+		`not json`: false,
+	} {
+		resp := &validator.DynamicResponse{}
+		payloadValidator{}.ValidateDynamic(ctx, validator.DynamicRequest{
+			Path:        path.Root("payload"),
+			ConfigValue: types.DynamicValue(types.StringValue(value)),
+		}, resp)
+		assert.Equal(t, !valid, resp.Diagnostics.HasError(), value)
+	}
+
+	resp := &validator.DynamicResponse{}
+	payloadValidator{}.ValidateDynamic(ctx, validator.DynamicRequest{
+		Path:        path.Root("payload"),
+		ConfigValue: types.DynamicValue(types.TupleValueMust([]attr.Type{types.StringType}, []attr.Value{types.StringValue("a")})),
+	}, resp)
+	assert.True(t, resp.Diagnostics.HasError(), "lists are not payloads")
 }
 
-func TestExtractMap(t *testing.T) {
+func TestParseDuration(t *testing.T) {
 	t.Parallel()
 
-	assert.Nil(t, extractMap(nil))
-	assert.Nil(t, extractMap(123))
-	value := map[string]any{"key": "value"}
-	assert.Equal(t, value, extractMap(value))
+	duration, err := parseDuration("7d")
+	require.NoError(t, err)
+	assert.Equal(t, "168h0m0s", duration.String())
+
+	for _, raw := range []string{"soon", "0s", "-1h", "d"} {
+		_, err := parseDuration(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func dynamicPayload(value attr.Value) jsonPayload {
+	if dynamic, ok := value.(basetypes.DynamicValue); ok {
+		return jsonPayload{DynamicValue: dynamic}
+	}
+	return jsonPayload{DynamicValue: types.DynamicValue(value)}
+}
+
+func mustParseNumber(t *testing.T, raw string) *big.Float {
+	t.Helper()
+	n, err := parsePayloadNumber(json.Number(raw))
+	require.NoError(t, err)
+	return n
+}
+
+func mustCanonicalJSON(t *testing.T, document any) string {
+	t.Helper()
+	encoded, err := canonicalJSON(document)
+	require.NoError(t, err)
+	return encoded
 }
@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+)
+
+// importLabelsPrefix marks an import ID that looks the resource up by its
+// labels instead of naming it, as in labels:type=foo,name=bar.
+const importLabelsPrefix = "labels:"
+
+// importLookup returns the IDs of the resources that carry all labels.
+type importLookup func(ctx context.Context, client *grantoryClient, labels map[string]string) ([]string, error)
+
+// importResourceState imports a resource by ID or by labels. Either form may
+// start with "<namespace>/" to import from a namespace other than the
+// provider's; the namespace is then recorded in the namespace attribute.
+// The read that follows the import fills in every other attribute.
+func importResourceState(ctx context.Context, client *grantoryClient, kind string, lookup importLookup, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	namespace, id := splitImportID(req.ID)
+	if namespace != "" {
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root(namespaceAttr), namespace)...)
+	}
+
+	if selector, ok := strings.CutPrefix(id, importLabelsPrefix); ok {
+		labels, err := parseImportLabels(selector)
+		if err != nil {
+			resp.Diagnostics.AddError("invalid import ID", err.Error())
+			return
+		}
+		ids, err := lookup(ctx, client.withNamespace(namespace), labels)
+		if err != nil {
+			resp.Diagnostics.AddError(fmt.Sprintf("failed to look up %s", kind), err.Error())
+			return
+		}
+		switch len(ids) {
+		case 0:
+			resp.Diagnostics.AddError("invalid import ID", fmt.Sprintf("no %s has the labels %s", kind, selector))
+			return
+		case 1:
+			id = ids[0]
+		default:
+			sort.Strings(ids)
+			resp.Diagnostics.AddError("invalid import ID", fmt.Sprintf("%d %ss have the labels %s, import one of them by ID: %s", len(ids), kind, selector, strings.Join(ids, ", ")))
+			return
+		}
+	}
+	if id == "" {
+		resp.Diagnostics.AddError("invalid import ID", fmt.Sprintf("import ID must name a %s or start with %q", kind, importLabelsPrefix))
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), id)...)
+}
+
+// splitImportID separates the optional namespace prefix from an import ID.
+// Label values may contain slashes, so IDs starting with the labels prefix
+// never carry a namespace.
+func splitImportID(raw string) (namespace, id string) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, importLabelsPrefix) {
+		return "", raw
+	}
+	if namespace, id, found := strings.Cut(raw, "/"); found {
+		return namespace, id
+	}
+	return "", raw
+}
+
+// parseImportLabels parses the key=value pairs of a label import ID.
+func parseImportLabels(selector string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(selector, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid label %q in import ID: use key=value pairs separated by commas", pair)
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
+
+func lookupHosts(ctx context.Context, client *grantoryClient, labels map[string]string) ([]string, error) {
+	hosts, err := client.listHosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, host := range hosts {
+		if matchesLabelFilters(host.Labels, labels) {
+			ids = append(ids, host.ID)
+		}
+	}
+	return ids, nil
+}
+
+func lookupRequests(ctx context.Context, client *grantoryClient, labels map[string]string) ([]string, error) {
+	requests, err := client.listRequests(ctx, requestListOptions{Labels: labels})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(requests))
+	for _, req := range requests {
+		ids = append(ids, req.ID)
+	}
+	return ids, nil
+}
+
+func lookupRegisters(ctx context.Context, client *grantoryClient, labels map[string]string) ([]string, error) {
+	registers, err := client.listRegisters(ctx, registerListOptions{Labels: labels})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(registers))
+	for _, reg := range registers {
+		ids = append(ids, reg.ID)
+	}
+	return ids, nil
+}
+
+func lookupGrants(ctx context.Context, client *grantoryClient, labels map[string]string) ([]string, error) {
+	grants, err := client.listGrants(ctx, grantListOptions{Labels: labels})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(grants))
+	for _, grant := range grants {
+		ids = append(ids, grant.ID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitImportID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		raw       string
+		namespace string
+		id        string
+	}{
+		{raw: "req-1", id: "req-1"},
+		{raw: "team-a/req-1", namespace: "team-a", id: "req-1"},
+		{raw: "labels:path=a/b", id: "labels:path=a/b"},
+		{raw: "team-a/labels:type=foo", namespace: "team-a", id: "labels:type=foo"},
+	}
+	for _, tc := range tests {
+		namespace, id := splitImportID(tc.raw)
+		assert.Equal(t, tc.namespace, namespace, tc.raw)
+		assert.Equal(t, tc.id, id, tc.raw)
+	}
+}
+
+func TestParseImportLabels(t *testing.T) {
+	t.Parallel()
+
+	labels, err := parseImportLabels("type=foo, name=bar")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"type": "foo", "name": "bar"}, labels)
+
+	_, err = parseImportLabels("type")
+	assert.Error(t, err, "labels need a value")
+	_, err = parseImportLabels("=foo")
+	assert.Error(t, err, "labels need a key")
+}
+
+func TestResourceImportByID(t *testing.T) {
+	t.Parallel()
+
+	server := newRequestTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
+
+	created := p.mustApply("grantory_request", nil, map[string]any{
+		"host_id": "host-1",
+		"payload": `{"size":"large"}`,
+		"labels":  map[string]any{"type": "foo"},
+	})
+
+	imported := importResource(t, p, "grantory_request", created.get("id").(string))
+	assert.Equal(t, created.get("id"), imported.get("id"))
+	assert.Equal(t, "host-1", imported.get("host_id"))
+	assert.Equal(t, `{"size":"large"}`, imported.get("payload"), "imported payloads take the jsonencode form")
+	assert.Equal(t, map[string]any{"type": "foo"}, imported.get("labels"))
+	assert.Nil(t, imported.get(namespaceAttr), "IDs without a prefix keep the provider namespace")
+
+	changed, replace := plannedChanges(t, imported, p.plan("grantory_request", &imported, map[string]any{
+		"host_id": "host-1",
+		"payload": `{"size":"large"}`,
+		"labels":  map[string]any{"type": "foo"},
+	}), p.resourceType("grantory_request"))
+	assert.Empty(t, changed, "imported requests plan no changes")
+	assert.False(t, replace, "imported requests are kept")
+}
+
+func TestResourceImportByLabels(t *testing.T) {
+	t.Parallel()
+
+	var queries []string
+	var namespaces []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query()["label"]...)
+		namespaces = append(namespaces, r.Header.Get(namespaceHeader))
+		var page []apiRequest
+		switch r.URL.Query().Get("label") {
+		case "name=bar", "type=foo":
+			page = []apiRequest{{ID: "req-1"}}
+		case "name=many":
+			page = []apiRequest{{ID: "req-3"}, {ID: "req-2"}}
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(page))
+	}))
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
+
+	state, diags := p.importState("grantory_request", "team-a/labels:type=foo,name=bar")
+	requireNoErrors(t, diags)
+	assert.Equal(t, "req-1", state.get("id"))
+	assert.Equal(t, "team-a", state.get(namespaceAttr), "the namespace prefix is kept")
+	assert.ElementsMatch(t, []string{"type=foo", "name=bar"}, queries, "labels are filtered by the server")
+	assert.Equal(t, []string{"team-a"}, namespaces)
+
+	_, diags = p.importState("grantory_request", "labels:name=none")
+	assert.Contains(t, diagnosticsText(diags), "no request has the labels name=none")
+
+	_, diags = p.importState("grantory_request", "labels:name=many")
+	assert.Contains(t, diagnosticsText(diags), "req-2, req-3", "ambiguous labels list the candidates")
+}
+
+func TestResourceImportHostByLabels(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/hosts", r.URL.Path)
+		assert.NoError(t, json.NewEncoder(w).Encode([]apiHost{
+			{ID: "host-1", Labels: map[string]string{"env": "prod", "role": "db"}},
+			{ID: "host-2", Labels: map[string]string{"env": "prod", "role": "web"}},
+		}))
+	}))
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
+
+	state, diags := p.importState("grantory_host", "labels:env=prod,role=web")
+	requireNoErrors(t, diags)
+	assert.Equal(t, "host-2", state.get("id"))
+}
+
+func TestResourcesSupportImport(t *testing.T) {
+	t.Parallel()
+
+	for name, newResource := range map[string]func() resource.Resource{
+		"grantory_host":     newHostResource,
+		"grantory_request":  newRequestResource,
+		"grantory_register": newRegisterResource,
+		"grantory_grant":    newGrantResource,
+	} {
+		_, ok := newResource().(resource.ResourceWithImportState)
+		assert.True(t, ok, "%s should be importable", name)
+	}
+}
+
+// importResource imports id and reads the resource like Terraform does.
+func importResource(t *testing.T, p *testProvider, typeName, id string) testState {
+	t.Helper()
+	state, diags := p.importState(typeName, id)
+	requireNoErrors(t, diags)
+	state, diags = p.read(typeName, state)
+	requireNoErrors(t, diags)
+	return state
+}
@@ -0,0 +1,454 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/dynamicplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// payloadNumberPrecision matches the precision Terraform parses numbers with,
+// so numbers read back from the server compare equal to configured ones.
+const payloadNumberPrecision = 512
+
+var (
+	_ basetypes.DynamicTypable                    = jsonPayloadType{}
+	_ basetypes.DynamicValuableWithSemanticEquals = jsonPayload{}
+)
+
+// jsonPayloadType is the type of payload attributes. A payload is written
+// either as an object, or as a string holding a JSON object as returned by
+// jsonencode. Both forms are equal when they describe the same document.
+type jsonPayloadType struct {
+	basetypes.DynamicType
+}
+
+func (t jsonPayloadType) Equal(o attr.Type) bool {
+	other, ok := o.(jsonPayloadType)
+	if !ok {
+		return false
+	}
+	return t.DynamicType.Equal(other.DynamicType)
+}
+
+func (t jsonPayloadType) String() string {
+	return "jsonPayloadType"
+}
+
+func (t jsonPayloadType) ValueFromDynamic(_ context.Context, in basetypes.DynamicValue) (basetypes.DynamicValuable, diag.Diagnostics) {
+	return jsonPayload{DynamicValue: in}, nil
+}
+
+func (t jsonPayloadType) ValueFromTerraform(ctx context.Context, in tftypes.Value) (attr.Value, error) {
+	value, err := t.DynamicType.ValueFromTerraform(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	dynamic, ok := value.(basetypes.DynamicValue)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type %T", value)
+	}
+	return jsonPayload{DynamicValue: dynamic}, nil
+}
+
+func (t jsonPayloadType) ValueType(context.Context) attr.Value {
+	return jsonPayload{}
+}
+
+// jsonPayload is a payload attribute value, see jsonPayloadType.
+type jsonPayload struct {
+	basetypes.DynamicValue
+}
+
+func newPayloadNull() jsonPayload {
+	return jsonPayload{DynamicValue: basetypes.NewDynamicNull()}
+}
+
+func (v jsonPayload) Equal(o attr.Value) bool {
+	other, ok := o.(jsonPayload)
+	if !ok {
+		return false
+	}
+	return v.DynamicValue.Equal(other.DynamicValue)
+}
+
+func (v jsonPayload) Type(context.Context) attr.Type {
+	return jsonPayloadType{}
+}
+
+// DynamicSemanticEquals reports whether both payloads describe the same JSON
+// document, regardless of their form, formatting and key order.
+func (v jsonPayload) DynamicSemanticEquals(ctx context.Context, other basetypes.DynamicValuable) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	otherValue, otherDiags := other.ToDynamicValue(ctx)
+	diags.Append(otherDiags...)
+	if diags.HasError() {
+		return false, diags
+	}
+	return payloadsEqual(ctx, v.DynamicValue, otherValue), diags
+}
+
+// isString reports whether the payload is written in its JSON string form.
+func (v jsonPayload) isString() bool {
+	_, ok := v.UnderlyingValue().(basetypes.StringValue)
+	return ok
+}
+
+// decode returns the payload as a JSON object for the API. Null and empty
+// payloads decode to nil.
+func (v jsonPayload) decode(ctx context.Context) (map[string]any, error) {
+	if v.IsNull() || v.IsUnderlyingValueNull() {
+		return nil, nil
+	}
+	document, err := payloadDocument(ctx, v.UnderlyingValue())
+	if err != nil {
+		return nil, err
+	}
+	if document == nil {
+		return nil, nil
+	}
+	object, ok := document.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("payload must be an object or a JSON-encoded object")
+	}
+	return object, nil
+}
+
+// newPayload converts a payload read from the server into an attribute value
+// written in the same form as like, so that a payload configured with
+// jsonencode stays a string and an object stays an object. Payloads without
+// a prior value, such as imported ones, take the jsonencode form.
+func newPayload(document map[string]any, like jsonPayload) (jsonPayload, error) {
+	if document == nil {
+		return newPayloadNull(), nil
+	}
+	if like.isString() || like.IsNull() || like.IsUnderlyingValueNull() {
+		encoded, err := canonicalJSON(document)
+		if err != nil {
+			return jsonPayload{}, err
+		}
+		return jsonPayload{DynamicValue: basetypes.NewDynamicValue(types.StringValue(encoded))}, nil
+	}
+	value, err := payloadValue(document)
+	if err != nil {
+		return jsonPayload{}, err
+	}
+	return jsonPayload{DynamicValue: basetypes.NewDynamicValue(value)}, nil
+}
+
+// upgradePayload converts a payload from schema version 0 to version 1.
+//
+// Version 0 is the state written by the SDKv2 provider. It stored payloads as
+// JSON strings and kept empty strings and maps for unset optional attributes,
+// which upgradeOptionalString and upgradeMap turn into nulls. Version 1 stores
+// payloads as dynamic values. An upgraded payload keeps its string form,
+// rewritten the way jsonencode writes it, so that configurations using
+// jsonencode plan no changes.
+func upgradePayload(value types.String) (jsonPayload, error) {
+	if strings.TrimSpace(value.ValueString()) == "" {
+		return newPayloadNull(), nil
+	}
+	normalized, err := normalizeJSON(value.ValueString())
+	if err != nil {
+		return jsonPayload{}, err
+	}
+	return jsonPayload{DynamicValue: basetypes.NewDynamicValue(types.StringValue(normalized))}, nil
+}
+
+// payloadsEqual compares two payload values as JSON documents. A null
+// payload equals an empty object, as the server stores neither. Values that
+// cannot be converted are never equal.
+func payloadsEqual(ctx context.Context, a, b basetypes.DynamicValue) bool {
+	left, err := payloadCanonical(ctx, a)
+	if err != nil {
+		return false
+	}
+	right, err := payloadCanonical(ctx, b)
+	if err != nil {
+		return false
+	}
+	return left == right
+}
+
+func payloadCanonical(ctx context.Context, value basetypes.DynamicValue) (string, error) {
+	if value.IsNull() || value.IsUnderlyingValueNull() {
+		return "", nil
+	}
+	if value.IsUnknown() || value.IsUnderlyingValueUnknown() {
+		return "", fmt.Errorf("payload is not known yet")
+	}
+	document, err := payloadDocument(ctx, value.UnderlyingValue())
+	if err != nil {
+		return "", err
+	}
+	if object, ok := document.(map[string]any); document == nil || ok && len(object) == 0 {
+		return "", nil
+	}
+	return canonicalJSON(document)
+}
+
+// payloadDocument converts an attribute value to the JSON document it
+// describes. Strings hold JSON text; an empty string is no document.
+func payloadDocument(ctx context.Context, value attr.Value) (any, error) {
+	if str, ok := value.(basetypes.StringValue); ok {
+		if str.IsUnknown() {
+			return nil, fmt.Errorf("payload is not known yet")
+		}
+		if strings.TrimSpace(str.ValueString()) == "" {
+			return nil, nil
+		}
+		return decodeJSON(str.ValueString())
+	}
+	raw, err := value.ToTerraformValue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return terraformDocument(raw)
+}
+
+// terraformDocument converts a Terraform value to Go values as decoded by
+// encoding/json, with numbers kept as json.Number.
+func terraformDocument(value tftypes.Value) (any, error) {
+	if !value.IsKnown() {
+		return nil, fmt.Errorf("payload is not known yet")
+	}
+	if value.IsNull() {
+		return nil, nil
+	}
+	typ := value.Type()
+	switch {
+	case typ.Is(tftypes.String):
+		var s string
+		err := value.As(&s)
+		return s, err
+	case typ.Is(tftypes.Bool):
+		var b bool
+		err := value.As(&b)
+		return b, err
+	case typ.Is(tftypes.Number):
+		n := new(big.Float)
+		if err := value.As(&n); err != nil {
+			return nil, err
+		}
+		return json.Number(n.Text('f', -1)), nil
+	case typ.Is(tftypes.Object{}), typ.Is(tftypes.Map{}):
+		var attributes map[string]tftypes.Value
+		if err := value.As(&attributes); err != nil {
+			return nil, err
+		}
+		document := make(map[string]any, len(attributes))
+		for key, attribute := range attributes {
+			converted, err := terraformDocument(attribute)
+			if err != nil {
+				return nil, err
+			}
+			document[key] = converted
+		}
+		return document, nil
+	case typ.Is(tftypes.Tuple{}), typ.Is(tftypes.List{}), typ.Is(tftypes.Set{}):
+		var elements []tftypes.Value
+		if err := value.As(&elements); err != nil {
+			return nil, err
+		}
+		document := make([]any, 0, len(elements))
+		for _, element := range elements {
+			converted, err := terraformDocument(element)
+			if err != nil {
+				return nil, err
+			}
+			document = append(document, converted)
+		}
+		return document, nil
+	default:
+		return nil, fmt.Errorf("unsupported payload value of type %s", typ)
+	}
+}
+
+// payloadValue converts a JSON document to the attribute value Terraform
+// would build from the same document written as an HCL expression.
+func payloadValue(document any) (attr.Value, error) {
+	switch value := document.(type) {
+	case nil:
+		return types.DynamicNull(), nil
+	case string:
+		return types.StringValue(value), nil
+	case bool:
+		return types.BoolValue(value), nil
+	case json.Number, float64:
+		n, err := parsePayloadNumber(value)
+		if err != nil {
+			return nil, err
+		}
+		return types.NumberValue(n), nil
+	case map[string]any:
+		attributeTypes := make(map[string]attr.Type, len(value))
+		attributes := make(map[string]attr.Value, len(value))
+		for key, element := range value {
+			converted, err := payloadValue(element)
+			if err != nil {
+				return nil, err
+			}
+			attributeTypes[key] = converted.Type(context.Background())
+			attributes[key] = converted
+		}
+		object, diags := types.ObjectValue(attributeTypes, attributes)
+		if diags.HasError() {
+			return nil, fmt.Errorf("convert payload object: %v", diags)
+		}
+		return object, nil
+	case []any:
+		elementTypes := make([]attr.Type, 0, len(value))
+		elements := make([]attr.Value, 0, len(value))
+		for _, element := range value {
+			converted, err := payloadValue(element)
+			if err != nil {
+				return nil, err
+			}
+			elementTypes = append(elementTypes, converted.Type(context.Background()))
+			elements = append(elements, converted)
+		}
+		tuple, diags := types.TupleValue(elementTypes, elements)
+		if diags.HasError() {
+			return nil, fmt.Errorf("convert payload array: %v", diags)
+		}
+		return tuple, nil
+	default:
+		return nil, fmt.Errorf("unsupported payload value of type %T", document)
+	}
+}
+
+func parsePayloadNumber(value any) (*big.Float, error) {
+	switch n := value.(type) {
+	case json.Number:
+		parsed, _, err := big.ParseFloat(n.String(), 10, payloadNumberPrecision, big.ToNearestEven)
+		if err != nil {
+			return nil, fmt.Errorf("parse number %q: %w", n, err)
+		}
+		return parsed, nil
+	case float64:
+		// Documents decoded without UseNumber hold the nearest float64; its
+		// shortest decimal form is the number that was written.
+		return parsePayloadNumber(json.Number(strconv.FormatFloat(n, 'g', -1, 64)))
+	default:
+		return nil, fmt.Errorf("unsupported number %T", value)
+	}
+}
+
+// decodeJSON decodes JSON text, keeping numbers as json.Number.
+func decodeJSON(raw string) (any, error) {
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON document")
+	}
+	return document, nil
+}
+
+// canonicalJSON encodes a document the way Terraform's jsonencode does:
+// compact, with sorted object keys and numbers written without exponent.
+// Payloads read from the server therefore match the configured string.
+func canonicalJSON(document any) (string, error) {
+	normalized, err := normalizeJSONNumbers(document)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// normalizeJSON rewrites JSON text in canonical form.
+func normalizeJSON(raw string) (string, error) {
+	document, err := decodeJSON(raw)
+	if err != nil {
+		return "", err
+	}
+	return canonicalJSON(document)
+}
+
+func normalizeJSONNumbers(document any) (any, error) {
+	switch value := document.(type) {
+	case json.Number, float64:
+		n, err := parsePayloadNumber(value)
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(n.Text('f', -1)), nil
+	case map[string]any:
+		normalized := make(map[string]any, len(value))
+		for key, element := range value {
+			converted, err := normalizeJSONNumbers(element)
+			if err != nil {
+				return nil, err
+			}
+			normalized[key] = converted
+		}
+		return normalized, nil
+	case []any:
+		normalized := make([]any, len(value))
+		for i, element := range value {
+			converted, err := normalizeJSONNumbers(element)
+			if err != nil {
+				return nil, err
+			}
+			normalized[i] = converted
+		}
+		return normalized, nil
+	default:
+		return document, nil
+	}
+}
+
+// payloadValidator accepts objects and strings holding a JSON object.
+type payloadValidator struct{}
+
+func (payloadValidator) Description(context.Context) string {
+	return "value must be an object or a JSON-encoded object"
+}
+
+func (v payloadValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v payloadValidator) ValidateDynamic(ctx context.Context, req validator.DynamicRequest, resp *validator.DynamicResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() || req.ConfigValue.IsUnderlyingValueNull() {
+		return
+	}
+	raw, err := req.ConfigValue.ToTerraformValue(ctx)
+	if err != nil || !raw.IsFullyKnown() {
+		return
+	}
+	if _, err := (jsonPayload{DynamicValue: req.ConfigValue}).decode(ctx); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid payload", fmt.Sprintf("%s: %s", v.Description(ctx), err))
+	}
+}
+
+// requiresReplaceIfPayloadChanged replaces the resource when the payload
+// describes a different document; switching between the object and the
+// jsonencode form of the same payload is an in-place change.
+func requiresReplaceIfPayloadChanged() planmodifier.Dynamic {
+	return dynamicplanmodifier.RequiresReplaceIf(
+		func(ctx context.Context, req planmodifier.DynamicRequest, resp *dynamicplanmodifier.RequiresReplaceIfFuncResponse) {
+			resp.RequiresReplace = !payloadsEqual(ctx, req.StateValue, req.PlanValue)
+		},
+		"Changing the payload document replaces the resource.",
+		"Changing the payload document replaces the resource.",
+	)
+}
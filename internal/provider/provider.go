@@ -8,92 +8,136 @@ import (
 	"os"
 	"strings"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 const (
-	serverAttr   = "server"
-	tokenAttr    = "token"
-	userAttr     = "user"
-	passwordAttr = "password"
-	EnvToken     = "TOKEN"
-	EnvUser      = "USER"
-	EnvPassword  = "PASSWORD"
+	serverAttr    = "server"
+	tokenAttr     = "token"
+	userAttr      = "user"
+	passwordAttr  = "password"
+	namespaceAttr = "namespace"
+	EnvToken      = "TOKEN"
+	EnvUser       = "USER"
+	EnvPassword   = "PASSWORD"
+	EnvNamespace  = "GRANTORY_NAMESPACE"
+
+	defaultServer = "http://localhost:8080"
 )
 
-// New constructs the Grantory Terraform/OpenTofu provider with its configuration schema.
-func New() *schema.Provider {
-	return &schema.Provider{
-		Schema: map[string]*schema.Schema{
-			serverAttr: {
-				Type:        schema.TypeString,
+var _ provider.Provider = (*grantoryProvider)(nil)
+
+// New constructs the Grantory Terraform/OpenTofu provider.
+func New() provider.Provider {
+	return &grantoryProvider{}
+}
+
+type grantoryProvider struct{}
+
+type providerModel struct {
+	Server    types.String `tfsdk:"server"`
+	Token     types.String `tfsdk:"token"`
+	User      types.String `tfsdk:"user"`
+	Password  types.String `tfsdk:"password"`
+	Namespace types.String `tfsdk:"namespace"`
+}
+
+func (p *grantoryProvider) Metadata(_ context.Context, _ provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "grantory"
+}
+
+func (p *grantoryProvider) Schema(_ context.Context, _ provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			serverAttr: schema.StringAttribute{
 				Optional:    true,
-				Default:     "http://localhost:8080",
-				Description: "URL of the Grantory server (http:// or https://) used for every API interaction. (default: http://localhost:8080)",
+				Description: "URL of the Grantory server (http:// or https://) used for every API interaction. (default: " + defaultServer + ")",
 			},
-			tokenAttr: {
-				Type:        schema.TypeString,
+			tokenAttr: schema.StringAttribute{
 				Optional:    true,
 				Sensitive:   true,
-				DefaultFunc: schema.EnvDefaultFunc(EnvToken, nil),
 				Description: "Bearer token for API requests (env: " + EnvToken + ").",
 			},
-			userAttr: {
-				Type:        schema.TypeString,
+			userAttr: schema.StringAttribute{
 				Optional:    true,
 				Description: "Username for basic auth (env: " + EnvUser + ").",
 			},
-			passwordAttr: {
-				Type:      schema.TypeString,
-				Optional:  true,
-				Sensitive: true,
-				Description: "Password for basic auth (env: " + EnvPassword +
-					").",
+			passwordAttr: schema.StringAttribute{
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Password for basic auth (env: " + EnvPassword + ").",
+			},
+			namespaceAttr: schema.StringAttribute{
+				Optional:    true,
+				Description: "Namespace used by resources and data sources that do not set their own (env: " + EnvNamespace + "). When empty, the server picks the namespace from the token or proxy.",
 			},
 		},
-		ConfigureContextFunc: configureProvider,
-		ResourcesMap: map[string]*schema.Resource{
-			"grantory_host":     resourceHost(),
-			"grantory_request":  resourceRequest(),
-			"grantory_register": resourceRegister(),
-			"grantory_grant":    resourceGrant(),
-		},
-		DataSourcesMap: map[string]*schema.Resource{
-			"grantory_hosts":     dataHosts(),
-			"grantory_requests":  dataRequests(),
-			"grantory_request":   dataRequest(),
-			"grantory_register":  dataRegister(),
-			"grantory_registers": dataRegisters(),
-			"grantory_grants":    dataGrants(),
-			"grantory_grant":     dataGrant(),
-		},
 	}
 }
 
-func configureProvider(ctx context.Context, d *schema.ResourceData) (any, diag.Diagnostics) {
+func (p *grantoryProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var config providerModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client, diags := configureProvider(config)
+	resp.Diagnostics.Append(diags...)
+	if diags.HasError() {
+		return
+	}
+	resp.ResourceData = client
+	resp.DataSourceData = client
+}
+
+func (p *grantoryProvider) Resources(context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		newHostResource,
+		newRequestResource,
+		newRegisterResource,
+		newGrantResource,
+		newWebhookResource,
+		newRequestTypeResource,
+	}
+}
+
+func (p *grantoryProvider) DataSources(context.Context) []func() datasource.DataSource {
+	return []func() datasource.DataSource{
+		newHostsDataSource,
+		newRequestsDataSource,
+		newRequestDataSource,
+		newRegisterDataSource,
+		newRegistersDataSource,
+		newGrantsDataSource,
+		newGrantDataSource,
+	}
+}
+
+// configureProvider builds the API client from the provider configuration,
+// falling back to the environment for unset attributes.
+func configureProvider(config providerModel) (*grantoryClient, diag.Diagnostics) {
 	var diags diag.Diagnostics
 
-	server := d.Get(serverAttr).(string)
+	server := defaultServer
+	if !config.Server.IsNull() {
+		server = config.Server.ValueString()
+	}
 	u, parseDiags := parseServerURL(server)
 	if parseDiags.HasError() {
 		return nil, parseDiags
 	}
 
-	token := strings.TrimSpace(d.Get(tokenAttr).(string))
-	user := strings.TrimSpace(d.Get(userAttr).(string))
-	password := strings.TrimSpace(d.Get(passwordAttr).(string))
+	token := strings.TrimSpace(stringOrEnv(config.Token, EnvToken))
+	user := strings.TrimSpace(config.User.ValueString())
+	password := strings.TrimSpace(config.Password.ValueString())
 
-	userSet := false
-	if _, ok := d.GetOk(userAttr); ok {
-		userSet = true
-	}
-	passwordSet := false
-	if _, ok := d.GetOk(passwordAttr); ok {
-		passwordSet = true
-	}
-
-	if !userSet && !passwordSet {
+	if config.User.IsNull() && config.Password.IsNull() {
 		envUser := strings.TrimSpace(os.Getenv(EnvUser))
 		envPassword := strings.TrimSpace(os.Getenv(EnvPassword))
 		if envUser != "" && envPassword != "" {
@@ -105,19 +149,11 @@ func configureProvider(ctx context.Context, d *schema.ResourceData) (any, diag.D
 	basicProvided := user != "" || password != ""
 
 	if token != "" && basicProvided {
-		diags = append(diags, diag.Diagnostic{
-			Severity: diag.Error,
-			Summary:  "conflicting authentication settings",
-			Detail:   "token and user/password cannot be configured at the same time",
-		})
+		diags.AddError("conflicting authentication settings", "token and user/password cannot be configured at the same time")
 		return nil, diags
 	}
 	if basicProvided && (user == "" || password == "") {
-		diags = append(diags, diag.Diagnostic{
-			Severity: diag.Error,
-			Summary:  "incomplete basic auth credentials",
-			Detail:   "both user and password must be provided for basic auth",
-		})
+		diags.AddError("incomplete basic auth credentials", "both user and password must be provided for basic auth")
 		return nil, diags
 	}
 
@@ -127,45 +163,39 @@ func configureProvider(ctx context.Context, d *schema.ResourceData) (any, diag.D
 		token:      token,
 		user:       user,
 		password:   password,
+		namespace:  strings.TrimSpace(stringOrEnv(config.Namespace, EnvNamespace)),
 	}
 	return client, diags
 }
 
+// stringOrEnv returns the configured value, or the environment variable key
+// when the attribute is not set.
+func stringOrEnv(value types.String, key string) string {
+	if value.IsNull() || value.IsUnknown() {
+		return os.Getenv(key)
+	}
+	return value.ValueString()
+}
+
 func parseServerURL(raw string) (*url.URL, diag.Diagnostics) {
 	var diags diag.Diagnostics
 	if raw == "" {
-		diags = append(diags, diag.Diagnostic{
-			Severity: diag.Error,
-			Summary:  "grantory server address is required",
-			Detail:   "the 'server' attribute must not be empty",
-		})
+		diags.AddError("grantory server address is required", "the 'server' attribute must not be empty")
 		return nil, diags
 	}
 
 	u, err := url.Parse(raw)
 	if err != nil {
-		diags = append(diags, diag.Diagnostic{
-			Severity: diag.Error,
-			Summary:  "invalid grantory server URL",
-			Detail:   err.Error(),
-		})
+		diags.AddError("invalid grantory server URL", err.Error())
 		return nil, diags
 	}
 
 	if u.Scheme != "http" && u.Scheme != "https" {
-		diags = append(diags, diag.Diagnostic{
-			Severity: diag.Error,
-			Summary:  "unsupported grantory server scheme",
-			Detail:   fmt.Sprintf("scheme %q is not supported", u.Scheme),
-		})
+		diags.AddError("unsupported grantory server scheme", fmt.Sprintf("scheme %q is not supported", u.Scheme))
 		return nil, diags
 	}
 	if u.Host == "" {
-		diags = append(diags, diag.Diagnostic{
-			Severity: diag.Error,
-			Summary:  "grantory server host is missing",
-			Detail:   "the URL must include a host",
-		})
+		diags.AddError("grantory server host is missing", "the URL must include a host")
 		return nil, diags
 	}
 
@@ -178,6 +208,20 @@ type grantoryClient struct {
 	token      string
 	user       string
 	password   string
+	// namespace is sent with every API call; empty leaves the choice to the
+	// server.
+	namespace string
+}
+
+// withNamespace returns a client for namespace, or c itself when namespace
+// is empty or already selected.
+func (c *grantoryClient) withNamespace(namespace string) *grantoryClient {
+	if c == nil || namespace == "" || namespace == c.namespace {
+		return c
+	}
+	scoped := *c
+	scoped.namespace = namespace
+	return &scoped
 }
 
 func (c *grantoryClient) baseAddress() string {
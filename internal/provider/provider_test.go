@@ -2,43 +2,42 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfigureProviderValidServer(t *testing.T) {
 	t.Parallel()
 
-	p := New()
-	data := schema.TestResourceDataRaw(t, p.Schema, map[string]any{
-		serverAttr: "https://example.com",
+	client, diags := configureProvider(providerModel{
+		Server: types.StringValue("https://example.com"),
 	})
-
-	client, diags := configureProvider(context.Background(), data)
 	assert.False(t, diags.HasError(), "expected no diagnostics")
-
-	c, ok := client.(*grantoryClient)
-	assert.True(t, ok, "expected grantoryClient")
-	assert.Equal(t, "https://example.com", c.baseAddress(), "base address should match server URI")
+	assert.Equal(t, "https://example.com", client.baseAddress(), "base address should match server URI")
 }
 
 func TestConfigureProviderInvalidServer(t *testing.T) {
 	t.Parallel()
 
-	p := New()
-	data := schema.TestResourceDataRaw(t, p.Schema, map[string]any{
-		serverAttr: "ftp://example.com",
+	client, diags := configureProvider(providerModel{
+		Server: types.StringValue("ftp://example.com"),
 	})
-
-	client, diags := configureProvider(context.Background(), data)
 	assert.Nil(t, client, "expected nil client for invalid server")
 	assert.True(t, diags.HasError(), "expected diagnostics for invalid server")
 
 	found := false
 	for _, diag := range diags {
-		if diag.Summary == "unsupported grantory server scheme" {
+		if diag.Summary() == "unsupported grantory server scheme" {
 			found = true
 			break
 		}
@@ -56,15 +55,12 @@ func TestParseServerURLMissingHost(t *testing.T) {
 func TestConfigureProviderConflictingAuth(t *testing.T) {
 	t.Parallel()
 
-	p := New()
-	data := schema.TestResourceDataRaw(t, p.Schema, map[string]any{
-		serverAttr:   "https://example.com",
-		tokenAttr:    "token",
-		userAttr:     "user",
-		passwordAttr: "pass",
+	client, diags := configureProvider(providerModel{
+		Server:   types.StringValue("https://example.com"),
+		Token:    types.StringValue("token"),
+		User:     types.StringValue("user"),
+		Password: types.StringValue("pass"),
 	})
-
-	client, diags := configureProvider(context.Background(), data)
 	assert.Nil(t, client, "expected nil client when auth conflicts")
 	assert.True(t, diags.HasError(), "expected diag for conflicting auth")
 }
@@ -72,13 +68,10 @@ func TestConfigureProviderConflictingAuth(t *testing.T) {
 func TestConfigureProviderIncompleteBasicAuth(t *testing.T) {
 	t.Parallel()
 
-	p := New()
-	data := schema.TestResourceDataRaw(t, p.Schema, map[string]any{
-		serverAttr: "https://example.com",
-		userAttr:   "user",
+	client, diags := configureProvider(providerModel{
+		Server: types.StringValue("https://example.com"),
+		User:   types.StringValue("user"),
 	})
-
-	client, diags := configureProvider(context.Background(), data)
 	assert.Nil(t, client, "expected nil client when password missing")
 	assert.True(t, diags.HasError(), "expected diag for incomplete auth")
 }
@@ -89,3 +82,526 @@ func TestGrantoryClientBaseAddressEmpty(t *testing.T) {
 	var c *grantoryClient
 	assert.Equal(t, "", c.baseAddress(), "nil client should return empty base address")
 }
+
+func TestConfigureProviderNamespace(t *testing.T) {
+	t.Setenv(EnvNamespace, "from-env")
+
+	client, diags := configureProvider(providerModel{
+		Server: types.StringValue("https://example.com"),
+	})
+	assert.False(t, diags.HasError(), "expected no diagnostics")
+	assert.Equal(t, "from-env", client.namespace, "namespace from env")
+
+	client, diags = configureProvider(providerModel{
+		Server:    types.StringValue("https://example.com"),
+		Namespace: types.StringValue("team-a"),
+	})
+	assert.False(t, diags.HasError(), "expected no diagnostics")
+	assert.Equal(t, "team-a", client.namespace, "namespace from configuration")
+}
+
+func TestProviderServesOverProtocol5(t *testing.T) {
+	t.Parallel()
+
+	p := newTestProvider(t, "http://localhost:8080", nil)
+	for _, name := range []string{"grantory_host", "grantory_request", "grantory_register", "grantory_grant", "grantory_webhook", "grantory_request_type"} {
+		assert.Contains(t, p.schemas.ResourceSchemas, name, "resource %s", name)
+	}
+	for _, name := range []string{"grantory_hosts", "grantory_requests", "grantory_request", "grantory_register", "grantory_registers", "grantory_grants", "grantory_grant"} {
+		assert.Contains(t, p.schemas.DataSourceSchemas, name, "data source %s", name)
+	}
+}
+
+// testProvider drives the provider through the plugin protocol, the way
+// Terraform does, so tests cover schemas, plan modifiers, semantic equality
+// and state upgrades along with the CRUD functions.
+type testProvider struct {
+	t       *testing.T
+	server  tfprotov5.ProviderServer
+	schemas *tfprotov5.GetProviderSchemaResponse
+}
+
+// testUnknown stands for an unknown value in values returned by the harness.
+type testUnknown struct{}
+
+func newTestProvider(t *testing.T, serverURL string, config map[string]any) *testProvider {
+	t.Helper()
+
+	server, err := providerserver.NewProtocol5WithError(New())()
+	require.NoError(t, err)
+	schemas, err := server.GetProviderSchema(context.Background(), &tfprotov5.GetProviderSchemaRequest{})
+	require.NoError(t, err)
+	requireNoErrors(t, schemas.Diagnostics)
+
+	p := &testProvider{t: t, server: server, schemas: schemas}
+	values := map[string]any{serverAttr: serverURL}
+	for key, value := range config {
+		values[key] = value
+	}
+	resp, err := server.ConfigureProvider(context.Background(), &tfprotov5.ConfigureProviderRequest{
+		Config: p.dynamicValue(schemas.Provider.ValueType(), values),
+	})
+	require.NoError(t, err)
+	requireNoErrors(t, resp.Diagnostics)
+	return p
+}
+
+func (p *testProvider) resourceType(typeName string) tftypes.Type {
+	p.t.Helper()
+	schema, ok := p.schemas.ResourceSchemas[typeName]
+	require.True(p.t, ok, "unknown resource %s", typeName)
+	return schema.ValueType()
+}
+
+func (p *testProvider) dataSourceType(typeName string) tftypes.Type {
+	p.t.Helper()
+	schema, ok := p.schemas.DataSourceSchemas[typeName]
+	require.True(p.t, ok, "unknown data source %s", typeName)
+	return schema.ValueType()
+}
+
+func (p *testProvider) dynamicValue(typ tftypes.Type, value any) *tfprotov5.DynamicValue {
+	p.t.Helper()
+	var raw tftypes.Value
+	if v, ok := value.(tftypes.Value); ok {
+		raw = v
+	} else {
+		raw = toTerraformValue(p.t, typ, value)
+	}
+	dv, err := tfprotov5.NewDynamicValue(typ, raw)
+	require.NoError(p.t, err)
+	return &dv
+}
+
+func (p *testProvider) unmarshal(typ tftypes.Type, dv *tfprotov5.DynamicValue) tftypes.Value {
+	p.t.Helper()
+	if dv == nil {
+		return tftypes.NewValue(typ, nil)
+	}
+	value, err := dv.Unmarshal(typ)
+	require.NoError(p.t, err)
+	return value
+}
+
+// testState is a resource instance as Terraform tracks it between
+// operations.
+type testState struct {
+	value   tftypes.Value
+	private []byte
+}
+
+// get returns the attribute name of the instance as Go values.
+func (s testState) get(name string) any {
+	return attributeOf(s.value, name)
+}
+
+func (s testState) isNull() bool {
+	return s.value.IsNull()
+}
+
+// stateOf builds the state of an instance with the given attributes.
+func (p *testProvider) stateOf(typeName string, values map[string]any) testState {
+	p.t.Helper()
+	return testState{value: toTerraformValue(p.t, p.resourceType(typeName), values)}
+}
+
+func (p *testProvider) validate(typeName string, config map[string]any) []*tfprotov5.Diagnostic {
+	p.t.Helper()
+	resp, err := p.server.ValidateResourceTypeConfig(context.Background(), &tfprotov5.ValidateResourceTypeConfigRequest{
+		TypeName: typeName,
+		Config:   p.dynamicValue(p.resourceType(typeName), config),
+	})
+	require.NoError(p.t, err)
+	return resp.Diagnostics
+}
+
+// plan plans moving the instance prior (nil when creating it) to config.
+func (p *testProvider) plan(typeName string, prior *testState, config map[string]any) *tfprotov5.PlanResourceChangeResponse {
+	p.t.Helper()
+	typ := p.resourceType(typeName)
+	priorValue := tftypes.NewValue(typ, nil)
+	var priorPrivate []byte
+	if prior != nil {
+		priorValue = prior.value
+		priorPrivate = prior.private
+	}
+	configValue := toTerraformValue(p.t, typ, config)
+
+	resp, err := p.server.PlanResourceChange(context.Background(), &tfprotov5.PlanResourceChangeRequest{
+		TypeName:         typeName,
+		PriorState:       p.dynamicValue(typ, priorValue),
+		ProposedNewState: p.dynamicValue(typ, proposedNewState(p.t, p.schemas.ResourceSchemas[typeName], priorValue, configValue)),
+		Config:           p.dynamicValue(typ, configValue),
+		PriorPrivate:     priorPrivate,
+	})
+	require.NoError(p.t, err)
+	return resp
+}
+
+// apply plans and applies config, returning the new state of the instance.
+func (p *testProvider) apply(typeName string, prior *testState, config map[string]any) (testState, []*tfprotov5.Diagnostic) {
+	p.t.Helper()
+	if diags := p.validate(typeName, config); hasError(diags) {
+		return testState{}, diags
+	}
+	planned := p.plan(typeName, prior, config)
+	if hasError(planned.Diagnostics) {
+		return testState{}, planned.Diagnostics
+	}
+
+	typ := p.resourceType(typeName)
+	priorValue := tftypes.NewValue(typ, nil)
+	if prior != nil {
+		priorValue = prior.value
+	}
+	resp, err := p.server.ApplyResourceChange(context.Background(), &tfprotov5.ApplyResourceChangeRequest{
+		TypeName:       typeName,
+		PriorState:     p.dynamicValue(typ, priorValue),
+		PlannedState:   planned.PlannedState,
+		Config:         p.dynamicValue(typ, config),
+		PlannedPrivate: planned.PlannedPrivate,
+	})
+	require.NoError(p.t, err)
+	return testState{value: p.unmarshal(typ, resp.NewState), private: resp.Private}, append(planned.Diagnostics, resp.Diagnostics...)
+}
+
+// mustApply is apply for steps that must succeed.
+func (p *testProvider) mustApply(typeName string, prior *testState, config map[string]any) testState {
+	p.t.Helper()
+	state, diags := p.apply(typeName, prior, config)
+	requireNoErrors(p.t, diags)
+	return state
+}
+
+func (p *testProvider) read(typeName string, state testState) (testState, []*tfprotov5.Diagnostic) {
+	p.t.Helper()
+	typ := p.resourceType(typeName)
+	resp, err := p.server.ReadResource(context.Background(), &tfprotov5.ReadResourceRequest{
+		TypeName:     typeName,
+		CurrentState: p.dynamicValue(typ, state.value),
+		Private:      state.private,
+	})
+	require.NoError(p.t, err)
+	return testState{value: p.unmarshal(typ, resp.NewState), private: resp.Private}, resp.Diagnostics
+}
+
+func (p *testProvider) destroy(typeName string, state testState) []*tfprotov5.Diagnostic {
+	p.t.Helper()
+	typ := p.resourceType(typeName)
+	resp, err := p.server.ApplyResourceChange(context.Background(), &tfprotov5.ApplyResourceChangeRequest{
+		TypeName:     typeName,
+		PriorState:   p.dynamicValue(typ, state.value),
+		PlannedState: p.dynamicValue(typ, tftypes.NewValue(typ, nil)),
+		Config:       p.dynamicValue(typ, tftypes.NewValue(typ, nil)),
+	})
+	require.NoError(p.t, err)
+	return resp.Diagnostics
+}
+
+// importState imports id; the imported state still needs a read, as
+// Terraform does right after the import.
+func (p *testProvider) importState(typeName, id string) (testState, []*tfprotov5.Diagnostic) {
+	p.t.Helper()
+	resp, err := p.server.ImportResourceState(context.Background(), &tfprotov5.ImportResourceStateRequest{
+		TypeName: typeName,
+		ID:       id,
+	})
+	require.NoError(p.t, err)
+	if hasError(resp.Diagnostics) {
+		return testState{}, resp.Diagnostics
+	}
+	require.Len(p.t, resp.ImportedResources, 1)
+	imported := resp.ImportedResources[0]
+	return testState{value: p.unmarshal(p.resourceType(typeName), imported.State), private: imported.Private}, resp.Diagnostics
+}
+
+// upgrade upgrades raw JSON state written with schema version.
+func (p *testProvider) upgrade(typeName string, version int64, rawJSON string) (testState, []*tfprotov5.Diagnostic) {
+	p.t.Helper()
+	typ := p.resourceType(typeName)
+	resp, err := p.server.UpgradeResourceState(context.Background(), &tfprotov5.UpgradeResourceStateRequest{
+		TypeName: typeName,
+		Version:  version,
+		RawState: &tfprotov5.RawState{JSON: []byte(rawJSON)},
+	})
+	require.NoError(p.t, err)
+	return testState{value: p.unmarshal(typ, resp.UpgradedState)}, resp.Diagnostics
+}
+
+func (p *testProvider) readDataSource(typeName string, config map[string]any) (testState, []*tfprotov5.Diagnostic) {
+	p.t.Helper()
+	typ := p.dataSourceType(typeName)
+	validated, err := p.server.ValidateDataSourceConfig(context.Background(), &tfprotov5.ValidateDataSourceConfigRequest{
+		TypeName: typeName,
+		Config:   p.dynamicValue(typ, config),
+	})
+	require.NoError(p.t, err)
+	if hasError(validated.Diagnostics) {
+		return testState{}, validated.Diagnostics
+	}
+	resp, err := p.server.ReadDataSource(context.Background(), &tfprotov5.ReadDataSourceRequest{
+		TypeName: typeName,
+		Config:   p.dynamicValue(typ, config),
+	})
+	require.NoError(p.t, err)
+	return testState{value: p.unmarshal(typ, resp.State)}, resp.Diagnostics
+}
+
+// plannedChanges lists the attributes a plan changes, and whether any of
+// them requires replacing the instance.
+func plannedChanges(t *testing.T, prior testState, resp *tfprotov5.PlanResourceChangeResponse, typ tftypes.Type) ([]string, bool) {
+	t.Helper()
+	requireNoErrors(t, resp.Diagnostics)
+	planned, err := resp.PlannedState.Unmarshal(typ)
+	require.NoError(t, err)
+
+	var attributes map[string]tftypes.Value
+	require.NoError(t, planned.As(&attributes))
+	var changed []string
+	for name, value := range attributes {
+		if !value.Equal(attributeValueOf(prior.value, name)) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed, len(resp.RequiresReplace) > 0
+}
+
+// proposedNewState merges config and prior the way Terraform proposes a new
+// state: configured values win, computed attributes keep their prior value.
+func proposedNewState(t *testing.T, schema *tfprotov5.Schema, prior, config tftypes.Value) tftypes.Value {
+	t.Helper()
+	var configAttributes map[string]tftypes.Value
+	require.NoError(t, config.As(&configAttributes))
+	priorAttributes := map[string]tftypes.Value{}
+	if !prior.IsNull() {
+		require.NoError(t, prior.As(&priorAttributes))
+	}
+
+	proposed := make(map[string]tftypes.Value, len(configAttributes))
+	for _, attribute := range schema.Block.Attributes {
+		value := configAttributes[attribute.Name]
+		if value.IsNull() && attribute.Computed {
+			if priorValue, ok := priorAttributes[attribute.Name]; ok {
+				value = priorValue
+			}
+		}
+		proposed[attribute.Name] = value
+	}
+	return tftypes.NewValue(config.Type(), proposed)
+}
+
+// toTerraformValue converts Go values to a value of typ. Missing object
+// attributes are null, and dynamic values take the type of their value.
+func toTerraformValue(t *testing.T, typ tftypes.Type, value any) tftypes.Value {
+	t.Helper()
+	if v, ok := value.(tftypes.Value); ok {
+		return v
+	}
+	if _, ok := value.(testUnknown); ok {
+		return tftypes.NewValue(typ, tftypes.UnknownValue)
+	}
+	if value == nil {
+		return tftypes.NewValue(typ, nil)
+	}
+
+	switch {
+	case typ.Is(tftypes.DynamicPseudoType):
+		return toTerraformValue(t, inferType(t, value), value)
+	case typ.Is(tftypes.String), typ.Is(tftypes.Bool):
+		return tftypes.NewValue(typ, value)
+	case typ.Is(tftypes.Number):
+		switch n := value.(type) {
+		case int:
+			return tftypes.NewValue(typ, big.NewFloat(float64(n)))
+		case float64:
+			return tftypes.NewValue(typ, big.NewFloat(n))
+		}
+	case typ.Is(tftypes.Map{}):
+		elementType := typ.(tftypes.Map).ElementType
+		elements := map[string]tftypes.Value{}
+		switch m := value.(type) {
+		case map[string]string:
+			for key, element := range m {
+				elements[key] = toTerraformValue(t, elementType, element)
+			}
+		case map[string]any:
+			for key, element := range m {
+				elements[key] = toTerraformValue(t, elementType, element)
+			}
+		}
+		return tftypes.NewValue(typ, elements)
+	case typ.Is(tftypes.Set{}), typ.Is(tftypes.List{}):
+		var elementType tftypes.Type
+		if set, ok := typ.(tftypes.Set); ok {
+			elementType = set.ElementType
+		} else {
+			elementType = typ.(tftypes.List).ElementType
+		}
+		var elements []tftypes.Value
+		switch s := value.(type) {
+		case []string:
+			for _, element := range s {
+				elements = append(elements, toTerraformValue(t, elementType, element))
+			}
+		case []any:
+			for _, element := range s {
+				elements = append(elements, toTerraformValue(t, elementType, element))
+			}
+		}
+		return tftypes.NewValue(typ, elements)
+	case typ.Is(tftypes.Tuple{}):
+		elementTypes := typ.(tftypes.Tuple).ElementTypes
+		elements := make([]tftypes.Value, 0, len(elementTypes))
+		for i, element := range value.([]any) {
+			elements = append(elements, toTerraformValue(t, elementTypes[i], element))
+		}
+		return tftypes.NewValue(typ, elements)
+	case typ.Is(tftypes.Object{}):
+		attributeTypes := typ.(tftypes.Object).AttributeTypes
+		values, ok := value.(map[string]any)
+		require.True(t, ok, "object value must be a map, got %T", value)
+		for key := range values {
+			_, known := attributeTypes[key]
+			require.True(t, known, "unexpected attribute %q", key)
+		}
+		attributes := make(map[string]tftypes.Value, len(attributeTypes))
+		for name, attributeType := range attributeTypes {
+			attributes[name] = toTerraformValue(t, attributeType, values[name])
+		}
+		return tftypes.NewValue(typ, attributes)
+	}
+	require.FailNow(t, fmt.Sprintf("cannot convert %T to %s", value, typ))
+	return tftypes.Value{}
+}
+
+// inferType returns the type Terraform gives value written as an HCL
+// expression.
+func inferType(t *testing.T, value any) tftypes.Type {
+	t.Helper()
+	switch v := value.(type) {
+	case string:
+		return tftypes.String
+	case bool:
+		return tftypes.Bool
+	case int, float64:
+		return tftypes.Number
+	case map[string]any:
+		attributeTypes := make(map[string]tftypes.Type, len(v))
+		for key, element := range v {
+			attributeTypes[key] = inferType(t, element)
+		}
+		return tftypes.Object{AttributeTypes: attributeTypes}
+	case []any:
+		elementTypes := make([]tftypes.Type, 0, len(v))
+		for _, element := range v {
+			elementTypes = append(elementTypes, inferType(t, element))
+		}
+		return tftypes.Tuple{ElementTypes: elementTypes}
+	}
+	require.FailNow(t, fmt.Sprintf("cannot infer the type of %T", value))
+	return nil
+}
+
+// fromTerraformValue converts a value to Go: objects and maps become
+// map[string]any, collections []any, whole numbers int and other numbers
+// float64.
+func fromTerraformValue(value tftypes.Value) any {
+	if !value.IsKnown() {
+		return testUnknown{}
+	}
+	if value.IsNull() {
+		return nil
+	}
+	typ := value.Type()
+	switch {
+	case typ.Is(tftypes.String):
+		var s string
+		_ = value.As(&s)
+		return s
+	case typ.Is(tftypes.Bool):
+		var b bool
+		_ = value.As(&b)
+		return b
+	case typ.Is(tftypes.Number):
+		n := new(big.Float)
+		_ = value.As(&n)
+		if n.IsInt() {
+			i, _ := n.Int64()
+			return int(i)
+		}
+		f, _ := n.Float64()
+		return f
+	case typ.Is(tftypes.Object{}), typ.Is(tftypes.Map{}):
+		var attributes map[string]tftypes.Value
+		_ = value.As(&attributes)
+		result := make(map[string]any, len(attributes))
+		for key, attribute := range attributes {
+			result[key] = fromTerraformValue(attribute)
+		}
+		return result
+	default:
+		var elements []tftypes.Value
+		_ = value.As(&elements)
+		result := make([]any, 0, len(elements))
+		for _, element := range elements {
+			result = append(result, fromTerraformValue(element))
+		}
+		return result
+	}
+}
+
+func attributeValueOf(object tftypes.Value, name string) tftypes.Value {
+	var attributes map[string]tftypes.Value
+	if object.IsNull() || object.As(&attributes) != nil {
+		return tftypes.Value{}
+	}
+	return attributes[name]
+}
+
+func attributeOf(object tftypes.Value, name string) any {
+	value := attributeValueOf(object, name)
+	if value.Type() == nil {
+		return nil
+	}
+	return fromTerraformValue(value)
+}
+
+func hasError(diags []*tfprotov5.Diagnostic) bool {
+	for _, diag := range diags {
+		if diag.Severity == tfprotov5.DiagnosticSeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// errorSummaries returns the summaries of the error diagnostics.
+func errorSummaries(diags []*tfprotov5.Diagnostic) []string {
+	var summaries []string
+	for _, diag := range diags {
+		if diag.Severity == tfprotov5.DiagnosticSeverityError {
+			summaries = append(summaries, diag.Summary)
+		}
+	}
+	return summaries
+}
+
+// diagnosticsText joins the summaries and details of diags, for matching
+// error messages.
+func diagnosticsText(diags []*tfprotov5.Diagnostic) string {
+	var lines []string
+	for _, diag := range diags {
+		lines = append(lines, diag.Summary+": "+diag.Detail)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func requireNoErrors(t *testing.T, diags []*tfprotov5.Diagnostic) {
+	t.Helper()
+	for _, diag := range diags {
+		if diag.Severity == tfprotov5.DiagnosticSeverityError {
+			require.FailNow(t, "unexpected error diagnostic", "%s: %s", diag.Summary, diag.Detail)
+		}
+	}
+}
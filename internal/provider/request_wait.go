@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const (
+	defaultWaitTimeout = "10m"
+
+	// grantEventWait is how long a single change feed call may be held open
+	// by the server; it stays below the server's maximum of 60s.
+	grantEventWait = 30 * time.Second
+	// grantPollInterval paces the polling of servers without a change feed.
+	grantPollInterval = 5 * time.Second
+)
+
+var (
+	errGrantWaitTimeout = errors.New("timed out waiting for a grant")
+	errRequestRejected  = errors.New("request will not be granted")
+)
+
+// awaitRequestGrant blocks until req has a grant when wait is set, and
+// returns the request as last seen. A denied, revoked or expired request and
+// a timeout are reported with the given severity: errors while applying,
+// warnings while refreshing so that plans and destroys keep working.
+func awaitRequestGrant(ctx context.Context, client *grantoryClient, req apiRequest, wait types.Bool, waitTimeout types.String, severity diag.Severity) (apiRequest, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if !wait.ValueBool() {
+		return req, diags
+	}
+	rawTimeout := defaultWaitTimeout
+	if !waitTimeout.IsNull() && !waitTimeout.IsUnknown() {
+		rawTimeout = waitTimeout.ValueString()
+	}
+	timeout, err := parseDuration(rawTimeout)
+	if err != nil {
+		diags.AddError("invalid wait_timeout", err.Error())
+		return req, diags
+	}
+
+	latest, err := waitForGrant(ctx, client, req, timeout)
+	switch {
+	case err == nil:
+		return latest, diags
+	case errors.Is(err, errRequestRejected):
+		detail := fmt.Sprintf("Request %s was %s and will not be granted.", latest.ID, latest.Status)
+		if latest.StatusReason != "" {
+			detail += " Reason: " + latest.StatusReason
+		}
+		diags.Append(newDiagnostic(severity, fmt.Sprintf("request %s", latest.Status), detail))
+	case errors.Is(err, errGrantWaitTimeout):
+		diags.Append(newDiagnostic(severity, "timed out waiting for grant",
+			err.Error()+". The request stays pending; a later apply or refresh picks the grant up once it exists."))
+	default:
+		diags.AddError("failed to wait for grant", err.Error())
+	}
+	return latest, diags
+}
+
+func newDiagnostic(severity diag.Severity, summary, detail string) diag.Diagnostic {
+	if severity == diag.SeverityWarning {
+		return diag.NewWarningDiagnostic(summary, detail)
+	}
+	return diag.NewErrorDiagnostic(summary, detail)
+}
+
+// waitForGrant re-reads req until it has a grant, is rejected, or timeout
+// elapses. Changes to the request are watched on the server's change feed;
+// servers without one are polled instead.
+func waitForGrant(ctx context.Context, client *grantoryClient, req apiRequest, timeout time.Duration) (apiRequest, error) {
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	timedOut := func() error {
+		return fmt.Errorf("%w: request %s has no grant after %s", errGrantWaitTimeout, req.ID, time.Since(started).Round(time.Second))
+	}
+
+	// Take the cursor before reading the request, so a grant issued in
+	// between still shows up on the feed.
+	cursor := ""
+	if page, err := client.listEvents(ctx, "latest", "requests", 0); err == nil {
+		cursor = strconv.FormatInt(page.NextCursor, 10)
+	}
+	latest, err := client.getRequest(ctx, req.ID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return req, timedOut()
+		}
+		return req, fmt.Errorf("read request %s while waiting for a grant: %w", req.ID, err)
+	}
+	req = latest
+
+	for {
+		if req.HasGrant {
+			return req, nil
+		}
+		switch req.Status {
+		case "denied", "revoked", "expired":
+			return req, fmt.Errorf("%w: request %s was %s", errRequestRejected, req.ID, req.Status)
+		}
+
+		if cursor != "" {
+			page, err := client.listEvents(ctx, cursor, "requests", eventWait(ctx))
+			if err == nil {
+				cursor = strconv.FormatInt(page.NextCursor, 10)
+				if !eventsMention(page.Events, req.ID) && ctx.Err() == nil {
+					continue
+				}
+			} else if ctx.Err() == nil {
+				// Fall back to polling when the feed goes away, for
+				// example after the namespace was restored.
+				cursor = ""
+			}
+		} else {
+			select {
+			case <-ctx.Done():
+			case <-time.After(grantPollInterval):
+			}
+		}
+		if ctx.Err() != nil {
+			return req, timedOut()
+		}
+
+		latest, err := client.getRequest(ctx, req.ID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return req, timedOut()
+			}
+			return req, fmt.Errorf("read request %s while waiting for a grant: %w", req.ID, err)
+		}
+		req = latest
+	}
+}
+
+// eventWait returns how long the next change feed call may wait: the
+// remaining time rounded up to a second, capped at grantEventWait.
+func eventWait(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return grantEventWait
+	}
+	remaining := time.Until(deadline).Truncate(time.Second) + time.Second
+	return min(remaining, grantEventWait)
+}
+
+func eventsMention(events []apiEvent, requestID string) bool {
+	for _, event := range events {
+		if event.ResourceID == requestID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForGrantWatchesEventFeed(t *testing.T) {
+	t.Parallel()
+
+	handler := &grantWaitTestHandler{
+		request: apiRequest{ID: testRequestID, HostID: "host-1", Status: "pending"},
+		feed:    true,
+		onPoll: func(h *grantWaitTestHandler, poll int) []apiEvent {
+			if poll == 1 {
+				return []apiEvent{{ResourceType: "requests", ResourceID: "req-other"}}
+			}
+			h.request.HasGrant = true
+			h.request.Status = "approved"
+			h.request.Grant = &apiRequestGrant{GrantID: "grant-1", Payload: map[string]any{"payload": map[string]any{"password": "secret"}}}
+			return []apiEvent{{ResourceType: "requests", ResourceID: testRequestID}}
+		},
+	}
+	client := handler.client(t)
+
+	req, err := waitForGrant(context.Background(), client, apiRequest{ID: testRequestID}, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, req.HasGrant)
+	assert.Equal(t, map[string]any{"password": "secret"}, extractGrantPayload(req))
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	assert.Equal(t, []string{"latest", "1", "2"}, handler.cursors, "the feed is followed from its newest event")
+	assert.Equal(t, 2, handler.reads, "the request is only re-read when an event names it")
+}
+
+func TestWaitForGrantRejected(t *testing.T) {
+	t.Parallel()
+
+	handler := &grantWaitTestHandler{
+		request: apiRequest{ID: testRequestID, HostID: "host-1", Status: "denied", StatusReason: "quota exceeded"},
+		feed:    true,
+	}
+	client := handler.client(t)
+
+	_, err := waitForGrant(context.Background(), client, apiRequest{ID: testRequestID}, time.Minute)
+	assert.ErrorIs(t, err, errRequestRejected)
+
+	p := newTestProvider(t, client.baseAddress(), nil)
+	prior := p.stateOf("grantory_request", map[string]any{
+		"id":             testRequestID,
+		"host_id":        "host-1",
+		"wait_for_grant": false,
+		"wait_timeout":   defaultWaitTimeout,
+	})
+	_, diags := p.apply("grantory_request", &prior, map[string]any{
+		"host_id":        "host-1",
+		"wait_for_grant": true,
+		"wait_timeout":   "1s",
+	})
+	require.Len(t, diags, 1)
+	assert.Equal(t, tfprotov5.DiagnosticSeverityError, diags[0].Severity, "applies fail on denial")
+	assert.Equal(t, "request denied", diags[0].Summary)
+	assert.Contains(t, diags[0].Detail, "quota exceeded")
+
+	handler.mu.Lock()
+	handler.request.Status = "pending"
+	handler.mu.Unlock()
+	waiting := p.stateOf("grantory_request", map[string]any{
+		"id":             testRequestID,
+		"host_id":        "host-1",
+		"wait_for_grant": true,
+		"wait_timeout":   "1s",
+	})
+	_, diags = p.read("grantory_request", waiting)
+	require.Len(t, diags, 1)
+	assert.Equal(t, tfprotov5.DiagnosticSeverityWarning, diags[0].Severity, "refreshes only warn when the wait times out")
+	assert.Equal(t, "timed out waiting for grant", diags[0].Summary)
+}
+
+func TestWaitForGrantTimeout(t *testing.T) {
+	t.Parallel()
+
+	for name, feed := range map[string]bool{"feed": true, "polling": false} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &grantWaitTestHandler{
+				request: apiRequest{ID: testRequestID, HostID: "host-1", Status: "pending"},
+				feed:    feed,
+			}
+			client := handler.client(t)
+
+			started := time.Now()
+			_, err := waitForGrant(context.Background(), client, apiRequest{ID: testRequestID}, 500*time.Millisecond)
+			assert.ErrorIs(t, err, errGrantWaitTimeout)
+			assert.Less(t, time.Since(started), 5*time.Second, "waiting stops at the timeout")
+		})
+	}
+}
+
+func TestResourceRequestWaitForGrant(t *testing.T) {
+	t.Parallel()
+
+	handler := &grantWaitTestHandler{
+		request: apiRequest{ID: testRequestID, HostID: "host-1", Status: "pending"},
+		feed:    true,
+		onPoll: func(h *grantWaitTestHandler, _ int) []apiEvent {
+			h.request.HasGrant = true
+			h.request.Status = "approved"
+			h.request.GrantID = "grant-1"
+			h.request.Grant = &apiRequestGrant{GrantID: "grant-1", Payload: map[string]any{"payload": map[string]any{"host": "db.internal"}}}
+			return []apiEvent{{ResourceType: "requests", ResourceID: testRequestID}}
+		},
+	}
+	client := handler.client(t)
+
+	p := newTestProvider(t, client.baseAddress(), nil)
+
+	planned := p.plan("grantory_request", nil, map[string]any{"host_id": "host-1"})
+	requireNoErrors(t, planned.Diagnostics)
+	plannedState := p.unmarshal(p.resourceType("grantory_request"), planned.PlannedState)
+	assert.Equal(t, false, attributeOf(plannedState, "wait_for_grant"))
+	assert.Equal(t, defaultWaitTimeout, attributeOf(plannedState, "wait_timeout"))
+
+	state, diags := p.apply("grantory_request", nil, map[string]any{
+		"host_id":        "host-1",
+		"wait_for_grant": true,
+		"wait_timeout":   "1m",
+	})
+	require.False(t, hasError(diags), "create should wait for the grant")
+	assert.Equal(t, true, state.get("has_grant"))
+	assert.Equal(t, "grant-1", state.get("grant_id"))
+	assert.JSONEq(t, `{"host":"db.internal"}`, state.get("grant_payload").(string), "the grant is available after a single apply")
+}
+
+// grantWaitTestHandler serves one request and a change feed. onPoll runs
+// for every long poll after the initial since=latest call and returns the
+// events of that poll; without it polls answer with no events.
+type grantWaitTestHandler struct {
+	mu      sync.Mutex
+	request apiRequest
+	feed    bool
+	onPoll  func(h *grantWaitTestHandler, poll int) []apiEvent
+	cursors []string
+	reads   int
+}
+
+func (h *grantWaitTestHandler) client(t *testing.T) *grantoryClient {
+	t.Helper()
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+	return &grantoryClient{
+		baseURL:    mustParseURL(t, server.URL),
+		httpClient: server.Client(),
+	}
+}
+
+func (h *grantWaitTestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/requests":
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(h.request)
+	case r.Method == http.MethodGet && r.URL.Path == "/requests/"+h.request.ID:
+		h.reads++
+		_ = json.NewEncoder(w).Encode(h.request)
+	case r.Method == http.MethodGet && r.URL.Path == "/events" && h.feed:
+		since := r.URL.Query().Get("since")
+		h.cursors = append(h.cursors, since)
+		page := apiEventList{NextCursor: int64(len(h.cursors))}
+		if since != "latest" {
+			if h.onPoll != nil {
+				page.Events = h.onPoll(h, len(h.cursors)-1)
+			} else {
+				// Stand in for the server holding the poll open.
+				time.Sleep(50 * time.Millisecond)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	default:
+		http.NotFound(w, r)
+	}
+}
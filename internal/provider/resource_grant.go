@@ -6,111 +6,269 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func resourceGrant() *schema.Resource {
-	return &schema.Resource{
-		Schema: map[string]*schema.Schema{
-			"request_id": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "Request identifier that owns this grant.",
-				ForceNew:    true,
+var (
+	_ resource.ResourceWithConfigure    = (*grantResource)(nil)
+	_ resource.ResourceWithImportState  = (*grantResource)(nil)
+	_ resource.ResourceWithUpgradeState = (*grantResource)(nil)
+)
+
+func newGrantResource() resource.Resource {
+	return &grantResource{}
+}
+
+type grantResource struct {
+	client *grantoryClient
+}
+
+type grantResourceModel struct {
+	ID        types.String `tfsdk:"id"`
+	Namespace types.String `tfsdk:"namespace"`
+	RequestID types.String `tfsdk:"request_id"`
+	Payload   jsonPayload  `tfsdk:"payload"`
+	Labels    types.Map    `tfsdk:"labels"`
+	TTL       types.String `tfsdk:"ttl"`
+	ExpiresAt types.String `tfsdk:"expires_at"`
+}
+
+// grantResourceModelV0 is the schema version 0 state, see upgradePayload.
+type grantResourceModelV0 struct {
+	ID        types.String `tfsdk:"id"`
+	Namespace types.String `tfsdk:"namespace"`
+	RequestID types.String `tfsdk:"request_id"`
+	Payload   types.String `tfsdk:"payload"`
+	Labels    types.Map    `tfsdk:"labels"`
+	TTL       types.String `tfsdk:"ttl"`
+	ExpiresAt types.String `tfsdk:"expires_at"`
+}
+
+func (r *grantResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_grant"
+}
+
+func (r *grantResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 1,
+		Attributes: map[string]schema.Attribute{
+			"id":          idAttribute("Identifier of the grant."),
+			namespaceAttr: resourceNamespaceAttribute("grant"),
+			"request_id": schema.StringAttribute{
+				Required:      true,
+				Description:   "Request identifier that owns this grant.",
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
 			},
-			"payload": {
-				Type:        schema.TypeString,
+			"payload": schema.DynamicAttribute{
+				CustomType:    jsonPayloadType{},
+				Optional:      true,
+				Description:   "Payload delivered by the grant when a request is approved, as an object or a JSON-encoded string.",
+				Validators:    []validator.Dynamic{payloadValidator{}},
+				PlanModifiers: []planmodifier.Dynamic{requiresReplaceIfPayloadChanged()},
+			},
+			"labels": schema.MapAttribute{
+				ElementType: types.StringType,
 				Optional:    true,
-				ForceNew:    true,
-				Description: "JSON-encoded payload delivered by the grant when a request is approved.",
+				Description: "Optional labels that tag the grant, such as the pipeline or policy that issued it.",
 			},
+			"ttl":        ttlAttribute("grant", true),
+			"expires_at": expiresAtAttribute("grant"),
 		},
-		CreateContext: resourceGrantCreate,
-		ReadContext:   resourceGrantRead,
-		DeleteContext: resourceGrantDelete,
-	}
-}
-
-func resourceGrantCreate(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-
-	var grantPayload map[string]any
-	if raw, ok := d.GetOk("payload"); ok {
-		if payloadString, _ := raw.(string); payloadString != "" {
-			parsed, err := parseJSONString(payloadString)
-			if err != nil {
-				return diag.Diagnostics{{
-					Severity: diag.Error,
-					Summary:  "invalid grant payload",
-					Detail:   err.Error(),
-				}}
-			}
-			grantPayload = parsed
-		}
 	}
+}
 
-	created, err := client.createGrant(ctx, apiGrantCreatePayload{
-		RequestID: d.Get("request_id").(string),
+func (r *grantResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = providerClient(req.ProviderData, &resp.Diagnostics)
+}
+
+func (r *grantResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan grantResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	grantPayload, err := plan.Payload.decode(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("invalid grant payload", err.Error())
+		return
+	}
+	labels, diags := expandLabels(ctx, plan.Labels)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	created, err := clientFor(r.client, plan.Namespace).createGrant(ctx, apiGrantCreatePayload{
+		RequestID: plan.RequestID.ValueString(),
 		Payload:   grantPayload,
+		Labels:    labels,
+		TTL:       plan.TTL.ValueString(),
 	})
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to create grant", err.Error())
+		return
 	}
 
-	d.SetId(created.ID)
-	return resourceGrantRefresh(ctx, d, created)
+	resp.Diagnostics.Append(plan.refresh(created)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func resourceGrantRead(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-	grantID := d.Id()
-	if grantID == "" {
-		return nil
+func (r *grantResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state grantResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if state.ID.ValueString() == "" {
+		return
 	}
 
-	grant, err := client.getGrant(ctx, grantID)
+	grant, err := clientFor(r.client, state.Namespace).getGrant(ctx, state.ID.ValueString())
 	if err != nil {
 		if errors.Is(err, errResourceNotFound) {
-			d.SetId("")
-			return nil
+			resp.State.RemoveResource(ctx)
+			return
 		}
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to read grant", err.Error())
+		return
 	}
 
-	d.SetId(grant.ID)
-	return resourceGrantRefresh(ctx, d, grant)
+	resp.Diagnostics.Append(state.refresh(grant)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
-func resourceGrantDelete(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-	if err := client.deleteGrant(ctx, d.Id()); err != nil {
-		if errors.Is(err, errResourceNotFound) {
-			d.SetId("")
-			return nil
-		}
-		return diag.FromErr(err)
+func (r *grantResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state grantResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if plan.Labels.Equal(state.Labels) {
+		// Only the form of the payload changed.
+		plan.ExpiresAt = state.ExpiresAt
+		resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+		return
+	}
+
+	labels, diags := expandLabels(ctx, plan.Labels)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	updated, err := clientFor(r.client, plan.Namespace).updateGrant(ctx, state.ID.ValueString(), apiGrantUpdatePayload{Labels: labels})
+	if err != nil {
+		resp.Diagnostics.AddError("failed to update grant", err.Error())
+		return
 	}
 
-	d.SetId("")
-	return nil
+	resp.Diagnostics.Append(plan.refresh(updated)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func resourceGrantRefresh(ctx context.Context, d *schema.ResourceData, grant apiGrant) diag.Diagnostics {
+func (r *grantResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state grantResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := clientFor(r.client, state.Namespace).deleteGrant(ctx, state.ID.ValueString()); err != nil && !errors.Is(err, errResourceNotFound) {
+		resp.Diagnostics.AddError("failed to delete grant", err.Error())
+	}
+}
+
+func (r *grantResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importResourceState(ctx, r.client, "grant", lookupGrants, req, resp)
+}
+
+func (r *grantResource) UpgradeState(context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: {
+			PriorSchema: &schema.Schema{
+				Attributes: map[string]schema.Attribute{
+					"id":          schema.StringAttribute{Computed: true},
+					namespaceAttr: schema.StringAttribute{Optional: true},
+					"request_id":  schema.StringAttribute{Required: true},
+					"payload":     schema.StringAttribute{Optional: true},
+					"labels":      schema.MapAttribute{ElementType: types.StringType, Optional: true},
+					"ttl":         schema.StringAttribute{Optional: true},
+					"expires_at":  schema.StringAttribute{Computed: true},
+				},
+			},
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var prior grantResourceModelV0
+				resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+				if resp.Diagnostics.HasError() {
+					return
+				}
+				payload, err := upgradePayload(prior.Payload)
+				if err != nil {
+					resp.Diagnostics.AddError("invalid grant payload in state", err.Error())
+					return
+				}
+				state := grantResourceModel{
+					ID:        prior.ID,
+					Namespace: upgradeOptionalString(prior.Namespace),
+					RequestID: prior.RequestID,
+					Payload:   payload,
+					Labels:    upgradeMap(prior.Labels),
+					TTL:       upgradeOptionalString(prior.TTL),
+					ExpiresAt: prior.ExpiresAt,
+				}
+				resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+			},
+		},
+	}
+}
+
+func (m *grantResourceModel) refresh(grant apiGrant) diag.Diagnostics {
 	var diags diag.Diagnostics
-	if err := d.Set("request_id", grant.RequestID); err != nil {
-		diags = append(diags, diag.FromErr(err)...)
+
+	m.ID = types.StringValue(grant.ID)
+	m.RequestID = types.StringValue(grant.RequestID)
+	m.ExpiresAt = types.StringValue(grant.ExpiresAt)
+	m.Labels = flattenLabelsLike(grant.Labels, m.Labels)
+	document, err := grantDocument(grant.Payload)
+	if err == nil {
+		m.Payload, err = newPayload(document, m.Payload)
 	}
-	payloadBytes := sanitizeGrantPayload(grant.Payload)
-	if len(payloadBytes) != 0 {
-		if err := d.Set("payload", string(payloadBytes)); err != nil {
-			diags = append(diags, diag.FromErr(err)...)
-		}
+	if err != nil {
+		diags.AddError("failed to decode grant payload", err.Error())
 	}
+
 	return diags
 }
 
+// grantDocument decodes the payload of a grant as returned by the server.
+func grantDocument(payload json.RawMessage) (map[string]any, error) {
+	payloadBytes := sanitizeGrantPayload(payload)
+	if len(payloadBytes) == 0 {
+		return nil, nil
+	}
+	document, err := decodeJSON(string(payloadBytes))
+	if err != nil {
+		return nil, err
+	}
+	object, ok := document.(map[string]any)
+	if !ok && document != nil {
+		return nil, fmt.Errorf("grant payload is not a JSON object")
+	}
+	return object, nil
+}
+
 func sanitizeGrantPayload(payload []byte) []byte {
 	payload = bytes.TrimSpace(payload)
 	if len(payload) == 0 || bytes.Equal(payload, []byte("null")) {
@@ -1,7 +1,6 @@
 package provider
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,7 +8,6 @@ import (
 	"sync"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -24,56 +22,92 @@ func TestResourceGrantLifecycle(t *testing.T) {
 
 	server := newGrantTestServer()
 	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
-
-	resource := resourceGrant()
-	grantData := map[string]any{
-		"user": "alice",
-	}
-	grantDataJSON, _ := json.Marshal(grantData)
-	data := schema.TestResourceDataRaw(t, resource.Schema, map[string]any{
+	state := p.mustApply("grantory_grant", nil, map[string]any{
 		"request_id": "req-123",
-		"payload":    string(grantDataJSON),
+		"payload":    `{"user":"alice"}`,
 	})
+	assert.Equal(t, testGrantID, state.get("id"), "resource id should match server-generated id")
+	assert.Equal(t, `{"user":"alice"}`, state.get("payload"), "grant payload keeps its string form")
 
-	assert.False(t, resource.CreateContext(context.Background(), data, client).HasError(), "unexpected diagnostics from create")
+	state, diags := p.read("grantory_grant", state)
+	requireNoErrors(t, diags)
+	assert.Equal(t, `{"user":"alice"}`, state.get("payload"), "grant payload reads back unchanged")
 
-	assert.Equal(t, testGrantID, data.Id(), "resource id should match server-generated id")
+	requireNoErrors(t, p.destroy("grantory_grant", state))
+	state, diags = p.read("grantory_grant", state)
+	requireNoErrors(t, diags)
+	assert.True(t, state.isNull(), "grant should be gone after delete")
+}
 
-	payloadValue, ok := data.Get("payload").(string)
-	assert.True(t, ok, "grant payload should be a string")
-	var decoded map[string]any
-	assert.NoError(t, json.Unmarshal([]byte(payloadValue), &decoded), "grant payload should decode")
-	assert.Equal(t, "alice", decoded["user"], "grant payload user value")
+func TestResourceGrantLabels(t *testing.T) {
+	t.Parallel()
 
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "read diagnostics")
+	server := newGrantTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	assert.False(t, resource.DeleteContext(context.Background(), data, client).HasError(), "delete diagnostics")
+	state := p.mustApply("grantory_grant", nil, map[string]any{
+		"request_id": "req-123",
+		"labels":     map[string]any{"pipeline": "ci"},
+	})
+	assert.Equal(t, map[string]any{"pipeline": "ci"}, state.get("labels"), "labels should be stored on create")
+
+	config := map[string]any{
+		"request_id": "req-123",
+		"labels":     map[string]any{"pipeline": "manual", "policy": "strict"},
+	}
+	_, replace := plannedChanges(t, state, p.plan("grantory_grant", &state, config), p.resourceType("grantory_grant"))
+	assert.False(t, replace, "label changes should not replace the grant")
+	state = p.mustApply("grantory_grant", &state, config)
+	assert.Equal(t, map[string]any{"pipeline": "manual", "policy": "strict"}, state.get("labels"), "labels should refresh after update")
 
-	assert.Empty(t, data.Id(), "id should be cleared after delete")
+	state = p.mustApply("grantory_grant", &state, map[string]any{
+		"request_id": "req-123",
+		"labels":     map[string]any{},
+	})
+	assert.Empty(t, state.get("labels"), "labels should be cleared")
 }
 
-func TestResourceGrantReadNotFound(t *testing.T) {
+func TestResourceGrantUpgradesSDKState(t *testing.T) {
 	t.Parallel()
 
 	server := newGrantTestServer()
 	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
+	state, diags := p.upgrade("grantory_grant", 0, `{
+		"id": "grant-123",
+		"namespace": "",
+		"request_id": "req-123",
+		"payload": "{\"user\": \"alice\"}",
+		"labels": {},
+		"ttl": "",
+		"expires_at": ""
+	}`)
+	requireNoErrors(t, diags)
+
+	changed, replace := plannedChanges(t, state, p.plan("grantory_grant", &state, map[string]any{
+		"request_id": "req-123",
+		"payload":    `{"user":"alice"}`,
+	}), p.resourceType("grantory_grant"))
+	assert.Empty(t, changed, "upgraded state plans no changes")
+	assert.False(t, replace, "upgraded state keeps the grant")
+}
 
-	resource := resourceGrant()
-	data := schema.TestResourceDataRaw(t, resource.Schema, nil)
-	data.SetId("missing-grant")
+func TestResourceGrantReadNotFound(t *testing.T) {
+	t.Parallel()
+
+	server := newGrantTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "read should handle missing grant")
-	assert.Empty(t, data.Id(), "id should clear after not found")
+	state, diags := p.importState("grantory_grant", "missing-grant")
+	requireNoErrors(t, diags)
+	state, diags = p.read("grantory_grant", state)
+	assert.False(t, hasError(diags), "read should handle missing grant")
+	assert.True(t, state.isNull(), "state should be removed after not found")
 }
 
 func TestResourceGrantDeleteNotFound(t *testing.T) {
@@ -81,18 +115,11 @@ func TestResourceGrantDeleteNotFound(t *testing.T) {
 
 	server := newGrantTestServer()
 	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
-
-	resource := resourceGrant()
-	data := schema.TestResourceDataRaw(t, resource.Schema, nil)
-	data.SetId("missing-grant")
-
-	assert.False(t, resource.DeleteContext(context.Background(), data, client).HasError(), "delete should handle missing grant")
-	assert.Empty(t, data.Id(), "id should remain empty")
+	state, diags := p.importState("grantory_grant", "missing-grant")
+	requireNoErrors(t, diags)
+	assert.False(t, hasError(p.destroy("grantory_grant", state)), "delete should handle missing grant")
 }
 
 func TestResourceGrantCreateWithoutPayload(t *testing.T) {
@@ -100,21 +127,13 @@ func TestResourceGrantCreateWithoutPayload(t *testing.T) {
 
 	server := newGrantTestServer()
 	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
-
-	resource := resourceGrant()
-	data := schema.TestResourceDataRaw(t, resource.Schema, map[string]any{
+	state := p.mustApply("grantory_grant", nil, map[string]any{
 		"request_id": "req-123",
 	})
-
-	assert.False(t, resource.CreateContext(context.Background(), data, client).HasError(), "create without payload should succeed")
-	assert.Equal(t, testGrantID, data.Id(), "id should still be populated")
-	_, ok := data.GetOk("payload")
-	assert.False(t, ok, "payload should not be set when not provided")
+	assert.Equal(t, testGrantID, state.get("id"), "id should still be populated")
+	assert.Nil(t, state.get("payload"), "payload should not be set when not provided")
 }
 
 func newGrantTestServer() *httptest.Server {
@@ -135,6 +154,8 @@ func (h *grantTestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.handleCreate(w, r)
 	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/grants/"):
 		h.handleGet(w, r)
+	case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/grants/"):
+		h.handleUpdate(w, r)
 	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/grants/"):
 		h.handleDelete(w, r)
 	default:
@@ -163,6 +184,7 @@ func (h *grantTestHandler) handleCreate(w http.ResponseWriter, r *http.Request)
 		ID:        testGrantID,
 		RequestID: payload.RequestID,
 		Payload:   json.RawMessage(payloadBytes),
+		Labels:    payload.Labels,
 		CreatedAt: testGrantCreatedAt,
 		UpdatedAt: testGrantUpdatedAt,
 	}
@@ -192,6 +214,34 @@ func (h *grantTestHandler) handleGet(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(grant)
 }
 
+func (h *grantTestHandler) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/grants/")
+
+	var payload struct {
+		Labels *map[string]string `json:"labels"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Labels == nil {
+		http.Error(w, "labels is required", http.StatusBadRequest)
+		return
+	}
+
+	h.mu.Lock()
+	grant, ok := h.grants[id]
+	if ok {
+		grant.Labels = *payload.Labels
+		h.grants[id] = grant
+	}
+	h.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(grant)
+}
+
 func (h *grantTestHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimPrefix(r.URL.Path, "/grants/")
 
@@ -4,112 +4,180 @@ import (
 	"context"
 	"errors"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func resourceHost() *schema.Resource {
-	return &schema.Resource{
-		Schema: map[string]*schema.Schema{
-			"host_id": {
-				Type:        schema.TypeString,
-				Computed:    true,
-				Description: "Server-generated identifier for the host.",
+var (
+	_ resource.ResourceWithConfigure    = (*hostResource)(nil)
+	_ resource.ResourceWithImportState  = (*hostResource)(nil)
+	_ resource.ResourceWithUpgradeState = (*hostResource)(nil)
+)
+
+func newHostResource() resource.Resource {
+	return &hostResource{}
+}
+
+type hostResource struct {
+	client *grantoryClient
+}
+
+type hostResourceModel struct {
+	ID        types.String `tfsdk:"id"`
+	Namespace types.String `tfsdk:"namespace"`
+	HostID    types.String `tfsdk:"host_id"`
+	Labels    types.Map    `tfsdk:"labels"`
+}
+
+func (r *hostResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_host"
+}
+
+func (r *hostResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 1,
+		Attributes: map[string]schema.Attribute{
+			"id":          idAttribute("Identifier of the host, the same as host_id."),
+			namespaceAttr: resourceNamespaceAttribute("host"),
+			"host_id": schema.StringAttribute{
+				Computed:      true,
+				Description:   "Server-generated identifier for the host.",
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
 			},
-			"labels": {
-				Type:        schema.TypeMap,
+			"labels": schema.MapAttribute{
+				ElementType: types.StringType,
 				Optional:    true,
 				Description: "Optional labels that accompany the host registration.",
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
-				},
 			},
 		},
-		CreateContext: resourceHostCreate,
-		ReadContext:   resourceHostRead,
-		UpdateContext: resourceHostUpdate,
-		DeleteContext: resourceHostDelete,
 	}
 }
 
-func resourceHostCreate(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-	var rawLabels map[string]any
-	if value, ok := d.Get("labels").(map[string]any); ok {
-		rawLabels = value
+func (r *hostResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = providerClient(req.ProviderData, &resp.Diagnostics)
+}
+
+func (r *hostResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan hostResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	payload := apiHost{
-		Labels: expandStringMap(rawLabels),
+	labels, diags := expandLabels(ctx, plan.Labels)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	host, err := client.createHost(ctx, payload)
+	host, err := clientFor(r.client, plan.Namespace).createHost(ctx, apiHost{Labels: labels})
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to create host", err.Error())
+		return
 	}
 
-	d.SetId(host.ID)
-	return resourceHostRefresh(ctx, d, host)
+	plan.refresh(host)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func resourceHostRead(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-	hostID := d.Id()
+func (r *hostResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state hostResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	hostID := state.ID.ValueString()
 	if hostID == "" {
-		hostID = d.Get("host_id").(string)
+		hostID = state.HostID.ValueString()
 	}
 
-	host, err := client.getHost(ctx, hostID)
+	host, err := clientFor(r.client, state.Namespace).getHost(ctx, hostID)
 	if err != nil {
 		if errors.Is(err, errResourceNotFound) {
-			d.SetId("")
-			return nil
+			resp.State.RemoveResource(ctx)
+			return
 		}
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to read host", err.Error())
+		return
 	}
 
-	d.SetId(host.ID)
-	return resourceHostRefresh(ctx, d, host)
+	state.refresh(host)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
-func resourceHostUpdate(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-	if !d.HasChange("labels") {
-		return nil
+func (r *hostResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state hostResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if plan.Labels.Equal(state.Labels) {
+		resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+		return
+	}
+
+	labels, diags := expandLabels(ctx, plan.Labels)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	labels := expandStringMap(extractMap(d.Get("labels")))
-	updated, err := client.updateHostLabels(ctx, d.Id(), labels)
+	updated, err := clientFor(r.client, plan.Namespace).updateHostLabels(ctx, state.ID.ValueString(), labels)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to update host", err.Error())
+		return
 	}
-	d.SetId(updated.ID)
-	return resourceHostRefresh(ctx, d, updated)
+
+	plan.refresh(updated)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func resourceHostDelete(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-	if err := client.deleteHost(ctx, d.Id()); err != nil {
-		if errors.Is(err, errResourceNotFound) {
-			d.SetId("")
-			return nil
-		}
-		return diag.FromErr(err)
+func (r *hostResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state hostResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	d.SetId("")
-	return nil
+	if err := clientFor(r.client, state.Namespace).deleteHost(ctx, state.ID.ValueString()); err != nil && !errors.Is(err, errResourceNotFound) {
+		resp.Diagnostics.AddError("failed to delete host", err.Error())
+	}
 }
 
-func resourceHostRefresh(ctx context.Context, d *schema.ResourceData, host apiHost) diag.Diagnostics {
-	var diags diag.Diagnostics
+func (r *hostResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importResourceState(ctx, r.client, "host", lookupHosts, req, resp)
+}
 
-	if err := d.Set("host_id", host.ID); err != nil {
-		diags = append(diags, diag.FromErr(err)...)
-	}
-	if host.Labels != nil {
-		if err := d.Set("labels", flattenStringMap(host.Labels)); err != nil {
-			diags = append(diags, diag.FromErr(err)...)
-		}
+func (r *hostResource) UpgradeState(context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: {
+			PriorSchema: &schema.Schema{
+				Attributes: map[string]schema.Attribute{
+					"id":          schema.StringAttribute{Computed: true},
+					namespaceAttr: schema.StringAttribute{Optional: true},
+					"host_id":     schema.StringAttribute{Computed: true},
+					"labels":      schema.MapAttribute{ElementType: types.StringType, Optional: true},
+				},
+			},
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var prior hostResourceModel
+				resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+				if resp.Diagnostics.HasError() {
+					return
+				}
+				prior.Namespace = upgradeOptionalString(prior.Namespace)
+				prior.Labels = upgradeMap(prior.Labels)
+				resp.Diagnostics.Append(resp.State.Set(ctx, &prior)...)
+			},
+		},
 	}
-	return diags
+}
+
+func (m *hostResourceModel) refresh(host apiHost) {
+	m.ID = types.StringValue(host.ID)
+	m.HostID = types.StringValue(host.ID)
+	m.Labels = flattenLabelsLike(host.Labels, m.Labels)
 }
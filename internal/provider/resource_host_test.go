@@ -1,7 +1,6 @@
 package provider
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -10,7 +9,6 @@ import (
 	"sync"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,31 +19,24 @@ func TestResourceHostLifecycle(t *testing.T) {
 
 	server := newHostTestServer()
 	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
-
-	resource := resourceHost()
-	data := schema.TestResourceDataRaw(t, resource.Schema, map[string]any{
+	state := p.mustApply("grantory_host", nil, map[string]any{
 		"labels": map[string]any{
 			"env": "test",
 		},
 	})
+	assert.Equal(t, state.get("id"), state.get("host_id"), "host_id should expose generated ID")
+	assert.Equal(t, map[string]any{"env": "test"}, state.get("labels"), "labels env value")
 
-	assert.False(t, resource.CreateContext(context.Background(), data, client).HasError(), "unexpected diagnostics from create")
-	assert.Equal(t, data.Id(), data.Get("host_id"), "host_id should expose generated ID")
-
-	labels, ok := data.Get("labels").(map[string]any)
-	assert.True(t, ok, "labels should be a map")
-	assert.Equal(t, "test", labels["env"], "labels env value")
-
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "read diagnostics")
+	state, diags := p.read("grantory_host", state)
+	requireNoErrors(t, diags)
+	assert.Equal(t, "host-created", state.get("id"), "read keeps the host")
 
-	assert.False(t, resource.DeleteContext(context.Background(), data, client).HasError(), "delete diagnostics")
-
-	assert.Empty(t, data.Id(), "id should be cleared after delete")
+	requireNoErrors(t, p.destroy("grantory_host", state))
+	state, diags = p.read("grantory_host", state)
+	requireNoErrors(t, diags)
+	assert.True(t, state.isNull(), "host should be gone after delete")
 }
 
 func TestResourceHostUpdatesLabels(t *testing.T) {
@@ -53,69 +44,97 @@ func TestResourceHostUpdatesLabels(t *testing.T) {
 
 	server := newHostTestServer()
 	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
-
-	resource := resourceHost()
-	data := schema.TestResourceDataRaw(t, resource.Schema, map[string]any{
+	config := map[string]any{
 		"labels": map[string]any{
 			"env": "initial",
 		},
+	}
+	state := p.mustApply("grantory_host", nil, config)
+	originalID := state.get("id")
+
+	changed, replace := plannedChanges(t, state, p.plan("grantory_host", &state, config), p.resourceType("grantory_host"))
+	assert.Empty(t, changed, "unchanged configuration plans no changes")
+	assert.False(t, replace, "unchanged configuration keeps the host")
+
+	state = p.mustApply("grantory_host", &state, map[string]any{
+		"labels": map[string]any{"env": "updated"},
 	})
+	assert.Equal(t, originalID, state.get("id"), "host_id should not change")
+	assert.Equal(t, map[string]any{"env": "updated"}, state.get("labels"), "labels should reflect changes")
+}
 
-	assert.False(t, resource.CreateContext(context.Background(), data, client).HasError(), "create should succeed")
-	originalID := data.Id()
+func TestResourceHostReadNotFound(t *testing.T) {
+	t.Parallel()
 
-	assert.False(t, resource.UpdateContext(context.Background(), data, client).HasError(), "no-op update succeeds")
+	server := newHostTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	updatedLabels := map[string]any{"env": "updated"}
-	assert.NoError(t, data.Set("labels", updatedLabels))
-	assert.False(t, resource.UpdateContext(context.Background(), data, client).HasError(), "update succeeds")
-	assert.Equal(t, originalID, data.Id(), "host_id should not change")
-	labelsValue, ok := data.Get("labels").(map[string]any)
-	assert.True(t, ok, "labels should still be a map")
-	assert.Equal(t, "updated", labelsValue["env"], "labels should reflect changes")
+	state, diags := p.importState("grantory_host", "missing-host")
+	requireNoErrors(t, diags)
+	state, diags = p.read("grantory_host", state)
+	assert.False(t, hasError(diags), "read should succeed even when not found")
+	assert.True(t, state.isNull(), "state should be removed when host missing")
 }
 
-func TestResourceHostReadNotFound(t *testing.T) {
+func TestResourceHostDeleteNotFound(t *testing.T) {
 	t.Parallel()
 
 	server := newHostTestServer()
 	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
+	state, diags := p.importState("grantory_host", "missing-host")
+	requireNoErrors(t, diags)
+	assert.False(t, hasError(p.destroy("grantory_host", state)), "delete should not error when host missing")
+}
+
+func TestResourceHostNamespaceOverride(t *testing.T) {
+	t.Parallel()
+
+	var namespaces []string
+	var mu sync.Mutex
+	handler := &hostTestHandler{hosts: make(map[string]apiHost)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		namespaces = append(namespaces, r.Header.Get(namespaceHeader))
+		mu.Unlock()
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	p := newTestProvider(t, server.URL, map[string]any{namespaceAttr: "team-a"})
+
+	state := p.mustApply("grantory_host", nil, map[string]any{
+		namespaceAttr: "team-b",
+	})
+	_, diags := p.read("grantory_host", state)
+	requireNoErrors(t, diags)
+
+	p.mustApply("grantory_host", nil, map[string]any{})
 
-	resource := resourceHost()
-	data := schema.TestResourceDataRaw(t, resource.Schema, nil)
-	data.SetId("missing-host")
+	assert.Equal(t, []string{"team-b", "team-b", "team-a"}, namespaces, "the resource namespace overrides the provider namespace")
 
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "read should succeed even when not found")
-	assert.Empty(t, data.Id(), "ID should be cleared when host missing")
+	_, replace := plannedChanges(t, state, p.plan("grantory_host", &state, map[string]any{namespaceAttr: "team-c"}), p.resourceType("grantory_host"))
+	assert.True(t, replace, "moving a host to another namespace replaces it")
 }
 
-func TestResourceHostDeleteNotFound(t *testing.T) {
+func TestResourceHostUpgradesSDKState(t *testing.T) {
 	t.Parallel()
 
 	server := newHostTestServer()
 	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
-
-	resource := resourceHost()
-	data := schema.TestResourceDataRaw(t, resource.Schema, nil)
-	data.SetId("missing-host")
+	state, diags := p.upgrade("grantory_host", 0, `{"id":"host-1","host_id":"host-1","namespace":"","labels":{}}`)
+	requireNoErrors(t, diags)
+	assert.Nil(t, state.get(namespaceAttr), "an empty namespace becomes null")
+	assert.Nil(t, state.get("labels"), "empty labels become null")
 
-	assert.False(t, resource.DeleteContext(context.Background(), data, client).HasError(), "delete should not error when host missing")
-	assert.Empty(t, data.Id(), "ID should remain empty after delete")
+	changed, replace := plannedChanges(t, state, p.plan("grantory_host", &state, map[string]any{}), p.resourceType("grantory_host"))
+	assert.Empty(t, changed, "upgraded state plans no changes")
+	assert.False(t, replace, "upgraded state keeps the host")
 }
 
 func newHostTestServer() *httptest.Server {
@@ -1,21 +1,17 @@
 package provider
 
 import (
-	"context"
 	"encoding/base64"
 	"encoding/json"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestResourceRequestRefreshIncludesGrant(t *testing.T) {
 	t.Parallel()
 
-	resource := resourceRequest()
-	data := schema.TestResourceDataRaw(t, resource.Schema, nil)
-
+	var model requestResourceModel
 	payload := map[string]any{"detail": "info"}
 	req := apiRequest{
 		ID:       "req-1",
@@ -28,11 +24,11 @@ func TestResourceRequestRefreshIncludesGrant(t *testing.T) {
 		},
 	}
 
-	diags := resourceRequestRefresh(context.Background(), data, req)
+	diags := model.refresh(req)
 	assert.Empty(t, diags)
-	assert.Equal(t, "grant-1", data.Get("grant_id"))
-	assert.Equal(t, true, data.Get("has_grant"))
-	assert.JSONEq(t, `{"detail":"info"}`, data.Get("grant_payload").(string))
+	assert.Equal(t, "grant-1", model.GrantID.ValueString())
+	assert.True(t, model.HasGrant.ValueBool())
+	assert.JSONEq(t, `{"detail":"info"}`, model.GrantPayload.ValueString())
 }
 
 func TestSanitizeGrantPayloadHandlesVariants(t *testing.T) {
@@ -56,11 +52,9 @@ func TestSanitizeGrantPayloadHandlesVariants(t *testing.T) {
 func TestResourceGrantRefreshClearsPayload(t *testing.T) {
 	t.Parallel()
 
-	resource := resourceGrant()
-	data := schema.TestResourceDataRaw(t, resource.Schema, nil)
-
+	model := grantResourceModel{Payload: newPayloadNull()}
 	grant := apiGrant{RequestID: "req-1", Payload: []byte("null")}
-	diags := resourceGrantRefresh(context.Background(), data, grant)
+	diags := model.refresh(grant)
 	assert.Empty(t, diags)
-	assert.Equal(t, "", data.Get("payload"))
+	assert.True(t, model.Payload.IsNull())
 }
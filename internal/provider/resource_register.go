@@ -4,149 +4,253 @@ import (
 	"context"
 	"errors"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func resourceRegister() *schema.Resource {
-	return &schema.Resource{
-		Schema: map[string]*schema.Schema{
-			"host_id": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "Host identifier that owns the register entry.",
-				ForceNew:    true,
+var (
+	_ resource.ResourceWithConfigure    = (*registerResource)(nil)
+	_ resource.ResourceWithImportState  = (*registerResource)(nil)
+	_ resource.ResourceWithUpgradeState = (*registerResource)(nil)
+)
+
+func newRegisterResource() resource.Resource {
+	return &registerResource{}
+}
+
+type registerResource struct {
+	client *grantoryClient
+}
+
+type registerResourceModel struct {
+	ID        types.String `tfsdk:"id"`
+	Namespace types.String `tfsdk:"namespace"`
+	HostID    types.String `tfsdk:"host_id"`
+	Payload   jsonPayload  `tfsdk:"payload"`
+	Labels    types.Map    `tfsdk:"labels"`
+	TTL       types.String `tfsdk:"ttl"`
+	ExpiresAt types.String `tfsdk:"expires_at"`
+}
+
+// registerResourceModelV0 is the schema version 0 state, see upgradePayload.
+type registerResourceModelV0 struct {
+	ID        types.String `tfsdk:"id"`
+	Namespace types.String `tfsdk:"namespace"`
+	HostID    types.String `tfsdk:"host_id"`
+	Payload   types.String `tfsdk:"payload"`
+	Labels    types.Map    `tfsdk:"labels"`
+	TTL       types.String `tfsdk:"ttl"`
+	ExpiresAt types.String `tfsdk:"expires_at"`
+}
+
+func (r *registerResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_register"
+}
+
+func (r *registerResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 1,
+		Attributes: map[string]schema.Attribute{
+			"id":          idAttribute("Identifier of the register entry."),
+			namespaceAttr: resourceNamespaceAttribute("register"),
+			"host_id": schema.StringAttribute{
+				Required:      true,
+				Description:   "Host identifier that owns the register entry.",
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
 			},
-			"payload": {
-				Type:        schema.TypeString,
-				Optional:    true,
-				ForceNew:    true,
-				Description: "JSON-encoded payload that describes the registered item.",
+			"payload": schema.DynamicAttribute{
+				CustomType:    jsonPayloadType{},
+				Optional:      true,
+				Description:   "Payload that describes the registered item, as an object or a JSON-encoded string.",
+				Validators:    []validator.Dynamic{payloadValidator{}},
+				PlanModifiers: []planmodifier.Dynamic{requiresReplaceIfPayloadChanged()},
 			},
-			"labels": {
-				Type:        schema.TypeMap,
+			"labels": schema.MapAttribute{
+				ElementType: types.StringType,
 				Optional:    true,
 				Description: "Optional labels that tag the register entry.",
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
-				},
 			},
+			"ttl":        ttlAttribute("register entry", false),
+			"expires_at": expiresAtAttribute("register entry"),
 		},
-		CreateContext: resourceRegisterCreate,
-		ReadContext:   resourceRegisterRead,
-		UpdateContext: resourceRegisterUpdate,
-		DeleteContext: resourceRegisterDelete,
 	}
 }
 
-func resourceRegisterCreate(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-
-	var registerPayload map[string]any
-	if raw, ok := d.GetOk("payload"); ok {
-		if payloadString, _ := raw.(string); payloadString != "" {
-			parsed, err := parseJSONString(payloadString)
-			if err != nil {
-				return diag.Diagnostics{{
-					Severity: diag.Error,
-					Summary:  "invalid register payload",
-					Detail:   err.Error(),
-				}}
-			}
-			registerPayload = parsed
-		}
+func (r *registerResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = providerClient(req.ProviderData, &resp.Diagnostics)
+}
+
+func (r *registerResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan registerResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	payload := apiRegister{
-		HostID:  d.Get("host_id").(string),
-		Payload: registerPayload,
-		Labels:  expandStringMap(extractMap(d.Get("labels"))),
+	registerPayload, err := plan.Payload.decode(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("invalid register payload", err.Error())
+		return
+	}
+	labels, diags := expandLabels(ctx, plan.Labels)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	created, err := client.createRegister(ctx, payload)
+	created, err := clientFor(r.client, plan.Namespace).createRegister(ctx, apiRegister{
+		HostID:  plan.HostID.ValueString(),
+		Payload: registerPayload,
+		Labels:  labels,
+		TTL:     plan.TTL.ValueString(),
+	})
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to create register", err.Error())
+		return
 	}
 
-	d.SetId(created.ID)
-	return resourceRegisterRefresh(ctx, d, created)
+	resp.Diagnostics.Append(plan.refresh(created)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func resourceRegisterRead(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-	registerID := d.Id()
-	if registerID == "" {
-		return nil
+func (r *registerResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state registerResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if state.ID.ValueString() == "" {
+		return
 	}
 
-	reg, err := client.getRegister(ctx, registerID)
+	reg, err := clientFor(r.client, state.Namespace).getRegister(ctx, state.ID.ValueString())
 	if err != nil {
 		if errors.Is(err, errResourceNotFound) {
-			d.SetId("")
-			return nil
+			resp.State.RemoveResource(ctx)
+			return
 		}
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to read register", err.Error())
+		return
 	}
 
-	d.SetId(reg.ID)
-	return resourceRegisterRefresh(ctx, d, reg)
+	resp.Diagnostics.Append(state.refresh(reg)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
-func resourceRegisterUpdate(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
+func (r *registerResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state registerResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	var payload apiRegisterUpdatePayload
 	changed := false
-	if d.HasChange("labels") {
-		payload.Labels = expandStringMap(extractMap(d.Get("labels")))
+	if !plan.Labels.Equal(state.Labels) {
+		labels, diags := expandLabels(ctx, plan.Labels)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		payload.Labels = labels
+		changed = true
+	}
+	if !plan.TTL.Equal(state.TTL) {
+		payload.TTL, payload.ExpiresAt = ttlUpdate(plan.TTL)
 		changed = true
 	}
 	if !changed {
-		return nil
+		// Only the form of the payload changed.
+		plan.ExpiresAt = state.ExpiresAt
+		resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+		return
 	}
 
-	updated, err := client.updateRegister(ctx, d.Id(), payload)
+	updated, err := clientFor(r.client, plan.Namespace).updateRegister(ctx, state.ID.ValueString(), payload)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to update register", err.Error())
+		return
 	}
 
-	d.SetId(updated.ID)
-	return resourceRegisterRefresh(ctx, d, updated)
+	resp.Diagnostics.Append(plan.refresh(updated)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func resourceRegisterDelete(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-	if err := client.deleteRegister(ctx, d.Id()); err != nil {
-		if errors.Is(err, errResourceNotFound) {
-			d.SetId("")
-			return nil
-		}
-		return diag.FromErr(err)
+func (r *registerResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state registerResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	d.SetId("")
-	return nil
+	if err := clientFor(r.client, state.Namespace).deleteRegister(ctx, state.ID.ValueString()); err != nil && !errors.Is(err, errResourceNotFound) {
+		resp.Diagnostics.AddError("failed to delete register", err.Error())
+	}
 }
 
-func resourceRegisterRefresh(ctx context.Context, d *schema.ResourceData, reg apiRegister) diag.Diagnostics {
-	return setRegisterAttributes(d, reg)
+func (r *registerResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importResourceState(ctx, r.client, "register", lookupRegisters, req, resp)
 }
 
-func setRegisterAttributes(d *schema.ResourceData, reg apiRegister) diag.Diagnostics {
+func (r *registerResource) UpgradeState(context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: {
+			PriorSchema: &schema.Schema{
+				Attributes: map[string]schema.Attribute{
+					"id":          schema.StringAttribute{Computed: true},
+					namespaceAttr: schema.StringAttribute{Optional: true},
+					"host_id":     schema.StringAttribute{Required: true},
+					"payload":     schema.StringAttribute{Optional: true},
+					"labels":      schema.MapAttribute{ElementType: types.StringType, Optional: true},
+					"ttl":         schema.StringAttribute{Optional: true},
+					"expires_at":  schema.StringAttribute{Computed: true},
+				},
+			},
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var prior registerResourceModelV0
+				resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+				if resp.Diagnostics.HasError() {
+					return
+				}
+				payload, err := upgradePayload(prior.Payload)
+				if err != nil {
+					resp.Diagnostics.AddError("invalid register payload in state", err.Error())
+					return
+				}
+				state := registerResourceModel{
+					ID:        prior.ID,
+					Namespace: upgradeOptionalString(prior.Namespace),
+					HostID:    prior.HostID,
+					Payload:   payload,
+					Labels:    upgradeMap(prior.Labels),
+					TTL:       upgradeOptionalString(prior.TTL),
+					ExpiresAt: prior.ExpiresAt,
+				}
+				resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+			},
+		},
+	}
+}
+
+func (m *registerResourceModel) refresh(reg apiRegister) diag.Diagnostics {
 	var diags diag.Diagnostics
 
-	if err := d.Set("host_id", reg.HostID); err != nil {
-		diags = append(diags, diag.FromErr(err)...)
-	}
-	if reg.Payload != nil {
-		if additional := setJSONStringAttribute(d, "payload", reg.Payload); additional != nil {
-			diags = append(diags, additional...)
-		}
-	}
-	if reg.Labels != nil {
-		if err := d.Set("labels", flattenStringMap(reg.Labels)); err != nil {
-			diags = append(diags, diag.FromErr(err)...)
-		}
+	m.ID = types.StringValue(reg.ID)
+	m.HostID = types.StringValue(reg.HostID)
+	payload, err := newPayload(reg.Payload, m.Payload)
+	if err != nil {
+		diags.AddError("failed to decode register payload", err.Error())
+	} else {
+		m.Payload = payload
 	}
+	m.Labels = flattenLabelsLike(reg.Labels, m.Labels)
+	m.ExpiresAt = types.StringValue(reg.ExpiresAt)
 
 	return diags
 }
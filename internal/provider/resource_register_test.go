@@ -1,7 +1,6 @@
 package provider
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,7 +8,6 @@ import (
 	"sync"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -24,59 +22,97 @@ func TestResourceRegisterLifecycle(t *testing.T) {
 
 	server := newRegisterTestServer()
 	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
-
-	resource := resourceRegister()
-	registerData := map[string]any{
-		"item": "test",
-	}
-	registerDataJSON, _ := json.Marshal(registerData)
-	data := schema.TestResourceDataRaw(t, resource.Schema, map[string]any{
+	config := map[string]any{
 		"host_id": "host-abc",
-		"payload": string(registerDataJSON),
+		"payload": map[string]any{
+			"item": "test",
+		},
 		"labels": map[string]any{
 			"env": "testing",
 		},
-	})
+	}
+	state := p.mustApply("grantory_register", nil, config)
+	assert.Equal(t, testRegisterID, state.get("id"), "resource id should match server-generated id")
+
+	state, diags := p.read("grantory_register", state)
+	requireNoErrors(t, diags)
 
-	assert.False(t, resource.CreateContext(context.Background(), data, client).HasError(), "unexpected diagnostics from create")
+	config["labels"] = map[string]any{"env": "prod"}
+	state = p.mustApply("grantory_register", &state, config)
+	assert.Equal(t, map[string]any{"env": "prod"}, state.get("labels"), "labels should refresh after update")
 
-	assert.Equal(t, testRegisterID, data.Id(), "resource id should match server-generated id")
+	requireNoErrors(t, p.destroy("grantory_register", state))
+	state, diags = p.read("grantory_register", state)
+	requireNoErrors(t, diags)
+	assert.True(t, state.isNull(), "register should be gone after delete")
+}
 
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "read diagnostics")
+func TestResourceRegisterPayloadChanges(t *testing.T) {
+	t.Parallel()
 
-	assert.NoError(t, data.Set("labels", map[string]any{"env": "prod"}), "prepare label update")
-	assert.False(t, resource.UpdateContext(context.Background(), data, client).HasError(), "update diagnostics")
+	server := newRegisterTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
+	typ := p.resourceType("grantory_register")
 
-	updatedLabels, _ := data.Get("labels").(map[string]any)
-	assert.Equal(t, "prod", updatedLabels["env"], "labels should refresh after update")
+	state := p.mustApply("grantory_register", nil, map[string]any{
+		"host_id": "host-abc",
+		"payload": `{"item":"test","count":3}`,
+	})
 
-	assert.False(t, resource.DeleteContext(context.Background(), data, client).HasError(), "delete diagnostics")
+	_, replace := plannedChanges(t, state, p.plan("grantory_register", &state, map[string]any{
+		"host_id": "host-abc",
+		"payload": map[string]any{"count": 3, "item": "test"},
+	}), typ)
+	assert.False(t, replace, "writing the same payload as an object keeps the register")
 
-	assert.Empty(t, data.Id(), "id should be cleared after delete")
+	_, replace = plannedChanges(t, state, p.plan("grantory_register", &state, map[string]any{
+		"host_id": "host-abc",
+		"payload": map[string]any{"count": 4, "item": "test"},
+	}), typ)
+	assert.True(t, replace, "a different payload replaces the register")
 }
 
-func TestResourceRegisterReadNotFound(t *testing.T) {
+func TestResourceRegisterUpgradesSDKState(t *testing.T) {
 	t.Parallel()
 
 	server := newRegisterTestServer()
 	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
+	state, diags := p.upgrade("grantory_register", 0, `{
+		"id": "reg-123",
+		"namespace": "",
+		"host_id": "host-abc",
+		"payload": "{\"item\":\"test\"}",
+		"labels": {},
+		"ttl": "",
+		"expires_at": ""
+	}`)
+	requireNoErrors(t, diags)
+
+	changed, replace := plannedChanges(t, state, p.plan("grantory_register", &state, map[string]any{
+		"host_id": "host-abc",
+		"payload": `{"item":"test"}`,
+	}), p.resourceType("grantory_register"))
+	assert.Empty(t, changed, "upgraded state plans no changes")
+	assert.False(t, replace, "upgraded state keeps the register")
+}
+
+func TestResourceRegisterReadNotFound(t *testing.T) {
+	t.Parallel()
 
-	resource := resourceRegister()
-	data := schema.TestResourceDataRaw(t, resource.Schema, nil)
-	data.SetId("missing-register")
+	server := newRegisterTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "read should not error on missing register")
-	assert.Empty(t, data.Id(), "ID should clear after not found")
+	state, diags := p.importState("grantory_register", "missing-register")
+	requireNoErrors(t, diags)
+	state, diags = p.read("grantory_register", state)
+	assert.False(t, hasError(diags), "read should not error on missing register")
+	assert.True(t, state.isNull(), "state should be removed after not found")
 }
 
 func TestResourceRegisterDeleteNotFound(t *testing.T) {
@@ -84,18 +120,11 @@ func TestResourceRegisterDeleteNotFound(t *testing.T) {
 
 	server := newRegisterTestServer()
 	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
-
-	resource := resourceRegister()
-	data := schema.TestResourceDataRaw(t, resource.Schema, nil)
-	data.SetId("missing-register")
-
-	assert.False(t, resource.DeleteContext(context.Background(), data, client).HasError(), "delete should succeed even when register missing")
-	assert.Empty(t, data.Id(), "ID should remain empty")
+	state, diags := p.importState("grantory_register", "missing-register")
+	requireNoErrors(t, diags)
+	assert.False(t, hasError(p.destroy("grantory_register", state)), "delete should succeed even when register missing")
 }
 
 func newRegisterTestServer() *httptest.Server {
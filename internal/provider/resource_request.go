@@ -4,190 +4,434 @@ import (
 	"context"
 	"errors"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func resourceRequest() *schema.Resource {
-	return &schema.Resource{
-		Schema: map[string]*schema.Schema{
-			"host_id": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "Host identifier that owns the request.",
-				ForceNew:    true,
+var (
+	_ resource.ResourceWithConfigure    = (*requestResource)(nil)
+	_ resource.ResourceWithImportState  = (*requestResource)(nil)
+	_ resource.ResourceWithUpgradeState = (*requestResource)(nil)
+	_ resource.ResourceWithModifyPlan   = (*requestResource)(nil)
+)
+
+func newRequestResource() resource.Resource {
+	return &requestResource{}
+}
+
+type requestResource struct {
+	client *grantoryClient
+}
+
+type requestResourceModel struct {
+	ID           types.String `tfsdk:"id"`
+	Namespace    types.String `tfsdk:"namespace"`
+	HostID       types.String `tfsdk:"host_id"`
+	Payload      jsonPayload  `tfsdk:"payload"`
+	Labels       types.Map    `tfsdk:"labels"`
+	HasGrant     types.Bool   `tfsdk:"has_grant"`
+	Status       types.String `tfsdk:"status"`
+	Revision     types.Int64  `tfsdk:"revision"`
+	StaleGrant   types.Bool   `tfsdk:"stale_grant"`
+	StatusReason types.String `tfsdk:"status_reason"`
+	GrantID      types.String `tfsdk:"grant_id"`
+	GrantPayload types.String `tfsdk:"grant_payload"`
+	TTL          types.String `tfsdk:"ttl"`
+	ExpiresAt    types.String `tfsdk:"expires_at"`
+	WaitForGrant types.Bool   `tfsdk:"wait_for_grant"`
+	WaitTimeout  types.String `tfsdk:"wait_timeout"`
+}
+
+// requestResourceModelV0 is the schema version 0 state, see upgradePayload.
+type requestResourceModelV0 struct {
+	ID           types.String `tfsdk:"id"`
+	Namespace    types.String `tfsdk:"namespace"`
+	HostID       types.String `tfsdk:"host_id"`
+	Payload      types.String `tfsdk:"payload"`
+	Labels       types.Map    `tfsdk:"labels"`
+	HasGrant     types.Bool   `tfsdk:"has_grant"`
+	Status       types.String `tfsdk:"status"`
+	Revision     types.Int64  `tfsdk:"revision"`
+	StaleGrant   types.Bool   `tfsdk:"stale_grant"`
+	StatusReason types.String `tfsdk:"status_reason"`
+	GrantID      types.String `tfsdk:"grant_id"`
+	GrantPayload types.String `tfsdk:"grant_payload"`
+	TTL          types.String `tfsdk:"ttl"`
+	ExpiresAt    types.String `tfsdk:"expires_at"`
+	WaitForGrant types.Bool   `tfsdk:"wait_for_grant"`
+	WaitTimeout  types.String `tfsdk:"wait_timeout"`
+}
+
+func (r *requestResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_request"
+}
+
+func (r *requestResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 1,
+		Attributes: map[string]schema.Attribute{
+			"id":          idAttribute("Identifier of the request."),
+			namespaceAttr: resourceNamespaceAttribute("request"),
+			"host_id": schema.StringAttribute{
+				Required:      true,
+				Description:   "Host identifier that owns the request.",
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
 			},
-			"payload": {
-				Type:        schema.TypeString,
+			"payload": schema.DynamicAttribute{
+				CustomType:  jsonPayloadType{},
 				Optional:    true,
-				ForceNew:    true,
-				Description: "JSON-encoded payload that describes the requested resource.",
+				Description: "Payload that describes the requested resource, as an object or a JSON-encoded string. Changes are applied in place and stored as a new revision.",
+				Validators:  []validator.Dynamic{payloadValidator{}},
 			},
-			"labels": {
-				Type:        schema.TypeMap,
+			"labels": schema.MapAttribute{
+				ElementType: types.StringType,
 				Optional:    true,
 				Description: "Optional labels that tag the request.",
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
-				},
 			},
-			"has_grant": {
-				Type:        schema.TypeBool,
+			"has_grant": schema.BoolAttribute{
 				Computed:    true,
 				Description: "Indicates whether the server has created a matching grant.",
 			},
-			"grant_id": {
-				Type:        schema.TypeString,
+			"status": schema.StringAttribute{
+				Computed:    true,
+				Description: "Lifecycle status of the request: pending, approved, denied, revoked, or expired.",
+			},
+			"revision": schema.Int64Attribute{
+				Computed:    true,
+				Description: "Revision of the request payload, starting at 1 and increased by every payload change.",
+			},
+			"stale_grant": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Indicates whether the grant was issued for an earlier revision of the payload.",
+			},
+			"status_reason": schema.StringAttribute{
+				Computed:    true,
+				Description: "Reason recorded by the grantor for the current status, if any.",
+			},
+			"grant_id": schema.StringAttribute{
 				Computed:    true,
 				Description: "Identifier reported by the Grantory server for the applied grant.",
 			},
-			"grant_payload": {
-				Type:        schema.TypeString,
+			"grant_payload": schema.StringAttribute{
 				Computed:    true,
 				Description: "JSON-encoded payload delivered by the grant, if any.",
 			},
+			"ttl":        ttlAttribute("request", false),
+			"expires_at": expiresAtAttribute("request"),
+			"wait_for_grant": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Wait for the grant while creating, updating and reading the request, so that grant_payload is available in the same apply. A denied, revoked or expired request or an elapsed wait_timeout fails the apply and is reported as a warning on refresh.",
+			},
+			"wait_timeout": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(defaultWaitTimeout),
+				Validators:  []validator.String{durationValidator{}},
+				Description: "How long to wait for the grant when wait_for_grant is set, such as 30s or 15m.",
+			},
 		},
-		CreateContext: resourceRequestCreate,
-		ReadContext:   resourceRequestRead,
-		UpdateContext: resourceRequestUpdate,
-		DeleteContext: resourceRequestDelete,
-	}
-}
-func resourceRequestCreate(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-
-	var requestPayload map[string]any
-	if raw, ok := d.GetOk("payload"); ok {
-		if payloadString, _ := raw.(string); payloadString != "" {
-			parsed, err := parseJSONString(payloadString)
-			if err != nil {
-				return diag.Diagnostics{{
-					Severity: diag.Error,
-					Summary:  "invalid request payload",
-					Detail:   err.Error(),
-				}}
-			}
-			requestPayload = parsed
-		}
 	}
+}
 
-	payload := apiRequest{
-		HostID:  d.Get("host_id").(string),
-		Payload: requestPayload,
-		Labels:  expandStringMap(extractMap(d.Get("labels"))),
+func (r *requestResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = providerClient(req.ProviderData, &resp.Diagnostics)
+}
+
+func (r *requestResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan requestResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	client := clientFor(r.client, plan.Namespace)
+
+	requestPayload, err := plan.Payload.decode(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("invalid request payload", err.Error())
+		return
+	}
+	labels, diags := expandLabels(ctx, plan.Labels)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	created, err := client.createRequest(ctx, payload)
+	created, err := client.createRequest(ctx, apiRequest{
+		HostID:  plan.HostID.ValueString(),
+		Payload: requestPayload,
+		Labels:  labels,
+		TTL:     plan.TTL.ValueString(),
+	})
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to create request", err.Error())
+		return
 	}
 
-	d.SetId(created.ID)
-	return resourceRequestRefresh(ctx, d, created)
+	granted, diags := awaitRequestGrant(ctx, client, created, plan.WaitForGrant, plan.WaitTimeout, diag.SeverityError)
+	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(plan.refresh(granted)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func resourceRequestRead(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-	reqID := d.Id()
-	if reqID == "" {
-		return nil
+func (r *requestResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state requestResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if state.ID.ValueString() == "" {
+		return
 	}
+	client := clientFor(r.client, state.Namespace)
 
-	req, err := client.getRequest(ctx, reqID)
+	found, err := client.getRequest(ctx, state.ID.ValueString())
 	if err != nil {
 		if errors.Is(err, errResourceNotFound) {
-			d.SetId("")
-			return nil
+			resp.State.RemoveResource(ctx)
+			return
 		}
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("failed to read request", err.Error())
+		return
 	}
 
-	d.SetId(req.ID)
-	return resourceRequestRefresh(ctx, d, req)
+	// Imported requests have no wait settings yet.
+	if state.WaitForGrant.IsNull() {
+		state.WaitForGrant = types.BoolValue(false)
+	}
+	if state.WaitTimeout.IsNull() {
+		state.WaitTimeout = types.StringValue(defaultWaitTimeout)
+	}
+	if found.Status == "pending" && !found.HasGrant {
+		var diags diag.Diagnostics
+		found, diags = awaitRequestGrant(ctx, client, found, state.WaitForGrant, state.WaitTimeout, diag.SeverityWarning)
+		resp.Diagnostics.Append(diags...)
+	}
+	resp.Diagnostics.Append(state.refresh(found)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
-func resourceRequestUpdate(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
+func (r *requestResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state requestResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	client := clientFor(r.client, plan.Namespace)
+
 	var payload apiRequestUpdatePayload
 	changed := false
-	if d.HasChange("labels") {
-		payload.Labels = expandStringMap(extractMap(d.Get("labels")))
+	if !payloadsEqual(ctx, plan.Payload.DynamicValue, state.Payload.DynamicValue) {
+		requestPayload, err := plan.Payload.decode(ctx)
+		if err != nil {
+			resp.Diagnostics.AddError("invalid request payload", err.Error())
+			return
+		}
+		if requestPayload == nil {
+			requestPayload = map[string]any{}
+		}
+		payload.Payload = &requestPayload
 		changed = true
 	}
-	if !changed {
-		return nil
+	if !plan.Labels.Equal(state.Labels) {
+		labels, diags := expandLabels(ctx, plan.Labels)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		payload.Labels = labels
+		changed = true
 	}
-
-	updated, err := client.updateRequest(ctx, d.Id(), payload)
-	if err != nil {
-		return diag.FromErr(err)
+	if !plan.TTL.Equal(state.TTL) {
+		payload.TTL, payload.ExpiresAt = ttlUpdate(plan.TTL)
+		changed = true
 	}
 
-	d.SetId(updated.ID)
-	return resourceRequestRefresh(ctx, d, updated)
-}
-
-func resourceRequestDelete(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
-	client := meta.(*grantoryClient)
-	if err := client.deleteRequest(ctx, d.Id()); err != nil {
-		if errors.Is(err, errResourceNotFound) {
-			d.SetId("")
-			return nil
-		}
-		return diag.FromErr(err)
+	if !changed && !plan.Status.IsUnknown() {
+		// Only the form of the payload or the wait settings changed, see
+		// ModifyPlan.
+		resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+		return
 	}
 
-	d.SetId("")
-	return nil
-}
+	var (
+		updated apiRequest
+		err     error
+	)
+	if changed {
+		updated, err = client.updateRequest(ctx, state.ID.ValueString(), payload)
+	} else {
+		// Nothing the server stores changed, for example when
+		// wait_for_grant was turned on; refresh the request instead.
+		updated, err = client.getRequest(ctx, state.ID.ValueString())
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("failed to update request", err.Error())
+		return
+	}
 
-func resourceRequestRefresh(ctx context.Context, d *schema.ResourceData, req apiRequest) diag.Diagnostics {
-	return setRequestAttributes(d, req)
+	granted, diags := awaitRequestGrant(ctx, client, updated, plan.WaitForGrant, plan.WaitTimeout, diag.SeverityError)
+	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(plan.refresh(granted)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func setRequestAttributes(d *schema.ResourceData, req apiRequest) diag.Diagnostics {
-	var diags diag.Diagnostics
-
-	if err := d.Set("host_id", req.HostID); err != nil {
-		diags = append(diags, diag.FromErr(err)...)
+// ModifyPlan keeps the computed attributes of the state when nothing the
+// server stores changes, such as when the payload only switches between its
+// object and its jsonencode form, and the update will not wait for a grant.
+func (r *requestResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
 	}
-	if req.Payload != nil {
-		if additional := setJSONStringAttribute(d, "payload", req.Payload); additional != nil {
-			diags = append(diags, additional...)
-		}
+	var plan, state requestResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	if req.Labels != nil {
-		if err := d.Set("labels", flattenStringMap(req.Labels)); err != nil {
-			diags = append(diags, diag.FromErr(err)...)
-		}
+	if !plan.HostID.Equal(state.HostID) || !plan.Namespace.Equal(state.Namespace) ||
+		!payloadsEqual(ctx, plan.Payload.DynamicValue, state.Payload.DynamicValue) ||
+		!plan.Labels.Equal(state.Labels) || !plan.TTL.Equal(state.TTL) {
+		return
 	}
-	if err := d.Set("has_grant", req.HasGrant); err != nil {
-		diags = append(diags, diag.FromErr(err)...)
+	if plan.WaitForGrant.ValueBool() && !state.HasGrant.ValueBool() {
+		return
 	}
-	var grantID any
-	if req.GrantID != "" {
-		grantID = req.GrantID
-		if err := d.Set("grant_id", grantID); err != nil {
-			diags = append(diags, diag.FromErr(err)...)
-		}
+
+	plan.HasGrant = state.HasGrant
+	plan.Status = state.Status
+	plan.Revision = state.Revision
+	plan.StaleGrant = state.StaleGrant
+	plan.StatusReason = state.StatusReason
+	plan.GrantID = state.GrantID
+	plan.GrantPayload = state.GrantPayload
+	plan.ExpiresAt = state.ExpiresAt
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+func (r *requestResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state requestResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	if grantPayload := extractGrantPayload(req); grantPayload != nil {
-		if additional := setJSONStringAttribute(d, "grant_payload", grantPayload); additional != nil {
-			diags = append(diags, additional...)
-		}
+
+	if err := clientFor(r.client, state.Namespace).deleteRequest(ctx, state.ID.ValueString()); err != nil && !errors.Is(err, errResourceNotFound) {
+		resp.Diagnostics.AddError("failed to delete request", err.Error())
 	}
+}
 
-	return diags
+func (r *requestResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importResourceState(ctx, r.client, "request", lookupRequests, req, resp)
 }
 
-func extractMap(value any) map[string]any {
-	if value == nil {
-		return nil
+// UpgradeState also sets the defaults of wait_for_grant and wait_timeout,
+// which the SDKv2 provider left unset.
+func (r *requestResource) UpgradeState(context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: {
+			PriorSchema: &schema.Schema{
+				Attributes: map[string]schema.Attribute{
+					"id":             schema.StringAttribute{Computed: true},
+					namespaceAttr:    schema.StringAttribute{Optional: true},
+					"host_id":        schema.StringAttribute{Required: true},
+					"payload":        schema.StringAttribute{Optional: true},
+					"labels":         schema.MapAttribute{ElementType: types.StringType, Optional: true},
+					"has_grant":      schema.BoolAttribute{Computed: true},
+					"status":         schema.StringAttribute{Computed: true},
+					"revision":       schema.Int64Attribute{Computed: true},
+					"stale_grant":    schema.BoolAttribute{Computed: true},
+					"status_reason":  schema.StringAttribute{Computed: true},
+					"grant_id":       schema.StringAttribute{Computed: true},
+					"grant_payload":  schema.StringAttribute{Computed: true},
+					"ttl":            schema.StringAttribute{Optional: true},
+					"expires_at":     schema.StringAttribute{Computed: true},
+					"wait_for_grant": schema.BoolAttribute{Optional: true},
+					"wait_timeout":   schema.StringAttribute{Optional: true},
+				},
+			},
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var prior requestResourceModelV0
+				resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+				if resp.Diagnostics.HasError() {
+					return
+				}
+				payload, err := upgradePayload(prior.Payload)
+				if err != nil {
+					resp.Diagnostics.AddError("invalid request payload in state", err.Error())
+					return
+				}
+				state := requestResourceModel{
+					ID:           prior.ID,
+					Namespace:    prior.Namespace,
+					HostID:       prior.HostID,
+					Labels:       prior.Labels,
+					HasGrant:     prior.HasGrant,
+					Status:       prior.Status,
+					Revision:     prior.Revision,
+					StaleGrant:   prior.StaleGrant,
+					StatusReason: prior.StatusReason,
+					GrantID:      prior.GrantID,
+					GrantPayload: prior.GrantPayload,
+					TTL:          prior.TTL,
+					ExpiresAt:    prior.ExpiresAt,
+					WaitForGrant: prior.WaitForGrant,
+					WaitTimeout:  prior.WaitTimeout,
+				}
+				state.Payload = payload
+				state.Namespace = upgradeOptionalString(state.Namespace)
+				state.Labels = upgradeMap(state.Labels)
+				state.TTL = upgradeOptionalString(state.TTL)
+				if state.WaitForGrant.IsNull() {
+					state.WaitForGrant = types.BoolValue(false)
+				}
+				if state.WaitTimeout.ValueString() == "" {
+					state.WaitTimeout = types.StringValue(defaultWaitTimeout)
+				}
+				resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+			},
+		},
 	}
-	if result, ok := value.(map[string]any); ok {
-		return result
+}
+
+// refresh stores req in the model. The payload keeps the form it was written
+// in, see newPayload.
+func (m *requestResourceModel) refresh(req apiRequest) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	m.ID = types.StringValue(req.ID)
+	m.HostID = types.StringValue(req.HostID)
+	payload, err := newPayload(req.Payload, m.Payload)
+	if err != nil {
+		diags.AddError("failed to decode request payload", err.Error())
+	} else {
+		m.Payload = payload
+	}
+	m.Labels = flattenLabelsLike(req.Labels, m.Labels)
+	m.HasGrant = types.BoolValue(req.HasGrant)
+	m.Status = types.StringValue(req.Status)
+	m.StatusReason = types.StringValue(req.StatusReason)
+	m.Revision = types.Int64Value(int64(req.Revision))
+	m.StaleGrant = types.BoolValue(req.StaleGrant)
+	m.GrantID = types.StringValue(req.GrantID)
+	m.ExpiresAt = types.StringValue(req.ExpiresAt)
+	grantPayload, err := encodeJSONAttribute(extractGrantPayload(req))
+	if err != nil {
+		diags.AddError("failed to encode grant_payload", err.Error())
+	} else {
+		m.GrantPayload = grantPayload
 	}
-	return nil
+
+	return diags
 }
 
 func extractGrantPayload(req apiRequest) map[string]any {
@@ -1,7 +1,6 @@
 package provider
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,7 +8,6 @@ import (
 	"sync"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,6 +15,7 @@ const (
 	testRequestCreatedAt = "2024-02-02T00:00:00Z"
 	testRequestUpdatedAt = "2024-02-02T00:00:00Z"
 	testRequestID        = "req-123"
+	testRequestExpiresAt = "2024-02-09T00:00:00Z"
 )
 
 func TestResourceRequestLifecycle(t *testing.T) {
@@ -24,91 +23,200 @@ func TestResourceRequestLifecycle(t *testing.T) {
 
 	server := newRequestTestServer()
 	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
-
-	resource := resourceRequest()
-	requestData := map[string]any{
-		"name": "test-db",
-	}
-	requestDataJSON, _ := json.Marshal(requestData)
-	data := schema.TestResourceDataRaw(t, resource.Schema, map[string]any{
+	state := p.mustApply("grantory_request", nil, map[string]any{
 		"host_id": "host-abc",
-		"payload": string(requestDataJSON),
+		"payload": map[string]any{
+			"name": "test-db",
+		},
 		"labels": map[string]any{
 			"env": "testing",
 		},
 	})
 
-	assert.False(t, resource.CreateContext(context.Background(), data, client).HasError(), "unexpected diagnostics from create")
+	assert.Equal(t, "req-123", state.get("id"), "resource id should match generated request ID")
+	assert.Equal(t, map[string]any{"name": "test-db"}, state.get("payload"), "payload keeps its object form")
+	assert.Equal(t, false, state.get("has_grant"), "has_grant should be false before grant")
+	assert.Equal(t, "", state.get("grant_id"), "grant_id should be empty before grant")
+	assert.Equal(t, "", state.get("grant_payload"), "grant_payload should be empty before grant")
 
-	assert.Equal(t, "req-123", data.Id(), "resource id should match generated request ID")
+	state, diags := p.read("grantory_request", state)
+	requireNoErrors(t, diags)
 
-	rawPayload, _ := data.Get("payload").(string)
-	assert.NotEmpty(t, rawPayload, "payload should be a JSON string")
-	var parsedPayload map[string]any
-	assert.NoError(t, json.Unmarshal([]byte(rawPayload), &parsedPayload), "payload should decode")
-	assert.Equal(t, "test-db", parsedPayload["name"], "payload name value")
+	state = p.mustApply("grantory_request", &state, map[string]any{
+		"host_id": "host-abc",
+		"payload": map[string]any{
+			"name": "test-db",
+		},
+		"labels": map[string]any{
+			"env": "prod",
+		},
+	})
+	assert.Equal(t, map[string]any{"env": "prod"}, state.get("labels"), "labels should refresh after update")
+	assert.Equal(t, 1, state.get("revision"), "label updates keep the revision")
 
-	hasGrant, _ := data.Get("has_grant").(bool)
-	assert.False(t, hasGrant, "has_grant should be false before grant")
-	_, grantOK := data.GetOk("grant_id")
-	assert.False(t, grantOK, "grant_id should be absent before grant")
-	payloadValue, _ := data.Get("grant_payload").(string)
-	assert.Empty(t, payloadValue, "grant_payload should be empty before grant")
+	requireNoErrors(t, p.destroy("grantory_request", state))
+	state, diags = p.read("grantory_request", state)
+	requireNoErrors(t, diags)
+	assert.True(t, state.isNull(), "request should be gone after delete")
+}
 
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "read diagnostics")
+func TestResourceRequestTTL(t *testing.T) {
+	t.Parallel()
 
-	assert.NoError(t, data.Set("labels", map[string]any{"env": "prod"}), "prepare label update")
-	assert.False(t, resource.UpdateContext(context.Background(), data, client).HasError(), "update diagnostics")
+	server := newRequestTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	updatedLabels, _ := data.Get("labels").(map[string]any)
-	assert.Equal(t, "prod", updatedLabels["env"], "labels should refresh after update")
+	state := p.mustApply("grantory_request", nil, map[string]any{
+		"host_id": "host-abc",
+		"ttl":     "7d",
+	})
+	assert.Equal(t, testRequestExpiresAt, state.get("expires_at"), "expires_at should be computed by the server")
 
-	assert.False(t, resource.DeleteContext(context.Background(), data, client).HasError(), "delete diagnostics")
+	state = p.mustApply("grantory_request", &state, map[string]any{
+		"host_id": "host-abc",
+	})
+	assert.Equal(t, "", state.get("expires_at"), "removing the ttl clears the expiry")
 
-	assert.Empty(t, data.Id(), "id should be cleared after delete")
+	diags := p.validate("grantory_request", map[string]any{"host_id": "host-abc", "ttl": "soon"})
+	assert.Equal(t, []string{"Invalid duration"}, errorSummaries(diags), "invalid ttl should be rejected")
+	diags = p.validate("grantory_request", map[string]any{"host_id": "host-abc", "ttl": "12h"})
+	assert.False(t, hasError(diags), "valid ttl should be accepted")
 }
 
-func TestResourceRequestReadNotFound(t *testing.T) {
+func TestResourceRequestPayloadUpdate(t *testing.T) {
 	t.Parallel()
 
 	server := newRequestTestServer()
 	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
+
+	state := p.mustApply("grantory_request", nil, map[string]any{
+		"host_id": "host-abc",
+		"payload": `{"size":"small"}`,
+	})
+	assert.Equal(t, 1, state.get("revision"), "new requests start at revision 1")
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
+	config := map[string]any{
+		"host_id": "host-abc",
+		"payload": `{"size":"large"}`,
 	}
+	_, replace := plannedChanges(t, state, p.plan("grantory_request", &state, config), p.resourceType("grantory_request"))
+	assert.False(t, replace, "payload changes should not replace the request")
 
-	resource := resourceRequest()
-	data := schema.TestResourceDataRaw(t, resource.Schema, nil)
-	data.SetId("missing-request")
+	state = p.mustApply("grantory_request", &state, config)
+	assert.Equal(t, testRequestID, state.get("id"), "payload update keeps the request")
+	assert.Equal(t, `{"size":"large"}`, state.get("payload"), "payload keeps its string form")
+	assert.Equal(t, 2, state.get("revision"), "payload update bumps the revision")
+	assert.Equal(t, false, state.get("stale_grant"), "requests without grant are never stale")
 
-	assert.False(t, resource.ReadContext(context.Background(), data, client).HasError(), "read should not error when request missing")
-	assert.Empty(t, data.Id(), "id should clear after not found")
+	diags := p.validate("grantory_request", map[string]any{"host_id": "host-abc", "payload": "not json"})
+	assert.Equal(t, []string{"Invalid payload"}, errorSummaries(diags), "invalid payload should fail")
 }
 
-func TestResourceRequestDeleteNotFound(t *testing.T) {
+func TestResourceRequestPayloadSemanticEquality(t *testing.T) {
 	t.Parallel()
 
 	server := newRequestTestServer()
 	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
+	typ := p.resourceType("grantory_request")
 
-	client := &grantoryClient{
-		baseURL:    mustParseURL(t, server.URL),
-		httpClient: server.Client(),
-	}
+	state := p.mustApply("grantory_request", nil, map[string]any{
+		"host_id": "host-abc",
+		"payload": "{\n  \"size\": \"small\",\n  \"replicas\": 2.50\n}",
+	})
+	assert.Equal(t, "{\n  \"size\": \"small\",\n  \"replicas\": 2.50\n}", state.get("payload"), "formatting of the configured payload is kept")
+
+	state, diags := p.read("grantory_request", state)
+	requireNoErrors(t, diags)
+	changed, _ := plannedChanges(t, state, p.plan("grantory_request", &state, map[string]any{
+		"host_id": "host-abc",
+		"payload": `{"replicas":2.5,"size":"small"}`,
+	}), typ)
+	assert.Equal(t, []string{"payload"}, changed, "reformatting the payload only updates the state")
+
+	state = p.mustApply("grantory_request", &state, map[string]any{
+		"host_id": "host-abc",
+		"payload": map[string]any{"size": "small", "replicas": 2.5},
+	})
+	assert.Equal(t, 1, state.get("revision"), "switching to the object form does not store a new revision")
+	assert.Equal(t, map[string]any{"size": "small", "replicas": 2.5}, state.get("payload"), "payload takes the object form")
+
+	state, diags = p.read("grantory_request", state)
+	requireNoErrors(t, diags)
+	changed, _ = plannedChanges(t, state, p.plan("grantory_request", &state, map[string]any{
+		"host_id": "host-abc",
+		"payload": map[string]any{"size": "small", "replicas": 2.5},
+	}), typ)
+	assert.Empty(t, changed, "object payloads read back without a diff")
+}
+
+func TestResourceRequestUpgradesSDKState(t *testing.T) {
+	t.Parallel()
+
+	server := newRequestTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
+
+	state, diags := p.upgrade("grantory_request", 0, `{
+		"id": "req-123",
+		"namespace": "",
+		"host_id": "host-abc",
+		"payload": "{\"size\":\"large\",\"tags\":[\"a\",1e2]}",
+		"labels": {"env": "prod"},
+		"has_grant": false,
+		"status": "pending",
+		"revision": 1,
+		"stale_grant": false,
+		"status_reason": "",
+		"grant_id": "",
+		"grant_payload": "",
+		"ttl": "",
+		"expires_at": "",
+		"wait_for_grant": null,
+		"wait_timeout": ""
+	}`)
+	requireNoErrors(t, diags)
+	assert.Equal(t, `{"size":"large","tags":["a",100]}`, state.get("payload"), "the payload is stored the way jsonencode writes it")
+	assert.Equal(t, false, state.get("wait_for_grant"), "wait_for_grant takes its default")
+	assert.Equal(t, defaultWaitTimeout, state.get("wait_timeout"), "wait_timeout takes its default")
+
+	changed, replace := plannedChanges(t, state, p.plan("grantory_request", &state, map[string]any{
+		"host_id": "host-abc",
+		"payload": `{"size":"large","tags":["a",100]}`,
+		"labels":  map[string]any{"env": "prod"},
+	}), p.resourceType("grantory_request"))
+	assert.Empty(t, changed, "upgraded state plans no changes")
+	assert.False(t, replace, "upgraded state keeps the request")
+}
+
+func TestResourceRequestReadNotFound(t *testing.T) {
+	t.Parallel()
 
-	resource := resourceRequest()
-	data := schema.TestResourceDataRaw(t, resource.Schema, nil)
-	data.SetId("missing-request")
+	server := newRequestTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
 
-	assert.False(t, resource.DeleteContext(context.Background(), data, client).HasError(), "delete should not fail for missing request")
-	assert.Empty(t, data.Id(), "id should remain empty")
+	state, diags := p.importState("grantory_request", "missing-request")
+	requireNoErrors(t, diags)
+	state, diags = p.read("grantory_request", state)
+	assert.False(t, hasError(diags), "read should not error when request missing")
+	assert.True(t, state.isNull(), "state should be removed after not found")
+}
+
+func TestResourceRequestDeleteNotFound(t *testing.T) {
+	t.Parallel()
+
+	server := newRequestTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
+
+	state, diags := p.importState("grantory_request", "missing-request")
+	requireNoErrors(t, diags)
+	assert.False(t, hasError(p.destroy("grantory_request", state)), "delete should not fail for missing request")
 }
 
 func newRequestTestServer() *httptest.Server {
@@ -152,7 +260,12 @@ func (h *requestTestHandler) handleCreate(w http.ResponseWriter, r *http.Request
 		payload.ID = testRequestID
 	}
 
+	if payload.TTL != "" {
+		payload.ExpiresAt = testRequestExpiresAt
+		payload.TTL = ""
+	}
 	payload.HasGrant = false
+	payload.Revision = 1
 	payload.CreatedAt = testRequestCreatedAt
 	payload.UpdatedAt = testRequestUpdatedAt
 
@@ -194,20 +307,31 @@ func (h *requestTestHandler) handleUpdate(w http.ResponseWriter, r *http.Request
 	}
 
 	var payload struct {
-		Payload *map[string]any    `json:"payload"`
-		Labels  *map[string]string `json:"labels"`
+		Payload   *map[string]any    `json:"payload"`
+		Labels    *map[string]string `json:"labels"`
+		TTL       *string            `json:"ttl"`
+		ExpiresAt *string            `json:"expires_at"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
-	if payload.Payload == nil && payload.Labels == nil {
-		http.Error(w, "data or labels required", http.StatusBadRequest)
+	if payload.Payload == nil && payload.Labels == nil && payload.TTL == nil && payload.ExpiresAt == nil {
+		http.Error(w, "data, labels or expiry required", http.StatusBadRequest)
 		return
 	}
 
+	if payload.TTL != nil {
+		req.ExpiresAt = testRequestExpiresAt
+	}
+	if payload.ExpiresAt != nil {
+		req.ExpiresAt = *payload.ExpiresAt
+	}
+
 	if payload.Payload != nil {
 		req.Payload = *payload.Payload
+		req.Revision++
+		req.StaleGrant = req.HasGrant
 	}
 	if payload.Labels != nil {
 		req.Labels = *payload.Labels
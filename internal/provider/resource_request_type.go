@@ -0,0 +1,212 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/hashicorp/terraform-plugin-framework-jsontypes/jsontypes"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ resource.ResourceWithConfigure    = (*requestTypeResource)(nil)
+	_ resource.ResourceWithUpgradeState = (*requestTypeResource)(nil)
+)
+
+func newRequestTypeResource() resource.Resource {
+	return &requestTypeResource{}
+}
+
+type requestTypeResource struct {
+	client *grantoryClient
+}
+
+type requestTypeResourceModel struct {
+	ID            types.String         `tfsdk:"id"`
+	Namespace     types.String         `tfsdk:"namespace"`
+	Name          types.String         `tfsdk:"name"`
+	RequestSchema jsontypes.Normalized `tfsdk:"request_schema"`
+	GrantSchema   jsontypes.Normalized `tfsdk:"grant_schema"`
+}
+
+// requestTypeResourceModelV0 is the schema version 0 state, see upgradePayload.
+type requestTypeResourceModelV0 struct {
+	ID            types.String `tfsdk:"id"`
+	Namespace     types.String `tfsdk:"namespace"`
+	Name          types.String `tfsdk:"name"`
+	RequestSchema types.String `tfsdk:"request_schema"`
+	GrantSchema   types.String `tfsdk:"grant_schema"`
+}
+
+func (r *requestTypeResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_request_type"
+}
+
+func (r *requestTypeResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 1,
+		Attributes: map[string]schema.Attribute{
+			"id":          idAttribute("Identifier of the request type, the same as name."),
+			namespaceAttr: resourceNamespaceAttribute("request type"),
+			"name": schema.StringAttribute{
+				Required:      true,
+				Description:   "Name of the request type. Requests labelled type=<name> are validated against its schemas.",
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"request_schema": schema.StringAttribute{
+				CustomType:  jsontypes.NormalizedType{},
+				Optional:    true,
+				Description: "JSON Schema that request payloads must satisfy.",
+			},
+			"grant_schema": schema.StringAttribute{
+				CustomType:  jsontypes.NormalizedType{},
+				Optional:    true,
+				Description: "JSON Schema that the payloads of grants for these requests must satisfy.",
+			},
+		},
+	}
+}
+
+func (r *requestTypeResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = providerClient(req.ProviderData, &resp.Diagnostics)
+}
+
+func (r *requestTypeResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan requestTypeResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.put(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *requestTypeResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state requestTypeResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if state.ID.ValueString() == "" {
+		return
+	}
+
+	requestType, err := clientFor(r.client, state.Namespace).getRequestType(ctx, state.ID.ValueString())
+	if err != nil {
+		if errors.Is(err, errResourceNotFound) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("failed to read request type", err.Error())
+		return
+	}
+
+	state.refresh(requestType)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *requestTypeResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan requestTypeResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.put(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// put stores the request type of model, replacing both schemas, and
+// refreshes model from the stored request type.
+func (r *requestTypeResource) put(ctx context.Context, model *requestTypeResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	payload := apiRequestType{Name: model.Name.ValueString()}
+	if value := model.RequestSchema.ValueString(); value != "" {
+		payload.RequestSchema = json.RawMessage(value)
+	}
+	if value := model.GrantSchema.ValueString(); value != "" {
+		payload.GrantSchema = json.RawMessage(value)
+	}
+
+	stored, err := clientFor(r.client, model.Namespace).putRequestType(ctx, payload)
+	if err != nil {
+		diags.AddError("failed to store request type", err.Error())
+		return diags
+	}
+	model.refresh(stored)
+	return diags
+}
+
+func (r *requestTypeResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state requestTypeResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := clientFor(r.client, state.Namespace).deleteRequestType(ctx, state.ID.ValueString()); err != nil && !errors.Is(err, errResourceNotFound) {
+		resp.Diagnostics.AddError("failed to delete request type", err.Error())
+	}
+}
+
+// UpgradeState turns the schemas, stored as JSON strings, into normalized JSON
+// values.
+func (r *requestTypeResource) UpgradeState(context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: {
+			PriorSchema: &schema.Schema{
+				Attributes: map[string]schema.Attribute{
+					"id":             schema.StringAttribute{Computed: true},
+					namespaceAttr:    schema.StringAttribute{Optional: true},
+					"name":           schema.StringAttribute{Required: true},
+					"request_schema": schema.StringAttribute{Optional: true},
+					"grant_schema":   schema.StringAttribute{Optional: true},
+				},
+			},
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var prior requestTypeResourceModelV0
+				resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+				if resp.Diagnostics.HasError() {
+					return
+				}
+				state := requestTypeResourceModel{
+					ID:            prior.ID,
+					Namespace:     upgradeOptionalString(prior.Namespace),
+					Name:          prior.Name,
+					RequestSchema: jsonSchemaValue(json.RawMessage(prior.RequestSchema.ValueString())),
+					GrantSchema:   jsonSchemaValue(json.RawMessage(prior.GrantSchema.ValueString())),
+				}
+				resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+			},
+		},
+	}
+}
+
+func (m *requestTypeResourceModel) refresh(requestType apiRequestType) {
+	m.ID = types.StringValue(requestType.Name)
+	m.Name = types.StringValue(requestType.Name)
+	m.RequestSchema = jsonSchemaValue(requestType.RequestSchema)
+	m.GrantSchema = jsonSchemaValue(requestType.GrantSchema)
+}
+
+// jsonSchemaValue converts a schema returned by the server to an attribute
+// value; request types without the schema have a null value.
+func jsonSchemaValue(raw json.RawMessage) jsontypes.Normalized {
+	if len(raw) == 0 || string(raw) == "null" {
+		return jsontypes.NewNormalizedNull()
+	}
+	return jsontypes.NewNormalizedValue(string(raw))
+}
@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceRequestTypeLifecycle(t *testing.T) {
+	t.Parallel()
+
+	server := newRequestTypeTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
+
+	state := p.mustApply("grantory_request_type", nil, map[string]any{
+		"name":           "database",
+		"request_schema": `{"type": "object", "required": ["name"]}`,
+	})
+	assert.Equal(t, "database", state.get("id"), "resource id should be the request type name")
+
+	state, diags := p.read("grantory_request_type", state)
+	requireNoErrors(t, diags)
+	assert.JSONEq(t, `{"type":"object","required":["name"]}`, state.get("request_schema").(string))
+	assert.Nil(t, state.get("grant_schema"), "grant schema is not set")
+
+	state = p.mustApply("grantory_request_type", &state, map[string]any{
+		"name":           "database",
+		"request_schema": `{"type": "object", "required": ["name"]}`,
+		"grant_schema":   `{"type":"object"}`,
+	})
+	assert.JSONEq(t, `{"type":"object"}`, state.get("grant_schema").(string))
+
+	requireNoErrors(t, p.destroy("grantory_request_type", state))
+	state, diags = p.read("grantory_request_type", state)
+	assert.False(t, hasError(diags), "read should not error on missing request type")
+	assert.True(t, state.isNull(), "state should be removed after not found")
+}
+
+func TestResourceRequestTypeSchemaSemanticEquality(t *testing.T) {
+	t.Parallel()
+
+	server := newRequestTypeTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
+
+	config := map[string]any{
+		"name":           "database",
+		"request_schema": "{\n  \"b\": [1, 2],\n  \"a\": 1\n}",
+	}
+	state := p.mustApply("grantory_request_type", nil, config)
+	state, diags := p.read("grantory_request_type", state)
+	requireNoErrors(t, diags)
+	assert.Equal(t, config["request_schema"], state.get("request_schema"), "an equivalent schema read back keeps the configured formatting")
+
+	changed, _ := plannedChanges(t, state, p.plan("grantory_request_type", &state, config), p.resourceType("grantory_request_type"))
+	assert.Empty(t, changed, "equivalent schemas plan no changes")
+
+	diags = p.validate("grantory_request_type", map[string]any{"name": "database", "request_schema": "not json"})
+	assert.True(t, hasError(diags), "invalid schemas should be rejected")
+}
+
+func newRequestTypeTestServer() *httptest.Server {
+	handler := &requestTypeTestHandler{
+		requestTypes: make(map[string]apiRequestType),
+	}
+	return httptest.NewServer(handler)
+}
+
+type requestTypeTestHandler struct {
+	mu           sync.Mutex
+	requestTypes map[string]apiRequestType
+}
+
+func (h *requestTypeTestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/request-types/")
+	if name == r.URL.Path {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		var payload apiRequestType
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Name != name {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		h.requestTypes[name] = payload
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(payload)
+	case http.MethodGet:
+		requestType, ok := h.requestTypes[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(requestType)
+	case http.MethodDelete:
+		if _, ok := h.requestTypes[name]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(h.requestTypes, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,290 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var webhookResourceTypes = []string{"hosts", "requests", "registers", "grants"}
+
+var (
+	_ resource.ResourceWithConfigure    = (*webhookResource)(nil)
+	_ resource.ResourceWithUpgradeState = (*webhookResource)(nil)
+)
+
+func newWebhookResource() resource.Resource {
+	return &webhookResource{}
+}
+
+type webhookResource struct {
+	client *grantoryClient
+}
+
+type webhookResourceModel struct {
+	ID             types.String `tfsdk:"id"`
+	Namespace      types.String `tfsdk:"namespace"`
+	URL            types.String `tfsdk:"url"`
+	Secret         types.String `tfsdk:"secret"`
+	ResourceTypes  types.Set    `tfsdk:"resource_types"`
+	LabelSelector  types.Map    `tfsdk:"label_selector"`
+	IncludePayload types.Bool   `tfsdk:"include_payload"`
+}
+
+// webhookResourceModelV0 is the schema version 0 state, see upgradePayload.
+type webhookResourceModelV0 struct {
+	ID            types.String `tfsdk:"id"`
+	Namespace     types.String `tfsdk:"namespace"`
+	URL           types.String `tfsdk:"url"`
+	Secret        types.String `tfsdk:"secret"`
+	ResourceTypes types.Set    `tfsdk:"resource_types"`
+	LabelSelector types.Map    `tfsdk:"label_selector"`
+}
+
+func (r *webhookResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_webhook"
+}
+
+func (r *webhookResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 1,
+		Attributes: map[string]schema.Attribute{
+			"id":          idAttribute("Identifier of the webhook."),
+			namespaceAttr: resourceNamespaceAttribute("webhook"),
+			"url": schema.StringAttribute{
+				Required:    true,
+				Description: "http(s) endpoint that receives signed event deliveries.",
+			},
+			"secret": schema.StringAttribute{
+				Optional:      true,
+				Computed:      true,
+				Sensitive:     true,
+				Description:   "HMAC secret used to sign deliveries. Generated by the server when omitted.",
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"resource_types": schema.SetAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Resource types whose events are delivered (hosts, requests, registers, grants). All types when empty.",
+				Validators: []validator.Set{
+					setvalidator.ValueStringsAre(stringvalidator.OneOf(webhookResourceTypes...)),
+				},
+			},
+			"label_selector": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Labels an event's resource must carry to be delivered.",
+			},
+			"include_payload": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Include request, register and grant payloads in deliveries. Payloads often hold credentials, so deliveries only carry identifiers, labels and status by default.",
+			},
+		},
+	}
+}
+
+func (r *webhookResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = providerClient(req.ProviderData, &resp.Diagnostics)
+}
+
+func (r *webhookResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan webhookResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resourceTypes, diags := expandWebhookResourceTypes(ctx, plan.ResourceTypes)
+	resp.Diagnostics.Append(diags...)
+	labelSelector, diags := expandLabels(ctx, plan.LabelSelector)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	payload := apiWebhook{
+		URL:            plan.URL.ValueString(),
+		Secret:         plan.Secret.ValueString(),
+		ResourceTypes:  resourceTypes,
+		LabelSelector:  labelSelector,
+		IncludePayload: plan.IncludePayload.ValueBool(),
+	}
+
+	created, err := clientFor(r.client, plan.Namespace).createWebhook(ctx, payload)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to create webhook", err.Error())
+		return
+	}
+
+	resp.Diagnostics.Append(plan.refresh(created)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *webhookResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state webhookResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if state.ID.ValueString() == "" {
+		return
+	}
+
+	webhook, err := clientFor(r.client, state.Namespace).getWebhook(ctx, state.ID.ValueString())
+	if err != nil {
+		if errors.Is(err, errResourceNotFound) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("failed to read webhook", err.Error())
+		return
+	}
+
+	resp.Diagnostics.Append(state.refresh(webhook)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *webhookResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state webhookResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resourceTypes, diags := expandWebhookResourceTypes(ctx, plan.ResourceTypes)
+	resp.Diagnostics.Append(diags...)
+	labelSelector, diags := expandLabels(ctx, plan.LabelSelector)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	payload := apiWebhookUpdatePayload{
+		URL:            plan.URL.ValueString(),
+		ResourceTypes:  resourceTypes,
+		LabelSelector:  labelSelector,
+		IncludePayload: plan.IncludePayload.ValueBool(),
+	}
+	if payload.ResourceTypes == nil {
+		payload.ResourceTypes = []string{}
+	}
+	if payload.LabelSelector == nil {
+		payload.LabelSelector = map[string]string{}
+	}
+	if !plan.Secret.IsUnknown() && !plan.Secret.Equal(state.Secret) {
+		secret := plan.Secret.ValueString()
+		payload.Secret = &secret
+	}
+
+	updated, err := clientFor(r.client, plan.Namespace).updateWebhook(ctx, state.ID.ValueString(), payload)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to update webhook", err.Error())
+		return
+	}
+
+	if plan.Secret.IsUnknown() {
+		plan.Secret = state.Secret
+	}
+	resp.Diagnostics.Append(plan.refresh(updated)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *webhookResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state webhookResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := clientFor(r.client, state.Namespace).deleteWebhook(ctx, state.ID.ValueString()); err != nil && !errors.Is(err, errResourceNotFound) {
+		resp.Diagnostics.AddError("failed to delete webhook", err.Error())
+	}
+}
+
+// UpgradeState turns empty resource_types into null. Version 0 had no
+// include_payload, so upgraded webhooks keep leaving payloads out.
+func (r *webhookResource) UpgradeState(context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: {
+			PriorSchema: &schema.Schema{
+				Attributes: map[string]schema.Attribute{
+					"id":             schema.StringAttribute{Computed: true},
+					namespaceAttr:    schema.StringAttribute{Optional: true},
+					"url":            schema.StringAttribute{Required: true},
+					"secret":         schema.StringAttribute{Optional: true, Computed: true, Sensitive: true},
+					"resource_types": schema.SetAttribute{ElementType: types.StringType, Optional: true},
+					"label_selector": schema.MapAttribute{ElementType: types.StringType, Optional: true},
+				},
+			},
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var prior webhookResourceModelV0
+				resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+				if resp.Diagnostics.HasError() {
+					return
+				}
+				state := webhookResourceModel{
+					ID:             prior.ID,
+					Namespace:      upgradeOptionalString(prior.Namespace),
+					URL:            prior.URL,
+					Secret:         prior.Secret,
+					ResourceTypes:  prior.ResourceTypes,
+					LabelSelector:  upgradeMap(prior.LabelSelector),
+					IncludePayload: types.BoolValue(false),
+				}
+				if len(state.ResourceTypes.Elements()) == 0 {
+					state.ResourceTypes = types.SetNull(types.StringType)
+				}
+				resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+			},
+		},
+	}
+}
+
+// refresh stores the webhook in the model. The server only returns the
+// secret when it was generated or rotated, so an empty one is kept as is.
+func (m *webhookResourceModel) refresh(webhook apiWebhook) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	m.ID = types.StringValue(webhook.ID)
+	m.URL = types.StringValue(webhook.URL)
+	if webhook.Secret != "" {
+		m.Secret = types.StringValue(webhook.Secret)
+	} else if m.Secret.IsUnknown() {
+		m.Secret = types.StringNull()
+	}
+	// An empty set from the server keeps resource_types = [] as configured.
+	switch {
+	case len(webhook.ResourceTypes) > 0:
+		resourceTypes, setDiags := types.SetValueFrom(context.Background(), types.StringType, webhook.ResourceTypes)
+		diags.Append(setDiags...)
+		m.ResourceTypes = resourceTypes
+	case m.ResourceTypes.IsNull() || m.ResourceTypes.IsUnknown() || len(m.ResourceTypes.Elements()) > 0:
+		m.ResourceTypes = types.SetNull(types.StringType)
+	}
+	m.LabelSelector = flattenLabelsLike(webhook.LabelSelector, m.LabelSelector)
+	m.IncludePayload = types.BoolValue(webhook.IncludePayload)
+
+	return diags
+}
+
+func expandWebhookResourceTypes(ctx context.Context, set types.Set) ([]string, diag.Diagnostics) {
+	if set.IsNull() || set.IsUnknown() || len(set.Elements()) == 0 {
+		return nil, nil
+	}
+	values := make([]string, 0, len(set.Elements()))
+	diags := set.ElementsAs(ctx, &values, false)
+	sort.Strings(values)
+	return values, diags
+}
@@ -0,0 +1,181 @@
+package provider
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testWebhookID     = "webhook-123"
+	testWebhookSecret = "generated-secret"
+)
+
+func TestResourceWebhookLifecycle(t *testing.T) {
+	t.Parallel()
+
+	server := newWebhookTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
+
+	state := p.mustApply("grantory_webhook", nil, map[string]any{
+		"url":            "https://hooks.example.com/grantory",
+		"resource_types": []any{"requests", "grants"},
+		"label_selector": map[string]any{"type": "db_user"},
+	})
+	assert.Equal(t, testWebhookID, state.get("id"), "resource id should match server-generated id")
+	assert.Equal(t, testWebhookSecret, state.get("secret"), "generated secret should be stored")
+	assert.Equal(t, false, state.get("include_payload"), "payloads are left out by default")
+
+	state, diags := p.read("grantory_webhook", state)
+	requireNoErrors(t, diags)
+	assert.Equal(t, testWebhookSecret, state.get("secret"), "read keeps the secret the server does not return")
+	assert.ElementsMatch(t, []any{"requests", "grants"}, state.get("resource_types"))
+
+	state = p.mustApply("grantory_webhook", &state, map[string]any{
+		"url":             "https://hooks.example.com/v2",
+		"resource_types":  []any{"requests", "grants"},
+		"label_selector":  map[string]any{},
+		"include_payload": true,
+	})
+	assert.Equal(t, "https://hooks.example.com/v2", state.get("url"), "url should refresh after update")
+	assert.Equal(t, true, state.get("include_payload"), "include_payload should refresh after update")
+	assert.Empty(t, state.get("label_selector"), "label selector should be cleared")
+	assert.Equal(t, testWebhookSecret, state.get("secret"), "secret is kept when not rotated")
+
+	requireNoErrors(t, p.destroy("grantory_webhook", state))
+	state, diags = p.read("grantory_webhook", state)
+	requireNoErrors(t, diags)
+	assert.True(t, state.isNull(), "webhook should be gone after delete")
+}
+
+func TestResourceWebhookValidatesResourceTypes(t *testing.T) {
+	t.Parallel()
+
+	p := newTestProvider(t, "http://localhost:8080", nil)
+	diags := p.validate("grantory_webhook", map[string]any{
+		"url":            "https://hooks.example.com/grantory",
+		"resource_types": []any{"requests", "tokens"},
+	})
+	assert.True(t, hasError(diags), "unknown resource types should be rejected")
+}
+
+func TestResourceWebhookUpgradesSDKState(t *testing.T) {
+	t.Parallel()
+
+	server := newWebhookTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
+
+	state, diags := p.upgrade("grantory_webhook", 0, `{
+		"id": "webhook-1",
+		"namespace": "",
+		"url": "https://hooks.example.com/grantory",
+		"secret": "s3cret",
+		"resource_types": [],
+		"label_selector": {}
+	}`)
+	requireNoErrors(t, diags)
+	assert.Nil(t, state.get(namespaceAttr), "an empty namespace becomes null")
+	assert.Nil(t, state.get("resource_types"), "empty resource types become null")
+	assert.Nil(t, state.get("label_selector"), "an empty label selector becomes null")
+	assert.Equal(t, false, state.get("include_payload"), "upgraded webhooks keep leaving out payloads")
+
+	changed, _ := plannedChanges(t, state, p.plan("grantory_webhook", &state, map[string]any{
+		"url":    "https://hooks.example.com/grantory",
+		"secret": "s3cret",
+	}), p.resourceType("grantory_webhook"))
+	assert.Empty(t, changed, "upgraded state plans no changes")
+}
+
+func TestResourceWebhookReadNotFound(t *testing.T) {
+	t.Parallel()
+
+	server := newWebhookTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server.URL, nil)
+
+	state, diags := p.read("grantory_webhook", p.stateOf("grantory_webhook", map[string]any{"id": "missing-webhook"}))
+	assert.False(t, hasError(diags), "read should not error on missing webhook")
+	assert.True(t, state.isNull(), "state should be removed after not found")
+}
+
+func newWebhookTestServer() *httptest.Server {
+	handler := &webhookTestHandler{
+		webhooks: make(map[string]apiWebhook),
+	}
+	return httptest.NewServer(handler)
+}
+
+type webhookTestHandler struct {
+	mu       sync.Mutex
+	webhooks map[string]apiWebhook
+}
+
+func (h *webhookTestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/webhooks/")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/webhooks":
+		var payload apiWebhook
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.URL == "" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		payload.ID = testWebhookID
+		h.webhooks[payload.ID] = payload
+		if payload.Secret == "" {
+			payload.Secret = testWebhookSecret
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(payload)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/webhooks/"):
+		webhook, ok := h.webhooks[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(webhook)
+	case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/webhooks/"):
+		webhook, ok := h.webhooks[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		var payload apiWebhookUpdatePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if payload.ResourceTypes == nil || payload.LabelSelector == nil {
+			http.Error(w, "filters must be sent explicitly", http.StatusBadRequest)
+			return
+		}
+		webhook.URL = payload.URL
+		webhook.ResourceTypes = payload.ResourceTypes
+		webhook.LabelSelector = payload.LabelSelector
+		webhook.IncludePayload = payload.IncludePayload
+		h.webhooks[id] = webhook
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(webhook)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/webhooks/"):
+		if _, ok := h.webhooks[id]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(h.webhooks, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
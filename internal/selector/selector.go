@@ -0,0 +1,262 @@
+// Package selector parses label selectors in the Kubernetes syntax and
+// compiles them to SQL conditions against the label tables.
+//
+// A selector is a comma-separated list of requirements that must all hold:
+//
+//	env=prod          label env equals prod (== works too)
+//	tier!=db          label tier is missing or differs from db
+//	env in (a,b)      label env is a or b
+//	env notin (a,b)   label env is missing or neither a nor b
+//	team              label team exists
+//	!deprecated       label deprecated does not exist
+package selector
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Operator is the comparison of a single requirement.
+type Operator string
+
+const (
+	OpEquals       Operator = "="
+	OpNotEquals    Operator = "!="
+	OpIn           Operator = "in"
+	OpNotIn        Operator = "notin"
+	OpExists       Operator = "exists"
+	OpDoesNotExist Operator = "!"
+)
+
+// Requirement is one condition on a label key.
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// Selector is a list of requirements that must all match. The zero value
+// matches everything.
+type Selector []Requirement
+
+// Parse reads a selector expression. An empty expression yields an empty
+// selector.
+func Parse(expression string) (Selector, error) {
+	terms, err := splitTerms(expression)
+	if err != nil {
+		return nil, err
+	}
+	selector := make(Selector, 0, len(terms))
+	for _, term := range terms {
+		requirement, err := parseRequirement(term)
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, requirement)
+	}
+	if len(selector) == 0 {
+		return nil, nil
+	}
+	return selector, nil
+}
+
+// splitTerms splits an expression on the commas outside of value lists.
+func splitTerms(expression string) ([]string, error) {
+	var (
+		terms []string
+		depth int
+		start int
+	)
+	for i, r := range expression {
+		switch r {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, fmt.Errorf("invalid selector %q: nested parentheses", expression)
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("invalid selector %q: unbalanced parentheses", expression)
+			}
+		case ',':
+			if depth == 0 {
+				terms = append(terms, expression[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("invalid selector %q: unbalanced parentheses", expression)
+	}
+	terms = append(terms, expression[start:])
+
+	if len(terms) == 1 && strings.TrimSpace(terms[0]) == "" {
+		return nil, nil
+	}
+	for i, term := range terms {
+		terms[i] = strings.TrimSpace(term)
+		if terms[i] == "" {
+			return nil, fmt.Errorf("invalid selector %q: empty requirement", expression)
+		}
+	}
+	return terms, nil
+}
+
+func parseRequirement(term string) (Requirement, error) {
+	if rest, ok := strings.CutPrefix(term, "!"); ok && !strings.ContainsAny(rest, "=()") {
+		key := strings.TrimSpace(rest)
+		if err := validateKey(key); err != nil {
+			return Requirement{}, fmt.Errorf("invalid requirement %q: %w", term, err)
+		}
+		return Requirement{Key: key, Operator: OpDoesNotExist}, nil
+	}
+
+	for _, candidate := range []struct {
+		token    string
+		operator Operator
+	}{
+		{"!=", OpNotEquals},
+		{"==", OpEquals},
+		{"=", OpEquals},
+	} {
+		key, value, found := strings.Cut(term, candidate.token)
+		if !found {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if err := validateKey(key); err != nil {
+			return Requirement{}, fmt.Errorf("invalid requirement %q: %w", term, err)
+		}
+		if err := validateValue(value); err != nil {
+			return Requirement{}, fmt.Errorf("invalid requirement %q: %w", term, err)
+		}
+		return Requirement{Key: key, Operator: candidate.operator, Values: []string{value}}, nil
+	}
+
+	if open := strings.Index(term, "("); open >= 0 {
+		fields := strings.Fields(term[:open])
+		if len(fields) != 2 || !strings.HasSuffix(term, ")") {
+			return Requirement{}, fmt.Errorf("invalid requirement %q: expected <key> in (<values>) or <key> notin (<values>)", term)
+		}
+		operator := Operator(fields[1])
+		if operator != OpIn && operator != OpNotIn {
+			return Requirement{}, fmt.Errorf("invalid requirement %q: unknown operator %q", term, fields[1])
+		}
+		if err := validateKey(fields[0]); err != nil {
+			return Requirement{}, fmt.Errorf("invalid requirement %q: %w", term, err)
+		}
+		var values []string
+		for _, value := range strings.Split(term[open+1:len(term)-1], ",") {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				return Requirement{}, fmt.Errorf("invalid requirement %q: empty value", term)
+			}
+			if err := validateValue(value); err != nil {
+				return Requirement{}, fmt.Errorf("invalid requirement %q: %w", term, err)
+			}
+			if !slices.Contains(values, value) {
+				values = append(values, value)
+			}
+		}
+		return Requirement{Key: fields[0], Operator: operator, Values: values}, nil
+	}
+
+	if err := validateKey(term); err != nil {
+		return Requirement{}, fmt.Errorf("invalid requirement %q: %w", term, err)
+	}
+	return Requirement{Key: term, Operator: OpExists}, nil
+}
+
+func validateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("empty key")
+	}
+	if strings.ContainsAny(key, " \t\n!=(),") {
+		return fmt.Errorf("key %q contains a reserved character", key)
+	}
+	return nil
+}
+
+func validateValue(value string) error {
+	if strings.ContainsAny(value, " \t\n!=(),") {
+		return fmt.Errorf("value %q contains a reserved character", value)
+	}
+	return nil
+}
+
+// String renders the selector in its canonical form, which Parse accepts.
+func (s Selector) String() string {
+	parts := make([]string, 0, len(s))
+	for _, requirement := range s {
+		parts = append(parts, requirement.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r Requirement) String() string {
+	switch r.Operator {
+	case OpExists:
+		return r.Key
+	case OpDoesNotExist:
+		return "!" + r.Key
+	case OpIn, OpNotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	default:
+		return r.Key + string(r.Operator) + strings.Join(r.Values, "")
+	}
+}
+
+// Matches reports whether a label set satisfies every requirement.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, requirement := range s {
+		value, ok := labels[requirement.Key]
+		var matched bool
+		switch requirement.Operator {
+		case OpEquals, OpIn:
+			matched = ok && slices.Contains(requirement.Values, value)
+		case OpNotEquals, OpNotIn:
+			matched = !ok || !slices.Contains(requirement.Values, value)
+		case OpExists:
+			matched = ok
+		case OpDoesNotExist:
+			matched = !ok
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// SQL compiles the selector to conditions for a WHERE clause. Every condition
+// is an EXISTS or NOT EXISTS subquery on labelsTable whose idColumn equals the
+// owner expression, for example:
+//
+//	conditions, args := sel.SQL("request_labels", "request_id", "requests.id")
+func (s Selector) SQL(labelsTable, idColumn, owner string) ([]string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	for _, requirement := range s {
+		subquery := fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s = %s AND key = ?", labelsTable, idColumn, owner)
+		args = append(args, requirement.Key)
+		if len(requirement.Values) > 0 {
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(requirement.Values)), ", ")
+			subquery += " AND value IN (" + placeholders + ")"
+			for _, value := range requirement.Values {
+				args = append(args, value)
+			}
+		}
+		subquery += ")"
+
+		switch requirement.Operator {
+		case OpNotEquals, OpNotIn, OpDoesNotExist:
+			subquery = "NOT " + subquery
+		}
+		conditions = append(conditions, subquery)
+	}
+	return conditions, args
+}
@@ -0,0 +1,85 @@
+package selector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	sel, err := Parse(" env in (prod, staging), tier!=db,!deprecated , team,owner==alice ")
+	require.NoError(t, err)
+	assert.Equal(t, Selector{
+		{Key: "env", Operator: OpIn, Values: []string{"prod", "staging"}},
+		{Key: "tier", Operator: OpNotEquals, Values: []string{"db"}},
+		{Key: "deprecated", Operator: OpDoesNotExist},
+		{Key: "team", Operator: OpExists},
+		{Key: "owner", Operator: OpEquals, Values: []string{"alice"}},
+	}, sel)
+	assert.Equal(t, "env in (prod,staging),tier!=db,!deprecated,team,owner=alice", sel.String())
+
+	again, err := Parse(sel.String())
+	require.NoError(t, err)
+	assert.Equal(t, sel, again, "the canonical form parses to the same selector")
+
+	empty, err := Parse("  ")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	for _, expression := range []string{
+		"env=prod,",
+		"env in (prod",
+		"env in prod)",
+		"env in ((prod))",
+		"env within (prod)",
+		"env in ()",
+		"=prod",
+		"!",
+		"env=pr od",
+		"bad key",
+	} {
+		_, err := Parse(expression)
+		assert.Error(t, err, "expression %q should be rejected", expression)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{"env": "prod", "team": "core"}
+	for expression, expected := range map[string]bool{
+		"":                         true,
+		"env=prod":                 true,
+		"env!=prod":                false,
+		"tier!=db":                 true,
+		"env in (dev,prod)":        true,
+		"env notin (dev,prod)":     false,
+		"tier notin (db)":          true,
+		"team":                     true,
+		"tier":                     false,
+		"!tier":                    true,
+		"!team":                    false,
+		"env=prod,team=core,!tier": true,
+		"env=prod,team=ops":        false,
+	} {
+		sel, err := Parse(expression)
+		require.NoError(t, err, expression)
+		assert.Equal(t, expected, sel.Matches(labels), "expression %q", expression)
+	}
+}
+
+func TestSQL(t *testing.T) {
+	t.Parallel()
+
+	sel, err := Parse("env in (prod,staging),!deprecated")
+	require.NoError(t, err)
+	conditions, args := sel.SQL("request_labels", "request_id", "requests.id")
+	assert.Equal(t, []string{
+		"EXISTS (SELECT 1 FROM request_labels WHERE request_id = requests.id AND key = ? AND value IN (?, ?))",
+		"NOT EXISTS (SELECT 1 FROM request_labels WHERE request_id = requests.id AND key = ?)",
+	}, conditions)
+	assert.Equal(t, []any{"env", "prod", "staging", "deprecated"}, args)
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+const maxAuditLimit = 1000
+
+func registerAuditRoutes(app fiber.Router) {
+	handler := auditHandler{}
+	app.Get("/audit", handler.list)
+}
+
+type auditHandler struct{}
+
+// list returns the audit events of the namespace, newest first.
+func (h auditHandler) list(c *fiber.Ctx) error {
+	filters, err := parseAuditListFilters(c)
+	if err != nil {
+		return err
+	}
+
+	logRequestEntry(c, "auditHandler.list", map[string]any{
+		"since":         filters.Since,
+		"until":         filters.Until,
+		"resource_type": filters.ResourceType,
+		"resource_id":   filters.ResourceID,
+		"limit":         filters.Limit,
+	})
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	events, err := store.ListAuditEvents(c.UserContext(), filters)
+	if err != nil {
+		logrus.WithError(err).WithField("namespace", namespace).Error("list audit events")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to list audit events")
+	}
+	return c.JSON(events)
+}
+
+func parseAuditListFilters(c *fiber.Ctx) (storage.AuditListFilters, error) {
+	values, err := url.ParseQuery(string(c.Context().URI().QueryString()))
+	if err != nil {
+		return storage.AuditListFilters{}, fiber.NewError(fiber.StatusBadRequest, "invalid query parameters")
+	}
+
+	filters := storage.AuditListFilters{Limit: storage.DefaultAuditListLimit}
+	for _, field := range []struct {
+		name   string
+		target **time.Time
+	}{
+		{"since", &filters.Since},
+		{"until", &filters.Until},
+	} {
+		raw := values.Get(field.name)
+		if raw == "" {
+			continue
+		}
+		value, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return storage.AuditListFilters{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid %s %q: must be an RFC 3339 timestamp", field.name, raw))
+		}
+		*field.target = &value
+	}
+
+	if raw := strings.ToLower(strings.TrimSpace(values.Get("resource_type"))); raw != "" {
+		if !slices.Contains(storage.AuditResourceTypes(), raw) {
+			return storage.AuditListFilters{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid resource_type %q: must be one of %s", raw, strings.Join(storage.AuditResourceTypes(), ", ")))
+		}
+		filters.ResourceType = raw
+	}
+	filters.ResourceID = strings.TrimSpace(values.Get("resource_id"))
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxAuditLimit {
+			return storage.AuditListFilters{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid limit %q: must be between 1 and %d", raw, maxAuditLimit))
+		}
+		filters.Limit = limit
+	}
+	return filters, nil
+}
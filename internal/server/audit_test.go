@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func TestAuditRoutes(t *testing.T) {
+	t.Parallel()
+
+	app, cleanup := newTestApp(t)
+	defer cleanup()
+
+	headers := map[string]string{"REMOTE_USER": "audit-user"}
+
+	res := sendTestRequest(t, app, http.MethodPost, "/hosts", headers, map[string]any{"labels": map[string]string{"env": "prod"}})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	host := decodeJSON[storage.Host](t, res)
+	res = sendTestRequest(t, app, http.MethodPatch, "/hosts/"+host.ID+"/labels", headers, map[string]any{"labels": map[string]string{"env": "dev"}})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/audit?resource_type=hosts&resource_id="+host.ID, headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	events := decodeJSON[[]storage.AuditEvent](t, res)
+	require.Len(t, events, 2)
+	assert.Equal(t, "update_labels", events[0].Action, "newest events come first")
+	assert.Equal(t, map[string]string{"env": "prod"}, events[0].Before.Labels)
+	assert.Equal(t, map[string]string{"env": "dev"}, events[0].After.Labels)
+
+	since := url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))
+	res = sendTestRequest(t, app, http.MethodGet, "/audit?since="+since, headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, decodeJSON[[]storage.AuditEvent](t, res))
+
+	res = sendTestRequest(t, app, http.MethodGet, "/audit?limit=1", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Len(t, decodeJSON[[]storage.AuditEvent](t, res), 1)
+
+	for _, query := range []string{"since=yesterday", "until=2024-13-01", "resource_type=tokens", "limit=0", "limit=1001"} {
+		res = sendTestRequest(t, app, http.MethodGet, "/audit?"+query, headers, nil)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, "query %q should be rejected", query)
+	}
+}
+
+func TestAuditActorFromRequest(t *testing.T) {
+	t.Parallel()
+
+	app, srv := newAuthTestApp(t)
+	registerHostRoutes(app)
+	registerAuditRoutes(app)
+	secret, _ := createTestToken(t, srv, map[string]storage.TokenScope{"team-a": storage.TokenScopeWrite})
+	bearer := map[string]string{"Authorization": "Bearer " + secret}
+
+	res := sendTestRequest(t, app, http.MethodPost, "/hosts", bearer, map[string]any{"labels": map[string]string{"env": "prod"}})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	host := decodeJSON[storage.Host](t, res)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/audit?resource_type=hosts&resource_id="+host.ID, bearer, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	events := decodeJSON[[]storage.AuditEvent](t, res)
+	require.Len(t, events, 1)
+	assert.Equal(t, "create", events[0].Action)
+	assert.Equal(t, "token:test", events[0].Actor, "token mode records the token identity")
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/config"
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+const (
+	namespaceHeader = "REMOTE_USER"
+	tokenCtxKey     = "grantory:token"
+	tokenPrefix     = "gty_"
+	tokenBytes      = 32
+	authRealm       = `Basic realm="grantory"`
+)
+
+// TokenDBPath returns the sqlite file that stores API tokens inside dataDir.
+// It lives in a subdirectory so it can never collide with a namespace database.
+func TokenDBPath(dataDir string) string {
+	return filepath.Join(dataDir, "system", "tokens.db")
+}
+
+// OpenTokenStore opens the API token database of cfg, creating it if needed:
+// a schema in the PostgreSQL database at cfg.StorageURL, or else a SQLite file
+// in cfg.DataDir.
+func OpenTokenStore(ctx context.Context, cfg config.Config) (*storage.TokenStore, error) {
+	if cfg.StorageURL != "" {
+		tokens, err := storage.NewPostgresTokenStore(ctx, cfg.StorageURL)
+		if err != nil {
+			return nil, fmt.Errorf("open token store: %w", err)
+		}
+		return tokens, nil
+	}
+	path := TokenDBPath(cfg.DataDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create token directory: %w", err)
+	}
+	tokens, err := storage.NewTokenStore(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("open token store: %w", err)
+	}
+	return tokens, nil
+}
+
+// GenerateToken returns a new random API token secret.
+func GenerateToken() (string, error) {
+	buf := make([]byte, tokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return tokenPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken returns the representation of a token secret that is stored at rest.
+func HashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// requiredScope maps an HTTP method to the token scope it needs.
+func requiredScope(method string) storage.TokenScope {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return storage.TokenScopeRead
+	default:
+		return storage.TokenScopeWrite
+	}
+}
+
+// tokenFromRequest extracts the token secret from a Bearer header, or from the
+// password of a basic auth header so browsers and basic-auth clients can log in.
+func tokenFromRequest(c *fiber.Ctx) string {
+	header := strings.TrimSpace(c.Get(fiber.HeaderAuthorization))
+	scheme, value, ok := strings.Cut(header, " ")
+	if !ok {
+		return ""
+	}
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		return value
+	case "basic":
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return ""
+		}
+		_, password, _ := strings.Cut(string(decoded), ":")
+		return password
+	default:
+		return ""
+	}
+}
+
+func unauthorized(c *fiber.Ctx, message string) error {
+	c.Set(fiber.HeaderWWWAuthenticate, authRealm)
+	return fiber.NewError(fiber.StatusUnauthorized, message)
+}
+
+// authenticate resolves the API token presented with the request.
+func (s *Server) authenticate(c *fiber.Ctx) (storage.Token, error) {
+	secret := tokenFromRequest(c)
+	if secret == "" {
+		return storage.Token{}, unauthorized(c, "missing API token")
+	}
+
+	token, err := s.tokens.GetTokenByHash(c.UserContext(), HashToken(secret))
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			return storage.Token{}, unauthorized(c, "invalid API token")
+		}
+		logrus.WithError(err).Error("lookup api token")
+		return storage.Token{}, fiber.NewError(fiber.StatusInternalServerError, "unable to verify API token")
+	}
+	if token.Revoked() {
+		return storage.Token{}, unauthorized(c, "API token revoked")
+	}
+	return token, nil
+}
+
+// resolveNamespace determines the namespace for the request and, in token mode,
+// verifies that the presented token may access it with the required scope.
+func (s *Server) resolveNamespace(c *fiber.Ctx) (string, error) {
+	namespace := c.Get(namespaceHeader)
+	if s.tokens == nil {
+		if namespace == "" {
+			namespace = DefaultNamespace
+		}
+		return namespace, nil
+	}
+
+	token, err := s.authenticate(c)
+	if err != nil {
+		return "", err
+	}
+	if namespace == "" {
+		namespace = DefaultNamespace
+		if namespaces := token.Namespaces(); len(namespaces) == 1 && namespaces[0] != storage.TokenNamespaceWildcard {
+			namespace = namespaces[0]
+		}
+	}
+	if err := ValidateNamespaceName(namespace); err != nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	scope := requiredScope(c.Method())
+	if !token.Allows(namespace, scope) {
+		return "", fiber.NewError(fiber.StatusForbidden, fmt.Sprintf("token has no %s access to namespace %q", scope, namespace))
+	}
+
+	c.Locals(tokenCtxKey, token)
+	return namespace, nil
+}
+
+// auditActor identifies who makes a request for the audit log: the token in
+// token mode, otherwise the REMOTE_USER set by the authenticating proxy.
+func auditActor(c *fiber.Ctx) string {
+	if token, ok := c.Locals(tokenCtxKey).(storage.Token); ok {
+		if token.Name != "" {
+			return "token:" + token.Name
+		}
+		return "token:" + token.ID
+	}
+	if user := strings.TrimSpace(c.Get(namespaceHeader)); user != "" {
+		return user
+	}
+	return "anonymous"
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/config"
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func newAuthTestApp(t *testing.T) (*fiber.App, *Server) {
+	t.Helper()
+
+	srv := newTestServer(t, config.Config{DataDir: t.TempDir()})
+	require.NotNil(t, srv.tokens, "token mode should be the default")
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(srv.namespaceMiddleware())
+	probe := func(c *fiber.Ctx) error {
+		namespace, _ := c.Locals(namespaceCtxKey).(string)
+		return c.JSON(map[string]string{"namespace": namespace})
+	}
+	app.Get("/probe", probe)
+	app.Post("/probe", probe)
+	return app, srv
+}
+
+func createTestToken(t *testing.T, srv *Server, scopes map[string]storage.TokenScope) (string, storage.Token) {
+	t.Helper()
+
+	secret, err := GenerateToken()
+	require.NoError(t, err, "GenerateToken() error")
+	token, err := srv.tokens.CreateToken(context.Background(), storage.Token{Name: "test", Scopes: scopes}, HashToken(secret))
+	require.NoError(t, err, "CreateToken() error")
+	return secret, token
+}
+
+func TestTokenAuthentication(t *testing.T) {
+	t.Parallel()
+
+	app, srv := newAuthTestApp(t)
+	secret, token := createTestToken(t, srv, map[string]storage.TokenScope{"team-a": storage.TokenScopeRead})
+
+	res := sendTestRequest(t, app, http.MethodGet, "/probe", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "missing token should be rejected")
+	assert.NotEmpty(t, res.Header.Get(fiber.HeaderWWWAuthenticate), "challenge header should be set")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/probe", map[string]string{"Authorization": "Bearer gty_unknown"}, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "unknown token should be rejected")
+
+	bearer := map[string]string{"Authorization": "Bearer " + secret}
+	res = sendTestRequest(t, app, http.MethodGet, "/probe", bearer, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "read token should read its namespace")
+	body := decodeJSON[map[string]string](t, res)
+	assert.Equal(t, "team-a", body["namespace"], "single-namespace tokens select their namespace")
+
+	res = sendTestRequest(t, app, http.MethodPost, "/probe", bearer, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "read token must not write")
+
+	other := map[string]string{"Authorization": "Bearer " + secret, "REMOTE_USER": "team-b"}
+	res = sendTestRequest(t, app, http.MethodGet, "/probe", other, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "token must not read other namespaces")
+
+	basic := map[string]string{"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte("anyone:"+secret))}
+	res = sendTestRequest(t, app, http.MethodGet, "/probe", basic, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode, "basic auth password should be accepted as token")
+
+	_, err := srv.tokens.RevokeToken(context.Background(), token.ID)
+	require.NoError(t, err, "RevokeToken() error")
+	res = sendTestRequest(t, app, http.MethodGet, "/probe", bearer, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "revoked token should be rejected")
+}
+
+func TestTokenAuthenticationWildcardWrite(t *testing.T) {
+	t.Parallel()
+
+	app, srv := newAuthTestApp(t)
+	secret, _ := createTestToken(t, srv, map[string]storage.TokenScope{storage.TokenNamespaceWildcard: storage.TokenScopeWrite})
+
+	headers := map[string]string{"Authorization": "Bearer " + secret, "REMOTE_USER": "team-c"}
+	res := sendTestRequest(t, app, http.MethodPost, "/probe", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "wildcard write token should write any namespace")
+	body := decodeJSON[map[string]string](t, res)
+	assert.Equal(t, "team-c", body["namespace"])
+
+	headers = map[string]string{"Authorization": "Bearer " + secret}
+	res = sendTestRequest(t, app, http.MethodGet, "/probe", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	body = decodeJSON[map[string]string](t, res)
+	assert.Equal(t, DefaultNamespace, body["namespace"], "wildcard tokens fall back to the default namespace")
+}
+
+func TestProxyAuthModeTrustsHeader(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, config.Config{DataDir: t.TempDir(), AuthMode: config.AuthModeProxy})
+	assert.Nil(t, srv.tokens, "proxy mode should not open the token store")
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(srv.namespaceMiddleware())
+	app.Post("/probe", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusNoContent)
+	})
+
+	res := sendTestRequest(t, app, http.MethodPost, "/probe", map[string]string{"REMOTE_USER": "proxy-user"}, nil)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode, "proxy mode should not require a token")
+}
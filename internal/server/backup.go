@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// backupTimeLayout names snapshot files so that they sort chronologically.
+const backupTimeLayout = "20060102T150405Z"
+
+// backupScheduler periodically writes a snapshot of every namespace into
+// <dir>/<namespace>/<timestamp>.db and removes the oldest snapshots beyond
+// keep.
+type backupScheduler struct {
+	nsStore  *NamespaceStore
+	dir      string
+	interval time.Duration
+	keep     int
+	now      func() time.Time
+}
+
+func newBackupScheduler(nsStore *NamespaceStore, dir string, interval time.Duration, keep int) *backupScheduler {
+	return &backupScheduler{
+		nsStore:  nsStore,
+		dir:      dir,
+		interval: interval,
+		keep:     keep,
+		now:      time.Now,
+	}
+}
+
+func (b *backupScheduler) run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		b.snapshot(ctx)
+	}
+}
+
+// snapshot backs up every namespace in the data directory once.
+func (b *backupScheduler) snapshot(ctx context.Context) {
+	namespaces, err := b.nsStore.NamespaceNames(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("list namespaces for backup")
+		return
+	}
+
+	stamp := b.now().UTC().Format(backupTimeLayout)
+	for _, namespace := range namespaces {
+		if ctx.Err() != nil {
+			return
+		}
+		path, err := b.backup(ctx, namespace, stamp)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				logrus.WithError(err).WithField("namespace", namespace).Warn("scheduled backup")
+			}
+			continue
+		}
+		logrus.WithFields(logrus.Fields{"namespace": namespace, "path": path}).Info("namespace backed up")
+	}
+}
+
+func (b *backupScheduler) backup(ctx context.Context, namespace, stamp string) (string, error) {
+	dir := filepath.Join(b.dir, url.PathEscape(namespace))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("create backup directory: %w", err)
+	}
+	path := filepath.Join(dir, stamp+".db")
+	if err := b.nsStore.BackupNamespace(ctx, namespace, path); err != nil {
+		return "", err
+	}
+	return path, rotateBackups(dir, b.keep)
+}
+
+// rotateBackups removes the oldest snapshots in dir so that at most keep
+// remain. A keep of zero retains every snapshot.
+func rotateBackups(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	snapshots, err := filepath.Glob(filepath.Join(dir, "*.db"))
+	if err != nil {
+		return fmt.Errorf("list backups: %w", err)
+	}
+	if len(snapshots) <= keep {
+		return nil
+	}
+	sort.Strings(snapshots)
+	for _, path := range snapshots[:len(snapshots)-keep] {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove old backup: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/config"
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func TestNamespaceBackupRoute(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	app, srv := newNamespaceTestApp(t, config.Config{DataDir: t.TempDir(), AuthMode: config.AuthModeProxy})
+	store, err := srv.nsStore.StoreFor(ctx, "team-a")
+	require.NoError(t, err)
+	host, err := store.CreateHost(ctx, storage.Host{})
+	require.NoError(t, err)
+
+	res := sendTestRequest(t, app, http.MethodGet, "/namespaces/team-a/backup", nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/vnd.sqlite3", res.Header.Get("Content-Type"))
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "team-a.db")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	backup, err := storage.New(ctx, path)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, backup.Close())
+	}()
+	require.NoError(t, backup.VerifyBackup(ctx))
+	_, err = backup.GetHost(ctx, host.ID)
+	assert.NoError(t, err, "the backup contains the namespace data")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/namespaces/team-b/backup", nil, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestNamespaceBackupRemovesTempDir(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	ctx := context.Background()
+	app, srv := newNamespaceTestApp(t, config.Config{DataDir: t.TempDir(), AuthMode: config.AuthModeProxy})
+	_, err := srv.nsStore.StoreFor(ctx, "team-a")
+	require.NoError(t, err)
+
+	res := sendTestRequest(t, app, http.MethodGet, "/namespaces/team-a/backup", nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.NotEmpty(t, data, "the snapshot is sent before its directory is removed")
+	dirs, err := filepath.Glob(filepath.Join(tmp, "grantory-backup-*"))
+	require.NoError(t, err)
+	assert.Empty(t, dirs, "the backup directory is removed after the response")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/namespaces/team-b/backup", nil, nil)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	dirs, err = filepath.Glob(filepath.Join(tmp, "grantory-backup-*"))
+	require.NoError(t, err)
+	assert.Empty(t, dirs, "failed backups remove their directory")
+}
+
+func TestRestoreNamespace(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	namespaces, err := NewNamespaceStore(ctx, t.TempDir())
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, namespaces.Close())
+	}()
+
+	store, err := namespaces.StoreFor(ctx, "team-a")
+	require.NoError(t, err)
+	host, err := store.CreateHost(ctx, storage.Host{})
+	require.NoError(t, err)
+	backup := filepath.Join(t.TempDir(), "team-a.db")
+	require.NoError(t, namespaces.BackupNamespace(ctx, "team-a", backup))
+	require.NoError(t, store.DeleteHost(ctx, host.ID))
+
+	assert.ErrorIs(t, namespaces.RestoreNamespace(ctx, "team-a", backup, false), ErrNamespaceExists)
+	require.NoError(t, namespaces.RestoreNamespace(ctx, "team-a", backup, true))
+	store, err = namespaces.StoreFor(ctx, "team-a")
+	require.NoError(t, err)
+	_, err = store.GetHost(ctx, host.ID)
+	assert.NoError(t, err, "restore brings back the deleted host")
+
+	require.NoError(t, namespaces.RestoreNamespace(ctx, "team-b", backup, false), "backups can be restored into a new namespace")
+
+	invalid := filepath.Join(t.TempDir(), "invalid.db")
+	require.NoError(t, os.WriteFile(invalid, []byte("not a database"), 0o600))
+	assert.ErrorIs(t, namespaces.RestoreNamespace(ctx, "team-a", invalid, true), storage.ErrInvalidBackup)
+	_, err = store.GetHost(ctx, host.ID)
+	assert.NoError(t, err, "a rejected backup leaves the namespace untouched")
+}
+
+func TestBackupSchedulerRotatesSnapshots(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	namespaces, err := NewNamespaceStore(ctx, t.TempDir())
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, namespaces.Close())
+	}()
+	_, err = namespaces.StoreFor(ctx, "team-a")
+	require.NoError(t, err)
+
+	backupDir := t.TempDir()
+	scheduler := newBackupScheduler(namespaces, backupDir, time.Hour, 2)
+	now := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	scheduler.now = func() time.Time { return now }
+	for range 3 {
+		scheduler.snapshot(ctx)
+		now = now.Add(time.Hour)
+	}
+
+	snapshots, err := filepath.Glob(filepath.Join(backupDir, "team-a", "*.db"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(backupDir, "team-a", "20300102T040405Z.db"),
+		filepath.Join(backupDir, "team-a", "20300102T050405Z.db"),
+	}, snapshots, "only the newest snapshots are kept")
+}
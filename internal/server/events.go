@@ -0,0 +1,170 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+const (
+	defaultEventWait = 30 * time.Second
+	maxEventWait     = 60 * time.Second
+	maxEventLimit    = 1000
+
+	// latestEventCursor starts a listing at the newest event, for clients
+	// that only care about changes from now on.
+	latestEventCursor = "latest"
+)
+
+func registerEventRoutes(app fiber.Router) {
+	handler := eventHandler{}
+	app.Get("/events", handler.list)
+}
+
+type eventHandler struct{}
+
+type eventListQuery struct {
+	filters storage.EventListFilters
+	wait    time.Duration
+	latest  bool
+}
+
+type eventListResponse struct {
+	Events     []storage.Event `json:"events"`
+	NextCursor int64           `json:"next_cursor"`
+}
+
+// list returns events after the `since` cursor. When none are available yet it
+// long-polls for up to `wait` before answering with an empty page, so clients
+// can loop on next_cursor without busy polling.
+func (h eventHandler) list(c *fiber.Ctx) error {
+	query, err := parseEventListQuery(c)
+	if err != nil {
+		return err
+	}
+
+	logRequestEntry(c, "eventHandler.list", map[string]any{
+		"since":          query.filters.Since,
+		"latest":         query.latest,
+		"limit":          query.filters.Limit,
+		"resource_types": query.filters.ResourceTypes,
+		"wait":           query.wait.String(),
+	})
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	latest, err := store.LatestEventSeq(c.UserContext())
+	if err != nil {
+		logrus.WithError(err).WithField("namespace", namespace).Error("load latest event")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to list events")
+	}
+	if query.latest {
+		query.filters.Since = latest
+	}
+	if query.filters.Since > latest {
+		return fiber.NewError(fiber.StatusGone, fmt.Sprintf("cursor %d is ahead of the event feed (latest %d); restart from 0", query.filters.Since, latest))
+	}
+
+	deadline := time.NewTimer(query.wait)
+	defer deadline.Stop()
+
+	for {
+		// Grab the signal before querying so an event committed in between still wakes us.
+		signal := store.EventSignal()
+		events, err := store.ListEvents(c.UserContext(), query.filters)
+		if err != nil {
+			logrus.WithError(err).WithField("namespace", namespace).Error("list events")
+			return fiber.NewError(fiber.StatusInternalServerError, "unable to list events")
+		}
+		if len(events) > 0 || query.wait <= 0 {
+			return c.JSON(newEventListResponse(events, query.filters.Since))
+		}
+
+		select {
+		case <-signal:
+		case <-deadline.C:
+			return c.JSON(newEventListResponse(events, query.filters.Since))
+		case <-c.Context().Done():
+			return c.JSON(newEventListResponse(events, query.filters.Since))
+		}
+	}
+}
+
+func newEventListResponse(events []storage.Event, since int64) eventListResponse {
+	next := since
+	if len(events) > 0 {
+		next = events[len(events)-1].Seq
+	}
+	return eventListResponse{Events: events, NextCursor: next}
+}
+
+func parseEventListQuery(c *fiber.Ctx) (eventListQuery, error) {
+	values, err := url.ParseQuery(string(c.Context().URI().QueryString()))
+	if err != nil {
+		return eventListQuery{}, fiber.NewError(fiber.StatusBadRequest, "invalid query parameters")
+	}
+
+	query := eventListQuery{
+		filters: storage.EventListFilters{Limit: storage.DefaultEventListLimit},
+		wait:    defaultEventWait,
+	}
+
+	if raw := values.Get("since"); raw == latestEventCursor {
+		query.latest = true
+	} else if raw != "" {
+		since, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || since < 0 {
+			return eventListQuery{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid since %q", raw))
+		}
+		query.filters.Since = since
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxEventLimit {
+			return eventListQuery{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid limit %q: must be between 1 and %d", raw, maxEventLimit))
+		}
+		query.filters.Limit = limit
+	}
+
+	if raw := values.Get("wait"); raw != "" {
+		wait, err := time.ParseDuration(raw)
+		if err != nil {
+			seconds, serr := strconv.Atoi(raw)
+			if serr != nil {
+				return eventListQuery{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid wait %q", raw))
+			}
+			wait = time.Duration(seconds) * time.Second
+		}
+		if wait < 0 || wait > maxEventWait {
+			return eventListQuery{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid wait %q: must be between 0 and %s", raw, maxEventWait))
+		}
+		query.wait = wait
+	}
+
+	for _, raw := range values["resource_type"] {
+		for _, value := range strings.Split(raw, ",") {
+			value = strings.ToLower(strings.TrimSpace(value))
+			if value == "" {
+				continue
+			}
+			if !slices.Contains(storage.EventResourceTypes(), value) {
+				return eventListQuery{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid resource_type %q", value))
+			}
+			query.filters.ResourceTypes = append(query.filters.ResourceTypes, value)
+		}
+	}
+
+	return query, nil
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func TestEventFeed(t *testing.T) {
+	t.Parallel()
+
+	app, cleanup := newTestApp(t)
+	defer cleanup()
+
+	headers := map[string]string{"REMOTE_USER": "events-user"}
+
+	res := sendTestRequest(t, app, http.MethodGet, "/events?wait=0", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	empty := decodeJSON[eventListResponse](t, res)
+	assert.Empty(t, empty.Events)
+	assert.Zero(t, empty.NextCursor)
+
+	res = sendTestRequest(t, app, http.MethodPost, "/hosts", headers, map[string]any{})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	host := decodeJSON[storage.Host](t, res)
+
+	res = sendTestRequest(t, app, http.MethodPost, "/requests", headers, map[string]any{"host_id": host.ID})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	req := decodeJSON[storage.Request](t, res)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/events", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	page := decodeJSON[eventListResponse](t, res)
+	require.Len(t, page.Events, 2, "existing events are returned without waiting")
+	assert.Equal(t, storage.EventResourceHosts, page.Events[0].ResourceType)
+	assert.Equal(t, storage.EventActionCreated, page.Events[0].Action)
+	assert.Equal(t, req.ID, page.Events[1].ResourceID)
+	assert.Equal(t, page.Events[1].Seq, page.NextCursor)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/events?resource_type=requests&limit=1", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	filtered := decodeJSON[eventListResponse](t, res)
+	require.Len(t, filtered.Events, 1)
+	assert.Equal(t, storage.EventResourceRequests, filtered.Events[0].ResourceType)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		del := httptest.NewRequest(http.MethodDelete, "/requests/"+req.ID, nil)
+		del.Header.Set("REMOTE_USER", "events-user")
+		if _, err := app.Test(del); err != nil {
+			t.Errorf("delete request: %v", err)
+		}
+	}()
+
+	poll := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/events?since=%d&wait=5s", page.NextCursor), nil)
+	poll.Header.Set("REMOTE_USER", "events-user")
+	started := time.Now()
+	res, err := app.Test(poll, 5000)
+	require.NoError(t, err, "long poll request")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	woken := decodeJSON[eventListResponse](t, res)
+	assert.Less(t, time.Since(started), 4*time.Second, "long poll should return when an event arrives")
+	require.Len(t, woken.Events, 1)
+	assert.Equal(t, storage.EventActionDeleted, woken.Events[0].Action)
+	assert.Greater(t, woken.NextCursor, page.NextCursor)
+
+	res = sendTestRequest(t, app, http.MethodGet, fmt.Sprintf("/events?since=%d&wait=0", woken.NextCursor), headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	idle := decodeJSON[eventListResponse](t, res)
+	assert.Empty(t, idle.Events)
+	assert.Equal(t, woken.NextCursor, idle.NextCursor, "cursor is kept when no events arrive")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/events?since=latest&wait=0", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	latest := decodeJSON[eventListResponse](t, res)
+	assert.Empty(t, latest.Events, "since=latest skips existing events")
+	assert.Equal(t, woken.NextCursor, latest.NextCursor, "since=latest answers with the newest cursor")
+}
+
+func TestEventFeedRejectsInvalidQueries(t *testing.T) {
+	t.Parallel()
+
+	app, cleanup := newTestApp(t)
+	defer cleanup()
+
+	headers := map[string]string{"REMOTE_USER": "events-invalid"}
+	for _, query := range []string{"since=-1", "since=abc", "limit=0", "limit=5000", "wait=2h", "wait=soon", "resource_type=widgets"} {
+		res := sendTestRequest(t, app, http.MethodGet, "/events?"+query, headers, nil)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, "query %q should be rejected", query)
+	}
+
+	res := sendTestRequest(t, app, http.MethodGet, "/events?since=99&wait=0", headers, nil)
+	assert.Equal(t, http.StatusGone, res.StatusCode, "cursor beyond the feed should be rejected")
+}
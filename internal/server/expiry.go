@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/config"
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+// expiryAuditActor is recorded in the audit log for records the reaper removes.
+const expiryAuditActor = "system:expiry"
+
+// expiryReaper periodically removes expired requests, registers and grants
+// from every open namespace. Servers sharing a PostgreSQL database reap at the
+// same time; records another server removed first are skipped.
+type expiryReaper struct {
+	nsStore  *NamespaceStore
+	interval time.Duration
+	now      func() time.Time
+}
+
+func newExpiryReaper(nsStore *NamespaceStore, interval time.Duration) *expiryReaper {
+	return &expiryReaper{
+		nsStore:  nsStore,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+func (r *expiryReaper) run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.reap(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reap runs one pass over every open namespace.
+func (r *expiryReaper) reap(ctx context.Context) {
+	stores := r.nsStore.OpenStores()
+	namespaces := make([]string, 0, len(stores))
+	for namespace := range stores {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	ctx = storage.WithAuditActor(ctx, expiryAuditActor)
+	now := r.now()
+	for _, namespace := range namespaces {
+		if ctx.Err() != nil {
+			return
+		}
+		report, err := stores[namespace].ExpireRecords(ctx, now)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				logrus.WithError(err).WithField("namespace", namespace).Warn("expire records")
+			}
+			continue
+		}
+		if report.Total() > 0 {
+			logrus.WithFields(logrus.Fields{
+				"namespace": namespace,
+				"requests":  report.Requests,
+				"registers": report.Registers,
+				"grants":    report.Grants,
+			}).Info("expired records removed")
+		}
+	}
+}
+
+// parseExpiry resolves the expires_at and ttl fields of a payload. It reports
+// whether either field was given; both at once are rejected. An empty
+// expires_at clears the expiry.
+func parseExpiry(expiresAt, ttl *string, now time.Time) (*time.Time, bool, error) {
+	if expiresAt != nil && ttl != nil {
+		return nil, false, fiber.NewError(fiber.StatusBadRequest, "expires_at and ttl are mutually exclusive")
+	}
+	if ttl != nil {
+		duration, err := config.ParseDuration(*ttl)
+		if err != nil || duration <= 0 {
+			return nil, false, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid ttl %q: must be a positive duration such as 30m, 12h or 7d", *ttl))
+		}
+		value := now.Add(duration)
+		return &value, true, nil
+	}
+	if expiresAt != nil {
+		if strings.TrimSpace(*expiresAt) == "" {
+			return nil, true, nil
+		}
+		value, err := time.Parse(time.RFC3339, *expiresAt)
+		if err != nil {
+			return nil, false, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid expires_at %q: must be an RFC 3339 timestamp", *expiresAt))
+		}
+		return &value, true, nil
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func TestExpiryRoutes(t *testing.T) {
+	t.Parallel()
+
+	app, cleanup := newTestApp(t)
+	defer cleanup()
+
+	headers := map[string]string{"REMOTE_USER": "expiry-user"}
+
+	res := sendTestRequest(t, app, http.MethodPost, "/hosts", headers, map[string]any{})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	host := decodeJSON[storage.Host](t, res)
+
+	before := time.Now()
+	res = sendTestRequest(t, app, http.MethodPost, "/requests", headers, map[string]any{"host_id": host.ID, "ttl": "2d"})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	req := decodeJSON[storage.Request](t, res)
+	require.NotNil(t, req.ExpiresAt)
+	assert.WithinDuration(t, before.Add(48*time.Hour), *req.ExpiresAt, time.Minute)
+
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	res = sendTestRequest(t, app, http.MethodPatch, "/requests/"+req.ID, headers, map[string]any{"expires_at": expiresAt.Format(time.RFC3339)})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	req = decodeJSON[storage.Request](t, res)
+	require.NotNil(t, req.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*req.ExpiresAt))
+
+	res = sendTestRequest(t, app, http.MethodPost, "/registers", headers, map[string]any{"host_id": host.ID, "ttl": "1h"})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	reg := decodeJSON[storage.Register](t, res)
+	require.NotNil(t, reg.ExpiresAt)
+
+	res = sendTestRequest(t, app, http.MethodPatch, "/registers/"+reg.ID, headers, map[string]any{"expires_at": ""})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Nil(t, decodeJSON[storage.Register](t, res).ExpiresAt, "an empty expires_at clears the expiry")
+
+	res = sendTestRequest(t, app, http.MethodPost, "/grants", headers, map[string]any{"request_id": req.ID, "ttl": "30m"})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.NotNil(t, decodeJSON[storage.Grant](t, res).ExpiresAt)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/requests/"+req.ID, headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	withGrant := decodeJSON[requestResponse](t, res)
+	assert.NotEmpty(t, withGrant.Grant["expires_at"], "the embedded grant reports its expiry")
+
+	for _, body := range []map[string]any{
+		{"host_id": host.ID, "ttl": "soon"},
+		{"host_id": host.ID, "ttl": "-1h"},
+		{"host_id": host.ID, "expires_at": "tomorrow"},
+		{"host_id": host.ID, "ttl": "1h", "expires_at": "2030-01-01T00:00:00Z"},
+	} {
+		res = sendTestRequest(t, app, http.MethodPost, "/requests", headers, body)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, "body %v should be rejected", body)
+	}
+	res = sendTestRequest(t, app, http.MethodPatch, "/registers/"+reg.ID, headers, map[string]any{})
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestExpiryReaperRemovesExpiredRecords(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	nsStore, err := NewNamespaceStore(ctx, t.TempDir())
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, nsStore.Close())
+	}()
+	store, err := nsStore.StoreFor(ctx, "reaped")
+	require.NoError(t, err)
+
+	host, err := store.CreateHost(ctx, storage.Host{})
+	require.NoError(t, err)
+	expiresAt := time.Now().Add(time.Hour)
+	req, err := store.CreateRequest(ctx, storage.Request{HostID: host.ID})
+	require.NoError(t, err)
+	_, err = store.CreateGrant(ctx, storage.Grant{RequestID: req.ID, ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+	reg, err := store.CreateRegister(ctx, storage.Register{HostID: host.ID, ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+
+	reaper := newExpiryReaper(nsStore, time.Minute)
+	reaper.reap(ctx)
+	_, err = store.GetRegister(ctx, reg.ID)
+	require.NoError(t, err, "nothing expires before its time")
+
+	reaper.now = func() time.Time { return expiresAt.Add(time.Second) }
+	reaper.reap(ctx)
+
+	_, err = store.GetRegister(ctx, reg.ID)
+	assert.ErrorIs(t, err, storage.ErrRegisterNotFound)
+	loaded, err := store.GetRequest(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, storage.RequestStatusExpired, loaded.Status)
+	assert.False(t, loaded.HasGrant)
+}
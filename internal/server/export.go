@@ -0,0 +1,77 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func registerExportRoutes(app fiber.Router) {
+	handler := exportHandler{}
+	app.Get("/export", handler.export)
+	app.Post("/import", handler.importDocument)
+}
+
+type exportHandler struct{}
+
+func (h exportHandler) export(c *fiber.Ctx) error {
+	logRequestEntry(c, "exportHandler.export", nil)
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	doc, err := store.ExportNamespace(c.UserContext())
+	if err != nil {
+		logrus.WithError(err).WithField("namespace", namespace).Error("export namespace")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to export namespace")
+	}
+	doc.Namespace = namespace
+	return c.JSON(doc)
+}
+
+// importDocument loads an export document into the namespace. The conflict
+// query parameter selects skip, overwrite or fail (the default).
+func (h exportHandler) importDocument(c *fiber.Ctx) error {
+	var doc storage.Export
+	if err := c.BodyParser(&doc); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	policy, err := storage.ParseConflictPolicy(c.Query("conflict", string(storage.ConflictFail)))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	logRequestEntry(c, "exportHandler.importDocument", map[string]any{
+		"version":   doc.Version,
+		"conflict":  policy,
+		"hosts":     len(doc.Hosts),
+		"requests":  len(doc.Requests),
+		"registers": len(doc.Registers),
+		"grants":    len(doc.Grants),
+	})
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := store.ImportNamespace(c.UserContext(), doc, policy)
+	if err != nil {
+		var schemaErr *storage.SchemaValidationError
+		switch {
+		case errors.As(err, &schemaErr):
+			return respondSchemaViolations(c, schemaErr)
+		case errors.Is(err, storage.ErrImportConflict):
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		case errors.Is(err, storage.ErrInvalidExport):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		logrus.WithError(err).WithField("namespace", namespace).Error("import namespace")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to import namespace")
+	}
+	return c.JSON(report)
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func TestExportImportRoutes(t *testing.T) {
+	t.Parallel()
+
+	app, cleanup := newTestApp(t)
+	defer cleanup()
+	targetApp, targetCleanup := newTestApp(t)
+	defer targetCleanup()
+
+	source := map[string]string{"REMOTE_USER": "export-source"}
+	target := map[string]string{"REMOTE_USER": "export-target"}
+
+	res := sendTestRequest(t, app, http.MethodPost, "/hosts", source, map[string]any{"labels": map[string]string{"env": "prod"}})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	host := decodeJSON[storage.Host](t, res)
+	res = sendTestRequest(t, app, http.MethodPost, "/requests", source, map[string]any{"host_id": host.ID, "payload": map[string]any{"db": "app"}})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/export", source, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	doc := decodeJSON[storage.Export](t, res)
+	assert.Equal(t, storage.ExportVersion, doc.Version)
+	assert.Equal(t, "export-source", doc.Namespace)
+	require.Len(t, doc.Hosts, 1)
+	require.Len(t, doc.Requests, 1)
+
+	res = sendTestRequest(t, targetApp, http.MethodPost, "/import", target, doc)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	report := decodeJSON[storage.ImportReport](t, res)
+	assert.Equal(t, 1, report.Hosts.Created)
+	assert.Equal(t, 1, report.Requests.Created)
+
+	res = sendTestRequest(t, targetApp, http.MethodGet, "/hosts/"+host.ID, target, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode, "host IDs are preserved")
+
+	res = sendTestRequest(t, targetApp, http.MethodPost, "/import", target, doc)
+	assert.Equal(t, http.StatusConflict, res.StatusCode, "fail is the default conflict policy")
+	res = sendTestRequest(t, targetApp, http.MethodPost, "/import?conflict=skip", target, doc)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 1, decodeJSON[storage.ImportReport](t, res).Hosts.Skipped)
+	res = sendTestRequest(t, targetApp, http.MethodPost, "/import?conflict=merge", target, doc)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	doc.Version = storage.ExportVersion + 1
+	res = sendTestRequest(t, targetApp, http.MethodPost, "/import?conflict=overwrite", target, doc)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "unknown document versions are rejected")
+}
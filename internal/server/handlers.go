@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -14,6 +15,8 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 
+	"github.com/tasansga/terraform-provider-grantory/internal/config"
+	"github.com/tasansga/terraform-provider-grantory/internal/selector"
 	"github.com/tasansga/terraform-provider-grantory/internal/storage"
 )
 
@@ -25,6 +28,7 @@ func registerHostRoutes(app fiber.Router) {
 	group.Get("/:id", handler.get)
 	group.Delete("/:id", handler.delete)
 	group.Patch("/:id/labels", handler.updateLabels)
+	group.Post("/:id/heartbeat", handler.heartbeat)
 }
 
 type hostHandler struct{}
@@ -75,7 +79,7 @@ func (h hostHandler) create(c *fiber.Ctx) error {
 		return err
 	}
 
-	host, err := store.CreateHost(c.Context(), storage.Host{
+	host, err := store.CreateHost(c.UserContext(), storage.Host{
 		Labels: payload.Labels,
 	})
 	if err != nil {
@@ -94,16 +98,30 @@ func (h hostHandler) create(c *fiber.Ctx) error {
 func (h hostHandler) list(c *fiber.Ctx) error {
 	logRequestEntry(c, "hostHandler.list", nil)
 
+	staleBefore, err := parseStaleAfter(c.Query("stale_after"), time.Now())
+	if err != nil {
+		return err
+	}
+
+	page, err := parsePageOptions(c)
+	if err != nil {
+		return err
+	}
+
 	store, namespace, err := resolveNamespaceStore(c)
 	if err != nil {
 		return err
 	}
 
-	hosts, err := store.ListHosts(c.Context())
+	hosts, next, err := store.ListHostsPage(c.UserContext(), &storage.HostListFilters{LastSeenBefore: staleBefore}, page)
 	if err != nil {
+		if errors.Is(err, storage.ErrInvalidPage) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
 		logrus.WithError(err).WithField("namespace", namespace).Error("list hosts")
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to list hosts")
 	}
+	setNextPageLink(c, next)
 	return c.JSON(hosts)
 }
 
@@ -116,7 +134,7 @@ func (h hostHandler) get(c *fiber.Ctx) error {
 		return err
 	}
 
-	host, err := store.GetHost(c.Context(), hostID)
+	host, err := store.GetHost(c.UserContext(), hostID)
 	if err != nil {
 		if errors.Is(err, storage.ErrHostNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "host not found")
@@ -136,7 +154,7 @@ func (h hostHandler) delete(c *fiber.Ctx) error {
 		return err
 	}
 
-	if err := store.DeleteHost(c.Context(), hostID); err != nil {
+	if err := store.DeleteHost(c.UserContext(), hostID); err != nil {
 		if errors.Is(err, storage.ErrHostNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "host not found")
 		}
@@ -166,7 +184,7 @@ func (h hostHandler) updateLabels(c *fiber.Ctx) error {
 		return err
 	}
 
-	if err := store.UpdateHostLabels(c.Context(), hostID, payload.Labels); err != nil {
+	if err := store.UpdateHostLabels(c.UserContext(), hostID, payload.Labels); err != nil {
 		if errors.Is(err, storage.ErrHostNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "host not found")
 		}
@@ -174,7 +192,7 @@ func (h hostHandler) updateLabels(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to update host")
 	}
 
-	updated, err := store.GetHost(c.Context(), hostID)
+	updated, err := store.GetHost(c.UserContext(), hostID)
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("fetch host after labels update")
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to return host")
@@ -182,6 +200,40 @@ func (h hostHandler) updateLabels(c *fiber.Ctx) error {
 	return c.JSON(updated)
 }
 
+func (h hostHandler) heartbeat(c *fiber.Ctx) error {
+	hostID := c.Params("id")
+	logRequestEntry(c, "hostHandler.heartbeat", map[string]any{"host_id": hostID})
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	host, err := store.RecordHostHeartbeat(c.UserContext(), hostID, time.Now())
+	if err != nil {
+		if errors.Is(err, storage.ErrHostNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "host not found")
+		}
+		logrus.WithError(err).WithField("namespace", namespace).Error("record host heartbeat")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to record heartbeat")
+	}
+	return c.JSON(host)
+}
+
+// parseStaleAfter turns a stale_after duration into the cutoff before which a
+// host's last heartbeat makes it stale. An empty value disables the filter.
+func parseStaleAfter(value string, now time.Time) (*time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	duration, err := config.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid stale_after %q: must be a positive duration such as 12h or 7d", value))
+	}
+	cutoff := now.Add(-duration)
+	return &cutoff, nil
+}
+
 func registerRequestRoutes(app fiber.Router) {
 	handler := requestHandler{}
 	group := app.Group("/requests")
@@ -189,6 +241,8 @@ func registerRequestRoutes(app fiber.Router) {
 	group.Post("/", handler.create)
 	group.Get("/:id", handler.get)
 	group.Patch("/:id", handler.update)
+	group.Patch("/:id/status", handler.updateStatus)
+	group.Get("/:id/revisions", handler.revisions)
 	group.Delete("/:id", handler.delete)
 }
 
@@ -211,13 +265,23 @@ type requestResponse struct {
 }
 
 type requestCreatePayload struct {
-	HostID  string            `json:"host_id"`
-	Payload map[string]any    `json:"payload"`
-	Labels  map[string]string `json:"labels"`
+	HostID    string            `json:"host_id"`
+	Payload   map[string]any    `json:"payload"`
+	Labels    map[string]string `json:"labels"`
+	ExpiresAt *string           `json:"expires_at"`
+	TTL       *string           `json:"ttl"`
 }
 
 type requestUpdatePayload struct {
-	Labels *map[string]string `json:"labels"`
+	Payload   *map[string]any    `json:"payload"`
+	Labels    *map[string]string `json:"labels"`
+	ExpiresAt *string            `json:"expires_at"`
+	TTL       *string            `json:"ttl"`
+}
+
+type requestStatusPayload struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
 }
 
 func (h requestHandler) create(c *fiber.Ctx) error {
@@ -228,11 +292,16 @@ func (h requestHandler) create(c *fiber.Ctx) error {
 	if payload.HostID == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "host_id is required")
 	}
+	expiresAt, _, err := parseExpiry(payload.ExpiresAt, payload.TTL, time.Now())
+	if err != nil {
+		return err
+	}
 
 	logRequestEntry(c, "requestHandler.create", map[string]any{
-		"host_id": payload.HostID,
-		"payload": payload.Payload,
-		"labels":  payload.Labels,
+		"host_id":    payload.HostID,
+		"payload":    payload.Payload,
+		"labels":     payload.Labels,
+		"expires_at": expiresAt,
 	})
 
 	store, namespace, err := resolveNamespaceStore(c)
@@ -241,13 +310,17 @@ func (h requestHandler) create(c *fiber.Ctx) error {
 	}
 
 	req := storage.Request{
-		HostID:  payload.HostID,
-		Payload: payload.Payload,
-		Labels:  payload.Labels,
+		HostID:    payload.HostID,
+		Payload:   payload.Payload,
+		Labels:    payload.Labels,
+		ExpiresAt: expiresAt,
 	}
-	created, err := store.CreateRequest(c.Context(), req)
+	created, err := store.CreateRequest(c.UserContext(), req)
 	if err != nil {
+		var validationErr *storage.SchemaValidationError
 		switch {
+		case errors.As(err, &validationErr):
+			return respondSchemaViolations(c, validationErr)
 		case errors.Is(err, storage.ErrRequestAlreadyExists):
 			return fiber.NewError(fiber.StatusConflict, "request already exists")
 		case errors.Is(err, storage.ErrReferencedHostNotFound):
@@ -258,13 +331,13 @@ func (h requestHandler) create(c *fiber.Ctx) error {
 		}
 	}
 
-	loaded, err := store.GetRequest(c.Context(), created.ID)
+	loaded, err := store.GetRequest(c.UserContext(), created.ID)
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("fetch request after create")
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to return request")
 	}
 
-	response, err := buildRequestResponse(c.Context(), store, loaded)
+	response, err := buildRequestResponse(c.UserContext(), store, loaded)
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).WithField("request_id", created.ID).Error("prepare request response")
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to include grant data")
@@ -281,20 +354,29 @@ func (h requestHandler) list(c *fiber.Ctx) error {
 
 	logRequestEntry(c, "requestHandler.list", map[string]any{"filters": loggableRequestFilters(filters)})
 
+	page, err := parsePageOptions(c)
+	if err != nil {
+		return err
+	}
+
 	store, namespace, err := resolveNamespaceStore(c)
 	if err != nil {
 		return err
 	}
 
-	requests, err := store.ListRequests(c.Context(), &filters)
+	requests, next, err := store.ListRequestsPage(c.UserContext(), &filters, page)
 	if err != nil {
+		if errors.Is(err, storage.ErrInvalidPage) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
 		logrus.WithError(err).WithField("namespace", namespace).Error("list requests")
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to list requests")
 	}
+	setNextPageLink(c, next)
 
 	responses := make([]requestResponse, 0, len(requests))
 	for _, req := range requests {
-		response, err := buildRequestResponse(c.Context(), store, req)
+		response, err := buildRequestResponse(c.UserContext(), store, req)
 		if err != nil {
 			logrus.WithError(err).WithField("namespace", namespace).WithField("request_id", req.ID).Error("prepare request response")
 			return fiber.NewError(fiber.StatusInternalServerError, "unable to include grant data")
@@ -314,7 +396,7 @@ func (h requestHandler) get(c *fiber.Ctx) error {
 		return err
 	}
 
-	req, err := store.GetRequest(c.Context(), reqID)
+	req, err := store.GetRequest(c.UserContext(), reqID)
 	if err != nil {
 		if errors.Is(err, storage.ErrRequestNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "request not found")
@@ -323,7 +405,7 @@ func (h requestHandler) get(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to fetch request")
 	}
 
-	response, err := buildRequestResponse(c.Context(), store, req)
+	response, err := buildRequestResponse(c.UserContext(), store, req)
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).WithField("request_id", req.ID).Error("prepare request response")
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to include grant data")
@@ -340,7 +422,7 @@ func (h requestHandler) delete(c *fiber.Ctx) error {
 		return err
 	}
 
-	if err := store.DeleteRequest(c.Context(), requestID); err != nil {
+	if err := store.DeleteRequest(c.UserContext(), requestID); err != nil {
 		if errors.Is(err, storage.ErrRequestNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "request not found")
 		}
@@ -355,12 +437,24 @@ func loggableRequestFilters(filters storage.RequestListFilters) map[string]any {
 	if filters.HasGrant != nil {
 		entry["has_grant"] = *filters.HasGrant
 	}
+	if filters.StaleGrant != nil {
+		entry["stale_grant"] = *filters.StaleGrant
+	}
+	if len(filters.Statuses) > 0 {
+		entry["statuses"] = filters.Statuses
+	}
 	if len(filters.Labels) > 0 {
 		entry["labels"] = filters.Labels
 	}
 	if len(filters.HostLabels) > 0 {
 		entry["host_labels"] = filters.HostLabels
 	}
+	if len(filters.Selector) > 0 {
+		entry["selector"] = filters.Selector.String()
+	}
+	if len(filters.Payload) > 0 {
+		entry["payload"] = filters.Payload
+	}
 	if len(entry) == 0 {
 		return nil
 	}
@@ -381,6 +475,22 @@ func parseRequestListFilters(c *fiber.Ctx) (storage.RequestListFilters, error) {
 		}
 		filters.HasGrant = &value
 	}
+	if raw := query.Get("stale_grant"); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return storage.RequestListFilters{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid stale_grant %q", raw))
+		}
+		filters.StaleGrant = &value
+	}
+	for _, raw := range query["status"] {
+		for _, part := range strings.Split(raw, ",") {
+			status, err := storage.ParseRequestStatus(part)
+			if err != nil {
+				return storage.RequestListFilters{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid status %q", part))
+			}
+			filters.Statuses = append(filters.Statuses, status)
+		}
+	}
 
 	if filters.Labels, err = parseLabelFilters(query); err != nil {
 		return storage.RequestListFilters{}, err
@@ -388,7 +498,79 @@ func parseRequestListFilters(c *fiber.Ctx) (storage.RequestListFilters, error) {
 	if filters.HostLabels, err = parseHostLabelFilters(query); err != nil {
 		return storage.RequestListFilters{}, err
 	}
+	if filters.Selector, err = parseSelectorFilter(query); err != nil {
+		return storage.RequestListFilters{}, err
+	}
+	if filters.Payload, err = parsePayloadFilters(query); err != nil {
+		return storage.RequestListFilters{}, err
+	}
+
+	return filters, nil
+}
+
+// parsePageOptions reads the limit, cursor, order_by and order query
+// parameters of a list endpoint. Without a limit every row is returned.
+func parsePageOptions(c *fiber.Ctx) (storage.PageOptions, error) {
+	query, err := url.ParseQuery(string(c.Context().URI().QueryString()))
+	if err != nil {
+		return storage.PageOptions{}, fiber.NewError(fiber.StatusBadRequest, "invalid query parameters")
+	}
+
+	page := storage.PageOptions{
+		Cursor:  query.Get("cursor"),
+		OrderBy: strings.TrimSpace(query.Get("order_by")),
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > storage.MaxPageLimit {
+			return storage.PageOptions{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid limit %q: must be between 1 and %d", raw, storage.MaxPageLimit))
+		}
+		page.Limit = limit
+	}
+	if page.Order, err = storage.ParseSortOrder(query.Get("order")); err != nil {
+		return storage.PageOptions{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return page, nil
+}
+
+// setNextPageLink points the Link header at the next page, which repeats the
+// current query with the cursor of the last returned row.
+func setNextPageLink(c *fiber.Ctx, next string) {
+	if next == "" {
+		return
+	}
+	query, err := url.ParseQuery(string(c.Context().URI().QueryString()))
+	if err != nil {
+		return
+	}
+	query.Set("cursor", next)
+	c.Set(fiber.HeaderLink, fmt.Sprintf("<%s?%s>; rel=\"next\"", c.Path(), query.Encode()))
+}
 
+// parseSelectorFilter reads the selector query parameter. Repeated selectors
+// must all match.
+func parseSelectorFilter(query url.Values) (selector.Selector, error) {
+	var sel selector.Selector
+	for _, raw := range query["selector"] {
+		parsed, err := selector.Parse(raw)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		sel = append(sel, parsed...)
+	}
+	return sel, nil
+}
+
+// parsePayloadFilters reads the payload=<path>=<value> query parameters.
+func parsePayloadFilters(query url.Values) ([]storage.PayloadFilter, error) {
+	var filters []storage.PayloadFilter
+	for _, raw := range query["payload"] {
+		filter, err := storage.ParsePayloadFilter(raw)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		filters = append(filters, filter)
+	}
 	return filters, nil
 }
 
@@ -422,7 +604,13 @@ func applyRequestFilters(requests []storage.Request, filters storage.RequestList
 		if filters.HasGrant != nil && req.HasGrant != *filters.HasGrant {
 			continue
 		}
-		if !matchesLabelFilters(req.Labels, filters.Labels) {
+		if filters.StaleGrant != nil && req.StaleGrant != *filters.StaleGrant {
+			continue
+		}
+		if len(filters.Statuses) > 0 && !slices.Contains(filters.Statuses, req.Status) {
+			continue
+		}
+		if !matchesLabelFilters(req.Labels, filters.Labels) || !filters.Selector.Matches(req.Labels) {
 			continue
 		}
 		filtered = append(filtered, req)
@@ -445,8 +633,11 @@ func matchesLabelFilters(labels map[string]string, filters map[string]string) bo
 	return true
 }
 
-func buildRequestResponse(ctx context.Context, store *storage.Store, req storage.Request) (requestResponse, error) {
+func buildRequestResponse(ctx context.Context, store storage.Backend, req storage.Request) (requestResponse, error) {
 	resp := requestResponse{Request: req}
+	if !req.HasGrant {
+		return resp, nil
+	}
 	grant, found, err := store.GetLatestGrantForRequest(ctx, req.ID)
 	if err != nil {
 		return resp, fmt.Errorf("fetch applied grant: %w", err)
@@ -459,9 +650,13 @@ func buildRequestResponse(ctx context.Context, store *storage.Store, req storage
 		return resp, fmt.Errorf("decode grant payload for request %s: %w", req.ID, err)
 	}
 	grantPayload := map[string]any{
-		"grant_id":   grant.ID,
-		"created_at": grant.CreatedAt.Format(time.RFC3339Nano),
-		"updated_at": grant.UpdatedAt.Format(time.RFC3339Nano),
+		"grant_id":         grant.ID,
+		"request_revision": grant.RequestRevision,
+		"created_at":       grant.CreatedAt.Format(time.RFC3339Nano),
+		"updated_at":       grant.UpdatedAt.Format(time.RFC3339Nano),
+	}
+	if grant.ExpiresAt != nil {
+		grantPayload["expires_at"] = grant.ExpiresAt.Format(time.RFC3339Nano)
 	}
 	if payload != nil {
 		grantPayload["payload"] = payload
@@ -489,14 +684,20 @@ func (h requestHandler) update(c *fiber.Ctx) error {
 	if err := c.BodyParser(&payload); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
-	if payload.Labels == nil {
-		return fiber.NewError(fiber.StatusBadRequest, "labels are required")
+	expiresAt, expirySet, err := parseExpiry(payload.ExpiresAt, payload.TTL, time.Now())
+	if err != nil {
+		return err
+	}
+	if payload.Payload == nil && payload.Labels == nil && !expirySet {
+		return fiber.NewError(fiber.StatusBadRequest, "payload, labels, expires_at or ttl is required")
 	}
 
 	reqID := c.Params("id")
 	logRequestEntry(c, "requestHandler.update", map[string]any{
 		"request_id": reqID,
+		"payload":    payload.Payload,
 		"labels":     payload.Labels,
+		"expires_at": expiresAt,
 	})
 
 	store, namespace, err := resolveNamespaceStore(c)
@@ -504,26 +705,99 @@ func (h requestHandler) update(c *fiber.Ctx) error {
 		return err
 	}
 
-	labels := map[string]string(nil)
-	if payload.Labels != nil {
-		labels = *payload.Labels
-	}
-
-	if err := store.UpdateRequestLabels(c.Context(), reqID, labels); err != nil {
+	update := storage.RequestUpdate{
+		Payload:   payload.Payload,
+		Labels:    payload.Labels,
+		SetExpiry: expirySet,
+		ExpiresAt: expiresAt,
+	}
+	if err := store.UpdateRequest(c.UserContext(), reqID, update); err != nil {
+		var validationErr *storage.SchemaValidationError
+		if errors.As(err, &validationErr) {
+			return respondSchemaViolations(c, validationErr)
+		}
 		if errors.Is(err, storage.ErrRequestNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "request not found")
 		}
-		logrus.WithError(err).WithField("namespace", namespace).Error("update request labels")
+		logrus.WithError(err).WithField("namespace", namespace).Error("update request")
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to update request")
 	}
 
-	updated, err := store.GetRequest(c.Context(), reqID)
+	updated, err := store.GetRequest(c.UserContext(), reqID)
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("fetch request after update")
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to return request")
 	}
 
-	response, err := buildRequestResponse(c.Context(), store, updated)
+	response, err := buildRequestResponse(c.UserContext(), store, updated)
+	if err != nil {
+		logrus.WithError(err).WithField("namespace", namespace).WithField("request_id", updated.ID).Error("prepare request response")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to include grant data")
+	}
+	return c.JSON(response)
+}
+
+func (h requestHandler) revisions(c *fiber.Ctx) error {
+	reqID := c.Params("id")
+	logRequestEntry(c, "requestHandler.revisions", map[string]any{"request_id": reqID})
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	revisions, err := store.ListRequestRevisions(c.UserContext(), reqID)
+	if err != nil {
+		if errors.Is(err, storage.ErrRequestNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "request not found")
+		}
+		logrus.WithError(err).WithField("namespace", namespace).Error("list request revisions")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to list request revisions")
+	}
+	return c.JSON(revisions)
+}
+
+func (h requestHandler) updateStatus(c *fiber.Ctx) error {
+	var payload requestStatusPayload
+	if err := c.BodyParser(&payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	status, err := storage.ParseRequestStatus(payload.Status)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid status %q", payload.Status))
+	}
+
+	reqID := c.Params("id")
+	logRequestEntry(c, "requestHandler.updateStatus", map[string]any{
+		"request_id": reqID,
+		"status":     status,
+		"reason":     payload.Reason,
+	})
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	if err := store.SetRequestStatus(c.UserContext(), reqID, status, payload.Reason); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrRequestNotFound):
+			return fiber.NewError(fiber.StatusNotFound, "request not found")
+		case errors.Is(err, storage.ErrInvalidStatusTransition):
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		default:
+			logrus.WithError(err).WithField("namespace", namespace).Error("update request status")
+			return fiber.NewError(fiber.StatusInternalServerError, "unable to update request status")
+		}
+	}
+
+	updated, err := store.GetRequest(c.UserContext(), reqID)
+	if err != nil {
+		logrus.WithError(err).WithField("namespace", namespace).Error("fetch request after status update")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to return request")
+	}
+
+	response, err := buildRequestResponse(c.UserContext(), store, updated)
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).WithField("request_id", updated.ID).Error("prepare request response")
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to include grant data")
@@ -534,13 +808,17 @@ func (h requestHandler) update(c *fiber.Ctx) error {
 type registerHandler struct{}
 
 type registerCreatePayload struct {
-	HostID  string            `json:"host_id"`
-	Payload map[string]any    `json:"payload"`
-	Labels  map[string]string `json:"labels"`
+	HostID    string            `json:"host_id"`
+	Payload   map[string]any    `json:"payload"`
+	Labels    map[string]string `json:"labels"`
+	ExpiresAt *string           `json:"expires_at"`
+	TTL       *string           `json:"ttl"`
 }
 
 type registerUpdatePayload struct {
-	Labels *map[string]string `json:"labels"`
+	Labels    *map[string]string `json:"labels"`
+	ExpiresAt *string            `json:"expires_at"`
+	TTL       *string            `json:"ttl"`
 }
 
 func (h registerHandler) create(c *fiber.Ctx) error {
@@ -551,11 +829,16 @@ func (h registerHandler) create(c *fiber.Ctx) error {
 	if payload.HostID == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "host_id is required")
 	}
+	expiresAt, _, err := parseExpiry(payload.ExpiresAt, payload.TTL, time.Now())
+	if err != nil {
+		return err
+	}
 
 	logRequestEntry(c, "registerHandler.create", map[string]any{
-		"host_id": payload.HostID,
-		"payload": payload.Payload,
-		"labels":  payload.Labels,
+		"host_id":    payload.HostID,
+		"payload":    payload.Payload,
+		"labels":     payload.Labels,
+		"expires_at": expiresAt,
 	})
 
 	store, namespace, err := resolveNamespaceStore(c)
@@ -564,11 +847,12 @@ func (h registerHandler) create(c *fiber.Ctx) error {
 	}
 
 	reg := storage.Register{
-		HostID:  payload.HostID,
-		Payload: payload.Payload,
-		Labels:  payload.Labels,
+		HostID:    payload.HostID,
+		Payload:   payload.Payload,
+		Labels:    payload.Labels,
+		ExpiresAt: expiresAt,
 	}
-	created, err := store.CreateRegister(c.Context(), reg)
+	created, err := store.CreateRegister(c.UserContext(), reg)
 	if err != nil {
 		switch {
 		case errors.Is(err, storage.ErrRegisterAlreadyExists):
@@ -581,7 +865,7 @@ func (h registerHandler) create(c *fiber.Ctx) error {
 		}
 	}
 
-	stored, err := store.GetRegister(c.Context(), created.ID)
+	stored, err := store.GetRegister(c.UserContext(), created.ID)
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("fetch register after create")
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to return register")
@@ -598,16 +882,25 @@ func (h registerHandler) list(c *fiber.Ctx) error {
 
 	logRequestEntry(c, "registerHandler.list", map[string]any{"filters": filters})
 
+	page, err := parsePageOptions(c)
+	if err != nil {
+		return err
+	}
+
 	store, namespace, err := resolveNamespaceStore(c)
 	if err != nil {
 		return err
 	}
 
-	registers, err := store.ListRegisters(c.Context(), &filters)
+	registers, next, err := store.ListRegistersPage(c.UserContext(), &filters, page)
 	if err != nil {
+		if errors.Is(err, storage.ErrInvalidPage) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
 		logrus.WithError(err).WithField("namespace", namespace).Error("list registers")
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to list registers")
 	}
+	setNextPageLink(c, next)
 
 	return c.JSON(registers)
 }
@@ -621,7 +914,7 @@ func (h registerHandler) get(c *fiber.Ctx) error {
 		return err
 	}
 
-	reg, err := store.GetRegister(c.Context(), registerID)
+	reg, err := store.GetRegister(c.UserContext(), registerID)
 	if err != nil {
 		if errors.Is(err, storage.ErrRegisterNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "register not found")
@@ -637,14 +930,19 @@ func (h registerHandler) update(c *fiber.Ctx) error {
 	if err := c.BodyParser(&payload); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
-	if payload.Labels == nil {
-		return fiber.NewError(fiber.StatusBadRequest, "labels are required")
+	expiresAt, expirySet, err := parseExpiry(payload.ExpiresAt, payload.TTL, time.Now())
+	if err != nil {
+		return err
+	}
+	if payload.Labels == nil && !expirySet {
+		return fiber.NewError(fiber.StatusBadRequest, "labels, expires_at or ttl is required")
 	}
 
 	registerID := c.Params("id")
 	logRequestEntry(c, "registerHandler.update", map[string]any{
 		"register_id": registerID,
 		"labels":      payload.Labels,
+		"expires_at":  expiresAt,
 	})
 
 	store, namespace, err := resolveNamespaceStore(c)
@@ -652,20 +950,26 @@ func (h registerHandler) update(c *fiber.Ctx) error {
 		return err
 	}
 
-	labels := map[string]string(nil)
 	if payload.Labels != nil {
-		labels = *payload.Labels
+		if err := store.UpdateRegisterLabels(c.UserContext(), registerID, *payload.Labels); err != nil {
+			if errors.Is(err, storage.ErrRegisterNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "register not found")
+			}
+			logrus.WithError(err).WithField("namespace", namespace).Error("update register labels")
+			return fiber.NewError(fiber.StatusInternalServerError, "unable to update register")
+		}
 	}
-
-	if err := store.UpdateRegisterLabels(c.Context(), registerID, labels); err != nil {
-		if errors.Is(err, storage.ErrRegisterNotFound) {
-			return fiber.NewError(fiber.StatusNotFound, "register not found")
+	if expirySet {
+		if err := store.SetRegisterExpiry(c.UserContext(), registerID, expiresAt); err != nil {
+			if errors.Is(err, storage.ErrRegisterNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "register not found")
+			}
+			logrus.WithError(err).WithField("namespace", namespace).Error("update register expiry")
+			return fiber.NewError(fiber.StatusInternalServerError, "unable to update register")
 		}
-		logrus.WithError(err).WithField("namespace", namespace).Error("update register labels")
-		return fiber.NewError(fiber.StatusInternalServerError, "unable to update register")
 	}
 
-	updated, err := store.GetRegister(c.Context(), registerID)
+	updated, err := store.GetRegister(c.UserContext(), registerID)
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("fetch register after update")
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to return register")
@@ -682,7 +986,7 @@ func (h registerHandler) delete(c *fiber.Ctx) error {
 		return err
 	}
 
-	if err := store.DeleteRegister(c.Context(), registerID); err != nil {
+	if err := store.DeleteRegister(c.UserContext(), registerID); err != nil {
 		if errors.Is(err, storage.ErrRegisterNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "register not found")
 		}
@@ -704,13 +1008,19 @@ func parseRegisterListFilters(c *fiber.Ctx) (storage.RegisterListFilters, error)
 	if filters.HostLabels, err = parseHostLabelFilters(query); err != nil {
 		return storage.RegisterListFilters{}, err
 	}
+	if filters.Selector, err = parseSelectorFilter(query); err != nil {
+		return storage.RegisterListFilters{}, err
+	}
+	if filters.Payload, err = parsePayloadFilters(query); err != nil {
+		return storage.RegisterListFilters{}, err
+	}
 	return filters, nil
 }
 
 func applyRegisterFilters(registers []storage.Register, filters storage.RegisterListFilters) []storage.Register {
 	var filtered []storage.Register
 	for _, reg := range registers {
-		if !matchesLabelFilters(reg.Labels, filters.Labels) {
+		if !matchesLabelFilters(reg.Labels, filters.Labels) || !filters.Selector.Matches(reg.Labels) {
 			continue
 		}
 		filtered = append(filtered, reg)
@@ -724,14 +1034,22 @@ func registerGrantRoutes(app fiber.Router) {
 	group.Get("/", handler.list)
 	group.Post("/", handler.create)
 	group.Get("/:id", handler.get)
+	group.Patch("/:id", handler.update)
 	group.Delete("/:id", handler.delete)
 }
 
 type grantHandler struct{}
 
 type grantCreatePayload struct {
-	RequestID string          `json:"request_id"`
-	Payload   json.RawMessage `json:"payload"`
+	RequestID string            `json:"request_id"`
+	Payload   json.RawMessage   `json:"payload"`
+	Labels    map[string]string `json:"labels"`
+	ExpiresAt *string           `json:"expires_at"`
+	TTL       *string           `json:"ttl"`
+}
+
+type grantUpdatePayload struct {
+	Labels *map[string]string `json:"labels"`
 }
 
 func (h grantHandler) create(c *fiber.Ctx) error {
@@ -742,9 +1060,15 @@ func (h grantHandler) create(c *fiber.Ctx) error {
 	if payload.RequestID == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "request_id is required")
 	}
+	expiresAt, _, err := parseExpiry(payload.ExpiresAt, payload.TTL, time.Now())
+	if err != nil {
+		return err
+	}
 
 	logRequestEntry(c, "grantHandler.create", map[string]any{
 		"request_id": payload.RequestID,
+		"labels":     payload.Labels,
+		"expires_at": expiresAt,
 	})
 
 	store, namespace, err := resolveNamespaceStore(c)
@@ -755,21 +1079,28 @@ func (h grantHandler) create(c *fiber.Ctx) error {
 	grant := storage.Grant{
 		RequestID: payload.RequestID,
 		Payload:   payload.Payload,
+		Labels:    payload.Labels,
+		ExpiresAt: expiresAt,
 	}
-	created, err := store.CreateGrant(c.Context(), grant)
+	created, err := store.CreateGrant(c.UserContext(), grant)
 	if err != nil {
+		var validationErr *storage.SchemaValidationError
 		switch {
+		case errors.As(err, &validationErr):
+			return respondSchemaViolations(c, validationErr)
 		case errors.Is(err, storage.ErrGrantAlreadyExists):
 			return fiber.NewError(fiber.StatusConflict, "grant already exists")
 		case errors.Is(err, storage.ErrReferencedRequestNotFound):
 			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("request %s not found", payload.RequestID))
+		case errors.Is(err, storage.ErrInvalidStatusTransition):
+			return fiber.NewError(fiber.StatusConflict, err.Error())
 		default:
 			logrus.WithError(err).WithField("namespace", namespace).Error("create grant")
 			return fiber.NewError(fiber.StatusInternalServerError, "unable to persist grant")
 		}
 	}
 
-	stored, err := store.GetGrant(c.Context(), created.ID)
+	stored, err := store.GetGrant(c.UserContext(), created.ID)
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("fetch grant after create")
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to return grant")
@@ -778,18 +1109,32 @@ func (h grantHandler) create(c *fiber.Ctx) error {
 }
 
 func (h grantHandler) list(c *fiber.Ctx) error {
-	logRequestEntry(c, "grantHandler.list", nil)
+	filters, err := parseGrantListFilters(c)
+	if err != nil {
+		return err
+	}
+
+	logRequestEntry(c, "grantHandler.list", map[string]any{"filters": filters})
+
+	page, err := parsePageOptions(c)
+	if err != nil {
+		return err
+	}
 
 	store, namespace, err := resolveNamespaceStore(c)
 	if err != nil {
 		return err
 	}
 
-	grants, err := store.ListGrants(c.Context())
+	grants, next, err := store.ListGrantsPage(c.UserContext(), &filters, page)
 	if err != nil {
+		if errors.Is(err, storage.ErrInvalidPage) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
 		logrus.WithError(err).WithField("namespace", namespace).Error("list grants")
 		return fiber.NewError(fiber.StatusInternalServerError, "unable to list grants")
 	}
+	setNextPageLink(c, next)
 	return c.JSON(grants)
 }
 
@@ -801,7 +1146,7 @@ func (h grantHandler) get(c *fiber.Ctx) error {
 		return err
 	}
 
-	grant, err := store.GetGrant(c.Context(), grantID)
+	grant, err := store.GetGrant(c.UserContext(), grantID)
 	if err != nil {
 		if errors.Is(err, storage.ErrGrantNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "grant not found")
@@ -812,6 +1157,54 @@ func (h grantHandler) get(c *fiber.Ctx) error {
 	return c.JSON(grant)
 }
 
+func (h grantHandler) update(c *fiber.Ctx) error {
+	var payload grantUpdatePayload
+	if err := c.BodyParser(&payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if payload.Labels == nil {
+		return fiber.NewError(fiber.StatusBadRequest, "labels is required")
+	}
+
+	grantID := c.Params("id")
+	logRequestEntry(c, "grantHandler.update", map[string]any{
+		"grant_id": grantID,
+		"labels":   payload.Labels,
+	})
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	if err := store.UpdateGrantLabels(c.UserContext(), grantID, *payload.Labels); err != nil {
+		if errors.Is(err, storage.ErrGrantNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "grant not found")
+		}
+		logrus.WithError(err).WithField("namespace", namespace).Error("update grant labels")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to update grant")
+	}
+
+	updated, err := store.GetGrant(c.UserContext(), grantID)
+	if err != nil {
+		logrus.WithError(err).WithField("namespace", namespace).Error("fetch grant after update")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to return grant")
+	}
+	return c.JSON(updated)
+}
+
+func parseGrantListFilters(c *fiber.Ctx) (storage.GrantListFilters, error) {
+	query, err := url.ParseQuery(string(c.Context().URI().QueryString()))
+	if err != nil {
+		return storage.GrantListFilters{}, fiber.NewError(fiber.StatusBadRequest, "invalid query parameters")
+	}
+	filters := storage.GrantListFilters{}
+	if filters.Labels, err = parseLabelFilters(query); err != nil {
+		return storage.GrantListFilters{}, err
+	}
+	return filters, nil
+}
+
 func (h grantHandler) delete(c *fiber.Ctx) error {
 	grantID := c.Params("id")
 	logRequestEntry(c, "grantHandler.delete", map[string]any{"grant_id": grantID})
@@ -821,7 +1214,7 @@ func (h grantHandler) delete(c *fiber.Ctx) error {
 		return err
 	}
 
-	if err := store.DeleteGrant(c.Context(), grantID); err != nil {
+	if err := store.DeleteGrant(c.UserContext(), grantID); err != nil {
 		if errors.Is(err, storage.ErrGrantNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "grant not found")
 		}
@@ -831,7 +1224,7 @@ func (h grantHandler) delete(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
-func resolveNamespaceStore(c *fiber.Ctx) (*storage.Store, string, error) {
+func resolveNamespaceStore(c *fiber.Ctx) (storage.Backend, string, error) {
 	namespace := namespaceFromCtx(c)
 	store := storeFromLocals(c.Locals(storeCtxKey))
 	if store == nil {
@@ -841,9 +1234,9 @@ func resolveNamespaceStore(c *fiber.Ctx) (*storage.Store, string, error) {
 	return store, namespace, nil
 }
 
-func storeFromLocals(value interface{}) *storage.Store {
+func storeFromLocals(value interface{}) storage.Backend {
 	switch v := value.(type) {
-	case *storage.Store:
+	case storage.Backend:
 		return v
 	case localStore:
 		return v.store
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/config"
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func TestHostHeartbeatRoutes(t *testing.T) {
+	t.Parallel()
+
+	app, cleanup := newTestApp(t)
+	defer cleanup()
+
+	headers := map[string]string{"REMOTE_USER": "heartbeat-user"}
+
+	res := sendTestRequest(t, app, http.MethodPost, "/hosts", headers, map[string]any{})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	host := decodeJSON[storage.Host](t, res)
+	assert.Nil(t, host.LastSeenAt)
+
+	before := time.Now()
+	res = sendTestRequest(t, app, http.MethodPost, "/hosts/"+host.ID+"/heartbeat", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	seen := decodeJSON[storage.Host](t, res)
+	require.NotNil(t, seen.LastSeenAt)
+	assert.WithinDuration(t, before, *seen.LastSeenAt, time.Minute)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/hosts?stale_after=1h", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, decodeJSON[[]storage.Host](t, res), "a fresh heartbeat is not stale")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/hosts", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Len(t, decodeJSON[[]storage.Host](t, res), 1)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/hosts?stale_after=soon", headers, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "invalid stale_after should fail")
+
+	res = sendTestRequest(t, app, http.MethodPost, "/hosts/missing/heartbeat", headers, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestIndexMarksStaleHosts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cfg := config.Config{DataDir: t.TempDir(), AuthMode: config.AuthModeProxy, HostStaleAfter: time.Hour}
+	srv, err := New(ctx, cfg)
+	require.NoError(t, err, "New() should succeed")
+	defer func() {
+		if err := srv.Close(); err != nil {
+			t.Errorf("close server: %v", err)
+		}
+	}()
+
+	store, err := srv.nsStore.StoreFor(ctx, "index-user")
+	require.NoError(t, err)
+	host, err := store.CreateHost(ctx, storage.Host{})
+	require.NoError(t, err)
+	_, err = store.RecordHostHeartbeat(ctx, host.ID, time.Now().Add(-2*time.Hour))
+	require.NoError(t, err)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(srv.namespaceMiddleware())
+	app.Get("/index.html", srv.handleIndex)
+
+	res := sendTestRequest(t, app, http.MethodGet, "/index.html", map[string]string{"REMOTE_USER": "index-user"}, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `<mark class="stale">stale</mark>`, "hosts past the heartbeat cutoff are marked")
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
@@ -23,6 +24,8 @@ var (
 
 	indexTemplate = template.Must(template.New("index").Funcs(template.FuncMap{
 		"labelSummary": labelSummary,
+		"hostStale":    hostStale,
+		"timestamp":    timestamp,
 	}).Parse(indexTemplateSource))
 )
 
@@ -37,6 +40,8 @@ type indexPageData struct {
 	Requests             []storage.Request
 	Grants               []storage.Grant
 	Registers            []storage.Register
+	// StaleBefore is the heartbeat cutoff for stale hosts; nil disables the marker.
+	StaleBefore *time.Time
 }
 
 func (s *Server) handleIndex(c *fiber.Ctx) error {
@@ -47,43 +52,43 @@ func (s *Server) handleIndex(c *fiber.Ctx) error {
 		return err
 	}
 
-	reqCounts, err := store.CountRequestsByGrantPresence(c.Context())
+	reqCounts, err := store.CountRequestsByGrantPresence(c.UserContext())
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("count requests for index")
 		return fiber.NewError(http.StatusInternalServerError, "unable to collect request stats")
 	}
 
-	registerCounts, err := store.CountRegisters(c.Context())
+	registerCounts, err := store.CountRegisters(c.UserContext())
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("count registers for index")
 		return fiber.NewError(http.StatusInternalServerError, "unable to collect register stats")
 	}
 
-	grantCounts, err := store.CountGrants(c.Context())
+	grantCounts, err := store.CountGrants(c.UserContext())
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("count grants for index")
 		return fiber.NewError(http.StatusInternalServerError, "unable to collect grant stats")
 	}
 
-	hosts, err := store.ListHosts(c.Context())
+	hosts, err := store.ListHosts(c.UserContext())
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("list hosts for index")
 		return fiber.NewError(http.StatusInternalServerError, "unable to list hosts")
 	}
 
-	requests, err := store.ListRequests(c.Context(), nil)
+	requests, err := store.ListRequests(c.UserContext(), nil)
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("list requests for index")
 		return fiber.NewError(http.StatusInternalServerError, "unable to list requests")
 	}
 
-	registers, err := store.ListRegisters(c.Context(), nil)
+	registers, err := store.ListRegisters(c.UserContext(), nil)
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("list registers for index")
 		return fiber.NewError(http.StatusInternalServerError, "unable to list registers")
 	}
 
-	grants, err := store.ListGrants(c.Context())
+	grants, err := store.ListGrants(c.UserContext(), nil)
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("list grants for index")
 		return fiber.NewError(http.StatusInternalServerError, "unable to list grants")
@@ -101,6 +106,10 @@ func (s *Server) handleIndex(c *fiber.Ctx) error {
 		Grants:               grants,
 		Registers:            registers,
 	}
+	if s.cfg.HostStaleAfter > 0 {
+		staleBefore := time.Now().Add(-s.cfg.HostStaleAfter)
+		data.StaleBefore = &staleBefore
+	}
 
 	var buf bytes.Buffer
 	if err := indexTemplate.Execute(&buf, data); err != nil {
@@ -134,3 +143,14 @@ func labelSummary(labels map[string]string) string {
 	}
 	return strings.Join(parts, ", ")
 }
+
+func hostStale(host storage.Host, before *time.Time) bool {
+	return before != nil && host.IsStale(*before)
+}
+
+func timestamp(value *time.Time) string {
+	if value == nil {
+		return "—"
+	}
+	return value.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+const metricsNamespace = "grantory"
+
+// serverMetrics holds the Prometheus collectors served on /metrics. Each
+// server uses its own registry so that several servers can share a process.
+type serverMetrics struct {
+	registry     *prometheus.Registry
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	dbDuration   *prometheus.HistogramVec
+}
+
+func newServerMetrics(nsStore *NamespaceStore) *serverMetrics {
+	m := &serverMetrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency, by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		dbDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "db_operation_duration_seconds",
+			Help:      "Database operation latency, by table and operation.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 9),
+		}, []string{"table", "operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.dbDuration,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "namespace_stores_open",
+			Help:      "Namespace databases currently held open by the server.",
+		}, func() float64 {
+			return float64(len(nsStore.OpenStores()))
+		}),
+		newNamespaceCollector(nsStore),
+	)
+	nsStore.SetOperationObserver(m.observeDBOperation)
+	return m
+}
+
+func (m *serverMetrics) observeDBOperation(table, operation string, duration time.Duration) {
+	m.dbDuration.WithLabelValues(table, operation).Observe(duration.Seconds())
+}
+
+// middleware records the count and latency of every request. Requests are
+// labelled by route pattern rather than path to keep the cardinality bounded.
+// Fiber reuses its buffers, so label values are copied.
+func (m *serverMetrics) middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+		labels := prometheus.Labels{
+			"method": utils.CopyString(c.Method()),
+			"route":  utils.CopyString(c.Route().Path),
+			"status": strconv.Itoa(responseStatus(c, err)),
+		}
+		m.httpRequests.With(labels).Inc()
+		m.httpDuration.With(labels).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+func (m *serverMetrics) handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+// responseStatus returns the status code a request ends with, including
+// errors that fiber's error handler has not turned into a response yet.
+func responseStatus(c *fiber.Ctx, err error) int {
+	status := c.Response().StatusCode()
+	if err != nil {
+		var fe *fiber.Error
+		if errors.As(err, &fe) {
+			return fe.Code
+		}
+		if status < http.StatusBadRequest {
+			return http.StatusInternalServerError
+		}
+	}
+	return status
+}
+
+// namespaceCollector reports record counts for every open namespace at
+// scrape time.
+type namespaceCollector struct {
+	nsStore   *NamespaceStore
+	hosts     *prometheus.Desc
+	requests  *prometheus.Desc
+	registers *prometheus.Desc
+	grants    *prometheus.Desc
+}
+
+func newNamespaceCollector(nsStore *NamespaceStore) *namespaceCollector {
+	return &namespaceCollector{
+		nsStore:   nsStore,
+		hosts:     prometheus.NewDesc(metricsNamespace+"_hosts", "Hosts stored in the namespace.", []string{"namespace"}, nil),
+		requests:  prometheus.NewDesc(metricsNamespace+"_requests", "Requests stored in the namespace, by status.", []string{"namespace", "status"}, nil),
+		registers: prometheus.NewDesc(metricsNamespace+"_registers", "Registers stored in the namespace.", []string{"namespace"}, nil),
+		grants:    prometheus.NewDesc(metricsNamespace+"_grants", "Grants stored in the namespace.", []string{"namespace"}, nil),
+	}
+}
+
+func (n *namespaceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- n.hosts
+	ch <- n.requests
+	ch <- n.registers
+	ch <- n.grants
+}
+
+func (n *namespaceCollector) Collect(ch chan<- prometheus.Metric) {
+	for namespace, store := range n.nsStore.OpenStores() {
+		stats, err := store.Stats(n.nsStore.ctx)
+		if err != nil {
+			logrus.WithError(err).WithField("namespace", namespace).Warn("collect namespace metrics")
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(n.hosts, prometheus.GaugeValue, float64(stats.Hosts), namespace)
+		for status, count := range stats.RequestsByStatus {
+			ch <- prometheus.MustNewConstMetric(n.requests, prometheus.GaugeValue, float64(count), namespace, status)
+		}
+		ch <- prometheus.MustNewConstMetric(n.registers, prometheus.GaugeValue, float64(stats.Registers), namespace)
+		ch <- prometheus.MustNewConstMetric(n.grants, prometheus.GaugeValue, float64(stats.Grants), namespace)
+	}
+}
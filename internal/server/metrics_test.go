@@ -0,0 +1,83 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/config"
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func newMetricsTestApp(t *testing.T, cfg config.Config) (*fiber.App, *Server) {
+	t.Helper()
+
+	srv := newTestServer(t, cfg)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(srv.metrics.middleware())
+	app.Get("/metrics", srv.metricsAuthMiddleware(), srv.metrics.handler())
+	api := app.Group("/", srv.namespaceMiddleware())
+	registerHostRoutes(api)
+	api.Get("/metrics/json", srv.handleMetrics)
+	return app, srv
+}
+
+func scrapeMetrics(t *testing.T, app *fiber.App, headers map[string]string) string {
+	t.Helper()
+
+	res := sendTestRequest(t, app, http.MethodGet, "/metrics", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "metrics status")
+	assert.Contains(t, res.Header.Get("Content-Type"), "text/plain")
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err, "read metrics")
+	return string(body)
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	t.Parallel()
+
+	app, _ := newMetricsTestApp(t, config.Config{DataDir: t.TempDir(), AuthMode: config.AuthModeProxy})
+
+	headers := map[string]string{"REMOTE_USER": "team-a"}
+	res := sendTestRequest(t, app, http.MethodPost, "/hosts", headers, map[string]any{"labels": map[string]string{"env": "prod"}})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	res = sendTestRequest(t, app, http.MethodGet, "/hosts/missing", headers, nil)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	body := scrapeMetrics(t, app, nil)
+	assert.Contains(t, body, `grantory_hosts{namespace="team-a"} 1`)
+	assert.Contains(t, body, `grantory_requests{namespace="team-a",status="pending"} 0`)
+	assert.Contains(t, body, `grantory_registers{namespace="team-a"} 0`)
+	assert.Contains(t, body, `grantory_grants{namespace="team-a"} 0`)
+	assert.Contains(t, body, `grantory_namespace_stores_open 1`)
+	assert.Contains(t, body, `grantory_http_requests_total{method="POST",route="/hosts/",status="201"} 1`)
+	assert.Contains(t, body, `grantory_http_requests_total{method="GET",route="/hosts/:id",status="404"} 1`, "routes are labelled by pattern")
+	assert.Contains(t, body, `grantory_http_request_duration_seconds_count{method="POST",route="/hosts/",status="201"} 1`)
+	assert.Contains(t, body, `grantory_db_operation_duration_seconds_count{operation="create",table="hosts"} 1`)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/metrics/json", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "the JSON metrics stay available")
+	metrics := decodeJSON[map[string]map[string]int64](t, res)
+	assert.EqualValues(t, 0, metrics["requests"]["without_grant"])
+}
+
+func TestPrometheusMetricsRequireWildcardToken(t *testing.T) {
+	t.Parallel()
+
+	app, srv := newMetricsTestApp(t, config.Config{DataDir: t.TempDir()})
+	scoped, _ := createTestToken(t, srv, map[string]storage.TokenScope{"team-a": storage.TokenScopeRead})
+	reader, _ := createTestToken(t, srv, map[string]storage.TokenScope{storage.TokenNamespaceWildcard: storage.TokenScopeRead})
+
+	res := sendTestRequest(t, app, http.MethodGet, "/metrics", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	res = sendTestRequest(t, app, http.MethodGet, "/metrics", map[string]string{"Authorization": "Bearer " + scoped}, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "metrics cover every namespace")
+
+	body := scrapeMetrics(t, app, map[string]string{"Authorization": "Bearer " + reader})
+	assert.Contains(t, body, `grantory_http_requests_total{method="GET",route="/metrics",status="401"} 1`, "rejected scrapes are counted")
+}
@@ -2,11 +2,15 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/sirupsen/logrus"
@@ -20,6 +24,15 @@ const (
 	namespaceMinLength = 4
 )
 
+var (
+	// ErrNamespaceNotFound is returned when a namespace has no database and
+	// implicit creation is disabled.
+	ErrNamespaceNotFound = errors.New("namespace not found")
+	// ErrNamespaceExists is returned when creating a namespace that already has
+	// a database.
+	ErrNamespaceExists = errors.New("namespace already exists")
+)
+
 var namespacePattern = regexp.MustCompile(`^[A-Za-z0-9_+,\-\.=:]{4,}$`)
 
 // NamespaceDBPath returns the sqlite file path for the given namespace inside dataDir.
@@ -28,6 +41,43 @@ func NamespaceDBPath(dataDir, namespace string) string {
 	return filepath.Join(dataDir, escaped+".db")
 }
 
+// ListNamespaceNames returns the namespaces that have a database file in dataDir.
+func ListNamespaceNames(dataDir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dataDir, "*.db"))
+	if err != nil {
+		return nil, fmt.Errorf("list namespace databases: %w", err)
+	}
+
+	namespaces := make([]string, 0, len(matches))
+	for _, match := range matches {
+		namespace, err := url.PathUnescape(strings.TrimSuffix(filepath.Base(match), ".db"))
+		if err != nil || ValidateNamespaceName(namespace) != nil {
+			logrus.WithField("path", match).Warn("ignoring database with invalid namespace name")
+			continue
+		}
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// NamespaceSize returns the combined size in bytes of the namespace database
+// and its sqlite journal files.
+func NamespaceSize(path string) (int64, error) {
+	var size int64
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		info, err := os.Stat(path + suffix)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return 0, fmt.Errorf("stat namespace database: %w", err)
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
+
 // ValidateNamespaceName ensures the namespace matches the allowed format.
 func ValidateNamespaceName(value string) error {
 	if value == "" {
@@ -42,12 +92,37 @@ func ValidateNamespaceName(value string) error {
 	return nil
 }
 
-// NamespaceStore manages sqlite stores split by namespace.
+// NamespaceInfo describes a namespace database in the data directory.
+type NamespaceInfo struct {
+	Name      string `json:"name"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// NamespaceStats combines NamespaceInfo with the record counts of the
+// namespace database.
+type NamespaceStats struct {
+	NamespaceInfo
+	storage.Stats
+}
+
+// NamespaceStore manages the stores of all namespaces on one storage backend.
 type NamespaceStore struct {
-	ctx     context.Context
-	dataDir string
-	mu      sync.Mutex
-	stores  map[string]*storage.Store
+	ctx        context.Context
+	databases  namespaceDatabases
+	autoCreate bool
+	mu         sync.Mutex
+	stores     map[string]storage.Backend
+	observer   storage.OperationObserver
+}
+
+// OpenNamespaceStore creates a manager for the storage backend selected in
+// cfg: the PostgreSQL database at StorageURL if set, otherwise SQLite files
+// in DataDir.
+func OpenNamespaceStore(ctx context.Context, cfg config.Config) (*NamespaceStore, error) {
+	if cfg.StorageURL != "" {
+		return NewPostgresNamespaceStore(ctx, cfg.StorageURL)
+	}
+	return NewNamespaceStore(ctx, cfg.DataDir)
 }
 
 // NewNamespaceStore creates a manager for the provided data directory.
@@ -58,15 +133,36 @@ func NewNamespaceStore(ctx context.Context, dataDir string) (*NamespaceStore, er
 	if err := os.MkdirAll(dataDir, 0o755); err != nil {
 		return nil, fmt.Errorf("create data dir: %w", err)
 	}
+	return newNamespaceStore(ctx, sqliteDatabases{dataDir: dataDir}), nil
+}
+
+// NewPostgresNamespaceStore creates a manager for the PostgreSQL database at
+// storageURL, which keeps every namespace in a schema of its own.
+func NewPostgresNamespaceStore(ctx context.Context, storageURL string) (*NamespaceStore, error) {
+	if !storage.IsPostgresURL(storageURL) {
+		return nil, fmt.Errorf("unsupported storage url: must start with postgres:// or postgresql://")
+	}
+	return newNamespaceStore(ctx, postgresDatabases{url: storageURL}), nil
+}
+
+func newNamespaceStore(ctx context.Context, databases namespaceDatabases) *NamespaceStore {
 	return &NamespaceStore{
-		ctx:     ctx,
-		dataDir: dataDir,
-		stores:  make(map[string]*storage.Store),
-	}, nil
+		ctx:        ctx,
+		databases:  databases,
+		autoCreate: true,
+		stores:     make(map[string]storage.Backend),
+	}
+}
+
+// SetAutoCreate controls whether StoreFor creates the database of an unknown
+// namespace. When disabled, only namespaces created through CreateNamespace
+// (and the default namespace) can be opened.
+func (n *NamespaceStore) SetAutoCreate(enabled bool) {
+	n.autoCreate = enabled
 }
 
-// StoreFor returns the sqlite store for namespace, creating it if needed.
-func (n *NamespaceStore) StoreFor(ctx context.Context, namespace string) (*storage.Store, error) {
+// StoreFor returns the store for namespace, creating it if needed.
+func (n *NamespaceStore) StoreFor(ctx context.Context, namespace string) (storage.Backend, error) {
 	if err := ValidateNamespaceName(namespace); err != nil {
 		return nil, err
 	}
@@ -75,12 +171,290 @@ func (n *NamespaceStore) StoreFor(ctx context.Context, namespace string) (*stora
 		return saved, nil
 	}
 
-	path := NamespaceDBPath(n.dataDir, namespace)
+	if !n.autoCreate && namespace != DefaultNamespace {
+		exists, err := n.databases.exists(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: %s", ErrNamespaceNotFound, namespace)
+		}
+	}
+	return n.open(ctx, namespace)
+}
+
+// CreateNamespace creates and migrates the database of a new namespace.
+func (n *NamespaceStore) CreateNamespace(ctx context.Context, namespace string) (NamespaceInfo, error) {
+	if err := ValidateNamespaceName(namespace); err != nil {
+		return NamespaceInfo{}, err
+	}
+	exists, err := n.databases.exists(ctx, namespace)
+	if err != nil {
+		return NamespaceInfo{}, err
+	}
+	if exists || n.get(namespace) != nil {
+		return NamespaceInfo{}, fmt.Errorf("%w: %s", ErrNamespaceExists, namespace)
+	}
+	if _, err := n.open(ctx, namespace); err != nil {
+		return NamespaceInfo{}, err
+	}
+	size, err := n.databases.size(ctx, namespace)
+	if err != nil {
+		return NamespaceInfo{}, err
+	}
+	return NamespaceInfo{Name: namespace, SizeBytes: size}, nil
+}
+
+// ListNamespaces returns every namespace that has a database.
+func (n *NamespaceStore) ListNamespaces(ctx context.Context) ([]NamespaceInfo, error) {
+	names, err := n.NamespaceNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make([]NamespaceInfo, 0, len(names))
+	for _, name := range names {
+		size, err := n.databases.size(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, NamespaceInfo{Name: name, SizeBytes: size})
+	}
+	return namespaces, nil
+}
+
+// NamespaceNames returns the names of every namespace that has a database,
+// in sorted order.
+func (n *NamespaceStore) NamespaceNames(ctx context.Context) ([]string, error) {
+	return n.databases.list(ctx)
+}
+
+// NamespaceExists reports whether namespace has a database.
+func (n *NamespaceStore) NamespaceExists(ctx context.Context, namespace string) (bool, error) {
+	if err := ValidateNamespaceName(namespace); err != nil {
+		return false, err
+	}
+	return n.databases.exists(ctx, namespace)
+}
+
+// NamespaceLocation describes where the database of namespace is kept: the
+// file path on SQLite or the schema name on PostgreSQL.
+func (n *NamespaceStore) NamespaceLocation(namespace string) string {
+	return n.databases.location(namespace)
+}
+
+// NamespaceStats returns the size and record counts of an existing namespace.
+func (n *NamespaceStore) NamespaceStats(ctx context.Context, namespace string) (NamespaceStats, error) {
+	store, err := n.existing(ctx, namespace)
+	if err != nil {
+		return NamespaceStats{}, err
+	}
+
+	stats, err := store.Stats(ctx)
+	if err != nil {
+		return NamespaceStats{}, err
+	}
+	size, err := n.databases.size(ctx, namespace)
+	if err != nil {
+		return NamespaceStats{}, err
+	}
+	return NamespaceStats{NamespaceInfo: NamespaceInfo{Name: namespace, SizeBytes: size}, Stats: stats}, nil
+}
+
+// BackupNamespace writes a consistent snapshot of an existing namespace to
+// path, which must not exist yet. Only SQLite namespaces can be backed up;
+// other backends return storage.ErrBackupUnsupported.
+func (n *NamespaceStore) BackupNamespace(ctx context.Context, namespace, path string) error {
+	store, err := n.existing(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	return store.Backup(ctx, path)
+}
+
+// RestoreNamespace replaces the database of namespace with the backup at
+// source. The backup is copied and verified before the current database is
+// touched. An existing namespace is only replaced when overwrite is set.
+// Only SQLite namespaces can be restored.
+func (n *NamespaceStore) RestoreNamespace(ctx context.Context, namespace, source string, overwrite bool) error {
+	if err := ValidateNamespaceName(namespace); err != nil {
+		return err
+	}
+	files, ok := n.databases.(sqliteDatabases)
+	if !ok {
+		return storage.ErrBackupUnsupported
+	}
+	path := NamespaceDBPath(files.dataDir, namespace)
+	exists, err := namespaceExists(path)
+	if err != nil {
+		return err
+	}
+	if exists && !overwrite {
+		return fmt.Errorf("%w: %s", ErrNamespaceExists, namespace)
+	}
+
+	staging := path + ".restore"
+	defer func() {
+		if err := RemoveNamespaceFiles(staging); err != nil {
+			logrus.WithError(err).WithField("namespace", namespace).Warn("remove restore staging file")
+		}
+	}()
+	if err := copyFile(source, staging); err != nil {
+		return err
+	}
+	if err := verifyBackup(ctx, staging); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	store := n.stores[namespace]
+	delete(n.stores, namespace)
+	n.mu.Unlock()
+	if store != nil {
+		if err := store.Close(); err != nil {
+			logrus.WithError(err).WithField("namespace", namespace).Warn("close restored namespace store")
+		}
+	}
+
+	if err := RemoveNamespaceFiles(path); err != nil {
+		return err
+	}
+	if err := os.Rename(staging, path); err != nil {
+		return fmt.Errorf("replace namespace database: %w", err)
+	}
+	// Reopening applies any migrations the backup is missing.
+	_, err = n.StoreFor(ctx, namespace)
+	return err
+}
+
+func verifyBackup(ctx context.Context, path string) error {
 	store, err := storage.New(ctx, path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", storage.ErrInvalidBackup, err)
+	}
+	verifyErr := store.VerifyBackup(ctx)
+	if err := store.Close(); err != nil && verifyErr == nil {
+		return fmt.Errorf("close backup: %w", err)
+	}
+	return verifyErr
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("open backup: %w", err)
+	}
+	defer func() {
+		if err := in.Close(); err != nil {
+			logrus.WithError(err).Debug("close backup source")
+		}
+	}()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", target, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		if cerr := out.Close(); cerr != nil {
+			return fmt.Errorf("copy backup: %w (close error: %v)", err, cerr)
+		}
+		return fmt.Errorf("copy backup: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", target, err)
+	}
+	return nil
+}
+
+// existing returns the store of a namespace that already has a database,
+// opening it if needed, without ever creating one.
+func (n *NamespaceStore) existing(ctx context.Context, namespace string) (storage.Backend, error) {
+	if err := ValidateNamespaceName(namespace); err != nil {
+		return nil, err
+	}
+	if store := n.get(namespace); store != nil {
+		return store, nil
+	}
+	exists, err := n.databases.exists(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrNamespaceNotFound, namespace)
+	}
+	return n.open(ctx, namespace)
+}
+
+// DeleteNamespace closes the namespace store, if open, and removes its
+// database.
+func (n *NamespaceStore) DeleteNamespace(ctx context.Context, namespace string) error {
+	if err := ValidateNamespaceName(namespace); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	store := n.stores[namespace]
+	delete(n.stores, namespace)
+	n.mu.Unlock()
+
+	if store != nil {
+		if err := store.Close(); err != nil {
+			logrus.WithError(err).WithField("namespace", namespace).Warn("close deleted namespace store")
+		}
+	} else {
+		exists, err := n.databases.exists(ctx, namespace)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("%w: %s", ErrNamespaceNotFound, namespace)
+		}
+	}
+	return n.databases.remove(ctx, namespace)
+}
+
+// RemoveNamespaceFiles deletes a namespace database and its sqlite journal
+// files. Missing files are ignored.
+func RemoveNamespaceFiles(path string) error {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove %s: %w", path+suffix, err)
+		}
+	}
+	return nil
+}
+
+func namespaceExists(path string) (bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("stat namespace database: %w", err)
+	}
+	return true, nil
+}
+
+// OpenDatabase opens the database of namespace without migrating or
+// tracking it, creating the database if needed. The caller closes it.
+func (n *NamespaceStore) OpenDatabase(ctx context.Context, namespace string) (storage.Backend, error) {
+	if err := ValidateNamespaceName(namespace); err != nil {
+		return nil, err
+	}
+	store, err := n.databases.open(ctx, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("open namespace store: %w", err)
 	}
 	store.SetNamespace(namespace)
+	return store, nil
+}
+
+func (n *NamespaceStore) open(ctx context.Context, namespace string) (storage.Backend, error) {
+	store, err := n.OpenDatabase(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	n.mu.Lock()
+	store.SetOperationObserver(n.observer)
+	n.mu.Unlock()
 
 	if err := store.Migrate(n.ctx); err != nil {
 		if cerr := store.Close(); cerr != nil {
@@ -92,13 +466,39 @@ func (n *NamespaceStore) StoreFor(ctx context.Context, namespace string) (*stora
 	return n.store(namespace, store), nil
 }
 
-func (n *NamespaceStore) get(namespace string) *storage.Store {
+// OpenExisting opens and migrates every namespace database that already
+// exists, so incompatible databases are reported at startup.
+func (n *NamespaceStore) OpenExisting(ctx context.Context) error {
+	namespaces, err := n.NamespaceNames(ctx)
+	if err != nil {
+		return err
+	}
+	for _, namespace := range namespaces {
+		if _, err := n.StoreFor(ctx, namespace); err != nil {
+			return fmt.Errorf("namespace %s: %w", namespace, err)
+		}
+	}
+	return nil
+}
+
+// OpenStores returns a snapshot of the namespace stores opened so far.
+func (n *NamespaceStore) OpenStores() map[string]storage.Backend {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	stores := make(map[string]storage.Backend, len(n.stores))
+	for namespace, store := range n.stores {
+		stores[namespace] = store
+	}
+	return stores
+}
+
+func (n *NamespaceStore) get(namespace string) storage.Backend {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 	return n.stores[namespace]
 }
 
-func (n *NamespaceStore) store(namespace string, store *storage.Store) *storage.Store {
+func (n *NamespaceStore) store(namespace string, store storage.Backend) storage.Backend {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 	if existing := n.stores[namespace]; existing != nil {
@@ -111,10 +511,21 @@ func (n *NamespaceStore) store(namespace string, store *storage.Store) *storage.
 	return store
 }
 
-// Close closes all tracked sqlite stores.
+// SetOperationObserver registers observer on every open namespace store and
+// on stores opened later.
+func (n *NamespaceStore) SetOperationObserver(observer storage.OperationObserver) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.observer = observer
+	for _, store := range n.stores {
+		store.SetOperationObserver(observer)
+	}
+}
+
+// Close closes all tracked stores.
 func (n *NamespaceStore) Close() error {
 	n.mu.Lock()
-	stores := make([]*storage.Store, 0, len(n.stores))
+	stores := make([]storage.Backend, 0, len(n.stores))
 	for _, s := range n.stores {
 		stores = append(stores, s)
 	}
@@ -128,3 +539,85 @@ func (n *NamespaceStore) Close() error {
 	}
 	return firstErr
 }
+
+// namespaceDatabases locates the namespace databases of one storage backend.
+type namespaceDatabases interface {
+	// open opens the database of namespace, creating it if needed.
+	open(ctx context.Context, namespace string) (*storage.Store, error)
+	exists(ctx context.Context, namespace string) (bool, error)
+	list(ctx context.Context) ([]string, error)
+	size(ctx context.Context, namespace string) (int64, error)
+	remove(ctx context.Context, namespace string) error
+	location(namespace string) string
+}
+
+// sqliteDatabases keeps every namespace in a file of its own in dataDir.
+type sqliteDatabases struct {
+	dataDir string
+}
+
+func (d sqliteDatabases) open(ctx context.Context, namespace string) (*storage.Store, error) {
+	return storage.New(ctx, NamespaceDBPath(d.dataDir, namespace))
+}
+
+func (d sqliteDatabases) exists(_ context.Context, namespace string) (bool, error) {
+	return namespaceExists(NamespaceDBPath(d.dataDir, namespace))
+}
+
+func (d sqliteDatabases) list(context.Context) ([]string, error) {
+	return ListNamespaceNames(d.dataDir)
+}
+
+func (d sqliteDatabases) size(_ context.Context, namespace string) (int64, error) {
+	return NamespaceSize(NamespaceDBPath(d.dataDir, namespace))
+}
+
+func (d sqliteDatabases) remove(_ context.Context, namespace string) error {
+	return RemoveNamespaceFiles(NamespaceDBPath(d.dataDir, namespace))
+}
+
+func (d sqliteDatabases) location(namespace string) string {
+	return NamespaceDBPath(d.dataDir, namespace)
+}
+
+// postgresDatabases keeps every namespace in a schema of its own in the
+// database at url.
+type postgresDatabases struct {
+	url string
+}
+
+func (d postgresDatabases) open(ctx context.Context, namespace string) (*storage.Store, error) {
+	return storage.OpenPostgres(ctx, d.url, namespace)
+}
+
+func (d postgresDatabases) exists(ctx context.Context, namespace string) (bool, error) {
+	return storage.PostgresNamespaceExists(ctx, d.url, namespace)
+}
+
+func (d postgresDatabases) list(ctx context.Context) ([]string, error) {
+	names, err := storage.ListPostgresNamespaces(ctx, d.url)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make([]string, 0, len(names))
+	for _, name := range names {
+		if ValidateNamespaceName(name) != nil {
+			logrus.WithField("schema", storage.PostgresNamespaceSchemaPrefix+name).Warn("ignoring schema with invalid namespace name")
+			continue
+		}
+		namespaces = append(namespaces, name)
+	}
+	return namespaces, nil
+}
+
+func (d postgresDatabases) size(ctx context.Context, namespace string) (int64, error) {
+	return storage.PostgresNamespaceSize(ctx, d.url, namespace)
+}
+
+func (d postgresDatabases) remove(ctx context.Context, namespace string) error {
+	return storage.DropPostgresNamespace(ctx, d.url, namespace)
+}
+
+func (d postgresDatabases) location(namespace string) string {
+	return storage.PostgresNamespaceSchemaPrefix + namespace
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+// registerNamespaceRoutes adds the namespace management endpoints to a router
+// mounted at /namespaces.
+func registerNamespaceRoutes(group fiber.Router, namespaces *NamespaceStore) {
+	handler := namespaceHandler{namespaces: namespaces}
+	group.Get("/", handler.list)
+	group.Post("/", handler.create)
+	group.Get("/:name", handler.stats)
+	group.Get("/:name/backup", handler.backup)
+	group.Delete("/:name", handler.delete)
+}
+
+// namespaceAdminMiddleware guards the /namespaces endpoints. They act on the
+// data directory rather than on a single namespace, so in token mode the token
+// must hold the required scope for every namespace (*). In proxy mode the
+// authenticating proxy is expected to restrict access to these paths.
+func (s *Server) namespaceAdminMiddleware() fiber.Handler {
+	return s.wildcardTokenMiddleware("namespace management")
+}
+
+// metricsAuthMiddleware guards /metrics, which reports on every namespace,
+// in the same way as the /namespaces endpoints.
+func (s *Server) metricsAuthMiddleware() fiber.Handler {
+	return s.wildcardTokenMiddleware("metrics")
+}
+
+func (s *Server) wildcardTokenMiddleware(purpose string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if s.tokens != nil {
+			token, err := s.authenticate(c)
+			if err != nil {
+				return err
+			}
+			scope := requiredScope(c.Method())
+			if !token.Allows(storage.TokenNamespaceWildcard, scope) {
+				return fiber.NewError(fiber.StatusForbidden, fmt.Sprintf("%s requires %s access to every namespace (%s)", purpose, scope, storage.TokenNamespaceWildcard))
+			}
+			c.Locals(tokenCtxKey, token)
+		}
+		return c.Next()
+	}
+}
+
+type namespaceHandler struct {
+	namespaces *NamespaceStore
+}
+
+type namespaceCreatePayload struct {
+	Name string `json:"name"`
+}
+
+func (h namespaceHandler) list(c *fiber.Ctx) error {
+	logRequestEntry(c, "namespaceHandler.list", nil)
+
+	namespaces, err := h.namespaces.ListNamespaces(c.UserContext())
+	if err != nil {
+		logrus.WithError(err).Error("list namespaces")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to list namespaces")
+	}
+	return c.JSON(namespaces)
+}
+
+func (h namespaceHandler) create(c *fiber.Ctx) error {
+	var payload namespaceCreatePayload
+	if err := c.BodyParser(&payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	logRequestEntry(c, "namespaceHandler.create", map[string]any{"name": payload.Name})
+
+	if err := ValidateNamespaceName(payload.Name); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	created, err := h.namespaces.CreateNamespace(c.UserContext(), payload.Name)
+	if err != nil {
+		if errors.Is(err, ErrNamespaceExists) {
+			return fiber.NewError(fiber.StatusConflict, "namespace already exists")
+		}
+		logrus.WithError(err).WithField("namespace", payload.Name).Error("create namespace")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to create namespace")
+	}
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+func (h namespaceHandler) stats(c *fiber.Ctx) error {
+	name := c.Params("name")
+	logRequestEntry(c, "namespaceHandler.stats", map[string]any{"name": name})
+
+	if err := ValidateNamespaceName(name); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	stats, err := h.namespaces.NamespaceStats(c.UserContext(), name)
+	if err != nil {
+		if errors.Is(err, ErrNamespaceNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "namespace not found")
+		}
+		logrus.WithError(err).WithField("namespace", name).Error("namespace stats")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to collect namespace stats")
+	}
+	return c.JSON(stats)
+}
+
+func (h namespaceHandler) delete(c *fiber.Ctx) error {
+	name := c.Params("name")
+	logRequestEntry(c, "namespaceHandler.delete", map[string]any{"name": name})
+
+	if err := ValidateNamespaceName(name); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := h.namespaces.DeleteNamespace(c.UserContext(), name); err != nil {
+		if errors.Is(err, ErrNamespaceNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "namespace not found")
+		}
+		logrus.WithError(err).WithField("namespace", name).Error("delete namespace")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to delete namespace")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// backup streams a consistent snapshot of the namespace database. The
+// snapshot is written to a temporary directory that is removed once the
+// response body has been sent.
+func (h namespaceHandler) backup(c *fiber.Ctx) error {
+	name := c.Params("name")
+	logRequestEntry(c, "namespaceHandler.backup", map[string]any{"name": name})
+
+	if err := ValidateNamespaceName(name); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	dir, err := os.MkdirTemp("", "grantory-backup-")
+	if err != nil {
+		logrus.WithError(err).Error("create backup directory")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to back up namespace")
+	}
+	path := filepath.Join(dir, "backup.db")
+	file, info, err := h.writeBackup(c, name, path)
+	if err != nil {
+		removeBackupDir(dir)
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "application/vnd.sqlite3")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name+".db"))
+	// The response closes the stream, and with it removes the directory, once
+	// the body has been sent.
+	return c.SendStream(&backupStream{File: file, dir: dir}, int(info.Size()))
+}
+
+// writeBackup snapshots the namespace to path and opens the snapshot.
+func (h namespaceHandler) writeBackup(c *fiber.Ctx, name, path string) (*os.File, os.FileInfo, error) {
+	if err := h.namespaces.BackupNamespace(c.UserContext(), name, path); err != nil {
+		if errors.Is(err, ErrNamespaceNotFound) {
+			return nil, nil, fiber.NewError(fiber.StatusNotFound, "namespace not found")
+		}
+		if errors.Is(err, storage.ErrBackupUnsupported) {
+			return nil, nil, fiber.NewError(fiber.StatusNotImplemented, err.Error())
+		}
+		logrus.WithError(err).WithField("namespace", name).Error("backup namespace")
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "unable to back up namespace")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		logrus.WithError(err).WithField("namespace", name).Error("open namespace backup")
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "unable to back up namespace")
+	}
+	info, err := file.Stat()
+	if err != nil {
+		if cerr := file.Close(); cerr != nil {
+			logrus.WithError(cerr).Debug("close namespace backup")
+		}
+		logrus.WithError(err).WithField("namespace", name).Error("stat namespace backup")
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "unable to back up namespace")
+	}
+	return file, info, nil
+}
+
+// backupStream is a backup snapshot that removes its temporary directory
+// when closed.
+type backupStream struct {
+	*os.File
+	dir string
+}
+
+// Close closes the snapshot and removes its directory.
+func (b *backupStream) Close() error {
+	err := b.File.Close()
+	removeBackupDir(b.dir)
+	return err
+}
+
+func removeBackupDir(dir string) {
+	if err := os.RemoveAll(dir); err != nil {
+		logrus.WithError(err).WithField("path", dir).Warn("remove backup directory")
+	}
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/config"
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func newNamespaceTestApp(t *testing.T, cfg config.Config) (*fiber.App, *Server) {
+	t.Helper()
+
+	srv := newTestServer(t, cfg)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	registerNamespaceRoutes(app.Group("/namespaces", srv.namespaceAdminMiddleware()), srv.nsStore)
+	api := app.Group("/", srv.namespaceMiddleware())
+	api.Get("/probe", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusNoContent)
+	})
+	return app, srv
+}
+
+func TestNamespaceRoutes(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	app, _ := newNamespaceTestApp(t, config.Config{DataDir: dataDir, AuthMode: config.AuthModeProxy, StrictNamespaces: true})
+
+	res := sendTestRequest(t, app, http.MethodGet, "/probe", map[string]string{"REMOTE_USER": "team-a"}, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "strict mode must not create namespaces implicitly")
+	_, err := os.Stat(NamespaceDBPath(dataDir, "team-a"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/probe", nil, nil)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode, "the default namespace is always available")
+
+	res = sendTestRequest(t, app, http.MethodPost, "/namespaces", nil, map[string]any{"name": "team-a"})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	created := decodeJSON[NamespaceInfo](t, res)
+	assert.Equal(t, "team-a", created.Name)
+	assert.Positive(t, created.SizeBytes)
+
+	res = sendTestRequest(t, app, http.MethodPost, "/namespaces", nil, map[string]any{"name": "team-a"})
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+	res = sendTestRequest(t, app, http.MethodPost, "/namespaces", nil, map[string]any{"name": "a/b"})
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/probe", map[string]string{"REMOTE_USER": "team-a"}, nil)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode, "created namespaces are usable")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/namespaces", nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	names := make([]string, 0)
+	for _, info := range decodeJSON[[]NamespaceInfo](t, res) {
+		names = append(names, info.Name)
+	}
+	assert.Equal(t, []string{DefaultNamespace, "team-a"}, names)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/namespaces/team-a", nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	stats := decodeJSON[NamespaceStats](t, res)
+	assert.Equal(t, "team-a", stats.Name)
+	assert.Equal(t, storage.SchemaVersion(), stats.SchemaVersion)
+	assert.Zero(t, stats.Requests)
+
+	res = sendTestRequest(t, app, http.MethodDelete, "/namespaces/team-a", nil, nil)
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+	res = sendTestRequest(t, app, http.MethodGet, "/namespaces/team-a", nil, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	res = sendTestRequest(t, app, http.MethodDelete, "/namespaces/team-a", nil, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	res = sendTestRequest(t, app, http.MethodGet, "/probe", map[string]string{"REMOTE_USER": "team-a"}, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "deleted namespaces are not recreated")
+}
+
+func TestNamespaceRoutesRequireWildcardToken(t *testing.T) {
+	t.Parallel()
+
+	app, srv := newNamespaceTestApp(t, config.Config{DataDir: t.TempDir()})
+	scoped, _ := createTestToken(t, srv, map[string]storage.TokenScope{"team-a": storage.TokenScopeWrite})
+	reader, _ := createTestToken(t, srv, map[string]storage.TokenScope{storage.TokenNamespaceWildcard: storage.TokenScopeRead})
+	admin, _ := createTestToken(t, srv, map[string]storage.TokenScope{storage.TokenNamespaceWildcard: storage.TokenScopeWrite})
+
+	res := sendTestRequest(t, app, http.MethodGet, "/namespaces", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	res = sendTestRequest(t, app, http.MethodGet, "/namespaces", map[string]string{"Authorization": "Bearer " + scoped}, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "tokens bound to one namespace cannot manage namespaces")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/namespaces", map[string]string{"Authorization": "Bearer " + reader}, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res = sendTestRequest(t, app, http.MethodPost, "/namespaces", map[string]string{"Authorization": "Bearer " + reader}, map[string]any{"name": "team-b"})
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "read tokens cannot create namespaces")
+
+	res = sendTestRequest(t, app, http.MethodPost, "/namespaces", map[string]string{"Authorization": "Bearer " + admin}, map[string]any{"name": "team-b"})
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+}
+
+func TestNamespaceStoreAutoCreate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	namespaces, err := NewNamespaceStore(ctx, dataDir)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, namespaces.Close())
+	}()
+
+	_, err = namespaces.StoreFor(ctx, "implicit")
+	require.NoError(t, err, "namespaces are created implicitly by default")
+
+	namespaces.SetAutoCreate(false)
+	_, err = namespaces.StoreFor(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNamespaceNotFound)
+	_, err = namespaces.StoreFor(ctx, "implicit")
+	assert.NoError(t, err, "existing namespaces stay available")
+	_, err = namespaces.StoreFor(ctx, DefaultNamespace)
+	assert.NoError(t, err, "the default namespace is always created")
+}
+
+func TestRemoveNamespaceFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "demo.db")
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		assert.NoError(t, os.WriteFile(base+suffix, []byte("x"), 0o600))
+	}
+
+	size, err := NamespaceSize(base)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), size, "size includes the journal files")
+
+	assert.NoError(t, RemoveNamespaceFiles(base))
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		_, err := os.Stat(base + suffix)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	}
+
+	assert.NoError(t, RemoveNamespaceFiles(filepath.Join(t.TempDir(), "missing.db")))
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func TestListPagination(t *testing.T) {
+	t.Parallel()
+
+	app, cleanup := newTestApp(t)
+	defer cleanup()
+
+	res := sendTestRequest(t, app, http.MethodPost, "/hosts", nil, map[string]any{})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	host := decodeJSON[storage.Host](t, res)
+
+	var created []string
+	for _, team := range []string{"a", "b", "a", "a"} {
+		res := sendTestRequest(t, app, http.MethodPost, "/registers", nil, map[string]any{
+			"host_id": host.ID,
+			"labels":  map[string]string{"team": team},
+		})
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		reg := decodeJSON[storage.Register](t, res)
+		if team == "a" {
+			created = append(created, reg.ID)
+		}
+	}
+
+	var listed []string
+	path := "/registers?label=team=a&limit=2"
+	for pages := 0; path != ""; pages++ {
+		require.Less(t, pages, 3, "too many pages")
+		res := sendTestRequest(t, app, http.MethodGet, path, nil, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		link := res.Header.Get("Link")
+		for _, reg := range decodeJSON[[]storage.Register](t, res) {
+			listed = append(listed, reg.ID)
+		}
+		path = ""
+		if link != "" {
+			require.True(t, strings.HasSuffix(link, `>; rel="next"`), "unexpected Link header %q", link)
+			next, err := url.Parse(strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`))
+			require.NoError(t, err)
+			assert.Equal(t, "/registers", next.Path)
+			assert.Equal(t, "team=a", next.Query().Get("label"), "the next link keeps the filters")
+			assert.NotEmpty(t, next.Query().Get("cursor"))
+			path = next.String()
+		}
+	}
+	assert.Equal(t, created, listed)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/registers?label=team=a&order=desc&order_by=updated_at&limit=10", nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Header.Get("Link"), "the last page has no next link")
+	registers := decodeJSON[[]storage.Register](t, res)
+	require.Len(t, registers, 3)
+	assert.Equal(t, created[2], registers[0].ID)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/hosts?limit=1", nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Len(t, decodeJSON[[]storage.Host](t, res), 1)
+
+	for _, path := range []string{
+		"/registers?limit=0",
+		"/requests?limit=1001",
+		"/grants?order=sideways",
+		"/hosts?order_by=updated_at",
+		"/requests?cursor=bogus",
+	} {
+		res := sendTestRequest(t, app, http.MethodGet, path, nil, nil)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, "path %q should be rejected", path)
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func registerRequestTypeRoutes(app fiber.Router) {
+	handler := requestTypeHandler{}
+	group := app.Group("/request-types")
+	group.Get("/", handler.list)
+	group.Get("/:name", handler.get)
+	group.Put("/:name", handler.put)
+	group.Delete("/:name", handler.delete)
+}
+
+type requestTypeHandler struct{}
+
+type requestTypePutPayload struct {
+	RequestSchema json.RawMessage `json:"request_schema"`
+	GrantSchema   json.RawMessage `json:"grant_schema"`
+}
+
+// schemaValidationResponse is the 422 body returned when a request or grant
+// payload does not match the schema of its request type.
+type schemaValidationResponse struct {
+	Error       string                    `json:"error"`
+	RequestType string                    `json:"request_type"`
+	Target      string                    `json:"target"`
+	Violations  []storage.SchemaViolation `json:"violations"`
+}
+
+func respondSchemaViolations(c *fiber.Ctx, err *storage.SchemaValidationError) error {
+	return c.Status(fiber.StatusUnprocessableEntity).JSON(schemaValidationResponse{
+		Error:       storage.ErrSchemaValidation.Error(),
+		RequestType: err.RequestType,
+		Target:      err.Target,
+		Violations:  err.Violations,
+	})
+}
+
+func (h requestTypeHandler) list(c *fiber.Ctx) error {
+	logRequestEntry(c, "requestTypeHandler.list", nil)
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	requestTypes, err := store.ListRequestTypes(c.UserContext())
+	if err != nil {
+		logrus.WithError(err).WithField("namespace", namespace).Error("list request types")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to list request types")
+	}
+	return c.JSON(requestTypes)
+}
+
+func (h requestTypeHandler) get(c *fiber.Ctx) error {
+	name := c.Params("name")
+	logRequestEntry(c, "requestTypeHandler.get", map[string]any{"name": name})
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	requestType, err := store.GetRequestType(c.UserContext(), name)
+	if err != nil {
+		if errors.Is(err, storage.ErrRequestTypeNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "request type not found")
+		}
+		logrus.WithError(err).WithField("namespace", namespace).Error("get request type")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to fetch request type")
+	}
+	return c.JSON(requestType)
+}
+
+func (h requestTypeHandler) put(c *fiber.Ctx) error {
+	var payload requestTypePutPayload
+	if err := c.BodyParser(&payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	name := c.Params("name")
+	logRequestEntry(c, "requestTypeHandler.put", map[string]any{
+		"name":               name,
+		"has_request_schema": len(payload.RequestSchema) > 0,
+		"has_grant_schema":   len(payload.GrantSchema) > 0,
+	})
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	stored, err := store.PutRequestType(c.UserContext(), storage.RequestType{
+		Name:          name,
+		RequestSchema: nullSchema(payload.RequestSchema),
+		GrantSchema:   nullSchema(payload.GrantSchema),
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidRequestType) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		logrus.WithError(err).WithField("namespace", namespace).Error("put request type")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to persist request type")
+	}
+	return c.JSON(stored)
+}
+
+// nullSchema treats an explicit JSON null like an omitted schema.
+func nullSchema(schema json.RawMessage) json.RawMessage {
+	if string(schema) == "null" {
+		return nil
+	}
+	return schema
+}
+
+func (h requestTypeHandler) delete(c *fiber.Ctx) error {
+	name := c.Params("name")
+	logRequestEntry(c, "requestTypeHandler.delete", map[string]any{"name": name})
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	if err := store.DeleteRequestType(c.UserContext(), name); err != nil {
+		if errors.Is(err, storage.ErrRequestTypeNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "request type not found")
+		}
+		logrus.WithError(err).WithField("namespace", namespace).Error("delete request type")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to delete request type")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func TestRequestTypeRoutes(t *testing.T) {
+	t.Parallel()
+
+	app, cleanup := newTestApp(t)
+	defer cleanup()
+
+	headers := map[string]string{"REMOTE_USER": "request-type-user"}
+
+	res := sendTestRequest(t, app, http.MethodPut, "/request-types/database", headers, map[string]any{
+		"request_schema": map[string]any{
+			"type":       "object",
+			"required":   []string{"name"},
+			"properties": map[string]any{"name": map[string]any{"type": "string"}, "port": map[string]any{"type": "integer"}},
+		},
+		"grant_schema": map[string]any{"type": "object", "required": []string{"password"}},
+	})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	stored := decodeJSON[storage.RequestType](t, res)
+	assert.Equal(t, "database", stored.Name)
+	assert.NotEmpty(t, stored.GrantSchema)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/request-types", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Len(t, decodeJSON[[]storage.RequestType](t, res), 1)
+
+	res = sendTestRequest(t, app, http.MethodPost, "/hosts", headers, map[string]any{})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	host := decodeJSON[storage.Host](t, res)
+
+	res = sendTestRequest(t, app, http.MethodPost, "/requests", headers, map[string]any{
+		"host_id": host.ID,
+		"labels":  map[string]string{"type": "database"},
+		"payload": map[string]any{"port": "5432"},
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+	failure := decodeJSON[schemaValidationResponse](t, res)
+	assert.Equal(t, "database", failure.RequestType)
+	assert.Equal(t, "request", failure.Target)
+	paths := make([]string, 0, len(failure.Violations))
+	for _, violation := range failure.Violations {
+		paths = append(paths, violation.Path)
+	}
+	assert.ElementsMatch(t, []string{"", "/port"}, paths)
+
+	res = sendTestRequest(t, app, http.MethodPost, "/requests", headers, map[string]any{
+		"host_id": host.ID,
+		"labels":  map[string]string{"type": "database"},
+		"payload": map[string]any{"name": "db", "port": 5432},
+	})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	req := decodeJSON[map[string]any](t, res)
+	reqID, _ := req["id"].(string)
+	require.NotEmpty(t, reqID)
+
+	res = sendTestRequest(t, app, http.MethodPatch, "/requests/"+reqID, headers, map[string]any{"payload": map[string]any{"name": 1}})
+	require.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+	assert.Equal(t, []storage.SchemaViolation{{Path: "/name", Message: "got number, want string"}}, decodeJSON[schemaValidationResponse](t, res).Violations)
+
+	res = sendTestRequest(t, app, http.MethodPut, "/request-types/cache", headers, map[string]any{
+		"request_schema": map[string]any{"type": "object", "required": []string{"endpoint"}},
+	})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res = sendTestRequest(t, app, http.MethodPost, "/requests", headers, map[string]any{
+		"host_id": host.ID,
+		"labels":  map[string]string{"type": "database"},
+		"payload": map[string]any{"name": "cache"},
+	})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	switched := decodeJSON[map[string]any](t, res)
+	switchedID, _ := switched["id"].(string)
+	res = sendTestRequest(t, app, http.MethodPatch, "/requests/"+switchedID, headers, map[string]any{
+		"labels":  map[string]string{"type": "cache"},
+		"payload": map[string]any{"endpoint": "redis:6379"},
+	})
+	require.Equal(t, http.StatusOK, res.StatusCode, "type and payload change together")
+
+	res = sendTestRequest(t, app, http.MethodPost, "/grants", headers, map[string]any{
+		"request_id": reqID,
+		"payload":    json.RawMessage(`{"user": "app"}`),
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+	assert.Equal(t, "grant", decodeJSON[schemaValidationResponse](t, res).Target)
+
+	res = sendTestRequest(t, app, http.MethodPost, "/grants", headers, map[string]any{
+		"request_id": reqID,
+		"payload":    json.RawMessage(`{"password": "secret"}`),
+	})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	res = sendTestRequest(t, app, http.MethodPut, "/request-types/cache", headers, map[string]any{"request_schema": map[string]any{"type": 1}})
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res = sendTestRequest(t, app, http.MethodPut, "/request-types/cache", headers, map[string]any{})
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = sendTestRequest(t, app, http.MethodDelete, "/request-types/database", headers, nil)
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+	res = sendTestRequest(t, app, http.MethodGet, "/request-types/database", headers, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	res = sendTestRequest(t, app, http.MethodDelete, "/request-types/database", headers, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
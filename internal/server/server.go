@@ -7,9 +7,12 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/tasansga/terraform-provider-grantory/internal/config"
 	"github.com/tasansga/terraform-provider-grantory/internal/storage"
@@ -21,24 +24,48 @@ const (
 )
 
 type localStore struct {
-	store *storage.Store
+	store storage.Backend
 }
 
 type Server struct {
 	cfg     config.Config
 	nsStore *NamespaceStore
+	tokens  *storage.TokenStore
+	metrics *serverMetrics
 }
 
 func New(ctx context.Context, cfg config.Config) (*Server, error) {
-	nsStore, err := NewNamespaceStore(ctx, cfg.DataDir)
+	nsStore, err := OpenNamespaceStore(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
-	return &Server{cfg: cfg, nsStore: nsStore}, nil
+	if err := nsStore.OpenExisting(ctx); err != nil {
+		if cerr := nsStore.Close(); cerr != nil {
+			logrus.WithError(cerr).Warn("close namespace stores")
+		}
+		return nil, err
+	}
+	nsStore.SetAutoCreate(!cfg.StrictNamespaces)
+	srv := &Server{cfg: cfg, nsStore: nsStore, metrics: newServerMetrics(nsStore)}
+
+	// Token authentication is the default; proxy mode must be selected explicitly.
+	if cfg.AuthMode != config.AuthModeProxy {
+		tokens, err := OpenTokenStore(ctx, cfg)
+		if err != nil {
+			if cerr := nsStore.Close(); cerr != nil {
+				logrus.WithError(cerr).Warn("close namespace stores")
+			}
+			return nil, err
+		}
+		srv.tokens = tokens
+	}
+	return srv, nil
 }
 
 func (s *Server) Serve(ctx context.Context) error {
 	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(tracingMiddleware())
+	app.Use(s.metrics.middleware())
 
 	app.Get("/static/water.min.css", s.handleWaterCSS)
 	app.Get("/", s.handleRoot)
@@ -47,13 +74,23 @@ func (s *Server) Serve(ctx context.Context) error {
 	app.Get("/readyz", s.handleReadiness)
 	app.Use(requestLoggingMiddleware())
 
+	// Namespace management is registered before the namespaced API so these
+	// routes never resolve (or implicitly create) a namespace of their own.
+	registerNamespaceRoutes(app.Group("/namespaces", s.namespaceAdminMiddleware()), s.nsStore)
+	app.Get("/metrics", s.metricsAuthMiddleware(), s.metrics.handler())
+
 	api := app.Group("/", s.namespaceMiddleware())
 
 	registerHostRoutes(api)
 	registerRequestRoutes(api)
 	registerRegisterRoutes(api)
 	registerGrantRoutes(api)
-	api.Get("/metrics", s.handleMetrics)
+	registerEventRoutes(api)
+	registerWebhookRoutes(api)
+	registerRequestTypeRoutes(api)
+	registerExportRoutes(api)
+	registerAuditRoutes(api)
+	api.Get("/metrics/json", s.handleMetrics)
 	api.Get("/index.html", s.handleIndex)
 
 	go func() {
@@ -61,6 +98,35 @@ func (s *Server) Serve(ctx context.Context) error {
 		_ = app.Shutdown()
 	}()
 
+	// Stop the background workers before returning so they never outlive the stores.
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		newWebhookDispatcher(s.nsStore).run(workerCtx)
+	}()
+	if s.cfg.ReapInterval > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			newExpiryReaper(s.nsStore, s.cfg.ReapInterval).run(workerCtx)
+		}()
+	}
+	if s.cfg.BackupDir != "" && s.cfg.BackupInterval > 0 && s.cfg.StorageURL != "" {
+		logrus.Warn("scheduled backups are disabled: PostgreSQL storage is backed up with the database's own tooling")
+	} else if s.cfg.BackupDir != "" && s.cfg.BackupInterval > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			newBackupScheduler(s.nsStore, s.cfg.BackupDir, s.cfg.BackupInterval, s.cfg.BackupKeep).run(workerCtx)
+		}()
+	}
+	defer func() {
+		stopWorkers()
+		workers.Wait()
+	}()
+
 	httpDisabled := isBindDisabled(s.cfg.BindAddr)
 	httpsDisabled := isBindDisabled(s.cfg.TLSBind)
 
@@ -106,15 +172,7 @@ func isBindDisabled(addr string) bool {
 func requestLoggingMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		err := c.Next()
-		status := c.Response().StatusCode()
-		if err != nil {
-			var fe *fiber.Error
-			if errors.As(err, &fe) {
-				status = fe.Code
-			} else if status < http.StatusBadRequest {
-				status = http.StatusInternalServerError
-			}
-		}
+		status := responseStatus(c, err)
 		if status >= http.StatusBadRequest {
 			details := map[string]any{"status": status}
 			if err != nil {
@@ -128,21 +186,26 @@ func requestLoggingMiddleware() fiber.Handler {
 
 func (s *Server) namespaceMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		namespace := c.Get("REMOTE_USER")
-		if namespace == "" {
-			namespace = DefaultNamespace
+		namespace, err := s.resolveNamespace(c)
+		if err != nil {
+			return err
 		}
-		store, err := s.nsStore.StoreFor(c.Context(), namespace)
+		store, err := s.nsStore.StoreFor(c.UserContext(), namespace)
 		if err != nil {
 			if err := ValidateNamespaceName(namespace); err != nil {
 				return fiber.NewError(fiber.StatusBadRequest, err.Error())
 			}
+			if errors.Is(err, ErrNamespaceNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("namespace %q does not exist", namespace))
+			}
 			logrus.WithError(err).WithField("namespace", namespace).Error("prepare namespace store")
 			return fiber.NewError(fiber.StatusInternalServerError, "unable to access namespace data")
 		}
 
+		trace.SpanFromContext(c.UserContext()).SetAttributes(attribute.String("grantory.namespace", namespace))
 		c.Locals(storeCtxKey, localStore{store: store})
 		c.Locals(namespaceCtxKey, namespace)
+		c.SetUserContext(storage.WithAuditActor(c.UserContext(), auditActor(c)))
 		return c.Next()
 	}
 }
@@ -170,7 +233,7 @@ func (s *Server) handleReadiness(c *fiber.Ctx) error {
 		}
 	}
 
-	if _, err := s.nsStore.StoreFor(c.Context(), DefaultNamespace); err != nil {
+	if _, err := s.nsStore.StoreFor(c.UserContext(), DefaultNamespace); err != nil {
 		logrus.WithError(err).Error("prepare default namespace")
 		return fiber.NewError(http.StatusServiceUnavailable, "database not ready")
 	}
@@ -191,23 +254,26 @@ func validateTLSFiles(cfg config.Config) error {
 	return nil
 }
 
+// handleMetrics reports request, grant and register counts of the caller's
+// namespace as JSON. Prometheus metrics for all namespaces are served on
+// /metrics.
 func (s *Server) handleMetrics(c *fiber.Ctx) error {
 	logRequestEntry(c, "Server.handleMetrics", nil)
 	store, namespace, err := resolveNamespaceStore(c)
 	if err != nil {
 		return err
 	}
-	reqCounts, err := store.CountRequestsByGrantPresence(c.Context())
+	reqCounts, err := store.CountRequestsByGrantPresence(c.UserContext())
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("count requests")
 		return fiber.NewError(http.StatusInternalServerError, "unable to collect request metrics")
 	}
-	grantCounts, err := store.CountGrants(c.Context())
+	grantCounts, err := store.CountGrants(c.UserContext())
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("count grants")
 		return fiber.NewError(http.StatusInternalServerError, "unable to collect grant metrics")
 	}
-	registerCounts, err := store.CountRegisters(c.Context())
+	registerCounts, err := store.CountRegisters(c.UserContext())
 	if err != nil {
 		logrus.WithError(err).WithField("namespace", namespace).Error("count registers")
 		return fiber.NewError(http.StatusInternalServerError, "unable to collect register metrics")
@@ -220,7 +286,11 @@ func (s *Server) handleMetrics(c *fiber.Ctx) error {
 	})
 }
 
-// Close releases all namespace databases.
+// Close releases all namespace databases and the token database.
 func (s *Server) Close() error {
-	return s.nsStore.Close()
+	err := s.nsStore.Close()
+	if terr := s.tokens.Close(); terr != nil && err == nil {
+		err = terr
+	}
+	return err
 }
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -80,7 +81,12 @@ func newTestApp(t *testing.T) (*fiber.App, func()) {
 	registerRequestRoutes(api)
 	registerRegisterRoutes(api)
 	registerGrantRoutes(api)
-	api.Get("/metrics", srv.handleMetrics)
+	registerEventRoutes(api)
+	registerWebhookRoutes(api)
+	registerRequestTypeRoutes(api)
+	registerExportRoutes(api)
+	registerAuditRoutes(api)
+	api.Get("/metrics/json", srv.handleMetrics)
 
 	cleanup := func() {
 		if err := store.Close(); err != nil {
@@ -94,6 +100,20 @@ func newTestApp(t *testing.T) (*fiber.App, func()) {
 	return app, cleanup
 }
 
+// newTestServer starts a server for cfg that is closed when the test ends.
+func newTestServer(t *testing.T, cfg config.Config) *Server {
+	t.Helper()
+
+	srv, err := New(context.Background(), cfg)
+	require.NoError(t, err, "New() should succeed")
+	t.Cleanup(func() {
+		if err := srv.Close(); err != nil {
+			t.Errorf("close server: %v", err)
+		}
+	})
+	return srv
+}
+
 func sendTestRequest(t *testing.T, app *fiber.App, method, path string, headers map[string]string, body any) *http.Response {
 	t.Helper()
 
@@ -305,7 +325,7 @@ func TestAPIEndpoints(t *testing.T) {
 	res = sendTestRequest(t, app, http.MethodGet, fmt.Sprintf("/requests/%s", reqID), headers, nil)
 	assert.Equal(t, http.StatusNotFound, res.StatusCode, "request should be missing after delete")
 
-	res = sendTestRequest(t, app, http.MethodGet, "/metrics", headers, nil)
+	res = sendTestRequest(t, app, http.MethodGet, "/metrics/json", headers, nil)
 	assert.Equal(t, http.StatusOK, res.StatusCode, "metrics status")
 	metrics := decodeJSON[map[string]map[string]int64](t, res)
 	assert.Contains(t, metrics, "requests", "metrics should include requests")
@@ -468,6 +488,28 @@ func TestRequestHandlerListWithFilters(t *testing.T) {
 	for _, req := range reqList {
 		assert.NotEqual(t, otherHost.ID, req.HostID, "host label filter should exclude non-matching hosts")
 	}
+
+	res = sendTestRequest(t, app, http.MethodGet, "/requests?selector="+url.QueryEscape("env notin (filter),team"), headers, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode, "selector filtered list should succeed")
+	reqList = decodeJSON[[]storage.Request](t, res)
+	if assert.Len(t, reqList, 1, "selector should return the request outside env=filter") {
+		assert.Equal(t, "other", reqList[0].Labels["env"])
+	}
+
+	res = sendTestRequest(t, app, http.MethodGet, "/requests?selector="+url.QueryEscape("env in (filter,other)")+"&selector=!team", headers, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode, "repeated selectors should succeed")
+	assert.Empty(t, decodeJSON[[]storage.Request](t, res), "repeated selectors must all match")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/requests?payload="+url.QueryEscape("name=host-filtered"), headers, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode, "payload filtered list should succeed")
+	reqList = decodeJSON[[]storage.Request](t, res)
+	if assert.Len(t, reqList, 1, "payload filter should return the matching request") {
+		assert.Equal(t, otherHost.ID, reqList[0].HostID)
+	}
+
+	res = sendTestRequest(t, app, http.MethodGet, "/requests?payload="+url.QueryEscape("$.name=other")+"&label=env=filter", headers, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode, "payload filter with labels should succeed")
+	assert.Empty(t, decodeJSON[[]storage.Request](t, res), "payload filters combine with label filters")
 }
 
 func TestRegisterHandlerListWithFilters(t *testing.T) {
@@ -537,6 +579,20 @@ func TestRegisterHandlerListWithFilters(t *testing.T) {
 	for _, reg := range regList {
 		assert.NotEqual(t, otherHost.ID, reg.HostID, "host label filter should exclude non-matching hosts")
 	}
+
+	res = sendTestRequest(t, app, http.MethodGet, "/registers?selector="+url.QueryEscape("env!=other")+"&host_label=env=filter", headers, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode, "selector filtered list should succeed")
+	assert.Len(t, decodeJSON[[]storage.Register](t, res), 1, "selector should combine with host label filters")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/registers?selector="+url.QueryEscape("env in (filter"), headers, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "invalid selector should fail")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/registers?payload="+url.QueryEscape("name=filtered"), headers, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode, "payload filtered list should succeed")
+	assert.Len(t, decodeJSON[[]storage.Register](t, res), 1, "payload filter should return the matching register")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/registers?payload=name", headers, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "invalid payload filter should fail")
 }
 
 func TestRequestHandlerListInvalidLabelFilter(t *testing.T) {
@@ -572,6 +628,152 @@ func TestRequestHandlerListInvalidHasGrant(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "invalid has_grant should fail")
 }
 
+func TestRequestStatusTransitions(t *testing.T) {
+	t.Parallel()
+
+	app, cleanup := newTestApp(t)
+	defer cleanup()
+
+	headers := map[string]string{"REMOTE_USER": "status-user"}
+	res := sendTestRequest(t, app, http.MethodPost, "/hosts", headers, map[string]any{})
+	require.Equal(t, http.StatusCreated, res.StatusCode, "create host status")
+	host := decodeJSON[storage.Host](t, res)
+
+	res = sendTestRequest(t, app, http.MethodPost, "/requests", headers, map[string]any{"host_id": host.ID})
+	require.Equal(t, http.StatusCreated, res.StatusCode, "create request status")
+	denied := decodeJSON[storage.Request](t, res)
+	assert.Equal(t, storage.RequestStatusPending, denied.Status, "new request should be pending")
+
+	res = sendTestRequest(t, app, http.MethodPost, "/requests", headers, map[string]any{"host_id": host.ID})
+	require.Equal(t, http.StatusCreated, res.StatusCode, "create second request status")
+	granted := decodeJSON[storage.Request](t, res)
+
+	res = sendTestRequest(t, app, http.MethodPatch, fmt.Sprintf("/requests/%s/status", denied.ID), headers, map[string]any{
+		"status": "denied",
+		"reason": "quota exceeded",
+	})
+	require.Equal(t, http.StatusOK, res.StatusCode, "deny request status")
+	deniedResp := decodeJSON[requestResponse](t, res)
+	assert.Equal(t, storage.RequestStatusDenied, deniedResp.Status, "request should be denied")
+	assert.Equal(t, "quota exceeded", deniedResp.StatusReason, "deny reason should be returned")
+
+	res = sendTestRequest(t, app, http.MethodPatch, fmt.Sprintf("/requests/%s/status", denied.ID), headers, map[string]any{"status": "revoked"})
+	assert.Equal(t, http.StatusConflict, res.StatusCode, "denied request cannot be revoked")
+
+	res = sendTestRequest(t, app, http.MethodPatch, fmt.Sprintf("/requests/%s/status", denied.ID), headers, map[string]any{"status": "bogus"})
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "unknown status should be rejected")
+
+	res = sendTestRequest(t, app, http.MethodPatch, "/requests/missing/status", headers, map[string]any{"status": "denied"})
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "missing request should 404")
+
+	res = sendTestRequest(t, app, http.MethodPost, "/grants", headers, map[string]any{
+		"request_id": granted.ID,
+		"payload":    map[string]string{"token": "abc"},
+	})
+	require.Equal(t, http.StatusCreated, res.StatusCode, "create grant status")
+
+	res = sendTestRequest(t, app, http.MethodPatch, fmt.Sprintf("/requests/%s/status", granted.ID), headers, map[string]any{
+		"status": "revoked",
+		"reason": "leaked",
+	})
+	require.Equal(t, http.StatusOK, res.StatusCode, "revoke grant status")
+	revoked := decodeJSON[requestResponse](t, res)
+	assert.Equal(t, storage.RequestStatusRevoked, revoked.Status, "request should be revoked")
+	assert.False(t, revoked.HasGrant, "revoked request should not report a grant")
+	assert.Nil(t, revoked.Grant, "revoked request should not expose grant payload")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/requests?status=denied", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "status filter should succeed")
+	list := decodeJSON[[]storage.Request](t, res)
+	require.Len(t, list, 1, "status filter should return denied request")
+	assert.Equal(t, denied.ID, list[0].ID, "status filter should return denied request")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/requests?status=denied,revoked", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "multi status filter should succeed")
+	list = decodeJSON[[]storage.Request](t, res)
+	assert.Len(t, list, 2, "multi status filter should return both requests")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/requests?status=granted", headers, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "invalid status filter should fail")
+
+	res = sendTestRequest(t, app, http.MethodPatch, fmt.Sprintf("/requests/%s/status", granted.ID), headers, map[string]any{"status": "pending"})
+	require.Equal(t, http.StatusOK, res.StatusCode, "reopen revoked request status")
+	reopened := decodeJSON[requestResponse](t, res)
+	assert.Equal(t, storage.RequestStatusPending, reopened.Status, "revoked request should reopen")
+
+	res = sendTestRequest(t, app, http.MethodPost, "/grants", headers, map[string]any{
+		"request_id": granted.ID,
+		"payload":    map[string]string{"token": "rotated"},
+	})
+	require.Equal(t, http.StatusCreated, res.StatusCode, "re-grant reopened request status")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/requests/"+granted.ID, headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "get re-granted request status")
+	regranted := decodeJSON[requestResponse](t, res)
+	assert.Equal(t, storage.RequestStatusApproved, regranted.Status, "re-granted request should be approved")
+	require.NotNil(t, regranted.Grant, "re-granted request should expose the new grant")
+	assert.Equal(t, map[string]any{"token": "rotated"}, regranted.Grant["payload"], "re-granted request should carry the new payload")
+}
+
+func TestRequestPayloadUpdates(t *testing.T) {
+	t.Parallel()
+
+	app, cleanup := newTestApp(t)
+	defer cleanup()
+
+	headers := map[string]string{"REMOTE_USER": "revision-user"}
+	res := sendTestRequest(t, app, http.MethodPost, "/hosts", headers, map[string]any{})
+	require.Equal(t, http.StatusCreated, res.StatusCode, "create host status")
+	host := decodeJSON[storage.Host](t, res)
+
+	res = sendTestRequest(t, app, http.MethodPost, "/requests", headers, map[string]any{
+		"host_id": host.ID,
+		"payload": map[string]any{"size": "small"},
+	})
+	require.Equal(t, http.StatusCreated, res.StatusCode, "create request status")
+	req := decodeJSON[storage.Request](t, res)
+	assert.Equal(t, 1, req.Revision, "new request should start at revision 1")
+
+	res = sendTestRequest(t, app, http.MethodPost, "/grants", headers, map[string]any{
+		"request_id": req.ID,
+		"payload":    map[string]string{"token": "abc"},
+	})
+	require.Equal(t, http.StatusCreated, res.StatusCode, "create grant status")
+
+	res = sendTestRequest(t, app, http.MethodPatch, fmt.Sprintf("/requests/%s", req.ID), headers, map[string]any{
+		"payload": map[string]any{"size": "large"},
+	})
+	require.Equal(t, http.StatusOK, res.StatusCode, "update payload status")
+	updated := decodeJSON[requestResponse](t, res)
+	assert.Equal(t, map[string]any{"size": "large"}, updated.Payload, "payload should be replaced")
+	assert.Equal(t, 2, updated.Revision, "payload update should bump the revision")
+	assert.True(t, updated.HasGrant, "payload update should keep the grant")
+	assert.True(t, updated.StaleGrant, "grant should be reported as stale")
+	assert.EqualValues(t, 1, updated.Grant["request_revision"], "grant should report the revision it covers")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/requests?stale_grant=true", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "stale_grant filter should succeed")
+	list := decodeJSON[[]storage.Request](t, res)
+	require.Len(t, list, 1, "stale_grant filter should return the updated request")
+	assert.Equal(t, req.ID, list[0].ID)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/requests?stale_grant=maybe", headers, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "invalid stale_grant filter should fail")
+
+	res = sendTestRequest(t, app, http.MethodGet, fmt.Sprintf("/requests/%s/revisions", req.ID), headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "list revisions status")
+	revisions := decodeJSON[[]storage.RequestRevision](t, res)
+	require.Len(t, revisions, 2, "both revisions should be listed")
+	assert.Equal(t, map[string]any{"size": "small"}, revisions[0].Payload)
+	assert.Equal(t, 2, revisions[1].Revision)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/requests/missing/revisions", headers, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "missing request revisions should 404")
+
+	res = sendTestRequest(t, app, http.MethodPatch, "/requests/missing", headers, map[string]any{"payload": map[string]any{}})
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "missing request payload update should 404")
+}
+
 func TestHandlersRejectInvalidJSON(t *testing.T) {
 	t.Parallel()
 
@@ -594,6 +796,60 @@ func TestHandlersRejectInvalidJSON(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "grant handler should reject bad json")
 }
 
+func TestGrantLabels(t *testing.T) {
+	t.Parallel()
+
+	app, cleanup := newTestApp(t)
+	defer cleanup()
+
+	headers := map[string]string{"REMOTE_USER": "grant-labels"}
+	res := sendTestRequest(t, app, http.MethodPost, "/hosts", headers, map[string]any{})
+	require.Equal(t, http.StatusCreated, res.StatusCode, "create host status")
+	host := decodeJSON[storage.Host](t, res)
+
+	var grants []storage.Grant
+	for _, pipeline := range []string{"ci", "manual"} {
+		res = sendTestRequest(t, app, http.MethodPost, "/requests", headers, map[string]any{"host_id": host.ID})
+		require.Equal(t, http.StatusCreated, res.StatusCode, "create request status")
+		req := decodeJSON[storage.Request](t, res)
+
+		res = sendTestRequest(t, app, http.MethodPost, "/grants", headers, map[string]any{
+			"request_id": req.ID,
+			"labels":     map[string]string{"pipeline": pipeline},
+		})
+		require.Equal(t, http.StatusCreated, res.StatusCode, "create grant status")
+		grant := decodeJSON[storage.Grant](t, res)
+		assert.Equal(t, pipeline, grant.Labels["pipeline"], "grant labels should be returned")
+		grants = append(grants, grant)
+	}
+
+	res = sendTestRequest(t, app, http.MethodGet, "/grants?label=pipeline=ci", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "label filter should succeed")
+	list := decodeJSON[[]storage.Grant](t, res)
+	require.Len(t, list, 1, "label filter should return one grant")
+	assert.Equal(t, grants[0].ID, list[0].ID)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/grants?label=pipeline", headers, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "invalid label filter should fail")
+
+	res = sendTestRequest(t, app, http.MethodPatch, fmt.Sprintf("/grants/%s", grants[1].ID), headers, map[string]any{
+		"labels": map[string]string{"pipeline": "ci", "policy": "strict"},
+	})
+	require.Equal(t, http.StatusOK, res.StatusCode, "update grant labels status")
+	updated := decodeJSON[storage.Grant](t, res)
+	assert.Equal(t, map[string]string{"pipeline": "ci", "policy": "strict"}, updated.Labels)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/grants?label=pipeline=ci", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "label filter should succeed")
+	assert.Len(t, decodeJSON[[]storage.Grant](t, res), 2, "updated grant should match the filter")
+
+	res = sendTestRequest(t, app, http.MethodPatch, fmt.Sprintf("/grants/%s", grants[1].ID), headers, map[string]any{})
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "grant update should require labels")
+
+	res = sendTestRequest(t, app, http.MethodPatch, "/grants/missing", headers, map[string]any{"labels": map[string]string{}})
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "missing grant should 404")
+}
+
 func TestGrantHandlerMissingFields(t *testing.T) {
 	t.Parallel()
 
@@ -764,7 +1020,7 @@ func TestRegisterHandlerListInvalidHostLabelFilter(t *testing.T) {
 func TestIndexHandler(t *testing.T) {
 	t.Parallel()
 
-	cfg := config.Config{DataDir: t.TempDir()}
+	cfg := config.Config{DataDir: t.TempDir(), AuthMode: config.AuthModeProxy}
 	srv, err := New(context.Background(), cfg)
 	require.NoError(t, err, "New() should succeed")
 	defer func() {
@@ -855,7 +1111,7 @@ func TestCreateRegisterMissingHostReturnsBadRequest(t *testing.T) {
 func TestNamespaceValidationMiddleware(t *testing.T) {
 	t.Parallel()
 
-	cfg := config.Config{DataDir: t.TempDir()}
+	cfg := config.Config{DataDir: t.TempDir(), AuthMode: config.AuthModeProxy}
 	srv, err := New(context.Background(), cfg)
 	assert.NoError(t, err, "initialize server")
 	defer func() {
@@ -153,7 +153,7 @@ func TestValidateTLSFilesSuccess(t *testing.T) {
 func TestNamespaceMiddlewareStoresStore(t *testing.T) {
 	t.Parallel()
 
-	cfg := config.Config{DataDir: t.TempDir()}
+	cfg := config.Config{DataDir: t.TempDir(), AuthMode: config.AuthModeProxy}
 	srv, err := New(context.Background(), cfg)
 	assert.NoError(t, err, "New() should succeed")
 	defer func() {
@@ -257,8 +257,38 @@ func TestHandleMetricsCountError(t *testing.T) {
 		c.Locals(namespaceCtxKey, DefaultNamespace)
 		return c.Next()
 	})
-	app.Get("/metrics", srv.handleMetrics)
+	app.Get("/metrics/json", srv.handleMetrics)
 
-	res := sendTestRequest(t, app, http.MethodGet, "/metrics", nil, nil)
+	res := sendTestRequest(t, app, http.MethodGet, "/metrics/json", nil, nil)
 	assert.Equal(t, http.StatusInternalServerError, res.StatusCode, "metrics should fail when store closed")
 }
+
+func TestNewRefusesNewerNamespaceSchema(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	store, err := storage.New(ctx, NamespaceDBPath(dataDir, "future-ns"))
+	assert.NoError(t, err, "storage.New() should succeed")
+	assert.NoError(t, store.Migrate(ctx), "Migrate() should succeed")
+	_, err = store.DB().ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES (?, 'future')`, storage.SchemaVersion()+1)
+	assert.NoError(t, err, "record future migration")
+	assert.NoError(t, store.Close(), "close store")
+
+	srv, err := New(ctx, config.Config{DataDir: dataDir})
+	assert.ErrorIs(t, err, storage.ErrSchemaTooNew, "server must refuse newer databases")
+	assert.Nil(t, srv)
+}
+
+func TestListNamespaceNames(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	for _, name := range []string{NamespaceDBPath(dataDir, "team-b"), NamespaceDBPath(dataDir, "team-a"), filepath.Join(dataDir, "x.db")} {
+		assert.NoError(t, os.WriteFile(name, nil, 0o600))
+	}
+
+	namespaces, err := ListNamespaceNames(dataDir)
+	assert.NoError(t, err, "ListNamespaceNames() error")
+	assert.Equal(t, []string{"team-a", "team-b"}, namespaces, "invalid namespace names are skipped")
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/telemetry"
+)
+
+// tracingMiddleware opens a server span for every request, continuing a W3C
+// trace context sent by the caller. The span is stored in the user context,
+// which handlers pass on to the storage layer.
+func tracingMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := telemetry.Propagator.Extract(c.Context(), fiberHeaderCarrier{c: c})
+		method := utils.CopyString(c.Method())
+		ctx, span := telemetry.Tracer().Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.URLPath(utils.CopyString(c.Path())),
+			),
+		)
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		// The route is only known once the router has matched the request.
+		route := utils.CopyString(c.Route().Path)
+		status := responseStatus(c, err)
+		span.SetName(method + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, utils.StatusMessage(status))
+		}
+		if err != nil {
+			span.RecordError(err)
+		}
+		return err
+	}
+}
+
+// fiberHeaderCarrier adapts the request headers to propagation.TextMapCarrier.
+type fiberHeaderCarrier struct {
+	c *fiber.Ctx
+}
+
+// Get copies the value because fiber reuses the request buffer while the
+// extracted trace state may outlive the request.
+func (f fiberHeaderCarrier) Get(key string) string {
+	return utils.CopyString(f.c.Get(key))
+}
+
+func (f fiberHeaderCarrier) Set(key, value string) {
+	f.c.Request().Header.Set(key, value)
+}
+
+func (f fiberHeaderCarrier) Keys() []string {
+	keys := make([]string, 0)
+	f.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
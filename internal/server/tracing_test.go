@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/config"
+)
+
+// TestTracingMiddleware swaps the global tracer provider and therefore does
+// not run in parallel.
+func TestTracingMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+	})
+
+	srv := newTestServer(t, config.Config{DataDir: t.TempDir(), AuthMode: config.AuthModeProxy})
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(tracingMiddleware())
+	registerHostRoutes(app.Group("/", srv.namespaceMiddleware()))
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	headers := map[string]string{
+		"REMOTE_USER": "trace-user",
+		"traceparent": "00-" + traceID + "-00f067aa0ba902b7-01",
+	}
+	res := sendTestRequest(t, app, http.MethodPost, "/hosts", headers, map[string]any{})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	serverSpan, ok := spans["POST /hosts/"]
+	require.True(t, ok, "a span is named after the route")
+	assert.Equal(t, traceID, serverSpan.SpanContext().TraceID().String(), "the caller's trace is continued")
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent().SpanID().String())
+	assert.Contains(t, serverSpan.Attributes(), attribute.Int("http.response.status_code", http.StatusCreated))
+	assert.Contains(t, serverSpan.Attributes(), attribute.String("grantory.namespace", "trace-user"))
+
+	storeSpan, ok := spans["storage hosts.create"]
+	require.True(t, ok, "store methods open spans")
+	assert.Equal(t, serverSpan.SpanContext().SpanID(), storeSpan.Parent().SpanID(), "store spans are children of the request span")
+	assert.Contains(t, storeSpan.Attributes(), attribute.String("db.collection.name", "hosts"))
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+// Headers sent with every webhook delivery.
+const (
+	WebhookSignatureHeader = "X-Grantory-Signature"
+	WebhookTimestampHeader = "X-Grantory-Timestamp"
+	WebhookDeliveryHeader  = "X-Grantory-Delivery"
+	WebhookEventHeader     = "X-Grantory-Event"
+)
+
+const (
+	webhookPollInterval     = time.Second
+	webhookRequestTimeout   = 10 * time.Second
+	webhookClaimLease       = time.Minute
+	webhookMaxAttempts      = 8
+	webhookBaseRetryDelay   = 10 * time.Second
+	webhookMaxRetryDelay    = time.Hour
+	webhookDispatchBatch    = 50
+	webhookMaxResponseBytes = 4096
+)
+
+// webhookBody is the JSON document POSTed to webhook endpoints.
+type webhookBody struct {
+	DeliveryID string        `json:"delivery_id"`
+	WebhookID  string        `json:"webhook_id"`
+	Namespace  string        `json:"namespace"`
+	Attempt    int           `json:"attempt"`
+	Event      storage.Event `json:"event"`
+	Resource   any           `json:"resource,omitempty"`
+}
+
+// SignWebhookPayload returns the signature header value for a delivery body:
+// the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookDispatcher queues deliveries from the change feed of every open
+// namespace and sends them, retrying failures with exponential backoff.
+type webhookDispatcher struct {
+	nsStore *NamespaceStore
+	client  *http.Client
+	now     func() time.Time
+}
+
+func newWebhookDispatcher(nsStore *NamespaceStore) *webhookDispatcher {
+	return &webhookDispatcher{
+		nsStore: nsStore,
+		client:  &http.Client{Timeout: webhookRequestTimeout},
+		now:     time.Now,
+	}
+}
+
+func (d *webhookDispatcher) run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		d.dispatch(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch runs one pass over every open namespace.
+func (d *webhookDispatcher) dispatch(ctx context.Context) {
+	stores := d.nsStore.OpenStores()
+	namespaces := make([]string, 0, len(stores))
+	for namespace := range stores {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	for _, namespace := range namespaces {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := d.dispatchNamespace(ctx, namespace, stores[namespace]); err != nil && !errors.Is(err, context.Canceled) {
+			logrus.WithError(err).WithField("namespace", namespace).Warn("dispatch webhooks")
+		}
+	}
+}
+
+func (d *webhookDispatcher) dispatchNamespace(ctx context.Context, namespace string, store storage.Backend) error {
+	now := d.now()
+	if _, err := store.EnqueueWebhookDeliveries(ctx, now); err != nil {
+		return fmt.Errorf("enqueue deliveries: %w", err)
+	}
+	due, err := store.DueWebhookDeliveries(ctx, now, webhookDispatchBatch)
+	if err != nil {
+		return fmt.Errorf("load due deliveries: %w", err)
+	}
+	for _, dispatch := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// Servers sharing a PostgreSQL database load the same due deliveries;
+		// only the one whose claim succeeds sends it.
+		claimedAt := d.now()
+		claimed, err := store.ClaimWebhookDelivery(ctx, dispatch.Delivery.ID, claimedAt, claimedAt.Add(webhookClaimLease))
+		if err != nil {
+			return fmt.Errorf("claim delivery %s: %w", dispatch.Delivery.ID, err)
+		}
+		if !claimed {
+			continue
+		}
+		attempt := d.deliver(ctx, namespace, store, dispatch)
+		delivery, err := store.RecordWebhookDeliveryAttempt(ctx, dispatch.Delivery.ID, attempt)
+		if err != nil {
+			return fmt.Errorf("record delivery %s: %w", dispatch.Delivery.ID, err)
+		}
+		entry := logrus.WithFields(logrus.Fields{
+			"namespace":       namespace,
+			"webhook_id":      delivery.WebhookID,
+			"delivery_id":     delivery.ID,
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"response_status": delivery.ResponseStatus,
+		})
+		if delivery.Status == storage.WebhookDeliverySucceeded {
+			entry.Info("webhook delivered")
+		} else {
+			entry.WithField("error", delivery.LastError).Warn("webhook delivery failed")
+		}
+	}
+	return nil
+}
+
+// deliver sends one delivery and reports the outcome, including when to retry.
+func (d *webhookDispatcher) deliver(ctx context.Context, namespace string, store storage.Backend, dispatch storage.WebhookDispatch) storage.WebhookDeliveryAttempt {
+	attemptNumber := dispatch.Delivery.Attempts + 1
+	fail := func(status int, err error) storage.WebhookDeliveryAttempt {
+		attempt := storage.WebhookDeliveryAttempt{ResponseStatus: status, Error: err.Error()}
+		if attemptNumber < webhookMaxAttempts {
+			retryAt := d.now().Add(webhookRetryDelay(attemptNumber))
+			attempt.RetryAt = &retryAt
+		}
+		return attempt
+	}
+
+	body, err := json.Marshal(webhookBody{
+		DeliveryID: dispatch.Delivery.ID,
+		WebhookID:  dispatch.Delivery.WebhookID,
+		Namespace:  namespace,
+		Attempt:    attemptNumber,
+		Event:      dispatch.Delivery.Event,
+		Resource:   loadWebhookResource(ctx, store, dispatch.Delivery.Event, dispatch.IncludePayload),
+	})
+	if err != nil {
+		return fail(0, fmt.Errorf("encode body: %w", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dispatch.URL, bytes.NewReader(body))
+	if err != nil {
+		return fail(0, fmt.Errorf("build request: %w", err))
+	}
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "grantory-webhook")
+	req.Header.Set(WebhookDeliveryHeader, dispatch.Delivery.ID)
+	req.Header.Set(WebhookEventHeader, dispatch.Delivery.Event.ResourceType+"."+dispatch.Delivery.Event.Action)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(dispatch.Secret, timestamp, body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return fail(0, fmt.Errorf("perform request: %w", err))
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(res.Body, webhookMaxResponseBytes))
+	if cerr := res.Body.Close(); cerr != nil {
+		logrus.WithError(cerr).Warn("close webhook response body")
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fail(res.StatusCode, fmt.Errorf("unexpected status %d: %s", res.StatusCode, bytes.TrimSpace(snippet)))
+	}
+	return storage.WebhookDeliveryAttempt{ResponseStatus: res.StatusCode}
+}
+
+// webhookRetryDelay doubles the delay after every failed attempt up to webhookMaxRetryDelay.
+func webhookRetryDelay(attempt int) time.Duration {
+	delay := webhookBaseRetryDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= webhookMaxRetryDelay {
+			return webhookMaxRetryDelay
+		}
+	}
+	return delay
+}
+
+// loadWebhookResource returns the current state of the event's resource, or
+// nil when it no longer exists. Payloads, which often hold credentials, are
+// left out unless the webhook opted in with includePayload.
+func loadWebhookResource(ctx context.Context, store storage.Backend, event storage.Event, includePayload bool) any {
+	switch event.ResourceType {
+	case storage.EventResourceHosts:
+		host, err := store.GetHost(ctx, event.ResourceID)
+		if err != nil {
+			return nil
+		}
+		return host
+	case storage.EventResourceRequests:
+		req, err := store.GetRequest(ctx, event.ResourceID)
+		if err != nil {
+			return nil
+		}
+		if !includePayload {
+			req.Payload = nil
+		}
+		return req
+	case storage.EventResourceRegisters:
+		reg, err := store.GetRegister(ctx, event.ResourceID)
+		if err != nil {
+			return nil
+		}
+		if !includePayload {
+			reg.Payload = nil
+		}
+		return reg
+	case storage.EventResourceGrants:
+		grant, err := store.GetGrant(ctx, event.ResourceID)
+		if err != nil {
+			return nil
+		}
+		if !includePayload {
+			grant.Payload = nil
+		}
+		return grant
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,273 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+const maxWebhookDeliveryLimit = 1000
+
+func registerWebhookRoutes(app fiber.Router) {
+	handler := webhookHandler{}
+	group := app.Group("/webhooks")
+	group.Get("/", handler.list)
+	group.Post("/", handler.create)
+	group.Get("/:id", handler.get)
+	group.Patch("/:id", handler.update)
+	group.Delete("/:id", handler.delete)
+	group.Get("/:id/deliveries", handler.deliveries)
+}
+
+type webhookHandler struct{}
+
+type webhookCreatePayload struct {
+	URL            string            `json:"url"`
+	Secret         string            `json:"secret"`
+	ResourceTypes  []string          `json:"resource_types"`
+	LabelSelector  map[string]string `json:"label_selector"`
+	IncludePayload bool              `json:"include_payload"`
+}
+
+type webhookUpdatePayload struct {
+	URL            *string            `json:"url"`
+	Secret         *string            `json:"secret"`
+	ResourceTypes  *[]string          `json:"resource_types"`
+	LabelSelector  *map[string]string `json:"label_selector"`
+	IncludePayload *bool              `json:"include_payload"`
+}
+
+func (h webhookHandler) create(c *fiber.Ctx) error {
+	var payload webhookCreatePayload
+	if err := c.BodyParser(&payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if strings.TrimSpace(payload.URL) == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "url is required")
+	}
+
+	logRequestEntry(c, "webhookHandler.create", map[string]any{
+		"url":             payload.URL,
+		"resource_types":  payload.ResourceTypes,
+		"label_selector":  payload.LabelSelector,
+		"include_payload": payload.IncludePayload,
+	})
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	created, err := store.CreateWebhook(c.UserContext(), storage.Webhook{
+		URL:            payload.URL,
+		Secret:         payload.Secret,
+		ResourceTypes:  normalizeResourceTypes(payload.ResourceTypes),
+		LabelSelector:  payload.LabelSelector,
+		IncludePayload: payload.IncludePayload,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidWebhook) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		logrus.WithError(err).WithField("namespace", namespace).Error("create webhook")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to persist webhook")
+	}
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+func (h webhookHandler) list(c *fiber.Ctx) error {
+	logRequestEntry(c, "webhookHandler.list", nil)
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	webhooks, err := store.ListWebhooks(c.UserContext())
+	if err != nil {
+		logrus.WithError(err).WithField("namespace", namespace).Error("list webhooks")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to list webhooks")
+	}
+	return c.JSON(webhooks)
+}
+
+func (h webhookHandler) get(c *fiber.Ctx) error {
+	webhookID := c.Params("id")
+	logRequestEntry(c, "webhookHandler.get", map[string]any{"webhook_id": webhookID})
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	webhook, err := store.GetWebhook(c.UserContext(), webhookID)
+	if err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "webhook not found")
+		}
+		logrus.WithError(err).WithField("namespace", namespace).Error("get webhook")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to fetch webhook")
+	}
+	return c.JSON(webhook)
+}
+
+func (h webhookHandler) update(c *fiber.Ctx) error {
+	var payload webhookUpdatePayload
+	if err := c.BodyParser(&payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	webhookID := c.Params("id")
+	logRequestEntry(c, "webhookHandler.update", map[string]any{
+		"webhook_id":      webhookID,
+		"url":             payload.URL,
+		"resource_types":  payload.ResourceTypes,
+		"label_selector":  payload.LabelSelector,
+		"include_payload": payload.IncludePayload,
+		"rotate_secret":   payload.Secret != nil,
+	})
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	webhook, err := store.GetWebhook(c.UserContext(), webhookID)
+	if err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "webhook not found")
+		}
+		logrus.WithError(err).WithField("namespace", namespace).Error("get webhook for update")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to fetch webhook")
+	}
+	if payload.URL != nil {
+		webhook.URL = *payload.URL
+	}
+	if payload.Secret != nil {
+		webhook.Secret = *payload.Secret
+	}
+	if payload.ResourceTypes != nil {
+		webhook.ResourceTypes = normalizeResourceTypes(*payload.ResourceTypes)
+	}
+	if payload.LabelSelector != nil {
+		webhook.LabelSelector = *payload.LabelSelector
+	}
+	if payload.IncludePayload != nil {
+		webhook.IncludePayload = *payload.IncludePayload
+	}
+
+	updated, err := store.UpdateWebhook(c.UserContext(), webhook)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrWebhookNotFound):
+			return fiber.NewError(fiber.StatusNotFound, "webhook not found")
+		case errors.Is(err, storage.ErrInvalidWebhook):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		default:
+			logrus.WithError(err).WithField("namespace", namespace).Error("update webhook")
+			return fiber.NewError(fiber.StatusInternalServerError, "unable to update webhook")
+		}
+	}
+	return c.JSON(updated)
+}
+
+func (h webhookHandler) delete(c *fiber.Ctx) error {
+	webhookID := c.Params("id")
+	logRequestEntry(c, "webhookHandler.delete", map[string]any{"webhook_id": webhookID})
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	if err := store.DeleteWebhook(c.UserContext(), webhookID); err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "webhook not found")
+		}
+		logrus.WithError(err).WithField("namespace", namespace).Error("delete webhook")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to delete webhook")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// deliveries returns the delivery log of a webhook, newest first.
+func (h webhookHandler) deliveries(c *fiber.Ctx) error {
+	webhookID := c.Params("id")
+	filters, err := parseWebhookDeliveryFilters(c)
+	if err != nil {
+		return err
+	}
+	filters.WebhookID = webhookID
+
+	logRequestEntry(c, "webhookHandler.deliveries", map[string]any{
+		"webhook_id": webhookID,
+		"statuses":   filters.Statuses,
+		"limit":      filters.Limit,
+	})
+
+	store, namespace, err := resolveNamespaceStore(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := store.GetWebhook(c.UserContext(), webhookID); err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "webhook not found")
+		}
+		logrus.WithError(err).WithField("namespace", namespace).Error("get webhook for deliveries")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to fetch webhook")
+	}
+
+	deliveries, err := store.ListWebhookDeliveries(c.UserContext(), filters)
+	if err != nil {
+		logrus.WithError(err).WithField("namespace", namespace).Error("list webhook deliveries")
+		return fiber.NewError(fiber.StatusInternalServerError, "unable to list webhook deliveries")
+	}
+	return c.JSON(deliveries)
+}
+
+func parseWebhookDeliveryFilters(c *fiber.Ctx) (storage.WebhookDeliveryListFilters, error) {
+	query, err := url.ParseQuery(string(c.Context().URI().QueryString()))
+	if err != nil {
+		return storage.WebhookDeliveryListFilters{}, fiber.NewError(fiber.StatusBadRequest, "invalid query parameters")
+	}
+
+	filters := storage.WebhookDeliveryListFilters{Limit: storage.DefaultWebhookDeliveryListLimit}
+	for _, raw := range query["status"] {
+		for _, part := range strings.Split(raw, ",") {
+			status, err := storage.ParseWebhookDeliveryStatus(part)
+			if err != nil {
+				return storage.WebhookDeliveryListFilters{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid status %q", part))
+			}
+			filters.Statuses = append(filters.Statuses, status)
+		}
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxWebhookDeliveryLimit {
+			return storage.WebhookDeliveryListFilters{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid limit %q: must be between 1 and %d", raw, maxWebhookDeliveryLimit))
+		}
+		filters.Limit = limit
+	}
+	return filters, nil
+}
+
+func normalizeResourceTypes(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	normalized := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			normalized = append(normalized, value)
+		}
+	}
+	return normalized
+}
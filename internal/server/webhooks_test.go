@@ -0,0 +1,294 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/storage"
+)
+
+func TestWebhookRoutes(t *testing.T) {
+	t.Parallel()
+
+	app, cleanup := newTestApp(t)
+	defer cleanup()
+
+	headers := map[string]string{"REMOTE_USER": "webhook-user"}
+
+	res := sendTestRequest(t, app, http.MethodPost, "/webhooks", headers, map[string]any{
+		"url":            "https://hooks.example.com/grantory",
+		"resource_types": []string{"Requests"},
+		"label_selector": map[string]string{"type": "db_user"},
+	})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	created := decodeJSON[storage.Webhook](t, res)
+	assert.NotEmpty(t, created.Secret, "the generated secret is returned on create")
+	assert.Equal(t, []string{storage.EventResourceRequests}, created.ResourceTypes)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/webhooks/"+created.ID, headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	loaded := decodeJSON[storage.Webhook](t, res)
+	assert.Empty(t, loaded.Secret)
+	assert.Equal(t, map[string]string{"type": "db_user"}, loaded.LabelSelector)
+
+	assert.False(t, loaded.IncludePayload, "payloads are left out by default")
+
+	res = sendTestRequest(t, app, http.MethodPatch, "/webhooks/"+created.ID, headers, map[string]any{
+		"url":             "https://hooks.example.com/v2",
+		"secret":          "rotated",
+		"include_payload": true,
+	})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	updated := decodeJSON[storage.Webhook](t, res)
+	assert.Equal(t, "https://hooks.example.com/v2", updated.URL)
+	assert.Equal(t, "rotated", updated.Secret)
+	assert.True(t, updated.IncludePayload)
+	assert.Equal(t, map[string]string{"type": "db_user"}, updated.LabelSelector, "omitted fields are kept")
+
+	res = sendTestRequest(t, app, http.MethodGet, "/webhooks", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Len(t, decodeJSON[[]storage.Webhook](t, res), 1)
+
+	res = sendTestRequest(t, app, http.MethodGet, "/webhooks/"+created.ID+"/deliveries?status=pending&limit=10", headers, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, decodeJSON[[]storage.WebhookDelivery](t, res))
+
+	for _, query := range []string{"status=unknown", "limit=0", "limit=abc"} {
+		res = sendTestRequest(t, app, http.MethodGet, "/webhooks/"+created.ID+"/deliveries?"+query, headers, nil)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, "query %q should be rejected", query)
+	}
+
+	res = sendTestRequest(t, app, http.MethodPost, "/webhooks", headers, map[string]any{"url": "ftp://example.com"})
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res = sendTestRequest(t, app, http.MethodPost, "/webhooks", headers, map[string]any{})
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res = sendTestRequest(t, app, http.MethodPatch, "/webhooks/"+created.ID, headers, map[string]any{"resource_types": []string{"widgets"}})
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = sendTestRequest(t, app, http.MethodDelete, "/webhooks/"+created.ID, headers, nil)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	for _, path := range []string{"/webhooks/" + created.ID, "/webhooks/" + created.ID + "/deliveries"} {
+		res = sendTestRequest(t, app, http.MethodGet, path, headers, nil)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode, path)
+	}
+	res = sendTestRequest(t, app, http.MethodDelete, "/webhooks/"+created.ID, headers, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestWebhookDispatcherDeliversSignedEvents(t *testing.T) {
+	t.Parallel()
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	var (
+		mu       sync.Mutex
+		requests []received
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, received{header: r.Header.Clone(), body: body})
+		first := len(requests) == 1
+		mu.Unlock()
+		if first {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	ctx := context.Background()
+	nsStore, err := NewNamespaceStore(ctx, t.TempDir())
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, nsStore.Close())
+	}()
+	store, err := nsStore.StoreFor(ctx, "hooks")
+	require.NoError(t, err)
+
+	webhook, err := store.CreateWebhook(ctx, storage.Webhook{
+		URL:            receiver.URL,
+		Secret:         "s3cret",
+		ResourceTypes:  []string{storage.EventResourceRequests},
+		LabelSelector:  map[string]string{"type": "db_user"},
+		IncludePayload: true,
+	})
+	require.NoError(t, err)
+
+	host, err := store.CreateHost(ctx, storage.Host{})
+	require.NoError(t, err)
+	req, err := store.CreateRequest(ctx, storage.Request{
+		HostID:  host.ID,
+		Payload: map[string]any{"database": "orders"},
+		Labels:  map[string]string{"type": "db_user"},
+	})
+	require.NoError(t, err)
+	_, err = store.CreateRequest(ctx, storage.Request{HostID: host.ID, Labels: map[string]string{"type": "bucket"}})
+	require.NoError(t, err)
+
+	now := time.Now()
+	dispatcher := newWebhookDispatcher(nsStore)
+	dispatcher.now = func() time.Time { return now }
+
+	dispatcher.dispatch(ctx)
+	deliveries, err := store.ListWebhookDeliveries(ctx, storage.WebhookDeliveryListFilters{WebhookID: webhook.ID})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1, "only the matching request is delivered")
+	assert.Equal(t, storage.WebhookDeliveryPending, deliveries[0].Status)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[0].ResponseStatus)
+	require.NotNil(t, deliveries[0].NextAttemptAt)
+	assert.WithinDuration(t, now.Add(webhookBaseRetryDelay), *deliveries[0].NextAttemptAt, time.Second)
+
+	dispatcher.dispatch(ctx)
+	mu.Lock()
+	assert.Len(t, requests, 1, "retries wait for the backoff")
+	mu.Unlock()
+
+	now = now.Add(webhookBaseRetryDelay)
+	dispatcher.dispatch(ctx)
+	deliveries, err = store.ListWebhookDeliveries(ctx, storage.WebhookDeliveryListFilters{WebhookID: webhook.ID})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, storage.WebhookDeliverySucceeded, deliveries[0].Status)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, requests, 2)
+	last := requests[1]
+	timestamp, err := strconv.ParseInt(last.header.Get(WebhookTimestampHeader), 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, SignWebhookPayload("s3cret", timestamp, last.body), last.header.Get(WebhookSignatureHeader))
+	assert.Equal(t, "requests.created", last.header.Get(WebhookEventHeader))
+	assert.Equal(t, deliveries[0].ID, last.header.Get(WebhookDeliveryHeader))
+
+	var body struct {
+		Namespace string          `json:"namespace"`
+		Attempt   int             `json:"attempt"`
+		Event     storage.Event   `json:"event"`
+		Resource  storage.Request `json:"resource"`
+	}
+	require.NoError(t, json.Unmarshal(last.body, &body))
+	assert.Equal(t, "hooks", body.Namespace)
+	assert.Equal(t, 2, body.Attempt)
+	assert.Equal(t, req.ID, body.Event.ResourceID)
+	assert.Equal(t, "orders", body.Resource.Payload["database"], "the webhook opted in to payloads")
+}
+
+func TestLoadWebhookResourceOmitsPayloads(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	nsStore, err := NewNamespaceStore(ctx, t.TempDir())
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, nsStore.Close())
+	}()
+	store, err := nsStore.StoreFor(ctx, "hooks")
+	require.NoError(t, err)
+
+	host, err := store.CreateHost(ctx, storage.Host{})
+	require.NoError(t, err)
+	req, err := store.CreateRequest(ctx, storage.Request{
+		HostID:  host.ID,
+		Payload: map[string]any{"database": "orders"},
+		Labels:  map[string]string{"type": "db_user"},
+	})
+	require.NoError(t, err)
+	grant, err := store.CreateGrant(ctx, storage.Grant{RequestID: req.ID, Payload: []byte(`{"password":"secret"}`)})
+	require.NoError(t, err)
+	reg, err := store.CreateRegister(ctx, storage.Register{HostID: host.ID, Payload: map[string]any{"token": "secret"}})
+	require.NoError(t, err)
+
+	requestEvent := storage.Event{ResourceType: storage.EventResourceRequests, ResourceID: req.ID}
+	grantEvent := storage.Event{ResourceType: storage.EventResourceGrants, ResourceID: grant.ID}
+	registerEvent := storage.Event{ResourceType: storage.EventResourceRegisters, ResourceID: reg.ID}
+
+	loadedRequest, ok := loadWebhookResource(ctx, store, requestEvent, false).(storage.Request)
+	require.True(t, ok)
+	assert.Nil(t, loadedRequest.Payload, "payloads are left out by default")
+	assert.Equal(t, map[string]string{"type": "db_user"}, loadedRequest.Labels)
+	assert.Equal(t, storage.RequestStatusApproved, loadedRequest.Status)
+	loadedGrant, ok := loadWebhookResource(ctx, store, grantEvent, false).(storage.Grant)
+	require.True(t, ok)
+	assert.Nil(t, loadedGrant.Payload, "grant payloads are left out by default")
+	assert.Equal(t, req.ID, loadedGrant.RequestID)
+	loadedRegister, ok := loadWebhookResource(ctx, store, registerEvent, false).(storage.Register)
+	require.True(t, ok)
+	assert.Nil(t, loadedRegister.Payload)
+
+	loadedRequest, ok = loadWebhookResource(ctx, store, requestEvent, true).(storage.Request)
+	require.True(t, ok)
+	assert.Equal(t, "orders", loadedRequest.Payload["database"])
+	loadedGrant, ok = loadWebhookResource(ctx, store, grantEvent, true).(storage.Grant)
+	require.True(t, ok)
+	assert.JSONEq(t, `{"password":"secret"}`, string(loadedGrant.Payload))
+}
+
+func TestWebhookDispatchersSendEachDeliveryOnce(t *testing.T) {
+	t.Parallel()
+
+	var received atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	ctx := context.Background()
+	nsStore, err := NewNamespaceStore(ctx, t.TempDir())
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, nsStore.Close())
+	}()
+	store, err := nsStore.StoreFor(ctx, "hooks")
+	require.NoError(t, err)
+	_, err = store.CreateWebhook(ctx, storage.Webhook{URL: receiver.URL, ResourceTypes: []string{storage.EventResourceHosts}})
+	require.NoError(t, err)
+	for range 5 {
+		_, err = store.CreateHost(ctx, storage.Host{})
+		require.NoError(t, err)
+	}
+
+	// Two dispatchers on one store stand in for replicas sharing a database.
+	var replicas sync.WaitGroup
+	for range 2 {
+		replicas.Add(1)
+		go func() {
+			defer replicas.Done()
+			assert.NoError(t, newWebhookDispatcher(nsStore).dispatchNamespace(ctx, "hooks", store))
+		}()
+	}
+	replicas.Wait()
+
+	assert.Equal(t, int32(5), received.Load(), "every delivery is sent exactly once")
+	deliveries, err := store.ListWebhookDeliveries(ctx, storage.WebhookDeliveryListFilters{})
+	require.NoError(t, err)
+	for _, delivery := range deliveries {
+		assert.Equal(t, storage.WebhookDeliverySucceeded, delivery.Status)
+		assert.Equal(t, 1, delivery.Attempts)
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, webhookBaseRetryDelay, webhookRetryDelay(1))
+	assert.Equal(t, 2*webhookBaseRetryDelay, webhookRetryDelay(2))
+	assert.Equal(t, 8*webhookBaseRetryDelay, webhookRetryDelay(4))
+	assert.Equal(t, webhookMaxRetryDelay, webhookRetryDelay(20))
+}
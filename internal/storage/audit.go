@@ -0,0 +1,351 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AuditResourceWebhooks is the audit resource type of webhook subscriptions.
+// The other resource types match the change feed.
+const AuditResourceWebhooks = "webhooks"
+
+// AuditActorSystem is recorded for mutations that carry no actor, such as the
+// ones made by background workers.
+const AuditActorSystem = "system"
+
+// DefaultAuditListLimit caps the number of audit events returned by
+// ListAuditEvents when no limit is given.
+const DefaultAuditListLimit = 100
+
+type auditContextKey string
+
+// auditActorKey is the context key that carries the actor recorded in audit
+// events.
+const auditActorKey auditContextKey = "grantory:audit_actor"
+
+// WithAuditActor returns a context whose mutations are attributed to actor.
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey, actor)
+}
+
+// AuditActor returns the actor carried by ctx, or AuditActorSystem.
+func AuditActor(ctx context.Context) string {
+	if actor, ok := ctx.Value(auditActorKey).(string); ok && strings.TrimSpace(actor) != "" {
+		return actor
+	}
+	return AuditActorSystem
+}
+
+const auditEventsTableStatement = `
+CREATE TABLE IF NOT EXISTS audit_events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	actor TEXT NOT NULL,
+	action TEXT NOT NULL,
+	resource_type TEXT NOT NULL,
+	resource_id TEXT NOT NULL,
+	state_before TEXT,
+	state_after TEXT,
+	created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+)`
+
+// AuditState is the snapshot of a resource before or after a mutation. The
+// payload is stored as a SHA-256 hash so the audit log never holds secrets.
+// Status is only set for requests.
+type AuditState struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	PayloadHash string            `json:"payload_hash,omitempty"`
+	Status      RequestStatus     `json:"status,omitempty"`
+}
+
+// AuditEvent records who changed which resource and how. Before is empty for
+// creations and After is empty for deletions.
+type AuditEvent struct {
+	ID           int64       `json:"id"`
+	Actor        string      `json:"actor"`
+	Action       string      `json:"action"`
+	ResourceType string      `json:"resource_type"`
+	ResourceID   string      `json:"resource_id"`
+	Before       *AuditState `json:"before,omitempty"`
+	After        *AuditState `json:"after,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// AuditListFilters describes optional filters for listing audit events.
+type AuditListFilters struct {
+	Since        *time.Time
+	Until        *time.Time
+	ResourceType string
+	ResourceID   string
+	Limit        int
+}
+
+// AuditResourceTypes returns every resource type recorded in the audit log.
+func AuditResourceTypes() []string {
+	return append(EventResourceTypes(), AuditResourceWebhooks, AuditResourceRequestTypes)
+}
+
+// ListAuditEvents returns audit events matching the filters, newest first.
+// Since is inclusive and Until is exclusive.
+func (s *Store) ListAuditEvents(ctx context.Context, filters AuditListFilters) ([]AuditEvent, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	if filters.Limit <= 0 {
+		filters.Limit = DefaultAuditListLimit
+	}
+
+	defer s.logDBOperation(ctx, "audit_events", "list", logrus.Fields{
+		"since":         filters.Since,
+		"until":         filters.Until,
+		"resource_type": filters.ResourceType,
+		"resource_id":   filters.ResourceID,
+		"limit":         filters.Limit,
+	})()
+
+	var (
+		conditions []string
+		args       []any
+	)
+	if filters.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, formatOptionalTime(filters.Since))
+	}
+	if filters.Until != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, formatOptionalTime(filters.Until))
+	}
+	if filters.ResourceType != "" {
+		conditions = append(conditions, "resource_type = ?")
+		args = append(args, filters.ResourceType)
+	}
+	if filters.ResourceID != "" {
+		conditions = append(conditions, "resource_id = ?")
+		args = append(args, filters.ResourceID)
+	}
+
+	query := `
+SELECT id, actor, action, resource_type, resource_id, state_before, state_after, created_at
+FROM audit_events
+`
+	if len(conditions) > 0 {
+		query += "WHERE " + strings.Join(conditions, " AND ") + "\n"
+	}
+	query += "ORDER BY id DESC\nLIMIT ?"
+	args = append(args, filters.Limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query audit events: %w", err)
+	}
+	defer closeRows(rows, "close audit event rows")
+
+	events := make([]AuditEvent, 0)
+	for rows.Next() {
+		var (
+			event     AuditEvent
+			before    sql.NullString
+			after     sql.NullString
+			createdAt string
+		)
+		if err := rows.Scan(&event.ID, &event.Actor, &event.Action, &event.ResourceType, &event.ResourceID, &before, &after, &createdAt); err != nil {
+			return nil, fmt.Errorf("scan audit event: %w", err)
+		}
+		if event.Before, err = decodeAuditState(before); err != nil {
+			return nil, err
+		}
+		if event.After, err = decodeAuditState(after); err != nil {
+			return nil, err
+		}
+		if event.CreatedAt, err = parseCreatedAt(createdAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan audit events: %w", err)
+	}
+	return events, nil
+}
+
+// auditSnapshot captures the labels, payload hash and request status of a
+// resource inside tx. It returns nil when the resource does not exist.
+func auditSnapshot(ctx context.Context, tx *sql.Tx, resourceType, id string) (*AuditState, error) {
+	payloadColumn, statusColumn, idColumn := "NULL", "NULL", "id"
+	switch resourceType {
+	case EventResourceRequests:
+		payloadColumn, statusColumn = "data", "status"
+	case EventResourceRegisters:
+		payloadColumn = "data"
+	case EventResourceGrants:
+		payloadColumn = "payload"
+	case EventResourceHosts, AuditResourceWebhooks:
+	case AuditResourceRequestTypes:
+		// Request types have no status; both schemas make up the payload.
+		payloadColumn, statusColumn, idColumn = "request_schema", "grant_schema", "name"
+	default:
+		return nil, fmt.Errorf("unknown audit resource type %q", resourceType)
+	}
+
+	var payload, status sql.NullString
+	if err := tx.QueryRowContext(ctx, `SELECT `+payloadColumn+`, `+statusColumn+` FROM `+resourceType+` WHERE `+idColumn+` = ?`, id).Scan(&payload, &status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("load %s audit state: %w", resourceType, err)
+	}
+
+	if resourceType == AuditResourceRequestTypes {
+		// Both schemas are hashed together, as a JSON array of the two documents.
+		pair, err := encodeSchemaPair(payload, status)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(pair)
+		return &AuditState{PayloadHash: hex.EncodeToString(sum[:])}, nil
+	}
+
+	state := &AuditState{Status: RequestStatus(status.String)}
+	if payload.Valid && payload.String != "" {
+		sum := sha256.Sum256([]byte(payload.String))
+		state.PayloadHash = hex.EncodeToString(sum[:])
+	}
+	if resourceType == AuditResourceWebhooks {
+		return state, nil
+	}
+
+	labelsTable, idColumn, err := eventLabelsSource(resourceType)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT key, value FROM `+labelsTable+` WHERE `+idColumn+` = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("load %s audit labels: %w", resourceType, err)
+	}
+	defer closeRows(rows, "close audit label rows")
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("scan %s audit labels: %w", resourceType, err)
+		}
+		if state.Labels == nil {
+			state.Labels = make(map[string]string)
+		}
+		state.Labels[key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan %s audit labels: %w", resourceType, err)
+	}
+	return state, nil
+}
+
+// recordAudit appends an audit event for a mutation. before is the state
+// captured ahead of the change; the state after the change is read inside the
+// same transaction, so deletions must be recorded after the row is removed.
+func recordAudit(ctx context.Context, tx *sql.Tx, action, resourceType, id string, before *AuditState) error {
+	after, err := auditSnapshot(ctx, tx, resourceType, id)
+	if err != nil {
+		return err
+	}
+	beforeValue, err := encodeAuditState(before)
+	if err != nil {
+		return err
+	}
+	afterValue, err := encodeAuditState(after)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO audit_events (actor, action, resource_type, resource_id, state_before, state_after)
+VALUES (?, ?, ?, ?, ?, ?)
+`, AuditActor(ctx), action, resourceType, id, beforeValue, afterValue); err != nil {
+		return fmt.Errorf("record %s audit event: %w", resourceType, err)
+	}
+	return nil
+}
+
+// cascadedDelete is a row removed by ON DELETE CASCADE, with its audit state
+// from before the deletion.
+type cascadedDelete struct {
+	resourceType string
+	id           string
+	before       *AuditState
+}
+
+// recordCascadeAudits records a delete audit event for every cascaded row.
+func recordCascadeAudits(ctx context.Context, tx *sql.Tx, deleted ...[]cascadedDelete) error {
+	for _, rows := range deleted {
+		for _, row := range rows {
+			if err := recordAudit(ctx, tx, "delete", row.resourceType, row.id, row.before); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encodeSchemaPair encodes two schema documents as a JSON array of strings.
+func encodeSchemaPair(requestSchema, grantSchema sql.NullString) ([]byte, error) {
+	pair := []any{nil, nil}
+	for i, schema := range []sql.NullString{requestSchema, grantSchema} {
+		if schema.Valid {
+			pair[i] = schema.String
+		}
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(pair); err != nil {
+		return nil, fmt.Errorf("encode request type audit state: %w", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func encodeAuditState(state *AuditState) (any, error) {
+	if state == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("encode audit state: %w", err)
+	}
+	return string(data), nil
+}
+
+func decodeAuditState(value sql.NullString) (*AuditState, error) {
+	if !value.Valid || value.String == "" {
+		return nil, nil
+	}
+	var state AuditState
+	if err := json.Unmarshal([]byte(value.String), &state); err != nil {
+		return nil, fmt.Errorf("decode audit state: %w", err)
+	}
+	return &state, nil
+}
+
+func (s *Store) ensureAuditEventsTable(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		auditEventsTableStatement,
+		`CREATE INDEX IF NOT EXISTS audit_events_created_at ON audit_events (created_at)`,
+		`CREATE INDEX IF NOT EXISTS audit_events_resource ON audit_events (resource_type, resource_id)`,
+		`CREATE TRIGGER IF NOT EXISTS audit_events_no_update BEFORE UPDATE ON audit_events
+BEGIN SELECT RAISE(ABORT, 'audit events are append-only'); END`,
+		`CREATE TRIGGER IF NOT EXISTS audit_events_no_delete BEFORE DELETE ON audit_events
+BEGIN SELECT RAISE(ABORT, 'audit events are append-only'); END`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("create audit events schema: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditEvents(t *testing.T) {
+	t.Parallel()
+
+	ctx := WithAuditActor(context.Background(), "alice")
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	host, err := store.CreateHost(ctx, Host{Labels: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+	req, err := store.CreateRequest(ctx, Request{HostID: host.ID, Payload: map[string]any{"db": "orders"}})
+	require.NoError(t, err)
+	require.NoError(t, store.UpdateRequestLabels(ctx, req.ID, map[string]string{"team": "ops"}))
+	grant, err := store.CreateGrant(WithAuditActor(ctx, "token:grantor"), Grant{RequestID: req.ID, Payload: []byte(`{"password":"secret"}`)})
+	require.NoError(t, err)
+	require.NoError(t, store.SetRequestStatus(WithAuditActor(ctx, "token:grantor"), req.ID, RequestStatusRevoked, "rotated"))
+	require.NoError(t, store.DeleteGrant(context.Background(), grant.ID))
+
+	events, err := store.ListAuditEvents(ctx, AuditListFilters{})
+	require.NoError(t, err)
+	require.Len(t, events, 6)
+
+	deleted := events[0]
+	assert.Equal(t, AuditActorSystem, deleted.Actor, "mutations without an actor are attributed to the system")
+	assert.Equal(t, "delete", deleted.Action)
+	assert.Equal(t, EventResourceGrants, deleted.ResourceType)
+	require.NotNil(t, deleted.Before)
+	assert.Len(t, deleted.Before.PayloadHash, 64, "payloads are stored as hashes")
+	assert.Nil(t, deleted.After, "deletions have no after state")
+
+	revoked := events[1]
+	assert.Equal(t, "token:grantor", revoked.Actor)
+	assert.Equal(t, "set_status", revoked.Action)
+	require.NotNil(t, revoked.Before)
+	require.NotNil(t, revoked.After)
+	assert.Equal(t, RequestStatusApproved, revoked.Before.Status)
+	assert.Equal(t, RequestStatusRevoked, revoked.After.Status)
+
+	relabeled := events[3]
+	assert.Equal(t, "alice", relabeled.Actor)
+	assert.Equal(t, "update_labels", relabeled.Action)
+	assert.Empty(t, relabeled.Before.Labels)
+	assert.Equal(t, map[string]string{"team": "ops"}, relabeled.After.Labels)
+	assert.Equal(t, relabeled.Before.PayloadHash, relabeled.After.PayloadHash, "label changes keep the payload hash")
+
+	created := events[5]
+	assert.Equal(t, "create", created.Action)
+	assert.Equal(t, EventResourceHosts, created.ResourceType)
+	assert.Nil(t, created.Before, "creations have no before state")
+	assert.Equal(t, map[string]string{"env": "prod"}, created.After.Labels)
+
+	byResource, err := store.ListAuditEvents(ctx, AuditListFilters{ResourceType: EventResourceRequests, ResourceID: req.ID})
+	require.NoError(t, err)
+	assert.Len(t, byResource, 3, "filter by resource")
+
+	future := time.Now().Add(time.Hour)
+	none, err := store.ListAuditEvents(ctx, AuditListFilters{Since: &future})
+	require.NoError(t, err)
+	assert.Empty(t, none, "filter by time range")
+	limited, err := store.ListAuditEvents(ctx, AuditListFilters{Until: &future, Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, limited, 2, "limit caps the result")
+
+	_, err = store.DB().ExecContext(ctx, `DELETE FROM audit_events`)
+	assert.Error(t, err, "audit events are append-only")
+	_, err = store.DB().ExecContext(ctx, `UPDATE audit_events SET actor = 'mallory'`)
+	assert.Error(t, err, "audit events are append-only")
+}
+
+func TestAuditCascadeDeletes(t *testing.T) {
+	t.Parallel()
+
+	ctx := WithAuditActor(context.Background(), "alice")
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	host, err := store.CreateHost(ctx, Host{})
+	require.NoError(t, err)
+	req, err := store.CreateRequest(ctx, Request{HostID: host.ID})
+	require.NoError(t, err)
+	grant, err := store.CreateGrant(ctx, Grant{RequestID: req.ID, Payload: []byte(`{"password":"secret"}`)})
+	require.NoError(t, err)
+	reg, err := store.CreateRegister(ctx, Register{HostID: host.ID})
+	require.NoError(t, err)
+
+	require.NoError(t, store.DeleteHost(WithAuditActor(ctx, "bob"), host.ID))
+
+	for resourceType, id := range map[string]string{
+		EventResourceHosts:     host.ID,
+		EventResourceRequests:  req.ID,
+		EventResourceGrants:    grant.ID,
+		EventResourceRegisters: reg.ID,
+	} {
+		events, err := store.ListAuditEvents(ctx, AuditListFilters{ResourceType: resourceType, ResourceID: id})
+		require.NoError(t, err)
+		require.NotEmpty(t, events, "%s deletion is audited", resourceType)
+		deleted := events[0]
+		assert.Equal(t, "delete", deleted.Action, resourceType)
+		assert.Equal(t, "bob", deleted.Actor, resourceType)
+		assert.NotNil(t, deleted.Before, resourceType)
+		assert.Nil(t, deleted.After, resourceType)
+	}
+}
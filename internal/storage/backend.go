@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// Backend is the namespace storage used by the server and the CLI. *Store
+// implements it on SQLite and PostgreSQL; see New and OpenPostgres.
+type Backend interface {
+	SetNamespace(namespace string)
+	SetOperationObserver(observer OperationObserver)
+	Migrate(ctx context.Context) error
+	MigrationStatus(ctx context.Context) (MigrationStatus, error)
+	Close() error
+
+	CreateHost(ctx context.Context, host Host) (Host, error)
+	GetHost(ctx context.Context, id string) (Host, error)
+	ListHosts(ctx context.Context) ([]Host, error)
+	ListHostsPage(ctx context.Context, filters *HostListFilters, opts PageOptions) ([]Host, string, error)
+	ListStaleHosts(ctx context.Context, before time.Time) ([]Host, error)
+	UpdateHostLabels(ctx context.Context, id string, labels map[string]string) error
+	RecordHostHeartbeat(ctx context.Context, id string, seenAt time.Time) (Host, error)
+	DeleteHost(ctx context.Context, id string) error
+
+	CreateRequest(ctx context.Context, req Request) (Request, error)
+	GetRequest(ctx context.Context, id string) (Request, error)
+	ListRequests(ctx context.Context, filters *RequestListFilters) ([]Request, error)
+	ListRequestsPage(ctx context.Context, filters *RequestListFilters, opts PageOptions) ([]Request, string, error)
+	UpdateRequest(ctx context.Context, id string, update RequestUpdate) error
+	UpdateRequestLabels(ctx context.Context, id string, labels map[string]string) error
+	UpdateRequestPayload(ctx context.Context, id string, payload map[string]any) error
+	ListRequestRevisions(ctx context.Context, id string) ([]RequestRevision, error)
+	SetRequestStatus(ctx context.Context, id string, status RequestStatus, reason string) error
+	SetRequestExpiry(ctx context.Context, id string, expiresAt *time.Time) error
+	DeleteRequest(ctx context.Context, id string) error
+	CountRequestsByGrantPresence(ctx context.Context) (map[string]int64, error)
+	CountRequestsByStatus(ctx context.Context) (map[string]int64, error)
+
+	CreateRegister(ctx context.Context, reg Register) (Register, error)
+	GetRegister(ctx context.Context, id string) (Register, error)
+	ListRegisters(ctx context.Context, filters *RegisterListFilters) ([]Register, error)
+	ListRegistersPage(ctx context.Context, filters *RegisterListFilters, opts PageOptions) ([]Register, string, error)
+	UpdateRegisterLabels(ctx context.Context, id string, labels map[string]string) error
+	SetRegisterExpiry(ctx context.Context, id string, expiresAt *time.Time) error
+	DeleteRegister(ctx context.Context, id string) error
+	CountRegisters(ctx context.Context) (map[string]int64, error)
+
+	CreateGrant(ctx context.Context, grant Grant) (Grant, error)
+	GetGrant(ctx context.Context, id string) (Grant, error)
+	GetLatestGrantForRequest(ctx context.Context, requestID string) (Grant, bool, error)
+	ListGrants(ctx context.Context, filters *GrantListFilters) ([]Grant, error)
+	ListGrantsPage(ctx context.Context, filters *GrantListFilters, opts PageOptions) ([]Grant, string, error)
+	UpdateGrantLabels(ctx context.Context, id string, labels map[string]string) error
+	DeleteGrant(ctx context.Context, id string) error
+	CountGrants(ctx context.Context) (map[string]int64, error)
+
+	PutRequestType(ctx context.Context, requestType RequestType) (RequestType, error)
+	GetRequestType(ctx context.Context, name string) (RequestType, error)
+	ListRequestTypes(ctx context.Context) ([]RequestType, error)
+	DeleteRequestType(ctx context.Context, name string) error
+
+	ListEvents(ctx context.Context, filters EventListFilters) ([]Event, error)
+	LatestEventSeq(ctx context.Context) (int64, error)
+	EventSignal() <-chan struct{}
+	ListAuditEvents(ctx context.Context, filters AuditListFilters) ([]AuditEvent, error)
+
+	CreateWebhook(ctx context.Context, webhook Webhook) (Webhook, error)
+	GetWebhook(ctx context.Context, id string) (Webhook, error)
+	ListWebhooks(ctx context.Context) ([]Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook Webhook) (Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	ListWebhookDeliveries(ctx context.Context, filters WebhookDeliveryListFilters) ([]WebhookDelivery, error)
+	EnqueueWebhookDeliveries(ctx context.Context, now time.Time) (int, error)
+	DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]WebhookDispatch, error)
+	ClaimWebhookDelivery(ctx context.Context, id string, now, until time.Time) (bool, error)
+	RecordWebhookDeliveryAttempt(ctx context.Context, id string, attempt WebhookDeliveryAttempt) (WebhookDelivery, error)
+
+	ExpireRecords(ctx context.Context, now time.Time) (ExpiryReport, error)
+	ExportNamespace(ctx context.Context) (Export, error)
+	ImportNamespace(ctx context.Context, doc Export, policy ConflictPolicy) (ImportReport, error)
+	Backup(ctx context.Context, path string) error
+	Stats(ctx context.Context) (Stats, error)
+}
+
+var _ Backend = (*Store)(nil)
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidBackup is returned when a file is not a usable namespace backup.
+var ErrInvalidBackup = errors.New("invalid backup")
+
+// Backup writes a consistent snapshot of the database to path with VACUUM
+// INTO, which is safe while the database is in use. path must not exist.
+func (s *Store) Backup(ctx context.Context, path string) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if s.dialect != dialectSQLite {
+		return ErrBackupUnsupported
+	}
+
+	defer s.logDBOperation(ctx, "namespace", "backup", map[string]any{"path": path})()
+
+	if _, err := s.db.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("backup database: %w", err)
+	}
+	return nil
+}
+
+// VerifyBackup checks that the database passes an integrity check, holds a
+// Grantory schema and was not migrated by a newer binary.
+func (s *Store) VerifyBackup(ctx context.Context) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("store not initialized")
+	}
+
+	var result string
+	if err := s.db.QueryRowContext(ctx, `PRAGMA integrity_check`).Scan(&result); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: integrity check failed: %s", ErrInvalidBackup, result)
+	}
+
+	status, err := s.MigrationStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if status.CurrentVersion == 0 {
+		return fmt.Errorf("%w: no grantory schema found", ErrInvalidBackup)
+	}
+	if status.CurrentVersion > status.LatestVersion {
+		return fmt.Errorf("%w: backup is at version %d, binary supports up to %d", ErrSchemaTooNew, status.CurrentVersion, status.LatestVersion)
+	}
+	return nil
+}
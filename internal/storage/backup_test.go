@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackup(t *testing.T) {
+	t.Parallel()
+	skipUnlessSQLite(t)
+
+	ctx := context.Background()
+	store, err := New(ctx, filepath.Join(t.TempDir(), "source.db"))
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+	host, err := store.CreateHost(ctx, Host{Labels: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "backup.db")
+	require.NoError(t, store.Backup(ctx, path))
+	assert.Error(t, store.Backup(ctx, path), "existing files are not overwritten")
+
+	backup, err := New(ctx, path)
+	require.NoError(t, err)
+	defer closeStore(t, backup)
+	require.NoError(t, backup.VerifyBackup(ctx))
+	restored, err := backup.GetHost(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, host.Labels, restored.Labels)
+
+	empty, err := newTestStore(ctx, t)
+	require.NoError(t, err)
+	defer closeStore(t, empty)
+	assert.ErrorIs(t, empty.VerifyBackup(ctx), ErrInvalidBackup, "databases without a schema are rejected")
+}
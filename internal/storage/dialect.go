@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dialect is the SQL flavour of the database behind a store. Queries are
+// written to run on both; the few constructs that differ are built here.
+type dialect int
+
+const (
+	dialectSQLite dialect = iota
+	dialectPostgres
+)
+
+func (d dialect) String() string {
+	if d == dialectPostgres {
+		return "postgresql"
+	}
+	return "sqlite"
+}
+
+// schemaMigrationsTable returns the statement that creates the migration
+// bookkeeping table.
+func (d dialect) schemaMigrationsTable() string {
+	if d == dialectPostgres {
+		return `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TEXT NOT NULL DEFAULT ` + postgresNow + `
+)`
+	}
+	return schemaMigrationsTableStatement
+}
+
+// tableExistsQuery returns a query reporting whether the table named by its
+// only argument exists in the store's schema.
+func (d dialect) tableExistsQuery() string {
+	if d == dialectPostgres {
+		return `SELECT to_regclass(quote_ident(?)) IS NOT NULL`
+	}
+	return `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)`
+}
+
+// payloadValue returns an expression that renders the JSON value at the
+// path given by its arguments the way PayloadFilter values are written, and
+// the arguments for path. column holds the payload as JSON text.
+func (d dialect) payloadValue(column, path string) (string, []any) {
+	if d == dialectPostgres {
+		// #>> renders strings without quotes and JSON null as SQL NULL.
+		return fmt.Sprintf(
+			"COALESCE(%[1]s::jsonb #>> ?::text[], CASE WHEN jsonb_typeof(%[1]s::jsonb #> ?::text[]) = 'null' THEN 'null' END)",
+			column,
+		), []any{postgresJSONPath(path), postgresJSONPath(path)}
+	}
+	return fmt.Sprintf(
+		"(CASE json_type(%[1]s, ?) WHEN 'true' THEN 'true' WHEN 'false' THEN 'false' WHEN 'null' THEN 'null' ELSE CAST(json_extract(%[1]s, ?) AS TEXT) END)",
+		column,
+	), []any{path, path}
+}
+
+// postgresJSONPath converts a normalized JSONPath such as $.items[0].id to
+// the text array form {items,0,id} used by the #> operators. Paths only
+// contain characters that need no quoting in an array literal.
+func postgresJSONPath(path string) string {
+	trimmed := strings.TrimPrefix(path, "$.")
+	trimmed = strings.ReplaceAll(trimmed, "[", ".")
+	trimmed = strings.ReplaceAll(trimmed, "]", "")
+	return "{" + strings.ReplaceAll(trimmed, ".", ",") + "}"
+}
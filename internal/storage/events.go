@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Resource types recorded in the change feed.
+const (
+	EventResourceHosts     = "hosts"
+	EventResourceRequests  = "requests"
+	EventResourceRegisters = "registers"
+	EventResourceGrants    = "grants"
+)
+
+// Actions recorded in the change feed.
+const (
+	EventActionCreated = "created"
+	EventActionUpdated = "updated"
+	EventActionDeleted = "deleted"
+)
+
+// DefaultEventListLimit caps the number of events returned by ListEvents when no limit is given.
+const DefaultEventListLimit = 100
+
+const eventsTableStatement = `
+CREATE TABLE IF NOT EXISTS events (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	resource_type TEXT NOT NULL,
+	resource_id TEXT NOT NULL,
+	action TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+)`
+
+// Event is a single entry of the namespace change feed. Sequence numbers
+// increase monotonically and are never reused, so clients can resume from
+// the last sequence they have seen. Labels are a snapshot taken when the
+// event was recorded, so they are still known after a deletion.
+type Event struct {
+	Seq          int64             `json:"seq"`
+	ResourceType string            `json:"resource_type"`
+	ResourceID   string            `json:"resource_id"`
+	Action       string            `json:"action"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// EventListFilters describes optional filters for listing events.
+type EventListFilters struct {
+	Since         int64
+	Limit         int
+	ResourceTypes []string
+}
+
+// EventResourceTypes returns every resource type recorded in the change feed.
+func EventResourceTypes() []string {
+	return []string{EventResourceHosts, EventResourceRequests, EventResourceRegisters, EventResourceGrants}
+}
+
+func (s *Store) ensureEventsTable(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, eventsTableStatement); err != nil {
+		return fmt.Errorf("create events table: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) ensureEventLabelsColumn(ctx context.Context, tx *sql.Tx) error {
+	columns, err := tableColumns(ctx, tx, "events")
+	if err != nil {
+		return err
+	}
+	if columns["labels"] {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE events ADD COLUMN labels TEXT`); err != nil {
+		return fmt.Errorf("add events labels column: %w", err)
+	}
+	return nil
+}
+
+// eventLabelsSource returns the label table and its id column for a resource type.
+func eventLabelsSource(resourceType string) (string, string, error) {
+	switch resourceType {
+	case EventResourceHosts:
+		return hostLabelsTable, "host_id", nil
+	case EventResourceRequests:
+		return requestLabelsTable, "request_id", nil
+	case EventResourceRegisters:
+		return registerLabelsTable, "register_id", nil
+	case EventResourceGrants:
+		return grantLabelsTable, "grant_id", nil
+	default:
+		return "", "", fmt.Errorf("unknown event resource type %q", resourceType)
+	}
+}
+
+// ListEvents returns events with a sequence greater than filters.Since in order.
+func (s *Store) ListEvents(ctx context.Context, filters EventListFilters) ([]Event, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	if filters.Limit <= 0 {
+		filters.Limit = DefaultEventListLimit
+	}
+
+	defer s.logDBOperation(ctx, "events", "list", logrus.Fields{
+		"since":          filters.Since,
+		"limit":          filters.Limit,
+		"resource_types": filters.ResourceTypes,
+	})()
+
+	query := strings.Builder{}
+	query.WriteString(`
+SELECT seq, resource_type, resource_id, action, labels, created_at
+FROM events
+WHERE seq > ?`)
+	args := []any{filters.Since}
+	if len(filters.ResourceTypes) > 0 {
+		query.WriteString(" AND resource_type IN (?" + strings.Repeat(", ?", len(filters.ResourceTypes)-1) + ")")
+		for _, resourceType := range filters.ResourceTypes {
+			args = append(args, resourceType)
+		}
+	}
+	query.WriteString(" ORDER BY seq ASC LIMIT ?")
+	args = append(args, filters.Limit)
+
+	rows, err := s.db.QueryContext(ctx, query.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query events: %w", err)
+	}
+	defer closeRows(rows, "close events rows")
+
+	events := make([]Event, 0)
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan events: %w", err)
+	}
+	return events, nil
+}
+
+// LatestEventSeq returns the sequence of the newest event, or 0 if there is none.
+func (s *Store) LatestEventSeq(ctx context.Context) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, fmt.Errorf("store not initialized")
+	}
+
+	var seq sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, `SELECT MAX(seq) FROM events`).Scan(&seq); err != nil {
+		return 0, fmt.Errorf("query latest event: %w", err)
+	}
+	return seq.Int64, nil
+}
+
+// EventSignal returns a channel that is closed the next time this store
+// records events. Callers must fetch a fresh channel after every wake-up.
+// Writes made by other processes against the same file do not signal.
+func (s *Store) EventSignal() <-chan struct{} {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	if s.eventsSignal == nil {
+		s.eventsSignal = make(chan struct{})
+	}
+	return s.eventsSignal
+}
+
+// notifyEvents wakes every waiter of EventSignal. Call it after committing a
+// transaction that recorded events.
+func (s *Store) notifyEvents() {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	if s.eventsSignal != nil {
+		close(s.eventsSignal)
+		s.eventsSignal = nil
+	}
+}
+
+func scanEvent(scanner rowScanner) (Event, error) {
+	var (
+		event     Event
+		labels    sql.NullString
+		createdAt string
+	)
+	if err := scanner.Scan(&event.Seq, &event.ResourceType, &event.ResourceID, &event.Action, &labels, &createdAt); err != nil {
+		return Event{}, fmt.Errorf("scan event: %w", err)
+	}
+	if labels.Valid && labels.String != "" && labels.String != "{}" {
+		if err := json.Unmarshal([]byte(labels.String), &event.Labels); err != nil {
+			return Event{}, fmt.Errorf("decode event labels: %w", err)
+		}
+	}
+	var err error
+	if event.CreatedAt, err = parseCreatedAt(createdAt); err != nil {
+		return Event{}, err
+	}
+	return event, nil
+}
+
+// recordEvent appends an event together with the current labels of the
+// resource. Deletions must be recorded before the row is removed so the
+// label snapshot is still available.
+func recordEvent(ctx context.Context, tx *sql.Tx, resourceType, resourceID, action string) error {
+	labels, err := eventLabels(ctx, tx, resourceType, resourceID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO events (resource_type, resource_id, action, labels)
+VALUES (?, ?, ?, ?)
+`, resourceType, resourceID, action, labels); err != nil {
+		return fmt.Errorf("record %s event: %w", resourceType, err)
+	}
+	return nil
+}
+
+// recordDeleteEvents records deletions for every row of table matched by
+// where, so rows removed by ON DELETE CASCADE still show up in the feed. It
+// returns the audit state of those rows; pass it to recordCascadeAudits once
+// the parent row is gone.
+func recordDeleteEvents(ctx context.Context, tx *sql.Tx, resourceType, where string, args ...any) ([]cascadedDelete, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM `+resourceType+` WHERE `+where+` ORDER BY created_at ASC, id ASC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("record %s delete events: %w", resourceType, err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			closeRows(rows, "close delete event rows")
+			return nil, fmt.Errorf("record %s delete events: %w", resourceType, err)
+		}
+		ids = append(ids, id)
+	}
+	closeRows(rows, "close delete event rows")
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("record %s delete events: %w", resourceType, err)
+	}
+
+	deleted := make([]cascadedDelete, 0, len(ids))
+	for _, id := range ids {
+		before, err := auditSnapshot(ctx, tx, resourceType, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := recordEvent(ctx, tx, resourceType, id, EventActionDeleted); err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, cascadedDelete{resourceType: resourceType, id: id, before: before})
+	}
+	return deleted, nil
+}
+
+// eventLabels returns the labels of a resource encoded as a JSON object.
+func eventLabels(ctx context.Context, tx *sql.Tx, resourceType, resourceID string) (string, error) {
+	labelsTable, idColumn, err := eventLabelsSource(resourceType)
+	if err != nil {
+		return "", err
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT key, value FROM `+labelsTable+` WHERE `+idColumn+` = ?`, resourceID)
+	if err != nil {
+		return "", fmt.Errorf("load %s event labels: %w", resourceType, err)
+	}
+	defer closeRows(rows, "close event label rows")
+
+	labels := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return "", fmt.Errorf("scan %s event labels: %w", resourceType, err)
+		}
+		labels[key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("scan %s event labels: %w", resourceType, err)
+	}
+	encoded, err := json.Marshal(labels)
+	if err != nil {
+		return "", fmt.Errorf("encode %s event labels: %w", resourceType, err)
+	}
+	return string(encoded), nil
+}
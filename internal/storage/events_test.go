@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsRecordMutations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	host, err := store.CreateHost(ctx, Host{})
+	require.NoError(t, err)
+	req, err := store.CreateRequest(ctx, Request{HostID: host.ID})
+	require.NoError(t, err)
+	reg, err := store.CreateRegister(ctx, Register{HostID: host.ID})
+	require.NoError(t, err)
+	grant, err := store.CreateGrant(ctx, Grant{RequestID: req.ID})
+	require.NoError(t, err)
+	require.NoError(t, store.UpdateHostLabels(ctx, host.ID, map[string]string{"env": "prod"}))
+
+	latest, err := store.LatestEventSeq(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, store.DeleteHost(ctx, host.ID))
+
+	events, err := store.ListEvents(ctx, EventListFilters{})
+	require.NoError(t, err)
+
+	type entry struct{ resourceType, id, action string }
+	got := make([]entry, 0, len(events))
+	for i, event := range events {
+		if i > 0 {
+			assert.Greater(t, event.Seq, events[i-1].Seq, "sequence must increase")
+		}
+		got = append(got, entry{event.ResourceType, event.ResourceID, event.Action})
+	}
+	assert.Equal(t, []entry{
+		{EventResourceHosts, host.ID, EventActionCreated},
+		{EventResourceRequests, req.ID, EventActionCreated},
+		{EventResourceRegisters, reg.ID, EventActionCreated},
+		{EventResourceGrants, grant.ID, EventActionCreated},
+		{EventResourceRequests, req.ID, EventActionUpdated},
+		{EventResourceHosts, host.ID, EventActionUpdated},
+		{EventResourceGrants, grant.ID, EventActionDeleted},
+		{EventResourceRequests, req.ID, EventActionDeleted},
+		{EventResourceRegisters, reg.ID, EventActionDeleted},
+		{EventResourceHosts, host.ID, EventActionDeleted},
+	}, got, "cascaded deletes are part of the feed")
+
+	resumed, err := store.ListEvents(ctx, EventListFilters{Since: latest, ResourceTypes: []string{EventResourceRequests}})
+	require.NoError(t, err)
+	require.Len(t, resumed, 1)
+	assert.Equal(t, req.ID, resumed[0].ResourceID)
+
+	limited, err := store.ListEvents(ctx, EventListFilters{Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, limited, 2)
+
+	err = store.DeleteRequest(ctx, "missing")
+	assert.ErrorIs(t, err, ErrRequestNotFound)
+	after, err := store.LatestEventSeq(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, events[len(events)-1].Seq, after, "failed mutations record no events")
+}
+
+func TestEventSignalWakesWaiters(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	signal := store.EventSignal()
+	select {
+	case <-signal:
+		t.Fatal("signal fired before any event")
+	default:
+	}
+
+	_, err = store.CreateHost(ctx, Host{})
+	require.NoError(t, err)
+
+	select {
+	case <-signal:
+	case <-time.After(time.Second):
+		t.Fatal("signal did not fire after an event")
+	}
+	assert.NotEqual(t, signal, store.EventSignal(), "a fresh signal is handed out after firing")
+}
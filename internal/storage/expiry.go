@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ExpiryReport counts the records handled by one ExpireRecords pass.
+type ExpiryReport struct {
+	Requests  int `json:"requests"`
+	Registers int `json:"registers"`
+	Grants    int `json:"grants"`
+}
+
+// Total returns the number of records handled by the pass.
+func (r ExpiryReport) Total() int {
+	return r.Requests + r.Registers + r.Grants
+}
+
+// grantExpiredReason is recorded on requests whose grant ran past its lifetime.
+const grantExpiredReason = "grant expired"
+
+// SetRequestExpiry sets or, with a nil value, clears the expiry of a request.
+func (s *Store) SetRequestExpiry(ctx context.Context, id string, expiresAt *time.Time) error {
+	return s.setExpiry(ctx, "requests", EventResourceRequests, id, expiresAt, ErrRequestNotFound)
+}
+
+// SetRegisterExpiry sets or, with a nil value, clears the expiry of a register entry.
+func (s *Store) SetRegisterExpiry(ctx context.Context, id string, expiresAt *time.Time) error {
+	return s.setExpiry(ctx, "registers", EventResourceRegisters, id, expiresAt, ErrRegisterNotFound)
+}
+
+func (s *Store) setExpiry(ctx context.Context, table, resourceType, id string, expiresAt *time.Time, notFound error) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("store not initialized")
+	}
+
+	defer s.logDBOperation(ctx, table, "set_expiry", logrus.Fields{
+		"id":         id,
+		"expires_at": expiresAt,
+	})()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin %s expiry transaction: %w", table, err)
+	}
+	defer rollbackTx(tx, "rollback "+table+" expiry transaction")
+
+	before, err := auditSnapshot(ctx, tx, resourceType, id)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET expires_at = ? WHERE id = ?`, table), formatOptionalTime(expiresAt), id)
+	if err != nil {
+		return fmt.Errorf("update %s expiry: %w", table, err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("update %s expiry rows affected: %w", table, err)
+	}
+	if count == 0 {
+		return notFound
+	}
+
+	if err := setUpdatedAt(ctx, tx, table, "id", id); err != nil {
+		return fmt.Errorf("refresh %s timestamp: %w", table, err)
+	}
+	if err := recordAudit(ctx, tx, "set_expiry", resourceType, id, before); err != nil {
+		return err
+	}
+	if err := recordEvent(ctx, tx, resourceType, id, EventActionUpdated); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit %s expiry update: %w", table, err)
+	}
+	s.notifyEvents()
+	return nil
+}
+
+// ExpireRecords removes every request, register entry and grant whose expiry
+// is at or before now. Expired requests and register entries are deleted; an
+// expired grant is deleted and its request moves to the expired status.
+func (s *Store) ExpireRecords(ctx context.Context, now time.Time) (ExpiryReport, error) {
+	if s == nil || s.db == nil {
+		return ExpiryReport{}, fmt.Errorf("store not initialized")
+	}
+
+	var report ExpiryReport
+
+	requestIDs, err := s.expiredIDs(ctx, "requests", now)
+	if err != nil {
+		return report, err
+	}
+	for _, id := range requestIDs {
+		if err := s.DeleteRequest(ctx, id); err != nil {
+			if errors.Is(err, ErrRequestNotFound) {
+				continue
+			}
+			return report, fmt.Errorf("expire request %s: %w", id, err)
+		}
+		report.Requests++
+	}
+
+	grantIDs, err := s.expiredIDs(ctx, "grants", now)
+	if err != nil {
+		return report, err
+	}
+	for _, id := range grantIDs {
+		if err := s.removeGrant(ctx, id, "expire", RequestStatusExpired, grantExpiredReason, grantExpiryTransitions); err != nil {
+			if errors.Is(err, ErrGrantNotFound) {
+				continue
+			}
+			return report, fmt.Errorf("expire grant %s: %w", id, err)
+		}
+		report.Grants++
+	}
+
+	registerIDs, err := s.expiredIDs(ctx, "registers", now)
+	if err != nil {
+		return report, err
+	}
+	for _, id := range registerIDs {
+		if err := s.DeleteRegister(ctx, id); err != nil {
+			if errors.Is(err, ErrRegisterNotFound) {
+				continue
+			}
+			return report, fmt.Errorf("expire register %s: %w", id, err)
+		}
+		report.Registers++
+	}
+
+	return report, nil
+}
+
+func (s *Store) expiredIDs(ctx context.Context, table string, now time.Time) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+SELECT id FROM %s
+WHERE expires_at IS NOT NULL AND expires_at <= ?
+ORDER BY expires_at ASC
+`, table), now.UTC().Format(timestampLayout))
+	if err != nil {
+		return nil, fmt.Errorf("query expired %s: %w", table, err)
+	}
+	defer closeRows(rows, "close expired "+table+" rows")
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan expired %s: %w", table, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan expired %s: %w", table, err)
+	}
+	return ids, nil
+}
+
+func (s *Store) ensureExpiryColumns(ctx context.Context, tx *sql.Tx) error {
+	for _, table := range []string{"requests", "registers", "grants"} {
+		columns, err := tableColumns(ctx, tx, table)
+		if err != nil {
+			return err
+		}
+		if !columns["expires_at"] {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN expires_at DATETIME`, table)); err != nil {
+				return fmt.Errorf("add %s expires_at column: %w", table, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_expires_at ON %s (expires_at) WHERE expires_at IS NOT NULL`, table, table)); err != nil {
+			return fmt.Errorf("create %s expiry index: %w", table, err)
+		}
+	}
+	return nil
+}
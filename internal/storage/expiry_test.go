@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpireRecords(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+
+	host, err := store.CreateHost(ctx, Host{})
+	require.NoError(t, err)
+
+	expiredRequest, err := store.CreateRequest(ctx, Request{HostID: host.ID, ExpiresAt: &past})
+	require.NoError(t, err)
+	liveRequest, err := store.CreateRequest(ctx, Request{HostID: host.ID, ExpiresAt: &future})
+	require.NoError(t, err)
+	grantedRequest, err := store.CreateRequest(ctx, Request{HostID: host.ID})
+	require.NoError(t, err)
+	expiredGrant, err := store.CreateGrant(ctx, Grant{RequestID: grantedRequest.ID, ExpiresAt: &past})
+	require.NoError(t, err)
+	revokedRequest, err := store.CreateRequest(ctx, Request{HostID: host.ID})
+	require.NoError(t, err)
+	_, err = store.CreateGrant(ctx, Grant{RequestID: revokedRequest.ID, ExpiresAt: &past})
+	require.NoError(t, err)
+	require.NoError(t, store.SetRequestStatus(ctx, revokedRequest.ID, RequestStatusRevoked, "key leaked"))
+	expiredRegister, err := store.CreateRegister(ctx, Register{HostID: host.ID, ExpiresAt: &past})
+	require.NoError(t, err)
+	liveRegister, err := store.CreateRegister(ctx, Register{HostID: host.ID})
+	require.NoError(t, err)
+
+	loaded, err := store.GetRequest(ctx, liveRequest.ID)
+	require.NoError(t, err)
+	require.NotNil(t, loaded.ExpiresAt)
+	assert.True(t, future.Equal(*loaded.ExpiresAt), "expiry should round-trip")
+
+	report, err := store.ExpireRecords(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, ExpiryReport{Requests: 1, Registers: 1, Grants: 2}, report)
+	assert.Equal(t, 4, report.Total())
+
+	_, err = store.GetRequest(ctx, expiredRequest.ID)
+	assert.ErrorIs(t, err, ErrRequestNotFound, "expired requests are purged")
+	_, err = store.GetRegister(ctx, expiredRegister.ID)
+	assert.ErrorIs(t, err, ErrRegisterNotFound, "expired registers are purged")
+	_, err = store.GetGrant(ctx, expiredGrant.ID)
+	assert.ErrorIs(t, err, ErrGrantNotFound, "expired grants are purged")
+
+	granted, err := store.GetRequest(ctx, grantedRequest.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusExpired, granted.Status, "grant expiry marks the request expired")
+	assert.Equal(t, grantExpiredReason, granted.StatusReason)
+	assert.False(t, granted.HasGrant)
+
+	revoked, err := store.GetRequest(ctx, revokedRequest.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusRevoked, revoked.Status, "grant expiry keeps a revoked request revoked")
+	assert.Equal(t, "key leaked", revoked.StatusReason)
+	assert.False(t, revoked.HasGrant)
+
+	_, err = store.GetRequest(ctx, liveRequest.ID)
+	assert.NoError(t, err, "requests before their expiry are kept")
+	_, err = store.GetRegister(ctx, liveRegister.ID)
+	assert.NoError(t, err, "registers without expiry are kept")
+
+	report, err = store.ExpireRecords(ctx, now)
+	require.NoError(t, err)
+	assert.Zero(t, report.Total(), "a second pass finds nothing")
+}
+
+func TestSetExpiry(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	host, err := store.CreateHost(ctx, Host{})
+	require.NoError(t, err)
+	req, err := store.CreateRequest(ctx, Request{HostID: host.ID})
+	require.NoError(t, err)
+	reg, err := store.CreateRegister(ctx, Register{HostID: host.ID})
+	require.NoError(t, err)
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond)
+	require.NoError(t, store.SetRequestExpiry(ctx, req.ID, &expiresAt))
+	require.NoError(t, store.SetRegisterExpiry(ctx, reg.ID, &expiresAt))
+
+	loadedRequest, err := store.GetRequest(ctx, req.ID)
+	require.NoError(t, err)
+	require.NotNil(t, loadedRequest.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*loadedRequest.ExpiresAt))
+
+	require.NoError(t, store.SetRegisterExpiry(ctx, reg.ID, nil))
+	loadedRegister, err := store.GetRegister(ctx, reg.ID)
+	require.NoError(t, err)
+	assert.Nil(t, loadedRegister.ExpiresAt, "a nil expiry clears it")
+
+	assert.ErrorIs(t, store.SetRequestExpiry(ctx, "missing", &expiresAt), ErrRequestNotFound)
+	assert.ErrorIs(t, store.SetRegisterExpiry(ctx, "missing", nil), ErrRegisterNotFound)
+}
@@ -0,0 +1,537 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ExportVersion is the version of the namespace export document. Documents
+// with a different version are rejected by ImportNamespace.
+const ExportVersion = 1
+
+// auditActionImport marks audit events written by ImportNamespace.
+const auditActionImport = "import"
+
+var (
+	// ErrInvalidExport is returned when an export document cannot be imported.
+	ErrInvalidExport = errors.New("invalid export document")
+	// ErrImportConflict is returned by the fail conflict policy when an
+	// imported record already exists.
+	ErrImportConflict = errors.New("import conflict")
+)
+
+// ConflictPolicy decides how ImportNamespace handles records whose ID exists.
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the existing record.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the existing record with the imported one.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictFail aborts the import without changing anything.
+	ConflictFail ConflictPolicy = "fail"
+)
+
+// ParseConflictPolicy validates a conflict policy name.
+func ParseConflictPolicy(value string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid conflict policy %q: must be %s, %s or %s", value, ConflictSkip, ConflictOverwrite, ConflictFail)
+}
+
+// Export is a logical dump of the hosts, requests, registers and grants of a
+// namespace.
+type Export struct {
+	Version    int              `json:"version"`
+	Namespace  string           `json:"namespace,omitempty"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Hosts      []ExportHost     `json:"hosts"`
+	Requests   []ExportRequest  `json:"requests"`
+	Registers  []ExportRegister `json:"registers"`
+	Grants     []ExportGrant    `json:"grants"`
+}
+
+// ExportHost is a host in an export document.
+type ExportHost struct {
+	ID         string            `json:"id"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	LastSeenAt *time.Time        `json:"last_seen_at,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// ExportRequest is a request in an export document. Only the current payload
+// revision is exported.
+type ExportRequest struct {
+	ID           string            `json:"id"`
+	HostID       string            `json:"host_id"`
+	Payload      map[string]any    `json:"payload,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Status       RequestStatus     `json:"status"`
+	StatusReason string            `json:"status_reason,omitempty"`
+	Revision     int               `json:"revision"`
+	ExpiresAt    *time.Time        `json:"expires_at,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// ExportRegister is a register in an export document.
+type ExportRegister struct {
+	ID        string            `json:"id"`
+	HostID    string            `json:"host_id"`
+	Payload   map[string]any    `json:"payload,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// ExportGrant is a grant in an export document.
+type ExportGrant struct {
+	ID              string            `json:"id"`
+	RequestID       string            `json:"request_id"`
+	Payload         json.RawMessage   `json:"payload,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	RequestRevision int               `json:"request_revision"`
+	ExpiresAt       *time.Time        `json:"expires_at,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// ImportCounts reports what happened to the records of one resource type.
+type ImportCounts struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+// ImportReport summarizes an import.
+type ImportReport struct {
+	Hosts     ImportCounts `json:"hosts"`
+	Requests  ImportCounts `json:"requests"`
+	Registers ImportCounts `json:"registers"`
+	Grants    ImportCounts `json:"grants"`
+}
+
+// ExportNamespace dumps every host, request, register and grant.
+func (s *Store) ExportNamespace(ctx context.Context) (Export, error) {
+	if s == nil || s.db == nil {
+		return Export{}, fmt.Errorf("store not initialized")
+	}
+
+	defer s.logDBOperation(ctx, "namespace", "export", nil)()
+
+	doc := Export{Version: ExportVersion, ExportedAt: time.Now().UTC()}
+
+	hosts, err := s.ListHosts(ctx)
+	if err != nil {
+		return Export{}, err
+	}
+	doc.Hosts = make([]ExportHost, 0, len(hosts))
+	for _, host := range hosts {
+		doc.Hosts = append(doc.Hosts, ExportHost(host))
+	}
+
+	requests, err := s.ListRequests(ctx, nil)
+	if err != nil {
+		return Export{}, err
+	}
+	doc.Requests = make([]ExportRequest, 0, len(requests))
+	for _, req := range requests {
+		doc.Requests = append(doc.Requests, ExportRequest{
+			ID:           req.ID,
+			HostID:       req.HostID,
+			Payload:      req.Payload,
+			Labels:       req.Labels,
+			Status:       req.Status,
+			StatusReason: req.StatusReason,
+			Revision:     req.Revision,
+			ExpiresAt:    req.ExpiresAt,
+			CreatedAt:    req.CreatedAt,
+			UpdatedAt:    req.UpdatedAt,
+		})
+	}
+
+	registers, err := s.ListRegisters(ctx, nil)
+	if err != nil {
+		return Export{}, err
+	}
+	doc.Registers = make([]ExportRegister, 0, len(registers))
+	for _, reg := range registers {
+		doc.Registers = append(doc.Registers, ExportRegister(reg))
+	}
+
+	grants, err := s.ListGrants(ctx, nil)
+	if err != nil {
+		return Export{}, err
+	}
+	doc.Grants = make([]ExportGrant, 0, len(grants))
+	for _, grant := range grants {
+		var payload json.RawMessage
+		if len(grant.Payload) > 0 {
+			if !json.Valid(grant.Payload) {
+				return Export{}, fmt.Errorf("grant %s payload is not valid JSON", grant.ID)
+			}
+			payload = json.RawMessage(grant.Payload)
+		}
+		doc.Grants = append(doc.Grants, ExportGrant{
+			ID:              grant.ID,
+			RequestID:       grant.RequestID,
+			Payload:         payload,
+			Labels:          grant.Labels,
+			RequestRevision: grant.RequestRevision,
+			ExpiresAt:       grant.ExpiresAt,
+			CreatedAt:       grant.CreatedAt,
+			UpdatedAt:       grant.UpdatedAt,
+		})
+	}
+	return doc, nil
+}
+
+// ImportNamespace loads an export document, preserving record IDs and
+// timestamps. Records that already exist are handled according to policy.
+// The import runs in a single transaction, so a failing record leaves the
+// namespace unchanged.
+func (s *Store) ImportNamespace(ctx context.Context, doc Export, policy ConflictPolicy) (ImportReport, error) {
+	if s == nil || s.db == nil {
+		return ImportReport{}, fmt.Errorf("store not initialized")
+	}
+	if doc.Version != ExportVersion {
+		return ImportReport{}, fmt.Errorf("%w: unsupported version %d, expected %d", ErrInvalidExport, doc.Version, ExportVersion)
+	}
+	if _, err := ParseConflictPolicy(string(policy)); err != nil {
+		return ImportReport{}, err
+	}
+
+	defer s.logDBOperation(ctx, "namespace", "import", logrus.Fields{
+		"policy":    policy,
+		"hosts":     len(doc.Hosts),
+		"requests":  len(doc.Requests),
+		"registers": len(doc.Registers),
+		"grants":    len(doc.Grants),
+	})()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("begin import transaction: %w", err)
+	}
+	defer rollbackTx(tx, "rollback import transaction")
+
+	now := time.Now()
+	var report ImportReport
+	for _, host := range doc.Hosts {
+		if err := importHost(ctx, tx, host, policy, now, &report.Hosts); err != nil {
+			return ImportReport{}, err
+		}
+	}
+	for _, req := range doc.Requests {
+		if err := importRequest(ctx, tx, req, policy, now, &report.Requests); err != nil {
+			return ImportReport{}, err
+		}
+	}
+	for _, reg := range doc.Registers {
+		if err := importRegister(ctx, tx, reg, policy, now, &report.Registers); err != nil {
+			return ImportReport{}, err
+		}
+	}
+	for _, grant := range doc.Grants {
+		if err := importGrant(ctx, tx, grant, policy, now, &report.Grants); err != nil {
+			return ImportReport{}, err
+		}
+	}
+	// Approval follows from a grant, so an approved request without one
+	// would never be reported as granted.
+	for _, req := range doc.Requests {
+		if req.Status != RequestStatusApproved {
+			continue
+		}
+		if err := requireImportedGrant(ctx, tx, req.ID); err != nil {
+			return ImportReport{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ImportReport{}, fmt.Errorf("commit import: %w", err)
+	}
+	s.notifyEvents()
+	return report, nil
+}
+
+// importRecord applies policy to one record: it calls insert for new IDs and
+// update for existing ones (overwrite only), then records the audit and
+// change feed events.
+func importRecord(ctx context.Context, tx *sql.Tx, resourceType, id string, policy ConflictPolicy, counts *ImportCounts, insert, update func() error) error {
+	if strings.TrimSpace(id) == "" {
+		return fmt.Errorf("%w: %s record without id", ErrInvalidExport, resourceType)
+	}
+	before, err := auditSnapshot(ctx, tx, resourceType, id)
+	if err != nil {
+		return err
+	}
+
+	action := EventActionCreated
+	if before != nil {
+		switch policy {
+		case ConflictSkip:
+			counts.Skipped++
+			return nil
+		case ConflictFail:
+			return fmt.Errorf("%w: %s %s already exists", ErrImportConflict, resourceType, id)
+		}
+		if err := update(); err != nil {
+			return err
+		}
+		action = EventActionUpdated
+		counts.Updated++
+	} else {
+		if err := insert(); err != nil {
+			return err
+		}
+		counts.Created++
+	}
+
+	if err := recordAudit(ctx, tx, auditActionImport, resourceType, id, before); err != nil {
+		return err
+	}
+	return recordEvent(ctx, tx, resourceType, id, action)
+}
+
+func importHost(ctx context.Context, tx *sql.Tx, host ExportHost, policy ConflictPolicy, now time.Time, counts *ImportCounts) error {
+	createdAt, _ := importTimestamps(host.CreatedAt, time.Time{}, now)
+	lastSeenAt := formatOptionalTime(host.LastSeenAt)
+	return importRecord(ctx, tx, EventResourceHosts, host.ID, policy, counts,
+		func() error {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO hosts (id, created_at, last_seen_at) VALUES (?, ?, ?)`, host.ID, createdAt, lastSeenAt); err != nil {
+				return fmt.Errorf("insert host %s: %w", host.ID, err)
+			}
+			return insertLabels(ctx, tx, hostLabelsTable, "host_id", host.ID, host.Labels)
+		},
+		func() error {
+			if _, err := tx.ExecContext(ctx, `UPDATE hosts SET created_at = ?, last_seen_at = ? WHERE id = ?`, createdAt, lastSeenAt, host.ID); err != nil {
+				return fmt.Errorf("update host %s: %w", host.ID, err)
+			}
+			return replaceLabels(ctx, tx, hostLabelsTable, "host_id", host.ID, host.Labels)
+		},
+	)
+}
+
+func importRequest(ctx context.Context, tx *sql.Tx, req ExportRequest, policy ConflictPolicy, now time.Time, counts *ImportCounts) error {
+	if err := requireImportParent(ctx, tx, "hosts", req.HostID, "request", req.ID); err != nil {
+		return err
+	}
+	status := RequestStatusPending
+	if req.Status != "" {
+		parsed, err := ParseRequestStatus(string(req.Status))
+		if err != nil {
+			return fmt.Errorf("%w: request %s: %w", ErrInvalidExport, req.ID, err)
+		}
+		status = parsed
+	}
+	var reason any
+	if strings.TrimSpace(req.StatusReason) != "" {
+		reason = req.StatusReason
+	}
+	revision := max(req.Revision, 1)
+	payloadValue, err := encodeJSON(req.Payload)
+	if err != nil {
+		return fmt.Errorf("encode request payload: %w", err)
+	}
+	createdAt, updatedAt := importTimestamps(req.CreatedAt, req.UpdatedAt, now)
+	expiresAt := formatOptionalTime(req.ExpiresAt)
+
+	// The request_revisions history is not exported; the current payload is
+	// stored as the only revision.
+	writeDetails := func() error {
+		if err := replaceLabels(ctx, tx, requestLabelsTable, "request_id", req.ID, req.Labels); err != nil {
+			return err
+		}
+		if err := validateRequestPayload(ctx, tx, req.Labels[RequestTypeLabel], payloadValue); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM request_revisions WHERE request_id = ?`, req.ID); err != nil {
+			return fmt.Errorf("clear request revisions: %w", err)
+		}
+		return insertRequestRevision(ctx, tx, req.ID, revision, payloadValue)
+	}
+	return importRecord(ctx, tx, EventResourceRequests, req.ID, policy, counts,
+		func() error {
+			if _, err := tx.ExecContext(ctx, `
+INSERT INTO requests (id, host_id, data, status, status_reason, revision, expires_at, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, req.ID, req.HostID, payloadValue, string(status), reason, revision, expiresAt, createdAt, updatedAt); err != nil {
+				return fmt.Errorf("insert request %s: %w", req.ID, err)
+			}
+			return writeDetails()
+		},
+		func() error {
+			if _, err := tx.ExecContext(ctx, `
+UPDATE requests
+SET host_id = ?, data = ?, status = ?, status_reason = ?, revision = ?, expires_at = ?, created_at = ?, updated_at = ?
+WHERE id = ?
+`, req.HostID, payloadValue, string(status), reason, revision, expiresAt, createdAt, updatedAt, req.ID); err != nil {
+				return fmt.Errorf("update request %s: %w", req.ID, err)
+			}
+			return writeDetails()
+		},
+	)
+}
+
+func requireImportedGrant(ctx context.Context, tx *sql.Tx, requestID string) error {
+	var exists int
+	err := tx.QueryRowContext(ctx, `
+SELECT 1 FROM requests
+WHERE id = ? AND (status != ? OR EXISTS (SELECT 1 FROM grants WHERE grants.request_id = requests.id))
+`, requestID, string(RequestStatusApproved)).Scan(&exists)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("%w: request %s is approved but has no grant", ErrInvalidExport, requestID)
+	case err != nil:
+		return fmt.Errorf("check request %s grant: %w", requestID, err)
+	}
+	return nil
+}
+
+func importRegister(ctx context.Context, tx *sql.Tx, reg ExportRegister, policy ConflictPolicy, now time.Time, counts *ImportCounts) error {
+	if err := requireImportParent(ctx, tx, "hosts", reg.HostID, "register", reg.ID); err != nil {
+		return err
+	}
+	payloadValue, err := encodeJSON(reg.Payload)
+	if err != nil {
+		return fmt.Errorf("encode register payload: %w", err)
+	}
+	createdAt, updatedAt := importTimestamps(reg.CreatedAt, reg.UpdatedAt, now)
+	expiresAt := formatOptionalTime(reg.ExpiresAt)
+
+	return importRecord(ctx, tx, EventResourceRegisters, reg.ID, policy, counts,
+		func() error {
+			if _, err := tx.ExecContext(ctx, `
+INSERT INTO registers (id, host_id, data, expires_at, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?)
+`, reg.ID, reg.HostID, payloadValue, expiresAt, createdAt, updatedAt); err != nil {
+				return fmt.Errorf("insert register %s: %w", reg.ID, err)
+			}
+			return insertLabels(ctx, tx, registerLabelsTable, "register_id", reg.ID, reg.Labels)
+		},
+		func() error {
+			if _, err := tx.ExecContext(ctx, `
+UPDATE registers
+SET host_id = ?, data = ?, expires_at = ?, created_at = ?, updated_at = ?
+WHERE id = ?
+`, reg.HostID, payloadValue, expiresAt, createdAt, updatedAt, reg.ID); err != nil {
+				return fmt.Errorf("update register %s: %w", reg.ID, err)
+			}
+			return replaceLabels(ctx, tx, registerLabelsTable, "register_id", reg.ID, reg.Labels)
+		},
+	)
+}
+
+func importGrant(ctx context.Context, tx *sql.Tx, grant ExportGrant, policy ConflictPolicy, now time.Time, counts *ImportCounts) error {
+	if err := requireImportParent(ctx, tx, "requests", grant.RequestID, "grant", grant.ID); err != nil {
+		return err
+	}
+
+	// A request holds at most one grant, so a different grant for the same
+	// request is a conflict as well.
+	var existingID string
+	err := tx.QueryRowContext(ctx, `SELECT id FROM grants WHERE request_id = ? AND id != ?`, grant.RequestID, grant.ID).Scan(&existingID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return fmt.Errorf("check grant conflicts: %w", err)
+	default:
+		switch policy {
+		case ConflictSkip:
+			counts.Skipped++
+			return nil
+		case ConflictFail:
+			return fmt.Errorf("%w: request %s already has grant %s", ErrImportConflict, grant.RequestID, existingID)
+		}
+		if err := deleteImportedGrant(ctx, tx, existingID); err != nil {
+			return err
+		}
+	}
+
+	var payload any
+	if len(grant.Payload) > 0 && string(grant.Payload) != "null" {
+		if !json.Valid(grant.Payload) {
+			return fmt.Errorf("%w: grant %s payload is not valid JSON", ErrInvalidExport, grant.ID)
+		}
+		if err := validateGrantPayload(ctx, tx, grant.RequestID, grant.Payload); err != nil {
+			return err
+		}
+		payload = string(grant.Payload)
+	}
+	revision := max(grant.RequestRevision, 1)
+	createdAt, updatedAt := importTimestamps(grant.CreatedAt, grant.UpdatedAt, now)
+	expiresAt := formatOptionalTime(grant.ExpiresAt)
+
+	return importRecord(ctx, tx, EventResourceGrants, grant.ID, policy, counts,
+		func() error {
+			if _, err := tx.ExecContext(ctx, `
+INSERT INTO grants (id, request_id, payload, request_revision, expires_at, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`, grant.ID, grant.RequestID, payload, revision, expiresAt, createdAt, updatedAt); err != nil {
+				return fmt.Errorf("insert grant %s: %w", grant.ID, err)
+			}
+			return insertLabels(ctx, tx, grantLabelsTable, "grant_id", grant.ID, grant.Labels)
+		},
+		func() error {
+			if _, err := tx.ExecContext(ctx, `
+UPDATE grants
+SET request_id = ?, payload = ?, request_revision = ?, expires_at = ?, created_at = ?, updated_at = ?
+WHERE id = ?
+`, grant.RequestID, payload, revision, expiresAt, createdAt, updatedAt, grant.ID); err != nil {
+				return fmt.Errorf("update grant %s: %w", grant.ID, err)
+			}
+			return replaceLabels(ctx, tx, grantLabelsTable, "grant_id", grant.ID, grant.Labels)
+		},
+	)
+}
+
+// deleteImportedGrant removes a grant that an overwriting import replaces.
+func deleteImportedGrant(ctx context.Context, tx *sql.Tx, id string) error {
+	before, err := auditSnapshot(ctx, tx, EventResourceGrants, id)
+	if err != nil {
+		return err
+	}
+	if err := recordEvent(ctx, tx, EventResourceGrants, id, EventActionDeleted); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM grants WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete replaced grant %s: %w", id, err)
+	}
+	return recordAudit(ctx, tx, auditActionImport, EventResourceGrants, id, before)
+}
+
+// requireImportParent ensures that the record a child references exists,
+// either from before or earlier in the same import.
+func requireImportParent(ctx context.Context, tx *sql.Tx, table, parentID, kind, id string) error {
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+table+` WHERE id = ?)`, parentID).Scan(&exists); err != nil {
+		return fmt.Errorf("check %s reference: %w", table, err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s %s references unknown %s %q", ErrInvalidExport, kind, id, strings.TrimSuffix(table, "s"), parentID)
+	}
+	return nil
+}
+
+// importTimestamps formats the created_at and updated_at values of an
+// imported record. Missing values default to now and created_at.
+func importTimestamps(createdAt, updatedAt, now time.Time) (string, string) {
+	if createdAt.IsZero() {
+		createdAt = now
+	}
+	if updatedAt.IsZero() {
+		updatedAt = createdAt
+	}
+	return createdAt.UTC().Format(timestampLayout), updatedAt.UTC().Format(timestampLayout)
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImportNamespace(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	source, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, source)
+	require.NoError(t, source.Migrate(ctx), "Migrate() error")
+
+	host, err := source.CreateHost(ctx, Host{Labels: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+	req, err := source.CreateRequest(ctx, Request{HostID: host.ID, Payload: map[string]any{"db": "app"}, Labels: map[string]string{"kind": "db"}})
+	require.NoError(t, err)
+	require.NoError(t, source.UpdateRequestPayload(ctx, req.ID, map[string]any{"db": "app2"}))
+	_, err = source.CreateRegister(ctx, Register{HostID: host.ID, Payload: map[string]any{"ip": "10.0.0.1"}})
+	require.NoError(t, err)
+	grant, err := source.CreateGrant(ctx, Grant{RequestID: req.ID, Payload: []byte(`{"password":"secret"}`)})
+	require.NoError(t, err)
+
+	doc, err := source.ExportNamespace(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, ExportVersion, doc.Version)
+	require.Len(t, doc.Requests, 1)
+	assert.Equal(t, 2, doc.Requests[0].Revision)
+	assert.Equal(t, RequestStatusApproved, doc.Requests[0].Status)
+	require.Len(t, doc.Grants, 1)
+	assert.JSONEq(t, `{"password":"secret"}`, string(doc.Grants[0].Payload))
+
+	target, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, target)
+	require.NoError(t, target.Migrate(ctx), "Migrate() error")
+
+	report, err := target.ImportNamespace(ctx, doc, ConflictFail)
+	require.NoError(t, err)
+	assert.Equal(t, ImportReport{
+		Hosts:     ImportCounts{Created: 1},
+		Requests:  ImportCounts{Created: 1},
+		Registers: ImportCounts{Created: 1},
+		Grants:    ImportCounts{Created: 1},
+	}, report)
+
+	imported, err := target.GetRequest(ctx, req.ID)
+	require.NoError(t, err, "request IDs are preserved")
+	assert.Equal(t, map[string]any{"db": "app2"}, imported.Payload)
+	assert.Equal(t, map[string]string{"kind": "db"}, imported.Labels)
+	assert.Equal(t, 2, imported.Revision)
+	assert.True(t, imported.HasGrant)
+	assert.False(t, imported.StaleGrant)
+	assert.WithinDuration(t, doc.Requests[0].CreatedAt, imported.CreatedAt, time.Millisecond, "timestamps are preserved")
+	importedGrant, err := target.GetGrant(ctx, grant.ID)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"password":"secret"}`, string(importedGrant.Payload))
+
+	_, err = target.ImportNamespace(ctx, doc, ConflictFail)
+	assert.ErrorIs(t, err, ErrImportConflict)
+
+	doc.Hosts[0].Labels = map[string]string{"env": "staging"}
+	report, err = target.ImportNamespace(ctx, doc, ConflictSkip)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Hosts.Skipped)
+	stored, err := target.GetHost(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", stored.Labels["env"], "skip keeps existing records")
+
+	report, err = target.ImportNamespace(ctx, doc, ConflictOverwrite)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Hosts.Updated)
+	assert.Equal(t, 1, report.Grants.Updated)
+	stored, err = target.GetHost(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "staging", stored.Labels["env"], "overwrite replaces existing records")
+	requests, err := target.ListRequests(ctx, nil)
+	require.NoError(t, err)
+	assert.Len(t, requests, 1, "overwriting a host keeps its requests")
+
+	events, err := target.ListAuditEvents(ctx, AuditListFilters{ResourceType: EventResourceHosts, ResourceID: host.ID})
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	assert.Equal(t, "import", events[0].Action)
+}
+
+func TestImportNamespaceRejectsInvalidDocuments(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	_, err = store.ImportNamespace(ctx, Export{Version: ExportVersion + 1}, ConflictFail)
+	assert.ErrorIs(t, err, ErrInvalidExport, "unknown versions are rejected")
+
+	_, err = store.ImportNamespace(ctx, Export{Version: ExportVersion}, ConflictPolicy("merge"))
+	assert.Error(t, err, "unknown policies are rejected")
+
+	doc := Export{
+		Version:  ExportVersion,
+		Hosts:    []ExportHost{{ID: "host-1"}},
+		Requests: []ExportRequest{{ID: "req-1", HostID: "host-1"}, {ID: "req-2", HostID: "missing"}},
+	}
+	_, err = store.ImportNamespace(ctx, doc, ConflictFail)
+	assert.ErrorIs(t, err, ErrInvalidExport, "dangling references are rejected")
+	hosts, err := store.ListHosts(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, hosts, "a failed import changes nothing")
+
+	approved := Export{
+		Version:  ExportVersion,
+		Hosts:    []ExportHost{{ID: "host-1"}},
+		Requests: []ExportRequest{{ID: "req-1", HostID: "host-1", Status: RequestStatusApproved}},
+	}
+	_, err = store.ImportNamespace(ctx, approved, ConflictFail)
+	assert.ErrorIs(t, err, ErrInvalidExport, "approved requests need a grant")
+
+	_, err = store.PutRequestType(ctx, RequestType{
+		Name:          "database",
+		RequestSchema: json.RawMessage(`{"type": "object", "required": ["name"]}`),
+		GrantSchema:   json.RawMessage(`{"type": "object", "required": ["password"]}`),
+	})
+	require.NoError(t, err)
+	typed := Export{
+		Version: ExportVersion,
+		Hosts:   []ExportHost{{ID: "host-1"}},
+		Requests: []ExportRequest{{
+			ID: "req-1", HostID: "host-1",
+			Labels:  map[string]string{RequestTypeLabel: "database"},
+			Payload: map[string]any{"size": "small"},
+		}},
+	}
+	_, err = store.ImportNamespace(ctx, typed, ConflictFail)
+	assert.ErrorIs(t, err, ErrSchemaValidation, "request payloads are checked against their type")
+	typed.Requests[0].Payload = map[string]any{"name": "db"}
+	typed.Grants = []ExportGrant{{ID: "grant-1", RequestID: "req-1", Payload: json.RawMessage(`{"user":"app"}`)}}
+	_, err = store.ImportNamespace(ctx, typed, ConflictFail)
+	assert.ErrorIs(t, err, ErrSchemaValidation, "grant payloads are checked against their type")
+	require.NoError(t, store.DeleteRequestType(ctx, "database"))
+
+	doc.Requests = doc.Requests[:1]
+	doc.Grants = []ExportGrant{{ID: "grant-1", RequestID: "req-1", Payload: json.RawMessage(`{"a":1}`)}}
+	report, err := store.ImportNamespace(ctx, doc, ConflictFail)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Grants.Created)
+	req, err := store.GetRequest(ctx, "req-1")
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusPending, req.Status, "missing statuses default to pending")
+}
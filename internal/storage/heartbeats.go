@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// IsStale reports whether the host sent its last heartbeat before the given
+// time. Hosts that never sent a heartbeat are never stale.
+func (h Host) IsStale(before time.Time) bool {
+	return h.LastSeenAt != nil && h.LastSeenAt.Before(before)
+}
+
+// RecordHostHeartbeat marks a host as seen at the given time and returns the
+// updated host. Heartbeats are not recorded in the change feed.
+func (s *Store) RecordHostHeartbeat(ctx context.Context, id string, seenAt time.Time) (Host, error) {
+	if s == nil || s.db == nil {
+		return Host{}, fmt.Errorf("store not initialized")
+	}
+
+	defer s.logDBOperation(ctx, "hosts", "heartbeat", logrus.Fields{
+		"host_id":      id,
+		"last_seen_at": seenAt,
+	})()
+
+	res, err := s.db.ExecContext(ctx, `UPDATE hosts SET last_seen_at = ? WHERE id = ?`, formatOptionalTime(&seenAt), id)
+	if err != nil {
+		return Host{}, fmt.Errorf("update host heartbeat: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return Host{}, fmt.Errorf("update host heartbeat rows affected: %w", err)
+	}
+	if count == 0 {
+		return Host{}, ErrHostNotFound
+	}
+
+	return s.GetHost(ctx, id)
+}
+
+// ListStaleHosts returns the hosts whose last heartbeat is older than the
+// given time, ordered by creation.
+func (s *Store) ListStaleHosts(ctx context.Context, before time.Time) ([]Host, error) {
+	hosts, _, err := s.ListHostsPage(ctx, &HostListFilters{LastSeenBefore: &before}, PageOptions{})
+	return hosts, err
+}
+
+func (s *Store) ensureHostHeartbeatColumn(ctx context.Context, tx *sql.Tx) error {
+	columns, err := tableColumns(ctx, tx, "hosts")
+	if err != nil {
+		return err
+	}
+	if !columns["last_seen_at"] {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE hosts ADD COLUMN last_seen_at DATETIME`); err != nil {
+			return fmt.Errorf("add hosts last_seen_at column: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS hosts_last_seen_at ON hosts (last_seen_at) WHERE last_seen_at IS NOT NULL`); err != nil {
+		return fmt.Errorf("create hosts last_seen_at index: %w", err)
+	}
+	return nil
+}
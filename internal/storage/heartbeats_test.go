@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostHeartbeats(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	silent, err := store.CreateHost(ctx, Host{})
+	require.NoError(t, err)
+	assert.Nil(t, silent.LastSeenAt, "new hosts have not been seen yet")
+	old, err := store.CreateHost(ctx, Host{Labels: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+	fresh, err := store.CreateHost(ctx, Host{})
+	require.NoError(t, err)
+
+	seen, err := store.RecordHostHeartbeat(ctx, old.ID, now.Add(-8*24*time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, seen.LastSeenAt)
+	assert.True(t, now.Add(-8*24*time.Hour).Equal(*seen.LastSeenAt), "last_seen_at should round-trip")
+	assert.Equal(t, map[string]string{"env": "prod"}, seen.Labels, "heartbeats keep labels")
+	_, err = store.RecordHostHeartbeat(ctx, fresh.ID, now)
+	require.NoError(t, err)
+
+	cutoff := now.Add(-7 * 24 * time.Hour)
+	stale, err := store.ListStaleHosts(ctx, cutoff)
+	require.NoError(t, err)
+	require.Len(t, stale, 1, "only hosts with an old heartbeat are stale")
+	assert.Equal(t, old.ID, stale[0].ID)
+	assert.True(t, stale[0].IsStale(cutoff))
+
+	hosts, err := store.ListHosts(ctx)
+	require.NoError(t, err)
+	require.Len(t, hosts, 3)
+	for _, host := range hosts {
+		assert.Equal(t, host.ID == old.ID, host.IsStale(cutoff), "host %s staleness", host.ID)
+	}
+
+	_, err = store.RecordHostHeartbeat(ctx, "missing", now)
+	assert.ErrorIs(t, err, ErrHostNotFound)
+
+	events, err := store.ListEvents(ctx, EventListFilters{})
+	require.NoError(t, err)
+	for _, event := range events {
+		assert.NotEqual(t, EventActionUpdated, event.Action, "heartbeats stay out of the change feed")
+	}
+}
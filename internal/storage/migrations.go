@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrSchemaTooNew is returned when a database was migrated by a newer binary.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+const schemaMigrationsTableStatement = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// migration is a single numbered schema change. Versions start at 1 and must
+// be consecutive; a migration is never edited once it has been released.
+type migration struct {
+	version int
+	name    string
+	up      func(context.Context, *sql.Tx) error
+}
+
+// MigrationRecord describes one known migration and when it was applied.
+type MigrationRecord struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// MigrationStatus summarizes the schema state of a database.
+type MigrationStatus struct {
+	CurrentVersion int               `json:"current_version"`
+	LatestVersion  int               `json:"latest_version"`
+	Pending        int               `json:"pending"`
+	Migrations     []MigrationRecord `json:"migrations"`
+}
+
+// migrations lists the namespace database schema changes in order.
+func (s *Store) migrations() []migration {
+	list := []migration{
+		{1, "initial schema", s.createInitialSchema},
+		{2, "request status", s.ensureRequestStatusColumns},
+		{3, "events", s.ensureEventsTable},
+		{4, "event labels", s.ensureEventLabelsColumn},
+		{5, "webhooks", s.ensureWebhookTables},
+		{6, "expiry", s.ensureExpiryColumns},
+		{7, "host heartbeats", s.ensureHostHeartbeatColumn},
+		{8, "audit events", s.ensureAuditEventsTable},
+		{9, "request revisions", s.ensureRequestRevisionColumns},
+		{10, "request types", s.ensureRequestTypesTable},
+		{11, "webhook payload option", s.ensureWebhookIncludePayloadColumn},
+	}
+	if s != nil && s.dialect == dialectPostgres {
+		return postgresMigrations(list)
+	}
+	return list
+}
+
+// SchemaVersion returns the newest namespace schema version this binary knows.
+func SchemaVersion() int {
+	return len((*Store)(nil).migrations())
+}
+
+func (s *Store) createInitialSchema(ctx context.Context, tx *sql.Tx) error {
+	tasks := []struct {
+		name string
+		fn   func(context.Context, *sql.Tx) error
+	}{
+		{"hosts", s.ensureHostsTable},
+		{"requests", s.ensureRequestsTable},
+		{"registers", s.ensureRegistersTable},
+		{"grants", s.ensureGrantsTable},
+		{"host labels", s.ensureHostLabelsTable},
+		{"request labels", s.ensureRequestLabelsTable},
+		{"register labels", s.ensureRegisterLabelsTable},
+		{"grant labels", s.ensureGrantLabelsTable},
+	}
+
+	for _, task := range tasks {
+		if err := task.fn(ctx, tx); err != nil {
+			return fmt.Errorf("ensure %s schema: %w", task.name, err)
+		}
+	}
+	return nil
+}
+
+// MigrationStatus reports which migrations have been applied to the database.
+func (s *Store) MigrationStatus(ctx context.Context) (MigrationStatus, error) {
+	if s == nil || s.db == nil {
+		return MigrationStatus{}, fmt.Errorf("store not initialized")
+	}
+	return migrationStatus(ctx, s.db, s.dialect, s.migrations())
+}
+
+func migrationStatus(ctx context.Context, db *sql.DB, d dialect, list []migration) (MigrationStatus, error) {
+	applied, err := appliedMigrations(ctx, db, d)
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+
+	status := MigrationStatus{
+		LatestVersion: len(list),
+		Migrations:    make([]MigrationRecord, 0, len(list)),
+	}
+	for version := range applied {
+		status.CurrentVersion = max(status.CurrentVersion, version)
+	}
+	for _, m := range list {
+		record := MigrationRecord{Version: m.version, Name: m.name}
+		if appliedAt, ok := applied[m.version]; ok {
+			record.AppliedAt = &appliedAt
+		} else {
+			status.Pending++
+		}
+		status.Migrations = append(status.Migrations, record)
+	}
+	return status, nil
+}
+
+// appliedMigrations returns the applied versions with their timestamps. A
+// database without the bookkeeping table reports no applied migrations.
+func appliedMigrations(ctx context.Context, db *sql.DB, d dialect) (map[int]time.Time, error) {
+	var exists bool
+	if err := db.QueryRowContext(ctx, d.tableExistsQuery(), "schema_migrations").Scan(&exists); err != nil {
+		return nil, fmt.Errorf("inspect schema migrations: %w", err)
+	}
+	applied := make(map[int]time.Time)
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations ORDER BY version ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("query schema migrations: %w", err)
+	}
+	defer closeRows(rows, "close schema migration rows")
+
+	for rows.Next() {
+		var (
+			version   int
+			appliedAt string
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("scan schema migration: %w", err)
+		}
+		ts, err := parseCreatedAt(appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = ts
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan schema migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// applyMigrations runs every pending migration in its own transaction and
+// records it in schema_migrations. It refuses to touch databases whose
+// schema is newer than the migrations this binary knows about. On PostgreSQL,
+// servers sharing the database take turns.
+func applyMigrations(ctx context.Context, db *sql.DB, d dialect, namespace string, list []migration) error {
+	if d == dialectPostgres {
+		unlock, err := lockPostgresMigrations(ctx, db)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	if _, err := db.ExecContext(ctx, d.schemaMigrationsTable()); err != nil {
+		return fmt.Errorf("create schema migrations table: %w", err)
+	}
+
+	status, err := migrationStatus(ctx, db, d, list)
+	if err != nil {
+		return err
+	}
+	if status.CurrentVersion > status.LatestVersion {
+		return fmt.Errorf("%w: database is at version %d, binary supports up to %d", ErrSchemaTooNew, status.CurrentVersion, status.LatestVersion)
+	}
+
+	for _, m := range list {
+		if status.Migrations[m.version-1].AppliedAt != nil {
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"namespace": namespace,
+			"version":   m.version,
+			"migration": m.name,
+		}).Info("applying schema migration")
+
+		if err := applyMigration(ctx, db, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin migration %d: %w", m.version, err)
+	}
+	defer rollbackTx(tx, "rollback migration transaction")
+
+	if err := m.up(ctx, tx); err != nil {
+		return fmt.Errorf("apply migration %d (%s): %w", m.version, m.name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.version, m.name); err != nil {
+		return fmt.Errorf("record migration %d: %w", m.version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit migration %d: %w", m.version, err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateRecordsVersions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+
+	status, err := store.MigrationStatus(ctx)
+	require.NoError(t, err, "MigrationStatus() error")
+	assert.Equal(t, 0, status.CurrentVersion, "fresh database has no migrations")
+	assert.Equal(t, SchemaVersion(), status.LatestVersion)
+	assert.Equal(t, SchemaVersion(), status.Pending)
+
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	status, err = store.MigrationStatus(ctx)
+	require.NoError(t, err, "MigrationStatus() error")
+	assert.Equal(t, SchemaVersion(), status.CurrentVersion)
+	assert.Zero(t, status.Pending)
+	require.Len(t, status.Migrations, SchemaVersion())
+	for i, record := range status.Migrations {
+		assert.Equal(t, i+1, record.Version, "migrations are numbered consecutively")
+		assert.NotEmpty(t, record.Name)
+		assert.NotNil(t, record.AppliedAt, "migration %d should be recorded", record.Version)
+	}
+
+	require.NoError(t, store.Migrate(ctx), "Migrate() should be idempotent")
+	var count int
+	require.NoError(t, store.DB().QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations`).Scan(&count))
+	assert.Equal(t, SchemaVersion(), count, "migrations are recorded once")
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	_, err = store.DB().ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES (?, 'from the future')`, SchemaVersion()+1)
+	require.NoError(t, err)
+
+	err = store.Migrate(ctx)
+	assert.ErrorIs(t, err, ErrSchemaTooNew)
+
+	status, err := store.MigrationStatus(ctx)
+	require.NoError(t, err, "status remains readable")
+	assert.Equal(t, SchemaVersion()+1, status.CurrentVersion)
+}
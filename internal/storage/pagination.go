@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SortOrder is the direction of a sorted list.
+type SortOrder string
+
+const (
+	SortAscending  SortOrder = "asc"
+	SortDescending SortOrder = "desc"
+)
+
+const (
+	// DefaultOrderBy is the sort column of lists that do not name one.
+	DefaultOrderBy = "created_at"
+	// MaxPageLimit caps the number of rows returned in one page.
+	MaxPageLimit = 1000
+)
+
+// ErrInvalidPage reports page options that cannot be applied, such as an
+// unknown sort column or a cursor issued for a different sort.
+var ErrInvalidPage = errors.New("invalid page options")
+
+var (
+	hostSortColumns     = []string{"created_at", "id"}
+	resourceSortColumns = []string{"created_at", "updated_at", "id"}
+)
+
+// PageOptions selects one page of a list. A zero Limit returns every remaining
+// row. Cursor is the next cursor returned with the previous page and is only
+// valid together with the same OrderBy and Order.
+type PageOptions struct {
+	Limit   int
+	Cursor  string
+	OrderBy string
+	Order   SortOrder
+}
+
+// ParseSortOrder validates a sort order. An empty value sorts ascending.
+func ParseSortOrder(value string) (SortOrder, error) {
+	switch SortOrder(strings.ToLower(strings.TrimSpace(value))) {
+	case "", SortAscending:
+		return SortAscending, nil
+	case SortDescending:
+		return SortDescending, nil
+	default:
+		return "", fmt.Errorf("%w: order %q must be %s or %s", ErrInvalidPage, value, SortAscending, SortDescending)
+	}
+}
+
+// pageCursor is the decoded form of an opaque cursor. It points at the last
+// row of a page by its sort value and rowid, which breaks ties between rows
+// sharing the same sort value.
+type pageCursor struct {
+	OrderBy string    `json:"o"`
+	Order   SortOrder `json:"d"`
+	Value   string    `json:"v"`
+	RowID   int64     `json:"r"`
+}
+
+func (c pageCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageCursor(value string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidPage)
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidPage)
+	}
+	return &cursor, nil
+}
+
+// pageQuery holds the validated pagination of a query against one table.
+type pageQuery struct {
+	table   string
+	orderBy string
+	order   SortOrder
+	limit   int
+	cursor  *pageCursor
+}
+
+func newPageQuery(table string, opts PageOptions, sortable []string) (pageQuery, error) {
+	page := pageQuery{table: table, orderBy: strings.TrimSpace(opts.OrderBy), limit: opts.Limit}
+	if page.orderBy == "" {
+		page.orderBy = DefaultOrderBy
+	}
+	if !slices.Contains(sortable, page.orderBy) {
+		return pageQuery{}, fmt.Errorf("%w: order_by %q must be one of %s", ErrInvalidPage, page.orderBy, strings.Join(sortable, ", "))
+	}
+	order, err := ParseSortOrder(string(opts.Order))
+	if err != nil {
+		return pageQuery{}, err
+	}
+	page.order = order
+	if page.limit < 0 || page.limit > MaxPageLimit {
+		return pageQuery{}, fmt.Errorf("%w: limit %d must be between 1 and %d", ErrInvalidPage, page.limit, MaxPageLimit)
+	}
+	if opts.Cursor != "" {
+		if page.cursor, err = decodePageCursor(opts.Cursor); err != nil {
+			return pageQuery{}, err
+		}
+		if page.cursor.OrderBy != page.orderBy || page.cursor.Order != page.order {
+			return pageQuery{}, fmt.Errorf("%w: cursor was issued for a different order", ErrInvalidPage)
+		}
+	}
+	return page, nil
+}
+
+// columns returns the extra SELECT columns that carry the sort key of a row.
+// The value is cast to text so DATETIME columns keep their stored form.
+func (p pageQuery) columns() string {
+	return fmt.Sprintf(", CAST(%s.%s AS TEXT), %s.rowid", p.table, p.orderBy, p.table)
+}
+
+// where returns the condition that skips the rows up to the cursor.
+func (p pageQuery) where() (string, []any) {
+	if p.cursor == nil {
+		return "", nil
+	}
+	op := ">"
+	if p.order == SortDescending {
+		op = "<"
+	}
+	column := p.table + "." + p.orderBy
+	return fmt.Sprintf("(%s %s ? OR (%s = ? AND %s.rowid %s ?))", column, op, column, p.table, op),
+		[]any{p.cursor.Value, p.cursor.Value, p.cursor.RowID}
+}
+
+// suffix returns the ORDER BY and LIMIT clauses. One extra row is fetched to
+// tell whether another page follows.
+func (p pageQuery) suffix() string {
+	direction := "ASC"
+	if p.order == SortDescending {
+		direction = "DESC"
+	}
+	clause := fmt.Sprintf(" ORDER BY %s.%s %s, %s.rowid %s", p.table, p.orderBy, direction, p.table, direction)
+	if p.limit > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", p.limit+1)
+	}
+	return clause
+}
+
+// pageKey is the sort key of one scanned row.
+type pageKey struct {
+	value string
+	rowID int64
+}
+
+// scanner wraps a row so the trailing sort key columns are read into the key.
+func (k *pageKey) scanner(row rowScanner) rowScanner {
+	return pageKeyScanner{row: row, key: k}
+}
+
+type pageKeyScanner struct {
+	row rowScanner
+	key *pageKey
+}
+
+func (s pageKeyScanner) Scan(dest ...any) error {
+	return s.row.Scan(append(dest, &s.key.value, &s.key.rowID)...)
+}
+
+// trimPage drops the extra row fetched by suffix and returns the cursor of the
+// next page, or an empty string on the last page.
+func trimPage[T any](p pageQuery, items []T, keys []pageKey) ([]T, string) {
+	if p.limit == 0 || len(items) <= p.limit {
+		return items, ""
+	}
+	last := keys[p.limit-1]
+	cursor := pageCursor{OrderBy: p.orderBy, Order: p.order, Value: last.value, RowID: last.rowID}
+	return items[:p.limit], cursor.encode()
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListPagination(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	var hostIDs []string
+	for range 5 {
+		host, err := store.CreateHost(ctx, Host{})
+		require.NoError(t, err)
+		hostIDs = append(hostIDs, host.ID)
+	}
+
+	collectHosts := func(opts PageOptions) ([]string, int) {
+		var ids []string
+		pages := 0
+		for {
+			hosts, next, err := store.ListHostsPage(ctx, nil, opts)
+			require.NoError(t, err)
+			pages++
+			for _, host := range hosts {
+				ids = append(ids, host.ID)
+			}
+			if next == "" {
+				return ids, pages
+			}
+			opts.Cursor = next
+		}
+	}
+
+	ids, pages := collectHosts(PageOptions{Limit: 2})
+	assert.Equal(t, hostIDs, ids, "pages follow insertion order even when created_at ties")
+	assert.Equal(t, 3, pages)
+
+	ids, pages = collectHosts(PageOptions{Limit: 5})
+	assert.Equal(t, hostIDs, ids)
+	assert.Equal(t, 1, pages, "an exactly full page has no next cursor")
+
+	reversed := make([]string, len(hostIDs))
+	for i, id := range hostIDs {
+		reversed[len(hostIDs)-1-i] = id
+	}
+	ids, _ = collectHosts(PageOptions{Limit: 2, Order: SortDescending})
+	assert.Equal(t, reversed, ids)
+
+	sorted := append([]string(nil), hostIDs...)
+	sort.Strings(sorted)
+	ids, _ = collectHosts(PageOptions{Limit: 3, OrderBy: "id"})
+	assert.Equal(t, sorted, ids)
+
+	for i, id := range hostIDs {
+		createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(-time.Duration(i) * time.Minute)
+		_, err := store.DB().ExecContext(ctx, `UPDATE hosts SET created_at = ? WHERE id = ?`, createdAt.Format(time.DateTime), id)
+		require.NoError(t, err)
+	}
+	ids, _ = collectHosts(PageOptions{Limit: 2})
+	assert.Equal(t, reversed, ids, "cursors compare against the stored timestamps")
+
+	all, err := store.ListHosts(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 5, "unpaged lists return every row")
+
+	var requestIDs []string
+	for i := range 4 {
+		labels := map[string]string{"team": "a"}
+		if i%2 == 1 {
+			labels["team"] = "b"
+		}
+		req, err := store.CreateRequest(ctx, Request{HostID: hostIDs[0], Labels: labels})
+		require.NoError(t, err)
+		requestIDs = append(requestIDs, req.ID)
+	}
+	filters := &RequestListFilters{Labels: map[string]string{"team": "a"}}
+	first, next, err := store.ListRequestsPage(ctx, filters, PageOptions{Limit: 1, OrderBy: "updated_at"})
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	assert.Equal(t, requestIDs[0], first[0].ID)
+	assert.Equal(t, map[string]string{"team": "a"}, first[0].Labels)
+	require.NotEmpty(t, next)
+	second, next, err := store.ListRequestsPage(ctx, filters, PageOptions{Limit: 1, OrderBy: "updated_at", Cursor: next})
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	assert.Equal(t, requestIDs[2], second[0].ID, "filters apply across pages")
+	assert.Empty(t, next)
+
+	_, cursor, err := store.ListRequestsPage(ctx, nil, PageOptions{Limit: 1})
+	require.NoError(t, err)
+	for name, opts := range map[string]PageOptions{
+		"unknown column":  {OrderBy: "payload"},
+		"unknown order":   {Order: "sideways"},
+		"negative limit":  {Limit: -1},
+		"limit too large": {Limit: MaxPageLimit + 1},
+		"garbage cursor":  {Cursor: "not a cursor"},
+		"cursor mismatch": {Cursor: cursor, Order: SortDescending},
+	} {
+		_, _, err := store.ListRequestsPage(ctx, nil, opts)
+		assert.ErrorIs(t, err, ErrInvalidPage, name)
+	}
+	_, _, err = store.ListHostsPage(ctx, nil, PageOptions{OrderBy: "updated_at"})
+	assert.ErrorIs(t, err, ErrInvalidPage, "hosts have no updated_at column")
+}
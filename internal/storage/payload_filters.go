@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// payloadPathPattern accepts dotted member names with optional array indexes,
+// such as name, spec.size or items[0].id.
+var payloadPathPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\[[0-9]+\])*(\.[A-Za-z0-9_-]+(\[[0-9]+\])*)*$`)
+
+// PayloadFilter matches records whose payload holds Value at Path. Path is a
+// JSONPath such as $.spec.size. Values compare as text: numbers in their JSON
+// form, booleans as true or false and JSON null as null.
+type PayloadFilter struct {
+	Path  string
+	Value string
+}
+
+// ParsePayloadFilter reads a filter in the form <path>=<value>. The path may
+// be given as a JSONPath ($.spec.size) or without the leading $ (spec.size).
+func ParsePayloadFilter(raw string) (PayloadFilter, error) {
+	path, value, found := strings.Cut(raw, "=")
+	if !found {
+		return PayloadFilter{}, fmt.Errorf("invalid payload filter %q: expected <path>=<value>", raw)
+	}
+	normalized, err := normalizePayloadPath(path)
+	if err != nil {
+		return PayloadFilter{}, fmt.Errorf("invalid payload filter %q: %w", raw, err)
+	}
+	return PayloadFilter{Path: normalized, Value: value}, nil
+}
+
+func normalizePayloadPath(path string) (string, error) {
+	trimmed := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(path), "$"), ".")
+	if !payloadPathPattern.MatchString(trimmed) {
+		return "", fmt.Errorf("unsupported path %q", path)
+	}
+	return "$." + trimmed, nil
+}
+
+// String renders the filter in the form ParsePayloadFilter accepts.
+func (f PayloadFilter) String() string {
+	return f.Path + "=" + f.Value
+}
+
+// payloadFilterConditions compiles payload filters against a JSON column to
+// conditions for a WHERE clause.
+func payloadFilterConditions(d dialect, column string, filters []PayloadFilter) ([]string, []any, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	for _, filter := range filters {
+		path, err := normalizePayloadPath(filter.Path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid payload filter %q: %w", filter.String(), err)
+		}
+		value, pathArgs := d.payloadValue(column, path)
+		conditions = append(conditions, value+" = ?")
+		args = append(args, pathArgs...)
+		args = append(args, filter.Value)
+	}
+	return conditions, args, nil
+}
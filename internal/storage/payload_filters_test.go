@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePayloadFilter(t *testing.T) {
+	t.Parallel()
+
+	for raw, expected := range map[string]PayloadFilter{
+		"name=foo":             {Path: "$.name", Value: "foo"},
+		"$.spec.size=large":    {Path: "$.spec.size", Value: "large"},
+		"items[0].id=a=b":      {Path: "$.items[0].id", Value: "a=b"},
+		".port=":               {Path: "$.port", Value: ""},
+		"nested-key.x_y[2]=42": {Path: "$.nested-key.x_y[2]", Value: "42"},
+	} {
+		filter, err := ParsePayloadFilter(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, expected, filter, raw)
+	}
+
+	for _, raw := range []string{"name", "=foo", "$=foo", "a..b=1", "a[x]=1", "a b=1", "$['name']=foo"} {
+		_, err := ParsePayloadFilter(raw)
+		assert.Error(t, err, "filter %q should be rejected", raw)
+	}
+}
+
+func TestListWithPayloadFilters(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	host, err := store.CreateHost(ctx, Host{})
+	require.NoError(t, err)
+
+	small, err := store.CreateRequest(ctx, Request{HostID: host.ID, Payload: map[string]any{
+		"name": "db",
+		"spec": map[string]any{"size": "small", "replicas": 1, "public": false},
+	}})
+	require.NoError(t, err)
+	large, err := store.CreateRequest(ctx, Request{HostID: host.ID, Payload: map[string]any{
+		"name":  "db",
+		"spec":  map[string]any{"size": "large", "replicas": 3, "public": true},
+		"ports": []any{5432, 6432},
+	}})
+	require.NoError(t, err)
+	_, err = store.CreateRequest(ctx, Request{HostID: host.ID})
+	require.NoError(t, err, "requests without payload never match")
+
+	filter := func(raw ...string) []PayloadFilter {
+		filters := make([]PayloadFilter, 0, len(raw))
+		for _, value := range raw {
+			parsed, err := ParsePayloadFilter(value)
+			require.NoError(t, err)
+			filters = append(filters, parsed)
+		}
+		return filters
+	}
+	for name, tc := range map[string]struct {
+		filters  []PayloadFilter
+		expected []string
+	}{
+		"string":        {filter("name=db"), []string{small.ID, large.ID}},
+		"nested string": {filter("spec.size=large"), []string{large.ID}},
+		"integer":       {filter("$.spec.replicas=3"), []string{large.ID}},
+		"boolean":       {filter("spec.public=false"), []string{small.ID}},
+		"array index":   {filter("ports[1]=6432"), []string{large.ID}},
+		"all must hold": {filter("name=db", "spec.size=medium"), []string{}},
+		"missing path":  {filter("owner=alice"), []string{}},
+	} {
+		requests, err := store.ListRequests(ctx, &RequestListFilters{Payload: tc.filters})
+		require.NoError(t, err, name)
+		ids := make([]string, 0, len(requests))
+		for _, req := range requests {
+			ids = append(ids, req.ID)
+		}
+		assert.Equal(t, tc.expected, ids, name)
+	}
+
+	reg, err := store.CreateRegister(ctx, Register{HostID: host.ID, Payload: map[string]any{"endpoint": map[string]any{"port": 443}}})
+	require.NoError(t, err)
+	_, err = store.CreateRegister(ctx, Register{HostID: host.ID, Payload: map[string]any{"endpoint": map[string]any{"port": 80}}})
+	require.NoError(t, err)
+	registers, err := store.ListRegisters(ctx, &RegisterListFilters{Payload: filter("endpoint.port=443")})
+	require.NoError(t, err)
+	require.Len(t, registers, 1)
+	assert.Equal(t, reg.ID, registers[0].ID)
+
+	_, err = store.ListRegisters(ctx, &RegisterListFilters{Payload: []PayloadFilter{{Path: "a..b", Value: "x"}}})
+	assert.Error(t, err, "unparsed filters are validated too")
+}
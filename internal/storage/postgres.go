@@ -0,0 +1,304 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/sirupsen/logrus"
+)
+
+// A PostgreSQL database holds every namespace in a schema of its own, named
+// after the namespace, and the API tokens in a shared schema. Several servers
+// may use the same database at once.
+const (
+	// PostgresNamespaceSchemaPrefix starts the schema name of every namespace.
+	PostgresNamespaceSchemaPrefix = "grantory_ns_"
+	// PostgresTokenSchema is the schema that holds the API tokens.
+	PostgresTokenSchema = "grantory_tokens"
+
+	// postgresMaxIdentifierLength is the length PostgreSQL truncates
+	// identifiers to; longer schema names would silently collide.
+	postgresMaxIdentifierLength = 63
+	postgresMaxOpenConns        = 8
+)
+
+// ErrBackupUnsupported is returned when backing up or restoring a namespace
+// on a storage backend without file snapshots. Use the database's own backup
+// tooling, or export the namespace, instead.
+var ErrBackupUnsupported = errors.New("backup is not supported by this storage backend")
+
+// IsPostgresURL reports whether storageURL selects the PostgreSQL backend.
+func IsPostgresURL(storageURL string) bool {
+	return strings.HasPrefix(storageURL, "postgres://") || strings.HasPrefix(storageURL, "postgresql://")
+}
+
+// PostgresNamespaceSchema returns the schema that holds namespace.
+func PostgresNamespaceSchema(namespace string) (string, error) {
+	schema := PostgresNamespaceSchemaPrefix + namespace
+	if len(schema) > postgresMaxIdentifierLength {
+		return "", fmt.Errorf("namespace %q is too long for postgres: schema names are limited to %d bytes", namespace, postgresMaxIdentifierLength)
+	}
+	return schema, nil
+}
+
+// OpenPostgres opens the schema of namespace in the PostgreSQL database at
+// storageURL, creating the schema if needed.
+func OpenPostgres(ctx context.Context, storageURL, namespace string) (*Store, error) {
+	schema, err := PostgresNamespaceSchema(namespace)
+	if err != nil {
+		return nil, err
+	}
+	db, err := openPostgres(ctx, storageURL, schema)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db, namespace: unknownNamespace, dialect: dialectPostgres}, nil
+}
+
+// NewPostgresTokenStore opens the API tokens in the PostgreSQL database at
+// storageURL and ensures their schema.
+func NewPostgresTokenStore(ctx context.Context, storageURL string) (*TokenStore, error) {
+	db, err := openPostgres(ctx, storageURL, PostgresTokenSchema)
+	if err != nil {
+		return nil, err
+	}
+	return newTokenStore(ctx, db, dialectPostgres)
+}
+
+// PostgresNamespaceExists reports whether namespace has a schema.
+func PostgresNamespaceExists(ctx context.Context, storageURL, namespace string) (bool, error) {
+	schema, err := PostgresNamespaceSchema(namespace)
+	if err != nil {
+		return false, err
+	}
+	exists := false
+	err = withPostgres(ctx, storageURL, func(db *sql.DB) error {
+		return db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = ?)`, schema).Scan(&exists)
+	})
+	if err != nil {
+		return false, fmt.Errorf("inspect namespace schema: %w", err)
+	}
+	return exists, nil
+}
+
+// ListPostgresNamespaces returns the namespaces that have a schema, sorted
+// by name.
+func ListPostgresNamespaces(ctx context.Context, storageURL string) ([]string, error) {
+	var namespaces []string
+	err := withPostgres(ctx, storageURL, func(db *sql.DB) error {
+		rows, err := db.QueryContext(ctx, `
+SELECT nspname FROM pg_namespace
+WHERE starts_with(nspname, ?)
+ORDER BY nspname COLLATE "C"
+`, PostgresNamespaceSchemaPrefix)
+		if err != nil {
+			return err
+		}
+		defer closeRows(rows, "close namespace schema rows")
+		for rows.Next() {
+			var schema string
+			if err := rows.Scan(&schema); err != nil {
+				return err
+			}
+			namespaces = append(namespaces, strings.TrimPrefix(schema, PostgresNamespaceSchemaPrefix))
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list namespace schemas: %w", err)
+	}
+	return namespaces, nil
+}
+
+// PostgresNamespaceSize returns the combined size in bytes of the tables and
+// indexes in the schema of namespace.
+func PostgresNamespaceSize(ctx context.Context, storageURL, namespace string) (int64, error) {
+	schema, err := PostgresNamespaceSchema(namespace)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	err = withPostgres(ctx, storageURL, func(db *sql.DB) error {
+		return db.QueryRowContext(ctx, `
+SELECT COALESCE(SUM(pg_total_relation_size(c.oid)), 0)::bigint
+FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = ? AND c.relkind IN ('r', 'p')
+`, schema).Scan(&size)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("measure namespace schema: %w", err)
+	}
+	return size, nil
+}
+
+// DropPostgresNamespace removes the schema of namespace with all its data.
+func DropPostgresNamespace(ctx context.Context, storageURL, namespace string) error {
+	schema, err := PostgresNamespaceSchema(namespace)
+	if err != nil {
+		return err
+	}
+	err = withPostgres(ctx, storageURL, func(db *sql.DB) error {
+		_, err := db.ExecContext(ctx, `DROP SCHEMA IF EXISTS `+quoteIdentifier(schema)+` CASCADE`)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("drop namespace schema: %w", err)
+	}
+	return nil
+}
+
+// withPostgres runs fn on a short-lived connection to the database at
+// storageURL, for work that does not belong to a namespace.
+func withPostgres(ctx context.Context, storageURL string, fn func(*sql.DB) error) error {
+	config, err := pgx.ParseConfig(storageURL)
+	if err != nil {
+		return fmt.Errorf("parse storage url: %w", err)
+	}
+	db := sql.OpenDB(rebindConnector{stdlib.GetConnector(*config)})
+	db.SetMaxOpenConns(1)
+	fnErr := fn(db)
+	if err := db.Close(); err != nil && fnErr == nil {
+		return fmt.Errorf("close postgres database: %w", err)
+	}
+	return fnErr
+}
+
+// openPostgres connects to the database at storageURL with schema as the
+// search path, so the unqualified table names of the store resolve to it.
+// The schema is created if it does not exist yet.
+func openPostgres(ctx context.Context, storageURL, schema string) (*sql.DB, error) {
+	config, err := pgx.ParseConfig(storageURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse storage url: %w", err)
+	}
+	config.RuntimeParams["search_path"] = quoteIdentifier(schema)
+	// Timestamps are stored as text in UTC, like on SQLite; the session time
+	// zone only matters for the defaults computed by the database.
+	config.RuntimeParams["timezone"] = "UTC"
+
+	db := sql.OpenDB(rebindConnector{stdlib.GetConnector(*config)})
+	db.SetMaxOpenConns(postgresMaxOpenConns)
+	db.SetMaxIdleConns(2)
+
+	if _, err := db.ExecContext(ctx, `CREATE SCHEMA IF NOT EXISTS `+quoteIdentifier(schema)); err != nil && !isUniqueConstraintError(err) {
+		// Servers starting at the same time may race to create the schema; the
+		// loser sees a unique violation and can use the winner's schema.
+		if cerr := db.Close(); cerr != nil {
+			logrus.WithError(cerr).Warn("close postgres database after schema setup failure")
+		}
+		return nil, fmt.Errorf("create postgres schema: %w", err)
+	}
+	return db, nil
+}
+
+// lockPostgresMigrations serializes migrations of one schema across servers
+// with a session advisory lock. The returned func releases the lock.
+func lockPostgresMigrations(ctx context.Context, db *sql.DB) (func(), error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire migration connection: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(hashtext(current_schema()))`); err != nil {
+		if cerr := conn.Close(); cerr != nil {
+			logrus.WithError(cerr).Warn("close migration connection")
+		}
+		return nil, fmt.Errorf("lock migrations: %w", err)
+	}
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext(current_schema()))`); err != nil {
+			logrus.WithError(err).Warn("unlock migrations")
+		}
+		if err := conn.Close(); err != nil {
+			logrus.WithError(err).Warn("close migration connection")
+		}
+	}, nil
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// isPostgresError reports whether err is a PostgreSQL error with the given
+// SQLSTATE code.
+func isPostgresError(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+// rebindConnector hands out connections that accept the ? placeholders used
+// throughout the store and pass them to PostgreSQL as $1, $2, ...
+type rebindConnector struct {
+	driver.Connector
+}
+
+func (c rebindConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pgConn, ok := conn.(*stdlib.Conn)
+	if !ok {
+		return nil, fmt.Errorf("unexpected postgres connection type %T", conn)
+	}
+	return rebindConn{pgConn}, nil
+}
+
+type rebindConn struct {
+	*stdlib.Conn
+}
+
+func (c rebindConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(rebindPlaceholders(query))
+}
+
+func (c rebindConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Conn.PrepareContext(ctx, rebindPlaceholders(query))
+}
+
+func (c rebindConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.Conn.ExecContext(ctx, rebindPlaceholders(query), args)
+}
+
+func (c rebindConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.Conn.QueryContext(ctx, rebindPlaceholders(query), args)
+}
+
+// rebindPlaceholders numbers the ? placeholders of query. Question marks in
+// string literals and quoted identifiers are left alone.
+func rebindPlaceholders(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+	var (
+		out   strings.Builder
+		quote byte
+		n     int
+	)
+	out.Grow(len(query) + 8)
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '?':
+			n++
+			out.WriteByte('$')
+			out.WriteString(strconv.Itoa(n))
+			continue
+		}
+		out.WriteByte(ch)
+	}
+	return out.String()
+}
@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// pgUniqueViolation is the SQLSTATE of a unique constraint violation.
+const pgUniqueViolation = "23505"
+
+// postgresNow is the default of timestamp columns. Timestamps are stored as
+// text in the same format as on SQLite, so they compare and sort alike; the
+// C collation keeps text ordering byte-wise like SQLite's.
+const postgresNow = `to_char(clock_timestamp() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS.MS')`
+
+// postgresMigrations keeps the versions and names of the SQLite migrations,
+// so both backends report the same schema versions. PostgreSQL support was
+// added at version 10: the first migration creates that schema in full and
+// the following ones have nothing left to do. Later migrations run their
+// PostgreSQL implementation from postgresUpgrades.
+func postgresMigrations(list []migration) []migration {
+	migrations := make([]migration, len(list))
+	for i, m := range list {
+		migrations[i] = migration{version: m.version, name: m.name, up: noopMigration}
+		if up, ok := postgresUpgrades[m.version]; ok {
+			migrations[i].up = up
+		}
+	}
+	migrations[0].up = createPostgresSchema
+	return migrations
+}
+
+// postgresUpgrades holds the PostgreSQL implementation of every migration
+// after version 10, by version.
+var postgresUpgrades = map[int]func(context.Context, *sql.Tx) error{
+	11: func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE webhooks ADD COLUMN include_payload INTEGER NOT NULL DEFAULT 0`); err != nil {
+			return fmt.Errorf("add webhooks include_payload column: %w", err)
+		}
+		return nil
+	},
+}
+
+func noopMigration(context.Context, *sql.Tx) error {
+	return nil
+}
+
+// postgresSchemaStatements create the namespace schema as of version 10.
+// Tables that are paginated carry a rowid column, which breaks ties between
+// rows sharing a sort value like SQLite's implicit rowid does.
+var postgresSchemaStatements = []string{
+	`CREATE TABLE hosts (
+	id TEXT COLLATE "C" PRIMARY KEY,
+	created_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `,
+	last_seen_at TEXT COLLATE "C",
+	rowid BIGINT GENERATED ALWAYS AS IDENTITY UNIQUE
+)`,
+	`CREATE INDEX hosts_last_seen_at ON hosts (last_seen_at) WHERE last_seen_at IS NOT NULL`,
+	`CREATE TABLE requests (
+	id TEXT COLLATE "C" PRIMARY KEY,
+	host_id TEXT COLLATE "C" NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+	data TEXT,
+	status TEXT NOT NULL DEFAULT 'pending',
+	status_reason TEXT,
+	revision INTEGER NOT NULL DEFAULT 1,
+	expires_at TEXT COLLATE "C",
+	created_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `,
+	updated_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `,
+	rowid BIGINT GENERATED ALWAYS AS IDENTITY UNIQUE
+)`,
+	`CREATE INDEX requests_host_id ON requests (host_id)`,
+	`CREATE INDEX requests_expires_at ON requests (expires_at) WHERE expires_at IS NOT NULL`,
+	`CREATE TABLE registers (
+	id TEXT COLLATE "C" PRIMARY KEY,
+	host_id TEXT COLLATE "C" NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+	data TEXT,
+	expires_at TEXT COLLATE "C",
+	created_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `,
+	updated_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `,
+	rowid BIGINT GENERATED ALWAYS AS IDENTITY UNIQUE
+)`,
+	`CREATE INDEX registers_host_id ON registers (host_id)`,
+	`CREATE INDEX registers_expires_at ON registers (expires_at) WHERE expires_at IS NOT NULL`,
+	`CREATE TABLE grants (
+	id TEXT COLLATE "C" PRIMARY KEY,
+	request_id TEXT COLLATE "C" NOT NULL UNIQUE REFERENCES requests(id) ON DELETE CASCADE,
+	payload TEXT,
+	request_revision INTEGER NOT NULL DEFAULT 1,
+	expires_at TEXT COLLATE "C",
+	created_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `,
+	updated_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `,
+	rowid BIGINT GENERATED ALWAYS AS IDENTITY UNIQUE
+)`,
+	`CREATE INDEX grants_expires_at ON grants (expires_at) WHERE expires_at IS NOT NULL`,
+	`CREATE TABLE host_labels (
+	host_id TEXT COLLATE "C" NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+	key TEXT COLLATE "C" NOT NULL CHECK(length(key) <= 256),
+	value TEXT NOT NULL CHECK(length(value) <= 256),
+	PRIMARY KEY (host_id, key)
+)`,
+	`CREATE TABLE request_labels (
+	request_id TEXT COLLATE "C" NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+	key TEXT COLLATE "C" NOT NULL CHECK(length(key) <= 256),
+	value TEXT NOT NULL CHECK(length(value) <= 256),
+	PRIMARY KEY (request_id, key)
+)`,
+	`CREATE TABLE register_labels (
+	register_id TEXT COLLATE "C" NOT NULL REFERENCES registers(id) ON DELETE CASCADE,
+	key TEXT COLLATE "C" NOT NULL CHECK(length(key) <= 256),
+	value TEXT NOT NULL CHECK(length(value) <= 256),
+	PRIMARY KEY (register_id, key)
+)`,
+	`CREATE TABLE grant_labels (
+	grant_id TEXT COLLATE "C" NOT NULL REFERENCES grants(id) ON DELETE CASCADE,
+	key TEXT COLLATE "C" NOT NULL CHECK(length(key) <= 256),
+	value TEXT NOT NULL CHECK(length(value) <= 256),
+	PRIMARY KEY (grant_id, key)
+)`,
+	`CREATE TABLE events (
+	seq BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	resource_type TEXT NOT NULL,
+	resource_id TEXT NOT NULL,
+	action TEXT NOT NULL,
+	labels TEXT,
+	created_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `
+)`,
+	`CREATE TABLE webhooks (
+	id TEXT COLLATE "C" PRIMARY KEY,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	resource_types TEXT,
+	label_selector TEXT,
+	cursor BIGINT NOT NULL DEFAULT 0,
+	created_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `,
+	updated_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `
+)`,
+	`CREATE TABLE webhook_deliveries (
+	id TEXT COLLATE "C" PRIMARY KEY,
+	webhook_id TEXT COLLATE "C" NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+	event_seq BIGINT NOT NULL,
+	event TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'succeeded', 'failed')),
+	attempts INTEGER NOT NULL DEFAULT 0,
+	response_status INTEGER,
+	last_error TEXT,
+	next_attempt_at TEXT COLLATE "C",
+	delivered_at TEXT COLLATE "C",
+	created_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `,
+	updated_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `,
+	UNIQUE(webhook_id, event_seq)
+)`,
+	`CREATE INDEX webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at)`,
+	`CREATE TABLE audit_events (
+	id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	actor TEXT NOT NULL,
+	action TEXT NOT NULL,
+	resource_type TEXT NOT NULL,
+	resource_id TEXT NOT NULL,
+	state_before TEXT,
+	state_after TEXT,
+	created_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `
+)`,
+	`CREATE INDEX audit_events_created_at ON audit_events (created_at)`,
+	`CREATE INDEX audit_events_resource ON audit_events (resource_type, resource_id)`,
+	`CREATE FUNCTION audit_events_append_only() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+	RAISE EXCEPTION 'audit events are append-only';
+END
+$$`,
+	`CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+FOR EACH ROW EXECUTE FUNCTION audit_events_append_only()`,
+	`CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON audit_events
+FOR EACH ROW EXECUTE FUNCTION audit_events_append_only()`,
+	`CREATE TABLE request_revisions (
+	request_id TEXT COLLATE "C" NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+	revision INTEGER NOT NULL,
+	data TEXT,
+	actor TEXT NOT NULL,
+	created_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `,
+	PRIMARY KEY (request_id, revision)
+)`,
+	`CREATE TABLE request_types (
+	name TEXT COLLATE "C" PRIMARY KEY,
+	request_schema TEXT,
+	grant_schema TEXT,
+	created_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `,
+	updated_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `
+)`,
+}
+
+func createPostgresSchema(ctx context.Context, tx *sql.Tx) error {
+	for _, statement := range postgresSchemaStatements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("create postgres schema: %w", err)
+		}
+	}
+	return nil
+}
+
+// postgresTokenMigrations mirrors tokenMigrations.
+var postgresTokenMigrations = []migration{
+	{1, "api tokens", func(ctx context.Context, tx *sql.Tx) error {
+		for _, stmt := range []string{
+			`CREATE TABLE api_tokens (
+	id TEXT COLLATE "C" PRIMARY KEY,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	created_at TEXT COLLATE "C" NOT NULL DEFAULT ` + postgresNow + `,
+	revoked_at TEXT COLLATE "C"
+)`,
+			`CREATE TABLE api_token_scopes (
+	token_id TEXT COLLATE "C" NOT NULL REFERENCES api_tokens(id) ON DELETE CASCADE,
+	namespace TEXT COLLATE "C" NOT NULL,
+	scope TEXT NOT NULL CHECK(scope IN ('read', 'write')),
+	PRIMARY KEY (token_id, namespace)
+)`,
+		} {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebindPlaceholders(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `SELECT 1`, rebindPlaceholders(`SELECT 1`))
+	assert.Equal(t,
+		`UPDATE hosts SET last_seen_at = $1 WHERE id = $2`,
+		rebindPlaceholders(`UPDATE hosts SET last_seen_at = ? WHERE id = ?`))
+	assert.Equal(t,
+		`SELECT '?', "a?b" FROM t WHERE x = $1 AND y = 'it''s?' AND z = $2`,
+		rebindPlaceholders(`SELECT '?', "a?b" FROM t WHERE x = ? AND y = 'it''s?' AND z = ?`),
+		"quoted question marks are not placeholders")
+}
+
+func TestPostgresJSONPath(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "{team}", postgresJSONPath("$.team"))
+	assert.Equal(t, "{items,0,id}", postgresJSONPath("$.items[0].id"))
+}
+
+func TestPostgresNamespaceSchema(t *testing.T) {
+	t.Parallel()
+
+	schema, err := PostgresNamespaceSchema("team-a")
+	require.NoError(t, err)
+	assert.Equal(t, "grantory_ns_team-a", schema)
+
+	_, err = PostgresNamespaceSchema(strings.Repeat("a", postgresMaxIdentifierLength))
+	assert.Error(t, err, "schema names longer than postgres identifiers are rejected")
+}
+
+func TestIsPostgresURL(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsPostgresURL("postgres://localhost/grantory"))
+	assert.True(t, IsPostgresURL("postgresql://localhost/grantory"))
+	assert.False(t, IsPostgresURL("data/default.db"))
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// requestStaleGrantCondition matches requests whose grant was issued for an
+// older revision of the request payload.
+const requestStaleGrantCondition = "EXISTS (SELECT 1 FROM grants WHERE grants.request_id = requests.id AND grants.request_revision < requests.revision)"
+
+const requestRevisionsTableStatement = `
+CREATE TABLE IF NOT EXISTS request_revisions (
+	request_id TEXT NOT NULL,
+	revision INTEGER NOT NULL,
+	data TEXT,
+	actor TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+	PRIMARY KEY (request_id, revision),
+	FOREIGN KEY(request_id) REFERENCES requests(id) ON DELETE CASCADE
+)`
+
+// RequestRevision is one stored version of a request payload. Revisions start
+// at 1 and grow by one with every payload change.
+type RequestRevision struct {
+	RequestID string         `json:"request_id"`
+	Revision  int            `json:"revision"`
+	Payload   map[string]any `json:"payload,omitempty"`
+	Actor     string         `json:"actor"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// UpdateRequestPayload replaces the payload of a request and stores it as a
+// new revision. Writing the current payload again is a no-op. A grant issued
+// for an earlier revision is kept and reported as stale.
+func (s *Store) UpdateRequestPayload(ctx context.Context, id string, payload map[string]any) error {
+	return s.UpdateRequest(ctx, id, RequestUpdate{Payload: &payload})
+}
+
+// ListRequestRevisions returns the payload history of a request, oldest first.
+func (s *Store) ListRequestRevisions(ctx context.Context, id string) ([]RequestRevision, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+
+	defer s.logDBOperation(ctx, "request_revisions", "list", logrus.Fields{
+		"request_id": id,
+	})()
+
+	if _, err := s.GetRequest(ctx, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT request_id, revision, data, actor, created_at
+FROM request_revisions
+WHERE request_id = ?
+ORDER BY revision ASC
+`, id)
+	if err != nil {
+		return nil, fmt.Errorf("query request revisions: %w", err)
+	}
+	defer closeRows(rows, "close request revision rows")
+
+	revisions := make([]RequestRevision, 0)
+	for rows.Next() {
+		var (
+			revision  RequestRevision
+			data      sql.NullString
+			createdAt string
+		)
+		if err := rows.Scan(&revision.RequestID, &revision.Revision, &data, &revision.Actor, &createdAt); err != nil {
+			return nil, fmt.Errorf("scan request revision: %w", err)
+		}
+		if revision.Payload, err = decodeAnyMap(data); err != nil {
+			return nil, fmt.Errorf("decode request revision payload: %w", err)
+		}
+		if revision.CreatedAt, err = parseCreatedAt(createdAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan request revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// writeRequestPayload stores an encoded payload as the given revision of a
+// request.
+func writeRequestPayload(ctx context.Context, tx *sql.Tx, id string, revision int, payloadValue any) error {
+	if _, err := tx.ExecContext(ctx, `UPDATE requests SET data = ?, revision = ? WHERE id = ?`, payloadValue, revision, id); err != nil {
+		return fmt.Errorf("update request payload: %w", err)
+	}
+	return insertRequestRevision(ctx, tx, id, revision, payloadValue)
+}
+
+func insertRequestRevision(ctx context.Context, tx *sql.Tx, id string, revision int, payloadValue any) error {
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO request_revisions (request_id, revision, data, actor)
+VALUES (?, ?, ?, ?)
+`, id, revision, payloadValue, AuditActor(ctx)); err != nil {
+		return fmt.Errorf("insert request revision: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) ensureRequestRevisionColumns(ctx context.Context, tx *sql.Tx) error {
+	for _, column := range []struct {
+		table string
+		name  string
+	}{
+		{"requests", "revision"},
+		{"grants", "request_revision"},
+	} {
+		columns, err := tableColumns(ctx, tx, column.table)
+		if err != nil {
+			return err
+		}
+		if columns[column.name] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s INTEGER NOT NULL DEFAULT 1`, column.table, column.name)); err != nil {
+			return fmt.Errorf("add %s %s column: %w", column.table, column.name, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, requestRevisionsTableStatement); err != nil {
+		return fmt.Errorf("create request revisions table: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+INSERT OR IGNORE INTO request_revisions (request_id, revision, data, actor, created_at)
+SELECT id, revision, data, ?, created_at FROM requests
+`, AuditActorSystem); err != nil {
+		return fmt.Errorf("backfill request revisions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestPayloadRevisions(t *testing.T) {
+	t.Parallel()
+
+	ctx := WithAuditActor(context.Background(), "alice")
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	host, err := store.CreateHost(ctx, Host{})
+	require.NoError(t, err)
+	req, err := store.CreateRequest(ctx, Request{HostID: host.ID, Payload: map[string]any{"size": "small"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, req.Revision, "new requests start at revision 1")
+
+	grant, err := store.CreateGrant(ctx, Grant{RequestID: req.ID, Payload: []byte(`{"ok":true}`)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, grant.RequestRevision, "grants remember the revision they were issued for")
+
+	require.NoError(t, store.UpdateRequestPayload(ctx, req.ID, map[string]any{"size": "small"}), "unchanged payload")
+	loaded, err := store.GetRequest(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, loaded.Revision, "writing the same payload keeps the revision")
+	assert.False(t, loaded.StaleGrant)
+
+	require.NoError(t, store.UpdateRequestPayload(WithAuditActor(ctx, "bob"), req.ID, map[string]any{"size": "large"}))
+	loaded, err = store.GetRequest(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, loaded.Revision)
+	assert.Equal(t, map[string]any{"size": "large"}, loaded.Payload)
+	assert.Equal(t, RequestStatusApproved, loaded.Status, "payload changes keep the status")
+	assert.True(t, loaded.HasGrant, "the grant survives payload changes")
+	assert.True(t, loaded.StaleGrant, "the grant was issued for an older revision")
+
+	stale := true
+	listed, err := store.ListRequests(ctx, &RequestListFilters{StaleGrant: &stale})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, req.ID, listed[0].ID)
+
+	revisions, err := store.ListRequestRevisions(ctx, req.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 1, revisions[0].Revision)
+	assert.Equal(t, "alice", revisions[0].Actor)
+	assert.Equal(t, map[string]any{"size": "small"}, revisions[0].Payload)
+	assert.Equal(t, 2, revisions[1].Revision)
+	assert.Equal(t, "bob", revisions[1].Actor)
+
+	require.NoError(t, store.DeleteGrant(ctx, grant.ID))
+	regrant, err := store.CreateGrant(ctx, Grant{RequestID: req.ID, Payload: []byte(`{"ok":true}`)})
+	require.NoError(t, err)
+	assert.Equal(t, 2, regrant.RequestRevision)
+	loaded, err = store.GetRequest(ctx, req.ID)
+	require.NoError(t, err)
+	assert.False(t, loaded.StaleGrant, "a new grant covers the current revision")
+
+	_, err = store.PutRequestType(ctx, RequestType{Name: "database", RequestSchema: json.RawMessage(`{"type": "object", "required": ["name"]}`)})
+	require.NoError(t, err)
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	payload := map[string]any{"size": "huge"}
+	labels := map[string]string{RequestTypeLabel: "database"}
+	err = store.UpdateRequest(ctx, req.ID, RequestUpdate{Payload: &payload, Labels: &labels, SetExpiry: true, ExpiresAt: &expiresAt})
+	require.ErrorIs(t, err, ErrSchemaValidation)
+	loaded, err = store.GetRequest(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, loaded.Revision, "a failed update keeps the revision")
+	assert.Equal(t, map[string]any{"size": "large"}, loaded.Payload)
+	assert.Empty(t, loaded.Labels)
+	assert.Nil(t, loaded.ExpiresAt)
+	assert.False(t, loaded.StaleGrant)
+	revisions, err = store.ListRequestRevisions(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Len(t, revisions, 2)
+
+	payload = map[string]any{"name": "db", "size": "huge"}
+	require.NoError(t, store.UpdateRequest(ctx, req.ID, RequestUpdate{Payload: &payload, Labels: &labels, SetExpiry: true, ExpiresAt: &expiresAt}))
+	loaded, err = store.GetRequest(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, loaded.Revision)
+	assert.Equal(t, labels, loaded.Labels)
+	require.NotNil(t, loaded.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*loaded.ExpiresAt))
+
+	assert.ErrorIs(t, store.UpdateRequestPayload(ctx, "missing", nil), ErrRequestNotFound)
+	_, err = store.ListRequestRevisions(ctx, "missing")
+	assert.ErrorIs(t, err, ErrRequestNotFound)
+}
@@ -0,0 +1,309 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RequestStatus describes where a request is in its grant lifecycle.
+type RequestStatus string
+
+const (
+	// RequestStatusPending marks a request that still waits for a grantor decision.
+	RequestStatusPending RequestStatus = "pending"
+	// RequestStatusApproved marks a request that holds an active grant.
+	RequestStatusApproved RequestStatus = "approved"
+	// RequestStatusDenied marks a request that a grantor explicitly refused.
+	RequestStatusDenied RequestStatus = "denied"
+	// RequestStatusRevoked marks a request whose grant was withdrawn but kept for
+	// reference until the request is reopened.
+	RequestStatusRevoked RequestStatus = "revoked"
+	// RequestStatusExpired marks a request or grant that ran past its lifetime.
+	RequestStatusExpired RequestStatus = "expired"
+)
+
+var (
+	// ErrInvalidRequestStatus is returned when a status value is not recognized.
+	ErrInvalidRequestStatus = errors.New("invalid request status")
+	// ErrInvalidStatusTransition is returned when a request cannot move to the requested status.
+	ErrInvalidStatusTransition = errors.New("invalid request status transition")
+)
+
+// requestHasGrantCondition matches requests that hold an active grant.
+const requestHasGrantCondition = "(requests.status = 'approved' AND EXISTS (SELECT 1 FROM grants WHERE grants.request_id = requests.id))"
+
+// manualTransitions lists the status changes callers may request explicitly.
+// Approval is never set directly; it follows from creating a grant.
+var manualTransitions = map[RequestStatus][]RequestStatus{
+	RequestStatusPending:  {RequestStatusDenied, RequestStatusExpired},
+	RequestStatusApproved: {RequestStatusRevoked, RequestStatusExpired},
+	RequestStatusDenied:   {RequestStatusPending},
+	RequestStatusRevoked:  {RequestStatusPending},
+	RequestStatusExpired:  {RequestStatusPending},
+}
+
+// grantTransitions lists the status changes caused by creating a grant.
+var grantTransitions = map[RequestStatus][]RequestStatus{
+	RequestStatusPending: {RequestStatusApproved},
+	RequestStatusDenied:  {RequestStatusApproved},
+}
+
+// grantRemovalTransitions lists the status changes caused by deleting a grant.
+var grantRemovalTransitions = map[RequestStatus][]RequestStatus{
+	RequestStatusApproved: {RequestStatusPending},
+	RequestStatusDenied:   {RequestStatusPending},
+	RequestStatusRevoked:  {RequestStatusPending},
+	RequestStatusExpired:  {RequestStatusPending},
+}
+
+// grantExpiryTransitions lists the status changes caused by an expiring grant.
+// A revoked request keeps its status and reason when its grant expires.
+var grantExpiryTransitions = map[RequestStatus][]RequestStatus{
+	RequestStatusApproved: {RequestStatusExpired},
+}
+
+// RequestStatuses returns every known request status in lifecycle order.
+func RequestStatuses() []RequestStatus {
+	return []RequestStatus{
+		RequestStatusPending,
+		RequestStatusApproved,
+		RequestStatusDenied,
+		RequestStatusRevoked,
+		RequestStatusExpired,
+	}
+}
+
+// ParseRequestStatus validates and normalizes a status value.
+func ParseRequestStatus(value string) (RequestStatus, error) {
+	normalized := RequestStatus(strings.ToLower(strings.TrimSpace(value)))
+	for _, status := range RequestStatuses() {
+		if status == normalized {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidRequestStatus, value)
+}
+
+// CanTransition reports whether a caller may move a request from one status to another.
+func (s RequestStatus) CanTransition(to RequestStatus) bool {
+	return transitionAllowed(manualTransitions, s, to)
+}
+
+func transitionAllowed(table map[RequestStatus][]RequestStatus, from, to RequestStatus) bool {
+	for _, candidate := range table[from] {
+		if candidate == to {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRequestStatus moves a request to the given status, recording an optional reason.
+func (s *Store) SetRequestStatus(ctx context.Context, id string, status RequestStatus, reason string) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if _, err := ParseRequestStatus(string(status)); err != nil {
+		return err
+	}
+
+	defer s.logDBOperation(ctx, "requests", "set_status", logrus.Fields{
+		"request_id": id,
+		"status":     status,
+		"reason":     reason,
+	})()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin request status transaction: %w", err)
+	}
+	defer rollbackTx(tx, "rollback request status transaction")
+
+	before, err := auditSnapshot(ctx, tx, EventResourceRequests, id)
+	if err != nil {
+		return err
+	}
+
+	if err := transitionRequestStatus(ctx, tx, id, status, reason, manualTransitions); err != nil {
+		return err
+	}
+	if status == RequestStatusPending {
+		// Revoked and expired requests keep their withdrawn grant for
+		// reference. Reopening removes it so that the request can be granted
+		// again; the audit log keeps its last state.
+		if err := deleteRequestGrants(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+
+	if err := recordAudit(ctx, tx, "set_status", EventResourceRequests, id, before); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit request status update: %w", err)
+	}
+	s.notifyEvents()
+	return nil
+}
+
+// CountRequestsByStatus returns the number of requests for every known status.
+func (s *Store) CountRequestsByStatus(ctx context.Context) (map[string]int64, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+
+	defer s.logDBOperation(ctx, "requests", "count_by_status", nil)()
+
+	counts := make(map[string]int64, len(RequestStatuses()))
+	for _, status := range RequestStatuses() {
+		counts[string(status)] = 0
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM requests GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("count requests by status: %w", err)
+	}
+	defer closeRows(rows, "close request status rows")
+
+	for rows.Next() {
+		var (
+			status string
+			count  int64
+		)
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("scan request status count: %w", err)
+		}
+		counts[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan request status counts: %w", err)
+	}
+	return counts, nil
+}
+
+func transitionRequestStatus(ctx context.Context, tx *sql.Tx, id string, to RequestStatus, reason string, table map[RequestStatus][]RequestStatus) error {
+	var current string
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM requests WHERE id = ?`, id).Scan(&current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRequestNotFound
+		}
+		return fmt.Errorf("load request status: %w", err)
+	}
+
+	from := RequestStatus(current)
+	if !transitionAllowed(table, from, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, from, to)
+	}
+	return setRequestStatus(ctx, tx, id, to, reason)
+}
+
+// deleteRequestGrants removes the grants of a request, recording a delete
+// event and audit entry for each.
+func deleteRequestGrants(ctx context.Context, tx *sql.Tx, requestID string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM grants WHERE request_id = ? ORDER BY created_at ASC, id ASC`, requestID)
+	if err != nil {
+		return fmt.Errorf("load request grants: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			closeRows(rows, "close request grant rows")
+			return fmt.Errorf("scan request grant: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	closeRows(rows, "close request grant rows")
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("scan request grants: %w", err)
+	}
+
+	for _, id := range ids {
+		before, err := auditSnapshot(ctx, tx, EventResourceGrants, id)
+		if err != nil {
+			return err
+		}
+		if err := recordEvent(ctx, tx, EventResourceGrants, id, EventActionDeleted); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM grants WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("delete grant: %w", err)
+		}
+		if err := recordAudit(ctx, tx, "delete", EventResourceGrants, id, before); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setRequestStatus(ctx context.Context, tx *sql.Tx, id string, status RequestStatus, reason string) error {
+	var reasonValue any
+	if strings.TrimSpace(reason) != "" {
+		reasonValue = reason
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE requests SET status = ?, status_reason = ? WHERE id = ?`, string(status), reasonValue, id); err != nil {
+		return fmt.Errorf("update request status: %w", err)
+	}
+	if err := setUpdatedAt(ctx, tx, "requests", "id", id); err != nil {
+		return fmt.Errorf("refresh request timestamp: %w", err)
+	}
+	return recordEvent(ctx, tx, EventResourceRequests, id, EventActionUpdated)
+}
+
+func (s *Store) ensureRequestStatusColumns(ctx context.Context, tx *sql.Tx) error {
+	columns, err := tableColumns(ctx, tx, "requests")
+	if err != nil {
+		return err
+	}
+	if !columns["status"] {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE requests ADD COLUMN status TEXT NOT NULL DEFAULT 'pending'`); err != nil {
+			return fmt.Errorf("add requests status column: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+UPDATE requests SET status = 'approved'
+WHERE EXISTS (SELECT 1 FROM grants WHERE grants.request_id = requests.id)
+`); err != nil {
+			return fmt.Errorf("backfill requests status: %w", err)
+		}
+	}
+	if !columns["status_reason"] {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE requests ADD COLUMN status_reason TEXT`); err != nil {
+			return fmt.Errorf("add requests status_reason column: %w", err)
+		}
+	}
+	return nil
+}
+
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return nil, fmt.Errorf("inspect %s columns: %w", table, err)
+	}
+	defer closeRows(rows, "close table info rows")
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return nil, fmt.Errorf("scan %s columns: %w", table, err)
+		}
+		columns[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan %s columns: %w", table, err)
+	}
+	return columns, nil
+}
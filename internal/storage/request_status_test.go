@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRequestStatus(t *testing.T) {
+	t.Parallel()
+
+	status, err := ParseRequestStatus(" Denied ")
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusDenied, status)
+
+	_, err = ParseRequestStatus("granted")
+	assert.ErrorIs(t, err, ErrInvalidRequestStatus)
+}
+
+func TestRequestStatusLifecycle(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	host, err := store.CreateHost(ctx, Host{})
+	require.NoError(t, err)
+	req, err := store.CreateRequest(ctx, Request{HostID: host.ID})
+	require.NoError(t, err)
+
+	loaded, err := store.GetRequest(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusPending, loaded.Status, "new requests start pending")
+
+	err = store.SetRequestStatus(ctx, req.ID, RequestStatusApproved, "")
+	assert.ErrorIs(t, err, ErrInvalidStatusTransition, "approval requires a grant")
+
+	require.NoError(t, store.SetRequestStatus(ctx, req.ID, RequestStatusDenied, "no capacity"))
+	loaded, err = store.GetRequest(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusDenied, loaded.Status)
+	assert.Equal(t, "no capacity", loaded.StatusReason)
+	assert.False(t, loaded.HasGrant)
+
+	grant, err := store.CreateGrant(ctx, Grant{RequestID: req.ID, Payload: []byte(`{"ok":true}`)})
+	require.NoError(t, err, "denied requests can still be granted")
+	loaded, err = store.GetRequest(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusApproved, loaded.Status)
+	assert.Empty(t, loaded.StatusReason, "approval clears the previous reason")
+	assert.True(t, loaded.HasGrant)
+
+	require.NoError(t, store.SetRequestStatus(ctx, req.ID, RequestStatusRevoked, "compromised"))
+	loaded, err = store.GetRequest(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusRevoked, loaded.Status)
+	assert.False(t, loaded.HasGrant, "revoked requests no longer report a grant")
+	_, err = store.GetGrant(ctx, grant.ID)
+	require.NoError(t, err, "revocation keeps the grant row")
+
+	_, err = store.CreateGrant(ctx, Grant{RequestID: req.ID})
+	assert.Error(t, err, "revoked requests must be reopened before granting")
+
+	require.NoError(t, store.SetRequestStatus(ctx, req.ID, RequestStatusPending, "reissue"))
+	loaded, err = store.GetRequest(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusPending, loaded.Status, "revoked requests can be reopened")
+	_, err = store.GetGrant(ctx, grant.ID)
+	assert.ErrorIs(t, err, ErrGrantNotFound, "reopening removes the revoked grant")
+
+	regrant, err := store.CreateGrant(ctx, Grant{RequestID: req.ID, Payload: []byte(`{"ok":"again"}`)})
+	require.NoError(t, err, "reopened requests can be granted again")
+	loaded, err = store.GetRequest(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusApproved, loaded.Status)
+	assert.True(t, loaded.HasGrant)
+	latest, found, err := store.GetLatestGrantForRequest(ctx, req.ID)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, regrant.ID, latest.ID)
+
+	require.NoError(t, store.DeleteGrant(ctx, regrant.ID))
+	loaded, err = store.GetRequest(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusPending, loaded.Status, "deleting the grant reopens the request")
+
+	require.NoError(t, store.SetRequestStatus(ctx, req.ID, RequestStatusExpired, ""))
+	_, err = store.CreateGrant(ctx, Grant{RequestID: req.ID})
+	assert.ErrorIs(t, err, ErrInvalidStatusTransition, "expired requests cannot be granted")
+
+	err = store.SetRequestStatus(ctx, "missing", RequestStatusDenied, "")
+	assert.ErrorIs(t, err, ErrRequestNotFound)
+}
+
+func TestListRequestsFilterByStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	host, err := store.CreateHost(ctx, Host{})
+	require.NoError(t, err)
+	pending, err := store.CreateRequest(ctx, Request{HostID: host.ID})
+	require.NoError(t, err)
+	denied, err := store.CreateRequest(ctx, Request{HostID: host.ID})
+	require.NoError(t, err)
+	approved, err := store.CreateRequest(ctx, Request{HostID: host.ID})
+	require.NoError(t, err)
+
+	require.NoError(t, store.SetRequestStatus(ctx, denied.ID, RequestStatusDenied, "nope"))
+	_, err = store.CreateGrant(ctx, Grant{RequestID: approved.ID})
+	require.NoError(t, err)
+
+	list, err := store.ListRequests(ctx, &RequestListFilters{Statuses: []RequestStatus{RequestStatusDenied}})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, denied.ID, list[0].ID)
+
+	list, err = store.ListRequests(ctx, &RequestListFilters{Statuses: []RequestStatus{RequestStatusPending, RequestStatusApproved}})
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+	for _, req := range list {
+		assert.NotEqual(t, denied.ID, req.ID)
+	}
+
+	list, err = store.ListRequests(ctx, &RequestListFilters{HasGrant: ptrBool(false)})
+	require.NoError(t, err)
+	assert.Len(t, list, 2, "denied and pending requests have no grant")
+	assert.ElementsMatch(t, []string{pending.ID, denied.ID}, []string{list[0].ID, list[1].ID})
+
+	counts, err := store.CountRequestsByStatus(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, counts["pending"])
+	assert.EqualValues(t, 1, counts["denied"])
+	assert.EqualValues(t, 1, counts["approved"])
+	assert.EqualValues(t, 0, counts["revoked"])
+}
+
+func TestMigrateAddsRequestStatusToExistingDatabase(t *testing.T) {
+	t.Parallel()
+	skipUnlessSQLite(t)
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+
+	legacy := []string{
+		`CREATE TABLE hosts (id TEXT PRIMARY KEY, created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE requests (id TEXT PRIMARY KEY, host_id TEXT NOT NULL, data TEXT, created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, FOREIGN KEY(host_id) REFERENCES hosts(id) ON DELETE CASCADE)`,
+		`CREATE TABLE grants (id TEXT PRIMARY KEY, request_id TEXT NOT NULL, payload TEXT, created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, FOREIGN KEY(request_id) REFERENCES requests(id) ON DELETE CASCADE, UNIQUE(request_id))`,
+		`INSERT INTO hosts (id) VALUES ('h1')`,
+		`INSERT INTO requests (id, host_id) VALUES ('r1', 'h1'), ('r2', 'h1')`,
+		`INSERT INTO grants (id, request_id) VALUES ('g1', 'r1')`,
+	}
+	for _, stmt := range legacy {
+		_, err := store.DB().ExecContext(ctx, stmt)
+		require.NoError(t, err, stmt)
+	}
+
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	granted, err := store.GetRequest(ctx, "r1")
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusApproved, granted.Status, "existing grants backfill approved")
+	assert.True(t, granted.HasGrant)
+
+	open, err := store.GetRequest(ctx, "r2")
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusPending, open.Status)
+
+	require.NoError(t, store.Migrate(ctx), "Migrate() should be idempotent")
+}
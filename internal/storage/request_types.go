@@ -0,0 +1,435 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/sirupsen/logrus"
+)
+
+// RequestTypeLabel is the request label whose value selects the request type
+// that request and grant payloads are validated against.
+const RequestTypeLabel = "type"
+
+// AuditResourceRequestTypes is the audit resource type of request types.
+const AuditResourceRequestTypes = "request_types"
+
+var (
+	// ErrRequestTypeNotFound is returned when a request type cannot be located.
+	ErrRequestTypeNotFound = errors.New("request type not found")
+	// ErrInvalidRequestType is returned when a request type definition is rejected.
+	ErrInvalidRequestType = errors.New("invalid request type")
+	// ErrSchemaValidation is returned when a payload does not satisfy the
+	// schema of its request type. The error is a *SchemaValidationError.
+	ErrSchemaValidation = errors.New("payload does not match schema")
+)
+
+// requestTypeNamePattern keeps names usable as label values and URL segments.
+var requestTypeNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+const requestTypesTableStatement = `
+CREATE TABLE IF NOT EXISTS request_types (
+	name TEXT PRIMARY KEY,
+	request_schema TEXT,
+	grant_schema TEXT,
+	created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+	updated_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+)`
+
+// RequestType holds the JSON Schemas for requests labelled with
+// type=<Name>. RequestSchema validates request payloads and GrantSchema
+// validates the payloads of grants issued for those requests. Either schema
+// may be empty, but not both.
+type RequestType struct {
+	Name          string          `json:"name"`
+	RequestSchema json.RawMessage `json:"request_schema,omitempty"`
+	GrantSchema   json.RawMessage `json:"grant_schema,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// SchemaViolation is a single schema failure. Path is the JSON pointer of
+// the offending value within the payload; the empty pointer is the payload
+// itself.
+type SchemaViolation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// SchemaValidationError lists every violation of a payload against the
+// schema of its request type. Target is either "request" or "grant".
+type SchemaValidationError struct {
+	RequestType string
+	Target      string
+	Violations  []SchemaViolation
+}
+
+func (e *SchemaValidationError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		path := violation.Path
+		if path == "" {
+			path = "/"
+		}
+		parts = append(parts, path+": "+violation.Message)
+	}
+	return fmt.Sprintf("%s payload does not match schema of request type %q: %s", e.Target, e.RequestType, strings.Join(parts, "; "))
+}
+
+func (e *SchemaValidationError) Unwrap() error {
+	return ErrSchemaValidation
+}
+
+func (s *Store) ensureRequestTypesTable(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, requestTypesTableStatement); err != nil {
+		return fmt.Errorf("create request types table: %w", err)
+	}
+	return nil
+}
+
+func validateRequestType(requestType RequestType) error {
+	if !requestTypeNamePattern.MatchString(requestType.Name) {
+		return fmt.Errorf("%w: name %q must start with a letter or digit and contain only letters, digits, '.', '_' and '-'", ErrInvalidRequestType, requestType.Name)
+	}
+	if len(requestType.RequestSchema) == 0 && len(requestType.GrantSchema) == 0 {
+		return fmt.Errorf("%w: request_schema or grant_schema is required", ErrInvalidRequestType)
+	}
+	for target, schema := range map[string]json.RawMessage{"request": requestType.RequestSchema, "grant": requestType.GrantSchema} {
+		if len(schema) == 0 {
+			continue
+		}
+		if _, err := compileSchema(schema); err != nil {
+			return fmt.Errorf("%w: %s_schema: %v", ErrInvalidRequestType, target, err)
+		}
+	}
+	return nil
+}
+
+// compileSchema compiles a JSON Schema document. Only the bundled meta
+// schemas can be referenced; remote and file references are rejected so a
+// schema cannot make the server fetch arbitrary resources.
+func compileSchema(schema json.RawMessage) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.UseLoader(jsonschema.SchemeURLLoader{})
+	if err := compiler.AddResource("schema.json", doc); err != nil {
+		return nil, fmt.Errorf("add schema: %w", err)
+	}
+	compiled, err := compiler.Compile("schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("compile schema: %w", err)
+	}
+	return compiled, nil
+}
+
+// validateAgainstSchema checks a JSON document against schema and collects
+// every leaf failure as a violation.
+func validateAgainstSchema(requestType, target string, schema json.RawMessage, document []byte) error {
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		return fmt.Errorf("%w: %s_schema of %q: %v", ErrInvalidRequestType, target, requestType, err)
+	}
+	if len(document) == 0 {
+		document = []byte("null")
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(document))
+	if err != nil {
+		return &SchemaValidationError{
+			RequestType: requestType,
+			Target:      target,
+			Violations:  []SchemaViolation{{Path: "", Message: "payload is not valid JSON"}},
+		}
+	}
+
+	err = compiled.Validate(instance)
+	if err == nil {
+		return nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return fmt.Errorf("validate %s payload: %w", target, err)
+	}
+	return &SchemaValidationError{
+		RequestType: requestType,
+		Target:      target,
+		Violations:  collectViolations(validationErr, nil),
+	}
+}
+
+func collectViolations(err *jsonschema.ValidationError, violations []SchemaViolation) []SchemaViolation {
+	if len(err.Causes) == 0 {
+		message := err.Error()
+		if output := err.BasicOutput(); output.Error != nil {
+			message = output.Error.String()
+		}
+		return append(violations, SchemaViolation{Path: jsonPointer(err.InstanceLocation), Message: message})
+	}
+	for _, cause := range err.Causes {
+		violations = collectViolations(cause, violations)
+	}
+	return violations
+}
+
+func jsonPointer(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteByte('/')
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return b.String()
+}
+
+// loadRequestTypeSchema returns the schema stored for the request type in
+// column, or nil when the type or the schema does not exist.
+func loadRequestTypeSchema(ctx context.Context, tx *sql.Tx, name, column string) (json.RawMessage, error) {
+	var schema sql.NullString
+	if err := tx.QueryRowContext(ctx, `SELECT `+column+` FROM request_types WHERE name = ?`, name).Scan(&schema); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("load request type schema: %w", err)
+	}
+	if !schema.Valid || schema.String == "" {
+		return nil, nil
+	}
+	return json.RawMessage(schema.String), nil
+}
+
+// requestTypeOf returns the type label of a request, or an empty string.
+func requestTypeOf(ctx context.Context, tx *sql.Tx, requestID string) (string, error) {
+	var name string
+	err := tx.QueryRowContext(ctx, `SELECT value FROM request_labels WHERE request_id = ? AND key = ?`, requestID, RequestTypeLabel).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("load request type label: %w", err)
+	}
+	return name, nil
+}
+
+// validateRequestPayload checks an encoded request payload, as returned by
+// encodeJSON, against the request schema of the named type. Payloads pass
+// when the type or its request schema is not registered.
+func validateRequestPayload(ctx context.Context, tx *sql.Tx, requestType string, payload any) error {
+	if requestType == "" {
+		return nil
+	}
+	schema, err := loadRequestTypeSchema(ctx, tx, requestType, "request_schema")
+	if err != nil || schema == nil {
+		return err
+	}
+	var document []byte
+	if encoded, ok := payload.(string); ok {
+		document = []byte(encoded)
+	}
+	return validateAgainstSchema(requestType, "request", schema, document)
+}
+
+// validateGrantPayload checks a grant payload against the grant schema of the
+// type of the request it is issued for.
+func validateGrantPayload(ctx context.Context, tx *sql.Tx, requestID string, payload []byte) error {
+	requestType, err := requestTypeOf(ctx, tx, requestID)
+	if err != nil || requestType == "" {
+		return err
+	}
+	schema, err := loadRequestTypeSchema(ctx, tx, requestType, "grant_schema")
+	if err != nil || schema == nil {
+		return err
+	}
+	return validateAgainstSchema(requestType, "grant", schema, payload)
+}
+
+// PutRequestType creates or replaces a request type. Existing requests and
+// grants are not revalidated.
+func (s *Store) PutRequestType(ctx context.Context, requestType RequestType) (RequestType, error) {
+	if s == nil || s.db == nil {
+		return RequestType{}, fmt.Errorf("store not initialized")
+	}
+	requestType.Name = strings.TrimSpace(requestType.Name)
+	if err := validateRequestType(requestType); err != nil {
+		return RequestType{}, err
+	}
+
+	defer s.logDBOperation(ctx, "request_types", "put", logrus.Fields{
+		"name": requestType.Name,
+	})()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return RequestType{}, fmt.Errorf("begin request type transaction: %w", err)
+	}
+	defer rollbackTx(tx, "rollback put request type transaction")
+
+	before, err := auditSnapshot(ctx, tx, AuditResourceRequestTypes, requestType.Name)
+	if err != nil {
+		return RequestType{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO request_types (name, request_schema, grant_schema, updated_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT(name) DO UPDATE SET
+    request_schema = excluded.request_schema,
+    grant_schema = excluded.grant_schema,
+    updated_at = excluded.updated_at
+`, requestType.Name, nullableSchema(requestType.RequestSchema), nullableSchema(requestType.GrantSchema), currentTimestamp()); err != nil {
+		return RequestType{}, fmt.Errorf("store request type: %w", err)
+	}
+
+	action := "update"
+	if before == nil {
+		action = "create"
+	}
+	if err := recordAudit(ctx, tx, action, AuditResourceRequestTypes, requestType.Name, before); err != nil {
+		return RequestType{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return RequestType{}, fmt.Errorf("commit request type: %w", err)
+	}
+
+	return s.GetRequestType(ctx, requestType.Name)
+}
+
+func nullableSchema(schema json.RawMessage) any {
+	if len(schema) == 0 {
+		return nil
+	}
+	return string(schema)
+}
+
+// GetRequestType returns the request type with the given name.
+func (s *Store) GetRequestType(ctx context.Context, name string) (RequestType, error) {
+	if s == nil || s.db == nil {
+		return RequestType{}, fmt.Errorf("store not initialized")
+	}
+
+	defer s.logDBOperation(ctx, "request_types", "get", logrus.Fields{
+		"name": name,
+	})()
+
+	row := s.db.QueryRowContext(ctx, `
+SELECT name, request_schema, grant_schema, created_at, updated_at
+FROM request_types
+WHERE name = ?
+`, name)
+	return scanRequestType(row)
+}
+
+// ListRequestTypes returns every request type ordered by name.
+func (s *Store) ListRequestTypes(ctx context.Context) ([]RequestType, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+
+	defer s.logDBOperation(ctx, "request_types", "list", nil)()
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT name, request_schema, grant_schema, created_at, updated_at
+FROM request_types
+ORDER BY name ASC
+`)
+	if err != nil {
+		return nil, fmt.Errorf("query request types: %w", err)
+	}
+	defer closeRows(rows, "close request type rows")
+
+	requestTypes := make([]RequestType, 0)
+	for rows.Next() {
+		requestType, err := scanRequestType(rows)
+		if err != nil {
+			return nil, err
+		}
+		requestTypes = append(requestTypes, requestType)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan request types: %w", err)
+	}
+	return requestTypes, nil
+}
+
+// DeleteRequestType removes a request type. Requests labelled with its name
+// are no longer validated.
+func (s *Store) DeleteRequestType(ctx context.Context, name string) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("store not initialized")
+	}
+
+	defer s.logDBOperation(ctx, "request_types", "delete", logrus.Fields{
+		"name": name,
+	})()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin delete request type transaction: %w", err)
+	}
+	defer rollbackTx(tx, "rollback delete request type transaction")
+
+	before, err := auditSnapshot(ctx, tx, AuditResourceRequestTypes, name)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM request_types WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("delete request type: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete request type rows affected: %w", err)
+	}
+	if count == 0 {
+		return ErrRequestTypeNotFound
+	}
+
+	if err := recordAudit(ctx, tx, "delete", AuditResourceRequestTypes, name, before); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit request type deletion: %w", err)
+	}
+	return nil
+}
+
+func scanRequestType(row rowScanner) (RequestType, error) {
+	var (
+		requestType   RequestType
+		requestSchema sql.NullString
+		grantSchema   sql.NullString
+		createdAt     string
+		updatedAt     string
+	)
+	if err := row.Scan(&requestType.Name, &requestSchema, &grantSchema, &createdAt, &updatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RequestType{}, ErrRequestTypeNotFound
+		}
+		return RequestType{}, fmt.Errorf("scan request type: %w", err)
+	}
+	if requestSchema.Valid && requestSchema.String != "" {
+		requestType.RequestSchema = json.RawMessage(requestSchema.String)
+	}
+	if grantSchema.Valid && grantSchema.String != "" {
+		requestType.GrantSchema = json.RawMessage(grantSchema.String)
+	}
+	var err error
+	if requestType.CreatedAt, err = parseCreatedAt(createdAt); err != nil {
+		return RequestType{}, err
+	}
+	if requestType.UpdatedAt, err = parseCreatedAt(updatedAt); err != nil {
+		return RequestType{}, err
+	}
+	return requestType, nil
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRequestSchema = `{
+	"type": "object",
+	"required": ["name", "size"],
+	"properties": {
+		"name": {"type": "string"},
+		"size": {"enum": ["small", "large"]},
+		"ports": {"type": "array", "items": {"type": "integer"}}
+	}
+}`
+
+func TestRequestTypeValidation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	created, err := store.PutRequestType(ctx, RequestType{
+		Name:          "database",
+		RequestSchema: json.RawMessage(testRequestSchema),
+		GrantSchema:   json.RawMessage(`{"type": "object", "required": ["password"]}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "database", created.Name)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	host, err := store.CreateHost(ctx, Host{})
+	require.NoError(t, err)
+
+	_, err = store.CreateRequest(ctx, Request{
+		HostID:  host.ID,
+		Labels:  map[string]string{RequestTypeLabel: "database"},
+		Payload: map[string]any{"name": 42, "ports": []any{5432, "x"}},
+	})
+	require.ErrorIs(t, err, ErrSchemaValidation)
+	var validationErr *SchemaValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "database", validationErr.RequestType)
+	assert.Equal(t, "request", validationErr.Target)
+	paths := make([]string, 0, len(validationErr.Violations))
+	for _, violation := range validationErr.Violations {
+		paths = append(paths, violation.Path)
+		assert.NotEmpty(t, violation.Message)
+	}
+	assert.ElementsMatch(t, []string{"", "/name", "/ports/1"}, paths)
+
+	requests, err := store.ListRequests(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, requests, "rejected requests are not stored")
+
+	req, err := store.CreateRequest(ctx, Request{
+		HostID:  host.ID,
+		Labels:  map[string]string{RequestTypeLabel: "database"},
+		Payload: map[string]any{"name": "db", "size": "small"},
+	})
+	require.NoError(t, err)
+
+	_, err = store.CreateRequest(ctx, Request{HostID: host.ID, Payload: map[string]any{"anything": true}})
+	require.NoError(t, err, "requests without a type are not validated")
+	_, err = store.CreateRequest(ctx, Request{HostID: host.ID, Labels: map[string]string{RequestTypeLabel: "unknown"}})
+	require.NoError(t, err, "unregistered types are not validated")
+
+	err = store.UpdateRequestPayload(ctx, req.ID, map[string]any{"name": "db", "size": "huge"})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []SchemaViolation{{Path: "/size", Message: validationErr.Violations[0].Message}}, validationErr.Violations)
+	require.NoError(t, store.UpdateRequestPayload(ctx, req.ID, map[string]any{"name": "db", "size": "large"}))
+
+	untyped, err := store.CreateRequest(ctx, Request{HostID: host.ID, Payload: map[string]any{"name": "db"}})
+	require.NoError(t, err)
+	err = store.UpdateRequestLabels(ctx, untyped.ID, map[string]string{RequestTypeLabel: "database"})
+	require.ErrorIs(t, err, ErrSchemaValidation, "switching the type validates the stored payload")
+
+	_, err = store.PutRequestType(ctx, RequestType{Name: "cache", RequestSchema: json.RawMessage(`{"type": "object", "required": ["endpoint"]}`)})
+	require.NoError(t, err)
+	typedLabels := map[string]string{RequestTypeLabel: "database"}
+	typedPayload := map[string]any{"name": "db", "size": "small"}
+	require.NoError(t, store.UpdateRequest(ctx, untyped.ID, RequestUpdate{Payload: &typedPayload, Labels: &typedLabels}))
+	cacheLabels := map[string]string{RequestTypeLabel: "cache"}
+	cachePayload := map[string]any{"endpoint": "redis:6379"}
+	require.NoError(t, store.UpdateRequest(ctx, untyped.ID, RequestUpdate{Payload: &cachePayload, Labels: &cacheLabels}),
+		"the new payload is checked against the new type")
+	err = store.UpdateRequest(ctx, untyped.ID, RequestUpdate{Payload: &typedPayload, Labels: &cacheLabels})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "cache", validationErr.RequestType)
+
+	_, err = store.CreateGrant(ctx, Grant{RequestID: req.ID, Payload: []byte(`{"user": "app"}`)})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "grant", validationErr.Target)
+	_, err = store.CreateGrant(ctx, Grant{RequestID: req.ID, Payload: []byte(`not json`)})
+	require.ErrorIs(t, err, ErrSchemaValidation)
+	_, err = store.CreateGrant(ctx, Grant{RequestID: req.ID, Payload: []byte(`{"password": "secret"}`)})
+	require.NoError(t, err)
+
+	require.NoError(t, store.DeleteRequestType(ctx, "database"))
+	_, err = store.CreateRequest(ctx, Request{HostID: host.ID, Labels: map[string]string{RequestTypeLabel: "database"}})
+	require.NoError(t, err, "deleted types no longer validate")
+	assert.ErrorIs(t, store.DeleteRequestType(ctx, "database"), ErrRequestTypeNotFound)
+}
+
+func TestRequestTypeCRUD(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	for name, requestType := range map[string]RequestType{
+		"bad name":       {Name: "a b", RequestSchema: json.RawMessage(`{}`)},
+		"no schema":      {Name: "db"},
+		"invalid json":   {Name: "db", RequestSchema: json.RawMessage(`{`)},
+		"invalid schema": {Name: "db", RequestSchema: json.RawMessage(`{"type": 1}`)},
+		"file reference": {Name: "db", GrantSchema: json.RawMessage(`{"$ref": "file:///etc/passwd"}`)},
+	} {
+		_, err := store.PutRequestType(ctx, requestType)
+		assert.ErrorIs(t, err, ErrInvalidRequestType, name)
+	}
+
+	_, err = store.PutRequestType(ctx, RequestType{Name: "db", RequestSchema: json.RawMessage(`{"type": "object"}`)})
+	require.NoError(t, err)
+	updated, err := store.PutRequestType(ctx, RequestType{Name: "db", GrantSchema: json.RawMessage(`{"type": "object"}`)})
+	require.NoError(t, err)
+	assert.Nil(t, updated.RequestSchema, "put replaces both schemas")
+	assert.JSONEq(t, `{"type": "object"}`, string(updated.GrantSchema))
+
+	_, err = store.PutRequestType(ctx, RequestType{Name: "cache", RequestSchema: json.RawMessage(`true`)})
+	require.NoError(t, err)
+	requestTypes, err := store.ListRequestTypes(ctx)
+	require.NoError(t, err)
+	require.Len(t, requestTypes, 2)
+	assert.Equal(t, "cache", requestTypes[0].Name)
+	assert.Equal(t, "db", requestTypes[1].Name)
+
+	_, err = store.GetRequestType(ctx, "missing")
+	assert.ErrorIs(t, err, ErrRequestTypeNotFound)
+
+	events, err := store.ListAuditEvents(ctx, AuditListFilters{ResourceType: AuditResourceRequestTypes, ResourceID: "db"})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "update", events[0].Action)
+	assert.NotEqual(t, events[0].Before.PayloadHash, events[0].After.PayloadHash)
+	assert.Equal(t, "create", events[1].Action)
+}
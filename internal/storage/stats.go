@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// Stats summarizes the contents of a namespace database.
+type Stats struct {
+	SchemaVersion    int              `json:"schema_version"`
+	Hosts            int64            `json:"hosts"`
+	Requests         int64            `json:"requests"`
+	RequestsByStatus map[string]int64 `json:"requests_by_status"`
+	Registers        int64            `json:"registers"`
+	Grants           int64            `json:"grants"`
+	RequestTypes     int64            `json:"request_types"`
+	Webhooks         int64            `json:"webhooks"`
+}
+
+// Stats counts the records stored in the namespace database.
+func (s *Store) Stats(ctx context.Context) (Stats, error) {
+	if s == nil || s.db == nil {
+		return Stats{}, fmt.Errorf("store not initialized")
+	}
+
+	defer s.logDBOperation(ctx, "namespace", "stats", nil)()
+
+	status, err := s.MigrationStatus(ctx)
+	if err != nil {
+		return Stats{}, err
+	}
+	stats := Stats{SchemaVersion: status.CurrentVersion}
+
+	counters := []struct {
+		table string
+		dest  *int64
+	}{
+		{"hosts", &stats.Hosts},
+		{"requests", &stats.Requests},
+		{"registers", &stats.Registers},
+		{"grants", &stats.Grants},
+		{"request_types", &stats.RequestTypes},
+		{"webhooks", &stats.Webhooks},
+	}
+	for _, counter := range counters {
+		// Table names come from the fixed list above.
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+counter.table).Scan(counter.dest); err != nil {
+			return Stats{}, fmt.Errorf("count %s: %w", counter.table, err)
+		}
+	}
+
+	if stats.RequestsByStatus, err = s.CountRequestsByStatus(ctx); err != nil {
+		return Stats{}, err
+	}
+	return stats, nil
+}
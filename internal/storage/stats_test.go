@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	host, err := store.CreateHost(ctx, Host{})
+	require.NoError(t, err)
+	req, err := store.CreateRequest(ctx, Request{HostID: host.ID})
+	require.NoError(t, err)
+	_, err = store.CreateRequest(ctx, Request{HostID: host.ID})
+	require.NoError(t, err)
+	_, err = store.CreateGrant(ctx, Grant{RequestID: req.ID})
+	require.NoError(t, err)
+
+	stats, err := store.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, SchemaVersion(), stats.SchemaVersion)
+	assert.Equal(t, int64(1), stats.Hosts)
+	assert.Equal(t, int64(2), stats.Requests)
+	assert.Equal(t, int64(1), stats.Grants)
+	assert.Zero(t, stats.Registers)
+	assert.Equal(t, int64(1), stats.RequestsByStatus[string(RequestStatusApproved)])
+	assert.Equal(t, int64(1), stats.RequestsByStatus[string(RequestStatusPending)])
+}
@@ -7,18 +7,31 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tasansga/terraform-provider-grantory/internal/selector"
+	"github.com/tasansga/terraform-provider-grantory/internal/telemetry"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// Store wraps an sqlite database connection for the provisioner server.
+// Store holds the database of one namespace, either an SQLite file or a
+// PostgreSQL schema.
 type Store struct {
 	db        *sql.DB
+	dialect   dialect
 	namespace string
+	observer  OperationObserver
+
+	eventsMu     sync.Mutex
+	eventsSignal chan struct{}
 }
 
 const unknownNamespace = "(unknown)"
@@ -35,6 +48,18 @@ func (s *Store) SetNamespace(namespace string) {
 	s.namespace = unknownNamespace
 }
 
+// OperationObserver receives the duration of each logged database operation.
+type OperationObserver func(table, operation string, duration time.Duration)
+
+// SetOperationObserver registers a callback that is invoked when a database
+// operation finishes, e.g. to export timing metrics.
+func (s *Store) SetOperationObserver(observer OperationObserver) {
+	if s == nil {
+		return
+	}
+	s.observer = observer
+}
+
 func (s *Store) namespaceForLog() string {
 	if s == nil {
 		return unknownNamespace
@@ -45,10 +70,43 @@ func (s *Store) namespaceForLog() string {
 	return unknownNamespace
 }
 
-func (s *Store) logDBOperation(table, operation string, params logrus.Fields) {
+// logDBOperation logs the start of a database operation and opens a span for
+// it. The returned func ends the span, reports the operation's duration to the
+// observer and is meant to be deferred.
+func (s *Store) logDBOperation(ctx context.Context, table, operation string, params logrus.Fields) func() {
 	if s == nil {
-		return
+		return func() {}
+	}
+	s.logDBEntry(table, operation, params)
+
+	_, span := telemetry.Tracer().Start(ctx, "storage "+table+"."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			s.dbSystem(),
+			semconv.DBNamespace(s.namespaceForLog()),
+			semconv.DBCollectionName(table),
+			semconv.DBOperationName(operation),
+		),
+	)
+	observer := s.observer
+	start := time.Now()
+	return func() {
+		span.End()
+		if observer != nil {
+			observer(table, operation, time.Since(start))
+		}
+	}
+}
+
+func (s *Store) dbSystem() attribute.KeyValue {
+	if s.dialect == dialectPostgres {
+		return semconv.DBSystemNamePostgreSQL
 	}
+	return semconv.DBSystemNameSQLite
+}
+
+// logDBEntry logs a database operation without tracing or timing it.
+func (s *Store) logDBEntry(table, operation string, params logrus.Fields) {
 	fields := logrus.Fields{
 		"namespace": s.namespaceForLog(),
 		"table":     table,
@@ -108,9 +166,15 @@ func (s *Store) ensureRequestExists(ctx context.Context, requestID string) error
 
 // Host describes the persisted labels for a registered host.
 type Host struct {
-	ID        string            `json:"id"`
-	Labels    map[string]string `json:"labels,omitempty"`
-	CreatedAt time.Time         `json:"created_at"`
+	ID         string            `json:"id"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	LastSeenAt *time.Time        `json:"last_seen_at,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// HostListFilters describes optional filters for listing hosts.
+type HostListFilters struct {
+	LastSeenBefore *time.Time
 }
 
 var (
@@ -137,6 +201,10 @@ var (
 
 const createdAtLayout = "2006-01-02 15:04:05"
 
+// timestampLayout matches the format sqlite uses for strftime('%Y-%m-%d %H:%M:%f'),
+// so stored timestamps compare correctly as strings.
+const timestampLayout = "2006-01-02 15:04:05.000"
+
 const (
 	hostsTableStatement = `
 CREATE TABLE IF NOT EXISTS hosts (
@@ -223,6 +291,14 @@ const maxLabelLength = 256
 // New opens or creates the sqlite database at the given path and prepares it
 // for use by the server.
 func New(ctx context.Context, path string) (*Store, error) {
+	db, err := openSQLite(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db, namespace: unknownNamespace}, nil
+}
+
+func openSQLite(ctx context.Context, path string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite3", path)
 	if err != nil {
 		return nil, fmt.Errorf("open sqlite database: %w", err)
@@ -237,7 +313,7 @@ func New(ctx context.Context, path string) (*Store, error) {
 		return nil, fmt.Errorf("enable foreign keys: %w", err)
 	}
 
-	return &Store{db: db, namespace: unknownNamespace}, nil
+	return db, nil
 }
 
 // Close tears down the underlying database connection.
@@ -256,43 +332,13 @@ func (s *Store) DB() *sql.DB {
 	return s.db
 }
 
-// Migrate ensures the schema for hosts, requests, and grants exists.
+// Migrate applies all pending schema migrations. It fails with ErrSchemaTooNew
+// when the database was migrated by a newer binary.
 func (s *Store) Migrate(ctx context.Context) error {
 	if s == nil || s.db == nil {
 		return fmt.Errorf("store not initialized")
 	}
-
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
-	}
-
-	tasks := []struct {
-		name string
-		fn   func(context.Context, *sql.Tx) error
-	}{
-		{"hosts", s.ensureHostsTable},
-		{"requests", s.ensureRequestsTable},
-		{"registers", s.ensureRegistersTable},
-		{"grants", s.ensureGrantsTable},
-		{"host labels", s.ensureHostLabelsTable},
-		{"request labels", s.ensureRequestLabelsTable},
-		{"register labels", s.ensureRegisterLabelsTable},
-		{"grant labels", s.ensureGrantLabelsTable},
-	}
-
-	for _, task := range tasks {
-		if err := task.fn(ctx, tx); err != nil {
-			rollbackTx(tx, "rollback migration transaction")
-			return fmt.Errorf("ensure %s schema: %w", task.name, err)
-		}
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit migration: %w", err)
-	}
-
-	return nil
+	return applyMigrations(ctx, s.db, s.dialect, s.namespaceForLog(), s.migrations())
 }
 
 func (s *Store) ensureHostsTable(ctx context.Context, tx *sql.Tx) error {
@@ -364,10 +410,10 @@ func (s *Store) CreateHost(ctx context.Context, host Host) (Host, error) {
 	}
 	defer rollbackTx(tx, "rollback create host transaction")
 
-	s.logDBOperation("hosts", "create", logrus.Fields{
+	defer s.logDBOperation(ctx, "hosts", "create", logrus.Fields{
 		"host_id": host.ID,
 		"labels":  host.Labels,
-	})
+	})()
 
 	if _, err := tx.ExecContext(ctx, `
 INSERT INTO hosts (id)
@@ -383,9 +429,18 @@ VALUES (?)
 		return Host{}, fmt.Errorf("insert host labels: %w", err)
 	}
 
+	if err := recordAudit(ctx, tx, "create", EventResourceHosts, host.ID, nil); err != nil {
+		return Host{}, err
+	}
+
+	if err := recordEvent(ctx, tx, EventResourceHosts, host.ID, EventActionCreated); err != nil {
+		return Host{}, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return Host{}, fmt.Errorf("commit host creation: %w", err)
 	}
+	s.notifyEvents()
 
 	return host, nil
 }
@@ -396,12 +451,12 @@ func (s *Store) GetHost(ctx context.Context, id string) (Host, error) {
 		return Host{}, fmt.Errorf("store not initialized")
 	}
 
-	s.logDBOperation("hosts", "get", logrus.Fields{
+	defer s.logDBOperation(ctx, "hosts", "get", logrus.Fields{
 		"host_id": id,
-	})
+	})()
 
 	row := s.db.QueryRowContext(ctx, `
-SELECT id, created_at
+SELECT id, last_seen_at, created_at
 FROM hosts
 WHERE id = ?
 `, id)
@@ -418,41 +473,87 @@ WHERE id = ?
 
 // ListHosts returns every host stored in the database ordered by creation.
 func (s *Store) ListHosts(ctx context.Context) ([]Host, error) {
+	hosts, _, err := s.ListHostsPage(ctx, nil, PageOptions{})
+	return hosts, err
+}
+
+// ListHostsPage returns one page of hosts together with the cursor of the
+// next page, which is empty on the last page.
+func (s *Store) ListHostsPage(ctx context.Context, filters *HostListFilters, opts PageOptions) ([]Host, string, error) {
 	if s == nil || s.db == nil {
-		return nil, fmt.Errorf("store not initialized")
+		return nil, "", fmt.Errorf("store not initialized")
+	}
+
+	page, err := newPageQuery("hosts", opts, hostSortColumns)
+	if err != nil {
+		return nil, "", err
 	}
 
-	s.logDBOperation("hosts", "list", nil)
+	logFields := logrus.Fields{}
+	if filters != nil && filters.LastSeenBefore != nil {
+		logFields["last_seen_before"] = *filters.LastSeenBefore
+	}
+	if opts != (PageOptions{}) {
+		logFields["page"] = opts
+	}
+	if len(logFields) == 0 {
+		logFields = nil
+	}
+	defer s.logDBOperation(ctx, "hosts", "list", logFields)()
 
-	rows, err := s.db.QueryContext(ctx, `
-SELECT id, created_at
-FROM hosts
-ORDER BY created_at ASC
-`)
+	var where []string
+	var args []any
+	if filters != nil && filters.LastSeenBefore != nil {
+		where = append(where, "last_seen_at IS NOT NULL AND last_seen_at < ?")
+		args = append(args, formatOptionalTime(filters.LastSeenBefore))
+	}
+	return s.queryHosts(ctx, page, where, args...)
+}
+
+// queryHosts loads one page of the hosts matching the WHERE conditions
+// together with their labels.
+func (s *Store) queryHosts(ctx context.Context, page pageQuery, where []string, args ...any) ([]Host, string, error) {
+	query := `
+SELECT id, last_seen_at, created_at` + page.columns() + `
+FROM hosts`
+	if condition, cursorArgs := page.where(); condition != "" {
+		where = append(where, condition)
+		args = append(args, cursorArgs...)
+	}
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += page.suffix()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("query hosts: %w", err)
+		return nil, "", fmt.Errorf("query hosts: %w", err)
 	}
 	defer closeRows(rows, "close hosts rows")
 
 	hosts := make([]Host, 0)
+	var keys []pageKey
 	for rows.Next() {
-		host, err := scanHost(rows)
+		var key pageKey
+		host, err := scanHost(key.scanner(rows))
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		hosts = append(hosts, host)
+		keys = append(keys, key)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("scan hosts: %w", err)
+		return nil, "", fmt.Errorf("scan hosts: %w", err)
 	}
 
+	hosts, next := trimPage(page, hosts, keys)
 	for i := range hosts {
 		if hosts[i].Labels, err = s.loadLabels(ctx, hostLabelsTable, "host_id", hosts[i].ID); err != nil {
-			return nil, fmt.Errorf("load host labels: %w", err)
+			return nil, "", fmt.Errorf("load host labels: %w", err)
 		}
 	}
 
-	return hosts, nil
+	return hosts, next, nil
 }
 
 // DeleteHost removes a host from storage.
@@ -461,11 +562,39 @@ func (s *Store) DeleteHost(ctx context.Context, id string) error {
 		return fmt.Errorf("store not initialized")
 	}
 
-	s.logDBOperation("hosts", "delete", logrus.Fields{
+	defer s.logDBOperation(ctx, "hosts", "delete", logrus.Fields{
 		"host_id": id,
-	})
+	})()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin delete host transaction: %w", err)
+	}
+	defer rollbackTx(tx, "rollback delete host transaction")
+
+	before, err := auditSnapshot(ctx, tx, EventResourceHosts, id)
+	if err != nil {
+		return err
+	}
+
+	grants, err := recordDeleteEvents(ctx, tx, EventResourceGrants, `request_id IN (SELECT id FROM requests WHERE host_id = ?)`, id)
+	if err != nil {
+		return err
+	}
+	requests, err := recordDeleteEvents(ctx, tx, EventResourceRequests, `host_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	registers, err := recordDeleteEvents(ctx, tx, EventResourceRegisters, `host_id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	if err := recordEvent(ctx, tx, EventResourceHosts, id, EventActionDeleted); err != nil {
+		return err
+	}
 
-	res, err := s.db.ExecContext(ctx, `DELETE FROM hosts WHERE id = ?`, id)
+	res, err := tx.ExecContext(ctx, `DELETE FROM hosts WHERE id = ?`, id)
 	if err != nil {
 		return fmt.Errorf("delete host: %w", err)
 	}
@@ -478,6 +607,18 @@ func (s *Store) DeleteHost(ctx context.Context, id string) error {
 		return ErrHostNotFound
 	}
 
+	if err := recordCascadeAudits(ctx, tx, grants, requests, registers); err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, tx, "delete", EventResourceHosts, id, before); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit host deletion: %w", err)
+	}
+	s.notifyEvents()
+
 	return nil
 }
 
@@ -487,10 +628,10 @@ func (s *Store) UpdateHostLabels(ctx context.Context, id string, labels map[stri
 		return fmt.Errorf("store not initialized")
 	}
 
-	s.logDBOperation("hosts", "update_labels", logrus.Fields{
+	defer s.logDBOperation(ctx, "hosts", "update_labels", logrus.Fields{
 		"host_id": id,
 		"labels":  labels,
-	})
+	})()
 
 	if err := s.ensureHostExists(ctx, id); err != nil {
 		return err
@@ -502,33 +643,58 @@ func (s *Store) UpdateHostLabels(ctx context.Context, id string, labels map[stri
 	}
 	defer rollbackTx(tx, "rollback host labels transaction")
 
+	before, err := auditSnapshot(ctx, tx, EventResourceHosts, id)
+	if err != nil {
+		return err
+	}
+
 	if err := replaceLabels(ctx, tx, hostLabelsTable, "host_id", id, labels); err != nil {
 		return fmt.Errorf("replace host labels: %w", err)
 	}
 
+	if err := recordAudit(ctx, tx, "update_labels", EventResourceHosts, id, before); err != nil {
+		return err
+	}
+
+	if err := recordEvent(ctx, tx, EventResourceHosts, id, EventActionUpdated); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("commit host labels transaction: %w", err)
 	}
+	s.notifyEvents()
 
 	return nil
 }
 
 // Request describes the persisted state for a resource request.
 type Request struct {
-	ID        string            `json:"id"`
-	HostID    string            `json:"host_id"`
-	Payload   map[string]any    `json:"payload,omitempty"`
-	Labels    map[string]string `json:"labels,omitempty"`
-	HasGrant  bool              `json:"has_grant"`
-	CreatedAt time.Time         `json:"created_at"`
-	UpdatedAt time.Time         `json:"updated_at"`
+	ID           string            `json:"id"`
+	HostID       string            `json:"host_id"`
+	Payload      map[string]any    `json:"payload,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	HasGrant     bool              `json:"has_grant"`
+	Status       RequestStatus     `json:"status"`
+	StatusReason string            `json:"status_reason,omitempty"`
+	Revision     int               `json:"revision"`
+	StaleGrant   bool              `json:"stale_grant"`
+	ExpiresAt    *time.Time        `json:"expires_at,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
 }
 
 // RequestListFilters describes optional filters for listing requests.
 type RequestListFilters struct {
 	HasGrant   *bool
+	StaleGrant *bool
+	Statuses   []RequestStatus
 	Labels     map[string]string
 	HostLabels map[string]string
+	// Selector is an additional label selector on the request labels.
+	Selector selector.Selector
+	// Payload filters must all match the request payload.
+	Payload []PayloadFilter
 }
 
 // Register describes the persisted state for register entries.
@@ -537,6 +703,7 @@ type Register struct {
 	HostID    string            `json:"host_id"`
 	Payload   map[string]any    `json:"payload,omitempty"`
 	Labels    map[string]string `json:"labels,omitempty"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
 	CreatedAt time.Time         `json:"created_at"`
 	UpdatedAt time.Time         `json:"updated_at"`
 }
@@ -545,6 +712,10 @@ type Register struct {
 type RegisterListFilters struct {
 	Labels     map[string]string
 	HostLabels map[string]string
+	// Selector is an additional label selector on the register labels.
+	Selector selector.Selector
+	// Payload filters must all match the register payload.
+	Payload []PayloadFilter
 }
 
 // CreateRequest inserts a new request record into storage.
@@ -561,12 +732,13 @@ func (s *Store) CreateRequest(ctx context.Context, req Request) (Request, error)
 
 	req.ID = generateID()
 
-	s.logDBOperation("requests", "create", logrus.Fields{
+	defer s.logDBOperation(ctx, "requests", "create", logrus.Fields{
 		"request_id": req.ID,
 		"host_id":    req.HostID,
 		"payload":    req.Payload,
 		"labels":     req.Labels,
-	})
+		"expires_at": req.ExpiresAt,
+	})()
 
 	payloadValue, err := encodeJSON(req.Payload)
 	if err != nil {
@@ -579,10 +751,14 @@ func (s *Store) CreateRequest(ctx context.Context, req Request) (Request, error)
 	}
 	defer rollbackTx(tx, "rollback create request transaction")
 
+	if err := validateRequestPayload(ctx, tx, req.Labels[RequestTypeLabel], payloadValue); err != nil {
+		return Request{}, err
+	}
+
 	if _, err := tx.ExecContext(ctx, `
-INSERT INTO requests (id, host_id, data)
-VALUES (?, ?, ?)
-`, req.ID, req.HostID, payloadValue); err != nil {
+INSERT INTO requests (id, host_id, data, expires_at)
+VALUES (?, ?, ?, ?)
+`, req.ID, req.HostID, payloadValue, formatOptionalTime(req.ExpiresAt)); err != nil {
 		if isUniqueConstraintError(err) {
 			return Request{}, fmt.Errorf("%w: %w", ErrRequestAlreadyExists, err)
 		}
@@ -593,9 +769,23 @@ VALUES (?, ?, ?)
 		return Request{}, fmt.Errorf("insert request labels: %w", err)
 	}
 
+	req.Revision = 1
+	if err := insertRequestRevision(ctx, tx, req.ID, req.Revision, payloadValue); err != nil {
+		return Request{}, err
+	}
+
+	if err := recordAudit(ctx, tx, "create", EventResourceRequests, req.ID, nil); err != nil {
+		return Request{}, err
+	}
+
+	if err := recordEvent(ctx, tx, EventResourceRequests, req.ID, EventActionCreated); err != nil {
+		return Request{}, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return Request{}, fmt.Errorf("commit request creation: %w", err)
 	}
+	s.notifyEvents()
 
 	return req, nil
 }
@@ -606,14 +796,16 @@ func (s *Store) GetRequest(ctx context.Context, id string) (Request, error) {
 		return Request{}, fmt.Errorf("store not initialized")
 	}
 
-	s.logDBOperation("requests", "get", logrus.Fields{
+	defer s.logDBOperation(ctx, "requests", "get", logrus.Fields{
 		"request_id": id,
-	})
+	})()
 
 	row := s.db.QueryRowContext(ctx, `
 SELECT id, host_id, data,
-       CASE WHEN EXISTS (SELECT 1 FROM grants WHERE request_id = requests.id) THEN 1 ELSE 0 END AS has_grant,
-       created_at, updated_at
+       CASE WHEN `+requestHasGrantCondition+` THEN 1 ELSE 0 END AS has_grant,
+       status, status_reason, revision,
+       CASE WHEN `+requestStaleGrantCondition+` THEN 1 ELSE 0 END AS stale_grant,
+       expires_at, created_at, updated_at
 FROM requests
 WHERE id = ?
 `, id)
@@ -631,8 +823,20 @@ WHERE id = ?
 
 // ListRequests returns stored requests ordered by creation time.
 func (s *Store) ListRequests(ctx context.Context, filters *RequestListFilters) ([]Request, error) {
+	requests, _, err := s.ListRequestsPage(ctx, filters, PageOptions{})
+	return requests, err
+}
+
+// ListRequestsPage returns one page of requests together with the cursor of
+// the next page, which is empty on the last page.
+func (s *Store) ListRequestsPage(ctx context.Context, filters *RequestListFilters, opts PageOptions) ([]Request, string, error) {
 	if s == nil || s.db == nil {
-		return nil, fmt.Errorf("store not initialized")
+		return nil, "", fmt.Errorf("store not initialized")
+	}
+
+	page, err := newPageQuery("requests", opts, resourceSortColumns)
+	if err != nil {
+		return nil, "", err
 	}
 
 	var logFields logrus.Fields
@@ -640,6 +844,12 @@ func (s *Store) ListRequests(ctx context.Context, filters *RequestListFilters) (
 		if filters.HasGrant != nil {
 			logFields = logrus.Fields{"has_grant": *filters.HasGrant}
 		}
+		if filters.StaleGrant != nil {
+			if logFields == nil {
+				logFields = logrus.Fields{}
+			}
+			logFields["stale_grant"] = *filters.StaleGrant
+		}
 		if len(filters.Labels) > 0 {
 			if logFields == nil {
 				logFields = logrus.Fields{}
@@ -652,14 +862,34 @@ func (s *Store) ListRequests(ctx context.Context, filters *RequestListFilters) (
 			}
 			logFields["host_labels"] = filters.HostLabels
 		}
+		if len(filters.Selector) > 0 {
+			if logFields == nil {
+				logFields = logrus.Fields{}
+			}
+			logFields["selector"] = filters.Selector.String()
+		}
+		if len(filters.Payload) > 0 {
+			if logFields == nil {
+				logFields = logrus.Fields{}
+			}
+			logFields["payload"] = filters.Payload
+		}
+	}
+	if opts != (PageOptions{}) {
+		if logFields == nil {
+			logFields = logrus.Fields{}
+		}
+		logFields["page"] = opts
 	}
-	s.logDBOperation("requests", "list", logFields)
+	defer s.logDBOperation(ctx, "requests", "list", logFields)()
 
 	query := strings.Builder{}
 	query.WriteString(`
 SELECT id, host_id, data,
-       CASE WHEN EXISTS (SELECT 1 FROM grants WHERE request_id = requests.id) THEN 1 ELSE 0 END AS has_grant,
-       created_at, updated_at
+       CASE WHEN ` + requestHasGrantCondition + ` THEN 1 ELSE 0 END AS has_grant,
+       status, status_reason, revision,
+       CASE WHEN ` + requestStaleGrantCondition + ` THEN 1 ELSE 0 END AS stale_grant,
+       expires_at, created_at, updated_at` + page.columns() + `
 FROM requests`)
 
 	var args []any
@@ -667,11 +897,26 @@ FROM requests`)
 	if filters != nil {
 		if filters.HasGrant != nil {
 			if *filters.HasGrant {
-				where = append(where, "EXISTS (SELECT 1 FROM grants WHERE request_id = requests.id)")
+				where = append(where, requestHasGrantCondition)
+			} else {
+				where = append(where, "NOT "+requestHasGrantCondition)
+			}
+		}
+		if filters.StaleGrant != nil {
+			if *filters.StaleGrant {
+				where = append(where, requestStaleGrantCondition)
 			} else {
-				where = append(where, "NOT EXISTS (SELECT 1 FROM grants WHERE request_id = requests.id)")
+				where = append(where, "NOT "+requestStaleGrantCondition)
 			}
 		}
+		if len(filters.Statuses) > 0 {
+			placeholders := make([]string, 0, len(filters.Statuses))
+			for _, status := range filters.Statuses {
+				placeholders = append(placeholders, "?")
+				args = append(args, string(status))
+			}
+			where = append(where, "status IN ("+strings.Join(placeholders, ", ")+")")
+		}
 		for key, value := range filters.Labels {
 			where = append(where, "EXISTS (SELECT 1 FROM request_labels WHERE request_id = requests.id AND key = ? AND value = ?)")
 			args = append(args, key, value)
@@ -680,58 +925,75 @@ FROM requests`)
 			where = append(where, "EXISTS (SELECT 1 FROM host_labels WHERE host_id = requests.host_id AND key = ? AND value = ?)")
 			args = append(args, key, value)
 		}
+		conditions, selectorArgs := filters.Selector.SQL(requestLabelsTable, "request_id", "requests.id")
+		where = append(where, conditions...)
+		args = append(args, selectorArgs...)
+		payloadConditions, payloadArgs, err := payloadFilterConditions(s.dialect, "requests.data", filters.Payload)
+		if err != nil {
+			return nil, "", err
+		}
+		where = append(where, payloadConditions...)
+		args = append(args, payloadArgs...)
+	}
+	if condition, cursorArgs := page.where(); condition != "" {
+		where = append(where, condition)
+		args = append(args, cursorArgs...)
 	}
 	if len(where) > 0 {
 		query.WriteString(" WHERE ")
 		query.WriteString(strings.Join(where, " AND "))
 	}
-	query.WriteString(" ORDER BY created_at ASC")
+	query.WriteString(page.suffix())
 
 	rows, err := s.db.QueryContext(ctx, query.String(), args...)
 	if err != nil {
-		return nil, fmt.Errorf("query requests: %w", err)
+		return nil, "", fmt.Errorf("query requests: %w", err)
 	}
 	defer closeRows(rows, "close requests rows")
 
 	requests := make([]Request, 0)
+	var keys []pageKey
 	for rows.Next() {
-		req, err := scanRequest(rows)
+		var key pageKey
+		req, err := scanRequest(key.scanner(rows))
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		requests = append(requests, req)
+		keys = append(keys, key)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("scan requests: %w", err)
+		return nil, "", fmt.Errorf("scan requests: %w", err)
 	}
 
+	requests, next := trimPage(page, requests, keys)
 	for i := range requests {
 		if requests[i].Labels, err = s.loadLabels(ctx, requestLabelsTable, "request_id", requests[i].ID); err != nil {
-			return nil, fmt.Errorf("load request labels: %w", err)
+			return nil, "", fmt.Errorf("load request labels: %w", err)
 		}
 	}
 
-	return requests, nil
+	return requests, next, nil
 }
 
-// CountRequestsByGrantPresence returns the number of requests grouped by whether they already have a grant.
+// CountRequestsByGrantPresence returns the number of requests grouped by whether they hold an approved grant.
 func (s *Store) CountRequestsByGrantPresence(ctx context.Context) (map[string]int64, error) {
 	if s == nil || s.db == nil {
 		return nil, fmt.Errorf("store not initialized")
 	}
 
-	s.logDBOperation("requests", "count_by_grant_presence", nil)
+	defer s.logDBOperation(ctx, "requests", "count_by_grant_presence", nil)()
 
 	var withGrant, withoutGrant int64
 	if err := s.db.QueryRowContext(ctx, `
 SELECT COUNT(*) FROM requests
-WHERE EXISTS (SELECT 1 FROM grants WHERE grants.request_id = requests.id)
+WHERE `+requestHasGrantCondition+`
 `).Scan(&withGrant); err != nil {
 		return nil, fmt.Errorf("count requests with grant: %w", err)
 	}
 	if err := s.db.QueryRowContext(ctx, `
 SELECT COUNT(*) FROM requests
-WHERE NOT EXISTS (SELECT 1 FROM grants WHERE grants.request_id = requests.id)
+WHERE NOT `+requestHasGrantCondition+`
 `).Scan(&withoutGrant); err != nil {
 		return nil, fmt.Errorf("count requests without grant: %w", err)
 	}
@@ -743,39 +1005,147 @@ WHERE NOT EXISTS (SELECT 1 FROM grants WHERE grants.request_id = requests.id)
 	return counts, nil
 }
 
+// UpdateRequestLabels replaces the labels of a request. A new type label
+// requires the current payload to match the schema of that type.
 func (s *Store) UpdateRequestLabels(ctx context.Context, id string, labels map[string]string) error {
+	return s.UpdateRequest(ctx, id, RequestUpdate{Labels: &labels})
+}
+
+// RequestUpdate describes a change to a request. Nil fields are kept, the
+// expiry is only changed when SetExpiry is true.
+type RequestUpdate struct {
+	Payload   *map[string]any
+	Labels    *map[string]string
+	SetExpiry bool
+	ExpiresAt *time.Time
+}
+
+// UpdateRequest applies the payload, label and expiry changes of update in one
+// transaction, so a failing step leaves the request untouched. The payload is checked against the schema of the type the
+// request has after the update, so that type and payload can change together.
+// A changed payload is stored as a new revision, see UpdateRequestPayload.
+func (s *Store) UpdateRequest(ctx context.Context, id string, update RequestUpdate) error {
 	if s == nil || s.db == nil {
 		return fmt.Errorf("store not initialized")
 	}
 
-	fields := logrus.Fields{
-		"request_id": id,
-		"labels":     labels,
+	fields := logrus.Fields{"request_id": id}
+	var payloadValue any
+	if update.Payload != nil {
+		fields["payload"] = *update.Payload
+		var err error
+		if payloadValue, err = encodeJSON(*update.Payload); err != nil {
+			return fmt.Errorf("encode request payload: %w", err)
+		}
 	}
+	if update.Labels != nil {
+		fields["labels"] = *update.Labels
+	}
+	if update.SetExpiry {
+		fields["expires_at"] = update.ExpiresAt
+	}
+	defer s.logDBOperation(ctx, "requests", "update", fields)()
 
-	s.logDBOperation("requests", "update_labels", fields)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin request update transaction: %w", err)
+	}
+	defer rollbackTx(tx, "rollback request update transaction")
 
-	if _, err := s.GetRequest(ctx, id); err != nil {
-		return err
+	var (
+		current  sql.NullString
+		revision int
+	)
+	if err := tx.QueryRowContext(ctx, `SELECT data, revision FROM requests WHERE id = ?`, id).Scan(&current, &revision); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRequestNotFound
+		}
+		return fmt.Errorf("load request: %w", err)
+	}
+	var currentPayload any
+	if current.Valid {
+		currentPayload = current.String
 	}
+	payloadChanged := update.Payload != nil && payloadValue != currentPayload
 
-	tx, err := s.db.BeginTx(ctx, nil)
+	currentType, err := requestTypeOf(ctx, tx, id)
 	if err != nil {
-		return fmt.Errorf("begin request labels transaction: %w", err)
+		return err
+	}
+	requestType := currentType
+	if update.Labels != nil {
+		requestType = (*update.Labels)[RequestTypeLabel]
+	}
+	switch {
+	case payloadChanged:
+		if err := validateRequestPayload(ctx, tx, requestType, payloadValue); err != nil {
+			return err
+		}
+	case requestType != currentType:
+		if err := validateRequestPayload(ctx, tx, requestType, currentPayload); err != nil {
+			return err
+		}
+	}
+	if !payloadChanged && update.Labels == nil && !update.SetExpiry {
+		return nil
 	}
-	defer rollbackTx(tx, "rollback request labels transaction")
 
-	if err := replaceLabels(ctx, tx, requestLabelsTable, "request_id", id, labels); err != nil {
-		return fmt.Errorf("replace request labels: %w", err)
+	if payloadChanged {
+		before, err := auditSnapshot(ctx, tx, EventResourceRequests, id)
+		if err != nil {
+			return err
+		}
+		if err := writeRequestPayload(ctx, tx, id, revision+1, payloadValue); err != nil {
+			return err
+		}
+		if err := setUpdatedAt(ctx, tx, "requests", "id", id); err != nil {
+			return fmt.Errorf("refresh request timestamp: %w", err)
+		}
+		if err := recordAudit(ctx, tx, "update_payload", EventResourceRequests, id, before); err != nil {
+			return err
+		}
 	}
 
-	if err := setUpdatedAt(ctx, tx, "requests", "id", id); err != nil {
-		return fmt.Errorf("refresh request timestamp: %w", err)
+	if update.Labels != nil {
+		before, err := auditSnapshot(ctx, tx, EventResourceRequests, id)
+		if err != nil {
+			return err
+		}
+		if err := replaceLabels(ctx, tx, requestLabelsTable, "request_id", id, *update.Labels); err != nil {
+			return fmt.Errorf("replace request labels: %w", err)
+		}
+		if err := setUpdatedAt(ctx, tx, "requests", "id", id); err != nil {
+			return fmt.Errorf("refresh request timestamp: %w", err)
+		}
+		if err := recordAudit(ctx, tx, "update_labels", EventResourceRequests, id, before); err != nil {
+			return err
+		}
+	}
+
+	if update.SetExpiry {
+		before, err := auditSnapshot(ctx, tx, EventResourceRequests, id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE requests SET expires_at = ? WHERE id = ?`, formatOptionalTime(update.ExpiresAt), id); err != nil {
+			return fmt.Errorf("update requests expiry: %w", err)
+		}
+		if err := setUpdatedAt(ctx, tx, "requests", "id", id); err != nil {
+			return fmt.Errorf("refresh request timestamp: %w", err)
+		}
+		if err := recordAudit(ctx, tx, "set_expiry", EventResourceRequests, id, before); err != nil {
+			return err
+		}
+	}
+
+	if err := recordEvent(ctx, tx, EventResourceRequests, id, EventActionUpdated); err != nil {
+		return err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit request labels update: %w", err)
+		return fmt.Errorf("commit request update: %w", err)
 	}
+	s.notifyEvents()
 
 	return nil
 }
@@ -786,11 +1156,31 @@ func (s *Store) DeleteRequest(ctx context.Context, id string) error {
 		return fmt.Errorf("store not initialized")
 	}
 
-	s.logDBOperation("requests", "delete", logrus.Fields{
+	defer s.logDBOperation(ctx, "requests", "delete", logrus.Fields{
 		"request_id": id,
-	})
+	})()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin delete request transaction: %w", err)
+	}
+	defer rollbackTx(tx, "rollback delete request transaction")
+
+	before, err := auditSnapshot(ctx, tx, EventResourceRequests, id)
+	if err != nil {
+		return err
+	}
+
+	grants, err := recordDeleteEvents(ctx, tx, EventResourceGrants, `request_id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	if err := recordEvent(ctx, tx, EventResourceRequests, id, EventActionDeleted); err != nil {
+		return err
+	}
 
-	res, err := s.db.ExecContext(ctx, `DELETE FROM requests WHERE id = ?`, id)
+	res, err := tx.ExecContext(ctx, `DELETE FROM requests WHERE id = ?`, id)
 	if err != nil {
 		return fmt.Errorf("delete request: %w", err)
 	}
@@ -803,6 +1193,18 @@ func (s *Store) DeleteRequest(ctx context.Context, id string) error {
 		return ErrRequestNotFound
 	}
 
+	if err := recordCascadeAudits(ctx, tx, grants); err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, tx, "delete", EventResourceRequests, id, before); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit request deletion: %w", err)
+	}
+	s.notifyEvents()
+
 	return nil
 }
 
@@ -820,12 +1222,13 @@ func (s *Store) CreateRegister(ctx context.Context, reg Register) (Register, err
 
 	reg.ID = generateID()
 
-	s.logDBOperation("registers", "create", logrus.Fields{
+	defer s.logDBOperation(ctx, "registers", "create", logrus.Fields{
 		"register_id": reg.ID,
 		"host_id":     reg.HostID,
 		"payload":     reg.Payload,
 		"labels":      reg.Labels,
-	})
+		"expires_at":  reg.ExpiresAt,
+	})()
 
 	payloadValue, err := encodeJSON(reg.Payload)
 	if err != nil {
@@ -839,9 +1242,9 @@ func (s *Store) CreateRegister(ctx context.Context, reg Register) (Register, err
 	defer rollbackTx(tx, "rollback create register transaction")
 
 	if _, err := tx.ExecContext(ctx, `
-INSERT INTO registers (id, host_id, data)
-VALUES (?, ?, ?)
-`, reg.ID, reg.HostID, payloadValue); err != nil {
+INSERT INTO registers (id, host_id, data, expires_at)
+VALUES (?, ?, ?, ?)
+`, reg.ID, reg.HostID, payloadValue, formatOptionalTime(reg.ExpiresAt)); err != nil {
 		if isUniqueConstraintError(err) {
 			return Register{}, fmt.Errorf("%w: %w", ErrRegisterAlreadyExists, err)
 		}
@@ -852,9 +1255,18 @@ VALUES (?, ?, ?)
 		return Register{}, fmt.Errorf("insert register labels: %w", err)
 	}
 
+	if err := recordAudit(ctx, tx, "create", EventResourceRegisters, reg.ID, nil); err != nil {
+		return Register{}, err
+	}
+
+	if err := recordEvent(ctx, tx, EventResourceRegisters, reg.ID, EventActionCreated); err != nil {
+		return Register{}, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return Register{}, fmt.Errorf("commit register creation: %w", err)
 	}
+	s.notifyEvents()
 
 	return reg, nil
 }
@@ -865,12 +1277,12 @@ func (s *Store) GetRegister(ctx context.Context, id string) (Register, error) {
 		return Register{}, fmt.Errorf("store not initialized")
 	}
 
-	s.logDBOperation("registers", "get", logrus.Fields{
+	defer s.logDBOperation(ctx, "registers", "get", logrus.Fields{
 		"register_id": id,
-	})
+	})()
 
 	row := s.db.QueryRowContext(ctx, `
-SELECT id, host_id, data, created_at, updated_at
+SELECT id, host_id, data, expires_at, created_at, updated_at
 FROM registers
 WHERE id = ?
 `, id)
@@ -888,8 +1300,20 @@ WHERE id = ?
 
 // ListRegisters returns stored registers ordered by creation time.
 func (s *Store) ListRegisters(ctx context.Context, filters *RegisterListFilters) ([]Register, error) {
+	registers, _, err := s.ListRegistersPage(ctx, filters, PageOptions{})
+	return registers, err
+}
+
+// ListRegistersPage returns one page of registers together with the cursor of
+// the next page, which is empty on the last page.
+func (s *Store) ListRegistersPage(ctx context.Context, filters *RegisterListFilters, opts PageOptions) ([]Register, string, error) {
 	if s == nil || s.db == nil {
-		return nil, fmt.Errorf("store not initialized")
+		return nil, "", fmt.Errorf("store not initialized")
+	}
+
+	page, err := newPageQuery("registers", opts, resourceSortColumns)
+	if err != nil {
+		return nil, "", err
 	}
 
 	var logFields logrus.Fields
@@ -903,12 +1327,30 @@ func (s *Store) ListRegisters(ctx context.Context, filters *RegisterListFilters)
 			}
 			logFields["host_labels"] = filters.HostLabels
 		}
+		if len(filters.Selector) > 0 {
+			if logFields == nil {
+				logFields = logrus.Fields{}
+			}
+			logFields["selector"] = filters.Selector.String()
+		}
+		if len(filters.Payload) > 0 {
+			if logFields == nil {
+				logFields = logrus.Fields{}
+			}
+			logFields["payload"] = filters.Payload
+		}
+	}
+	if opts != (PageOptions{}) {
+		if logFields == nil {
+			logFields = logrus.Fields{}
+		}
+		logFields["page"] = opts
 	}
-	s.logDBOperation("registers", "list", logFields)
+	defer s.logDBOperation(ctx, "registers", "list", logFields)()
 
 	query := strings.Builder{}
 	query.WriteString(`
-SELECT id, host_id, data, created_at, updated_at
+SELECT id, host_id, data, expires_at, created_at, updated_at` + page.columns() + `
 FROM registers`)
 
 	var args []any
@@ -922,37 +1364,54 @@ FROM registers`)
 			where = append(where, "EXISTS (SELECT 1 FROM host_labels WHERE host_id = registers.host_id AND key = ? AND value = ?)")
 			args = append(args, key, value)
 		}
+		conditions, selectorArgs := filters.Selector.SQL(registerLabelsTable, "register_id", "registers.id")
+		where = append(where, conditions...)
+		args = append(args, selectorArgs...)
+		payloadConditions, payloadArgs, err := payloadFilterConditions(s.dialect, "registers.data", filters.Payload)
+		if err != nil {
+			return nil, "", err
+		}
+		where = append(where, payloadConditions...)
+		args = append(args, payloadArgs...)
+	}
+	if condition, cursorArgs := page.where(); condition != "" {
+		where = append(where, condition)
+		args = append(args, cursorArgs...)
 	}
 	if len(where) > 0 {
 		query.WriteString(" WHERE ")
 		query.WriteString(strings.Join(where, " AND "))
 	}
-	query.WriteString(" ORDER BY created_at ASC")
+	query.WriteString(page.suffix())
 
 	rows, err := s.db.QueryContext(ctx, query.String(), args...)
 	if err != nil {
-		return nil, fmt.Errorf("query registers: %w", err)
+		return nil, "", fmt.Errorf("query registers: %w", err)
 	}
 	defer closeRows(rows, "close registers rows")
 
 	registers := make([]Register, 0)
+	var keys []pageKey
 	for rows.Next() {
-		reg, err := scanRegister(rows)
+		var key pageKey
+		reg, err := scanRegister(key.scanner(rows))
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		registers = append(registers, reg)
+		keys = append(keys, key)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("scan registers: %w", err)
+		return nil, "", fmt.Errorf("scan registers: %w", err)
 	}
 
+	registers, next := trimPage(page, registers, keys)
 	for i := range registers {
 		if registers[i].Labels, err = s.loadLabels(ctx, registerLabelsTable, "register_id", registers[i].ID); err != nil {
-			return nil, fmt.Errorf("load register labels: %w", err)
+			return nil, "", fmt.Errorf("load register labels: %w", err)
 		}
 	}
-	return registers, nil
+	return registers, next, nil
 }
 
 // UpdateRegisterLabels replaces the labels stored for a register record.
@@ -965,7 +1424,7 @@ func (s *Store) UpdateRegisterLabels(ctx context.Context, id string, labels map[
 		"register_id": id,
 		"labels":      labels,
 	}
-	s.logDBOperation("registers", "update_labels", fields)
+	defer s.logDBOperation(ctx, "registers", "update_labels", fields)()
 	if _, err := s.GetRegister(ctx, id); err != nil {
 		return err
 	}
@@ -976,6 +1435,11 @@ func (s *Store) UpdateRegisterLabels(ctx context.Context, id string, labels map[
 	}
 	defer rollbackTx(tx, "rollback register labels transaction")
 
+	before, err := auditSnapshot(ctx, tx, EventResourceRegisters, id)
+	if err != nil {
+		return err
+	}
+
 	if err := replaceLabels(ctx, tx, registerLabelsTable, "register_id", id, labels); err != nil {
 		return fmt.Errorf("replace register labels: %w", err)
 	}
@@ -984,9 +1448,18 @@ func (s *Store) UpdateRegisterLabels(ctx context.Context, id string, labels map[
 		return fmt.Errorf("refresh register timestamp: %w", err)
 	}
 
+	if err := recordAudit(ctx, tx, "update_labels", EventResourceRegisters, id, before); err != nil {
+		return err
+	}
+
+	if err := recordEvent(ctx, tx, EventResourceRegisters, id, EventActionUpdated); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("commit register labels update: %w", err)
 	}
+	s.notifyEvents()
 
 	return nil
 }
@@ -997,11 +1470,26 @@ func (s *Store) DeleteRegister(ctx context.Context, id string) error {
 		return fmt.Errorf("store not initialized")
 	}
 
-	s.logDBOperation("registers", "delete", logrus.Fields{
+	defer s.logDBOperation(ctx, "registers", "delete", logrus.Fields{
 		"register_id": id,
-	})
+	})()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin delete register transaction: %w", err)
+	}
+	defer rollbackTx(tx, "rollback delete register transaction")
+
+	before, err := auditSnapshot(ctx, tx, EventResourceRegisters, id)
+	if err != nil {
+		return err
+	}
+
+	if err := recordEvent(ctx, tx, EventResourceRegisters, id, EventActionDeleted); err != nil {
+		return err
+	}
 
-	res, err := s.db.ExecContext(ctx, `DELETE FROM registers WHERE id = ?`, id)
+	res, err := tx.ExecContext(ctx, `DELETE FROM registers WHERE id = ?`, id)
 	if err != nil {
 		return fmt.Errorf("delete register: %w", err)
 	}
@@ -1014,6 +1502,15 @@ func (s *Store) DeleteRegister(ctx context.Context, id string) error {
 		return ErrRegisterNotFound
 	}
 
+	if err := recordAudit(ctx, tx, "delete", EventResourceRegisters, id, before); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit register deletion: %w", err)
+	}
+	s.notifyEvents()
+
 	return nil
 }
 
@@ -1022,7 +1519,7 @@ func (s *Store) CountRegisters(ctx context.Context) (map[string]int64, error) {
 	if s == nil || s.db == nil {
 		return nil, fmt.Errorf("store not initialized")
 	}
-	s.logDBOperation("registers", "count", nil)
+	defer s.logDBOperation(ctx, "registers", "count", nil)()
 	var total int64
 	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM registers`).Scan(&total); err != nil {
 		return nil, fmt.Errorf("count registers: %w", err)
@@ -1032,11 +1529,19 @@ func (s *Store) CountRegisters(ctx context.Context) (map[string]int64, error) {
 
 // Grant models payloads returned for resource requests.
 type Grant struct {
-	ID        string    `json:"id"`
-	RequestID string    `json:"request_id"`
-	Payload   []byte    `json:"payload"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              string            `json:"id"`
+	RequestID       string            `json:"request_id"`
+	Payload         []byte            `json:"payload"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	RequestRevision int               `json:"request_revision"`
+	ExpiresAt       *time.Time        `json:"expires_at,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// GrantListFilters describes optional filters for listing grants.
+type GrantListFilters struct {
+	Labels map[string]string
 }
 
 // CreateGrant stores a new grant with its payload.
@@ -1053,22 +1558,62 @@ func (s *Store) CreateGrant(ctx context.Context, grant Grant) (Grant, error) {
 
 	grant.ID = generateID()
 
-	s.logDBOperation("grants", "create", logrus.Fields{
+	defer s.logDBOperation(ctx, "grants", "create", logrus.Fields{
 		"grant_id":     grant.ID,
 		"request_id":   grant.RequestID,
 		"payload_size": len(grant.Payload),
-	})
+		"labels":       grant.Labels,
+		"expires_at":   grant.ExpiresAt,
+	})()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Grant{}, fmt.Errorf("begin grant transaction: %w", err)
+	}
+	defer rollbackTx(tx, "rollback create grant transaction")
 
-	if _, err := s.db.ExecContext(ctx, `
-INSERT INTO grants (id, request_id, payload)
-VALUES (?, ?, ?)
-`, grant.ID, grant.RequestID, grant.Payload); err != nil {
+	if err := tx.QueryRowContext(ctx, `SELECT revision FROM requests WHERE id = ?`, grant.RequestID).Scan(&grant.RequestRevision); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Grant{}, ErrReferencedRequestNotFound
+		}
+		return Grant{}, fmt.Errorf("load request revision: %w", err)
+	}
+
+	if err := validateGrantPayload(ctx, tx, grant.RequestID, grant.Payload); err != nil {
+		return Grant{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO grants (id, request_id, payload, request_revision, expires_at)
+VALUES (?, ?, ?, ?, ?)
+`, grant.ID, grant.RequestID, grant.Payload, grant.RequestRevision, formatOptionalTime(grant.ExpiresAt)); err != nil {
 		if isUniqueConstraintError(err) {
 			return Grant{}, fmt.Errorf("%w: %w", ErrGrantAlreadyExists, err)
 		}
 		return Grant{}, fmt.Errorf("insert grant: %w", err)
 	}
 
+	if err := insertLabels(ctx, tx, grantLabelsTable, "grant_id", grant.ID, grant.Labels); err != nil {
+		return Grant{}, fmt.Errorf("insert grant labels: %w", err)
+	}
+
+	if err := recordAudit(ctx, tx, "create", EventResourceGrants, grant.ID, nil); err != nil {
+		return Grant{}, err
+	}
+
+	if err := recordEvent(ctx, tx, EventResourceGrants, grant.ID, EventActionCreated); err != nil {
+		return Grant{}, err
+	}
+
+	if err := transitionRequestStatus(ctx, tx, grant.RequestID, RequestStatusApproved, "", grantTransitions); err != nil {
+		return Grant{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Grant{}, fmt.Errorf("commit grant creation: %w", err)
+	}
+	s.notifyEvents()
+
 	return grant, nil
 }
 
@@ -1078,49 +1623,157 @@ func (s *Store) GetGrant(ctx context.Context, id string) (Grant, error) {
 		return Grant{}, fmt.Errorf("store not initialized")
 	}
 
-	s.logDBOperation("grants", "get", logrus.Fields{
+	defer s.logDBOperation(ctx, "grants", "get", logrus.Fields{
 		"grant_id": id,
-	})
+	})()
 
 	row := s.db.QueryRowContext(ctx, `
-SELECT id, request_id, payload, created_at, updated_at
+SELECT id, request_id, payload, request_revision, expires_at, created_at, updated_at
 FROM grants
 WHERE id = ?
 `, id)
-	return scanGrant(row)
+	grant, err := scanGrant(row)
+	if err != nil {
+		return Grant{}, err
+	}
+	if grant.Labels, err = s.loadLabels(ctx, grantLabelsTable, "grant_id", grant.ID); err != nil {
+		return Grant{}, fmt.Errorf("load grant labels: %w", err)
+	}
+	return grant, nil
+}
+
+// ListGrants returns stored grants ordered by creation.
+func (s *Store) ListGrants(ctx context.Context, filters *GrantListFilters) ([]Grant, error) {
+	grants, _, err := s.ListGrantsPage(ctx, filters, PageOptions{})
+	return grants, err
 }
 
-// ListGrants returns every stored grant ordered by creation.
-func (s *Store) ListGrants(ctx context.Context) ([]Grant, error) {
+// ListGrantsPage returns one page of grants together with the cursor of the
+// next page, which is empty on the last page.
+func (s *Store) ListGrantsPage(ctx context.Context, filters *GrantListFilters, opts PageOptions) ([]Grant, string, error) {
 	if s == nil || s.db == nil {
-		return nil, fmt.Errorf("store not initialized")
+		return nil, "", fmt.Errorf("store not initialized")
 	}
 
-	s.logDBOperation("grants", "list", nil)
+	page, err := newPageQuery("grants", opts, resourceSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
 
-	rows, err := s.db.QueryContext(ctx, `
-SELECT id, request_id, payload, created_at, updated_at
-FROM grants
-ORDER BY created_at ASC
-`)
+	var logFields logrus.Fields
+	if filters != nil && len(filters.Labels) > 0 {
+		logFields = logrus.Fields{"labels": filters.Labels}
+	}
+	if opts != (PageOptions{}) {
+		if logFields == nil {
+			logFields = logrus.Fields{}
+		}
+		logFields["page"] = opts
+	}
+	defer s.logDBOperation(ctx, "grants", "list", logFields)()
+
+	query := strings.Builder{}
+	query.WriteString(`
+SELECT id, request_id, payload, request_revision, expires_at, created_at, updated_at` + page.columns() + `
+FROM grants`)
+
+	var args []any
+	var where []string
+	if filters != nil {
+		for key, value := range filters.Labels {
+			where = append(where, "EXISTS (SELECT 1 FROM grant_labels WHERE grant_id = grants.id AND key = ? AND value = ?)")
+			args = append(args, key, value)
+		}
+	}
+	if condition, cursorArgs := page.where(); condition != "" {
+		where = append(where, condition)
+		args = append(args, cursorArgs...)
+	}
+	if len(where) > 0 {
+		query.WriteString(" WHERE ")
+		query.WriteString(strings.Join(where, " AND "))
+	}
+	query.WriteString(page.suffix())
+
+	rows, err := s.db.QueryContext(ctx, query.String(), args...)
 	if err != nil {
-		return nil, fmt.Errorf("query grants: %w", err)
+		return nil, "", fmt.Errorf("query grants: %w", err)
 	}
 	defer closeRows(rows, "close grants rows")
 
 	grants := make([]Grant, 0)
+	var keys []pageKey
 	for rows.Next() {
-		grant, err := scanGrant(rows)
+		var key pageKey
+		grant, err := scanGrant(key.scanner(rows))
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		grants = append(grants, grant)
+		keys = append(keys, key)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("scan grants: %w", err)
+		return nil, "", fmt.Errorf("scan grants: %w", err)
+	}
+
+	grants, next := trimPage(page, grants, keys)
+	for i := range grants {
+		if grants[i].Labels, err = s.loadLabels(ctx, grantLabelsTable, "grant_id", grants[i].ID); err != nil {
+			return nil, "", fmt.Errorf("load grant labels: %w", err)
+		}
+	}
+
+	return grants, next, nil
+}
+
+// UpdateGrantLabels replaces the labels stored for a grant.
+func (s *Store) UpdateGrantLabels(ctx context.Context, id string, labels map[string]string) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("store not initialized")
+	}
+
+	fields := logrus.Fields{
+		"grant_id": id,
+		"labels":   labels,
+	}
+	defer s.logDBOperation(ctx, "grants", "update_labels", fields)()
+	if _, err := s.GetGrant(ctx, id); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin grant labels transaction: %w", err)
 	}
+	defer rollbackTx(tx, "rollback grant labels transaction")
 
-	return grants, nil
+	before, err := auditSnapshot(ctx, tx, EventResourceGrants, id)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceLabels(ctx, tx, grantLabelsTable, "grant_id", id, labels); err != nil {
+		return fmt.Errorf("replace grant labels: %w", err)
+	}
+
+	if err := setUpdatedAt(ctx, tx, "grants", "id", id); err != nil {
+		return fmt.Errorf("refresh grant timestamp: %w", err)
+	}
+
+	if err := recordAudit(ctx, tx, "update_labels", EventResourceGrants, id, before); err != nil {
+		return err
+	}
+
+	if err := recordEvent(ctx, tx, EventResourceGrants, id, EventActionUpdated); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit grant labels update: %w", err)
+	}
+	s.notifyEvents()
+
+	return nil
 }
 
 // CountGrants returns the total number of grants.
@@ -1129,7 +1782,7 @@ func (s *Store) CountGrants(ctx context.Context) (map[string]int64, error) {
 		return nil, fmt.Errorf("store not initialized")
 	}
 
-	s.logDBOperation("grants", "count", nil)
+	defer s.logDBOperation(ctx, "grants", "count", nil)()
 
 	var total int64
 	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM grants`).Scan(&total); err != nil {
@@ -1144,12 +1797,12 @@ func (s *Store) GetLatestGrantForRequest(ctx context.Context, requestID string)
 		return Grant{}, false, fmt.Errorf("store not initialized")
 	}
 
-	s.logDBOperation("grants", "get_latest_for_request", logrus.Fields{
+	defer s.logDBOperation(ctx, "grants", "get_latest_for_request", logrus.Fields{
 		"request_id": requestID,
-	})
+	})()
 
 	row := s.db.QueryRowContext(ctx, `
-SELECT id, request_id, payload, created_at, updated_at
+SELECT id, request_id, payload, request_revision, expires_at, created_at, updated_at
 FROM grants
 WHERE request_id = ?
 ORDER BY created_at DESC
@@ -1163,25 +1816,63 @@ LIMIT 1
 		}
 		return Grant{}, false, fmt.Errorf("get latest grant for request: %w", err)
 	}
+	if grant.Labels, err = s.loadLabels(ctx, grantLabelsTable, "grant_id", grant.ID); err != nil {
+		return Grant{}, false, fmt.Errorf("load grant labels: %w", err)
+	}
 
 	return grant, true, nil
 }
 
-// DeleteGrant removes a grant record.
+// DeleteGrant removes a grant record and reopens its request.
 func (s *Store) DeleteGrant(ctx context.Context, id string) error {
+	return s.removeGrant(ctx, id, "delete", RequestStatusPending, "", grantRemovalTransitions)
+}
+
+// removeGrant deletes a grant and moves its request to the given status if
+// transitions allows it. Otherwise the request status is left alone.
+func (s *Store) removeGrant(ctx context.Context, id, operation string, status RequestStatus, reason string, transitions map[RequestStatus][]RequestStatus) error {
 	if s == nil || s.db == nil {
 		return fmt.Errorf("store not initialized")
 	}
 
-	s.logDBOperation("grants", "delete", logrus.Fields{
+	defer s.logDBOperation(ctx, "grants", operation, logrus.Fields{
 		"grant_id": id,
-	})
+	})()
 
-	res, err := s.db.ExecContext(ctx, `DELETE FROM grants WHERE id = ?`, id)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("delete grant: %w", err)
+		return fmt.Errorf("begin %s grant transaction: %w", operation, err)
+	}
+	defer rollbackTx(tx, "rollback "+operation+" grant transaction")
+
+	var requestID, requestStatus string
+	if err := tx.QueryRowContext(ctx, `
+SELECT grants.request_id, requests.status
+FROM grants
+JOIN requests ON requests.id = grants.request_id
+WHERE grants.id = ?
+`, id).Scan(&requestID, &requestStatus); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrGrantNotFound
+		}
+		return fmt.Errorf("lookup grant: %w", err)
+	}
+
+	before, err := auditSnapshot(ctx, tx, EventResourceGrants, id)
+	if err != nil {
+		return err
 	}
 
+	if err := recordEvent(ctx, tx, EventResourceGrants, id, EventActionDeleted); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM grants WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete grant: %w", err)
+	}
+	// Another server sharing the database may have removed the grant since
+	// the lookup, for example when both reap it at once.
 	count, err := res.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("delete grant rows affected: %w", err)
@@ -1190,6 +1881,21 @@ func (s *Store) DeleteGrant(ctx context.Context, id string) error {
 		return ErrGrantNotFound
 	}
 
+	if err := recordAudit(ctx, tx, operation, EventResourceGrants, id, before); err != nil {
+		return err
+	}
+
+	if transitionAllowed(transitions, RequestStatus(requestStatus), status) {
+		if err := setRequestStatus(ctx, tx, requestID, status, reason); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit grant deletion: %w", err)
+	}
+	s.notifyEvents()
+
 	return nil
 }
 
@@ -1199,11 +1905,12 @@ type rowScanner interface {
 
 func scanHost(scanner rowScanner) (Host, error) {
 	var (
-		host      Host
-		createdAt string
+		host       Host
+		lastSeenAt sql.NullString
+		createdAt  string
 	)
 
-	if err := scanner.Scan(&host.ID, &createdAt); err != nil {
+	if err := scanner.Scan(&host.ID, &lastSeenAt, &createdAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return Host{}, ErrHostNotFound
 		}
@@ -1216,6 +1923,10 @@ func scanHost(scanner rowScanner) (Host, error) {
 	}
 	host.CreatedAt = t
 
+	if host.LastSeenAt, err = parseOptionalTime(lastSeenAt); err != nil {
+		return Host{}, fmt.Errorf("parse host last_seen_at: %w", err)
+	}
+
 	return host, nil
 }
 
@@ -1224,11 +1935,15 @@ func scanRequest(scanner rowScanner) (Request, error) {
 		req          Request
 		payloadValue sql.NullString
 		hasGrant     sql.NullInt64
+		status       string
+		reason       sql.NullString
+		staleGrant   sql.NullInt64
+		expiresAt    sql.NullString
 		createdAt    string
 		updatedAt    string
 	)
 
-	if err := scanner.Scan(&req.ID, &req.HostID, &payloadValue, &hasGrant, &createdAt, &updatedAt); err != nil {
+	if err := scanner.Scan(&req.ID, &req.HostID, &payloadValue, &hasGrant, &status, &reason, &req.Revision, &staleGrant, &expiresAt, &createdAt, &updatedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return Request{}, ErrRequestNotFound
 		}
@@ -1247,8 +1962,14 @@ func scanRequest(scanner rowScanner) (Request, error) {
 	if req.UpdatedAt, err = parseCreatedAt(updatedAt); err != nil {
 		return Request{}, err
 	}
+	if req.ExpiresAt, err = parseOptionalTime(expiresAt); err != nil {
+		return Request{}, err
+	}
 
 	req.HasGrant = hasGrant.Valid && hasGrant.Int64 > 0
+	req.Status = RequestStatus(status)
+	req.StatusReason = reason.String
+	req.StaleGrant = staleGrant.Valid && staleGrant.Int64 > 0
 
 	return req, nil
 }
@@ -1257,11 +1978,12 @@ func scanRegister(scanner rowScanner) (Register, error) {
 	var (
 		reg          Register
 		payloadValue sql.NullString
+		expiresAt    sql.NullString
 		createdAt    string
 		updatedAt    string
 	)
 
-	if err := scanner.Scan(&reg.ID, &reg.HostID, &payloadValue, &createdAt, &updatedAt); err != nil {
+	if err := scanner.Scan(&reg.ID, &reg.HostID, &payloadValue, &expiresAt, &createdAt, &updatedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return Register{}, ErrRegisterNotFound
 		}
@@ -1280,6 +2002,9 @@ func scanRegister(scanner rowScanner) (Register, error) {
 	if reg.UpdatedAt, err = parseCreatedAt(updatedAt); err != nil {
 		return Register{}, err
 	}
+	if reg.ExpiresAt, err = parseOptionalTime(expiresAt); err != nil {
+		return Register{}, err
+	}
 
 	return reg, nil
 }
@@ -1288,11 +2013,12 @@ func scanGrant(scanner rowScanner) (Grant, error) {
 	var (
 		grant     Grant
 		payload   []byte
+		expiresAt sql.NullString
 		createdAt string
 		updatedAt string
 	)
 
-	if err := scanner.Scan(&grant.ID, &grant.RequestID, &payload, &createdAt, &updatedAt); err != nil {
+	if err := scanner.Scan(&grant.ID, &grant.RequestID, &payload, &grant.RequestRevision, &expiresAt, &createdAt, &updatedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return Grant{}, ErrGrantNotFound
 		}
@@ -1308,6 +2034,9 @@ func scanGrant(scanner rowScanner) (Grant, error) {
 	if grant.UpdatedAt, err = parseCreatedAt(updatedAt); err != nil {
 		return Grant{}, err
 	}
+	if grant.ExpiresAt, err = parseOptionalTime(expiresAt); err != nil {
+		return Grant{}, err
+	}
 
 	return grant, nil
 }
@@ -1330,6 +2059,31 @@ func parseCreatedAt(value string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
 }
 
+func parseOptionalTime(value sql.NullString) (*time.Time, error) {
+	if !value.Valid || value.String == "" {
+		return nil, nil
+	}
+	ts, err := parseCreatedAt(value.String)
+	if err != nil {
+		return nil, err
+	}
+	return &ts, nil
+}
+
+// currentTimestamp returns the current time in the stored timestamp format.
+// Timestamps are computed here rather than in SQL so that every database
+// stores them alike.
+func currentTimestamp() string {
+	return time.Now().UTC().Format(timestampLayout)
+}
+
+func formatOptionalTime(value *time.Time) any {
+	if value == nil {
+		return nil
+	}
+	return value.UTC().Format(timestampLayout)
+}
+
 func generateID() string {
 	return uuid.NewString()
 }
@@ -1384,8 +2138,8 @@ func replaceLabels(ctx context.Context, tx *sql.Tx, table, idColumn, id string,
 }
 
 func setUpdatedAt(ctx context.Context, tx *sql.Tx, table, idColumn, id string) error {
-	stmt := fmt.Sprintf(`UPDATE %s SET updated_at = strftime('%%Y-%%m-%%d %%H:%%M:%%f', 'now') WHERE %s = ?`, table, idColumn)
-	if _, err := tx.ExecContext(ctx, stmt, id); err != nil {
+	stmt := fmt.Sprintf(`UPDATE %s SET updated_at = ? WHERE %s = ?`, table, idColumn)
+	if _, err := tx.ExecContext(ctx, stmt, currentTimestamp(), id); err != nil {
 		return fmt.Errorf("update %s timestamp: %w", table, err)
 	}
 	return nil
@@ -1419,5 +2173,5 @@ func (s *Store) loadLabels(ctx context.Context, table, idColumn, id string) (map
 }
 
 func isUniqueConstraintError(err error) bool {
-	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+	return strings.Contains(err.Error(), "UNIQUE constraint failed") || isPostgresError(err, pgUniqueViolation)
 }
@@ -0,0 +1,351 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TokenScope describes what a token may do inside a namespace.
+type TokenScope string
+
+const (
+	// TokenScopeRead allows read-only access to a namespace.
+	TokenScopeRead TokenScope = "read"
+	// TokenScopeWrite allows read and write access to a namespace.
+	TokenScopeWrite TokenScope = "write"
+)
+
+// TokenNamespaceWildcard binds a token scope to every namespace.
+const TokenNamespaceWildcard = "*"
+
+var (
+	// ErrTokenNotFound is returned when a token cannot be located.
+	ErrTokenNotFound = errors.New("token not found")
+	// ErrInvalidTokenScope is returned when a scope value is not recognized.
+	ErrInvalidTokenScope = errors.New("invalid token scope")
+)
+
+// Token describes an API token without its secret.
+type Token struct {
+	ID        string                `json:"id"`
+	Name      string                `json:"name"`
+	Scopes    map[string]TokenScope `json:"scopes"`
+	CreatedAt time.Time             `json:"created_at"`
+	RevokedAt *time.Time            `json:"revoked_at,omitempty"`
+}
+
+// ParseTokenScope validates and normalizes a scope value.
+func ParseTokenScope(value string) (TokenScope, error) {
+	switch scope := TokenScope(strings.ToLower(strings.TrimSpace(value))); scope {
+	case TokenScopeRead, TokenScopeWrite:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidTokenScope, value)
+	}
+}
+
+// Revoked reports whether the token was revoked.
+func (t Token) Revoked() bool {
+	return t.RevokedAt != nil
+}
+
+// Allows reports whether the token grants scope in namespace. Write access implies read access.
+func (t Token) Allows(namespace string, scope TokenScope) bool {
+	if t.Revoked() {
+		return false
+	}
+	for _, key := range []string{namespace, TokenNamespaceWildcard} {
+		granted, ok := t.Scopes[key]
+		if !ok {
+			continue
+		}
+		if granted == TokenScopeWrite || granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Namespaces returns the namespaces bound to the token in sorted order.
+func (t Token) Namespaces() []string {
+	namespaces := make([]string, 0, len(t.Scopes))
+	for namespace := range t.Scopes {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+const (
+	apiTokensTableStatement = `
+CREATE TABLE IF NOT EXISTS api_tokens (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	revoked_at DATETIME
+)`
+	apiTokenScopesTableStatement = `
+CREATE TABLE IF NOT EXISTS api_token_scopes (
+	token_id TEXT NOT NULL,
+	namespace TEXT NOT NULL,
+	scope TEXT NOT NULL CHECK(scope IN ('read', 'write')),
+	PRIMARY KEY (token_id, namespace),
+	FOREIGN KEY(token_id) REFERENCES api_tokens(id) ON DELETE CASCADE
+)`
+)
+
+// tokenMigrations lists the token database schema changes in order.
+var tokenMigrations = []migration{
+	{1, "api tokens", func(ctx context.Context, tx *sql.Tx) error {
+		for _, stmt := range []string{apiTokensTableStatement, apiTokenScopesTableStatement} {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+}
+
+// TokenStore persists API tokens in a database shared by all namespaces.
+// Only token hashes are stored; the plain secret is never written to disk.
+type TokenStore struct {
+	db *sql.DB
+}
+
+// NewTokenStore opens or creates the token database at path and ensures its schema.
+func NewTokenStore(ctx context.Context, path string) (*TokenStore, error) {
+	db, err := openSQLite(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return newTokenStore(ctx, db, dialectSQLite)
+}
+
+func newTokenStore(ctx context.Context, db *sql.DB, d dialect) (*TokenStore, error) {
+	migrations := tokenMigrations
+	if d == dialectPostgres {
+		migrations = postgresTokenMigrations
+	}
+	if err := applyMigrations(ctx, db, d, "(tokens)", migrations); err != nil {
+		if cerr := db.Close(); cerr != nil {
+			logrus.WithError(cerr).Warn("close token database after migration failure")
+		}
+		return nil, fmt.Errorf("migrate token database: %w", err)
+	}
+
+	return &TokenStore{db: db}, nil
+}
+
+// Close tears down the underlying database connection.
+func (s *TokenStore) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// CreateToken stores token metadata together with the hash of its secret.
+func (s *TokenStore) CreateToken(ctx context.Context, token Token, hash string) (Token, error) {
+	if s == nil || s.db == nil {
+		return Token{}, fmt.Errorf("store not initialized")
+	}
+	if strings.TrimSpace(hash) == "" {
+		return Token{}, fmt.Errorf("token hash is required")
+	}
+	if len(token.Scopes) == 0 {
+		return Token{}, fmt.Errorf("token requires at least one namespace scope")
+	}
+	for namespace, scope := range token.Scopes {
+		if strings.TrimSpace(namespace) == "" {
+			return Token{}, fmt.Errorf("token scope namespace is required")
+		}
+		if _, err := ParseTokenScope(string(scope)); err != nil {
+			return Token{}, err
+		}
+	}
+
+	token.ID = generateID()
+	token.RevokedAt = nil
+
+	logrus.WithFields(logrus.Fields{
+		"table":      "api_tokens",
+		"operation":  "create",
+		"token_id":   token.ID,
+		"name":       token.Name,
+		"namespaces": token.Namespaces(),
+	}).Info("database operation")
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Token{}, fmt.Errorf("begin token transaction: %w", err)
+	}
+	defer rollbackTx(tx, "rollback create token transaction")
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO api_tokens (id, name, token_hash)
+VALUES (?, ?, ?)
+`, token.ID, token.Name, hash); err != nil {
+		return Token{}, fmt.Errorf("insert token: %w", err)
+	}
+	for _, namespace := range token.Namespaces() {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO api_token_scopes (token_id, namespace, scope)
+VALUES (?, ?, ?)
+`, token.ID, namespace, string(token.Scopes[namespace])); err != nil {
+			return Token{}, fmt.Errorf("insert token scope %s: %w", namespace, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Token{}, fmt.Errorf("commit token creation: %w", err)
+	}
+
+	return s.getToken(ctx, `WHERE id = ?`, token.ID)
+}
+
+// GetTokenByHash looks up a token by the hash of its secret.
+func (s *TokenStore) GetTokenByHash(ctx context.Context, hash string) (Token, error) {
+	if s == nil || s.db == nil {
+		return Token{}, fmt.Errorf("store not initialized")
+	}
+	return s.getToken(ctx, `WHERE token_hash = ?`, hash)
+}
+
+// ListTokens returns every token ordered by creation.
+func (s *TokenStore) ListTokens(ctx context.Context) ([]Token, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, name, created_at, revoked_at
+FROM api_tokens
+ORDER BY created_at ASC, id ASC
+`)
+	if err != nil {
+		return nil, fmt.Errorf("query tokens: %w", err)
+	}
+	defer closeRows(rows, "close tokens rows")
+
+	tokens := make([]Token, 0)
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan tokens: %w", err)
+	}
+
+	for i := range tokens {
+		if tokens[i].Scopes, err = s.loadTokenScopes(ctx, tokens[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return tokens, nil
+}
+
+// RevokeToken marks a token as revoked so it can no longer authenticate.
+func (s *TokenStore) RevokeToken(ctx context.Context, id string) (Token, error) {
+	if s == nil || s.db == nil {
+		return Token{}, fmt.Errorf("store not initialized")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"table":     "api_tokens",
+		"operation": "revoke",
+		"token_id":  id,
+	}).Info("database operation")
+
+	res, err := s.db.ExecContext(ctx, `
+UPDATE api_tokens
+SET revoked_at = COALESCE(revoked_at, ?)
+WHERE id = ?
+`, currentTimestamp(), id)
+	if err != nil {
+		return Token{}, fmt.Errorf("revoke token: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return Token{}, fmt.Errorf("revoke token rows affected: %w", err)
+	}
+	if affected == 0 {
+		return Token{}, ErrTokenNotFound
+	}
+	return s.getToken(ctx, `WHERE id = ?`, id)
+}
+
+func (s *TokenStore) getToken(ctx context.Context, where string, arg any) (Token, error) {
+	row := s.db.QueryRowContext(ctx, `
+SELECT id, name, created_at, revoked_at
+FROM api_tokens
+`+where, arg)
+	token, err := scanToken(row)
+	if err != nil {
+		return Token{}, err
+	}
+	if token.Scopes, err = s.loadTokenScopes(ctx, token.ID); err != nil {
+		return Token{}, err
+	}
+	return token, nil
+}
+
+func (s *TokenStore) loadTokenScopes(ctx context.Context, id string) (map[string]TokenScope, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT namespace, scope FROM api_token_scopes WHERE token_id = ? ORDER BY namespace ASC`, id)
+	if err != nil {
+		return nil, fmt.Errorf("query token scopes: %w", err)
+	}
+	defer closeRows(rows, "close token scope rows")
+
+	scopes := make(map[string]TokenScope)
+	for rows.Next() {
+		var namespace, scope string
+		if err := rows.Scan(&namespace, &scope); err != nil {
+			return nil, fmt.Errorf("scan token scope: %w", err)
+		}
+		scopes[namespace] = TokenScope(scope)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan token scopes: %w", err)
+	}
+	return scopes, nil
+}
+
+func scanToken(scanner rowScanner) (Token, error) {
+	var (
+		token     Token
+		createdAt string
+		revokedAt sql.NullString
+	)
+
+	if err := scanner.Scan(&token.ID, &token.Name, &createdAt, &revokedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Token{}, ErrTokenNotFound
+		}
+		return Token{}, err
+	}
+
+	var err error
+	if token.CreatedAt, err = parseCreatedAt(createdAt); err != nil {
+		return Token{}, err
+	}
+	if revokedAt.Valid {
+		revoked, err := parseCreatedAt(revokedAt.String)
+		if err != nil {
+			return Token{}, err
+		}
+		token.RevokedAt = &revoked
+	}
+
+	return token, nil
+}
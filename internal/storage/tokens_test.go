@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenStoreLifecycle(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tokens, err := NewTokenStore(ctx, filepath.Join(t.TempDir(), "tokens.db"))
+	require.NoError(t, err, "NewTokenStore() error")
+	defer func() {
+		if err := tokens.Close(); err != nil {
+			t.Errorf("close token store: %v", err)
+		}
+	}()
+
+	_, err = tokens.CreateToken(ctx, Token{Name: "empty"}, "hash-0")
+	assert.Error(t, err, "tokens need at least one scope")
+	_, err = tokens.CreateToken(ctx, Token{Name: "bad", Scopes: map[string]TokenScope{"team-a": "admin"}}, "hash-0")
+	assert.ErrorIs(t, err, ErrInvalidTokenScope)
+
+	created, err := tokens.CreateToken(ctx, Token{
+		Name:   "ci",
+		Scopes: map[string]TokenScope{"team-a": TokenScopeWrite, "team-b": TokenScopeRead},
+	}, "hash-1")
+	require.NoError(t, err, "CreateToken() error")
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, []string{"team-a", "team-b"}, created.Namespaces())
+	assert.False(t, created.CreatedAt.IsZero())
+
+	loaded, err := tokens.GetTokenByHash(ctx, "hash-1")
+	require.NoError(t, err, "GetTokenByHash() error")
+	assert.Equal(t, created.ID, loaded.ID)
+	assert.True(t, loaded.Allows("team-a", TokenScopeWrite))
+	assert.True(t, loaded.Allows("team-a", TokenScopeRead), "write implies read")
+	assert.True(t, loaded.Allows("team-b", TokenScopeRead))
+	assert.False(t, loaded.Allows("team-b", TokenScopeWrite))
+	assert.False(t, loaded.Allows("team-c", TokenScopeRead))
+
+	_, err = tokens.GetTokenByHash(ctx, "missing")
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	revoked, err := tokens.RevokeToken(ctx, created.ID)
+	require.NoError(t, err, "RevokeToken() error")
+	require.NotNil(t, revoked.RevokedAt)
+	assert.False(t, revoked.Allows("team-a", TokenScopeRead), "revoked tokens allow nothing")
+
+	_, err = tokens.RevokeToken(ctx, "missing")
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	list, err := tokens.ListTokens(ctx)
+	require.NoError(t, err, "ListTokens() error")
+	require.Len(t, list, 1)
+	assert.True(t, list[0].Revoked())
+	assert.Len(t, list[0].Scopes, 2)
+}
+
+func TestTokenWildcardScope(t *testing.T) {
+	t.Parallel()
+
+	token := Token{Scopes: map[string]TokenScope{TokenNamespaceWildcard: TokenScopeRead, "team-a": TokenScopeWrite}}
+	assert.True(t, token.Allows("anything", TokenScopeRead))
+	assert.False(t, token.Allows("anything", TokenScopeWrite))
+	assert.True(t, token.Allows("team-a", TokenScopeWrite))
+}
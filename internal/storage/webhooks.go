@@ -0,0 +1,841 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WebhookDeliveryStatus describes where a delivery is in its retry lifecycle.
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending marks a delivery that waits for its next attempt.
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliverySucceeded marks a delivery acknowledged with a 2xx response.
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryFailed marks a delivery that ran out of attempts.
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// DefaultWebhookDeliveryListLimit caps the number of deliveries returned when no limit is given.
+const DefaultWebhookDeliveryListLimit = 100
+
+var (
+	// ErrWebhookNotFound is returned when a webhook cannot be located.
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrInvalidWebhook is returned when a webhook definition is rejected.
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	// ErrInvalidWebhookDeliveryStatus is returned when a delivery status value is not recognized.
+	ErrInvalidWebhookDeliveryStatus = errors.New("invalid webhook delivery status")
+)
+
+const (
+	webhooksTableStatement = `
+CREATE TABLE IF NOT EXISTS webhooks (
+	id TEXT PRIMARY KEY,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	resource_types TEXT,
+	label_selector TEXT,
+	cursor INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+	webhookDeliveriesTableStatement = `
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id TEXT PRIMARY KEY,
+	webhook_id TEXT NOT NULL,
+	event_seq INTEGER NOT NULL,
+	event TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'succeeded', 'failed')),
+	attempts INTEGER NOT NULL DEFAULT 0,
+	response_status INTEGER,
+	last_error TEXT,
+	next_attempt_at DATETIME,
+	delivered_at DATETIME,
+	created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+	updated_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+	FOREIGN KEY(webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+	UNIQUE(webhook_id, event_seq)
+)`
+	webhookDeliveriesDueIndexStatement = `
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at)`
+)
+
+// Webhook subscribes an HTTP endpoint to the namespace change feed. Empty
+// ResourceTypes and LabelSelector match every event. The secret is only
+// returned when it is set or generated, never when reading a webhook back.
+// Deliveries leave out request, register and grant payloads unless
+// IncludePayload is set.
+type Webhook struct {
+	ID             string            `json:"id"`
+	URL            string            `json:"url"`
+	Secret         string            `json:"secret,omitempty"`
+	ResourceTypes  []string          `json:"resource_types,omitempty"`
+	LabelSelector  map[string]string `json:"label_selector,omitempty"`
+	IncludePayload bool              `json:"include_payload"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// Matches reports whether the webhook subscribes to the event.
+func (w Webhook) Matches(event Event) bool {
+	if len(w.ResourceTypes) > 0 && !slices.Contains(w.ResourceTypes, event.ResourceType) {
+		return false
+	}
+	for key, value := range w.LabelSelector {
+		if got, ok := event.Labels[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+// WebhookDelivery records one event sent, or still to be sent, to a webhook.
+type WebhookDelivery struct {
+	ID             string                `json:"id"`
+	WebhookID      string                `json:"webhook_id"`
+	Event          Event                 `json:"event"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	ResponseStatus int                   `json:"response_status,omitempty"`
+	LastError      string                `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
+
+// WebhookDeliveryListFilters describes optional filters for listing deliveries.
+type WebhookDeliveryListFilters struct {
+	WebhookID string
+	Statuses  []WebhookDeliveryStatus
+	Limit     int
+}
+
+// WebhookDispatch is a due delivery together with the target it must be sent to.
+type WebhookDispatch struct {
+	Delivery       WebhookDelivery
+	URL            string
+	Secret         string
+	IncludePayload bool
+}
+
+// WebhookDeliveryAttempt describes the outcome of sending a delivery once.
+// An empty Error marks success; otherwise the delivery is retried at RetryAt,
+// or marked failed when RetryAt is nil.
+type WebhookDeliveryAttempt struct {
+	ResponseStatus int
+	Error          string
+	RetryAt        *time.Time
+}
+
+// WebhookDeliveryStatuses returns every known delivery status.
+func WebhookDeliveryStatuses() []WebhookDeliveryStatus {
+	return []WebhookDeliveryStatus{WebhookDeliveryPending, WebhookDeliverySucceeded, WebhookDeliveryFailed}
+}
+
+// ParseWebhookDeliveryStatus validates and normalizes a delivery status value.
+func ParseWebhookDeliveryStatus(value string) (WebhookDeliveryStatus, error) {
+	normalized := WebhookDeliveryStatus(strings.ToLower(strings.TrimSpace(value)))
+	if slices.Contains(WebhookDeliveryStatuses(), normalized) {
+		return normalized, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidWebhookDeliveryStatus, value)
+}
+
+func (s *Store) ensureWebhookTables(ctx context.Context, tx *sql.Tx) error {
+	for _, stmt := range []string{webhooksTableStatement, webhookDeliveriesTableStatement, webhookDeliveriesDueIndexStatement} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("create webhook tables: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) ensureWebhookIncludePayloadColumn(ctx context.Context, tx *sql.Tx) error {
+	columns, err := tableColumns(ctx, tx, "webhooks")
+	if err != nil {
+		return err
+	}
+	if columns["include_payload"] {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE webhooks ADD COLUMN include_payload INTEGER NOT NULL DEFAULT 0`); err != nil {
+		return fmt.Errorf("add webhooks include_payload column: %w", err)
+	}
+	return nil
+}
+
+func validateWebhook(webhook Webhook) error {
+	u, err := url.Parse(strings.TrimSpace(webhook.URL))
+	if err != nil {
+		return fmt.Errorf("%w: parse url: %v", ErrInvalidWebhook, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: url scheme must be http or https", ErrInvalidWebhook)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%w: url host is required", ErrInvalidWebhook)
+	}
+	for _, resourceType := range webhook.ResourceTypes {
+		if !slices.Contains(EventResourceTypes(), resourceType) {
+			return fmt.Errorf("%w: unknown resource type %q", ErrInvalidWebhook, resourceType)
+		}
+	}
+	for key := range webhook.LabelSelector {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("%w: label selector keys must not be empty", ErrInvalidWebhook)
+		}
+	}
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// CreateWebhook stores a new subscription. A secret is generated when none is
+// given. The webhook only receives events recorded after its creation.
+func (s *Store) CreateWebhook(ctx context.Context, webhook Webhook) (Webhook, error) {
+	if s == nil || s.db == nil {
+		return Webhook{}, fmt.Errorf("store not initialized")
+	}
+	webhook.URL = strings.TrimSpace(webhook.URL)
+	if err := validateWebhook(webhook); err != nil {
+		return Webhook{}, err
+	}
+	if webhook.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			return Webhook{}, err
+		}
+		webhook.Secret = secret
+	}
+
+	webhook.ID = generateID()
+
+	defer s.logDBOperation(ctx, "webhooks", "create", logrus.Fields{
+		"webhook_id":      webhook.ID,
+		"url":             webhook.URL,
+		"resource_types":  webhook.ResourceTypes,
+		"label_selector":  webhook.LabelSelector,
+		"include_payload": webhook.IncludePayload,
+	})()
+
+	resourceTypes, labelSelector, err := encodeWebhookFilters(webhook)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Webhook{}, fmt.Errorf("begin webhook transaction: %w", err)
+	}
+	defer rollbackTx(tx, "rollback create webhook transaction")
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO webhooks (id, url, secret, resource_types, label_selector, include_payload, cursor)
+VALUES (?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(seq), 0) FROM events))
+`, webhook.ID, webhook.URL, webhook.Secret, resourceTypes, labelSelector, boolColumn(webhook.IncludePayload)); err != nil {
+		return Webhook{}, fmt.Errorf("insert webhook: %w", err)
+	}
+
+	if err := recordAudit(ctx, tx, "create", AuditResourceWebhooks, webhook.ID, nil); err != nil {
+		return Webhook{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Webhook{}, fmt.Errorf("commit webhook creation: %w", err)
+	}
+
+	created, err := s.GetWebhook(ctx, webhook.ID)
+	if err != nil {
+		return Webhook{}, err
+	}
+	created.Secret = webhook.Secret
+	return created, nil
+}
+
+// GetWebhook returns the webhook for the given identifier without its secret.
+func (s *Store) GetWebhook(ctx context.Context, id string) (Webhook, error) {
+	if s == nil || s.db == nil {
+		return Webhook{}, fmt.Errorf("store not initialized")
+	}
+
+	defer s.logDBOperation(ctx, "webhooks", "get", logrus.Fields{
+		"webhook_id": id,
+	})()
+
+	row := s.db.QueryRowContext(ctx, `
+SELECT id, url, resource_types, label_selector, include_payload, created_at, updated_at
+FROM webhooks
+WHERE id = ?
+`, id)
+	return scanWebhook(row)
+}
+
+// ListWebhooks returns every webhook ordered by creation, without secrets.
+func (s *Store) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+
+	defer s.logDBOperation(ctx, "webhooks", "list", nil)()
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, url, resource_types, label_selector, include_payload, created_at, updated_at
+FROM webhooks
+ORDER BY created_at ASC, id ASC
+`)
+	if err != nil {
+		return nil, fmt.Errorf("query webhooks: %w", err)
+	}
+	defer closeRows(rows, "close webhooks rows")
+
+	webhooks := make([]Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// UpdateWebhook replaces the url, filters and payload option of a webhook. The
+// secret is only rotated when webhook.Secret is not empty.
+func (s *Store) UpdateWebhook(ctx context.Context, webhook Webhook) (Webhook, error) {
+	if s == nil || s.db == nil {
+		return Webhook{}, fmt.Errorf("store not initialized")
+	}
+	webhook.URL = strings.TrimSpace(webhook.URL)
+	if err := validateWebhook(webhook); err != nil {
+		return Webhook{}, err
+	}
+
+	defer s.logDBOperation(ctx, "webhooks", "update", logrus.Fields{
+		"webhook_id":      webhook.ID,
+		"url":             webhook.URL,
+		"resource_types":  webhook.ResourceTypes,
+		"label_selector":  webhook.LabelSelector,
+		"include_payload": webhook.IncludePayload,
+		"rotate_secret":   webhook.Secret != "",
+	})()
+
+	resourceTypes, labelSelector, err := encodeWebhookFilters(webhook)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Webhook{}, fmt.Errorf("begin webhook update transaction: %w", err)
+	}
+	defer rollbackTx(tx, "rollback update webhook transaction")
+
+	before, err := auditSnapshot(ctx, tx, AuditResourceWebhooks, webhook.ID)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+UPDATE webhooks
+SET url = ?, resource_types = ?, label_selector = ?, include_payload = ?,
+    secret = CASE WHEN ? = '' THEN secret ELSE ? END,
+    updated_at = ?
+WHERE id = ?
+`, webhook.URL, resourceTypes, labelSelector, boolColumn(webhook.IncludePayload), webhook.Secret, webhook.Secret, currentTimestamp(), webhook.ID)
+	if err != nil {
+		return Webhook{}, fmt.Errorf("update webhook: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return Webhook{}, fmt.Errorf("update webhook rows affected: %w", err)
+	}
+	if affected == 0 {
+		return Webhook{}, ErrWebhookNotFound
+	}
+
+	if err := recordAudit(ctx, tx, "update", AuditResourceWebhooks, webhook.ID, before); err != nil {
+		return Webhook{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Webhook{}, fmt.Errorf("commit webhook update: %w", err)
+	}
+
+	updated, err := s.GetWebhook(ctx, webhook.ID)
+	if err != nil {
+		return Webhook{}, err
+	}
+	updated.Secret = webhook.Secret
+	return updated, nil
+}
+
+// DeleteWebhook removes a webhook together with its delivery log.
+func (s *Store) DeleteWebhook(ctx context.Context, id string) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("store not initialized")
+	}
+
+	defer s.logDBOperation(ctx, "webhooks", "delete", logrus.Fields{
+		"webhook_id": id,
+	})()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin delete webhook transaction: %w", err)
+	}
+	defer rollbackTx(tx, "rollback delete webhook transaction")
+
+	before, err := auditSnapshot(ctx, tx, AuditResourceWebhooks, id)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete webhook rows affected: %w", err)
+	}
+	if count == 0 {
+		return ErrWebhookNotFound
+	}
+
+	if err := recordAudit(ctx, tx, "delete", AuditResourceWebhooks, id, before); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit webhook deletion: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns deliveries newest first.
+func (s *Store) ListWebhookDeliveries(ctx context.Context, filters WebhookDeliveryListFilters) ([]WebhookDelivery, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	if filters.Limit <= 0 {
+		filters.Limit = DefaultWebhookDeliveryListLimit
+	}
+
+	defer s.logDBOperation(ctx, "webhook_deliveries", "list", logrus.Fields{
+		"webhook_id": filters.WebhookID,
+		"statuses":   filters.Statuses,
+		"limit":      filters.Limit,
+	})()
+
+	query := strings.Builder{}
+	query.WriteString(`
+SELECT ` + webhookDeliveryColumns + `
+FROM webhook_deliveries`)
+	var (
+		where []string
+		args  []any
+	)
+	if filters.WebhookID != "" {
+		where = append(where, "webhook_id = ?")
+		args = append(args, filters.WebhookID)
+	}
+	if len(filters.Statuses) > 0 {
+		where = append(where, "status IN (?"+strings.Repeat(", ?", len(filters.Statuses)-1)+")")
+		for _, status := range filters.Statuses {
+			args = append(args, string(status))
+		}
+	}
+	if len(where) > 0 {
+		query.WriteString(" WHERE ")
+		query.WriteString(strings.Join(where, " AND "))
+	}
+	query.WriteString(" ORDER BY event_seq DESC, created_at DESC LIMIT ?")
+	args = append(args, filters.Limit)
+
+	rows, err := s.db.QueryContext(ctx, query.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query webhook deliveries: %w", err)
+	}
+	defer closeRows(rows, "close webhook deliveries rows")
+
+	deliveries := make([]WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// EnqueueWebhookDeliveries advances every webhook over the change feed and
+// queues a pending delivery, due at now, for each matching event. It returns
+// the number of deliveries queued.
+func (s *Store) EnqueueWebhookDeliveries(ctx context.Context, now time.Time) (int, error) {
+	if s == nil || s.db == nil {
+		return 0, fmt.Errorf("store not initialized")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, resource_types, label_selector, cursor
+FROM webhooks
+ORDER BY created_at ASC, id ASC
+`)
+	if err != nil {
+		return 0, fmt.Errorf("query webhook cursors: %w", err)
+	}
+	type subscription struct {
+		webhook Webhook
+		cursor  int64
+	}
+	var subscriptions []subscription
+	for rows.Next() {
+		var (
+			sub           subscription
+			resourceTypes sql.NullString
+			labelSelector sql.NullString
+		)
+		if err := rows.Scan(&sub.webhook.ID, &resourceTypes, &labelSelector, &sub.cursor); err != nil {
+			closeRows(rows, "close webhook cursor rows")
+			return 0, fmt.Errorf("scan webhook cursor: %w", err)
+		}
+		if err := decodeWebhookFilters(&sub.webhook, resourceTypes, labelSelector); err != nil {
+			closeRows(rows, "close webhook cursor rows")
+			return 0, err
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+	closeRows(rows, "close webhook cursor rows")
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("scan webhook cursors: %w", err)
+	}
+
+	queued := 0
+	for _, sub := range subscriptions {
+		for {
+			events, err := s.ListEvents(ctx, EventListFilters{Since: sub.cursor, ResourceTypes: sub.webhook.ResourceTypes})
+			if err != nil {
+				return queued, err
+			}
+			if len(events) == 0 {
+				break
+			}
+			count, err := s.enqueueWebhookEvents(ctx, sub.webhook, events, now)
+			if err != nil {
+				return queued, err
+			}
+			queued += count
+			sub.cursor = events[len(events)-1].Seq
+		}
+	}
+	return queued, nil
+}
+
+func (s *Store) enqueueWebhookEvents(ctx context.Context, webhook Webhook, events []Event, now time.Time) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin webhook enqueue transaction: %w", err)
+	}
+	defer rollbackTx(tx, "rollback webhook enqueue transaction")
+
+	due := now.UTC().Format(timestampLayout)
+	queued := 0
+	for _, event := range events {
+		if !webhook.Matches(event) {
+			continue
+		}
+		encoded, err := json.Marshal(event)
+		if err != nil {
+			return 0, fmt.Errorf("encode webhook event: %w", err)
+		}
+		res, err := tx.ExecContext(ctx, `
+INSERT INTO webhook_deliveries (id, webhook_id, event_seq, event, next_attempt_at)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT DO NOTHING
+`, generateID(), webhook.ID, event.Seq, string(encoded), due)
+		if err != nil {
+			return 0, fmt.Errorf("insert webhook delivery: %w", err)
+		}
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("insert webhook delivery rows affected: %w", err)
+		}
+		queued += int(inserted)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE webhooks SET cursor = ? WHERE id = ?`, events[len(events)-1].Seq, webhook.ID); err != nil {
+		return 0, fmt.Errorf("advance webhook cursor: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit webhook enqueue: %w", err)
+	}
+	if queued > 0 {
+		s.logDBEntry("webhook_deliveries", "enqueue", logrus.Fields{
+			"webhook_id": webhook.ID,
+			"count":      queued,
+		})
+	}
+	return queued, nil
+}
+
+// DueWebhookDeliveries returns pending deliveries whose next attempt is at or
+// before now, oldest event first. Servers sharing the database see the same
+// deliveries; each must be claimed with ClaimWebhookDelivery before it is sent.
+func (s *Store) DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]WebhookDispatch, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	if limit <= 0 {
+		limit = DefaultWebhookDeliveryListLimit
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+webhookDeliveryColumns+`, webhooks.url, webhooks.secret, webhooks.include_payload
+FROM webhook_deliveries
+JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+WHERE status = 'pending' AND next_attempt_at <= ?
+ORDER BY event_seq ASC, webhook_deliveries.created_at ASC
+LIMIT ?
+`, now.UTC().Format(timestampLayout), limit)
+	if err != nil {
+		return nil, fmt.Errorf("query due webhook deliveries: %w", err)
+	}
+	defer closeRows(rows, "close due webhook deliveries rows")
+
+	dispatches := make([]WebhookDispatch, 0)
+	for rows.Next() {
+		var dispatch WebhookDispatch
+		var includePayload int64
+		dispatch.Delivery, err = scanWebhookDelivery(rows, &dispatch.URL, &dispatch.Secret, &includePayload)
+		if err != nil {
+			return nil, err
+		}
+		dispatch.IncludePayload = includePayload != 0
+		dispatches = append(dispatches, dispatch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan due webhook deliveries: %w", err)
+	}
+	return dispatches, nil
+}
+
+// ClaimWebhookDelivery reserves a due delivery for one attempt by moving its
+// next attempt to until, so that other servers sharing the database skip it
+// while the attempt runs. It reports false when the delivery is no longer due
+// because another server claimed or finished it first. A claim that is never
+// recorded, for example because the server stopped, lapses at until and the
+// delivery is retried.
+func (s *Store) ClaimWebhookDelivery(ctx context.Context, id string, now, until time.Time) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, fmt.Errorf("store not initialized")
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+UPDATE webhook_deliveries
+SET next_attempt_at = ?, updated_at = ?
+WHERE id = ? AND status = 'pending' AND next_attempt_at <= ?
+`, until.UTC().Format(timestampLayout), currentTimestamp(), id, now.UTC().Format(timestampLayout))
+	if err != nil {
+		return false, fmt.Errorf("claim webhook delivery: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("claim webhook delivery rows affected: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// RecordWebhookDeliveryAttempt stores the outcome of one delivery attempt.
+func (s *Store) RecordWebhookDeliveryAttempt(ctx context.Context, id string, attempt WebhookDeliveryAttempt) (WebhookDelivery, error) {
+	if s == nil || s.db == nil {
+		return WebhookDelivery{}, fmt.Errorf("store not initialized")
+	}
+
+	status := WebhookDeliverySucceeded
+	var nextAttemptAt, lastError any
+	if attempt.Error != "" {
+		lastError = attempt.Error
+		status = WebhookDeliveryFailed
+		if attempt.RetryAt != nil {
+			status = WebhookDeliveryPending
+			nextAttemptAt = attempt.RetryAt.UTC().Format(timestampLayout)
+		}
+	}
+	var responseStatus any
+	if attempt.ResponseStatus > 0 {
+		responseStatus = attempt.ResponseStatus
+	}
+
+	defer s.logDBOperation(ctx, "webhook_deliveries", "record_attempt", logrus.Fields{
+		"delivery_id":     id,
+		"status":          status,
+		"response_status": attempt.ResponseStatus,
+		"error":           attempt.Error,
+	})()
+
+	now := currentTimestamp()
+	res, err := s.db.ExecContext(ctx, `
+UPDATE webhook_deliveries
+SET status = ?, attempts = attempts + 1, response_status = ?, last_error = ?, next_attempt_at = ?,
+    delivered_at = CASE WHEN ? = 'succeeded' THEN ? ELSE delivered_at END,
+    updated_at = ?
+WHERE id = ?
+`, string(status), responseStatus, lastError, nextAttemptAt, string(status), now, now, id)
+	if err != nil {
+		return WebhookDelivery{}, fmt.Errorf("record webhook delivery attempt: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return WebhookDelivery{}, fmt.Errorf("record webhook delivery rows affected: %w", err)
+	}
+	if affected == 0 {
+		return WebhookDelivery{}, fmt.Errorf("webhook delivery %s not found", id)
+	}
+
+	row := s.db.QueryRowContext(ctx, `SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id)
+	return scanWebhookDelivery(row)
+}
+
+const webhookDeliveryColumns = `webhook_deliveries.id, webhook_id, event, status, attempts, response_status, last_error,
+       next_attempt_at, delivered_at, webhook_deliveries.created_at, webhook_deliveries.updated_at`
+
+// boolColumn encodes a flag for an INTEGER column, as 1 or 0 on both backends.
+func boolColumn(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func encodeWebhookFilters(webhook Webhook) (any, any, error) {
+	var resourceTypes, labelSelector any
+	var err error
+	if len(webhook.ResourceTypes) > 0 {
+		if resourceTypes, err = encodeJSON(webhook.ResourceTypes); err != nil {
+			return nil, nil, fmt.Errorf("encode webhook resource types: %w", err)
+		}
+	}
+	if len(webhook.LabelSelector) > 0 {
+		if labelSelector, err = encodeJSON(webhook.LabelSelector); err != nil {
+			return nil, nil, fmt.Errorf("encode webhook label selector: %w", err)
+		}
+	}
+	return resourceTypes, labelSelector, nil
+}
+
+func decodeWebhookFilters(webhook *Webhook, resourceTypes, labelSelector sql.NullString) error {
+	if resourceTypes.Valid && resourceTypes.String != "" {
+		if err := json.Unmarshal([]byte(resourceTypes.String), &webhook.ResourceTypes); err != nil {
+			return fmt.Errorf("decode webhook resource types: %w", err)
+		}
+	}
+	if labelSelector.Valid && labelSelector.String != "" {
+		if err := json.Unmarshal([]byte(labelSelector.String), &webhook.LabelSelector); err != nil {
+			return fmt.Errorf("decode webhook label selector: %w", err)
+		}
+	}
+	return nil
+}
+
+func scanWebhook(scanner rowScanner) (Webhook, error) {
+	var (
+		webhook        Webhook
+		resourceTypes  sql.NullString
+		labelSelector  sql.NullString
+		includePayload int64
+		createdAt      string
+		updatedAt      string
+	)
+
+	if err := scanner.Scan(&webhook.ID, &webhook.URL, &resourceTypes, &labelSelector, &includePayload, &createdAt, &updatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Webhook{}, ErrWebhookNotFound
+		}
+		return Webhook{}, err
+	}
+
+	if err := decodeWebhookFilters(&webhook, resourceTypes, labelSelector); err != nil {
+		return Webhook{}, err
+	}
+	webhook.IncludePayload = includePayload != 0
+	var err error
+	if webhook.CreatedAt, err = parseCreatedAt(createdAt); err != nil {
+		return Webhook{}, err
+	}
+	if webhook.UpdatedAt, err = parseCreatedAt(updatedAt); err != nil {
+		return Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func scanWebhookDelivery(scanner rowScanner, extra ...any) (WebhookDelivery, error) {
+	var (
+		delivery       WebhookDelivery
+		event          string
+		status         string
+		responseStatus sql.NullInt64
+		lastError      sql.NullString
+		nextAttemptAt  sql.NullString
+		deliveredAt    sql.NullString
+		createdAt      string
+		updatedAt      string
+	)
+
+	dest := append([]any{
+		&delivery.ID, &delivery.WebhookID, &event, &status, &delivery.Attempts, &responseStatus, &lastError,
+		&nextAttemptAt, &deliveredAt, &createdAt, &updatedAt,
+	}, extra...)
+	if err := scanner.Scan(dest...); err != nil {
+		return WebhookDelivery{}, fmt.Errorf("scan webhook delivery: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(event), &delivery.Event); err != nil {
+		return WebhookDelivery{}, fmt.Errorf("decode webhook delivery event: %w", err)
+	}
+	delivery.Status = WebhookDeliveryStatus(status)
+	delivery.ResponseStatus = int(responseStatus.Int64)
+	delivery.LastError = lastError.String
+
+	var err error
+	if delivery.NextAttemptAt, err = parseOptionalTime(nextAttemptAt); err != nil {
+		return WebhookDelivery{}, err
+	}
+	if delivery.DeliveredAt, err = parseOptionalTime(deliveredAt); err != nil {
+		return WebhookDelivery{}, err
+	}
+	if delivery.CreatedAt, err = parseCreatedAt(createdAt); err != nil {
+		return WebhookDelivery{}, err
+	}
+	if delivery.UpdatedAt, err = parseCreatedAt(updatedAt); err != nil {
+		return WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookCRUD(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	created, err := store.CreateWebhook(ctx, Webhook{
+		URL:           " https://hooks.example.com/grantory ",
+		ResourceTypes: []string{EventResourceRequests},
+		LabelSelector: map[string]string{"type": "db_user"},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, "https://hooks.example.com/grantory", created.URL)
+	assert.Len(t, created.Secret, 64, "a secret is generated when none is given")
+
+	loaded, err := store.GetWebhook(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Empty(t, loaded.Secret, "secrets are never read back")
+	assert.Equal(t, []string{EventResourceRequests}, loaded.ResourceTypes)
+	assert.Equal(t, map[string]string{"type": "db_user"}, loaded.LabelSelector)
+	assert.False(t, loaded.IncludePayload, "payloads are left out by default")
+
+	loaded.URL = "http://other.example.com/hook"
+	loaded.ResourceTypes = nil
+	loaded.IncludePayload = true
+	updated, err := store.UpdateWebhook(ctx, loaded)
+	require.NoError(t, err)
+	assert.Equal(t, "http://other.example.com/hook", updated.URL)
+	assert.Nil(t, updated.ResourceTypes)
+	assert.True(t, updated.IncludePayload)
+	assert.Empty(t, updated.Secret, "the secret is kept unless rotated")
+
+	list, err := store.ListWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, created.ID, list[0].ID)
+
+	for _, invalid := range []Webhook{
+		{URL: "ftp://example.com"},
+		{URL: "https://"},
+		{URL: "https://example.com", ResourceTypes: []string{"widgets"}},
+		{URL: "https://example.com", LabelSelector: map[string]string{" ": "x"}},
+	} {
+		_, err := store.CreateWebhook(ctx, invalid)
+		assert.ErrorIs(t, err, ErrInvalidWebhook, "webhook %+v should be rejected", invalid)
+	}
+
+	_, err = store.UpdateWebhook(ctx, Webhook{ID: "missing", URL: "https://example.com"})
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+
+	require.NoError(t, store.DeleteWebhook(ctx, created.ID))
+	_, err = store.GetWebhook(ctx, created.ID)
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+	assert.ErrorIs(t, store.DeleteWebhook(ctx, created.ID), ErrWebhookNotFound)
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	host, err := store.CreateHost(ctx, Host{})
+	require.NoError(t, err)
+	_, err = store.CreateRequest(ctx, Request{HostID: host.ID, Labels: map[string]string{"type": "db_user"}})
+	require.NoError(t, err, "events before the webhook exists are not delivered")
+
+	webhook, err := store.CreateWebhook(ctx, Webhook{
+		URL:           "https://hooks.example.com",
+		Secret:        "s3cret",
+		ResourceTypes: []string{EventResourceRequests},
+		LabelSelector: map[string]string{"type": "db_user"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", webhook.Secret)
+
+	matching, err := store.CreateRequest(ctx, Request{HostID: host.ID, Labels: map[string]string{"type": "db_user"}})
+	require.NoError(t, err)
+	_, err = store.CreateRequest(ctx, Request{HostID: host.ID, Labels: map[string]string{"type": "bucket"}})
+	require.NoError(t, err)
+	_, err = store.CreateRegister(ctx, Register{HostID: host.ID, Labels: map[string]string{"type": "db_user"}})
+	require.NoError(t, err)
+	require.NoError(t, store.DeleteRequest(ctx, matching.ID))
+
+	now := time.Now()
+	queued, err := store.EnqueueWebhookDeliveries(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, queued, "create and delete of the matching request")
+
+	queued, err = store.EnqueueWebhookDeliveries(ctx, now)
+	require.NoError(t, err)
+	assert.Zero(t, queued, "events are only queued once")
+
+	due, err := store.DueWebhookDeliveries(ctx, now, 0)
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	assert.Equal(t, "https://hooks.example.com", due[0].URL)
+	assert.Equal(t, "s3cret", due[0].Secret)
+	assert.False(t, due[0].IncludePayload)
+	assert.Equal(t, matching.ID, due[0].Delivery.Event.ResourceID)
+	assert.Equal(t, EventActionCreated, due[0].Delivery.Event.Action)
+	assert.Equal(t, EventActionDeleted, due[1].Delivery.Event.Action)
+	assert.Equal(t, map[string]string{"type": "db_user"}, due[1].Delivery.Event.Labels, "deletions keep their label snapshot")
+
+	retryAt := now.Add(time.Minute)
+	retried, err := store.RecordWebhookDeliveryAttempt(ctx, due[0].Delivery.ID, WebhookDeliveryAttempt{
+		ResponseStatus: 500,
+		Error:          "unexpected status 500",
+		RetryAt:        &retryAt,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, WebhookDeliveryPending, retried.Status)
+	assert.Equal(t, 1, retried.Attempts)
+	assert.Equal(t, 500, retried.ResponseStatus)
+	require.NotNil(t, retried.NextAttemptAt)
+
+	failed, err := store.RecordWebhookDeliveryAttempt(ctx, due[1].Delivery.ID, WebhookDeliveryAttempt{Error: "connection refused"})
+	require.NoError(t, err)
+	assert.Equal(t, WebhookDeliveryFailed, failed.Status)
+	assert.Nil(t, failed.NextAttemptAt)
+
+	due, err = store.DueWebhookDeliveries(ctx, now, 0)
+	require.NoError(t, err)
+	assert.Empty(t, due, "retries wait for their next attempt")
+
+	due, err = store.DueWebhookDeliveries(ctx, retryAt.Add(time.Second), 0)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+
+	succeeded, err := store.RecordWebhookDeliveryAttempt(ctx, due[0].Delivery.ID, WebhookDeliveryAttempt{ResponseStatus: 204})
+	require.NoError(t, err)
+	assert.Equal(t, WebhookDeliverySucceeded, succeeded.Status)
+	assert.Equal(t, 2, succeeded.Attempts)
+	assert.Empty(t, succeeded.LastError)
+	assert.NotNil(t, succeeded.DeliveredAt)
+
+	deliveries, err := store.ListWebhookDeliveries(ctx, WebhookDeliveryListFilters{WebhookID: webhook.ID})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, EventActionDeleted, deliveries[0].Event.Action, "newest first")
+
+	onlyFailed, err := store.ListWebhookDeliveries(ctx, WebhookDeliveryListFilters{Statuses: []WebhookDeliveryStatus{WebhookDeliveryFailed}})
+	require.NoError(t, err)
+	require.Len(t, onlyFailed, 1)
+	assert.Equal(t, "connection refused", onlyFailed[0].LastError)
+
+	require.NoError(t, store.DeleteWebhook(ctx, webhook.ID))
+	deliveries, err = store.ListWebhookDeliveries(ctx, WebhookDeliveryListFilters{})
+	require.NoError(t, err)
+	assert.Empty(t, deliveries, "the delivery log is removed with its webhook")
+}
+
+func TestClaimWebhookDelivery(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := newTestStore(ctx, t)
+	require.NoError(t, err, "New() error")
+	defer closeStore(t, store)
+	require.NoError(t, store.Migrate(ctx), "Migrate() error")
+
+	_, err = store.CreateWebhook(ctx, Webhook{URL: "https://hooks.example.com"})
+	require.NoError(t, err)
+	_, err = store.CreateHost(ctx, Host{})
+	require.NoError(t, err)
+
+	now := time.Now()
+	_, err = store.EnqueueWebhookDeliveries(ctx, now)
+	require.NoError(t, err)
+	due, err := store.DueWebhookDeliveries(ctx, now, 0)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	id := due[0].Delivery.ID
+
+	lease := now.Add(time.Minute)
+	claimed, err := store.ClaimWebhookDelivery(ctx, id, now, lease)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = store.ClaimWebhookDelivery(ctx, id, now, lease)
+	require.NoError(t, err)
+	assert.False(t, claimed, "a claimed delivery is not claimed twice")
+	due, err = store.DueWebhookDeliveries(ctx, now, 0)
+	require.NoError(t, err)
+	assert.Empty(t, due, "claimed deliveries are not due")
+
+	afterLease := lease.Add(time.Second)
+	claimed, err = store.ClaimWebhookDelivery(ctx, id, afterLease, afterLease.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, claimed, "a lapsed claim can be taken over")
+
+	_, err = store.RecordWebhookDeliveryAttempt(ctx, id, WebhookDeliveryAttempt{ResponseStatus: 204})
+	require.NoError(t, err)
+	claimed, err = store.ClaimWebhookDelivery(ctx, id, afterLease.Add(time.Hour), afterLease.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.False(t, claimed, "finished deliveries cannot be claimed")
+}
+
+func TestWebhookMatches(t *testing.T) {
+	t.Parallel()
+
+	event := Event{ResourceType: EventResourceRequests, Labels: map[string]string{"type": "db_user", "env": "prod"}}
+	assert.True(t, Webhook{}.Matches(event))
+	assert.True(t, Webhook{ResourceTypes: []string{EventResourceRequests, EventResourceGrants}}.Matches(event))
+	assert.False(t, Webhook{ResourceTypes: []string{EventResourceGrants}}.Matches(event))
+	assert.True(t, Webhook{LabelSelector: map[string]string{"type": "db_user"}}.Matches(event))
+	assert.False(t, Webhook{LabelSelector: map[string]string{"type": "bucket"}}.Matches(event))
+	assert.False(t, Webhook{LabelSelector: map[string]string{"team": ""}}.Matches(event), "selector keys must be present")
+}
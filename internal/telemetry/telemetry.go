@@ -0,0 +1,80 @@
+// Package telemetry configures OpenTelemetry tracing for the Grantory server,
+// CLI and provider.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/tasansga/terraform-provider-grantory"
+
+// Propagator reads and writes W3C trace context and baggage headers. It is
+// used directly rather than through the global propagator so that clients
+// propagate trace context even when they never call Setup.
+var Propagator propagation.TextMapPropagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{},
+	propagation.Baggage{},
+)
+
+// Options configures trace export.
+type Options struct {
+	// Endpoint is the OTLP/HTTP endpoint URL, e.g. http://localhost:4318.
+	// Tracing stays a no-op when it is empty.
+	Endpoint string
+	// SampleRatio is the fraction of new traces that are recorded. Traces
+	// started by a caller follow the caller's sampling decision.
+	SampleRatio float64
+	ServiceName string
+	Version     string
+}
+
+// Setup installs a global tracer provider that exports spans over OTLP. The
+// returned function flushes pending spans and must be called before exit.
+// Without an endpoint the default no-op provider is kept.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(Propagator)
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(opts.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("create otlp exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(opts.ServiceName),
+		semconv.ServiceVersion(opts.Version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer for Grantory spans. It follows the global tracer
+// provider, so spans are dropped until Setup configured an exporter.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// InjectHeaders writes the trace context of ctx into header.
+func InjectHeaders(ctx context.Context, header http.Header) {
+	Propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
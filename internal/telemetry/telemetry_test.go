@@ -0,0 +1,35 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetupWithoutEndpointIsNoop(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Options{ServiceName: "grantory"})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, span := Tracer().Start(context.Background(), "noop")
+	defer span.End()
+	assert.False(t, span.IsRecording(), "spans are dropped without an exporter")
+}
+
+func TestInjectHeaders(t *testing.T) {
+	header := http.Header{}
+	InjectHeaders(context.Background(), header)
+	assert.Empty(t, header.Get("traceparent"), "nothing is injected without a span")
+
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	InjectHeaders(ctx, header)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", header.Get("traceparent"))
+}